@@ -0,0 +1,87 @@
+// Command sf-agent runs next to a CS2 server and uploads finished GOTV
+// recordings to StratagemForge.
+//
+//	SF_API_KEY=sfk_... sf-agent -url https://forge.example.com -dir /home/cs2/game/csgo -team t1
+//
+// The API key needs the upload scope. Flags can also be given as SF_URL,
+// SF_DIR, SF_TEAM_ID and SF_BANDWIDTH_KBPS.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/mwridgway/StratagemForge/clients/go/internal/agent"
+	"github.com/mwridgway/StratagemForge/clients/go/pkg/client"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "sf-agent:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var cfg agent.Config
+	baseURL := flag.String("url", os.Getenv("SF_URL"), "StratagemForge base URL")
+	flag.StringVar(&cfg.Dir, "dir", os.Getenv("SF_DIR"), "GOTV recording directory")
+	flag.StringVar(&cfg.Pattern, "pattern", "*.dem", "recording file glob within -dir")
+	flag.StringVar(&cfg.TeamID, "team", os.Getenv("SF_TEAM_ID"), "team the demos belong to")
+	flag.StringVar(&cfg.CompetitionID, "competition", "", "competition to attach demos to")
+	flag.DurationVar(&cfg.PollInterval, "poll", 10*time.Second, "how often to scan the directory")
+	flag.DurationVar(&cfg.SettleTime, "settle", 30*time.Second, "how long a recording must stay unchanged to count as finished")
+	bandwidth := flag.Int64("bandwidth-kbps", envInt("SF_BANDWIDTH_KBPS"), "upload bandwidth cap in KiB/s (0 = unlimited)")
+	statePath := flag.String("state", "", "state file (default <dir>/.sf-agent-state.json)")
+	retries := flag.Int("retries", 5, "retries per upload attempt for network errors, 429 and 5xx")
+	flag.Parse()
+
+	apiKey := os.Getenv("SF_API_KEY")
+	switch {
+	case *baseURL == "":
+		return fmt.Errorf("-url or SF_URL is required")
+	case cfg.Dir == "":
+		return fmt.Errorf("-dir or SF_DIR is required")
+	case apiKey == "":
+		return fmt.Errorf("SF_API_KEY is required")
+	}
+	cfg.BytesPerSecond = *bandwidth * 1024
+	if *statePath == "" {
+		*statePath = filepath.Join(cfg.Dir, ".sf-agent-state.json")
+	}
+
+	api, err := client.New(*baseURL,
+		client.WithAPIKey(apiKey),
+		client.WithRetries(*retries, time.Second, time.Minute),
+		client.WithUserAgent("sf-agent/0.1"),
+	)
+	if err != nil {
+		return err
+	}
+	state, err := agent.LoadState(*statePath)
+	if err != nil {
+		return fmt.Errorf("loading state: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	logger.Info("watching for recordings", "dir", cfg.Dir, "url", *baseURL)
+	if err := agent.New(cfg, api, state, logger).Run(ctx); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func envInt(name string) int64 {
+	value, _ := strconv.ParseInt(os.Getenv(name), 10, 64)
+	return value
+}
@@ -0,0 +1,3 @@
+module github.com/mwridgway/StratagemForge/clients/go
+
+go 1.22
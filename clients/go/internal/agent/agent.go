@@ -0,0 +1,196 @@
+// Package agent watches a CS2 server's GOTV recording directory and uploads
+// finished demos to StratagemForge.
+//
+// CS2 writes a recording while the match runs, so a .dem file only counts as
+// finished once its size and modification time have not changed for
+// SettleTime. Uploaded (and rejected) recordings are remembered in a state
+// file; failed uploads are retried with a growing delay.
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mwridgway/StratagemForge/clients/go/pkg/client"
+)
+
+const (
+	// StatusRejected marks recordings the API refused (e.g. not a demo); they are not retried.
+	StatusRejected = "rejected"
+	maxRetryDelay  = time.Hour
+)
+
+// Uploader is the part of client.Client the agent needs.
+type Uploader interface {
+	UploadFile(ctx context.Context, path string, opts client.UploadOptions) (*client.UploadResult, error)
+}
+
+// Config controls what the agent watches and how it uploads.
+type Config struct {
+	Dir            string        // GOTV recording directory (tv_record output)
+	Pattern        string        // glob within Dir; defaults to "*.dem"
+	PollInterval   time.Duration // how often Dir is scanned; defaults to 10s
+	SettleTime     time.Duration // how long a file must stay unchanged to count as finished; defaults to 30s
+	TeamID         string        // StratagemForge team the demos belong to
+	CompetitionID  string
+	BytesPerSecond int64 // upload bandwidth cap; zero means unlimited
+}
+
+type observation struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // when the file was last seen changing
+}
+
+type failure struct {
+	attempts int
+	retryAt  time.Time
+}
+
+// Agent uploads finished recordings from one directory.
+type Agent struct {
+	cfg      Config
+	uploader Uploader
+	state    *State
+	log      *slog.Logger
+	now      func() time.Time
+	seen     map[string]observation
+	failures map[string]failure
+}
+
+// New returns an agent for cfg that remembers handled recordings in state.
+func New(cfg Config, uploader Uploader, state *State, logger *slog.Logger) *Agent {
+	if cfg.Pattern == "" {
+		cfg.Pattern = "*.dem"
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	if cfg.SettleTime <= 0 {
+		cfg.SettleTime = 30 * time.Second
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Agent{
+		cfg:      cfg,
+		uploader: uploader,
+		state:    state,
+		log:      logger,
+		now:      time.Now,
+		seen:     map[string]observation{},
+		failures: map[string]failure{},
+	}
+}
+
+// Run scans the directory every PollInterval until ctx is cancelled.
+func (a *Agent) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := a.Scan(ctx); err != nil {
+			a.log.Error("scanning recordings failed", "dir", a.cfg.Dir, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan uploads every recording that has finished since the last scan.
+func (a *Agent) Scan(ctx context.Context) error {
+	paths, err := filepath.Glob(filepath.Join(a.cfg.Dir, a.cfg.Pattern))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	now := a.now()
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		name := filepath.Base(path)
+		if a.state.Handled(name, info.Size()) || !a.finished(name, info, now) {
+			continue
+		}
+		if f, ok := a.failures[name]; ok && now.Before(f.retryAt) {
+			continue
+		}
+		a.upload(ctx, path, name, info.Size(), now)
+	}
+	return nil
+}
+
+// finished reports whether the file has stayed unchanged for SettleTime.
+func (a *Agent) finished(name string, info os.FileInfo, now time.Time) bool {
+	previous, ok := a.seen[name]
+	if !ok || previous.size != info.Size() || !previous.modTime.Equal(info.ModTime()) {
+		a.seen[name] = observation{size: info.Size(), modTime: info.ModTime(), since: now}
+		return false
+	}
+	return now.Sub(previous.since) >= a.cfg.SettleTime
+}
+
+func (a *Agent) upload(ctx context.Context, path, name string, size int64, now time.Time) {
+	a.log.Info("uploading recording", "file", name, "bytes", size)
+	result, err := a.uploader.UploadFile(ctx, path, client.UploadOptions{
+		TeamID:         a.cfg.TeamID,
+		CompetitionID:  a.cfg.CompetitionID,
+		BytesPerSecond: a.cfg.BytesPerSecond,
+	})
+	record := Record{Size: size, UploadedAt: now}
+	switch {
+	case err == nil:
+		record.DemoID, record.Status = result.ID, result.Status
+		a.log.Info("recording uploaded", "file", name, "demo_id", result.ID, "status", result.Status)
+	case rejected(err):
+		record.Status, record.Error = StatusRejected, err.Error()
+		a.log.Warn("recording rejected", "file", name, "error", err)
+	default:
+		f := a.failures[name]
+		f.attempts++
+		f.retryAt = now.Add(retryDelay(a.cfg.PollInterval, f.attempts))
+		a.failures[name] = f
+		a.log.Warn("upload failed; will retry", "file", name, "attempt", f.attempts, "retry_at", f.retryAt, "error", err)
+		return
+	}
+	delete(a.failures, name)
+	delete(a.seen, name)
+	if err := a.state.Put(name, record); err != nil {
+		a.log.Error("saving agent state failed", "error", err)
+	}
+}
+
+// rejected reports whether the API refused the demo itself, so sending it
+// again cannot succeed. Auth errors are retried: keys get fixed.
+func rejected(err error) bool {
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+func retryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base << attempts
+	if delay <= 0 || delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
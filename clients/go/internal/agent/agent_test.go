@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mwridgway/StratagemForge/clients/go/pkg/client"
+)
+
+type fakeUploader struct {
+	uploads []string
+	errs    []error
+}
+
+func (f *fakeUploader) UploadFile(_ context.Context, path string, _ client.UploadOptions) (*client.UploadResult, error) {
+	f.uploads = append(f.uploads, filepath.Base(path))
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &client.UploadResult{Demo: client.Demo{ID: "d1", Status: client.StatusProcessed}}, nil
+}
+
+func newTestAgent(t *testing.T, uploader Uploader) (*Agent, string, *time.Time) {
+	t.Helper()
+	dir := t.TempDir()
+	state, err := LoadState(filepath.Join(dir, ".sf-agent-state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := New(Config{Dir: dir, PollInterval: time.Second, SettleTime: 30 * time.Second}, uploader, state, logger)
+	now := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+	return a, dir, &now
+}
+
+func writeDemo(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadsRecordingsOnceTheyStopGrowing(t *testing.T) {
+	uploader := &fakeUploader{}
+	a, dir, now := newTestAgent(t, uploader)
+	ctx := context.Background()
+	writeDemo(t, dir, "auto0-20240501-de_mirage.dem", "PBDEMS2")
+	writeDemo(t, dir, "notes.txt", "ignored")
+
+	_ = a.Scan(ctx)
+	*now = now.Add(10 * time.Second)
+	writeDemo(t, dir, "auto0-20240501-de_mirage.dem", "PBDEMS2 still recording")
+	_ = a.Scan(ctx)
+	*now = now.Add(20 * time.Second)
+	_ = a.Scan(ctx)
+	if len(uploader.uploads) != 0 {
+		t.Fatalf("uploaded a recording still being written: %v", uploader.uploads)
+	}
+
+	*now = now.Add(15 * time.Second)
+	_ = a.Scan(ctx)
+	*now = now.Add(time.Minute)
+	_ = a.Scan(ctx)
+	if len(uploader.uploads) != 1 || uploader.uploads[0] != "auto0-20240501-de_mirage.dem" {
+		t.Fatalf("uploads = %v, want the finished recording once", uploader.uploads)
+	}
+
+	reloaded, err := LoadState(filepath.Join(dir, ".sf-agent-state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record := reloaded.Records["auto0-20240501-de_mirage.dem"]; record.DemoID != "d1" {
+		t.Errorf("state record = %+v", record)
+	}
+}
+
+func TestFailedUploadsAreRetriedAndRejectedOnesAreNot(t *testing.T) {
+	uploader := &fakeUploader{errs: []error{
+		&client.APIError{StatusCode: http.StatusServiceUnavailable, Detail: "Parse queue is full"},
+		nil,
+		&client.APIError{StatusCode: http.StatusUnprocessableEntity, Detail: "Not a CS2 demo"},
+	}}
+	a, dir, now := newTestAgent(t, uploader)
+	ctx := context.Background()
+	writeDemo(t, dir, "a.dem", "PBDEMS2")
+	_ = a.Scan(ctx)
+	*now = now.Add(time.Minute)
+	_ = a.Scan(ctx) // fails with 503; retry in 2s
+	_ = a.Scan(ctx) // still backing off
+	*now = now.Add(3 * time.Second)
+	_ = a.Scan(ctx) // succeeds
+	if len(uploader.uploads) != 2 || !a.state.Handled("a.dem", 7) {
+		t.Fatalf("uploads = %v, state = %+v", uploader.uploads, a.state.Records)
+	}
+
+	writeDemo(t, dir, "b.dem", "garbage")
+	_ = a.Scan(ctx)
+	*now = now.Add(time.Minute)
+	_ = a.Scan(ctx)
+	*now = now.Add(time.Hour)
+	_ = a.Scan(ctx)
+	if len(uploader.uploads) != 3 || a.state.Records["b.dem"].Status != StatusRejected {
+		t.Fatalf("uploads = %v, state = %+v", uploader.uploads, a.state.Records)
+	}
+}
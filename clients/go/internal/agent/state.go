@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Record is what the agent remembers about a recording it has dealt with.
+type Record struct {
+	Size       int64     `json:"size"`
+	DemoID     string    `json:"demo_id,omitempty"`
+	Status     string    `json:"status"` // server status, or "rejected" for demos the API refused
+	Error      string    `json:"error,omitempty"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// State persists handled recordings so restarts do not upload them again.
+type State struct {
+	path    string
+	Records map[string]Record `json:"records"`
+}
+
+// LoadState reads the state file at path; a missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	state := &State{path: path, Records: map[string]Record{}}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, state); err != nil {
+		return nil, err
+	}
+	if state.Records == nil {
+		state.Records = map[string]Record{}
+	}
+	return state, nil
+}
+
+// Handled reports whether the recording name of the given size was already
+// uploaded or rejected. A size change means the file was replaced.
+func (s *State) Handled(name string, size int64) bool {
+	record, ok := s.Records[name]
+	return ok && record.Size == size
+}
+
+// Put records name and writes the state file atomically.
+func (s *State) Put(name string, record Record) error {
+	s.Records[name] = record
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".sf-agent-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// RunAnalysis runs an analysis ("basic" when analysisType is empty) over a
+// processed demo.
+func (c *Client) RunAnalysis(ctx context.Context, demoID, analysisType string) (*AnalysisResult, error) {
+	if analysisType == "" {
+		analysisType = "basic"
+	}
+	body, err := jsonBody(map[string]string{"demo_id": demoID, "analysis_type": analysisType})
+	if err != nil {
+		return nil, err
+	}
+	var result AnalysisResult
+	err = c.doJSON(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/analysis",
+		body:        body,
+		contentType: "application/json",
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Get decodes the JSON response of any read endpoint into out, with the
+// client's auth and retries. It reaches reports without a typed wrapper:
+//
+//	var report map[string]any
+//	err := c.Get(ctx, "/api/analysis/teams/Vitality/post-plant", url.Values{"map_name": {"de_mirage"}}, &report)
+func (c *Client) Get(ctx context.Context, path string, query url.Values, out any) error {
+	return c.doJSON(ctx, request{method: http.MethodGet, path: path, query: query}, out)
+}
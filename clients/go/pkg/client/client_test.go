@@ -0,0 +1,204 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := New(server.URL, append([]Option{WithRetries(3, time.Millisecond, time.Millisecond)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.sleep = func(context.Context, time.Duration) error { return nil }
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestUploadFileStreamsMultipartAndReportsProgress(t *testing.T) {
+	demo := filepath.Join(t.TempDir(), "match.dem")
+	if err := os.WriteFile(demo, []byte("PBDEMS2\x00demo data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/demos/upload" || r.URL.Query().Get("force") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if got := r.Header.Get("X-API-Key"); got != "sfk_test" {
+			t.Errorf("X-API-Key = %q", got)
+		}
+		file, header, err := r.FormFile("demo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "match.dem" || !bytes.HasPrefix(data, []byte("PBDEMS2")) {
+			t.Errorf("unexpected file %q: %q", header.Filename, data)
+		}
+		if r.FormValue("team_id") != "t1" || r.FormValue("competition_id") != "" {
+			t.Errorf("unexpected form %v", r.MultipartForm.Value)
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"id": "d1", "status": "processed", "message": "Demo uploaded and processed", "owner_id": "u1",
+		})
+	}, WithAPIKey("sfk_test"))
+
+	var lastSent, total int64
+	result, err := c.UploadFile(context.Background(), demo, UploadOptions{
+		TeamID:   "t1",
+		Force:    true,
+		Progress: func(sent, size int64) { lastSent, total = sent, size },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ID != "d1" || result.Status != StatusProcessed || result.OwnerID != "u1" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if lastSent != 17 || total != 17 {
+		t.Errorf("progress = %d/%d, want 17/17", lastSent, total)
+	}
+}
+
+func TestRetriesTransientFailuresWithBearerToken(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer jwt-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"detail": "Parse queue is full"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"demo_id": "d1", "status": "processed"})
+	}, WithBearerToken("jwt-token"))
+
+	status, err := c.Status(context.Background(), "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Processed() || calls.Load() != 3 {
+		t.Errorf("status %+v after %d calls", status, calls.Load())
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeJSON(w, http.StatusNotFound, map[string]string{"detail": "Demo not found"})
+	})
+
+	_, err := c.GetDemo(context.Background(), "missing")
+	if !IsNotFound(err) || calls.Load() != 1 {
+		t.Fatalf("err = %v after %d calls", err, calls.Load())
+	}
+	if apiErr := err.(*APIError); apiErr.Detail != "Demo not found" {
+		t.Errorf("detail = %q", apiErr.Detail)
+	}
+}
+
+func TestWaitProcessedPollsUntilProcessed(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		status := StatusUploaded
+		if calls.Add(1) >= 3 {
+			status = StatusProcessed
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"demo_id": "d1", "status": status})
+	})
+
+	updates, errs := c.WatchStatus(context.Background(), "d1", time.Millisecond)
+	var seen []string
+	for update := range updates {
+		seen = append(seen, update.Status)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2 || seen[0] != StatusUploaded || seen[1] != StatusProcessed {
+		t.Errorf("updates = %v", seen)
+	}
+
+	status, err := c.WaitProcessed(context.Background(), "d1", time.Millisecond)
+	if err != nil || !status.Processed() {
+		t.Errorf("WaitProcessed = %+v, %v", status, err)
+	}
+}
+
+func TestFilesAndDownload(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/demos/d1/files":
+			writeJSON(w, http.StatusOK, map[string]any{
+				"demo_id": "d1",
+				"files":   []map[string]any{{"name": "kills", "filename": "d1-kills.parquet", "download_url": "/x"}},
+			})
+		case "/api/demos/d1/files/kills":
+			_, _ = w.Write([]byte("PAR1"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	files, err := c.Files(context.Background(), "d1")
+	if err != nil || len(files) != 1 || files[0].Name != "kills" {
+		t.Fatalf("Files = %+v, %v", files, err)
+	}
+	var buf bytes.Buffer
+	n, err := c.DownloadFile(context.Background(), "d1", "kills", &buf)
+	if err != nil || n != 4 || buf.String() != "PAR1" {
+		t.Errorf("DownloadFile = %d %q, %v", n, buf.String(), err)
+	}
+}
+
+func TestThrottledReaderCapsBandwidth(t *testing.T) {
+	reader := &throttledReader{
+		ctx: context.Background(), r: bytes.NewReader(make([]byte, 3000)), rate: 10000, start: time.Now(),
+	}
+	started := time.Now()
+	n, err := io.Copy(io.Discard, reader)
+	if err != nil || n != 3000 {
+		t.Fatalf("copied %d, %v", n, err)
+	}
+	if elapsed := time.Since(started); elapsed < 250*time.Millisecond {
+		t.Errorf("3000 bytes at 10000 B/s took %s, want about 300ms", elapsed)
+	}
+}
+
+func TestCheckSchemaRejectsNewerLayouts(t *testing.T) {
+	version := func(v int) *int { return &v }
+	for _, f := range []DemoFile{
+		{Name: "kills"},
+		{Name: "kills", SchemaVersion: version(1)},
+		{Name: "brand_new_dataset", SchemaVersion: version(7)},
+	} {
+		if err := f.CheckSchema(); err != nil {
+			t.Errorf("CheckSchema(%+v) = %v", f, err)
+		}
+	}
+	var schemaErr *SchemaError
+	err := DemoFile{Name: "summary", SchemaVersion: version(3)}.CheckSchema()
+	if !errors.As(err, &schemaErr) || schemaErr.Version != 3 || schemaErr.Supported != 2 {
+		t.Errorf("CheckSchema(summary v3) = %v", err)
+	}
+}
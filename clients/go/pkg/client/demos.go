@@ -0,0 +1,331 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// UploadOptions are the optional fields of an upload or URL ingest.
+type UploadOptions struct {
+	CompetitionID   string
+	TeamID          string
+	ExternalMatchID string // e.g. the FACEIT match id
+	ExternalSource  string
+	CallbackURL     string // receives a signed POST once processing finishes
+	Force           bool   // reprocess even if the demo was already processed
+	// Progress, when set, is called as the file is sent with the bytes sent
+	// so far and the total (-1 if unknown). It restarts from 0 on retry.
+	Progress func(sent, total int64)
+	// BytesPerSecond caps the upload bandwidth; zero means unlimited.
+	BytesPerSecond int64
+}
+
+func (o UploadOptions) query() url.Values {
+	if !o.Force {
+		return nil
+	}
+	return url.Values{"force": {"true"}}
+}
+
+// UploadFile uploads the demo at path (.dem, optionally .gz, .bz2 or zipped).
+// The server parses the demo before answering, so the result is usually
+// already processed. Retrying is safe: re-sent files are matched by checksum.
+func (c *Client) UploadFile(ctx context.Context, path string, opts UploadOptions) (*UploadResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	open := func() (io.ReadCloser, error) { return os.Open(path) }
+	return c.upload(ctx, filepath.Base(path), open, info.Size(), false, opts)
+}
+
+// Upload uploads a demo read from r. Because r cannot be rewound it is sent
+// once, without retries; prefer UploadFile for files on disk.
+func (c *Client) Upload(ctx context.Context, filename string, r io.Reader, opts UploadOptions) (*UploadResult, error) {
+	open := func() (io.ReadCloser, error) { return io.NopCloser(r), nil }
+	return c.upload(ctx, filename, open, -1, true, opts)
+}
+
+func (c *Client) upload(
+	ctx context.Context, filename string, open func() (io.ReadCloser, error), size int64, once bool, opts UploadOptions,
+) (*UploadResult, error) {
+	fields := map[string]string{
+		"competition_id":    opts.CompetitionID,
+		"team_id":           opts.TeamID,
+		"external_match_id": opts.ExternalMatchID,
+		"external_source":   opts.ExternalSource,
+		"callback_url":      opts.CallbackURL,
+	}
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	body := func() (io.Reader, error) {
+		file, err := open()
+		if err != nil {
+			return nil, err
+		}
+		var source io.Reader = file
+		if opts.BytesPerSecond > 0 {
+			source = &throttledReader{ctx: ctx, r: file, rate: opts.BytesPerSecond, start: time.Now()}
+		}
+		// Stream the multipart body so large demos are never held in memory.
+		pr, pw := io.Pipe()
+		go func() {
+			defer file.Close()
+			pw.CloseWithError(writeMultipart(pw, boundary, fields, filename, &progressReader{
+				r: source, total: size, report: opts.Progress,
+			}))
+		}()
+		return pr, nil
+	}
+	var result UploadResult
+	err := c.doJSON(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/demos/upload",
+		query:       opts.query(),
+		body:        body,
+		contentType: "multipart/form-data; boundary=" + boundary,
+		noRetry:     once,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func writeMultipart(w io.Writer, boundary string, fields map[string]string, filename string, file io.Reader) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := mw.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("demo", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+type progressReader struct {
+	r      io.Reader
+	sent   int64
+	total  int64
+	report func(sent, total int64)
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	p.sent += int64(n)
+	if p.report != nil && n > 0 {
+		p.report(p.sent, p.total)
+	}
+	return n, err
+}
+
+// throttledReader delays reads so that no more than rate bytes per second
+// pass on average since start.
+type throttledReader struct {
+	ctx   context.Context
+	r     io.Reader
+	rate  int64
+	start time.Time
+	sent  int64
+}
+
+func (t *throttledReader) Read(buf []byte) (int, error) {
+	if int64(len(buf)) > t.rate {
+		buf = buf[:t.rate] // keep bursts to at most one second of budget
+	}
+	n, err := t.r.Read(buf)
+	t.sent += int64(n)
+	due := t.start.Add(time.Duration(float64(t.sent) / float64(t.rate) * float64(time.Second)))
+	if wait := time.Until(due); wait > 0 {
+		if sleepErr := sleepContext(t.ctx, wait); sleepErr != nil {
+			return n, sleepErr
+		}
+	}
+	return n, err
+}
+
+// IngestURL has the server download a demo from url (e.g. a FACEIT or Valve
+// replay link) and process it like an upload.
+func (c *Client) IngestURL(ctx context.Context, demoURL string, opts UploadOptions) (*UploadResult, error) {
+	payload := map[string]string{"url": demoURL}
+	for name, value := range map[string]string{
+		"competition_id":    opts.CompetitionID,
+		"team_id":           opts.TeamID,
+		"external_match_id": opts.ExternalMatchID,
+		"external_source":   opts.ExternalSource,
+		"callback_url":      opts.CallbackURL,
+	} {
+		if value != "" {
+			payload[name] = value
+		}
+	}
+	body, err := jsonBody(payload)
+	if err != nil {
+		return nil, err
+	}
+	var result UploadResult
+	err = c.doJSON(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/demos/ingest/url",
+		query:       opts.query(),
+		body:        body,
+		contentType: "application/json",
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListDemosOptions filters and pages ListDemos. Zero values are omitted.
+type ListDemosOptions struct {
+	CompetitionID string
+	Map           string
+	Player        string // SteamID or name
+	From, To      time.Time
+	Sort          string // e.g. "-played_at"
+	Page          int
+	PageSize      int // at most 200
+}
+
+// ListDemos returns one page of demos, newest upload first by default.
+func (c *Client) ListDemos(ctx context.Context, opts ListDemosOptions) (*DemoPage, error) {
+	query := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	set("competition_id", opts.CompetitionID)
+	set("map", opts.Map)
+	set("player", opts.Player)
+	set("sort", opts.Sort)
+	if !opts.From.IsZero() {
+		query.Set("from", opts.From.UTC().Format(time.RFC3339))
+	}
+	if !opts.To.IsZero() {
+		query.Set("to", opts.To.UTC().Format(time.RFC3339))
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PageSize > 0 {
+		query.Set("page_size", strconv.Itoa(opts.PageSize))
+	}
+	var page DemoPage
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: "/api/demos", query: query}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetDemo returns a demo with its metadata and match.
+func (c *Client) GetDemo(ctx context.Context, demoID string) (*Demo, error) {
+	var demo Demo
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: "/api/demos/" + url.PathEscape(demoID)}, &demo); err != nil {
+		return nil, err
+	}
+	return &demo, nil
+}
+
+// Status returns the processing state of a demo.
+func (c *Client) Status(ctx context.Context, demoID string) (*ProcessingStatus, error) {
+	var status ProcessingStatus
+	path := "/api/demos/" + url.PathEscape(demoID) + "/status"
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: path}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// WatchStatus polls a demo's status every interval and sends each change on
+// the returned channel. The channel is closed once the demo is processed,
+// ctx is done or polling fails; the error channel then carries the failure.
+func (c *Client) WatchStatus(ctx context.Context, demoID string, interval time.Duration) (<-chan ProcessingStatus, <-chan error) {
+	updates := make(chan ProcessingStatus)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(updates)
+		defer close(errs)
+		last := ""
+		for {
+			status, err := c.Status(ctx, demoID)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if status.Status != last {
+				last = status.Status
+				select {
+				case updates <- *status:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+			if status.Processed() {
+				return
+			}
+			if err := c.sleep(ctx, interval); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	return updates, errs
+}
+
+// WaitProcessed blocks until the demo is processed, polling every interval.
+func (c *Client) WaitProcessed(ctx context.Context, demoID string, interval time.Duration) (*ProcessingStatus, error) {
+	updates, errs := c.WatchStatus(ctx, demoID, interval)
+	var last *ProcessingStatus
+	for status := range updates {
+		last = &status
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// Files lists the datasets stored for a processed demo.
+func (c *Client) Files(ctx context.Context, demoID string) ([]DemoFile, error) {
+	var files demoFiles
+	path := "/api/demos/" + url.PathEscape(demoID) + "/files"
+	if err := c.doJSON(ctx, request{method: http.MethodGet, path: path}, &files); err != nil {
+		return nil, err
+	}
+	return files.Files, nil
+}
+
+// DownloadFile writes the parquet dataset name (e.g. "summary" or "kills")
+// of a demo to w and returns the number of bytes written. Redirects to
+// presigned object-store URLs are followed.
+func (c *Client) DownloadFile(ctx context.Context, demoID, name string, w io.Writer) (int64, error) {
+	path := fmt.Sprintf("/api/demos/%s/files/%s", url.PathEscape(demoID), url.PathEscape(name))
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return io.Copy(w, resp.Body)
+}
@@ -0,0 +1,19 @@
+// Package client is a typed Go client for the StratagemForge API.
+//
+// It covers the calls automation usually needs: uploading demos (with
+// progress reporting), waiting for processing to finish, listing and
+// downloading the parquet datasets of a demo, and running analyses. Requests
+// carry an API key or bearer token, and transient failures (network errors,
+// 429, 502, 503 and 504) are retried with exponential backoff that honours
+// Retry-After.
+//
+//	c, err := client.New("https://forge.example.com", client.WithAPIKey(os.Getenv("SF_API_KEY")))
+//	if err != nil {
+//		return err
+//	}
+//	demo, err := c.UploadFile(ctx, "match.dem", client.UploadOptions{TeamID: "t1"})
+//	if err != nil {
+//		return err
+//	}
+//	files, err := c.Files(ctx, demo.ID)
+package client
@@ -0,0 +1,53 @@
+package client
+
+import "fmt"
+
+// Schema is the parquet layout of one dataset at one version. The server
+// records it in each file's key-value metadata under
+// "stratagemforge.dataset" and "stratagemforge.schema_version", and lists it
+// as DemoFile.SchemaVersion.
+type Schema struct {
+	Dataset string
+	Version int
+}
+
+// Schemas are the dataset layouts this package was written against. A file
+// with a newer version may have columns this version does not know about.
+var Schemas = map[string]Schema{
+	"summary":        {Dataset: "summary", Version: 2},
+	"round_summary":  {Dataset: "round_summary", Version: 1},
+	"post_plant":     {Dataset: "post_plant", Version: 1},
+	"pistol_rounds":  {Dataset: "pistol_rounds", Version: 1},
+	"role_features":  {Dataset: "role_features", Version: 1},
+	"stats":          {Dataset: "stats", Version: 3},
+	"player_utility": {Dataset: "player_utility", Version: 1},
+	"clutches":       {Dataset: "clutches", Version: 1},
+	"kills":          {Dataset: "kills", Version: 3},
+	"duels":          {Dataset: "duels", Version: 1},
+	"site_hits":      {Dataset: "site_hits", Version: 1},
+	"player_ticks":   {Dataset: "player_ticks", Version: 1},
+	"save_decisions": {Dataset: "save_decisions", Version: 1},
+}
+
+// SchemaError reports a file written with a layout newer than Schemas knows.
+type SchemaError struct {
+	Dataset   string
+	Version   int
+	Supported int
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("stratagemforge: %s has schema version %d, this client supports up to %d",
+		e.Dataset, e.Version, e.Supported)
+}
+
+// CheckSchema returns a *SchemaError when f was written with a newer layout
+// than this package knows. Files from before versioning, and datasets the
+// package does not know at all, pass.
+func (f DemoFile) CheckSchema() error {
+	known, ok := Schemas[f.Name]
+	if !ok || f.SchemaVersion == nil || *f.SchemaVersion <= known.Version {
+		return nil
+	}
+	return &SchemaError{Dataset: f.Name, Version: *f.SchemaVersion, Supported: known.Version}
+}
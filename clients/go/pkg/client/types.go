@@ -0,0 +1,103 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Demo status values reported by the API.
+const (
+	StatusUploaded  = "uploaded"
+	StatusProcessed = "processed"
+)
+
+// Match is the match recorded from a parsed demo.
+type Match struct {
+	ID             string     `json:"id"`
+	MapName        string     `json:"map_name,omitempty"`
+	PlayedAt       time.Time  `json:"played_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	TeamA          string     `json:"team_a"`
+	TeamB          string     `json:"team_b"`
+	ScoreA         int        `json:"score_a"`
+	ScoreB         int        `json:"score_b"`
+	Winner         string     `json:"winner,omitempty"`
+	Rounds         int        `json:"rounds"`
+	PlayerCount    int        `json:"player_count"`
+	Source         string     `json:"source"`
+	EventName      string     `json:"event_name,omitempty"`
+	ExternalID     string     `json:"external_id,omitempty"`
+	ExternalSource string     `json:"external_source,omitempty"`
+	GameID         string     `json:"game_id,omitempty"`
+	IsPrimary      bool       `json:"is_primary"`
+}
+
+// Demo is an uploaded demo file. Metadata is only filled by GetDemo and uploads.
+type Demo struct {
+	ID               string         `json:"id"`
+	OriginalFilename string         `json:"original_filename"`
+	Checksum         string         `json:"checksum"`
+	SizeBytes        int64          `json:"size_bytes"`
+	Status           string         `json:"status"`
+	UploadedAt       time.Time      `json:"uploaded_at"`
+	ProcessedAt      *time.Time     `json:"processed_at,omitempty"`
+	CompetitionID    string         `json:"competition_id,omitempty"`
+	Series           string         `json:"series,omitempty"`
+	OwnerID          string         `json:"owner_id,omitempty"`
+	ContentType      string         `json:"content_type,omitempty"`
+	Metadata         map[string]any `json:"extra_metadata,omitempty"`
+	Match            *Match         `json:"match,omitempty"`
+}
+
+// UploadResult is a stored demo plus how the server handled it. Status is
+// "duplicate" when the same file was already processed and force was not set.
+type UploadResult struct {
+	Demo
+	Message string `json:"message"`
+}
+
+// DemoPage is one page of ListDemos.
+type DemoPage struct {
+	Demos    []Demo `json:"demos"`
+	Count    int    `json:"count"`
+	Total    int    `json:"total"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+}
+
+// ProcessingStatus is the processing state of a demo.
+type ProcessingStatus struct {
+	DemoID      string         `json:"demo_id"`
+	Status      string         `json:"status"`
+	Message     string         `json:"message"`
+	ProcessedAt *time.Time     `json:"processed_at,omitempty"`
+	Metadata    map[string]any `json:"extra_metadata,omitempty"`
+}
+
+// Processed reports whether the demo's datasets are available.
+func (s ProcessingStatus) Processed() bool { return s.Status == StatusProcessed }
+
+// DemoFile is a downloadable dataset of a processed demo, e.g. "summary" or
+// "kills". SchemaVersion is nil for files written before versioning.
+type DemoFile struct {
+	Name          string `json:"name"`
+	Filename      string `json:"filename"`
+	SizeBytes     *int64 `json:"size_bytes,omitempty"`
+	DownloadURL   string `json:"download_url"`
+	SchemaVersion *int   `json:"schema_version,omitempty"`
+}
+
+type demoFiles struct {
+	DemoID string     `json:"demo_id"`
+	Files  []DemoFile `json:"files"`
+}
+
+// AnalysisResult is the outcome of RunAnalysis.
+type AnalysisResult struct {
+	DemoID       string          `json:"demo_id"`
+	AnalysisType string          `json:"analysis_type"`
+	Status       string          `json:"status"`
+	Results      json.RawMessage `json:"results"`
+	Message      string          `json:"message"`
+	GeneratedAt  time.Time       `json:"generated_at"`
+}
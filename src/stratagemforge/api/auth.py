@@ -0,0 +1,302 @@
+from __future__ import annotations
+
+import json
+import re
+from dataclasses import dataclass, field
+from typing import Any, Awaitable, Callable, Dict, List, Optional, Tuple
+
+from fastapi import Depends, HTTPException, Request, status
+from starlette.concurrency import run_in_threadpool
+
+from ..core.config import Settings
+from ..core.database import get_session_factory
+from ..core.i18n import get_translator
+from ..core.limits import Message, RateLimiter, RateLimitExceededError, Receive, Scope, Send
+from ..core.tokens import JwtValidator
+from ..domain.users.roles import ROLE_SCOPES, role_at_least
+from . import deps
+
+API_KEY_HEADER = b"x-api-key"
+# Health checks, API docs and the routes that carry their own credentials stay reachable without a key.
+EXEMPT_PATHS = frozenset(
+    {
+        "/",
+        "/health",
+        "/ready",
+        "/config",
+        "/docs",
+        "/docs/oauth2-redirect",
+        "/redoc",
+        "/openapi.json",
+        "/api/auth/login",
+        "/api/auth/refresh",
+        "/api/auth/logout",
+        "/api/auth/password-reset",
+        "/api/auth/password-reset/confirm",
+        "/api/auth/steam/login",
+        "/api/auth/steam/callback",
+        "/.well-known/jwks.json",
+        "/.well-known/openid-configuration",
+        "/oauth2/authorize",
+        "/oauth2/token",
+        "/api/schedule/calendar.ics",
+    }
+)
+EXEMPT_PREFIXES = ("/public/",)
+# Sign-up works without credentials even with AUTH_REQUIRED on; credentials that are sent are
+# still checked so an admin can create accounts while registration is closed.
+ANONYMOUS_ROUTES = frozenset({("POST", "/api/users")})
+# Account self-service: a ``read`` credential may change its own user (routes refuse anyone else's).
+SELF_SERVICE_PATH = re.compile(r"^/api/users/[^/]+(/password|/preferences|/steam)?$")
+# Team management: the team service checks the caller's team role instead of a scope.
+TEAM_PATH = re.compile(r"^/api/teams(/.*)?$")
+# The caller's own API keys; new keys never get a scope the caller lacks.
+API_KEYS_PATH = re.compile(r"^/api/apikeys(/.*)?$")
+# Operator routes; reading them (exports, alert channel targets, invites) needs ``admin`` too.
+ADMIN_PATH = re.compile(r"^/api/admin(/.*)?$")
+# Any valid credential reaches these; OIDC access tokens carry no platform scope.
+SCOPELESS_PATHS = frozenset({"/oauth2/userinfo"})
+READ_METHODS = frozenset({"GET", "HEAD", "OPTIONS"})
+UPLOAD_PATHS = frozenset(
+    {
+        "/api/demos/upload",
+        "/api/demos/upload/batch",
+        "/api/demos/upload/archive",
+        "/api/demos/ingest/url",
+        "/api/demos/ingest/sharecode",
+        "/api/demos/live",
+        "/api/activity",  # clients recording strategies, comments and reviews
+    }
+)
+# Trashing, restoring and sharing a demo; the routes check the caller uploaded it or coaches its team.
+DEMO_PATH = re.compile(r"^/api/demos/[^/]+$")
+RESTORE_PATH = re.compile(r"^/api/demos/(matches/)?[^/]+/restore$")
+VISIBILITY_PATH = re.compile(r"^/api/demos/[^/]+/visibility$")
+BROADCAST_STOP_PATH = re.compile(r"^/api/demos/live/[^/]+/stop$")
+SCOPES = ("read", "upload", "admin")
+# Scopes of a bearer token without a ``scope`` claim or a known ``role``.
+DEFAULT_USER_SCOPES = ("read", "upload")
+
+
+@dataclass(frozen=True)
+class Identity:
+    """Who is calling: the user behind an API key or bearer token."""
+
+    user_id: str
+    scopes: Tuple[str, ...]
+    credential: str  # "api_key" or "jwt"
+    credential_id: str  # key id, or the token's subject so a user's tokens share one rate limit
+    team_id: Optional[str] = None
+    role: Optional[str] = None  # platform role: player, analyst, coach or admin
+    steamid: Optional[str] = None  # from the ``steamid`` claim of tokens issued after Steam sign-in or linking
+    claims: Dict[str, Any] = field(default_factory=dict, compare=False)
+
+    def allows(self, scope: str) -> bool:
+        return scope in self.scopes or "admin" in self.scopes
+
+
+def required_scope(method: str, path: str) -> str:
+    """``read`` for safe methods, ``upload`` for ingestion and ``admin`` for every other change.
+
+    Everything under ``/api/admin`` needs ``admin``, reads included.
+    """
+
+    if ADMIN_PATH.match(path):
+        return "admin"
+    if method in READ_METHODS:
+        return "read"
+    if method == "POST" and path in UPLOAD_PATHS:
+        return "upload"
+    if (method == "DELETE" and DEMO_PATH.match(path)) or (method == "POST" and RESTORE_PATH.match(path)):
+        return "upload"
+    if (method == "PUT" and VISIBILITY_PATH.match(path)) or (method == "POST" and BROADCAST_STOP_PATH.match(path)):
+        return "upload"
+    if SELF_SERVICE_PATH.match(path) or TEAM_PATH.match(path) or API_KEYS_PATH.match(path):
+        return "read"
+    return "admin"
+
+
+def is_exempt(path: str) -> bool:
+    return path in EXEMPT_PATHS or path.startswith(EXEMPT_PREFIXES)
+
+
+def identity_from_claims(claims: Dict[str, Any], team_claim: str = "team_id") -> Identity:
+    requested = str(claims.get("scope") or "").split()
+    role = claims.get("role")
+    if requested:
+        scopes = [scope for scope in requested if scope in SCOPES]
+    else:
+        scopes = list(ROLE_SCOPES.get(role, DEFAULT_USER_SCOPES))
+    if role == "admin" and "admin" not in scopes:
+        scopes.append("admin")
+    return Identity(
+        user_id=str(claims["sub"]),
+        scopes=tuple(scopes),
+        credential="jwt",
+        credential_id=str(claims["sub"]),
+        team_id=claims.get(team_claim),
+        role=role,
+        steamid=claims.get("steamid"),
+        claims=claims,
+    )
+
+
+def get_identity(request: Request) -> Optional[Identity]:
+    """Route dependency: the authenticated caller, or ``None`` for anonymous requests."""
+
+    return getattr(request.state, "identity", None)
+
+
+def require_caller(identity: Optional[Identity] = Depends(get_identity)) -> Identity:
+    """Route dependency for routes about the caller's own things (teams, API keys).
+
+    They need credentials even when ``auth_required`` is off.
+    """
+
+    if identity is None:
+        raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail="Authentication required")
+    return identity
+
+
+def require_role(minimum: str) -> Callable[..., Optional[Identity]]:
+    """Route dependency that lets through callers whose platform role ranks at least ``minimum``.
+
+    ``admin`` credentials always pass. Anonymous callers get 401 when
+    ``auth_required`` is on and pass otherwise, like every other route.
+    """
+
+    def check(identity: Optional[Identity] = Depends(get_identity)) -> Optional[Identity]:
+        if identity is None:
+            if deps.get_active_settings().auth_required:
+                raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail="Authentication required")
+            return None
+        if not identity.allows("admin") and not role_at_least(identity.role, minimum):
+            raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=f"Requires the {minimum} role")
+        return identity
+
+    return check
+
+
+class AuthMiddleware:
+    """Authenticate callers by ``X-API-Key`` or ``Authorization: Bearer <JWT>``.
+
+    API keys are checked against their stored hash; bearer tokens are
+    validated against the user service's JWKS, or this deployment's own
+    signing key (signature, issuer, audience, expiry), and must not have
+    been revoked at logout. The caller must hold a scope that covers the request (``admin``
+    covers everything) and have requests left in the current minute; the
+    ``X-RateLimit-*`` headers report where it stands. Routes read the caller
+    from ``request.state.identity``. With ``auth_required`` off, anonymous
+    requests pass, but presented credentials are still checked so uploads
+    can be attributed to their owner.
+    """
+
+    def __init__(
+        self,
+        app: Callable[..., Awaitable[None]],
+        settings: Settings,
+        rate_limiter: Optional[RateLimiter] = None,
+        jwt_validator: Optional[JwtValidator] = None,
+    ) -> None:
+        self.app = app
+        self.settings = settings
+        self.rate_limiter = rate_limiter or RateLimiter()
+        self._jwt_validator = jwt_validator
+        self.translator = get_translator(settings.default_locale)
+
+    async def __call__(self, scope: Scope, receive: Receive, send: Send) -> None:
+        if scope["type"] != "http" or is_exempt(scope["path"]):
+            await self.app(scope, receive, send)
+            return
+
+        headers = dict(scope.get("headers") or [])
+        locale = self.translator.negotiate((headers.get(b"accept-language") or b"").decode("latin-1") or None)
+        key = headers.get(API_KEY_HEADER, b"").decode("latin-1") or None
+        scheme, _, bearer = headers.get(b"authorization", b"").decode("latin-1").partition(" ")
+        bearer = bearer.strip() if scheme.lower() == "bearer" else ""
+        if not key and not bearer:
+            if self.settings.auth_required and (scope["method"], scope["path"]) not in ANONYMOUS_ROUTES:
+                await self._reject(send, 401, "Authentication required", locale, {"WWW-Authenticate": "Bearer"})
+                return
+            await self.app(scope, receive, send)
+            return
+
+        try:
+            if bearer:
+                identity, limit = await run_in_threadpool(self._authenticate_bearer, bearer)
+            else:
+                identity, limit = await run_in_threadpool(self._authenticate_key, key)
+        except PermissionError as exc:
+            await self._reject(send, 401, str(exc), locale, {"WWW-Authenticate": "Bearer"})
+            return
+
+        needed = required_scope(scope["method"], scope["path"])
+        if scope["path"] not in SCOPELESS_PATHS and not identity.allows(needed):
+            await self._reject(send, 403, f"Credentials lack the {needed} scope", locale)
+            return
+        try:
+            quota = self.rate_limiter.hit(f"{identity.credential}:{identity.credential_id}", limit)
+        except RateLimitExceededError as exc:
+            headers = {**exc.quota.headers(), "Retry-After": str(exc.quota.reset_seconds)}
+            await self._reject(send, 429, str(exc), locale, headers)
+            return
+
+        scope.setdefault("state", {})["identity"] = identity
+
+        async def send_with_quota(message: Message) -> None:
+            if message["type"] == "http.response.start":
+                message["headers"] = list(message.get("headers") or []) + _encode(quota.headers())
+            await send(message)
+
+        await self.app(scope, receive, send_with_quota)
+
+    @property
+    def jwt_validator(self) -> JwtValidator:
+        return self._jwt_validator or deps.get_user_service().token_validator
+
+    def _authenticate_bearer(self, token: str) -> Tuple[Identity, int]:
+        # Validation may fetch the JWKS, so it runs off the event loop like the key lookup.
+        claims = self.jwt_validator.validate(token)
+        if claims.get("jti") and claims.get("iss") == self.settings.auth_token_issuer:
+            session = get_session_factory()()
+            try:
+                if deps.get_user_service().is_access_token_revoked(session, claims["jti"]):
+                    raise PermissionError("Bearer token has been revoked")
+            finally:
+                session.close()
+        return identity_from_claims(claims, self.settings.jwt_team_claim), self.settings.api_key_rate_limit_per_minute
+
+    def _authenticate_key(self, key: str) -> Tuple[Identity, int]:
+        service = deps.get_user_service()
+        session = get_session_factory()()
+        try:
+            api_key = service.authenticate_api_key(session, key)
+            user = service.get_user(session, api_key.user_id)
+            identity = Identity(
+                user_id=api_key.user_id,
+                scopes=tuple(api_key.scopes or ()),
+                credential="api_key",
+                credential_id=api_key.id,
+                role=user.role,
+                steamid=user.steamid,
+            )
+            return identity, service.api_key_limit(api_key)
+        finally:
+            session.close()
+
+    async def _reject(
+        self, send: Send, status_code: int, detail: str, locale: str, extra: Optional[Dict[str, str]] = None
+    ) -> None:
+        body = json.dumps({"detail": self.translator.translate(detail, locale)}).encode()
+        headers = {
+            "content-type": "application/json",
+            "content-length": str(len(body)),
+            "content-language": locale,
+            **(extra or {}),
+        }
+        await send({"type": "http.response.start", "status": status_code, "headers": _encode(headers)})
+        await send({"type": "http.response.body", "body": body})
+
+
+def _encode(headers: Dict[str, str]) -> List[Tuple[bytes, bytes]]:
+    return [(name.lower().encode("latin-1"), value.encode("latin-1")) for name, value in headers.items()]
@@ -1,27 +1,128 @@
 from __future__ import annotations
 
-from fastapi import Depends
+from typing import Callable, Optional
+
+from fastapi import Depends, Header, HTTPException, Request, status
 from sqlalchemy.orm import Session
 
+from ..core import metrics
 from ..core.config import Settings, get_settings
 from ..core.database import get_session, init_engine
+from ..core.i18n import Translator, get_translator
+from ..domain.activity.service import ActivityService
+from ..domain.admin.integrity import IntegrityAuditService
+from ..domain.admin.tenant_export import TenantExportService
+from ..domain.admin.throughput import ThroughputService
 from ..domain.analysis.service import AnalysisService
+from ..domain.competitions.service import CompetitionService
+from ..domain.dashboard.service import DashboardService
+from ..domain.demos.broadcast import BroadcastService
+from ..domain.demos.correlation import MatchCorrelator
+from ..domain.demos.lake import LakeWriter
+from ..domain.demos.replication import ArtifactReplicator
 from ..domain.demos.service import DemoService
+from ..domain.demos.webhooks import WebhookNotifier
+from ..domain.events.service import OutboxService
+from ..domain.exports.service import SheetsExportService
+from ..domain.faceit.service import FaceitService
+from ..domain.flags.service import FeatureFlagService
+from ..domain.notifications.alerts import AlertService
+from ..domain.notifications.service import NotificationService
+from ..domain.onboarding.service import OnboardingService
+from ..domain.teams.service import TeamService
+from ..domain.public.service import PublicStatsService
+from ..domain.query.service import QueryService
+from ..domain.schedule.service import ScheduleService
+from ..domain.stats.service import StatsService
+from ..domain.usage.service import UsageService
+from ..domain.users.oidc import OidcProvider
 from ..domain.users.service import UserService
 
 _demo_service: DemoService | None = None
+_broadcast_service: BroadcastService | None = None
+_faceit_service: FaceitService | None = None
 _analysis_service: AnalysisService | None = None
 _user_service: UserService | None = None
+_oidc_provider: OidcProvider | None = None
+_competition_service: CompetitionService | None = None
+_stats_service: StatsService | None = None
+_sheets_export_service: SheetsExportService | None = None
+_schedule_service: ScheduleService | None = None
+_dashboard_service: DashboardService | None = None
+_onboarding_service: OnboardingService | None = None
+_team_service: TeamService | None = None
+_activity_service: ActivityService | None = None
+_notification_service: NotificationService | None = None
+_alert_service: AlertService | None = None
+_feature_flag_service: FeatureFlagService | None = None
+_usage_service: UsageService | None = None
+_integrity_audit_service: IntegrityAuditService | None = None
+_throughput_service: ThroughputService | None = None
+_tenant_export_service: TenantExportService | None = None
+_outbox_service: OutboxService | None = None
+_public_stats_service: PublicStatsService | None = None
+_query_service: QueryService | None = None
 _current_settings: Settings | None = None
 
 
 def configure(settings: Settings | None = None) -> None:
-    global _demo_service, _analysis_service, _user_service, _current_settings
+    global _demo_service, _analysis_service, _user_service, _competition_service, _stats_service
+    global _sheets_export_service, _schedule_service, _dashboard_service, _onboarding_service, _notification_service
+    global _feature_flag_service, _usage_service, _integrity_audit_service, _tenant_export_service, _outbox_service
+    global _public_stats_service, _alert_service, _throughput_service, _team_service, _current_settings
+    global _broadcast_service, _faceit_service, _oidc_provider, _activity_service, _query_service
     _current_settings = settings or get_settings()
     init_engine(_current_settings)
-    _demo_service = DemoService(_current_settings)
+    _feature_flag_service = FeatureFlagService(_current_settings)
+    _demo_service = DemoService(_current_settings, feature_flags=_feature_flag_service)
+    metrics.track_parse_queue(_demo_service.parse_limiter.snapshot)
+    _broadcast_service = BroadcastService(_current_settings, processor=_demo_service.processor)
+    _faceit_service = FaceitService(_current_settings)
+    correlator = MatchCorrelator(_current_settings)
+    _demo_service.post_process_hooks.append(correlator.correlate_after_ingest)
+    _demo_service.delete_hooks.append(correlator.release_before_delete)
+    replicator = ArtifactReplicator(_current_settings, storage=_demo_service.storage)
+    if replicator.enabled:
+        _demo_service.post_process_hooks.append(replicator.replicate_after_ingest)
+        _demo_service.delete_hooks.append(replicator.delete_replicas)
+    lake = LakeWriter(_current_settings, storage=_demo_service.storage)
+    if lake.enabled:
+        _demo_service.post_process_hooks.append(lake.write_after_ingest)
+        _demo_service.delete_hooks.append(lake.delete_partitions)
     _analysis_service = AnalysisService(_current_settings)
+    _demo_service.post_process_hooks.append(_analysis_service.invalidate_after_change)
+    _demo_service.delete_hooks.append(_analysis_service.invalidate_after_change)
     _user_service = UserService(_current_settings)
+    if _current_settings.smtp_host:
+        _user_service.password_reset_hooks.append(_user_service.email_reset_link)
+    _oidc_provider = OidcProvider(_current_settings, _user_service)
+    _competition_service = CompetitionService(_current_settings)
+    _stats_service = StatsService(_current_settings)
+    _sheets_export_service = SheetsExportService(_current_settings)
+    _demo_service.post_process_hooks.append(_sheets_export_service.export_after_ingest)
+    _schedule_service = ScheduleService(_current_settings)
+    _dashboard_service = DashboardService(_current_settings)
+    _demo_service.post_process_hooks.append(_dashboard_service.invalidate_after_ingest)
+    _public_stats_service = PublicStatsService(_current_settings)
+    _demo_service.post_process_hooks.append(_public_stats_service.invalidate_after_ingest)
+    _onboarding_service = OnboardingService(_current_settings)
+    _team_service = TeamService(_current_settings)
+    _activity_service = ActivityService(_current_settings, _team_service)
+    _demo_service.post_process_hooks.append(_activity_service.record_upload_after_ingest)
+    _notification_service = NotificationService(_current_settings)
+    _demo_service.post_process_hooks.append(_notification_service.notify_after_ingest)
+    _alert_service = AlertService(_current_settings, _notification_service)
+    _demo_service.post_process_hooks.append(_alert_service.evaluate_after_ingest)
+    _demo_service.post_process_hooks.append(WebhookNotifier(_current_settings).notify_after_ingest)
+    _outbox_service = OutboxService(_current_settings)
+    if _outbox_service.enabled:
+        _demo_service.record_hooks.append(_outbox_service.record_match_processed)
+        _demo_service.post_process_hooks.append(_outbox_service.relay_after_ingest)
+    _usage_service = UsageService(_current_settings)
+    _integrity_audit_service = IntegrityAuditService(_current_settings)
+    _throughput_service = ThroughputService(_current_settings)
+    _tenant_export_service = TenantExportService(_current_settings, storage=_demo_service.storage)
+    _query_service = QueryService(_current_settings, storage=_demo_service.storage)
 
 
 def _ensure_configured() -> Settings:
@@ -42,6 +143,20 @@ def get_demo_service() -> DemoService:
     return _demo_service
 
 
+def get_broadcast_service() -> BroadcastService:
+    if _broadcast_service is None:
+        configure()
+    assert _broadcast_service is not None
+    return _broadcast_service
+
+
+def get_faceit_service() -> FaceitService:
+    if _faceit_service is None:
+        configure()
+    assert _faceit_service is not None
+    return _faceit_service
+
+
 def get_analysis_service() -> AnalysisService:
     if _analysis_service is None:
         configure()
@@ -56,5 +171,165 @@ def get_user_service() -> UserService:
     return _user_service
 
 
+def get_oidc_provider() -> OidcProvider:
+    if _oidc_provider is None:
+        configure()
+    assert _oidc_provider is not None
+    return _oidc_provider
+
+
+def get_competition_service() -> CompetitionService:
+    if _competition_service is None:
+        configure()
+    assert _competition_service is not None
+    return _competition_service
+
+
+def get_stats_service() -> StatsService:
+    if _stats_service is None:
+        configure()
+    assert _stats_service is not None
+    return _stats_service
+
+
+def get_sheets_export_service() -> SheetsExportService:
+    if _sheets_export_service is None:
+        configure()
+    assert _sheets_export_service is not None
+    return _sheets_export_service
+
+
+def get_schedule_service() -> ScheduleService:
+    if _schedule_service is None:
+        configure()
+    assert _schedule_service is not None
+    return _schedule_service
+
+
+def get_dashboard_service() -> DashboardService:
+    if _dashboard_service is None:
+        configure()
+    assert _dashboard_service is not None
+    return _dashboard_service
+
+
+def get_public_stats_service() -> PublicStatsService:
+    if _public_stats_service is None:
+        configure()
+    assert _public_stats_service is not None
+    return _public_stats_service
+
+
+def get_onboarding_service() -> OnboardingService:
+    if _onboarding_service is None:
+        configure()
+    assert _onboarding_service is not None
+    return _onboarding_service
+
+
+def get_team_service() -> TeamService:
+    if _team_service is None:
+        configure()
+    assert _team_service is not None
+    return _team_service
+
+
+def get_activity_service() -> ActivityService:
+    if _activity_service is None:
+        configure()
+    assert _activity_service is not None
+    return _activity_service
+
+
+def get_notification_service() -> NotificationService:
+    if _notification_service is None:
+        configure()
+    assert _notification_service is not None
+    return _notification_service
+
+
+def get_alert_service() -> AlertService:
+    if _alert_service is None:
+        configure()
+    assert _alert_service is not None
+    return _alert_service
+
+
+def get_feature_flag_service() -> FeatureFlagService:
+    if _feature_flag_service is None:
+        configure()
+    assert _feature_flag_service is not None
+    return _feature_flag_service
+
+
+def get_usage_service() -> UsageService:
+    if _usage_service is None:
+        configure()
+    assert _usage_service is not None
+    return _usage_service
+
+
+def get_integrity_audit_service() -> IntegrityAuditService:
+    if _integrity_audit_service is None:
+        configure()
+    assert _integrity_audit_service is not None
+    return _integrity_audit_service
+
+
+def get_throughput_service() -> ThroughputService:
+    if _throughput_service is None:
+        configure()
+    assert _throughput_service is not None
+    return _throughput_service
+
+
+def get_outbox_service() -> OutboxService:
+    if _outbox_service is None:
+        configure()
+    assert _outbox_service is not None
+    return _outbox_service
+
+
+def get_tenant_export_service() -> TenantExportService:
+    if _tenant_export_service is None:
+        configure()
+    assert _tenant_export_service is not None
+    return _tenant_export_service
+
+
+def get_query_service() -> QueryService:
+    if _query_service is None:
+        configure()
+    assert _query_service is not None
+    return _query_service
+
+
+def get_translator_for_request() -> Translator:
+    return get_translator(_ensure_configured().default_locale)
+
+
+def get_locale(request: Request, translator: Translator = Depends(get_translator_for_request)) -> str:
+    """Locale negotiated from the request's ``Accept-Language`` header."""
+
+    return translator.negotiate(request.headers.get("accept-language"))
+
+
+def require_feature(key: str) -> Callable[..., None]:
+    """Route dependency that hides an endpoint (404) unless ``key`` is on for the caller's team.
+
+    The team comes from the ``X-Team-Id`` header; without it the global value applies.
+    """
+
+    def check(
+        team_id: Optional[str] = Header(default=None, alias="X-Team-Id"),
+        session: Session = Depends(get_db),
+        flags: FeatureFlagService = Depends(get_feature_flag_service),
+    ) -> None:
+        if not flags.is_enabled(session, key, team_id=team_id):
+            raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Not Found")
+
+    return check
+
+
 def get_active_settings() -> Settings:
     return _ensure_configured()
@@ -0,0 +1,140 @@
+from __future__ import annotations
+
+from typing import List, Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, status
+from sqlalchemy.orm import Session
+
+from ...core.ids import next_cursor
+from ...domain.activity.schemas import ActivityCollection, ActivityCreate, ActivitySummary
+from .. import deps
+from ..auth import Identity, require_caller
+
+router = APIRouter(prefix="/api/activity", tags=["activity"])
+
+
+@router.get("", response_model=ActivityCollection)
+def home_feed(
+    verb: List[str] = Query(default=[], description="Only these verbs, e.g. demo.uploaded (repeatable)"),
+    page: int = Query(default=1, ge=1),
+    page_size: int = Query(default=20, ge=1, le=100),
+    cursor: Optional[str] = Query(default=None, description="next_cursor of the previous page; replaces page"),
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_activity_service),
+) -> ActivityCollection:
+    """The caller's own activity and that of their teams, newest first."""
+
+    try:
+        entries, total = service.feed(
+            session,
+            identity.user_id,
+            admin=identity.allows("admin"),
+            verbs=verb,
+            page=page,
+            page_size=page_size,
+            cursor=cursor,
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return _collection(entries, total, page, page_size)
+
+
+@router.get("/teams/{team_id}", response_model=ActivityCollection)
+def team_feed(
+    team_id: str,
+    verb: List[str] = Query(default=[]),
+    page: int = Query(default=1, ge=1),
+    page_size: int = Query(default=20, ge=1, le=100),
+    cursor: Optional[str] = Query(default=None, description="next_cursor of the previous page; replaces page"),
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_activity_service),
+) -> ActivityCollection:
+    """A team's activity for its home page; private entries only show to their actor."""
+
+    try:
+        entries, total = service.feed(
+            session,
+            identity.user_id,
+            admin=identity.allows("admin"),
+            team_id=team_id,
+            verbs=verb,
+            page=page,
+            page_size=page_size,
+            cursor=cursor,
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return _collection(entries, total, page, page_size)
+
+
+@router.get("/users/{user_id}", response_model=ActivityCollection)
+def user_feed(
+    user_id: str,
+    verb: List[str] = Query(default=[]),
+    page: int = Query(default=1, ge=1),
+    page_size: int = Query(default=20, ge=1, le=100),
+    cursor: Optional[str] = Query(default=None, description="next_cursor of the previous page; replaces page"),
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_activity_service),
+) -> ActivityCollection:
+    """What a user did, limited to the teams the caller shares with them unless it is the caller."""
+
+    try:
+        entries, total = service.feed(
+            session,
+            identity.user_id,
+            admin=identity.allows("admin"),
+            actor_id=user_id,
+            verbs=verb,
+            page=page,
+            page_size=page_size,
+            cursor=cursor,
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return _collection(entries, total, page, page_size)
+
+
+@router.post("", response_model=ActivitySummary, status_code=status.HTTP_201_CREATED)
+def record_activity(
+    payload: ActivityCreate,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_activity_service),
+) -> ActivitySummary:
+    """Record something the caller did in a client, e.g. commenting on round 14 of a demo."""
+
+    try:
+        entry = service.record_client_activity(
+            session,
+            identity.user_id,
+            payload.verb,
+            payload.subject_type,
+            payload.subject_id,
+            team_id=payload.team_id,
+            details=payload.details,
+            admin=identity.allows("admin"),
+        )
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return ActivitySummary.from_orm(entry)
+
+
+def _collection(entries, total: int, page: int, page_size: int) -> ActivityCollection:
+    return ActivityCollection(
+        entries=[ActivitySummary.from_orm(entry) for entry in entries],
+        count=len(entries),
+        total=total,
+        page=page,
+        page_size=page_size,
+        next_cursor=next_cursor([entry.id for entry in entries], page_size),
+    )
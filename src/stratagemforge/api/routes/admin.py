@@ -0,0 +1,363 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, status
+from fastapi.responses import FileResponse
+from sqlalchemy.orm import Session
+
+from ...domain.admin.schemas import (
+    IntegrityAuditSummary,
+    StorageRegionSummary,
+    TeamStorageRegion,
+    TeamStorageRegionUpdate,
+    TenantExportRequest,
+    TenantExportSummary,
+    ThroughputReport,
+)
+from ...domain.admin.throughput import parse_window
+from ...domain.events.schemas import OutboxRelayResult, OutboxSummary
+from ...domain.notifications.schemas import AlertEvaluation, AlertRuleCreate, AlertRuleSummary
+from ...domain.public.schemas import EmbedTokenRequest, EmbedTokenResponse, PublicApiKeyRequest, PublicApiKeyResponse
+from ...domain.users.schemas import (
+    OidcClientCreate,
+    OidcClientCreated,
+    OidcClientSummary,
+    RegistrationInviteCreate,
+    RegistrationInviteCreated,
+    RegistrationInviteSummary,
+    RegistrationPolicySummary,
+    RegistrationPolicyUpdate,
+)
+from .. import deps
+from ..auth import Identity, get_identity
+
+router = APIRouter(prefix="/api/admin", tags=["admin"])
+
+
+@router.post("/integrity/audits", response_model=IntegrityAuditSummary, status_code=status.HTTP_201_CREATED)
+def run_integrity_audit(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_integrity_audit_service),
+) -> IntegrityAuditSummary:
+    return IntegrityAuditSummary.from_orm(service.run(session))
+
+
+@router.get("/integrity/audits/latest", response_model=IntegrityAuditSummary)
+def latest_integrity_audit(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_integrity_audit_service),
+) -> IntegrityAuditSummary:
+    run = service.latest(session)
+    if not run:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="No integrity audit has run yet")
+    return IntegrityAuditSummary.from_orm(run)
+
+
+@router.get("/throughput", response_model=ThroughputReport)
+def throughput(
+    window: str = Query(default="7d", description="How far back to look, e.g. 24h, 7d or 4w"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_throughput_service),
+) -> ThroughputReport:
+    """Jobs processed, failure rate, p50/p95 parse times and data volume from the parse job history."""
+
+    try:
+        span = parse_window(window)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return service.report(session, span)
+
+
+@router.get("/alert-rules", response_model=list[AlertRuleSummary])
+def list_alert_rules(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_alert_service),
+) -> list[AlertRuleSummary]:
+    return [AlertRuleSummary.from_orm(rule) for rule in service.list_rules(session)]
+
+
+@router.post("/alert-rules", response_model=AlertRuleSummary, status_code=status.HTTP_201_CREATED)
+def create_alert_rule(
+    request: AlertRuleCreate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_alert_service),
+) -> AlertRuleSummary:
+    return AlertRuleSummary.from_orm(service.create_rule(session, request))
+
+
+@router.put("/alert-rules/{rule_id}", response_model=AlertRuleSummary)
+def update_alert_rule(
+    rule_id: str,
+    request: AlertRuleCreate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_alert_service),
+) -> AlertRuleSummary:
+    try:
+        return AlertRuleSummary.from_orm(service.update_rule(session, rule_id, request))
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.delete("/alert-rules/{rule_id}", status_code=status.HTTP_204_NO_CONTENT)
+def delete_alert_rule(
+    rule_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_alert_service),
+) -> None:
+    try:
+        service.delete_rule(session, rule_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.post("/alert-rules/evaluate", response_model=list[AlertEvaluation])
+def evaluate_alert_rules(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_alert_service),
+) -> list[AlertEvaluation]:
+    """Evaluate every enabled rule now and notify as the schedule would."""
+
+    return service.evaluate(session)
+
+
+@router.post("/public-keys", response_model=PublicApiKeyResponse, status_code=status.HTTP_201_CREATED)
+def issue_public_key(
+    request: PublicApiKeyRequest,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_public_stats_service),
+) -> PublicApiKeyResponse:
+    try:
+        api_key, key = service.issue_key(session, request.team_id, request.label, request.rate_limit_per_minute)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return PublicApiKeyResponse(
+        id=api_key.id,
+        team_id=api_key.team_id,
+        label=api_key.label,
+        key=key,
+        rate_limit_per_minute=service.limit_for(api_key),
+        created_at=api_key.created_at,
+    )
+
+
+@router.delete("/public-keys/{key_id}", status_code=status.HTTP_204_NO_CONTENT)
+def revoke_public_key(
+    key_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_public_stats_service),
+) -> None:
+    try:
+        service.revoke_key(session, key_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/oidc-clients", response_model=list[OidcClientSummary])
+def list_oidc_clients(
+    session: Session = Depends(deps.get_db),
+    provider=Depends(deps.get_oidc_provider),
+) -> list[OidcClientSummary]:
+    return [OidcClientSummary.from_orm(client) for client in provider.list_clients(session)]
+
+
+@router.post("/oidc-clients", response_model=OidcClientCreated, status_code=status.HTTP_201_CREATED)
+def register_oidc_client(
+    request: OidcClientCreate,
+    session: Session = Depends(deps.get_db),
+    provider=Depends(deps.get_oidc_provider),
+) -> OidcClientCreated:
+    """Register an application that signs users in through ``/oauth2/authorize``."""
+
+    try:
+        client, secret = provider.create_client(session, request.name, request.redirect_uris, request.confidential)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return OidcClientCreated(**OidcClientSummary.from_orm(client).dict(), client_secret=secret)
+
+
+@router.delete("/oidc-clients/{client_id}", status_code=status.HTTP_204_NO_CONTENT)
+def revoke_oidc_client(
+    client_id: str,
+    session: Session = Depends(deps.get_db),
+    provider=Depends(deps.get_oidc_provider),
+) -> None:
+    """Stop accepting the client; tokens it already obtained stay valid until they expire."""
+
+    try:
+        provider.revoke_client(session, client_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/registration", response_model=RegistrationPolicySummary)
+def registration_policy(
+    session: Session = Depends(deps.get_db),
+    users=Depends(deps.get_user_service),
+) -> RegistrationPolicySummary:
+    return RegistrationPolicySummary.from_orm(users.registration_policy(session))
+
+
+@router.put("/registration", response_model=RegistrationPolicySummary)
+def set_registration_policy(
+    request: RegistrationPolicyUpdate,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    users=Depends(deps.get_user_service),
+) -> RegistrationPolicySummary:
+    """Choose who may sign up at ``POST /api/users``; admins can always create accounts."""
+
+    try:
+        policy = users.set_registration_policy(
+            session, request.mode, request.allowed_domains, actor_id=identity.user_id if identity else None
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return RegistrationPolicySummary.from_orm(policy)
+
+
+@router.get("/registration/invites", response_model=list[RegistrationInviteSummary])
+def list_registration_invites(
+    include_used: bool = Query(default=False, description="Also list used and expired invites"),
+    session: Session = Depends(deps.get_db),
+    users=Depends(deps.get_user_service),
+) -> list[RegistrationInviteSummary]:
+    invites = users.list_registration_invites(session, include_used=include_used)
+    return [RegistrationInviteSummary.from_orm(invite) for invite in invites]
+
+
+@router.post("/registration/invites", response_model=RegistrationInviteCreated, status_code=status.HTTP_201_CREATED)
+def create_registration_invite(
+    request: RegistrationInviteCreate,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    users=Depends(deps.get_user_service),
+) -> RegistrationInviteCreated:
+    """Issue a single-use sign-up invite with the role the new account gets; the token is shown once."""
+
+    try:
+        invite, token = users.create_registration_invite(
+            session,
+            role=request.role,
+            email=request.email,
+            expires_in_days=request.expires_in_days,
+            actor_id=identity.user_id if identity else None,
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return RegistrationInviteCreated(**RegistrationInviteSummary.from_orm(invite).dict(), token=token)
+
+
+@router.delete("/registration/invites/{invite_id}", status_code=status.HTTP_204_NO_CONTENT)
+def revoke_registration_invite(
+    invite_id: str,
+    session: Session = Depends(deps.get_db),
+    users=Depends(deps.get_user_service),
+) -> None:
+    try:
+        users.revoke_registration_invite(session, invite_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.post("/embed-tokens", response_model=EmbedTokenResponse, status_code=status.HTTP_201_CREATED)
+def issue_embed_token(
+    request: EmbedTokenRequest,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_public_stats_service),
+) -> EmbedTokenResponse:
+    try:
+        token, subject, expires_at = service.issue_embed_token(
+            session, request.kind, request.subject, request.expires_in_days
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return EmbedTokenResponse(
+        kind=request.kind,
+        subject=subject,
+        token=token,
+        card_url=f"/public/v1/widgets/{request.kind}?token={token}",
+        expires_at=expires_at,
+    )
+
+
+@router.get("/events/outbox", response_model=OutboxSummary)
+def outbox_summary(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_outbox_service),
+) -> OutboxSummary:
+    return service.summary(session)
+
+
+@router.post("/events/outbox/relay", response_model=OutboxRelayResult)
+def relay_outbox(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_outbox_service),
+) -> OutboxRelayResult:
+    """Publish pending events now instead of waiting for the next poll."""
+
+    if not service.enabled:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail="No event broker is configured")
+    return service.relay(session)
+
+
+@router.get("/storage-regions", response_model=list[StorageRegionSummary])
+def list_storage_regions(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> list[StorageRegionSummary]:
+    return [StorageRegionSummary(**region) for region in service.list_storage_regions(session)]
+
+
+@router.put("/teams/{team_id}/storage-region", response_model=TeamStorageRegion)
+def set_team_storage_region(
+    team_id: str,
+    payload: TeamStorageRegionUpdate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> TeamStorageRegion:
+    """Pin where a team's uploads are stored from now on; demos stored before are not moved."""
+
+    try:
+        team = service.set_storage_region(session, team_id, payload.region)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return TeamStorageRegion(team_id=team.id, region=team.storage_region)
+
+
+@router.post("/teams/{team_id}/export", response_model=TenantExportSummary, status_code=status.HTTP_201_CREATED)
+def export_team(
+    team_id: str,
+    request: TenantExportRequest,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_tenant_export_service),
+) -> TenantExportSummary:
+    try:
+        result = service.export_team(
+            session, team_id, include_raw=request.include_raw, bucket=request.bucket, prefix=request.prefix
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+    return TenantExportSummary(
+        team_id=result.team_id,
+        filename=result.path.name,
+        demos=result.demos,
+        files=result.files,
+        size_bytes=result.size_bytes,
+        download_url=f"/api/admin/exports/teams/{result.path.name}",
+        location=result.location,
+    )
+
+
+@router.get("/exports/teams/{filename}")
+def download_team_export(filename: str, service=Depends(deps.get_tenant_export_service)) -> FileResponse:
+    try:
+        path = service.archive_path(filename)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return FileResponse(path, media_type="application/gzip", filename=filename)
@@ -1,14 +1,45 @@
 from __future__ import annotations
 
-from fastapi import APIRouter, Depends, HTTPException, status
+from datetime import datetime
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, status
 from sqlalchemy.orm import Session
 
-from ...domain.analysis.schemas import AnalysisRequest, AnalysisResult
+from ...domain.analysis.schemas import (
+    AnalysisRequest,
+    AnalysisResult,
+    DuelMatrix,
+    OpponentModelSummary,
+    PistolReport,
+    PlayerRoleSummary,
+    PostPlantReport,
+    RosterReport,
+    SaveReport,
+    SitePrediction,
+)
+from ...domain.analysis.windows import AnalysisWindow
 from ...domain.demos.schemas import DemoCollection
 from .. import deps
 
 router = APIRouter(prefix="/api/analysis", tags=["analysis"])
 
+ERA_DESCRIPTION = 'Roster era number, or "current" for the latest lineup'
+
+
+def analysis_window(
+    played_from: Optional[datetime] = Query(default=None, alias="from", description="Played on or after this time"),
+    played_to: Optional[datetime] = Query(default=None, alias="to", description="Played on or before this time"),
+    last_n_matches: Optional[int] = Query(
+        default=None, ge=1, description="Only the most recent N matches (the team's own, in team reports)"
+    ),
+    competition_id: Optional[str] = Query(default=None, description="Restrict to demos from this competition"),
+) -> AnalysisWindow:
+    try:
+        return AnalysisWindow(played_from, played_to, last_n_matches, competition_id)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
 
 @router.get("/demos", response_model=DemoCollection)
 def list_available_demos(
@@ -16,7 +47,7 @@ def list_available_demos(
     service=Depends(deps.get_analysis_service),
 ) -> DemoCollection:
     demos = service.list_available_demos(session)
-    return DemoCollection(demos=demos, count=len(demos))
+    return DemoCollection(demos=demos, count=len(demos), total=len(demos))
 
 
 @router.post("", response_model=AnalysisResult)
@@ -31,3 +62,152 @@ def run_analysis(
         raise HTTPException(status_code=status.HTTP_500_INTERNAL_SERVER_ERROR, detail=str(exc)) from exc
     except ValueError as exc:
         raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/teams/{team}/post-plant", response_model=PostPlantReport)
+def post_plant_report(
+    team: str,
+    map_name: Optional[str] = Query(default=None, description="Restrict to demos played on this map"),
+    era: Optional[str] = Query(default=None, description=ERA_DESCRIPTION),
+    window: AnalysisWindow = Depends(analysis_window),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> PostPlantReport:
+    try:
+        return service.post_plant_report(session, team, map_name=map_name, era=era, window=window)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/teams/{team}/pistol-rounds", response_model=PistolReport)
+def pistol_report(
+    team: str,
+    map_name: Optional[str] = Query(default=None, description="Restrict to demos played on this map"),
+    era: Optional[str] = Query(default=None, description=ERA_DESCRIPTION),
+    window: AnalysisWindow = Depends(analysis_window),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> PistolReport:
+    try:
+        return service.pistol_report(session, team, map_name=map_name, era=era, window=window)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/teams/{team}/saves", response_model=SaveReport)
+def save_report(
+    team: str,
+    map_name: Optional[str] = Query(default=None, description="Restrict to demos played on this map"),
+    era: Optional[str] = Query(default=None, description=ERA_DESCRIPTION),
+    window: AnalysisWindow = Depends(analysis_window),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> SaveReport:
+    """Equipment a team kept after lost rounds and whether it saved or forced in the next one."""
+
+    try:
+        return service.save_report(session, team, map_name=map_name, era=era, window=window)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/duels", response_model=DuelMatrix)
+def duel_matrix(
+    player: Optional[str] = Query(default=None, description="Only duels this SteamID took part in"),
+    map_name: Optional[str] = Query(default=None, description="Restrict to demos played on this map"),
+    window: AnalysisWindow = Depends(analysis_window),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> DuelMatrix:
+    """Who-killed-whom across processed demos, with weapon and situation breakdowns."""
+
+    return service.duel_matrix(session, steamid=player, map_name=map_name, window=window)
+
+
+@router.get("/demos/{demo_id}/duels", response_model=DuelMatrix)
+def demo_duel_matrix(
+    demo_id: str,
+    player: Optional[str] = Query(default=None, description="Only duels this SteamID took part in"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> DuelMatrix:
+    """Who-killed-whom in one demo."""
+
+    try:
+        return service.duel_matrix(session, demo_id=demo_id, steamid=player)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/roles", response_model=list[PlayerRoleSummary])
+def list_player_roles(
+    map_name: Optional[str] = None,
+    team: Optional[str] = None,
+    steamid: Optional[str] = None,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> list[PlayerRoleSummary]:
+    roles = service.list_player_roles(session, map_name=map_name, team=team, steamid=steamid)
+    return [PlayerRoleSummary.from_orm(role) for role in roles]
+
+
+@router.post("/roles/refresh", response_model=list[PlayerRoleSummary])
+def refresh_player_roles(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> list[PlayerRoleSummary]:
+    return [PlayerRoleSummary.from_orm(role) for role in service.refresh_player_roles(session)]
+
+
+@router.get("/opponents", response_model=list[OpponentModelSummary])
+def list_opponent_models(
+    team: Optional[str] = None,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> list[OpponentModelSummary]:
+    return [OpponentModelSummary.from_orm(model) for model in service.list_opponent_models(session, team=team)]
+
+
+@router.post("/opponents/refresh", response_model=list[OpponentModelSummary])
+def refresh_opponent_models(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> list[OpponentModelSummary]:
+    return [OpponentModelSummary.from_orm(model) for model in service.refresh_opponent_models(session)]
+
+
+@router.get("/teams/{team}/site-prediction", response_model=SitePrediction)
+def predict_site_hit(
+    team: str,
+    map_name: str = Query(alias="map", description="e.g. de_mirage"),
+    economy: Optional[str] = Query(default=None, description="The team's T buy: pistol, eco, force or full"),
+    score_for: Optional[int] = Query(default=None, ge=0, description="The team's score before the round"),
+    score_against: Optional[int] = Query(default=None, ge=0),
+    elapsed: Optional[float] = Query(default=None, ge=0, description="Seconds since freeze time ended"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> SitePrediction:
+    """Chance the team hits A, B or splits through mid this round, from its fitted opponent model."""
+
+    try:
+        return service.predict_site_hit(
+            session,
+            team,
+            map_name,
+            economy=economy,
+            score_for=score_for,
+            score_against=score_against,
+            elapsed_seconds=elapsed,
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/teams/{team}/rosters", response_model=RosterReport)
+def roster_report(
+    team: str,
+    min_changes: int = Query(default=1, ge=1, le=5, description="Players that must change to start a new era"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_analysis_service),
+) -> RosterReport:
+    return service.roster_report(session, team, min_changes=min_changes)
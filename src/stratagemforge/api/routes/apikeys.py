@@ -0,0 +1,138 @@
+from __future__ import annotations
+
+from typing import Iterable
+
+from fastapi import APIRouter, Depends, HTTPException, status
+from sqlalchemy.orm import Session
+
+from ...domain.users.models import ApiKey
+from ...domain.users.schemas import ApiKeyCreate, ApiKeyCreated, ApiKeySummary, ApiKeyUpdate
+from .. import deps
+from ..auth import Identity, require_caller
+
+# Self-service keys for the caller; admins manage anyone's under /api/users/{user_id}/api-keys.
+router = APIRouter(prefix="/api/apikeys", tags=["api keys"])
+
+
+@router.get("", response_model=list[ApiKeySummary])
+def list_api_keys(
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> list[ApiKeySummary]:
+    return [ApiKeySummary.from_orm(api_key) for api_key in service.list_api_keys(session, identity.user_id)]
+
+
+@router.post("", response_model=ApiKeyCreated, status_code=status.HTTP_201_CREATED)
+def create_api_key(
+    payload: ApiKeyCreate,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> ApiKeyCreated:
+    """Mint a key for the caller. The key is only ever shown in this response."""
+
+    _require_grantable(identity, payload.scopes)
+    if payload.rate_limit_per_minute is not None and not identity.allows("admin"):
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail="Only admins can set a key's rate limit")
+    try:
+        api_key, key = service.create_api_key(
+            session, identity.user_id, payload.name, payload.scopes, payload.rate_limit_per_minute
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return _created(service, api_key, key)
+
+
+@router.get("/{key_id}", response_model=ApiKeySummary)
+def get_api_key(
+    key_id: str,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> ApiKeySummary:
+    try:
+        return ApiKeySummary.from_orm(service.get_api_key(session, identity.user_id, key_id))
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.patch("/{key_id}", response_model=ApiKeySummary)
+def update_api_key(
+    key_id: str,
+    payload: ApiKeyUpdate,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> ApiKeySummary:
+    """Rename a key or change its scopes; the secret stays the same."""
+
+    if payload.scopes is not None:
+        _require_grantable(identity, payload.scopes)
+        _require_grantable(identity, _own_key(service, session, identity, key_id).scopes)
+    try:
+        api_key = service.update_api_key(session, identity.user_id, key_id, payload.name, payload.scopes)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return ApiKeySummary.from_orm(api_key)
+
+
+@router.post("/{key_id}/rotate", response_model=ApiKeyCreated)
+def rotate_api_key(
+    key_id: str,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> ApiKeyCreated:
+    """Issue a new secret for a key; the old one stops working immediately.
+
+    The new secret carries the key's scopes, so the caller must hold all of them.
+    """
+
+    _require_grantable(identity, _own_key(service, session, identity, key_id).scopes)
+    try:
+        api_key, key = service.rotate_api_key(session, identity.user_id, key_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return _created(service, api_key, key)
+
+
+@router.delete("/{key_id}", status_code=status.HTTP_204_NO_CONTENT)
+def revoke_api_key(
+    key_id: str,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> None:
+    try:
+        service.revoke_api_key(session, identity.user_id, key_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+def _require_grantable(identity: Identity, scopes: Iterable[str]) -> None:
+    """A key may not do more than the credentials that created it."""
+
+    for scope in scopes:
+        if not identity.allows(scope):
+            raise HTTPException(
+                status_code=status.HTTP_403_FORBIDDEN, detail=f"You cannot grant the {scope} scope"
+            )
+
+
+def _own_key(service, session: Session, identity: Identity, key_id: str) -> ApiKey:
+    try:
+        return service.get_api_key(session, identity.user_id, key_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+def _created(service, api_key: ApiKey, key: str) -> ApiKeyCreated:
+    return ApiKeyCreated(
+        id=api_key.id,
+        name=api_key.name,
+        key=key,
+        scopes=api_key.scopes,
+        rate_limit_per_minute=service.api_key_limit(api_key),
+        created_at=api_key.created_at,
+    )
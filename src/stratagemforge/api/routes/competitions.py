@@ -0,0 +1,65 @@
+from __future__ import annotations
+
+from fastapi import APIRouter, Depends, HTTPException, status
+from sqlalchemy.orm import Session
+
+from ...domain.competitions.schemas import (
+    CompetitionCreate,
+    CompetitionDetail,
+    CompetitionSummary,
+    CompetitionUpdate,
+)
+from ...domain.demos.schemas import DemoSummary
+from .. import deps
+
+router = APIRouter(prefix="/api/competitions", tags=["competitions"])
+
+
+@router.get("", response_model=list[CompetitionSummary])
+def list_competitions(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_competition_service),
+) -> list[CompetitionSummary]:
+    return [CompetitionSummary.from_orm(competition) for competition in service.list_competitions(session)]
+
+
+@router.post("", response_model=CompetitionSummary, status_code=status.HTTP_201_CREATED)
+def create_competition(
+    payload: CompetitionCreate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_competition_service),
+) -> CompetitionSummary:
+    try:
+        competition = service.create_competition(session, payload)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    return CompetitionSummary.from_orm(competition)
+
+
+@router.get("/{competition_id}", response_model=CompetitionDetail)
+def get_competition(
+    competition_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_competition_service),
+) -> CompetitionDetail:
+    competition = service.get_competition(session, competition_id)
+    if not competition:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Competition not found")
+    demos = [DemoSummary.from_orm(demo) for demo in service.list_competition_demos(session, competition_id)]
+    return CompetitionDetail.from_orm(competition).copy(update={"demos": demos})
+
+
+@router.patch("/{competition_id}", response_model=CompetitionSummary)
+def update_competition(
+    competition_id: str,
+    payload: CompetitionUpdate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_competition_service),
+) -> CompetitionSummary:
+    try:
+        competition = service.update_competition(session, competition_id, payload)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return CompetitionSummary.from_orm(competition)
@@ -0,0 +1,30 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, status
+from sqlalchemy.orm import Session
+
+from ...domain.dashboard.schemas import Dashboard
+from .. import deps
+
+router = APIRouter(prefix="/api/dashboard", tags=["dashboard"])
+
+
+@router.get("", response_model=Dashboard)
+def get_dashboard(
+    team: Optional[str] = Query(
+        default=None, description="Team for the win-rate trend; defaults to the most played team"
+    ),
+    weeks: int = Query(default=8, ge=1, le=52, description="Weeks covered by the win-rate trend"),
+    tz: Optional[str] = Query(default=None, description="IANA timezone for weekly buckets, e.g. Europe/Berlin"),
+    user_id: Optional[str] = Query(default=None, description="Use this user's timezone preference when tz is omitted"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_dashboard_service),
+) -> Dashboard:
+    try:
+        return service.dashboard(session, team=team, weeks=weeks, timezone=tz, user_id=user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
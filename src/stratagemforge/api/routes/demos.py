@@ -1,66 +1,976 @@
 from __future__ import annotations
 
-from fastapi import APIRouter, Depends, File, HTTPException, UploadFile, status
+import json
+import zlib
+from datetime import datetime
+from pathlib import Path
+from typing import Iterator, List, Literal, Optional
+
+from fastapi import APIRouter, Depends, File, Form, HTTPException, Query, Request, UploadFile, status
+from fastapi.responses import FileResponse, RedirectResponse, Response, StreamingResponse
 from sqlalchemy.orm import Session
 
-from ...domain.demos.schemas import DemoCollection, DemoDetail, DemoProcessingStatus, DemoUploadResponse
+from ...core.parquet import ARROW_STREAM_MEDIA_TYPE, arrow_stream
+from ...domain.analysis.heatmaps import HEATMAP_BINS, heatmap_png
+from ...domain.analysis.schemas import Heatmap
+from ...domain.demos.archives import InvalidDemoError, UploadTooLargeError
+from ...domain.demos.concurrency import ParseQueueFullError
+from ...domain.demos.csv_export import ZIP_MEDIA_TYPE, zip_stream
+from ...domain.demos.hltv import HltvError
+from ...domain.demos.replay import DEFAULT_REPLAY_RATE
+from ...domain.demos.repository import DemoVisibility
+from ...domain.demos.retry import DemoProcessingError
+from ...domain.demos.trajectories import DEFAULT_TOLERANCE
+from ...domain.demos.schemas import (
+    DemoCollection,
+    DemoCompetitionAssignment,
+    DemoDetail,
+    DemoFile,
+    DemoFileCollection,
+    DemoProcessingStatus,
+    DemoSharecodeIngestRequest,
+    DemoSummary,
+    DemoUploadResponse,
+    DemoUrlIngestRequest,
+    DemoVisibilityUpdate,
+    GameSources,
+    AimTrack,
+    HltvImportRequest,
+    LiveBroadcastStart,
+    LiveBroadcastSummary,
+    MatchClock,
+    RoundReplay,
+    TrashedDemo,
+    UploadBatchSummary,
+)
 from .. import deps
+from ..auth import Identity, get_identity
 
 router = APIRouter(prefix="/api/demos", tags=["demos"])
 
+PARQUET_MEDIA_TYPE = "application/vnd.apache.parquet"
+NDJSON_MEDIA_TYPE = "application/x-ndjson"
+
+
+def demo_visibility(
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    teams=Depends(deps.get_team_service),
+) -> Optional[DemoVisibility]:
+    """Demos the caller may see; ``None`` (everything) for admins and anonymous callers on open deployments."""
+
+    if identity is None or identity.allows("admin"):
+        return None
+    return DemoVisibility(user_id=identity.user_id, team_ids=tuple(teams.team_ids(session, identity.user_id, "view")))
+
 
 @router.get("", response_model=DemoCollection)
 def list_demos(
+    competition_id: Optional[str] = Query(default=None, description="Only demos attached to this competition"),
+    map_name: Optional[str] = Query(default=None, alias="map", description="Only matches on this map, e.g. de_mirage"),
+    player: Optional[str] = Query(default=None, description="SteamID or player name that appeared in the match"),
+    played_from: Optional[datetime] = Query(default=None, alias="from", description="Played on or after this time"),
+    played_to: Optional[datetime] = Query(default=None, alias="to", description="Played on or before this time"),
+    sort: str = Query(
+        default="-uploaded_at",
+        description="uploaded_at, played_at, map, filename or size; prefix with - for descending order",
+    ),
+    page: int = Query(default=1, ge=1),
+    page_size: int = Query(default=50, ge=1, le=200),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
     session: Session = Depends(deps.get_db),
     service=Depends(deps.get_demo_service),
 ) -> DemoCollection:
-    demos = service.list_demos(session)
-    return DemoCollection(demos=demos, count=len(demos))
+    try:
+        demos, total = service.search_demos(
+            session,
+            competition_id=competition_id,
+            map_name=map_name,
+            player=player,
+            played_from=played_from,
+            played_to=played_to,
+            sort=sort,
+            page=page,
+            page_size=page_size,
+            visibility=visibility,
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return DemoCollection(demos=demos, count=len(demos), total=total, page=page, page_size=page_size)
+
+
+@router.get("/trash", response_model=list[TrashedDemo])
+def list_trash(
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> list[TrashedDemo]:
+    """Deleted demos that can still be restored, with when each is purged."""
+
+    return [
+        TrashedDemo(
+            **DemoSummary.from_orm(demo).model_dump(), deleted_at=demo.deleted_at, purge_at=service.purge_at(demo)
+        )
+        for demo in service.list_trash(session, visibility=visibility)
+    ]
+
+
+@router.get("/live", response_model=list[LiveBroadcastSummary])
+def list_broadcasts(
+    active: bool = Query(default=False, description="Only broadcasts still being recorded"),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    broadcasts=Depends(deps.get_broadcast_service),
+) -> list[LiveBroadcastSummary]:
+    return [
+        LiveBroadcastSummary.from_orm(broadcast)
+        for broadcast in broadcasts.list_broadcasts(session, active_only=active)
+        if _broadcast_visible(broadcast, visibility)
+    ]
+
+
+@router.post("/live", response_model=LiveBroadcastSummary, status_code=status.HTTP_201_CREATED)
+def start_broadcast(
+    request: LiveBroadcastStart,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    broadcasts=Depends(deps.get_broadcast_service),
+    teams=Depends(deps.get_team_service),
+) -> LiveBroadcastSummary:
+    """Start recording a GOTV+ broadcast; it becomes a regular demo when the broadcast ends."""
+
+    if broadcasts.settings.broadcast_poll_seconds <= 0:
+        raise HTTPException(status_code=status.HTTP_503_SERVICE_UNAVAILABLE, detail="Live recording is disabled")
+    try:
+        broadcast = broadcasts.start(
+            session,
+            str(request.url),
+            owner_id=identity.user_id if identity else None,
+            team_id=_uploading_team(request.team_id, identity, session, teams),
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return LiveBroadcastSummary.from_orm(broadcast)
+
+
+@router.get("/live/{broadcast_id}", response_model=LiveBroadcastSummary)
+def get_broadcast(
+    broadcast_id: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    broadcasts=Depends(deps.get_broadcast_service),
+) -> LiveBroadcastSummary:
+    """Live round, score and the partial parquet segments written so far."""
+
+    return LiveBroadcastSummary.from_orm(_visible_broadcast(broadcasts, session, broadcast_id, visibility))
+
+
+@router.post("/live/{broadcast_id}/stop", response_model=LiveBroadcastSummary)
+def stop_broadcast(
+    broadcast_id: str,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    broadcasts=Depends(deps.get_broadcast_service),
+    teams=Depends(deps.get_team_service),
+) -> LiveBroadcastSummary:
+    """Stop recording early; what was recorded is still ingested."""
+
+    try:
+        require_demo_manager(identity, broadcasts.get(session, broadcast_id), session, teams, "delete")
+        broadcast = broadcasts.stop(session, broadcast_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    return LiveBroadcastSummary.from_orm(broadcast)
+
+
+@router.get("/live/{broadcast_id}/segments/{number}/{dataset}")
+def download_segment(
+    broadcast_id: str,
+    number: int,
+    dataset: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    broadcasts=Depends(deps.get_broadcast_service),
+) -> FileResponse:
+    broadcast = _visible_broadcast(broadcasts, session, broadcast_id, visibility)
+    segment = next((entry for entry in broadcast.segments or [] if entry["number"] == number), None)
+    if not segment or dataset not in segment["datasets"]:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=f"File {dataset} not found for demo")
+    return FileResponse(
+        broadcasts.segment_path(broadcast, number, dataset),
+        media_type=PARQUET_MEDIA_TYPE,
+        filename=f"live-{broadcast.id}-{number:04d}-{dataset}.parquet",
+    )
 
 
 @router.get("/{demo_id}", response_model=DemoDetail)
 def get_demo(
     demo_id: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
     session: Session = Depends(deps.get_db),
     service=Depends(deps.get_demo_service),
 ) -> DemoDetail:
     demo = service.get_demo(session, demo_id)
-    if not demo:
+    if not demo or (visibility and not visibility.allows(demo)):
         raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Demo not found")
     return DemoDetail.from_orm(demo)
 
 
+@router.get("/matches/{match_id}", response_model=DemoDetail)
+def get_demo_by_match(
+    match_id: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> DemoDetail:
+    """Look a demo up by its match id or by the external match id it was uploaded with."""
+
+    demo = service.get_demo_by_match(session, match_id)
+    if not demo or (visibility and not visibility.allows(demo)):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Match not found")
+    return DemoDetail.from_orm(demo)
+
+
+@router.get("/matches/{match_id}/export", responses={200: {"content": {ZIP_MEDIA_TYPE: {}}}})
+def export_match(
+    match_id: str,
+    format: Literal["csv", "parquet"] = Query(default="csv"),
+    datasets: Optional[str] = Query(default=None, description="Comma-separated datasets (default: all of them)"),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> StreamingResponse:
+    """A zip of the match's datasets: gzip CSVs that Excel and Sheets can open, or the parquet files."""
+
+    demo = service.get_demo_by_match(session, match_id)
+    if not demo or (visibility and not visibility.allows(demo)):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Match not found")
+    selected = [name.strip() for name in datasets.split(",") if name.strip()] if datasets else None
+    try:
+        files = service.export_files(demo, format, selected)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return StreamingResponse(
+        zip_stream(files),
+        media_type=ZIP_MEDIA_TYPE,
+        headers={"Content-Disposition": f'attachment; filename="{match_id}-{format}.zip"'},
+    )
+
+
+@router.get("/matches/{match_id}/players/{steamid}/aim", response_model=AimTrack)
+def aim_track(
+    match_id: str,
+    steamid: str,
+    round_number: int = Query(..., alias="round", ge=1),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> AimTrack:
+    """Every-tick view angles and shots of one player in one round, for aim review tools.
+
+    Read on demand from the stored demo, so expect a few seconds per call.
+    """
+
+    demo = service.get_demo_by_match(session, match_id)
+    if not demo or (visibility and not visibility.allows(demo)):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Match not found")
+    try:
+        return service.aim_track(session, match_id, steamid, round_number)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+@router.get(
+    "/matches/{match_id}/rounds/{round_number}/replay",
+    response_model=RoundReplay,
+    responses={200: {"content": {NDJSON_MEDIA_TYPE: {}}}},
+)
+def round_replay(
+    match_id: str,
+    round_number: int,
+    rate: float = Query(default=DEFAULT_REPLAY_RATE, gt=0, le=128, description="Frames per second"),
+    format: Literal["json", "ndjson"] = Query(default="json", description="ndjson streams one event per line"),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+):
+    """Time-ordered player positions, view angles, shots, kills and grenades of one round, for 2D playback.
+
+    Read on demand from the stored demo, so expect a few seconds per call.
+    """
+
+    demo = service.get_demo_by_match(session, match_id)
+    if not demo or (visibility and not visibility.allows(demo)):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Match not found")
+    try:
+        replay = service.round_replay(session, match_id, round_number, rate)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    if format == "ndjson":
+        return StreamingResponse(_ndjson_lines(replay), media_type=NDJSON_MEDIA_TYPE)
+    return replay
+
+
+@router.get("/matches/{match_id}/trajectories")
+def match_trajectories(
+    match_id: str,
+    round_number: Optional[int] = Query(default=None, alias="round", ge=1),
+    steamid: Optional[str] = Query(default=None),
+    tolerance: float = Query(
+        default=DEFAULT_TOLERANCE, ge=0, le=1000, description="Largest offset in world units a dropped point may have"
+    ),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> dict:
+    """Each player's path through each round as a simplified GeoJSON-like ``LineString`` of world positions."""
+
+    demo = service.get_demo_by_match(session, match_id)
+    if not demo or (visibility and not visibility.allows(demo)):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Match not found")
+    try:
+        return service.trajectories(session, match_id, tolerance, round_number=round_number, steamid=steamid)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/matches/{match_id}/heatmap", response_model=Heatmap, responses={200: {"content": {"image/png": {}}}})
+def match_heatmap(
+    match_id: str,
+    player: Optional[str] = Query(default=None, description="Only this SteamID's positions"),
+    side: Optional[Literal["CT", "T"]] = Query(default=None),
+    round_number: Optional[int] = Query(default=None, alias="round", ge=1),
+    phase: Optional[Literal["freezetime", "live"]] = Query(default=None),
+    bins: int = Query(default=HEATMAP_BINS, ge=4, le=256, description="Cells per row and column"),
+    format: Literal["json", "png"] = Query(default="json", description="png renders the grid as an overlay image"),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    analysis=Depends(deps.get_analysis_service),
+):
+    """Binned player positions of one match, per player, side, round and round phase.
+
+    On standard maps the grid covers the radar overview and ``radar`` carries
+    its calibration, so the PNG can be stretched straight over the radar image.
+    """
+
+    demo = service.get_demo_by_match(session, match_id)
+    if not demo or (visibility and not visibility.allows(demo)):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Match not found")
+    try:
+        heatmap = analysis.match_heatmap(session, match_id, player, side, round_number, phase, bins)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    if format == "png":
+        return Response(content=heatmap_png(heatmap.grid), media_type="image/png")
+    return heatmap
+
+
 @router.post("/upload", response_model=DemoUploadResponse, status_code=status.HTTP_201_CREATED)
 async def upload_demo(
     demo: UploadFile = File(...),
+    competition_id: Optional[str] = Form(default=None),
+    team_id: Optional[str] = Form(default=None, description="Uploading team; selects its feature-flag overrides"),
+    external_match_id: Optional[str] = Form(default=None, max_length=128, description="e.g. the FACEIT match id"),
+    external_source: Optional[str] = Form(default=None, max_length=32, description="Where the external id comes from"),
+    callback_url: Optional[str] = Form(default=None, description="Receives a signed POST once processing finishes"),
+    visibility: Optional[str] = Form(default=None, description="private, team or public"),
+    force: bool = Query(default=False, description="Reprocess the demo even if it was already processed"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+    identity: Optional[Identity] = Depends(get_identity),
+) -> DemoUploadResponse:
+    try:
+        stored, created = await service.upload_demo(
+            demo,
+            session,
+            force=force,
+            competition_id=competition_id,
+            team_id=_uploading_team(team_id, identity, session, teams),
+            external_match_id=external_match_id,
+            external_source=external_source,
+            callback_url=callback_url,
+            owner_id=identity.user_id if identity else None,
+            visibility=visibility,
+        )
+    except InvalidDemoError as exc:
+        raise HTTPException(status_code=status.HTTP_422_UNPROCESSABLE_ENTITY, detail=str(exc)) from exc
+    except UploadTooLargeError as exc:
+        raise HTTPException(status_code=status.HTTP_413_REQUEST_ENTITY_TOO_LARGE, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    except ParseQueueFullError as exc:
+        raise HTTPException(
+            status_code=status.HTTP_503_SERVICE_UNAVAILABLE, detail=str(exc), headers={"Retry-After": "30"}
+        ) from exc
+    except DemoProcessingError as exc:
+        raise _processing_failed(exc) from exc
+
+    return _upload_response(stored, created, "Demo uploaded and processed")
+
+
+@router.post("/upload/batch", response_model=UploadBatchSummary, status_code=status.HTTP_202_ACCEPTED)
+async def upload_batch(
+    demos: List[UploadFile] = File(..., description="The demos, as repeated multipart parts"),
+    competition_id: Optional[str] = Form(default=None),
+    team_id: Optional[str] = Form(default=None),
+    visibility: Optional[str] = Form(default=None, description="private, team or public"),
+    force: bool = Query(default=False, description="Reprocess demos that were already processed"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+    identity: Optional[Identity] = Depends(get_identity),
+) -> UploadBatchSummary:
+    """Queue several demos at once; each is processed as its own job. Follow progress at ``/batches/{id}``."""
+
+    try:
+        batch = await service.upload_batch(
+            demos,
+            session,
+            force=force,
+            competition_id=competition_id,
+            team_id=_uploading_team(team_id, identity, session, teams),
+            owner_id=identity.user_id if identity else None,
+            visibility=visibility,
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return UploadBatchSummary.from_orm(batch)
+
+
+@router.post("/upload/archive", response_model=UploadBatchSummary, status_code=status.HTTP_202_ACCEPTED)
+async def upload_batch_archive(
+    archive: UploadFile = File(..., description="A zip archive of demos"),
+    competition_id: Optional[str] = Form(default=None),
+    team_id: Optional[str] = Form(default=None),
+    visibility: Optional[str] = Form(default=None, description="private, team or public"),
+    force: bool = Query(default=False, description="Reprocess demos that were already processed"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+    identity: Optional[Identity] = Depends(get_identity),
+) -> UploadBatchSummary:
+    """Queue every demo in a zip archive as one batch."""
+
+    try:
+        batch = await service.upload_batch_archive(
+            archive,
+            session,
+            force=force,
+            competition_id=competition_id,
+            team_id=_uploading_team(team_id, identity, session, teams),
+            owner_id=identity.user_id if identity else None,
+            visibility=visibility,
+        )
+    except InvalidDemoError as exc:
+        raise HTTPException(status_code=status.HTTP_422_UNPROCESSABLE_ENTITY, detail=str(exc)) from exc
+    except UploadTooLargeError as exc:
+        raise HTTPException(status_code=status.HTTP_413_REQUEST_ENTITY_TOO_LARGE, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return UploadBatchSummary.from_orm(batch)
+
+
+@router.get("/batches/{batch_id}", response_model=UploadBatchSummary)
+def get_batch(
+    batch_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    identity: Optional[Identity] = Depends(get_identity),
+) -> UploadBatchSummary:
+    """A batch's aggregate status and the outcome of each of its demos; only the uploader and admins see it."""
+
+    try:
+        batch = service.get_batch(session, batch_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    if identity and not identity.allows("admin") and batch.owner_id != identity.user_id:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Upload batch not found")
+    return UploadBatchSummary.from_orm(batch)
+
+
+@router.post("/ingest/url", response_model=DemoUploadResponse, status_code=status.HTTP_201_CREATED)
+async def ingest_demo_from_url(
+    request: DemoUrlIngestRequest,
+    force: bool = Query(default=False, description="Reprocess the demo even if it was already processed"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+    identity: Optional[Identity] = Depends(get_identity),
+) -> DemoUploadResponse:
+    try:
+        stored, created = await service.ingest_from_url(
+            str(request.url),
+            session,
+            force=force,
+            competition_id=request.competition_id,
+            team_id=_uploading_team(request.team_id, identity, session, teams),
+            external_match_id=request.external_match_id,
+            external_source=request.external_source,
+            callback_url=str(request.callback_url) if request.callback_url else None,
+            owner_id=identity.user_id if identity else None,
+            visibility=request.visibility,
+        )
+    except InvalidDemoError as exc:
+        raise HTTPException(status_code=status.HTTP_422_UNPROCESSABLE_ENTITY, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    except ParseQueueFullError as exc:
+        raise HTTPException(
+            status_code=status.HTTP_503_SERVICE_UNAVAILABLE, detail=str(exc), headers={"Retry-After": "30"}
+        ) from exc
+    except DemoProcessingError as exc:
+        raise _processing_failed(exc) from exc
+
+    return _upload_response(stored, created, "Demo downloaded and processed")
+
+
+@router.post("/ingest/sharecode", response_model=DemoUploadResponse, status_code=status.HTTP_201_CREATED)
+async def ingest_demo_from_sharecode(
+    request: DemoSharecodeIngestRequest,
+    force: bool = Query(default=False, description="Reprocess the demo even if it was already processed"),
     session: Session = Depends(deps.get_db),
     service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+    identity: Optional[Identity] = Depends(get_identity),
 ) -> DemoUploadResponse:
+    """Look up a matchmaking sharecode's replay on Valve's servers, then download and process it."""
+
+    if not service.replays.enabled:
+        raise HTTPException(
+            status_code=status.HTTP_503_SERVICE_UNAVAILABLE, detail="Sharecode ingestion is not configured"
+        )
+    try:
+        stored, created = await service.ingest_sharecode(
+            request.sharecode,
+            session,
+            force=force,
+            competition_id=request.competition_id,
+            team_id=_uploading_team(request.team_id, identity, session, teams),
+            callback_url=str(request.callback_url) if request.callback_url else None,
+            owner_id=identity.user_id if identity else None,
+            visibility=request.visibility,
+        )
+    except InvalidDemoError as exc:
+        raise HTTPException(status_code=status.HTTP_422_UNPROCESSABLE_ENTITY, detail=str(exc)) from exc
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    except ParseQueueFullError as exc:
+        raise HTTPException(
+            status_code=status.HTTP_503_SERVICE_UNAVAILABLE, detail=str(exc), headers={"Retry-After": "30"}
+        ) from exc
+    except DemoProcessingError as exc:
+        raise _processing_failed(exc) from exc
+
+    return _upload_response(stored, created, "Demo downloaded and processed")
+
+
+@router.delete("/{demo_id}", status_code=status.HTTP_204_NO_CONTENT)
+def delete_demo(
+    demo_id: str,
+    permanent: bool = Query(default=False, description="Skip the trash and delete files and rows now (admins)"),
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+    activity=Depends(deps.get_activity_service),
+) -> None:
+    """Move a demo to the trash; it can be restored until ``TRASH_RETENTION_DAYS`` have passed."""
+
+    demo = service.get_demo(session, demo_id, include_deleted=permanent)
+    if demo:
+        require_demo_manager(identity, demo, session, teams, "delete")
+    if permanent and identity is not None and not identity.allows("admin"):
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail="Only admins can delete demos permanently")
+    try:
+        service.delete_demo(session, demo_id, soft=not permanent)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    activity.record_demo(session, identity and identity.user_id, "demo.deleted", demo, permanent=permanent)
+
+
+@router.post("/{demo_id}/restore", response_model=DemoDetail)
+def restore_demo(
+    demo_id: str,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+) -> DemoDetail:
+    return _restore(service.get_demo(session, demo_id, include_deleted=True), identity, session, service, teams)
+
+
+@router.post("/matches/{match_id}/restore", response_model=DemoDetail)
+def restore_match(
+    match_id: str,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+) -> DemoDetail:
+    """Restore a deleted match by its match id or external match id."""
+
+    demo = service.get_demo_by_match(session, match_id, include_deleted=True)
+    return _restore(demo, identity, session, service, teams)
+
+
+@router.put("/{demo_id}/visibility", response_model=DemoDetail)
+def set_visibility(
+    demo_id: str,
+    update: DemoVisibilityUpdate,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+    activity=Depends(deps.get_activity_service),
+) -> DemoDetail:
+    """Make a demo private, share it with its team or publish it to everyone."""
+
+    demo = service.get_demo(session, demo_id)
+    if demo:
+        require_demo_manager(identity, demo, session, teams, "share")
     try:
-        stored, created = await service.upload_demo(demo, session)
+        demo = service.set_visibility(session, demo_id, update.visibility)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
     except ValueError as exc:
         raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    activity.record_demo_shared(session, identity and identity.user_id, demo)
+    return DemoDetail.from_orm(demo)
+
 
-    message = "Demo uploaded and processed" if created else "Demo already processed"
-    return DemoUploadResponse.from_orm(stored).copy(update={"message": message})
+@router.put("/{demo_id}/competition", response_model=DemoDetail)
+def assign_competition(
+    demo_id: str,
+    assignment: DemoCompetitionAssignment,
+    identity: Optional[Identity] = Depends(get_identity),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+) -> DemoDetail:
+    _require_managed(service, session, demo_id, identity, visibility, teams)
+    try:
+        demo = service.assign_competition(session, demo_id, assignment.competition_id, assignment.series)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return DemoDetail.from_orm(demo)
+
+
+@router.post("/{demo_id}/hltv", response_model=DemoDetail)
+def import_hltv_result(
+    demo_id: str,
+    request: HltvImportRequest,
+    identity: Optional[Identity] = Depends(get_identity),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+) -> DemoDetail:
+    _require_managed(service, session, demo_id, identity, visibility, teams)
+    try:
+        demo = service.import_hltv_result(session, demo_id, str(request.url), map_name=request.map_name)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    except HltvError as exc:
+        raise HTTPException(status_code=status.HTTP_502_BAD_GATEWAY, detail=str(exc)) from exc
+    return DemoDetail.from_orm(demo)
 
 
 @router.get("/{demo_id}/status", response_model=DemoProcessingStatus)
 def processing_status(
     demo_id: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
     session: Session = Depends(deps.get_db),
     service=Depends(deps.get_demo_service),
 ) -> DemoProcessingStatus:
     demo = service.get_demo(session, demo_id)
-    if not demo:
+    if not demo or (visibility and not visibility.allows(demo)):
         raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Demo not found")
 
-    status_message = "processed" if demo.status == "processed" else "pending"
+    messages = {
+        "processed": "Demo is processed",
+        "failed": "Processing failed; retry the demo's last job",
+        "dead_letter": "Demo could not be processed; retry its last job once the cause is fixed",
+    }
     return DemoProcessingStatus(
         demo_id=demo.id,
         status=demo.status,
-        message=f"Demo is {status_message}",
+        message=messages.get(demo.status, "Demo is pending"),
         processed_at=demo.processed_at,
         processed_path=demo.processed_path,
         extra_metadata=demo.extra_metadata or {},
     )
+
+
+@router.get("/{demo_id}/sources", response_model=GameSources)
+def game_sources(
+    demo_id: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> GameSources:
+    """Other uploads of the same game (GOTV, FACEIT, POV), primary source first."""
+
+    _require_visible(service, session, demo_id, visibility)
+    try:
+        demos = service.game_sources(session, demo_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    demos = [demo for demo in demos if not visibility or visibility.allows(demo)]
+    primary = next((demo for demo in demos if demo.match is None or demo.match.is_primary), demos[0])
+    return GameSources(
+        game_id=primary.match.game_id if primary.match else None,
+        primary_demo_id=primary.id,
+        demos=[DemoDetail.from_orm(demo) for demo in demos],
+    )
+
+
+@router.get("/{demo_id}/clock", response_model=MatchClock)
+def match_clock(
+    demo_id: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> MatchClock:
+    """Tick to real-time mapping per round, for syncing VODs of the same game to parsed events."""
+
+    _require_visible(service, session, demo_id, visibility)
+    try:
+        return service.match_clock(session, demo_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/{demo_id}/files", response_model=DemoFileCollection)
+def list_files(
+    demo_id: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> DemoFileCollection:
+    _require_visible(service, session, demo_id, visibility)
+    try:
+        keys = service.artifact_keys(session, demo_id)
+        versions = service.schema_versions(session, demo_id)
+        row_groups = service.row_group_ranges(service.get_demo(session, demo_id))
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+    files = []
+    for name, key in keys.items():
+        size = service.storage.size(key)
+        if size is None:
+            continue
+        files.append(
+            DemoFile(
+                name=name,
+                filename=_download_name(demo_id, name),
+                size_bytes=size,
+                download_url=f"{router.prefix}/{demo_id}/files/{name}",
+                schema_version=versions.get(name),
+                row_groups=row_groups.get(name),
+            )
+        )
+    return DemoFileCollection(demo_id=demo_id, files=files)
+
+
+@router.get(
+    "/{demo_id}/files/{name}",
+    responses={200: {"content": {PARQUET_MEDIA_TYPE: {}, ARROW_STREAM_MEDIA_TYPE: {}}}},
+)
+def download_file(
+    demo_id: str,
+    name: str,
+    request: Request,
+    redirect: bool = Query(default=True, description="Redirect to a presigned object-store URL when available"),
+    format: Optional[Literal["parquet", "arrow"]] = Query(
+        default=None, description="arrow streams the dataset as Arrow IPC; the default follows the Accept header"
+    ),
+    columns: Optional[str] = Query(default=None, description="Comma-separated columns for the Arrow stream"),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+):
+    """Download one dataset as parquet, or as an Arrow IPC stream for notebooks and ML pipelines.
+
+    Read the stream with ``pyarrow.ipc.open_stream(...).read_pandas()`` (or ``polars.read_ipc_stream``).
+    """
+
+    _require_visible(service, session, demo_id, visibility)
+    try:
+        key = service.artifact_keys(session, demo_id).get(name)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    if not key or not service.storage.exists(key):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=f"File {name} not found for demo")
+
+    if format == "arrow" or (format is None and ARROW_STREAM_MEDIA_TYPE in request.headers.get("accept", "")):
+        selected = [column.strip() for column in columns.split(",") if column.strip()] if columns else None
+        try:
+            chunks = arrow_stream(service.storage.get(key), columns=selected)
+        except ValueError as exc:
+            raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+        return StreamingResponse(
+            chunks,
+            media_type=ARROW_STREAM_MEDIA_TYPE,
+            headers={
+                "Content-Disposition": f'attachment; filename="{demo_id}-{name}.arrows"',
+                "Vary": "Accept",
+            },
+        )
+
+    if redirect:
+        url = service.storage.url(key)
+        if url:
+            return RedirectResponse(url, status_code=status.HTTP_307_TEMPORARY_REDIRECT)
+
+    filename = _download_name(demo_id, name)
+    path = service.storage.get(key)
+    if "gzip" in request.headers.get("accept-encoding", ""):
+        return StreamingResponse(
+            _gzip_chunks(path),
+            media_type=PARQUET_MEDIA_TYPE,
+            headers={
+                "Content-Disposition": f'attachment; filename="{filename}"',
+                "Content-Encoding": "gzip",
+                "Vary": "Accept-Encoding",
+            },
+        )
+    return FileResponse(path, media_type=PARQUET_MEDIA_TYPE, filename=filename)
+
+
+def _download_name(demo_id: str, name: str) -> str:
+    return f"{demo_id}-{name}.parquet"
+
+
+def _gzip_chunks(path: Path, chunk_size: int = 1024 * 1024) -> Iterator[bytes]:
+    compressor = zlib.compressobj(wbits=zlib.MAX_WBITS | 16)  # gzip container
+    with path.open("rb") as handle:
+        while chunk := handle.read(chunk_size):
+            yield compressor.compress(chunk)
+    yield compressor.flush()
+
+
+def _ndjson_lines(replay: RoundReplay) -> Iterator[str]:
+    """The replay header as ``{"type": "round", ...}``, then each event on its own line."""
+
+    header = replay.model_dump(mode="json", exclude={"events"})
+    for record in ({"type": "round", **header}, *replay.events):
+        yield json.dumps(record, separators=(",", ":")) + "\n"
+
+
+def _processing_failed(exc: DemoProcessingError) -> HTTPException:
+    """The demo is stored but could not be processed: 422 if it is corrupt, 503 if the failure was transient."""
+
+    if exc.dead_letter:
+        return HTTPException(status_code=status.HTTP_422_UNPROCESSABLE_ENTITY, detail=str(exc))
+    return HTTPException(
+        status_code=status.HTTP_503_SERVICE_UNAVAILABLE, detail=str(exc), headers={"Retry-After": "30"}
+    )
+
+
+def _upload_response(demo, created: bool, message: str) -> DemoUploadResponse:
+    response = DemoUploadResponse.from_orm(demo)
+    if created:
+        return response.copy(update={"message": message})
+    return response.copy(update={"status": "duplicate", "message": "Demo already processed; pass force=true to reprocess"})
+
+
+def _uploading_team(team_id: Optional[str], identity: Optional[Identity], session: Session, teams) -> Optional[str]:
+    """The team a demo is uploaded for, which scopes who can see it.
+
+    An explicit ``team_id`` wins, then the team named by the caller's token,
+    then the caller's only team. Callers other than admins may only upload
+    for teams they are on and whose permissions let their role upload.
+    """
+
+    if identity is None:
+        return team_id
+    member_of = teams.team_ids(session, identity.user_id)
+    team_id = team_id or identity.team_id or (member_of[0] if len(member_of) == 1 else None)
+    if not team_id or identity.allows("admin") or team_id == identity.team_id:
+        return team_id
+    if team_id not in member_of:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail="You are not on this team")
+    if not teams.can(session, team_id, identity.user_id, "upload"):
+        raise HTTPException(
+            status_code=status.HTTP_403_FORBIDDEN, detail="Your team role cannot upload demos for this team"
+        )
+    return team_id
+
+
+def require_demo_manager(identity: Optional[Identity], demo, session: Session, teams, action: str) -> None:
+    """Trash, restore and sharing are for admins, the uploader and the members the team's permissions allow.
+
+    Anonymous callers on open deployments may only change demos nobody owns.
+    """
+
+    if identity is None:
+        if demo.owner_id or demo.team_id:
+            raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail="Authentication required")
+        return
+    if identity.allows("admin") or demo.owner_id == identity.user_id:
+        return
+    if not demo.team_id or not teams.can(session, demo.team_id, identity.user_id, action):
+        raise HTTPException(
+            status_code=status.HTTP_403_FORBIDDEN,
+            detail="Only the uploader or team members the team's permissions allow can do that",
+        )
+
+
+def _require_managed(
+    service, session: Session, demo_id: str, identity: Optional[Identity], visibility: Optional[DemoVisibility], teams
+) -> None:
+    """Edits of a demo's metadata: 404 when the caller cannot see it, 403 unless they may share it."""
+
+    _require_visible(service, session, demo_id, visibility)
+    demo = service.get_demo(session, demo_id)
+    if demo:
+        require_demo_manager(identity, demo, session, teams, "share")
+
+
+def _restore(demo, identity: Optional[Identity], session: Session, service, teams) -> DemoDetail:
+    if not demo or demo.deleted_at is None:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Demo not found in trash")
+    require_demo_manager(identity, demo, session, teams, "delete")
+    return DemoDetail.from_orm(service.restore_demo(session, demo.id))
+
+
+def _broadcast_visible(broadcast, visibility: Optional[DemoVisibility]) -> bool:
+    """Broadcasts follow the team rules of demos: the recorder and the team's members see them."""
+
+    if visibility is None or (broadcast.owner_id is None and broadcast.team_id is None):
+        return True
+    return broadcast.owner_id == visibility.user_id or broadcast.team_id in visibility.team_ids
+
+
+def _visible_broadcast(broadcasts, session: Session, broadcast_id: str, visibility: Optional[DemoVisibility]):
+    try:
+        broadcast = broadcasts.get(session, broadcast_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    if not _broadcast_visible(broadcast, visibility):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Broadcast not found")
+    return broadcast
+
+
+def _require_visible(service, session: Session, demo_id: str, visibility: Optional[DemoVisibility]) -> None:
+    """Answer 404 for demos of other teams, as if they did not exist."""
+
+    demo = service.get_demo(session, demo_id)
+    if demo and visibility and not visibility.allows(demo):
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Demo not found")
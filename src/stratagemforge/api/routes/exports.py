@@ -0,0 +1,22 @@
+from __future__ import annotations
+
+from fastapi import APIRouter, Depends, HTTPException, status
+from sqlalchemy.orm import Session
+
+from ...domain.exports.schemas import SheetsExportResult
+from .. import deps
+
+router = APIRouter(prefix="/api/exports", tags=["exports"])
+
+
+@router.post("/sheets", response_model=SheetsExportResult)
+def export_to_sheets(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_sheets_export_service),
+) -> SheetsExportResult:
+    try:
+        return service.export(session)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    except RuntimeError as exc:
+        raise HTTPException(status_code=status.HTTP_502_BAD_GATEWAY, detail=str(exc)) from exc
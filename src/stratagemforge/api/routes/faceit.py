@@ -0,0 +1,121 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, status
+from sqlalchemy.orm import Session
+
+from ...domain.faceit.schemas import (
+    FaceitConnectorCreate,
+    FaceitConnectorStatus,
+    FaceitConnectorSummary,
+    FaceitPollResult,
+)
+from .. import deps
+from ..auth import Identity, get_identity
+
+router = APIRouter(prefix="/api/faceit", tags=["faceit"])
+
+# Pulls listed in a connector's status.
+RECENT_PULLS = 50
+
+
+@router.post("/connectors", response_model=FaceitConnectorSummary, status_code=status.HTTP_201_CREATED)
+def create_connector(
+    payload: FaceitConnectorCreate,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_faceit_service),
+) -> FaceitConnectorSummary:
+    """Pull a FACEIT player's or hub's finished matches automatically from now on."""
+
+    try:
+        connector = service.create_connector(
+            session,
+            payload.kind,
+            payload.faceit_id,
+            api_key=payload.api_key,
+            team_id=payload.team_id,
+            owner_id=identity.user_id if identity else None,
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return FaceitConnectorSummary.from_orm(connector)
+
+
+@router.get("/connectors", response_model=list[FaceitConnectorSummary])
+def list_connectors(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_faceit_service),
+) -> list[FaceitConnectorSummary]:
+    return [FaceitConnectorSummary.from_orm(connector) for connector in service.list_connectors(session)]
+
+
+@router.get("/connectors/{connector_id}", response_model=FaceitConnectorStatus)
+def connector_status(
+    connector_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_faceit_service),
+) -> FaceitConnectorStatus:
+    try:
+        connector = service.get_connector(session, connector_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    result = FaceitConnectorStatus.from_orm(connector)
+    result.pulls = result.pulls[:RECENT_PULLS]
+    return result
+
+
+@router.post("/connectors/{connector_id}/enable", response_model=FaceitConnectorSummary)
+def enable_connector(
+    connector_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_faceit_service),
+) -> FaceitConnectorSummary:
+    return _set_enabled(session, service, connector_id, True)
+
+
+@router.post("/connectors/{connector_id}/disable", response_model=FaceitConnectorSummary)
+def disable_connector(
+    connector_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_faceit_service),
+) -> FaceitConnectorSummary:
+    return _set_enabled(session, service, connector_id, False)
+
+
+@router.post("/connectors/{connector_id}/poll", response_model=FaceitPollResult)
+async def poll_connector(
+    connector_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_faceit_service),
+    demos=Depends(deps.get_demo_service),
+) -> FaceitPollResult:
+    """Look for finished matches now instead of waiting for the next scheduled poll."""
+
+    try:
+        connector = service.get_connector(session, connector_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    ingested = await service.poll(session, connector, demos)
+    return FaceitPollResult(connector_id=connector.id, ingested=ingested, last_error=connector.last_error)
+
+
+@router.delete("/connectors/{connector_id}", status_code=status.HTTP_204_NO_CONTENT)
+def delete_connector(
+    connector_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_faceit_service),
+) -> None:
+    try:
+        service.delete_connector(session, connector_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+def _set_enabled(session: Session, service, connector_id: str, enabled: bool) -> FaceitConnectorSummary:
+    try:
+        connector = service.set_enabled(session, connector_id, enabled)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return FaceitConnectorSummary.from_orm(connector)
@@ -0,0 +1,88 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, status
+from sqlalchemy.orm import Session
+
+from ...domain.flags.schemas import (
+    FeatureFlagEvaluation,
+    FeatureFlagOverrideUpdate,
+    FeatureFlagSummary,
+    FeatureFlagUpdate,
+)
+from .. import deps
+
+router = APIRouter(prefix="/api/flags", tags=["flags"])
+
+
+@router.get("", response_model=list[FeatureFlagSummary])
+def list_flags(
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_feature_flag_service),
+) -> list[FeatureFlagSummary]:
+    return [FeatureFlagSummary.from_orm(flag) for flag in service.list_flags(session)]
+
+
+@router.get("/evaluate", response_model=FeatureFlagEvaluation)
+def evaluate_flags(
+    team_id: Optional[str] = Query(default=None, description="Apply this team's overrides"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_feature_flag_service),
+) -> FeatureFlagEvaluation:
+    return FeatureFlagEvaluation(team_id=team_id, flags=service.evaluate(session, team_id))
+
+
+@router.put("/{key}", response_model=FeatureFlagSummary)
+def set_flag(
+    key: str,
+    payload: FeatureFlagUpdate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_feature_flag_service),
+) -> FeatureFlagSummary:
+    try:
+        flag = service.set_flag(session, key, payload.enabled, payload.description)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return FeatureFlagSummary.from_orm(flag)
+
+
+@router.delete("/{key}", status_code=status.HTTP_204_NO_CONTENT)
+def delete_flag(
+    key: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_feature_flag_service),
+) -> None:
+    try:
+        service.delete_flag(session, key)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.put("/{key}/teams/{team_id}", response_model=FeatureFlagSummary)
+def set_override(
+    key: str,
+    team_id: str,
+    payload: FeatureFlagOverrideUpdate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_feature_flag_service),
+) -> FeatureFlagSummary:
+    try:
+        flag = service.set_override(session, key, team_id, payload.enabled)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return FeatureFlagSummary.from_orm(flag)
+
+
+@router.delete("/{key}/teams/{team_id}", response_model=FeatureFlagSummary)
+def clear_override(
+    key: str,
+    team_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_feature_flag_service),
+) -> FeatureFlagSummary:
+    try:
+        flag = service.clear_override(session, key, team_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return FeatureFlagSummary.from_orm(flag)
@@ -22,10 +22,30 @@ def root() -> dict[str, object]:
             "demos": "/api/demos",
             "analysis": "/api/analysis",
             "users": "/api/users",
+            "competitions": "/api/competitions",
+            "stats": "/api/stats",
+            "exports": "/api/exports",
+            "schedule": "/api/schedule",
+            "dashboard": "/api/dashboard",
+            "onboarding": "/api/onboarding",
+            "notifications": "/api/notifications",
+            "flags": "/api/flags",
+            "usage": "/api/usage",
+            "admin": "/api/admin",
+            "public": "/public/v1",
+            "openid_configuration": "/.well-known/openid-configuration",
+            "metrics": "/metrics",
         },
     }
 
 
+@router.get("/.well-known/jwks.json", tags=["auth"])
+def jwks() -> dict[str, object]:
+    """Public key of the access tokens issued at /api/auth/login, for other services to verify them."""
+
+    return deps.get_user_service().tokens.jwks()
+
+
 @router.get("/health", tags=["health"])
 def health_check() -> dict[str, object]:
     settings = deps.get_active_settings()
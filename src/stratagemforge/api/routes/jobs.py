@@ -0,0 +1,90 @@
+from __future__ import annotations
+
+from typing import List, Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, status
+from sqlalchemy.orm import Session
+
+from ...domain.demos.concurrency import ParseQueueFullError
+from ...domain.demos.repository import DemoVisibility
+from ...domain.demos.schemas import ParseJobSummary
+from .. import deps
+from ..auth import Identity, get_identity
+from .demos import demo_visibility, require_demo_manager
+
+router = APIRouter(prefix="/api/jobs", tags=["jobs"])
+
+
+@router.get("", response_model=List[ParseJobSummary])
+def list_jobs(
+    status_filter: Optional[str] = Query(default=None, alias="status", description="e.g. dead_letter or error"),
+    demo_id: Optional[str] = Query(default=None),
+    limit: int = Query(default=100, ge=1, le=1000),
+    cursor: Optional[str] = Query(default=None, description="Id of the last job of the previous page"),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> List[ParseJobSummary]:
+    """Processing runs of the demos the caller may see, newest first."""
+
+    try:
+        jobs = service.list_jobs(
+            session, status=status_filter, demo_id=demo_id, limit=limit, cursor=cursor, visibility=visibility
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return [ParseJobSummary.from_orm(job) for job in jobs]
+
+
+@router.get("/{job_id}", response_model=ParseJobSummary)
+def get_job(
+    job_id: str,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> ParseJobSummary:
+    return ParseJobSummary.from_orm(_visible_job(service, session, job_id, visibility))
+
+
+@router.post("/{job_id}/retry", response_model=ParseJobSummary)
+async def retry_job(
+    job_id: str,
+    identity: Optional[Identity] = Depends(get_identity),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+    teams=Depends(deps.get_team_service),
+) -> ParseJobSummary:
+    """Reprocess the job's demo from its stored file, e.g. after a parser fix; returns the new job.
+
+    Like trashing the demo, this is for admins, its uploader and the team members allowed to delete it.
+    """
+
+    demo = service.get_demo(session, _visible_job(service, session, job_id, visibility).demo_id)
+    if demo:
+        require_demo_manager(identity, demo, session, teams, "delete")
+    try:
+        job = await service.retry_job(session, job_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    except ParseQueueFullError as exc:
+        raise HTTPException(
+            status_code=status.HTTP_503_SERVICE_UNAVAILABLE, detail=str(exc), headers={"Retry-After": "30"}
+        ) from exc
+    return ParseJobSummary.from_orm(job)
+
+
+def _visible_job(service, session: Session, job_id: str, visibility: Optional[DemoVisibility]):
+    """The job, or 404 when its demo is hidden from the caller (or gone, for anyone but admins)."""
+
+    try:
+        job = service.get_job(session, job_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    if visibility is not None:
+        demo = service.get_demo(session, job.demo_id, include_deleted=True)
+        if demo is None or not visibility.allows(demo):
+            raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Job not found")
+    return job
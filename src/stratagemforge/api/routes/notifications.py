@@ -0,0 +1,111 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, status
+from sqlalchemy.orm import Session
+
+from ...core.i18n import Translator
+from ...core.ids import next_cursor
+from ...domain.notifications.schemas import (
+    MarkReadResult,
+    NotificationCollection,
+    NotificationCreate,
+    NotificationSummary,
+    UnreadCount,
+)
+from .. import deps
+
+router = APIRouter(prefix="/api/notifications", tags=["notifications"])
+
+
+@router.get("", response_model=NotificationCollection)
+def list_notifications(
+    user_id: str = Query(...),
+    unread: bool = Query(default=False, description="Only unread notifications"),
+    page: int = Query(default=1, ge=1),
+    page_size: int = Query(default=20, ge=1, le=100),
+    cursor: Optional[str] = Query(default=None, description="next_cursor of the previous page; replaces page"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_notification_service),
+    translator=Depends(deps.get_translator_for_request),
+    locale: str = Depends(deps.get_locale),
+) -> NotificationCollection:
+    try:
+        notifications, total = service.list_notifications(
+            session, user_id, unread_only=unread, page=page, page_size=page_size, cursor=cursor
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return NotificationCollection(
+        notifications=[_localized(NotificationSummary.from_orm(item), translator, locale) for item in notifications],
+        count=len(notifications),
+        total=total,
+        unread=service.unread_count(session, user_id),
+        page=page,
+        page_size=page_size,
+        next_cursor=next_cursor([item.id for item in notifications], page_size),
+    )
+
+
+@router.get("/unread-count", response_model=UnreadCount)
+def unread_count(
+    user_id: str = Query(...),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_notification_service),
+) -> UnreadCount:
+    return UnreadCount(user_id=user_id, unread=service.unread_count(session, user_id))
+
+
+@router.post("", response_model=list[NotificationSummary], status_code=status.HTTP_201_CREATED)
+def create_notification(
+    payload: NotificationCreate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_notification_service),
+) -> list[NotificationSummary]:
+    try:
+        notifications = service.notify(
+            session,
+            payload.kind,
+            payload.title,
+            user_id=payload.user_id,
+            body=payload.body,
+            link=payload.link,
+            data=payload.data,
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return [NotificationSummary.from_orm(notification) for notification in notifications]
+
+
+@router.post("/read-all", response_model=MarkReadResult)
+def mark_all_read(
+    user_id: str = Query(...),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_notification_service),
+) -> MarkReadResult:
+    updated = service.mark_all_read(session, user_id)
+    return MarkReadResult(updated=updated, unread=service.unread_count(session, user_id))
+
+
+@router.post("/{notification_id}/read", response_model=NotificationSummary)
+def mark_read(
+    notification_id: str,
+    user_id: Optional[str] = Query(default=None, description="Reject the request if the notification belongs to someone else"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_notification_service),
+    translator=Depends(deps.get_translator_for_request),
+    locale: str = Depends(deps.get_locale),
+) -> NotificationSummary:
+    try:
+        notification = service.mark_read(session, notification_id, user_id=user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return _localized(NotificationSummary.from_orm(notification), translator, locale)
+
+
+def _localized(summary: NotificationSummary, translator: Translator, locale: str) -> NotificationSummary:
+    summary.title = translator.translate(summary.title, locale)
+    if summary.body:
+        summary.body = translator.translate(summary.body, locale)
+    return summary
@@ -0,0 +1,165 @@
+from __future__ import annotations
+
+import base64
+import binascii
+from html import escape
+from typing import Dict, Optional, Tuple
+from urllib.parse import unquote
+
+from fastapi import APIRouter, Depends, Form, HTTPException, Query, Request, status
+from fastapi.responses import HTMLResponse, JSONResponse, RedirectResponse
+from sqlalchemy.orm import Session
+
+from ...domain.users.oidc import OidcError
+from .. import deps
+from ..auth import Identity, require_caller
+
+router = APIRouter(tags=["oidc"])
+
+# Parameters of an authorization request carried through the sign-in form.
+AUTHORIZE_PARAMS = ("response_type", "scope", "state", "nonce", "code_challenge", "code_challenge_method")
+NO_STORE = {"Cache-Control": "no-store", "Pragma": "no-cache"}
+
+
+@router.get("/.well-known/openid-configuration")
+def openid_configuration(request: Request, provider=Depends(deps.get_oidc_provider)) -> Dict[str, object]:
+    return provider.discovery(str(request.base_url))
+
+
+@router.get("/oauth2/authorize", response_class=HTMLResponse)
+def authorize_form(
+    request: Request,
+    client_id: str = Query(...),
+    redirect_uri: str = Query(...),
+    session: Session = Depends(deps.get_db),
+    provider=Depends(deps.get_oidc_provider),
+) -> HTMLResponse:
+    """Sign-in page of the authorization code flow; it posts back to this URL."""
+
+    try:
+        client = provider.authorization_client(session, client_id, redirect_uri)
+    except OidcError as exc:
+        return _error_page(exc)
+    params = {name: request.query_params.get(name) for name in AUTHORIZE_PARAMS}
+    return _sign_in_page(client.name, client_id, redirect_uri, params)
+
+
+@router.post("/oauth2/authorize")
+def authorize(
+    client_id: str = Form(...),
+    redirect_uri: str = Form(...),
+    email: str = Form(...),
+    password: str = Form(...),
+    response_type: Optional[str] = Form(default=None),
+    scope: Optional[str] = Form(default=None),
+    state: Optional[str] = Form(default=None),
+    nonce: Optional[str] = Form(default=None),
+    code_challenge: Optional[str] = Form(default=None),
+    code_challenge_method: Optional[str] = Form(default=None),
+    session: Session = Depends(deps.get_db),
+    provider=Depends(deps.get_oidc_provider),
+    users=Depends(deps.get_user_service),
+):
+    """Check the credentials from the sign-in page and send the browser back to the client with a code."""
+
+    params = {
+        "response_type": response_type,
+        "scope": scope,
+        "state": state,
+        "nonce": nonce,
+        "code_challenge": code_challenge,
+        "code_challenge_method": code_challenge_method,
+    }
+    try:
+        client = provider.authorization_client(session, client_id, redirect_uri)
+    except OidcError as exc:
+        return _error_page(exc)
+    try:
+        user = users.authenticate(session, email, password)
+    except PermissionError as exc:
+        return _sign_in_page(client.name, client_id, redirect_uri, params, error=str(exc))
+    location = provider.authorize(session, user, client, redirect_uri, params)
+    return RedirectResponse(location, status_code=status.HTTP_303_SEE_OTHER)
+
+
+@router.post("/oauth2/token")
+async def token(
+    request: Request,
+    session: Session = Depends(deps.get_db),
+    provider=Depends(deps.get_oidc_provider),
+) -> JSONResponse:
+    """Token endpoint for the ``authorization_code`` and ``refresh_token`` grants (form encoded)."""
+
+    form = {key: value for key, value in (await request.form()).items() if isinstance(value, str)}
+    try:
+        basic = _basic_credentials(request.headers.get("authorization"))
+        tokens = provider.exchange(session, form, basic)
+    except OidcError as exc:
+        code = status.HTTP_401_UNAUTHORIZED if exc.error == "invalid_client" else status.HTTP_400_BAD_REQUEST
+        return JSONResponse({"error": exc.error, "error_description": str(exc)}, status_code=code, headers=NO_STORE)
+    return JSONResponse(tokens, headers=NO_STORE)
+
+
+@router.get("/oauth2/userinfo")
+def userinfo(
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    provider=Depends(deps.get_oidc_provider),
+    users=Depends(deps.get_user_service),
+) -> Dict[str, object]:
+    """Claims about the user behind the bearer token."""
+
+    try:
+        user = users.get_user(session, identity.user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail="Invalid bearer token") from exc
+    return provider.userinfo(user)
+
+
+def _basic_credentials(header: Optional[str]) -> Optional[Tuple[str, str]]:
+    """``client_secret_basic`` credentials: form-encoded id and secret in HTTP Basic auth."""
+
+    scheme, _, value = (header or "").partition(" ")
+    if scheme.lower() != "basic":
+        return None
+    try:
+        client_id, separator, secret = base64.b64decode(value.strip()).decode().partition(":")
+    except (binascii.Error, UnicodeDecodeError) as exc:
+        raise OidcError("invalid_client", "Malformed Basic credentials") from exc
+    if not separator:
+        raise OidcError("invalid_client", "Malformed Basic credentials")
+    return unquote(client_id), unquote(secret)
+
+
+def _error_page(exc: OidcError) -> HTMLResponse:
+    body = f"<h1>Sign-in request rejected</h1><p>{escape(str(exc))}</p>"
+    return HTMLResponse(_page(body), status_code=status.HTTP_400_BAD_REQUEST)
+
+
+def _sign_in_page(
+    client_name: str,
+    client_id: str,
+    redirect_uri: str,
+    params: Dict[str, Optional[str]],
+    error: Optional[str] = None,
+) -> HTMLResponse:
+    hidden = {"client_id": client_id, "redirect_uri": redirect_uri, **params}
+    fields = "".join(
+        f'<input type="hidden" name="{name}" value="{escape(value)}">' for name, value in hidden.items() if value
+    )
+    body = (
+        f"<h1>Sign in to {escape(client_name)}</h1>"
+        + (f'<p role="alert">{escape(error)}</p>' if error else "")
+        + '<form method="post" action="authorize">'
+        + fields
+        + '<label>Email <input type="email" name="email" autocomplete="username" required></label>'
+        + '<label>Password <input type="password" name="password" autocomplete="current-password" required></label>'
+        + '<button type="submit">Sign in</button></form>'
+    )
+    code = status.HTTP_401_UNAUTHORIZED if error else status.HTTP_200_OK
+    return HTMLResponse(_page(body), status_code=code, headers={"X-Frame-Options": "DENY", **NO_STORE})
+
+
+def _page(body: str) -> str:
+    head = '<meta charset="utf-8"><meta name="viewport" content="width=device-width"><title>StratagemForge</title>'
+    return f"<!doctype html><html><head>{head}</head><body>{body}</body></html>"
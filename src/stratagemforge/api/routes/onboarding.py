@@ -0,0 +1,129 @@
+from __future__ import annotations
+
+from fastapi import APIRouter, Depends, HTTPException, status
+from sqlalchemy.orm import Session
+
+from ...domain.onboarding.schemas import (
+    FirstDemoLink,
+    InviteResult,
+    MapPoolUpdate,
+    MemberInvites,
+    OnboardingStatus,
+    SteamLink,
+    TeamCreate,
+)
+from ...domain.onboarding.service import OnboardingStepError
+from .. import deps
+
+router = APIRouter(prefix="/api/onboarding", tags=["onboarding"])
+
+@router.get("/{user_id}", response_model=OnboardingStatus)
+def get_onboarding(
+    user_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_onboarding_service),
+) -> OnboardingStatus:
+    try:
+        return service.status(session, user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.post("/{user_id}/team", response_model=OnboardingStatus)
+def create_team(
+    user_id: str,
+    payload: TeamCreate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_onboarding_service),
+) -> OnboardingStatus:
+    try:
+        return service.create_team(session, user_id, payload.name, payload.tag)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except OnboardingStepError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+@router.post("/{user_id}/steam", response_model=OnboardingStatus)
+def link_steam(
+    user_id: str,
+    payload: SteamLink,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_onboarding_service),
+) -> OnboardingStatus:
+    try:
+        return service.link_steam(session, user_id, payload.steamid)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except OnboardingStepError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+@router.put("/{user_id}/map-pool", response_model=OnboardingStatus)
+def set_map_pool(
+    user_id: str,
+    payload: MapPoolUpdate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_onboarding_service),
+) -> OnboardingStatus:
+    try:
+        return service.set_map_pool(session, user_id, payload.maps)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except OnboardingStepError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+@router.post("/{user_id}/invites", response_model=InviteResult)
+def invite_members(
+    user_id: str,
+    payload: MemberInvites,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_onboarding_service),
+) -> InviteResult:
+    try:
+        onboarding, invites = service.invite_members(session, user_id, [str(email) for email in payload.emails])
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except OnboardingStepError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return InviteResult(onboarding=onboarding, invites=invites)
+
+
+@router.post("/{user_id}/first-demo", response_model=OnboardingStatus)
+def link_first_demo(
+    user_id: str,
+    payload: FirstDemoLink,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_onboarding_service),
+) -> OnboardingStatus:
+    try:
+        return service.link_first_demo(session, user_id, payload.demo_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except OnboardingStepError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+@router.post("/{user_id}/skip", response_model=OnboardingStatus)
+def skip_step(
+    user_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_onboarding_service),
+) -> OnboardingStatus:
+    try:
+        return service.skip(session, user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except OnboardingStepError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
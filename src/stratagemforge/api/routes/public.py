@@ -0,0 +1,113 @@
+from __future__ import annotations
+
+import hashlib
+from typing import Optional
+
+from fastapi import APIRouter, Depends, Header, HTTPException, Query, Request, Response, status
+from pydantic import BaseModel
+from sqlalchemy.orm import Session
+
+from ...core.limits import RateLimitExceededError
+from ...domain.public.models import PublicApiKey
+from ...domain.public.schemas import MatchCard, PlayerCard, PublicRoster, PublicTeamStats
+from ...domain.public.service import PublicStatsService
+from .. import deps
+
+# Kept apart from /api: only read-only, team-scoped aggregates live here.
+router = APIRouter(prefix="/public/v1", tags=["public"])
+
+
+def require_public_key(
+    response: Response,
+    x_api_key: Optional[str] = Header(default=None, alias="X-API-Key"),
+    session: Session = Depends(deps.get_db),
+    service: PublicStatsService = Depends(deps.get_public_stats_service),
+) -> PublicApiKey:
+    try:
+        api_key = service.authenticate(session, x_api_key)
+        quota = service.check_rate(api_key)
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail=str(exc)) from exc
+    except RateLimitExceededError as exc:
+        headers = {**exc.quota.headers(), "Retry-After": str(exc.quota.reset_seconds)}
+        raise HTTPException(status_code=status.HTTP_429_TOO_MANY_REQUESTS, detail=str(exc), headers=headers) from exc
+    response.headers.update(quota.headers())
+    return api_key
+
+
+@router.get("/team", response_model=PublicTeamStats)
+def team_stats(
+    request: Request,
+    response: Response,
+    api_key: PublicApiKey = Depends(require_public_key),
+    session: Session = Depends(deps.get_db),
+    service: PublicStatsService = Depends(deps.get_public_stats_service),
+):
+    """Record, map win rates and recent results of the key's team."""
+
+    return _cacheable(request, response, service, service.team_stats(session, api_key.team_id))
+
+
+@router.get("/team/players", response_model=PublicRoster)
+def team_players(
+    request: Request,
+    response: Response,
+    api_key: PublicApiKey = Depends(require_public_key),
+    session: Session = Depends(deps.get_db),
+    service: PublicStatsService = Depends(deps.get_public_stats_service),
+):
+    """Per-player totals of the key's team from parsed demos."""
+
+    return _cacheable(request, response, service, service.roster(session, api_key.team_id))
+
+
+@router.get("/widgets/match", response_model=MatchCard)
+def match_card(
+    request: Request,
+    response: Response,
+    token: str = Query(..., description="Embed token issued via POST /api/admin/embed-tokens"),
+    session: Session = Depends(deps.get_db),
+    service: PublicStatsService = Depends(deps.get_public_stats_service),
+):
+    """Everything a match-result card shows: map, teams, score and the best rated player."""
+
+    card = _card(service.match_card, session, token)
+    response.headers["Access-Control-Allow-Origin"] = "*"
+    return _cacheable(request, response, service, card)
+
+
+@router.get("/widgets/player", response_model=PlayerCard)
+def player_card(
+    request: Request,
+    response: Response,
+    token: str = Query(..., description="Embed token issued via POST /api/admin/embed-tokens"),
+    session: Session = Depends(deps.get_db),
+    service: PublicStatsService = Depends(deps.get_public_stats_service),
+):
+    """Everything a player-profile card shows: career totals, averages and favourite map."""
+
+    card = _card(service.player_card, session, token)
+    response.headers["Access-Control-Allow-Origin"] = "*"
+    return _cacheable(request, response, service, card)
+
+
+def _card(build, session: Session, token: str) -> BaseModel:
+    try:
+        return build(session, token)
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail=str(exc)) from exc
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+def _cacheable(request: Request, response: Response, service: PublicStatsService, payload: BaseModel):
+    """Add caching headers and answer ``If-None-Match`` revalidations with 304."""
+
+    etag = '"' + hashlib.sha256(payload.model_dump_json().encode()).hexdigest()[:32] + '"'
+    response.headers["ETag"] = etag
+    response.headers["Cache-Control"] = f"public, max-age={service.settings.public_api_cache_seconds}"
+    if request.headers.get("if-none-match") == etag:
+        return Response(status_code=status.HTTP_304_NOT_MODIFIED, headers=dict(response.headers))
+    return payload
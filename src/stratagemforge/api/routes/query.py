@@ -0,0 +1,46 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, status
+from fastapi.responses import Response
+from sqlalchemy.orm import Session
+
+from ...core.parquet import ARROW_STREAM_MEDIA_TYPE
+from ...domain.demos.repository import DemoVisibility
+from ...domain.query.schemas import QueryRequest, QueryResult
+from .. import deps
+from .demos import demo_visibility
+
+router = APIRouter(prefix="/api/query", tags=["query"])
+
+
+@router.post("", response_model=QueryResult, responses={200: {"content": {ARROW_STREAM_MEDIA_TYPE: {}}}})
+def run_query(
+    payload: QueryRequest,
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    demos=Depends(deps.get_demo_service),
+    service=Depends(deps.get_query_service),
+):
+    """Run one read-only SELECT over the parquet datasets of the given matches.
+
+    Each dataset is a table named after it (``kills``, ``rounds``, ...) with a
+    ``match_id`` column added, e.g. ``SELECT weapon, count(*) FROM kills GROUP BY weapon``.
+    """
+
+    selected = []
+    for match_id in dict.fromkeys(payload.match_ids):
+        demo = demos.get_demo_by_match(session, match_id)
+        if not demo or (visibility and not visibility.allows(demo)):
+            raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=f"Match {match_id} not found")
+        selected.append(demo)
+    try:
+        output = service.run(selected, payload.sql)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    except RuntimeError as exc:
+        raise HTTPException(status_code=status.HTTP_503_SERVICE_UNAVAILABLE, detail=str(exc)) from exc
+    if payload.format == "arrow":
+        return Response(content=output.to_arrow_stream(), media_type=ARROW_STREAM_MEDIA_TYPE)
+    return output.to_result()
@@ -0,0 +1,119 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, Response, status
+from sqlalchemy.orm import Session
+
+from ...domain.schedule.schemas import (
+    FeedTokenRequest,
+    FeedTokenResponse,
+    ScheduledSessionCreate,
+    ScheduledSessionSummary,
+    ScheduledSessionUpdate,
+    SessionKind,
+)
+from .. import deps
+
+router = APIRouter(prefix="/api/schedule", tags=["schedule"])
+
+
+@router.get("/sessions", response_model=list[ScheduledSessionSummary])
+def list_sessions(
+    kind: Optional[SessionKind] = Query(default=None),
+    starts_after: Optional[datetime] = Query(default=None, alias="from"),
+    starts_before: Optional[datetime] = Query(default=None, alias="to"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_schedule_service),
+) -> list[ScheduledSessionSummary]:
+    sessions = service.list_sessions(session, kind=kind, starts_after=starts_after, starts_before=starts_before)
+    return [ScheduledSessionSummary.from_orm(scheduled) for scheduled in sessions]
+
+
+@router.post("/sessions", response_model=ScheduledSessionSummary, status_code=status.HTTP_201_CREATED)
+def create_session(
+    payload: ScheduledSessionCreate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_schedule_service),
+) -> ScheduledSessionSummary:
+    try:
+        scheduled = service.create_session(session, payload)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return ScheduledSessionSummary.from_orm(scheduled)
+
+
+@router.patch("/sessions/{session_id}", response_model=ScheduledSessionSummary)
+def update_session(
+    session_id: str,
+    payload: ScheduledSessionUpdate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_schedule_service),
+) -> ScheduledSessionSummary:
+    try:
+        scheduled = service.update_session(session, session_id, payload)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return ScheduledSessionSummary.from_orm(scheduled)
+
+
+@router.delete("/sessions/{session_id}", status_code=status.HTTP_204_NO_CONTENT)
+def delete_session(
+    session_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_schedule_service),
+) -> None:
+    try:
+        service.delete_session(session, session_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.post("/feed-tokens", response_model=FeedTokenResponse, status_code=status.HTTP_201_CREATED)
+def issue_feed_token(
+    request: FeedTokenRequest,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_schedule_service),
+) -> FeedTokenResponse:
+    try:
+        feed_token, token = service.issue_feed_token(session, request.user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return FeedTokenResponse(
+        id=feed_token.id,
+        token=token,
+        feed_url=f"{router.prefix}/calendar.ics?token={token}",
+        created_at=feed_token.created_at,
+    )
+
+
+@router.delete("/feed-tokens/{token_id}", status_code=status.HTTP_204_NO_CONTENT)
+def revoke_feed_token(
+    token_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_schedule_service),
+) -> None:
+    try:
+        service.revoke_feed_token(session, token_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/calendar.ics", response_class=Response)
+def calendar_feed(
+    token: str = Query(..., description="Feed token issued via POST /api/schedule/feed-tokens"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_schedule_service),
+) -> Response:
+    try:
+        calendar = service.render_feed(session, token)
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail=str(exc)) from exc
+    return Response(
+        content=calendar,
+        media_type="text/calendar; charset=utf-8",
+        headers={"Content-Disposition": 'inline; filename="stratagemforge.ics"'},
+    )
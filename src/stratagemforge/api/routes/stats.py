@@ -0,0 +1,99 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, File, Form, HTTPException, Query, UploadFile, status
+from sqlalchemy.orm import Session
+
+from ...domain.stats.schemas import Benchmarks, PlayerMatchStatSummary, PlayerPercentiles, StatsImportResult
+from .. import deps
+from ..auth import Identity, get_identity
+
+router = APIRouter(prefix="/api/stats", tags=["stats"])
+
+FORMAT_DESCRIPTION = "Only matches of competitions in this format, e.g. bo3"
+MIN_ROUNDS_DESCRIPTION = "Leave players with fewer rounds out of the population"
+
+
+@router.post("/import", response_model=StatsImportResult)
+async def import_stats(
+    file: UploadFile = File(...),
+    source: str = Form(..., description="Export format: leetify or scopegg"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_stats_service),
+) -> StatsImportResult:
+    content = await file.read()
+    try:
+        return service.import_csv(session, content, source.lower())
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+@router.get("/players", response_model=list[PlayerMatchStatSummary])
+def list_player_stats(
+    steamid: Optional[str] = Query(default=None, description="SteamID64, or me for the caller's linked account"),
+    source: Optional[str] = Query(default=None, description="demo, leetify or scopegg"),
+    map_name: Optional[str] = Query(default=None, alias="map"),
+    match_id: Optional[str] = Query(default=None),
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_stats_service),
+) -> list[PlayerMatchStatSummary]:
+    if steamid == "me":
+        steamid = _linked_steamid(identity)
+    stats = service.list_player_stats(session, steamid=steamid, source=source, map_name=map_name, match_id=match_id)
+    return [PlayerMatchStatSummary.from_orm(stat) for stat in stats]
+
+
+@router.get("/players/{steamid}/percentiles", response_model=PlayerPercentiles)
+def player_percentiles(
+    steamid: str,
+    competition_format: Optional[str] = Query(default=None, alias="format", description=FORMAT_DESCRIPTION),
+    map_name: Optional[str] = Query(default=None, alias="map"),
+    min_rounds: int = Query(default=0, ge=0, description=MIN_ROUNDS_DESCRIPTION),
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_stats_service),
+) -> PlayerPercentiles:
+    """Where each of a player's career metrics ranks among every player in the dataset."""
+
+    if steamid == "me":
+        steamid = _linked_steamid(identity)
+    try:
+        return service.percentiles(
+            session, steamid, competition_format=competition_format, map_name=map_name, min_rounds=min_rounds
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.get("/benchmarks", response_model=Benchmarks)
+def benchmarks(
+    competition_format: Optional[str] = Query(default=None, alias="format", description=FORMAT_DESCRIPTION),
+    map_name: Optional[str] = Query(default=None, alias="map"),
+    min_rounds: int = Query(default=0, ge=0, description=MIN_ROUNDS_DESCRIPTION),
+    metric: Optional[str] = Query(default=None, description="Only this metric, e.g. adr"),
+    value: Optional[float] = Query(default=None, description="With metric: the percentile of this value, e.g. 78"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_stats_service),
+) -> Benchmarks:
+    """Percentiles of every player metric across the dataset."""
+
+    try:
+        return service.benchmarks(
+            session,
+            competition_format=competition_format,
+            map_name=map_name,
+            min_rounds=min_rounds,
+            metric=metric,
+            value=value,
+        )
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+def _linked_steamid(identity: Optional[Identity]) -> str:
+    steamid = identity.steamid if identity else None
+    if not steamid:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail="No Steam account is linked")
+    return steamid
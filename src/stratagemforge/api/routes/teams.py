@@ -0,0 +1,214 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, status
+from sqlalchemy.orm import Session
+
+from ...domain.onboarding.schemas import IssuedInvite
+from ...domain.teams.policy import TEAM_ACTIONS
+from ...domain.teams.schemas import (
+    InviteAcceptance,
+    MemberRoleUpdate,
+    TeamCreate,
+    TeamInviteCreate,
+    TeamMemberSummary,
+    TeamPermissions,
+    TeamPermissionsUpdate,
+    TeamSummary,
+)
+from .. import deps
+from ..auth import Identity, require_caller, require_role
+
+router = APIRouter(prefix="/api/teams", tags=["teams"])
+
+
+@router.post("", response_model=TeamSummary, status_code=status.HTTP_201_CREATED)
+def create_team(
+    payload: TeamCreate,
+    identity: Identity = Depends(require_caller),
+    _role: Optional[Identity] = Depends(require_role("analyst")),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+    activity=Depends(deps.get_activity_service),
+) -> TeamSummary:
+    """Create a team; the creator becomes its first coach."""
+
+    try:
+        team = service.create_team(session, identity.user_id, payload.name, payload.tag)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    activity.record(
+        session, identity.user_id, "team.created", "team", team.id, team_id=team.id, details={"name": team.name}
+    )
+    return TeamSummary.from_orm(team)
+
+
+@router.get("", response_model=list[TeamSummary])
+def list_teams(
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+) -> list[TeamSummary]:
+    """The caller's teams; admins see every team."""
+
+    teams = service.list_teams(session, identity.user_id, admin=identity.allows("admin"))
+    return [TeamSummary.from_orm(team) for team in teams]
+
+
+@router.get("/{team_id}", response_model=TeamSummary)
+def get_team(
+    team_id: str,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+) -> TeamSummary:
+    try:
+        team = service.get_team(session, team_id, identity.user_id, admin=identity.allows("admin"))
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return TeamSummary.from_orm(team)
+
+
+@router.get("/{team_id}/permissions", response_model=TeamPermissions)
+def get_permissions(
+    team_id: str,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+) -> TeamPermissions:
+    """Which team roles may view, upload, share and delete the team's demos and manage its roster."""
+
+    try:
+        team = service.get_team(session, team_id, identity.user_id, admin=identity.allows("admin"))
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return _permissions(service, session, team)
+
+
+@router.put("/{team_id}/permissions", response_model=TeamPermissions)
+def set_permissions(
+    team_id: str,
+    payload: TeamPermissionsUpdate,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+    activity=Depends(deps.get_activity_service),
+) -> TeamPermissions:
+    """Change the lowest team role allowed to take some actions; actions left out keep their setting."""
+
+    try:
+        team = service.set_permissions(
+            session, team_id, identity.user_id, payload.permissions, admin=identity.allows("admin")
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    changes = {"permissions": payload.permissions}
+    activity.record(
+        session, identity.user_id, "team.permissions_changed", "team", team.id, team_id=team.id, details=changes
+    )
+    return _permissions(service, session, team)
+
+
+@router.post("/{team_id}/invites", response_model=IssuedInvite, status_code=status.HTTP_201_CREATED)
+def invite_member(
+    team_id: str,
+    payload: TeamInviteCreate,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+) -> IssuedInvite:
+    """Invite someone by email with the team role they get on accepting; the token is shown once."""
+
+    try:
+        return service.invite(
+            session, team_id, identity.user_id, payload.email, payload.role, admin=identity.allows("admin")
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+@router.post("/invites/accept", response_model=TeamSummary)
+def accept_invite(
+    payload: InviteAcceptance,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+    activity=Depends(deps.get_activity_service),
+) -> TeamSummary:
+    try:
+        team = service.accept_invite(session, payload.token, identity.user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    role = service.membership(session, team.id, identity.user_id).role
+    activity.record(
+        session, identity.user_id, "team.member_joined", "team", team.id, team_id=team.id, details={"role": role}
+    )
+    return TeamSummary.from_orm(team)
+
+
+@router.put("/{team_id}/members/{user_id}", response_model=TeamMemberSummary)
+def set_member_role(
+    team_id: str,
+    user_id: str,
+    payload: MemberRoleUpdate,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+    activity=Depends(deps.get_activity_service),
+) -> TeamMemberSummary:
+    try:
+        member = service.set_member_role(
+            session, team_id, identity.user_id, user_id, payload.role, admin=identity.allows("admin")
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    activity.record(
+        session, identity.user_id, "team.role_changed", "user", user_id, team_id=team_id, details={"role": member.role}
+    )
+    return TeamMemberSummary.from_orm(member)
+
+
+@router.delete("/{team_id}/members/{user_id}", status_code=status.HTTP_204_NO_CONTENT)
+def remove_member(
+    team_id: str,
+    user_id: str,
+    identity: Identity = Depends(require_caller),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_team_service),
+    activity=Depends(deps.get_activity_service),
+) -> None:
+    """Remove a member (coaches) or leave the team (anyone, for themselves)."""
+
+    try:
+        service.remove_member(session, team_id, identity.user_id, user_id, admin=identity.allows("admin"))
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    verb = "team.member_left" if user_id == identity.user_id else "team.member_removed"
+    activity.record(session, identity.user_id, verb, "user", user_id, team_id=team_id)
+
+
+def _permissions(service, session: Session, team) -> TeamPermissions:
+    return TeamPermissions(
+        permissions=dict(service.policy(session, team.id).rules),
+        overrides=team.permissions or {},
+        actions=TEAM_ACTIONS,
+    )
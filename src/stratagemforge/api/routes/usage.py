@@ -0,0 +1,63 @@
+from __future__ import annotations
+
+from datetime import date, datetime, timedelta
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Query, status
+from sqlalchemy.orm import Session
+
+from ...domain.usage.schemas import (
+    UsageEventBatch,
+    UsageExportResult,
+    UsageIngestResult,
+    UsageOptOut,
+    UsageOptOutStatus,
+    UsageSummary,
+)
+from .. import deps
+
+router = APIRouter(prefix="/api/usage", tags=["usage"])
+
+
+@router.post("/events", response_model=UsageIngestResult, status_code=status.HTTP_202_ACCEPTED)
+def record_events(
+    batch: UsageEventBatch,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_usage_service),
+) -> UsageIngestResult:
+    accepted, dropped = service.record(session, batch.events, source=batch.source)
+    return UsageIngestResult(accepted=accepted, dropped=dropped)
+
+
+@router.get("/summary", response_model=UsageSummary)
+def usage_summary(
+    since: Optional[datetime] = Query(default=None, description="Defaults to 30 days ago"),
+    until: Optional[datetime] = Query(default=None, description="Defaults to now"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_usage_service),
+) -> UsageSummary:
+    until = until or datetime.utcnow()
+    return service.summary(session, since or until - timedelta(days=30), until)
+
+
+@router.put("/opt-out/{team_id}", response_model=UsageOptOutStatus)
+def set_opt_out(
+    team_id: str,
+    payload: UsageOptOut,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_usage_service),
+) -> UsageOptOutStatus:
+    try:
+        team = service.set_opt_out(session, team_id, payload.opted_out)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return UsageOptOutStatus(team_id=team.id, opted_out=team.usage_analytics_opt_out)
+
+
+@router.post("/export", response_model=UsageExportResult)
+def export_day(
+    day: date = Query(..., description="UTC day to write to usage/day=<date>/events.parquet"),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_usage_service),
+) -> UsageExportResult:
+    return service.export_day(session, day)
@@ -1,20 +1,330 @@
 from __future__ import annotations
 
-from fastapi import APIRouter, Depends, HTTPException, status
+import secrets
+from typing import Optional
+from urllib.parse import urlencode, urlparse
+
+from fastapi import APIRouter, Depends, Header, HTTPException, Query, Request, Response, status
+from fastapi.responses import RedirectResponse
 from sqlalchemy.orm import Session
 
-from ...domain.users.schemas import LoginRequest, LoginResponse, UserSummary
+from ...domain.demos.repository import DemoVisibility
+from ...domain.demos.schemas import DemoCollection
+from ...domain.users.models import User
+from ...domain.users.schemas import (
+    ApiKeyCreate,
+    ApiKeyCreated,
+    ApiKeySummary,
+    LoginRequest,
+    LoginResponse,
+    LogoutRequest,
+    PasswordChange,
+    PasswordResetConfirm,
+    PasswordResetRequest,
+    RefreshRequest,
+    SteamLinkUrl,
+    UserCreate,
+    UserPreferencesUpdate,
+    UserRoleUpdate,
+    UserSearchResult,
+    UserSummary,
+    UserUpdate,
+)
+from ...domain.users.service import EmailTakenError, SessionTokens
 from .. import deps
+from ..auth import Identity, get_identity
+from .demos import demo_visibility
 
 router = APIRouter(prefix="/api", tags=["users"])
 
+# Holds the nonce a Steam link is bound to, so only the browser that started it can finish it.
+STEAM_LINK_COOKIE = "stratagemforge_steam_link"
+STEAM_LINK_COOKIE_SECONDS = 10 * 60
+
 
 @router.get("/users", response_model=list[UserSummary])
 def list_users(
+    identity: Optional[Identity] = Depends(get_identity),
     session: Session = Depends(deps.get_db),
     service=Depends(deps.get_user_service),
 ) -> list[UserSummary]:
-    return [UserSummary.from_orm(user) for user in service.list_users(session)]
+    """Every account; email addresses only for admins and the caller's own account."""
+
+    return [_user_summary(user, identity) for user in service.list_users(session)]
+
+
+@router.get("/users/search", response_model=list[UserSearchResult])
+def search_users(
+    q: str = Query(..., min_length=2, max_length=255, description="Start of (or close to) a name, persona or email"),
+    team_id: Optional[str] = Query(default=None, description="Only search this team's roster, e.g. for mentions"),
+    limit: int = Query(default=10, ge=1, le=50),
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+    teams=Depends(deps.get_team_service),
+) -> list[UserSearchResult]:
+    """Find users for team invitations, mentions and admin tooling.
+
+    Only admins (and anonymous callers on open deployments) match on and see email addresses; everyone else
+    finds a user by email only by typing it in full.
+    """
+
+    admin = identity is None or identity.allows("admin")
+    member_ids = None
+    if team_id:
+        try:
+            team = teams.get_team(session, team_id, identity.user_id if identity else "", admin=admin)
+        except LookupError as exc:
+            raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+        member_ids = [member.user_id for member in team.members]
+    try:
+        results = service.search_users(session, q, include_email=admin, user_ids=member_ids, limit=limit)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return [UserSearchResult(**result) for result in results]
+
+
+@router.post("/users", response_model=UserSummary, status_code=status.HTTP_201_CREATED)
+def register(
+    payload: UserCreate,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> UserSummary:
+    """Sign up as the registration policy allows: open, with an invite token or from an allowed email domain.
+
+    Admins can always create accounts.
+    """
+
+    try:
+        user = service.register(
+            session,
+            payload.email,
+            payload.display_name,
+            payload.password,
+            payload.timezone,
+            invite_token=payload.invite_token,
+            admin=bool(identity and identity.allows("admin")),
+        )
+    except EmailTakenError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return UserSummary.from_orm(user)
+
+
+@router.get("/users/{user_id}", response_model=UserSummary)
+def get_user(
+    user_id: str,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> UserSummary:
+    try:
+        user = service.get_user(session, user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return _user_summary(user, identity)
+
+
+@router.get("/users/{user_id}/matches", response_model=DemoCollection)
+def list_user_matches(
+    user_id: str,
+    page: int = Query(default=1, ge=1),
+    page_size: int = Query(default=50, ge=1, le=200),
+    visibility: Optional[DemoVisibility] = Depends(demo_visibility),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_demo_service),
+) -> DemoCollection:
+    """Demos a user uploaded, newest first, limited to the ones the caller may see."""
+
+    demos, total = service.search_demos(
+        session, page=page, page_size=page_size, visibility=visibility, owner_id=user_id
+    )
+    return DemoCollection(demos=demos, count=len(demos), total=total, page=page, page_size=page_size)
+
+
+@router.patch("/users/{user_id}", response_model=UserSummary)
+def update_user(
+    user_id: str,
+    payload: UserUpdate,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> UserSummary:
+    _require_account_access(identity, user_id)
+    try:
+        user = service.update_profile(
+            session,
+            user_id,
+            **payload.model_dump(exclude_unset=True),
+            admin=bool(identity and identity.allows("admin")),
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except EmailTakenError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return UserSummary.from_orm(user)
+
+
+@router.delete("/users/{user_id}", status_code=status.HTTP_204_NO_CONTENT)
+def delete_user(
+    user_id: str,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> None:
+    """Soft-delete an account; its demos and stats stay, its sessions and API keys end."""
+
+    _require_account_access(identity, user_id)
+    try:
+        service.delete_user(session, user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+
+
+@router.put("/users/{user_id}/role", response_model=UserSummary)
+def set_role(
+    user_id: str,
+    payload: UserRoleUpdate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> UserSummary:
+    """Assign a platform role (admin only): player, analyst, coach or admin."""
+
+    try:
+        user = service.set_role(session, user_id, payload.role)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return UserSummary.from_orm(user)
+
+
+@router.post("/users/{user_id}/password", status_code=status.HTTP_204_NO_CONTENT)
+def change_password(
+    user_id: str,
+    payload: PasswordChange,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> None:
+    """Change a password; every login session of the user ends, so log in again afterwards."""
+
+    _require_account_access(identity, user_id)
+    try:
+        service.change_password(session, user_id, payload.current_password, payload.new_password)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+@router.post("/users/{user_id}/steam", response_model=SteamLinkUrl)
+def link_steam(
+    user_id: str,
+    request: Request,
+    response: Response,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> SteamLinkUrl:
+    """Start linking a Steam account: send the browser to the returned URL.
+
+    The response sets an HttpOnly cookie the callback checks, so the link
+    must be finished in the same browser.
+    """
+
+    _require_account_access(identity, user_id)
+    try:
+        service.get_user(session, user_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    callback_url = _steam_callback_url(request, service)
+    nonce = secrets.token_urlsafe(32)
+    response.set_cookie(
+        STEAM_LINK_COOKIE,
+        nonce,
+        max_age=STEAM_LINK_COOKIE_SECONDS,
+        path=urlparse(callback_url).path,
+        secure=callback_url.startswith("https://"),
+        httponly=True,
+        samesite="lax",
+    )
+    return SteamLinkUrl(url=service.steam_login_url(callback_url, link_user_id=user_id, link_nonce=nonce))
+
+
+@router.put("/users/{user_id}/preferences", response_model=UserSummary)
+def update_preferences(
+    user_id: str,
+    payload: UserPreferencesUpdate,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> UserSummary:
+    _require_account_access(identity, user_id)
+    try:
+        user = service.update_preferences(session, user_id, payload.timezone)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+    return UserSummary.from_orm(user)
+
+
+@router.get("/users/{user_id}/api-keys", response_model=list[ApiKeySummary])
+def list_api_keys(
+    user_id: str,
+    identity: Optional[Identity] = Depends(get_identity),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> list[ApiKeySummary]:
+    _require_account_access(identity, user_id)
+    return [ApiKeySummary.from_orm(api_key) for api_key in service.list_api_keys(session, user_id)]
+
+
+@router.post("/users/{user_id}/api-keys", response_model=ApiKeyCreated, status_code=status.HTTP_201_CREATED)
+def create_api_key(
+    user_id: str,
+    payload: ApiKeyCreate,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> ApiKeyCreated:
+    try:
+        api_key, key = service.create_api_key(
+            session, user_id, payload.name, payload.scopes, payload.rate_limit_per_minute
+        )
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    return ApiKeyCreated(
+        id=api_key.id,
+        name=api_key.name,
+        key=key,
+        scopes=api_key.scopes,
+        rate_limit_per_minute=service.api_key_limit(api_key),
+        created_at=api_key.created_at,
+    )
+
+
+@router.delete("/users/{user_id}/api-keys/{key_id}", status_code=status.HTTP_204_NO_CONTENT)
+def revoke_api_key(
+    user_id: str,
+    key_id: str,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> None:
+    try:
+        service.revoke_api_key(session, user_id, key_id)
+    except LookupError as exc:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
 
 
 @router.post("/auth/login", response_model=LoginResponse)
@@ -24,8 +334,141 @@ def login(
     service=Depends(deps.get_user_service),
 ) -> LoginResponse:
     try:
-        user, token = service.authenticate(session, request.email)
-    except ValueError as exc:
+        user = service.authenticate(session, request.email, request.password)
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail=str(exc)) from exc
+
+    return _login_response(user, service.start_session(session, user), "Login successful")
+
+
+@router.post("/auth/refresh", response_model=LoginResponse)
+def refresh(
+    request: RefreshRequest,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> LoginResponse:
+    """Trade a refresh token for a new access token and a new refresh token."""
+
+    try:
+        user, tokens = service.refresh_session(session, request.refresh_token)
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail=str(exc)) from exc
+    return _login_response(user, tokens, "Session refreshed")
+
+
+@router.post("/auth/logout", status_code=status.HTTP_204_NO_CONTENT)
+def logout(
+    request: LogoutRequest,
+    authorization: Optional[str] = Header(default=None),
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> None:
+    """Revoke the refresh token's session and the bearer token the request was sent with."""
+
+    scheme, _, token = (authorization or "").partition(" ")
+    access_token = token.strip() if scheme.lower() == "bearer" else None
+    service.logout(session, refresh_token=request.refresh_token, access_token=access_token)
+
+
+@router.get("/auth/steam/login", response_class=RedirectResponse, status_code=status.HTTP_303_SEE_OTHER)
+def steam_login(request: Request, service=Depends(deps.get_user_service)) -> RedirectResponse:
+    """Send the browser to Steam's "Sign in through Steam" page."""
+
+    url = service.steam_login_url(_steam_callback_url(request, service))
+    return RedirectResponse(url, status_code=status.HTTP_303_SEE_OTHER)
+
+
+@router.get("/auth/steam/callback", response_model=LoginResponse, name="steam_callback")
+def steam_callback(
+    request: Request,
+    reply: Response,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+):
+    """Where Steam sends the browser back; signs the user in (creating the account on first visit)."""
+
+    callback_url = _steam_callback_url(request, service)
+    nonce = request.cookies.get(STEAM_LINK_COOKIE)
+    try:
+        user = service.sign_in_with_steam(session, dict(request.query_params), callback_url, link_nonce=nonce)
+    except PermissionError as exc:
+        raise HTTPException(status_code=status.HTTP_401_UNAUTHORIZED, detail=str(exc)) from exc
+    except LookupError as exc:
         raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail=str(exc)) from exc
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc)) from exc
+    if nonce is not None:
+        reply.delete_cookie(STEAM_LINK_COOKIE, path=urlparse(callback_url).path)
+    response = _login_response(user, service.start_session(session, user), "Signed in through Steam")
+    if not service.settings.steam_login_redirect_url:
+        return response
+    # Tokens go in the fragment so they never reach the frontend's server logs.
+    fragment = urlencode(
+        {
+            "access_token": response.access_token,
+            "refresh_token": response.refresh_token,
+            "token_type": response.token_type,
+            "expires_in": response.expires_in,
+        }
+    )
+    return RedirectResponse(
+        f"{service.settings.steam_login_redirect_url}#{fragment}",
+        status_code=status.HTTP_303_SEE_OTHER,
+        headers={"set-cookie": reply.headers["set-cookie"]} if nonce is not None else None,
+    )
+
+
+@router.post("/auth/password-reset", status_code=status.HTTP_204_NO_CONTENT)
+def request_password_reset(
+    request: PasswordResetRequest,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> None:
+    """Send a reset token to the address if it has an account; the answer is the same either way."""
+
+    service.request_password_reset(session, request.email)
+
+
+@router.post("/auth/password-reset/confirm", status_code=status.HTTP_204_NO_CONTENT)
+def confirm_password_reset(
+    request: PasswordResetConfirm,
+    session: Session = Depends(deps.get_db),
+    service=Depends(deps.get_user_service),
+) -> None:
+    try:
+        service.confirm_password_reset(session, request.token, request.new_password)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc)) from exc
+
+
+def _user_summary(user, identity: Optional[Identity]) -> UserSummary:
+    """Like user search, emails go only to admins (and anonymous callers on open deployments) and the user."""
+
+    summary = UserSummary.from_orm(user)
+    if identity is None or identity.allows("admin") or identity.user_id == user.id:
+        return summary
+    return summary.copy(update={"email": None})
+
+
+def _require_account_access(identity: Optional[Identity], user_id: str) -> None:
+    """Callers may manage their own account; admins (and anonymous callers on open deployments) any."""
+
+    if identity and identity.user_id != user_id and not identity.allows("admin"):
+        raise HTTPException(status_code=status.HTTP_403_FORBIDDEN, detail="You can only change your own account")
+
+
+def _steam_callback_url(request: Request, service) -> str:
+    if service.settings.public_url:
+        return service.settings.public_url.rstrip("/") + "/api/auth/steam/callback"
+    return str(request.url_for("steam_callback"))
+
 
-    return LoginResponse(token=token, user=UserSummary.from_orm(user), message="Login successful")
+def _login_response(user: User, tokens: SessionTokens, message: str) -> LoginResponse:
+    return LoginResponse(
+        access_token=tokens.access.token,
+        refresh_token=tokens.refresh_token,
+        expires_in=tokens.access.lifetime_seconds,
+        refresh_expires_at=tokens.refresh_expires_at,
+        user=UserSummary.from_orm(user),
+        message=message,
+    )
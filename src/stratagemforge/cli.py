@@ -0,0 +1,245 @@
+"""Operational commands: ``stratagemforge-admin backup|restore|parse|export-team|create-api-key|set-password``."""
+
+from __future__ import annotations
+
+import argparse
+import getpass
+import json
+import sys
+import tempfile
+from datetime import datetime
+from pathlib import Path
+from typing import Optional, Sequence
+
+import pandas as pd
+from sqlalchemy import select
+
+from .api import deps  # noqa: F401 - imports every domain so all tables are registered
+from .core.config import get_settings
+from .core.database import create_all, get_session_factory, init_engine
+from .domain.admin.backup import BackupService
+from .domain.admin.tenant_export import TenantExportService
+from .domain.demos.archives import check_demo_file, detect_compression
+from .domain.demos.identity import match_id_for
+from .domain.demos.parser import load_default_parser, netmsg_types
+from .domain.demos.pipeline import build_processing_input, build_processor, unpack_demo
+from .domain.demos.service import file_sha256
+from .domain.demos.trajectories import DEFAULT_TOLERANCE, build_trajectories
+from .domain.users.models import User
+from .domain.users.service import UserService
+
+
+def main(argv: Optional[Sequence[str]] = None) -> int:
+    parser = argparse.ArgumentParser(prog="stratagemforge-admin")
+    commands = parser.add_subparsers(dest="command", required=True)
+
+    backup = commands.add_parser("backup", help="Snapshot the metadata database and artefact manifest")
+    backup.add_argument("--output", type=Path, help="Archive path (default: data/backups/<timestamp>.tar.gz)")
+
+    restore = commands.add_parser("restore", help="Load a backup and check its artefacts are still in storage")
+    restore.add_argument("archive", type=Path)
+    restore.add_argument("--force", action="store_true", help="Overwrite a database that already has data")
+    restore.add_argument(
+        "--allow-missing", action="store_true", help="Exit successfully even if artefacts are missing from storage"
+    )
+
+    parse = commands.add_parser("parse", help="Run a demo through the ingestion processor without storing it")
+    parse.add_argument("demo", type=Path)
+    parse.add_argument("--output", type=Path, default=Path("parsed"), help="Directory for the parquet datasets")
+    parse.add_argument("--radar-columns", action="store_true", help="Add radar coordinates to position datasets")
+    parse.add_argument("--csv", action="store_true", help="Also write every dataset as a gzip CSV")
+    parse.add_argument(
+        "--trajectories",
+        nargs="?",
+        type=float,
+        const=DEFAULT_TOLERANCE,
+        metavar="TOLERANCE",
+        help=f"Also write simplified player paths to trajectories.json (tolerance {DEFAULT_TOLERANCE:g} units)",
+    )
+    parse.add_argument(
+        "--dump-netmsgs",
+        metavar="types=ServerInfo,GameEventList",
+        help="Also write these net messages as JSON to <output>/<demo>.netmsgs.json, for debugging the parser",
+    )
+
+    export_team = commands.add_parser("export-team", help="Package a team's matches into a downloadable archive")
+    export_team.add_argument("team_id")
+    export_team.add_argument("--include-raw", action="store_true", help="Also include the original .dem files")
+    export_team.add_argument("--bucket", help="Copy the archive to this S3 bucket")
+    export_team.add_argument("--prefix", default="", help="Key prefix inside --bucket")
+
+    create_key = commands.add_parser("create-api-key", help="Mint an API key, e.g. the first admin key")
+    create_key.add_argument("email", help="User the key belongs to")
+    create_key.add_argument("--name", default="cli", help="What the key is for")
+    create_key.add_argument(
+        "--scope", action="append", choices=["read", "upload", "admin"], help="Repeat for several scopes (default: read)"
+    )
+
+    set_password = commands.add_parser("set-password", help="Set a user's login password (prompts for it)")
+    set_password.add_argument("email")
+
+    args = parser.parse_args(argv)
+    if args.command == "parse":
+        return _parse(
+            args.demo,
+            args.output,
+            radar_columns=args.radar_columns,
+            csv=args.csv,
+            trajectories=args.trajectories,
+            dump_netmsgs=args.dump_netmsgs,
+        )
+
+    settings = get_settings()
+    init_engine(settings)
+    create_all()
+    service = BackupService(settings)
+    session = get_session_factory()()
+    try:
+        if args.command == "create-api-key":
+            users = UserService(settings)
+            users.ensure_seed(session)
+            user = session.scalars(select(User).where(User.email == args.email)).first()
+            if user is None:
+                print(f"No user with email {args.email}", file=sys.stderr)
+                return 2
+            api_key, key = users.create_api_key(session, user.id, args.name, args.scope or ["read"])
+            print(f"Created key {api_key.id} ({', '.join(api_key.scopes)}) for {user.email}:")
+            print(key)
+            return 0
+
+        if args.command == "set-password":
+            users = UserService(settings)
+            users.ensure_seed(session)
+            user = session.scalars(select(User).where(User.email == args.email)).first()
+            if user is None:
+                print(f"No user with email {args.email}", file=sys.stderr)
+                return 2
+            password = getpass.getpass(f"New password for {user.email}: ")
+            if password != getpass.getpass("Repeat it: "):
+                print("Passwords do not match", file=sys.stderr)
+                return 2
+            try:
+                users.set_password(session, user.id, password)
+            except ValueError as exc:
+                print(str(exc), file=sys.stderr)
+                return 2
+            print(f"Password set for {user.email}; their existing sessions were signed out")
+            return 0
+
+        if args.command == "export-team":
+            try:
+                exported = TenantExportService(settings).export_team(
+                    session, args.team_id, include_raw=args.include_raw, bucket=args.bucket, prefix=args.prefix
+                )
+            except LookupError as exc:
+                print(f"Export failed: {exc}", file=sys.stderr)
+                return 2
+            print(f"Wrote {exported.path}: {exported.demos} demos, {exported.files} files")
+            if exported.location:
+                print(f"Copied to {exported.location}")
+            return 0
+
+        if args.command == "backup":
+            stamp = datetime.utcnow().strftime("%Y%m%dT%H%M%SZ")
+            output = args.output or settings.data_dir / "backups" / f"stratagemforge-{stamp}.tar.gz"
+            result = service.backup(session, output)
+            print(f"Wrote {result.path}: {sum(result.tables.values())} rows, {result.artifacts} artefacts")
+            return 0
+
+        try:
+            result = service.restore(session, args.archive, force=args.force)
+        except ValueError as exc:
+            print(f"Restore failed: {exc}", file=sys.stderr)
+            return 2
+        print(f"Restored {sum(result.tables.values())} rows across {len(result.tables)} tables")
+        if result.missing_artifacts:
+            missing = len(result.missing_artifacts)
+            print(f"{missing} of {result.artifacts} artefacts are missing from storage:", file=sys.stderr)
+            for key in result.missing_artifacts:
+                print(f"  {key}", file=sys.stderr)
+            return 0 if args.allow_missing else 1
+        print(f"All {result.artifacts} artefacts present in storage")
+        return 0
+    finally:
+        session.close()
+
+
+def _parse(
+    demo: Path,
+    output: Path,
+    radar_columns: bool = False,
+    csv: bool = False,
+    trajectories: Optional[float] = None,
+    dump_netmsgs: Optional[str] = None,
+) -> int:
+    """Parse ``demo`` the way ``POST /api/demos/upload`` does and write the datasets to ``output``.
+
+    Uses the configured dataset and parquet settings (``--radar-columns`` and
+    ``--csv`` turn those options on), unpacks compressed demos and falls back
+    to the map in the file name; nothing is stored or recorded.
+    """
+
+    if not demo.is_file():
+        print(f"{demo} does not exist", file=sys.stderr)
+        return 2
+    settings = get_settings()
+    settings = settings.model_copy(
+        update={
+            "dataset_radar_columns": radar_columns or settings.dataset_radar_columns,
+            "dataset_csv_export": csv or settings.dataset_csv_export,
+        }
+    )
+    with tempfile.TemporaryDirectory() as workdir:
+        raw_path, size_bytes = demo, demo.stat().st_size
+        try:
+            if detect_compression(demo):
+                checksum, raw_path, size_bytes = unpack_demo(demo, Path(workdir), settings.max_upload_size)
+            else:
+                checksum = file_sha256(demo)
+            check_demo_file(raw_path)
+        except ValueError as exc:
+            print(f"{demo.name}: {exc}", file=sys.stderr)
+            return 2
+        if dump_netmsgs:
+            # Dumped before parsing, so it is there to look at even when extraction breaks.
+            try:
+                types = netmsg_types(dump_netmsgs)
+                parser = load_default_parser()
+                if parser is None:
+                    raise ValueError("Install the parsing or csgo extra to dump net messages")
+                messages = parser.dump_netmsgs(raw_path, types)
+            except ValueError as exc:
+                print(str(exc), file=sys.stderr)
+                return 2
+            output.mkdir(parents=True, exist_ok=True)
+            dump_path = output / f"{demo.stem}.netmsgs.json"
+            dump_path.write_text(json.dumps(messages, indent=2, default=str), encoding="utf-8")
+            print(f"{demo.name}: {', '.join(types)} written to {dump_path}")
+        result = build_processor(settings, output).process(
+            build_processing_input(
+                settings,
+                demo_id=match_id_for(checksum),
+                original_filename=demo.name,
+                checksum=checksum,
+                size_bytes=size_bytes,
+                uploaded_at=datetime.utcnow(),
+                raw_path=raw_path,
+            )
+        )
+    status = result.summary.get("parse_status")
+    print(f"{demo.name}: {status}; summary at {result.parquet_path}")
+    for name, path in result.datasets.items():
+        print(f"  {name}: {path}")
+    for path in result.csv_datasets.values():
+        print(f"  {path}")
+    if trajectories is not None and "player_ticks" in result.datasets:
+        paths = build_trajectories(pd.read_parquet(result.datasets["player_ticks"]), trajectories)
+        trajectories_path = result.datasets["player_ticks"].with_name("trajectories.json")
+        trajectories_path.write_text(json.dumps(paths, separators=(",", ":")), encoding="utf-8")
+        counts = paths["properties"]
+        print(f"  trajectories: {trajectories_path} ({counts['points']} of {counts['source_points']} points kept)")
+    return 0 if status == "parsed" else 1
+
+
+if __name__ == "__main__":  # pragma: no cover
+    sys.exit(main())
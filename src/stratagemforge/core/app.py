@@ -1,11 +1,46 @@
 from __future__ import annotations
 
-from fastapi import FastAPI
+import asyncio
+import logging
+
+from fastapi import FastAPI, Request
+from fastapi.exception_handlers import http_exception_handler
+from starlette.exceptions import HTTPException as StarletteHTTPException
 
 from ..api import deps
-from ..api.routes import analysis, demos, health, users
+from ..api.auth import AuthMiddleware
+from ..api.routes import (
+    activity,
+    admin,
+    analysis,
+    apikeys,
+    competitions,
+    dashboard,
+    demos,
+    exports,
+    faceit,
+    flags,
+    health,
+    jobs,
+    metrics,
+    notifications,
+    oidc,
+    onboarding,
+    public,
+    query,
+    schedule,
+    stats,
+    teams,
+    usage,
+    users,
+)
 from .config import Settings, get_settings
-from .database import Base, create_all, init_engine, session_scope
+from .database import Base, create_all, get_engine, init_engine, session_scope
+from .i18n import get_translator
+from .limits import MULTIPART_OVERHEAD, MaxBodySizeMiddleware
+from .tracing import configure_tracing
+
+logger = logging.getLogger(__name__)
 
 
 def create_app(settings: Settings | None = None) -> FastAPI:
@@ -16,15 +51,212 @@ def create_app(settings: Settings | None = None) -> FastAPI:
     create_all()
 
     app = FastAPI(title=settings.app_name, version=settings.version)
+    configure_tracing(settings, app=app, engine=get_engine())
+    app.add_middleware(
+        MaxBodySizeMiddleware,
+        max_bytes=settings.max_upload_size + MULTIPART_OVERHEAD,
+        paths=["/api/demos/upload", "/api/demos/upload/archive"],
+    )
+    app.add_middleware(
+        MaxBodySizeMiddleware,
+        max_bytes=settings.max_batch_files * (settings.max_upload_size + MULTIPART_OVERHEAD),
+        paths=["/api/demos/upload/batch"],
+    )
+    app.add_middleware(AuthMiddleware, settings=settings)
 
     app.include_router(health.router)
     app.include_router(demos.router)
+    app.include_router(jobs.router)
     app.include_router(analysis.router)
     app.include_router(users.router)
+    app.include_router(oidc.router)
+    app.include_router(apikeys.router)
+    app.include_router(competitions.router)
+    app.include_router(stats.router)
+    app.include_router(exports.router)
+    app.include_router(schedule.router)
+    app.include_router(dashboard.router)
+    app.include_router(onboarding.router)
+    app.include_router(teams.router)
+    app.include_router(activity.router)
+    app.include_router(notifications.router)
+    app.include_router(flags.router)
+    app.include_router(faceit.router)
+    app.include_router(usage.router)
+    app.include_router(admin.router)
+    app.include_router(public.router)
+    app.include_router(query.router)
+    if settings.metrics_enabled:
+        app.include_router(metrics.router)
+
+    translator = get_translator(settings.default_locale)
+
+    @app.exception_handler(StarletteHTTPException)
+    async def localized_http_exception(request: Request, exc: StarletteHTTPException):
+        locale = translator.negotiate(request.headers.get("accept-language"))
+        if isinstance(exc.detail, str):
+            exc.detail = translator.translate(exc.detail, locale)
+        response = await http_exception_handler(request, exc)
+        response.headers["Content-Language"] = locale
+        return response
 
     @app.on_event("startup")
     def seed_users() -> None:  # pragma: no cover - simple startup hook
         with session_scope() as session:
             deps.get_user_service().ensure_seed(session)
 
+    @app.on_event("startup")
+    async def schedule_sheets_export() -> None:  # pragma: no cover - background loop
+        interval = settings.sheets_export_interval_minutes
+        if interval > 0 and deps.get_sheets_export_service().enabled:
+            app.state.sheets_export_task = asyncio.create_task(_export_sheets_periodically(interval * 60))
+
+    @app.on_event("startup")
+    async def schedule_integrity_audit() -> None:  # pragma: no cover - background loop
+        interval = settings.integrity_audit_interval_minutes
+        if interval > 0:
+            app.state.integrity_audit_task = asyncio.create_task(_audit_storage_periodically(interval * 60))
+
+    @app.on_event("startup")
+    async def schedule_outbox_relay() -> None:  # pragma: no cover - background loop
+        interval = settings.event_outbox_poll_seconds
+        if interval > 0 and deps.get_outbox_service().enabled:
+            app.state.outbox_relay_task = asyncio.create_task(_relay_outbox_periodically(interval))
+
+    @app.on_event("startup")
+    async def schedule_alert_rules() -> None:  # pragma: no cover - background loop
+        interval = settings.alert_interval_seconds
+        if interval > 0:
+            app.state.alert_rules_task = asyncio.create_task(_evaluate_alerts_periodically(interval))
+
+    @app.on_event("startup")
+    async def schedule_trash_purge() -> None:  # pragma: no cover - background loop
+        interval = settings.trash_purge_interval_minutes
+        if interval > 0:
+            app.state.trash_purge_task = asyncio.create_task(_purge_trash_periodically(interval * 60))
+
+    @app.on_event("startup")
+    async def schedule_broadcast_recording() -> None:  # pragma: no cover - background loop
+        interval = settings.broadcast_poll_seconds
+        if interval > 0:
+            app.state.broadcast_task = asyncio.create_task(_record_broadcasts_periodically(interval))
+
+    @app.on_event("startup")
+    async def schedule_faceit_pulls() -> None:  # pragma: no cover - background loop
+        interval = settings.faceit_poll_minutes
+        if interval > 0:
+            app.state.faceit_task = asyncio.create_task(_pull_faceit_matches_periodically(interval * 60))
+
+    @app.on_event("startup")
+    async def schedule_batch_processing() -> None:  # pragma: no cover - background loop
+        interval = settings.upload_batch_poll_seconds
+        if interval > 0:
+            app.state.upload_batch_task = asyncio.create_task(_process_upload_batches_periodically(interval))
+
+    @app.on_event("shutdown")
+    async def stop_background_tasks() -> None:  # pragma: no cover - background loop
+        for name in (
+            "sheets_export_task",
+            "integrity_audit_task",
+            "outbox_relay_task",
+            "alert_rules_task",
+            "trash_purge_task",
+            "broadcast_task",
+            "faceit_task",
+            "upload_batch_task",
+        ):
+            task = getattr(app.state, name, None)
+            if task is not None:
+                task.cancel()
+
     return app
+
+
+async def _export_sheets_periodically(interval_seconds: int) -> None:  # pragma: no cover - background loop
+    service = deps.get_sheets_export_service()
+    while True:
+        await asyncio.sleep(interval_seconds)
+        try:
+            with session_scope() as session:
+                await asyncio.to_thread(service.export, session)
+        except Exception:  # noqa: BLE001 - keep the schedule alive
+            logger.exception("Scheduled Google Sheets export failed")
+
+
+async def _audit_storage_periodically(interval_seconds: int) -> None:  # pragma: no cover - background loop
+    service = deps.get_integrity_audit_service()
+    while True:
+        await asyncio.sleep(interval_seconds)
+        try:
+            with session_scope() as session:
+                await asyncio.to_thread(service.run, session)
+        except Exception:  # noqa: BLE001 - keep the schedule alive
+            logger.exception("Scheduled storage integrity audit failed")
+
+
+async def _relay_outbox_periodically(interval_seconds: int) -> None:  # pragma: no cover - background loop
+    service = deps.get_outbox_service()
+    while True:
+        await asyncio.sleep(interval_seconds)
+        try:
+            with session_scope() as session:
+                await asyncio.to_thread(service.relay, session)
+        except Exception:  # noqa: BLE001 - keep the schedule alive
+            logger.exception("Scheduled outbox relay failed")
+
+
+async def _evaluate_alerts_periodically(interval_seconds: int) -> None:  # pragma: no cover - background loop
+    service = deps.get_alert_service()
+    while True:
+        await asyncio.sleep(interval_seconds)
+        try:
+            with session_scope() as session:
+                await asyncio.to_thread(service.evaluate, session)
+        except Exception:  # noqa: BLE001 - keep the schedule alive
+            logger.exception("Scheduled alert rule evaluation failed")
+
+
+async def _purge_trash_periodically(interval_seconds: int) -> None:  # pragma: no cover - background loop
+    service = deps.get_demo_service()
+    while True:
+        await asyncio.sleep(interval_seconds)
+        try:
+            with session_scope() as session:
+                await asyncio.to_thread(service.purge_trash, session)
+        except Exception:  # noqa: BLE001 - keep the schedule alive
+            logger.exception("Scheduled trash purge failed")
+
+
+async def _record_broadcasts_periodically(interval_seconds: int) -> None:  # pragma: no cover - background loop
+    broadcasts = deps.get_broadcast_service()
+    while True:
+        await asyncio.sleep(interval_seconds)
+        try:
+            with session_scope() as session:
+                await asyncio.to_thread(broadcasts.poll_all, session)
+                for broadcast in broadcasts.ended(session):
+                    await broadcasts.finish(session, broadcast, deps.get_demo_service())
+        except Exception:  # noqa: BLE001 - keep the schedule alive
+            logger.exception("Live broadcast recording failed")
+
+
+async def _pull_faceit_matches_periodically(interval_seconds: int) -> None:  # pragma: no cover - background loop
+    faceit = deps.get_faceit_service()
+    while True:
+        await asyncio.sleep(interval_seconds)
+        try:
+            with session_scope() as session:
+                await faceit.poll_all(session, deps.get_demo_service())
+        except Exception:  # noqa: BLE001 - keep the schedule alive
+            logger.exception("Pulling FACEIT matches failed")
+
+
+async def _process_upload_batches_periodically(interval_seconds: int) -> None:  # pragma: no cover - background loop
+    service = deps.get_demo_service()
+    while True:
+        await asyncio.sleep(interval_seconds)
+        try:
+            with session_scope() as session:
+                await service.process_batches(session)
+        except Exception:  # noqa: BLE001 - keep the schedule alive
+            logger.exception("Processing batch uploads failed")
@@ -2,7 +2,7 @@ from __future__ import annotations
 
 from functools import lru_cache
 from pathlib import Path
-from typing import Any
+from typing import Any, Dict, Optional
 
 from pydantic import field_validator
 from pydantic_settings import BaseSettings, SettingsConfigDict
@@ -19,6 +19,129 @@ class Settings(BaseSettings):
     raw_dir_name: str = "uploads"
     processed_dir_name: str = "processed"
     max_upload_size: int = 1_073_741_824  # 1GB default limit
+    max_batch_files: int = 50  # demos per batch upload or zip archive
+    upload_batch_poll_seconds: int = 2  # how often queued batch uploads are picked up; 0 disables processing
+    download_timeout_seconds: float = 60.0
+    demo_filename_timezone: str = "UTC"  # timezone of recording stamps in demo file names
+    correlation_window_minutes: int = 240  # sources of one game must have been played this close together
+    correlation_min_player_overlap: float = 0.8  # Jaccard overlap of SteamIDs
+    max_concurrent_parses: int = 2
+    max_queued_parses: int = 8  # further uploads are rejected with 503; 0 queues without limit
+    parse_memory_budget_mb: int = 0  # 0 disables memory budgeting
+    parse_memory_factor: float = 4.0  # estimated parser memory per byte of demo
+    dataset_radar_columns: bool = False  # add normalised radar x/y and level columns to position datasets
+    dataset_csv_export: bool = False  # also write a gzip CSV of every dataset, for spreadsheet users
+    parquet_compression: str = "snappy"  # snappy, zstd (about half the size for tick data), gzip or none
+    parquet_compression_level: Optional[int] = None  # codec default when unset, e.g. 3 for zstd
+    parquet_row_group_rows: int = 1024 * 1024  # most rows per row group
+    parquet_page_size_bytes: int = 1024 * 1024  # target size of a data page
+    processing_max_retries: int = 2  # extra attempts after an I/O or database error
+    processing_retry_backoff_seconds: float = 2.0  # doubled after every failed attempt
+    download_max_retries: int = 3
+    outbound_allowed_hosts: str = ""  # comma-separated hosts downloads and webhooks may reach on private addresses
+    webhook_url: Optional[str] = None  # receives every processing result, besides per-upload callback URLs
+    webhook_secret: Optional[str] = None  # HMAC-SHA256 key for the signature header
+    webhook_timeout_seconds: float = 5.0
+    webhook_max_retries: int = 2
+    webhook_retry_budget_seconds: float = 15.0  # longest one delivery, retries included, may hold a worker
+    storage_backend: str = "local"  # "local" or "s3"
+    s3_bucket: Optional[str] = None
+    s3_prefix: str = ""
+    s3_endpoint_url: Optional[str] = None  # set for MinIO or other S3-compatible stores
+    s3_region: Optional[str] = None
+    s3_access_key_id: Optional[str] = None
+    s3_secret_access_key: Optional[str] = None
+    s3_presign_expiry_seconds: int = 3600
+    # Optional secondary bucket (another region or provider) that processed artefacts are copied to.
+    replica_bucket: Optional[str] = None
+    replica_prefix: str = ""
+    replica_endpoint_url: Optional[str] = None
+    replica_region: Optional[str] = None
+    replica_access_key_id: Optional[str] = None  # defaults to the primary S3 credentials
+    replica_secret_access_key: Optional[str] = None
+    # Also copy datasets to a Hive-partitioned layout (<prefix>/<dataset>/match_id=/round=) for lake engines.
+    lake_partitioned: bool = False
+    lake_prefix: str = "lake"
+    lake_row_group_rows: int = 128 * 1024
+    # Data residency: named regions teams can be pinned to, as JSON, e.g.
+    # {"eu": {"backend": "s3", "bucket": "sf-eu", "region": "eu-central-1"}, "lan": {"backend": "local", "path": "/srv"}}
+    storage_regions: Dict[str, Dict[str, Any]] = {}
+    hltv_min_interval_seconds: float = 2.0
+    steam_gc_url: Optional[str] = None  # Game Coordinator bridge resolving sharecodes to replay URLs
+    steam_api_key: Optional[str] = None  # passed to the bridge as ``key`` when set
+    faceit_api_key: Optional[str] = None  # default key of FACEIT connectors without their own
+    faceit_poll_minutes: int = 10  # how often FACEIT connectors look for finished matches; 0 disables polling
+    hltv_cache_ttl_seconds: int = 86_400
+    cache_dir_name: str = "cache"
+    sheets_spreadsheet_id: Optional[str] = None
+    sheets_credentials_file: Optional[Path] = None  # service account JSON key
+    sheets_tables: str = "scoreboard,weekly_players"
+    sheets_export_on_ingest: bool = True
+    sheets_export_interval_minutes: int = 0  # 0 disables the scheduled export
+    dashboard_cache_seconds: int = 60
+    analysis_cache_seconds: int = 300  # cross-demo analysis reports, per team, map and window
+    feature_flag_cache_seconds: int = 30
+    auth_required: bool = False  # require an API key or bearer token on every route except health, docs and token links
+    api_key_rate_limit_per_minute: int = 120  # per key or bearer-token user, unless the key has its own limit
+    jwt_jwks_url: Optional[str] = None  # user-service JWKS endpoint; bearer tokens are accepted once set
+    jwt_issuer: Optional[str] = None
+    jwt_audience: Optional[str] = None
+    jwt_algorithms: str = "RS256"  # comma separated
+    jwt_leeway_seconds: int = 30
+    jwt_jwks_cache_seconds: int = 300
+    jwt_team_claim: str = "team_id"  # claim naming the user's team, used as the owner of their uploads
+    auth_token_issuer: str = "stratagemforge"  # iss of the access tokens issued at /api/auth/login
+    auth_signing_key_file: Optional[Path] = None  # RSA PEM; generated under data_dir/auth when unset
+    access_token_minutes: int = 15
+    refresh_token_days: int = 30
+    oidc_code_seconds: int = 120  # lifetime of authorization codes from /oauth2/authorize
+    seed_user_password: Optional[str] = None  # lets the seeded analyst log in; unset leaves them without a password
+    registration_enabled: bool = True  # false is the same as REGISTRATION_MODE=invite
+    # Initial sign-up policy until an admin sets one: open, invite (token required) or domain.
+    registration_mode: str = "open"
+    registration_allowed_domains: str = ""  # comma separated, e.g. "example.com,example.org" for domain mode
+    registration_invite_days: int = 7  # default lifetime of sign-up invites
+    password_reset_minutes: int = 60
+    password_reset_url: Optional[str] = None  # link mailed for resets, e.g. https://app.example.com/reset?token={token}
+    public_url: Optional[str] = None  # external base URL of the API, for Steam's return address behind proxies
+    steam_login_redirect_url: Optional[str] = None  # frontend page receiving tokens after Steam sign-in
+    public_api_cache_seconds: int = 300  # public stats responses are cached in process and by clients
+    public_api_rate_limit_per_minute: int = 60  # per key, unless the key has its own limit
+    embed_secret: Optional[str] = None  # HMAC key for embed tokens; cards are disabled without it
+    default_locale: str = "en"  # used when Accept-Language names no supported locale
+    metrics_enabled: bool = True  # expose Prometheus metrics at /metrics
+    otel_enabled: bool = False  # requires the tracing extra
+    otel_service_name: str = "stratagemforge"
+    event_broker: Optional[str] = None  # "kafka" or "nats"; requires the events extra
+    event_broker_url: str = "localhost:9092"  # Kafka bootstrap servers or NATS URLs, comma separated
+    event_topic_prefix: str = ""  # prepended to event names such as match.processed
+    event_publish_timeout_seconds: float = 10.0
+    event_outbox_poll_seconds: int = 10  # how often pending outbox messages are retried
+    event_outbox_batch_size: int = 100
+    integrity_audit_interval_minutes: int = 0  # 0 disables the scheduled audit
+    integrity_verify_checksums: bool = True
+    usage_events_enabled: bool = True
+    alert_interval_seconds: int = 60  # how often alert rules are evaluated; 0 disables the schedule
+    # Who sees demos uploaded without a team by a signed-in user: private, team (same as private then) or public.
+    default_demo_visibility: str = "private"
+    broadcast_poll_seconds: int = 3  # how often live GOTV broadcasts are polled; 0 disables live recording
+    broadcast_segment_fragments: int = 20  # fragments between partial parquet segments (about 3s each)
+    broadcast_idle_seconds: int = 90  # a broadcast without new fragments for this long has ended
+    trash_retention_days: int = 30  # deleted demos can be restored for this long before their files are purged
+    trash_purge_interval_minutes: int = 60  # 0 disables the scheduled purge
+    smtp_host: Optional[str] = None  # email alert channels need it
+    smtp_port: int = 587
+    smtp_username: Optional[str] = None
+    smtp_password: Optional[str] = None
+    smtp_starttls: bool = True
+    smtp_from: str = "stratagemforge@localhost"
+    pagerduty_events_url: str = "https://events.pagerduty.com/v2/enqueue"
+    query_max_matches: int = 20  # matches one SQL query may read
+    query_max_rows: int = 10_000  # rows returned per query; more are cut off
+    query_timeout_seconds: float = 10.0
+    query_memory_limit_mb: int = 512  # per query
+    query_threads: int = 2  # per query
+    usage_hash_salt: Optional[str] = None  # generated under data/usage/ when unset, never left empty
 
     model_config = SettingsConfigDict(env_file=".env", env_nested_delimiter="__", case_sensitive=False)
 
@@ -29,6 +152,23 @@ class Settings(BaseSettings):
             return value
         return Path(str(value))
 
+    @field_validator("parquet_compression")
+    @classmethod
+    def _check_parquet_compression(cls, value: str) -> str:
+        value = value.lower()
+        if value == "uncompressed":
+            value = "none"
+        if value not in ("snappy", "zstd", "gzip", "none"):
+            raise ValueError("PARQUET_COMPRESSION must be snappy, zstd, gzip or none")
+        return value
+
+    @field_validator("registration_mode")
+    @classmethod
+    def _check_registration_mode(cls, value: str) -> str:
+        if value not in ("open", "invite", "domain"):
+            raise ValueError("REGISTRATION_MODE must be open, invite or domain")
+        return value
+
     @property
     def raw_data_path(self) -> Path:
         return self.data_dir / self.raw_dir_name
@@ -37,6 +177,10 @@ class Settings(BaseSettings):
     def processed_data_path(self) -> Path:
         return self.data_dir / self.processed_dir_name
 
+    @property
+    def cache_data_path(self) -> Path:
+        return self.data_dir / self.cache_dir_name
+
     def ensure_directories(self) -> None:
         for path in (self.data_dir, self.raw_data_path, self.processed_data_path):
             path.mkdir(parents=True, exist_ok=True)
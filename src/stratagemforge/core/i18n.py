@@ -0,0 +1,106 @@
+"""Translate user-visible API text according to ``Accept-Language``.
+
+Catalogs live in ``stratagemforge/locales/<locale>.json`` and map the English
+source text to its translation. Messages with variable parts use ``{name}``
+placeholders in both, e.g. ``"Team {team_id} does not exist"``; rendered
+English text is matched against these templates, so domain code keeps raising
+plain English messages and only the API boundary translates them. Text with no
+catalog entry is returned unchanged.
+"""
+
+from __future__ import annotations
+
+import json
+import re
+from functools import lru_cache
+from pathlib import Path
+from typing import Dict, List, Optional, Pattern, Tuple
+
+DEFAULT_LOCALE = "en"
+LOCALES_DIR = Path(__file__).resolve().parent.parent / "locales"
+
+_PLACEHOLDER = re.compile(r"\\\{(\w+)\\\}")
+
+
+class Translator:
+    def __init__(self, catalogs: Dict[str, Dict[str, str]], default_locale: str = DEFAULT_LOCALE) -> None:
+        self.default_locale = default_locale
+        self.catalogs = {locale.lower(): catalog for locale, catalog in catalogs.items()}
+        self._templates: Dict[str, List[Tuple[Pattern[str], str]]] = {
+            locale: _compile(catalog) for locale, catalog in self.catalogs.items()
+        }
+
+    @classmethod
+    def load(cls, directory: Path = LOCALES_DIR, default_locale: str = DEFAULT_LOCALE) -> "Translator":
+        catalogs = {path.stem: json.loads(path.read_text(encoding="utf-8")) for path in directory.glob("*.json")}
+        return cls(catalogs, default_locale=default_locale)
+
+    @property
+    def locales(self) -> List[str]:
+        return sorted({DEFAULT_LOCALE, self.default_locale, *self.catalogs})
+
+    def negotiate(self, accept_language: Optional[str]) -> str:
+        """Pick the best supported locale from an ``Accept-Language`` header."""
+
+        available = set(self.locales)
+        for tag in _preferred(accept_language or ""):
+            if tag in available:
+                return tag
+            primary = tag.split("-")[0]
+            if primary in available:
+                return primary
+        return self.default_locale
+
+    def gettext(self, msgid: str, locale: str, **params: object) -> str:
+        """Translate a catalog message and fill in its placeholders."""
+
+        template = self.catalogs.get(locale, {}).get(msgid, msgid)
+        return template.format(**params) if params else template
+
+    def translate(self, text: str, locale: str) -> str:
+        """Translate already rendered English text, keeping its variable parts."""
+
+        catalog = self.catalogs.get(locale)
+        if not catalog or not text:
+            return text
+        if text in catalog:
+            return catalog[text]
+        for pattern, translation in self._templates[locale]:
+            match = pattern.fullmatch(text)
+            if match:
+                return translation.format(**match.groupdict())
+        return text
+
+
+@lru_cache
+def get_translator(default_locale: str = DEFAULT_LOCALE) -> Translator:
+    return Translator.load(default_locale=default_locale)
+
+
+def _compile(catalog: Dict[str, str]) -> List[Tuple[Pattern[str], str]]:
+    templates = [msgid for msgid in catalog if "{" in msgid]
+    # Most literal text first so specific templates win over looser ones.
+    templates.sort(key=lambda msgid: len(re.sub(r"\{\w+\}", "", msgid)), reverse=True)
+    return [
+        (re.compile(_PLACEHOLDER.sub(r"(?P<\1>.+?)", re.escape(msgid)), re.DOTALL), catalog[msgid])
+        for msgid in templates
+    ]
+
+
+def _preferred(header: str) -> List[str]:
+    weighted = []
+    for position, part in enumerate(header.split(",")):
+        tag, _, params = part.strip().partition(";")
+        if not tag or tag == "*":
+            continue
+        quality = 1.0
+        for param in params.split(";"):
+            key, _, value = param.strip().partition("=")
+            if key == "q":
+                try:
+                    quality = float(value)
+                except ValueError:
+                    quality = 0.0
+        if quality > 0:
+            weighted.append((-quality, position, tag.lower()))
+    return [tag for _, _, tag in sorted(weighted)]
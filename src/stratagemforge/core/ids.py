@@ -0,0 +1,99 @@
+"""Identifiers for database rows.
+
+Records that are listed newest first and paged through (processing jobs,
+upload batches, activity entries, notifications, outbox messages) get
+ULIDs: 26 Crockford base32 characters whose first 10 encode the creation
+time in milliseconds, so sorting by id sorts by age and the last id of a
+page is a stable cursor for the next one. IDs made in the same millisecond
+by this process still sort in creation order. Everything else keeps random
+UUIDs, made by :func:`new_uuid` so there is one place to change them.
+"""
+
+from __future__ import annotations
+
+import os
+import re
+import threading
+import time
+from datetime import datetime
+from typing import List, Optional
+from uuid import uuid4
+
+_ALPHABET = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+_ULID_PATTERN = re.compile(r"^[0-7][0-9A-HJKMNP-TV-Z]{25}$")
+_RANDOM_BITS = 80
+
+_lock = threading.Lock()
+_last_millis = -1
+_last_random = 0
+
+
+def new_ulid(now: Optional[datetime] = None) -> str:
+    """A new ULID for the current time, or for ``now`` (naive datetimes are UTC)."""
+
+    global _last_millis, _last_random
+    if now is not None:
+        millis = int(_as_utc_timestamp(now) * 1000)
+        return _encode(millis, int.from_bytes(os.urandom(10), "big"))
+    with _lock:
+        millis = time.time_ns() // 1_000_000
+        if millis <= _last_millis:
+            # Same millisecond (or the clock stepped back): count up so ids keep their order.
+            millis = _last_millis
+            _last_random = (_last_random + 1) % (1 << _RANDOM_BITS)
+        else:
+            _last_random = int.from_bytes(os.urandom(10), "big")
+        _last_millis = millis
+        return _encode(millis, _last_random)
+
+
+def new_uuid() -> str:
+    return str(uuid4())
+
+
+def is_ulid(value: Optional[str]) -> bool:
+    return bool(value) and bool(_ULID_PATTERN.match(value.upper()))
+
+
+def ulid_time(value: str) -> datetime:
+    """When a ULID was made, as a naive UTC datetime."""
+
+    if not is_ulid(value):
+        raise ValueError(f"Not a ULID: {value!r}")
+    millis = 0
+    for char in value.upper()[:10]:
+        millis = millis * 32 + _ALPHABET.index(char)
+    return datetime.utcfromtimestamp(millis / 1000)
+
+
+def parse_cursor(value: Optional[str]) -> Optional[str]:
+    """Normalise a pagination cursor (the last id of the previous page); raises ``ValueError`` if it is not a ULID."""
+
+    if value is None:
+        return None
+    if not is_ulid(value):
+        raise ValueError("Invalid cursor")
+    return value.upper()
+
+
+def next_cursor(ids: List[str], page_size: int) -> Optional[str]:
+    """The cursor of the page after one holding ``ids``; ``None`` on the last page or for non-ULID ids."""
+
+    if len(ids) < page_size or not ids or not is_ulid(ids[-1]):
+        return None
+    return ids[-1]
+
+
+def _encode(millis: int, random: int) -> str:
+    value = (millis << _RANDOM_BITS) | random
+    chars = []
+    for _ in range(26):
+        chars.append(_ALPHABET[value & 31])
+        value >>= 5
+    return "".join(reversed(chars))
+
+
+def _as_utc_timestamp(moment: datetime) -> float:
+    if moment.tzinfo is None:
+        return (moment - datetime(1970, 1, 1)).total_seconds()
+    return moment.timestamp()
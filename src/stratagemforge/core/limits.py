@@ -0,0 +1,114 @@
+from __future__ import annotations
+
+import json
+import math
+import threading
+import time
+from dataclasses import dataclass
+from typing import Any, Awaitable, Callable, Dict, Iterable, Tuple
+
+from fastapi import HTTPException, status
+
+Scope = Dict[str, Any]
+Message = Dict[str, Any]
+Receive = Callable[[], Awaitable[Message]]
+Send = Callable[[Message], Awaitable[None]]
+
+# Room for the multipart boundaries and form fields around the demo itself.
+MULTIPART_OVERHEAD = 64 * 1024
+TOO_LARGE = "Uploaded file exceeds maximum allowed size"
+RATE_WINDOW_SECONDS = 60
+
+
+class MaxBodySizeMiddleware:
+    """Cap request bodies on upload paths before FastAPI spools them to disk.
+
+    Requests announcing a larger ``Content-Length`` are answered with ``413``
+    straight away; chunked bodies are counted as they arrive and cut off once
+    they pass ``max_bytes`` by raising a ``413`` from inside the request.
+    """
+
+    def __init__(self, app: Callable[..., Awaitable[None]], max_bytes: int, paths: Iterable[str]) -> None:
+        self.app = app
+        self.max_bytes = max_bytes
+        self.paths = frozenset(paths)
+
+    async def __call__(self, scope: Scope, receive: Receive, send: Send) -> None:
+        if scope["type"] != "http" or scope["path"] not in self.paths:
+            await self.app(scope, receive, send)
+            return
+
+        headers = dict(scope.get("headers") or [])
+        declared = headers.get(b"content-length")
+        if declared is not None and declared.isdigit() and int(declared) > self.max_bytes:
+            await _reject(send)
+            return
+
+        received = 0
+
+        async def limited_receive() -> Message:
+            nonlocal received
+            message = await receive()
+            if message["type"] == "http.request":
+                received += len(message.get("body", b""))
+                if received > self.max_bytes:
+                    raise HTTPException(status_code=status.HTTP_413_REQUEST_ENTITY_TOO_LARGE, detail=TOO_LARGE)
+            return message
+
+        await self.app(scope, limited_receive, send)
+
+
+async def _reject(send: Send) -> None:
+    body = json.dumps({"detail": TOO_LARGE}).encode()
+    await send(
+        {
+            "type": "http.response.start",
+            "status": 413,
+            "headers": [(b"content-type", b"application/json"), (b"content-length", str(len(body)).encode())],
+        }
+    )
+    await send({"type": "http.response.body", "body": body})
+
+
+class RateLimitExceededError(RuntimeError):
+    """Raised when an API key used up its requests for the current window."""
+
+    def __init__(self, quota: "RateLimitStatus") -> None:
+        super().__init__("Rate limit exceeded")
+        self.quota = quota
+
+
+@dataclass(frozen=True)
+class RateLimitStatus:
+    limit: int
+    remaining: int
+    reset_seconds: int
+
+    def headers(self) -> Dict[str, str]:
+        return {
+            "X-RateLimit-Limit": str(self.limit),
+            "X-RateLimit-Remaining": str(self.remaining),
+            "X-RateLimit-Reset": str(self.reset_seconds),
+        }
+
+
+class RateLimiter:
+    """Fixed one-minute windows per key, kept in process memory."""
+
+    def __init__(self, clock: Callable[[], float] = time.monotonic) -> None:
+        self._clock = clock
+        self._windows: Dict[str, Tuple[int, int]] = {}
+        self._lock = threading.Lock()
+
+    def hit(self, key: str, limit: int) -> RateLimitStatus:
+        now = self._clock()
+        window = int(now // RATE_WINDOW_SECONDS)
+        reset = math.ceil((window + 1) * RATE_WINDOW_SECONDS - now)
+        with self._lock:
+            started, count = self._windows.get(key, (window, 0))
+            if started != window:
+                count = 0
+            if count >= limit:
+                raise RateLimitExceededError(RateLimitStatus(limit, 0, reset))
+            self._windows[key] = (window, count + 1)
+        return RateLimitStatus(limit, limit - count - 1, reset)
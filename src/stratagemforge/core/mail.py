@@ -0,0 +1,24 @@
+from __future__ import annotations
+
+import smtplib
+from email.message import EmailMessage
+
+from .config import Settings
+
+
+def send_email(settings: Settings, to: str, subject: str, body: str) -> None:
+    """Send a plain-text email through ``SMTP_HOST``; raises ``ValueError`` when it is not configured."""
+
+    if not settings.smtp_host:
+        raise ValueError("SMTP is not configured")
+    message = EmailMessage()
+    message["Subject"] = subject
+    message["From"] = settings.smtp_from
+    message["To"] = to
+    message.set_content(body)
+    with smtplib.SMTP(settings.smtp_host, settings.smtp_port, timeout=10) as smtp:
+        if settings.smtp_starttls:
+            smtp.starttls()
+        if settings.smtp_username:
+            smtp.login(settings.smtp_username, settings.smtp_password or "")
+        smtp.send_message(message)
@@ -0,0 +1,54 @@
+from __future__ import annotations
+
+from typing import Callable, Dict
+
+from prometheus_client import CONTENT_TYPE_LATEST, REGISTRY, Counter, Gauge, Histogram, generate_latest
+
+__all__ = ["CONTENT_TYPE_LATEST", "render", "track_parse_queue"]
+
+DEMOS_PROCESSED = Counter(
+    "stratagemforge_demos_processed_total",
+    "Demos run through the processor, by parse outcome (parsed, failed or skipped).",
+    ["status"],
+)
+PARSE_DURATION = Histogram(
+    "stratagemforge_parse_duration_seconds",
+    "Time spent parsing a demo file.",
+    buckets=(1, 2.5, 5, 10, 20, 30, 60, 120, 300),
+)
+PARSE_FAILURES = Counter(
+    "stratagemforge_parse_failures_total",
+    "Demos the parser rejected, by exception type.",
+    ["error_type"],
+)
+PARSE_TICKS_PER_SECOND = Histogram(
+    "stratagemforge_parse_ticks_per_second",
+    "Demo ticks parsed per second of parse time.",
+    buckets=(1_000, 5_000, 10_000, 25_000, 50_000, 100_000, 250_000),
+)
+PARQUET_BYTES_WRITTEN = Counter(
+    "stratagemforge_parquet_bytes_written_total",
+    "Bytes of parquet artefacts written by the processor.",
+)
+BROADCAST_FRAGMENTS = Counter(
+    "stratagemforge_broadcast_fragments_total",
+    "GOTV broadcast fragments recorded from live matches.",
+)
+PARSE_QUEUE_DEPTH = Gauge("stratagemforge_parse_queue_depth", "Demos waiting for a parse slot.")
+PARSES_IN_FLIGHT = Gauge("stratagemforge_parses_in_flight", "Demos currently being parsed.")
+INTEGRITY_ISSUES = Gauge(
+    "stratagemforge_integrity_issues",
+    "Storage drift found by the most recent integrity audit, by kind.",
+    ["kind"],
+)
+
+
+def track_parse_queue(snapshot: Callable[[], Dict[str, int]]) -> None:
+    """Report queue depth and in-flight parses from a limiter's ``snapshot``."""
+
+    PARSE_QUEUE_DEPTH.set_function(lambda: snapshot()["waiting"])
+    PARSES_IN_FLIGHT.set_function(lambda: snapshot()["running"])
+
+
+def render() -> bytes:
+    return generate_latest(REGISTRY)
@@ -0,0 +1,117 @@
+"""Keep requests to user-supplied URLs away from internal services.
+
+Demo downloads and webhook deliveries go to URLs callers choose, so without
+a check a caller could make the server fetch ``http://169.254.169.254/`` or
+an admin port on localhost. ``check_public_url`` resolves a URL's host and
+refuses it unless every address is public; ``public_opener`` does the same
+for the address each connection actually reaches, on every redirect hop, so
+a redirect or a DNS answer that changes between the check and the request
+cannot get around it. Hosts listed in ``OUTBOUND_ALLOWED_HOSTS`` (a demo
+mirror on the local network, say) are exempt.
+"""
+
+from __future__ import annotations
+
+import functools
+import http.client
+import ipaddress
+import socket
+from typing import Optional
+from urllib.parse import urlparse
+from urllib.request import HTTPHandler, HTTPRedirectHandler, HTTPSHandler, OpenerDirector, build_opener
+
+SCHEMES = frozenset({"http", "https"})
+
+
+class NonPublicAddressError(ValueError):
+    """The URL leads to a loopback, private, link-local or otherwise internal address."""
+
+
+def allowed_hosts(value: Optional[str]) -> frozenset[str]:
+    """The hosts of a comma-separated ``OUTBOUND_ALLOWED_HOSTS`` value, lower-cased."""
+
+    return frozenset(host.strip().lower() for host in (value or "").split(",") if host.strip())
+
+
+def is_public_address(address: str) -> bool:
+    ip = ipaddress.ip_address(address.split("%", 1)[0])
+    if isinstance(ip, ipaddress.IPv6Address) and ip.ipv4_mapped:
+        ip = ip.ipv4_mapped
+    return ip.is_global and not ip.is_multicast
+
+
+def check_public_url(url: str, allowed: frozenset[str] = frozenset()) -> None:
+    """Raise ``ValueError`` unless ``url`` is http(s) and its host resolves only to public addresses."""
+
+    parsed = urlparse(url)
+    if parsed.scheme not in SCHEMES or not parsed.hostname:
+        raise ValueError("Only http and https URLs are supported")
+    host = parsed.hostname.lower()
+    if host in allowed:
+        return
+    try:
+        addresses = {info[4][0] for info in socket.getaddrinfo(host, None, type=socket.SOCK_STREAM)}
+    except (socket.gaierror, UnicodeError) as exc:
+        raise ValueError(f"Cannot resolve host {host}") from exc
+    if not all(is_public_address(address) for address in addresses):
+        raise NonPublicAddressError(f"{host} is not a public address")
+
+
+def public_opener(allowed: frozenset[str] = frozenset()) -> OpenerDirector:
+    """A ``urllib`` opener that only connects to public addresses and only follows http(s) redirects."""
+
+    return build_opener(_PublicHTTPHandler(allowed), _PublicHTTPSHandler(allowed), _PublicRedirectHandler(allowed))
+
+
+class _PublicConnectionMixin:
+    allowed: frozenset[str] = frozenset()
+
+    def connect(self) -> None:
+        super().connect()
+        if self.host.lower() in self.allowed:
+            return
+        address = self.sock.getpeername()[0]
+        if not is_public_address(address):
+            self.sock.close()
+            raise NonPublicAddressError(f"{self.host} is not a public address")
+
+
+class _PublicHTTPConnection(_PublicConnectionMixin, http.client.HTTPConnection):
+    def __init__(self, *args, allowed: frozenset[str], **kwargs) -> None:
+        super().__init__(*args, **kwargs)
+        self.allowed = allowed
+
+
+class _PublicHTTPSConnection(_PublicConnectionMixin, http.client.HTTPSConnection):
+    def __init__(self, *args, allowed: frozenset[str], **kwargs) -> None:
+        super().__init__(*args, **kwargs)
+        self.allowed = allowed
+
+
+class _PublicHTTPHandler(HTTPHandler):
+    def __init__(self, allowed: frozenset[str]) -> None:
+        super().__init__()
+        self.allowed = allowed
+
+    def http_open(self, req):
+        return self.do_open(functools.partial(_PublicHTTPConnection, allowed=self.allowed), req)
+
+
+class _PublicHTTPSHandler(HTTPSHandler):
+    def __init__(self, allowed: frozenset[str]) -> None:
+        super().__init__()
+        self.allowed = allowed
+
+    def https_open(self, req):
+        return self.do_open(functools.partial(_PublicHTTPSConnection, allowed=self.allowed), req, context=self._context)
+
+
+class _PublicRedirectHandler(HTTPRedirectHandler):
+    def __init__(self, allowed: frozenset[str]) -> None:
+        super().__init__()
+        self.allowed = allowed
+
+    def redirect_request(self, req, fp, code, msg, headers, newurl):
+        # Fail fast on a redirect to an internal name; the connection check still covers DNS changes.
+        check_public_url(newurl, self.allowed)
+        return super().redirect_request(req, fp, code, msg, headers, newurl)
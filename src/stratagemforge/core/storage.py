@@ -0,0 +1,278 @@
+from __future__ import annotations
+
+import shutil
+from pathlib import Path
+from typing import Any, Dict, Mapping, Optional, Protocol, Tuple
+
+from .config import Settings
+
+
+class ArtifactStorage(Protocol):
+    """Where processed artefacts live once written.
+
+    Keys are POSIX-style paths relative to the processed data directory, e.g.
+    ``<demo_id>.parquet`` or ``<demo_id>/round_summary.parquet``.
+    """
+
+    name: str
+
+    def put(self, path: Path, key: str) -> str:
+        """Store the local file ``path`` under ``key`` and return the key."""
+
+    def get(self, key: str) -> Path:
+        """Return a local path to read ``key`` from, fetching it if needed."""
+
+    def exists(self, key: str) -> bool:
+        ...
+
+    def size(self, key: str) -> Optional[int]:
+        """Return the stored object's size in bytes, or ``None`` if it is missing."""
+
+    def delete(self, key: str) -> None:
+        ...
+
+    def url(self, key: str, expires_in: Optional[int] = None) -> Optional[str]:
+        """Return a direct download URL if the backend supports one."""
+
+
+class LocalStorage:
+    """Keep artefacts on the local filesystem beneath ``root``."""
+
+    name = "local"
+
+    def __init__(self, root: Path) -> None:
+        self.root = root
+        self.root.mkdir(parents=True, exist_ok=True)
+
+    def put(self, path: Path, key: str) -> str:
+        target = self.get(key)
+        if path.resolve() != target.resolve():
+            target.parent.mkdir(parents=True, exist_ok=True)
+            shutil.copyfile(path, target)
+        return key
+
+    def get(self, key: str) -> Path:
+        # Absolute keys (recorded before storage backends existed) resolve to themselves.
+        return self.root / key
+
+    def exists(self, key: str) -> bool:
+        return self.get(key).exists()
+
+    def size(self, key: str) -> Optional[int]:
+        path = self.get(key)
+        return path.stat().st_size if path.exists() else None
+
+    def delete(self, key: str) -> None:
+        self.get(key).unlink(missing_ok=True)
+
+    def url(self, key: str, expires_in: Optional[int] = None) -> Optional[str]:
+        return None
+
+
+class S3Storage:
+    """Store artefacts in an S3-compatible bucket (AWS S3, MinIO, ...).
+
+    Files are uploaded after processing and cached locally in ``cache_dir`` so
+    repeated reads on the same replica do not hit the bucket again.
+    """
+
+    name = "s3"
+
+    def __init__(
+        self,
+        bucket: str,
+        cache_dir: Path,
+        prefix: str = "",
+        endpoint_url: Optional[str] = None,
+        region: Optional[str] = None,
+        access_key_id: Optional[str] = None,
+        secret_access_key: Optional[str] = None,
+        presign_expiry_seconds: int = 3600,
+    ) -> None:
+        import boto3
+
+        self.bucket = bucket
+        self.cache_dir = cache_dir
+        self.prefix = prefix.strip("/")
+        self.presign_expiry_seconds = presign_expiry_seconds
+        self.client = boto3.client(
+            "s3",
+            endpoint_url=endpoint_url,
+            region_name=region,
+            aws_access_key_id=access_key_id,
+            aws_secret_access_key=secret_access_key,
+        )
+        self.cache_dir.mkdir(parents=True, exist_ok=True)
+
+    def object_key(self, key: str) -> str:
+        return f"{self.prefix}/{key}" if self.prefix else key
+
+    def put(self, path: Path, key: str) -> str:
+        self.client.upload_file(str(path), self.bucket, self.object_key(key))
+        return key
+
+    def get(self, key: str) -> Path:
+        local = self.cache_dir / key
+        if not local.exists():
+            local.parent.mkdir(parents=True, exist_ok=True)
+            self.client.download_file(self.bucket, self.object_key(key), str(local))
+        return local
+
+    def exists(self, key: str) -> bool:
+        return self.size(key) is not None
+
+    def size(self, key: str) -> Optional[int]:
+        from botocore.exceptions import ClientError
+
+        try:
+            head = self.client.head_object(Bucket=self.bucket, Key=self.object_key(key))
+        except ClientError:
+            return None
+        return int(head["ContentLength"])
+
+    def delete(self, key: str) -> None:
+        self.client.delete_object(Bucket=self.bucket, Key=self.object_key(key))
+        (self.cache_dir / key).unlink(missing_ok=True)
+
+    def url(self, key: str, expires_in: Optional[int] = None) -> Optional[str]:
+        return self.client.generate_presigned_url(
+            "get_object",
+            Params={"Bucket": self.bucket, "Key": self.object_key(key)},
+            ExpiresIn=expires_in or self.presign_expiry_seconds,
+        )
+
+
+class RoutedStorage:
+    """Route keys between the default backend and per-region backends.
+
+    Teams with data residency requirements are assigned a region from
+    ``STORAGE_REGIONS``; their artefacts are stored under ``@<region>/<key>``,
+    which this class sends to that region's backend. Every other key goes to
+    the default backend, so code that only passes keys around keeps working.
+    """
+
+    def __init__(self, default: ArtifactStorage, regions: Mapping[str, ArtifactStorage]) -> None:
+        self.default = default
+        self.regions = dict(regions)
+
+    @property
+    def name(self) -> str:
+        return self.default.name
+
+    @staticmethod
+    def region_key(region: Optional[str], key: str) -> str:
+        return f"@{region}/{key}" if region else key
+
+    @staticmethod
+    def region_of(key: str) -> Optional[str]:
+        return key[1:].split("/", 1)[0] if key.startswith("@") else None
+
+    def backend(self, region: Optional[str]) -> ArtifactStorage:
+        if region is None:
+            return self.default
+        if region not in self.regions:
+            raise ValueError(f"Storage region {region} is not configured")
+        return self.regions[region]
+
+    def put(self, path: Path, key: str) -> str:
+        backend, inner = self._route(key)
+        backend.put(path, inner)
+        return key
+
+    def get(self, key: str) -> Path:
+        backend, inner = self._route(key)
+        return backend.get(inner)
+
+    def exists(self, key: str) -> bool:
+        backend, inner = self._route(key)
+        return backend.exists(inner)
+
+    def size(self, key: str) -> Optional[int]:
+        backend, inner = self._route(key)
+        return backend.size(inner)
+
+    def delete(self, key: str) -> None:
+        backend, inner = self._route(key)
+        backend.delete(inner)
+
+    def url(self, key: str, expires_in: Optional[int] = None) -> Optional[str]:
+        backend, inner = self._route(key)
+        return backend.url(inner, expires_in)
+
+    def _route(self, key: str) -> Tuple[ArtifactStorage, str]:
+        region = self.region_of(key)
+        if region is None:
+            return self.default, key
+        return self.backend(region), key.split("/", 1)[1]
+
+
+def build_storage(settings: Settings) -> ArtifactStorage:
+    """Instantiate the storage backend selected by ``STORAGE_BACKEND``, plus any ``STORAGE_REGIONS``."""
+
+    default = _build_default_storage(settings)
+    if not settings.storage_regions:
+        return default
+    regions = {
+        name: _build_region_storage(settings, name, config) for name, config in settings.storage_regions.items()
+    }
+    return RoutedStorage(default, regions)
+
+
+def _build_region_storage(settings: Settings, name: str, config: Dict[str, Any]) -> ArtifactStorage:
+    """One ``STORAGE_REGIONS`` entry: ``{"backend": "local", "path": ...}`` or ``{"backend": "s3", "bucket": ...}``."""
+
+    backend = str(config.get("backend", "s3")).lower()
+    if backend == "local":
+        if not config.get("path"):
+            raise ValueError(f"Storage region {name} needs a path")
+        return LocalStorage(Path(config["path"]))
+    if backend == "s3":
+        if not config.get("bucket"):
+            raise ValueError(f"Storage region {name} needs a bucket")
+        return S3Storage(
+            bucket=config["bucket"],
+            cache_dir=settings.cache_data_path / "regions" / name,
+            prefix=config.get("prefix", ""),
+            endpoint_url=config.get("endpoint_url"),
+            region=config.get("region"),
+            access_key_id=config.get("access_key_id") or settings.s3_access_key_id,
+            secret_access_key=config.get("secret_access_key") or settings.s3_secret_access_key,
+            presign_expiry_seconds=settings.s3_presign_expiry_seconds,
+        )
+    raise ValueError(f"Unknown storage backend for region {name}: {backend}")
+
+
+def _build_default_storage(settings: Settings) -> ArtifactStorage:
+    backend = settings.storage_backend.lower()
+    if backend == "local":
+        return LocalStorage(settings.processed_data_path)
+    if backend == "s3":
+        if not settings.s3_bucket:
+            raise ValueError("S3_BUCKET must be set when STORAGE_BACKEND=s3")
+        return S3Storage(
+            bucket=settings.s3_bucket,
+            cache_dir=settings.processed_data_path,
+            prefix=settings.s3_prefix,
+            endpoint_url=settings.s3_endpoint_url,
+            region=settings.s3_region,
+            access_key_id=settings.s3_access_key_id,
+            secret_access_key=settings.s3_secret_access_key,
+            presign_expiry_seconds=settings.s3_presign_expiry_seconds,
+        )
+    raise ValueError(f"Unknown storage backend: {settings.storage_backend}")
+
+
+def build_replica_storage(settings: Settings) -> Optional[ArtifactStorage]:
+    """Instantiate the secondary bucket configured by ``REPLICA_BUCKET``, if any."""
+
+    if not settings.replica_bucket:
+        return None
+    return S3Storage(
+        bucket=settings.replica_bucket,
+        cache_dir=settings.cache_data_path / "replica",
+        prefix=settings.replica_prefix,
+        endpoint_url=settings.replica_endpoint_url,
+        region=settings.replica_region,
+        access_key_id=settings.replica_access_key_id or settings.s3_access_key_id,
+        secret_access_key=settings.replica_secret_access_key or settings.s3_secret_access_key,
+    )
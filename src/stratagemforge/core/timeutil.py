@@ -0,0 +1,53 @@
+"""Timestamp conventions shared by every domain.
+
+The database stores naive datetimes that are always UTC. Values entering the
+system (request bodies, query parameters, imported files) are normalised with
+:func:`to_utc_naive`, and API responses render them with an explicit ``Z`` so
+clients never have to guess. Local time only appears at the edges, through a
+user's or team's IANA timezone.
+"""
+
+from __future__ import annotations
+
+from datetime import datetime, timezone
+from typing import Annotated, Optional
+from zoneinfo import ZoneInfo, ZoneInfoNotFoundError
+
+from pydantic import AfterValidator, PlainSerializer
+
+UTC = timezone.utc
+
+
+def to_utc_naive(value: datetime) -> datetime:
+    """Convert ``value`` to the naive UTC form stored in the database; naive input is taken as UTC."""
+
+    if value.tzinfo is None:
+        return value
+    return value.astimezone(UTC).replace(tzinfo=None)
+
+
+def as_utc(value: datetime) -> datetime:
+    """Attach UTC to a stored naive timestamp (aware values are converted)."""
+
+    return value.replace(tzinfo=UTC) if value.tzinfo is None else value.astimezone(UTC)
+
+
+def isoformat_utc(value: datetime) -> str:
+    return as_utc(value).isoformat().replace("+00:00", "Z")
+
+
+def resolve_timezone(name: Optional[str]) -> ZoneInfo:
+    """Look up an IANA timezone such as ``Europe/Berlin``; ``None`` means UTC."""
+
+    try:
+        return ZoneInfo(name or "UTC")
+    except (ZoneInfoNotFoundError, ValueError):
+        raise ValueError(f"Unknown timezone {name}") from None
+
+
+def to_local(value: datetime, tz: ZoneInfo) -> datetime:
+    return as_utc(value).astimezone(tz)
+
+
+# Datetime field for API schemas: stored as naive UTC, rendered as ``...Z``.
+UtcDateTime = Annotated[datetime, AfterValidator(to_utc_naive), PlainSerializer(isoformat_utc, when_used="json")]
@@ -0,0 +1,78 @@
+"""Validate bearer JWTs issued by the user service.
+
+Signing keys come from ``JWT_JWKS_URL`` and are cached for
+``JWT_JWKS_CACHE_SECONDS``; a token signed with a key id that is not cached
+triggers one refetch, so key rotation needs no restart. Issuer, audience and
+expiry are checked when configured, with ``JWT_LEEWAY_SECONDS`` of clock skew.
+Without a JWKS URL the users module passes its own signing key in, so the
+tokens issued by ``/api/auth/login`` are accepted. Tokens whose ``token_use``
+claim is not ``access``, such as OIDC ID tokens signed with the same key, are
+rejected; validators of our own tokens also reject tokens without the claim.
+"""
+
+from __future__ import annotations
+
+from typing import Any, Callable, Dict, List, Optional
+
+import jwt
+
+from .config import Settings
+
+KeyResolver = Callable[[str], Any]
+
+ACCESS_TOKEN_USE = "access"
+
+
+class JwtValidator:
+    def __init__(
+        self,
+        settings: Settings,
+        key_resolver: Optional[KeyResolver] = None,
+        issuer: Optional[str] = None,
+        require_token_use: bool = False,
+    ) -> None:
+        self.settings = settings
+        self.issuer = issuer or settings.jwt_issuer
+        self.require_token_use = require_token_use
+        self._key_resolver = key_resolver
+
+    @property
+    def enabled(self) -> bool:
+        return self._key_resolver is not None or bool(self.settings.jwt_jwks_url)
+
+    @property
+    def algorithms(self) -> List[str]:
+        return [name.strip() for name in self.settings.jwt_algorithms.split(",") if name.strip()]
+
+    def validate(self, token: str) -> Dict[str, Any]:
+        """Return the claims of a valid token; raises ``PermissionError`` otherwise."""
+
+        if not self.enabled:
+            raise PermissionError("Bearer tokens are not accepted")
+        try:
+            key = self._resolve_key(token)
+            claims = jwt.decode(
+                token,
+                key,
+                algorithms=self.algorithms,
+                audience=self.settings.jwt_audience,
+                issuer=self.issuer,
+                leeway=self.settings.jwt_leeway_seconds,
+                options={"require": ["exp", "sub"], "verify_aud": self.settings.jwt_audience is not None},
+            )
+        except jwt.ExpiredSignatureError as exc:
+            raise PermissionError("Bearer token has expired") from exc
+        except jwt.PyJWTError as exc:
+            raise PermissionError("Invalid bearer token") from exc
+        token_use = claims.get("token_use")
+        if token_use != ACCESS_TOKEN_USE and (token_use is not None or self.require_token_use):
+            raise PermissionError("Invalid bearer token")
+        return claims
+
+    def _resolve_key(self, token: str) -> Any:
+        if self._key_resolver is None:
+            client = jwt.PyJWKClient(
+                self.settings.jwt_jwks_url, cache_keys=True, lifespan=self.settings.jwt_jwks_cache_seconds
+            )
+            self._key_resolver = lambda raw: client.get_signing_key_from_jwt(raw).key
+        return self._key_resolver(token)
@@ -0,0 +1,88 @@
+"""OpenTelemetry tracing for the ingestion pipeline.
+
+Spans are only recorded when the ``tracing`` extra is installed and
+``OTEL_ENABLED`` is set; otherwise :func:`span` is a no-op so call sites never
+need to check. Spans are exported over OTLP/HTTP to ``OTEL_EXPORTER_OTLP_ENDPOINT``.
+"""
+
+from __future__ import annotations
+
+import logging
+from contextlib import contextmanager, nullcontext
+from typing import Any, ContextManager, Iterator, Optional
+
+from .config import Settings
+
+logger = logging.getLogger(__name__)
+
+try:  # pragma: no cover - depends on the optional extra
+    from opentelemetry import trace
+except ImportError:  # pragma: no cover - tracing extra not installed
+    trace = None
+
+_TRACER_NAME = "stratagemforge"
+_configured = False
+
+
+def configure_tracing(settings: Settings, app: Any = None, engine: Any = None) -> bool:
+    """Install the OTLP exporter and instrument FastAPI and SQLAlchemy.
+
+    Returns whether tracing is active. Safe to call more than once; the
+    tracer provider is only installed the first time.
+    """
+
+    global _configured
+    if not settings.otel_enabled:
+        return False
+    if trace is None:
+        logger.warning("OTEL_ENABLED is set but the tracing extra is not installed; spans are not exported")
+        return False
+
+    if not _configured:  # pragma: no cover - exercised against a collector
+        from opentelemetry.exporter.otlp.proto.http.trace_exporter import OTLPSpanExporter
+        from opentelemetry.sdk.resources import Resource
+        from opentelemetry.sdk.trace import TracerProvider
+        from opentelemetry.sdk.trace.export import BatchSpanProcessor
+
+        provider = TracerProvider(
+            resource=Resource.create({"service.name": settings.otel_service_name, "service.version": settings.version})
+        )
+        # The exporter reads the standard OTEL_EXPORTER_OTLP_* variables itself.
+        provider.add_span_processor(BatchSpanProcessor(OTLPSpanExporter()))
+        trace.set_tracer_provider(provider)
+        _configured = True
+
+    if app is not None:  # pragma: no cover - exercised against a collector
+        from opentelemetry.instrumentation.fastapi import FastAPIInstrumentor
+
+        FastAPIInstrumentor.instrument_app(app, excluded_urls="/metrics,/health")
+    if engine is not None:  # pragma: no cover - exercised against a collector
+        from opentelemetry.instrumentation.sqlalchemy import SQLAlchemyInstrumentor
+
+        SQLAlchemyInstrumentor().instrument(engine=engine)
+    return True
+
+
+def span(name: str, **attributes: Any) -> ContextManager[Optional[Any]]:
+    """Start a span named ``name`` with ``attributes`` (``None`` values are dropped)."""
+
+    if trace is None:
+        return nullcontext()
+    return _span(name, {key: value for key, value in attributes.items() if value is not None})
+
+
+@contextmanager
+def _span(name: str, attributes: dict) -> Iterator[Any]:
+    with trace.get_tracer(_TRACER_NAME).start_as_current_span(name, attributes=attributes) as current:
+        yield current
+
+
+def set_attributes(**attributes: Any) -> None:
+    """Add attributes to the current span, if any."""
+
+    if trace is None:
+        return
+    current = trace.get_current_span()
+    for key, value in attributes.items():
+        if value is not None:
+            current.set_attribute(key, value)
@@ -0,0 +1,37 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Any, Dict, Optional
+
+from sqlalchemy import Boolean, DateTime, ForeignKey, Index, JSON, String
+from sqlalchemy.orm import Mapped, mapped_column
+
+from ...core.database import Base
+from ...core.ids import new_ulid
+from ..users.models import User  # noqa: F401 - registers the users FK target
+
+
+class ActivityEntry(Base):
+    """Something a user did, e.g. uploaded a demo or commented on a round, for activity feeds.
+
+    Entries with a ``team_id`` show on the team's feed for the members its
+    ``view`` permission allows; ``private`` entries (about private demos)
+    only show to the actor and admins.
+    """
+
+    __tablename__ = "activity_entries"
+    __table_args__ = (
+        Index("ix_activity_entries_team_created", "team_id", "created_at"),
+        Index("ix_activity_entries_actor_created", "actor_id", "created_at"),
+    )
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_ulid)
+    actor_id: Mapped[str] = mapped_column(String(36), ForeignKey("users.id"), nullable=False)
+    team_id: Mapped[Optional[str]] = mapped_column(String(36))
+    verb: Mapped[str] = mapped_column(String(64), nullable=False, index=True)
+    # What the activity is about, e.g. ("demo", <demo id>) or ("team", <team id>).
+    subject_type: Mapped[str] = mapped_column(String(32), nullable=False)
+    subject_id: Mapped[Optional[str]] = mapped_column(String(64))
+    details: Mapped[Dict[str, Any]] = mapped_column(JSON, default=dict)
+    private: Mapped[bool] = mapped_column(Boolean, default=False, nullable=False)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False, index=True)
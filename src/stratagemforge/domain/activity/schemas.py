@@ -0,0 +1,44 @@
+from __future__ import annotations
+
+from typing import Any, Dict, List, Literal, Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+# Activity the clients record themselves; the server records everything else.
+ClientVerb = Literal["strategy.created", "comment.created", "review.requested", "review.completed"]
+
+
+class ActivitySummary(BaseModel):
+    id: str
+    actor_id: str
+    team_id: Optional[str] = None
+    verb: str
+    subject_type: str
+    subject_id: Optional[str] = None
+    details: Dict[str, Any] = Field(default_factory=dict)
+    private: bool = False
+    created_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class ActivityCreate(BaseModel):
+    verb: ClientVerb
+    subject_type: str = Field(min_length=1, max_length=32, description="e.g. strategy, comment or demo")
+    subject_id: Optional[str] = Field(default=None, max_length=64)
+    team_id: Optional[str] = Field(default=None, description="Team feed to show the entry on; the caller must be on it")
+    details: Dict[str, Any] = Field(
+        default_factory=dict, description='Shown with the entry, e.g. {"demo_id": "...", "round": 14}'
+    )
+
+
+class ActivityCollection(BaseModel):
+    entries: List[ActivitySummary]
+    count: int
+    total: int
+    page: int = 1
+    page_size: int
+    next_cursor: Optional[str] = Field(default=None, description="Pass as cursor for the next page")
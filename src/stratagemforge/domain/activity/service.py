@@ -0,0 +1,179 @@
+from __future__ import annotations
+
+import json
+import logging
+from typing import Any, Dict, List, Optional, Sequence, Tuple
+
+from sqlalchemy import and_, func, or_, select, true, update
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.ids import parse_cursor
+from ..demos.models import Demo
+from ..teams.service import TeamService
+from .models import ActivityEntry
+
+logger = logging.getLogger(__name__)
+
+# Upper bound on the JSON size of an entry's details, so feeds stay cheap to page through.
+MAX_DETAILS_BYTES = 2000
+
+
+class ActivityService:
+    """Activity feeds of users and teams: who uploaded, shared or reviewed what, newest first.
+
+    The server records demo and roster changes; clients record what only they
+    know about (strategies, comments, reviews). Every feed shows a viewer only
+    their own entries, non-private entries of the teams whose ``view``
+    permission they have and, on a user's feed, that user's entries outside
+    any team. Admins see everything.
+    """
+
+    def __init__(self, settings: Settings, teams: TeamService) -> None:
+        self.settings = settings
+        self.teams = teams
+
+    def record(
+        self,
+        session: Session,
+        actor_id: str,
+        verb: str,
+        subject_type: str,
+        subject_id: Optional[str] = None,
+        *,
+        team_id: Optional[str] = None,
+        details: Optional[Dict[str, Any]] = None,
+        private: bool = False,
+    ) -> ActivityEntry:
+        if len(json.dumps(details or {}, default=str)) > MAX_DETAILS_BYTES:
+            raise ValueError(f"Activity details are limited to {MAX_DETAILS_BYTES} bytes of JSON")
+        entry = ActivityEntry(
+            actor_id=actor_id,
+            team_id=team_id,
+            verb=verb,
+            subject_type=subject_type,
+            subject_id=subject_id,
+            details=details or {},
+            private=private,
+        )
+        session.add(entry)
+        session.commit()
+        return entry
+
+    def record_client_activity(
+        self,
+        session: Session,
+        actor_id: str,
+        verb: str,
+        subject_type: str,
+        subject_id: Optional[str] = None,
+        *,
+        team_id: Optional[str] = None,
+        details: Optional[Dict[str, Any]] = None,
+        admin: bool = False,
+    ) -> ActivityEntry:
+        """Record activity reported by a client; it goes on a team's feed only if the actor is on the team."""
+
+        if team_id and not admin and not self.teams.membership(session, team_id, actor_id):
+            raise PermissionError("You are not on this team")
+        # Without a team there is nobody to share e.g. a draft strategy with.
+        return self.record(
+            session, actor_id, verb, subject_type, subject_id, team_id=team_id, details=details, private=not team_id
+        )
+
+    def record_demo(self, session: Session, actor_id: Optional[str], verb: str, demo: Demo, **details: Any) -> None:
+        """Record ``verb`` (e.g. ``demo.deleted``) about ``demo``; anonymous callers leave no entry."""
+
+        if not actor_id:
+            return
+        details = {"filename": demo.original_filename, **details}
+        self.record(
+            session,
+            actor_id,
+            verb,
+            "demo",
+            demo.id,
+            team_id=demo.team_id,
+            details=details,
+            private=demo.visibility == "private",
+        )
+
+    def record_demo_shared(self, session: Session, actor_id: Optional[str], demo: Demo) -> None:
+        """Record a visibility change and re-scope the demo's earlier entries to match it."""
+
+        session.execute(
+            update(ActivityEntry)
+            .where(ActivityEntry.subject_type == "demo", ActivityEntry.subject_id == demo.id)
+            .values(private=demo.visibility == "private")
+        )
+        session.commit()
+        self.record_demo(session, actor_id, "demo.shared", demo, visibility=demo.visibility)
+
+    def record_upload_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: add ``demo.uploaded`` for the uploader once, never failing the upload."""
+
+        if not demo.owner_id:
+            return
+        try:
+            stmt = select(ActivityEntry.id).where(
+                ActivityEntry.verb == "demo.uploaded",
+                ActivityEntry.subject_type == "demo",
+                ActivityEntry.subject_id == demo.id,
+            )
+            if session.scalar(stmt) is None:  # reprocessing is not a new upload
+                self.record_demo(session, demo.owner_id, "demo.uploaded", demo)
+        except Exception:  # noqa: BLE001 - the demo is stored either way
+            session.rollback()
+            logger.exception("Could not record the upload of demo %s in the activity feed", demo.id)
+
+    def feed(
+        self,
+        session: Session,
+        viewer_id: str,
+        *,
+        admin: bool = False,
+        team_id: Optional[str] = None,
+        actor_id: Optional[str] = None,
+        verbs: Sequence[str] = (),
+        page: int = 1,
+        page_size: int = 20,
+        cursor: Optional[str] = None,
+    ) -> Tuple[List[ActivityEntry], int]:
+        """One page of entries the viewer may see, newest first.
+
+        With ``team_id`` this is the team's feed, with ``actor_id`` a user's;
+        otherwise the viewer's home feed of their own and their teams' activity.
+        A ``cursor`` (the last entry id of the previous page) replaces ``page``
+        and stays stable while new entries arrive.
+        """
+
+        view_teams = self.teams.team_ids(session, viewer_id, "view")
+        if team_id and not admin:
+            self.teams.get_team(session, team_id, viewer_id)
+            if team_id not in view_teams:
+                raise PermissionError("Your team role cannot see this team's activity")
+
+        visible = true() if admin else or_(
+            ActivityEntry.actor_id == viewer_id,
+            and_(
+                ActivityEntry.private.is_(False),
+                or_(ActivityEntry.team_id.in_(view_teams), ActivityEntry.team_id.is_(None)),
+            ),
+        )
+        stmt = select(ActivityEntry).where(visible)
+        if team_id:
+            stmt = stmt.where(ActivityEntry.team_id == team_id)
+        elif actor_id:
+            stmt = stmt.where(ActivityEntry.actor_id == actor_id)
+        elif not admin:
+            stmt = stmt.where(or_(ActivityEntry.actor_id == viewer_id, ActivityEntry.team_id.in_(view_teams)))
+        if verbs:
+            stmt = stmt.where(ActivityEntry.verb.in_(list(verbs)))
+
+        total = session.scalar(select(func.count()).select_from(stmt.subquery())) or 0
+        if cursor:
+            page_stmt = stmt.where(ActivityEntry.id < parse_cursor(cursor)).order_by(ActivityEntry.id.desc())
+        else:
+            newest_first = stmt.order_by(ActivityEntry.created_at.desc(), ActivityEntry.id.desc())
+            page_stmt = newest_first.offset((page - 1) * page_size)
+        return list(session.scalars(page_stmt.limit(page_size)).all()), total
@@ -0,0 +1,152 @@
+"""Snapshot and restore the metadata database together with the artefact manifest.
+
+A backup is a ``.tar.gz`` holding ``manifest.json`` (tables, row counts and
+every artefact key the metadata points at) and one ``tables/<name>.jsonl`` per
+table. Rows are read in a single transaction so the snapshot is consistent.
+Artefacts themselves stay in storage; restore checks they are still there.
+"""
+
+from __future__ import annotations
+
+import io
+import json
+import tarfile
+from dataclasses import dataclass, field
+from datetime import date, datetime
+from pathlib import Path
+from typing import Any, Dict, Iterator, List
+
+from sqlalchemy import Date, DateTime, delete, func, insert, select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.database import Base
+from ...core.storage import ArtifactStorage, build_storage
+from ..demos.models import Demo
+
+BACKUP_FORMAT_VERSION = 1
+
+
+@dataclass
+class BackupResult:
+    path: Path
+    tables: Dict[str, int]
+    artifacts: int
+
+
+@dataclass
+class RestoreResult:
+    tables: Dict[str, int]
+    artifacts: int
+    missing_artifacts: List[str] = field(default_factory=list)
+
+
+class BackupService:
+    def __init__(self, settings: Settings, storage: ArtifactStorage | None = None) -> None:
+        self.settings = settings
+        self.storage = storage or build_storage(settings)
+
+    def backup(self, session: Session, output: Path) -> BackupResult:
+        tables: Dict[str, int] = {}
+        output.parent.mkdir(parents=True, exist_ok=True)
+        with session.begin(), tarfile.open(output, "w:gz") as archive:
+            if session.get_bind().dialect.name == "postgresql":
+                # One snapshot for every table, not one per statement.
+                session.connection(execution_options={"isolation_level": "REPEATABLE READ"})
+            for table in Base.metadata.sorted_tables:
+                rows = session.execute(select(table))
+                lines = [json.dumps(_encode_row(row._mapping), sort_keys=True) for row in rows]
+                tables[table.name] = len(lines)
+                _add_file(archive, f"tables/{table.name}.jsonl", "\n".join(lines).encode())
+
+            artifacts = [
+                {"demo_id": demo.id, "name": name, "key": key, "size_bytes": self.storage.size(key)}
+                for demo in session.scalars(select(Demo).order_by(Demo.uploaded_at))
+                for name, key in demo.artifact_keys().items()
+            ]
+            manifest = {
+                "format_version": BACKUP_FORMAT_VERSION,
+                "created_at": datetime.utcnow().isoformat(),
+                "app_version": self.settings.version,
+                "storage_backend": self.storage.name,
+                "tables": tables,
+                "artifacts": artifacts,
+            }
+            _add_file(archive, "manifest.json", json.dumps(manifest, indent=2).encode())
+        return BackupResult(path=output, tables=tables, artifacts=len(artifacts))
+
+    def restore(self, session: Session, archive_path: Path, force: bool = False) -> RestoreResult:
+        """Load a backup into the database and verify its artefacts are present in storage.
+
+        The target must be empty unless ``force``, in which case every table is
+        cleared first.
+        """
+
+        with tarfile.open(archive_path, "r:gz") as archive:
+            manifest = json.loads(_read_file(archive, "manifest.json"))
+            if manifest.get("format_version") != BACKUP_FORMAT_VERSION:
+                raise ValueError(f"Unsupported backup format {manifest.get('format_version')}")
+            unknown = sorted(set(manifest["tables"]) - set(Base.metadata.tables))
+            if unknown:
+                raise ValueError(f"Backup contains tables this version does not know: {', '.join(unknown)}")
+
+            tables = Base.metadata.sorted_tables
+            with session.begin():
+                populated = [table.name for table in tables if session.scalar(select(func.count()).select_from(table))]
+                if populated and not force:
+                    raise ValueError(f"Database is not empty ({', '.join(populated)}); pass force to overwrite")
+                for table in reversed(tables):
+                    session.execute(delete(table))
+
+                restored: Dict[str, int] = {}
+                for table in tables:
+                    if table.name not in manifest["tables"]:
+                        continue
+                    rows = [_decode_row(table, json.loads(line)) for line in _lines(archive, table.name)]
+                    if rows:
+                        session.execute(insert(table), rows)
+                    restored[table.name] = len(rows)
+
+        missing = [
+            artifact["key"]
+            for artifact in manifest["artifacts"]
+            if artifact.get("size_bytes") is not None and not self.storage.exists(artifact["key"])
+        ]
+        return RestoreResult(tables=restored, artifacts=len(manifest["artifacts"]), missing_artifacts=missing)
+
+
+def _encode_row(row: Any) -> Dict[str, Any]:
+    return {key: value.isoformat() if isinstance(value, (datetime, date)) else value for key, value in row.items()}
+
+
+def _decode_row(table: Any, row: Dict[str, Any]) -> Dict[str, Any]:
+    decoded = dict(row)
+    for column in table.columns:
+        value = decoded.get(column.name)
+        if value is None:
+            continue
+        if isinstance(column.type, DateTime):
+            decoded[column.name] = datetime.fromisoformat(value)
+        elif isinstance(column.type, Date):
+            decoded[column.name] = date.fromisoformat(value)
+    return decoded
+
+
+def _add_file(archive: tarfile.TarFile, name: str, data: bytes) -> None:
+    info = tarfile.TarInfo(name)
+    info.size = len(data)
+    info.mtime = int(datetime.utcnow().timestamp())
+    archive.addfile(info, io.BytesIO(data))
+
+
+def _read_file(archive: tarfile.TarFile, name: str) -> bytes:
+    member = archive.extractfile(name)
+    if member is None:
+        raise ValueError(f"Backup is missing {name}")
+    return member.read()
+
+
+def _lines(archive: tarfile.TarFile, table_name: str) -> Iterator[str]:
+    for line in _read_file(archive, f"tables/{table_name}.jsonl").decode().splitlines():
+        if line.strip():
+            yield line
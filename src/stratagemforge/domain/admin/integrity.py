@@ -0,0 +1,89 @@
+from __future__ import annotations
+
+import logging
+from collections import Counter
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from ...core import metrics
+from ...core.config import Settings
+from ...core.storage import ArtifactStorage, build_storage
+from ..demos.models import Demo
+from ..demos.service import file_sha256
+from .models import IntegrityAuditRun
+
+logger = logging.getLogger(__name__)
+
+
+class IntegrityAuditService:
+    """Cross-check processed demos against the parquet files in storage.
+
+    Every artefact key a demo points at must exist and, when the ingest
+    recorded it, match the stored size and SHA-256. The match record's
+    artefact map must agree with the demo's. Checksums require reading each
+    file (downloading it with the S3 backend) and can be turned off with
+    ``INTEGRITY_VERIFY_CHECKSUMS``.
+    """
+
+    def __init__(self, settings: Settings, storage: ArtifactStorage | None = None) -> None:
+        self.settings = settings
+        self.storage = storage or build_storage(settings)
+
+    def run(self, session: Session) -> IntegrityAuditRun:
+        started_at = datetime.utcnow()
+        issues: List[Dict[str, Any]] = []
+        artifacts_checked = unverified = 0
+
+        demos = list(session.scalars(select(Demo).where(Demo.status == "processed").order_by(Demo.uploaded_at)))
+        for demo in demos:
+            keys = demo.artifact_keys()
+            manifest = (demo.extra_metadata or {}).get("artifact_manifest") or {}
+            for key in keys.values():
+                artifacts_checked += 1
+                recorded = manifest.get(key)
+                if recorded is None:
+                    unverified += 1
+                issue = self._check(key, recorded)
+                if issue:
+                    issues.append({"demo_id": demo.id, "key": key, **issue})
+            if demo.match is not None and demo.match.artifacts and demo.match.artifacts != keys:
+                detail = "match artefacts differ from demo metadata"
+                issues.append({"demo_id": demo.id, "key": None, "kind": "manifest_mismatch", "detail": detail})
+
+        run = IntegrityAuditRun(
+            started_at=started_at,
+            finished_at=datetime.utcnow(),
+            demos_checked=len(demos),
+            artifacts_checked=artifacts_checked,
+            unverified=unverified,
+            issue_counts=dict(Counter(issue["kind"] for issue in issues)),
+            issues=issues,
+        )
+        session.add(run)
+        session.commit()
+        metrics.INTEGRITY_ISSUES.clear()
+        for kind, count in run.issue_counts.items():
+            metrics.INTEGRITY_ISSUES.labels(kind=kind).set(count)
+        if issues:
+            logger.warning("Storage integrity audit found %d issue(s): %s", len(issues), run.issue_counts)
+        return run
+
+    def latest(self, session: Session) -> Optional[IntegrityAuditRun]:
+        stmt = select(IntegrityAuditRun).order_by(IntegrityAuditRun.started_at.desc()).limit(1)
+        return session.scalars(stmt).first()
+
+    def _check(self, key: str, recorded: Optional[Dict[str, Any]]) -> Optional[Dict[str, str]]:
+        size = self.storage.size(key)
+        if size is None:
+            return {"kind": "missing", "detail": "not found in storage"}
+        if recorded is None:
+            return None
+        if recorded.get("size_bytes") is not None and size != recorded["size_bytes"]:
+            return {"kind": "size_mismatch", "detail": f"expected {recorded['size_bytes']} bytes, found {size}"}
+        if self.settings.integrity_verify_checksums and recorded.get("sha256"):
+            if file_sha256(self.storage.get(key)) != recorded["sha256"]:
+                return {"kind": "checksum_mismatch", "detail": "sha256 differs from the value recorded at ingest"}
+        return None
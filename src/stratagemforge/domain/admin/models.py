@@ -0,0 +1,25 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+
+from sqlalchemy import DateTime, Integer, JSON, String
+from sqlalchemy.orm import Mapped, mapped_column
+
+from ...core.database import Base
+from ...core.ids import new_uuid
+
+
+class IntegrityAuditRun(Base):
+    """Result of one pass comparing demo metadata against the artefacts in storage."""
+
+    __tablename__ = "integrity_audit_runs"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    started_at: Mapped[datetime] = mapped_column(DateTime, nullable=False, index=True)
+    finished_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    demos_checked: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    artifacts_checked: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    unverified: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    issue_counts: Mapped[Dict[str, int]] = mapped_column(JSON, default=dict)
+    issues: Mapped[List[Dict[str, Any]]] = mapped_column(JSON, default=list)
@@ -0,0 +1,85 @@
+from __future__ import annotations
+
+from typing import Dict, List, Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+
+class IntegrityIssue(BaseModel):
+    demo_id: str
+    kind: str = Field(description="missing, size_mismatch, checksum_mismatch or manifest_mismatch")
+    key: Optional[str] = None
+    detail: Optional[str] = None
+
+
+class IntegrityAuditSummary(BaseModel):
+    id: str
+    started_at: UtcDateTime
+    finished_at: Optional[UtcDateTime] = None
+    demos_checked: int
+    artifacts_checked: int
+    unverified: int = Field(description="Artefacts stored before sizes and checksums were recorded")
+    issue_counts: Dict[str, int] = Field(default_factory=dict)
+    issues: List[IntegrityIssue] = Field(default_factory=list)
+
+    class Config:
+        orm_mode = True
+
+
+class StorageRegionSummary(BaseModel):
+    name: str
+    backend: str = Field(description="local or s3")
+    location: Optional[str] = Field(default=None, description="Directory or s3:// bucket and prefix")
+    region: Optional[str] = None
+    team_ids: List[str] = Field(default_factory=list, description="Teams whose new uploads are stored here")
+
+
+class TeamStorageRegionUpdate(BaseModel):
+    region: Optional[str] = Field(default=None, description="A configured storage region, or null for the default")
+
+
+class TeamStorageRegion(BaseModel):
+    team_id: str
+    region: Optional[str] = None
+
+
+class TenantExportRequest(BaseModel):
+    include_raw: bool = Field(default=False, description="Also include the original .dem files")
+    bucket: Optional[str] = Field(default=None, description="Copy the archive to this S3 bucket as well")
+    prefix: str = ""
+
+
+class TenantExportSummary(BaseModel):
+    team_id: str
+    filename: str
+    demos: int
+    files: int
+    size_bytes: int
+    download_url: str
+    location: Optional[str] = None
+
+
+class ThroughputBucket(BaseModel):
+    start: UtcDateTime
+    jobs: int
+    failed: int
+    failure_rate: Optional[float] = None
+    p50_parse_seconds: Optional[float] = None
+    p95_parse_seconds: Optional[float] = None
+    bytes_processed: int
+
+
+class ThroughputReport(BaseModel):
+    window: str
+    since: UtcDateTime
+    until: UtcDateTime
+    jobs: int
+    failed: int = Field(description="Runs where the parser failed or processing raised")
+    failure_rate: Optional[float] = None
+    p50_parse_seconds: Optional[float] = None
+    p95_parse_seconds: Optional[float] = None
+    bytes_processed: int = Field(description="Size of the demo files processed")
+    bucket: str = Field(description="hour or day")
+    buckets: List[ThroughputBucket] = Field(default_factory=list)
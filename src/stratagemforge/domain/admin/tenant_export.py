@@ -0,0 +1,169 @@
+"""Package everything a team has on the platform into one archive.
+
+Used when a team leaves or wants its own copy. The ``.tar.gz`` holds
+``team.json``, then per demo ``matches/<demo_id>/metadata.json`` (demo, match,
+players and scoreboard rows), its parquet artefacts and, on request, the raw
+``.dem`` file. ``manifest.json`` lists every file with its size.
+"""
+
+from __future__ import annotations
+
+import io
+import json
+import re
+import tarfile
+from dataclasses import dataclass
+from datetime import datetime
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from sqlalchemy import and_, or_, select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.storage import ArtifactStorage, S3Storage, build_storage
+from ..demos.models import Demo
+from ..demos.schemas import DemoDetail
+from ..stats.models import PlayerMatchStat
+from ..stats.schemas import PlayerMatchStatSummary
+from ..teams.models import Team, TeamMember
+
+TENANT_EXPORT_FORMAT_VERSION = 1
+_ARCHIVE_NAME = re.compile(r"^team-[\w-]+-\d{8}T\d{6}Z\.tar\.gz$")
+
+
+@dataclass
+class TenantExportResult:
+    team_id: str
+    path: Path
+    demos: int
+    files: int
+    size_bytes: int
+    location: Optional[str] = None
+
+
+class TenantExportService:
+    def __init__(self, settings: Settings, storage: ArtifactStorage | None = None) -> None:
+        self.settings = settings
+        self.storage = storage or build_storage(settings)
+        self.export_dir = settings.data_dir / "exports" / "teams"
+
+    def export_team(
+        self,
+        session: Session,
+        team_id: str,
+        include_raw: bool = False,
+        bucket: str | None = None,
+        prefix: str = "",
+    ) -> TenantExportResult:
+        """Write the team's archive and optionally copy it to ``bucket``."""
+
+        team = session.get(Team, team_id)
+        if not team:
+            raise LookupError("Team not found")
+
+        demos = self.team_demos(session, team)
+        stamp = datetime.utcnow().strftime("%Y%m%dT%H%M%SZ")
+        self.export_dir.mkdir(parents=True, exist_ok=True)
+        path = self.export_dir / f"team-{team.id}-{stamp}.tar.gz"
+
+        files: List[Dict[str, Any]] = []
+        with tarfile.open(path, "w:gz") as archive:
+            files.append(_add_json(archive, "team.json", _team_record(team)))
+            for demo in demos:
+                base = f"matches/{demo.id}"
+                files.append(_add_json(archive, f"{base}/metadata.json", self._demo_record(session, demo)))
+                for name, key in demo.artifact_keys().items():
+                    if self.storage.exists(key):
+                        files.append(_add_path(archive, f"{base}/{name}.parquet", self.storage.get(key)))
+                raw = Path(demo.stored_path)
+                if include_raw and raw.exists():
+                    files.append(_add_path(archive, f"{base}/raw/{Path(demo.original_filename).name}", raw))
+            manifest = {
+                "format_version": TENANT_EXPORT_FORMAT_VERSION,
+                "created_at": datetime.utcnow().isoformat(),
+                "team_id": team.id,
+                "demos": [demo.id for demo in demos],
+                "files": files,
+            }
+            _add_json(archive, "manifest.json", manifest)
+
+        location = self._transfer(path, bucket, prefix) if bucket else None
+        return TenantExportResult(
+            team_id=team.id,
+            path=path,
+            demos=len(demos),
+            files=len(files),
+            size_bytes=path.stat().st_size,
+            location=location,
+        )
+
+    def archive_path(self, filename: str) -> Path:
+        path = self.export_dir / filename
+        if not _ARCHIVE_NAME.match(filename) or not path.is_file():
+            raise LookupError("Export not found")
+        return path
+
+    @staticmethod
+    def team_demos(session: Session, team: Team) -> List[Demo]:
+        """Demos uploaded for the team, and those its members uploaded without naming any team.
+
+        Match records are not consulted: another tenant's team can have the
+        same name, and its demos must never end up in this team's archive.
+        """
+
+        members = select(TeamMember.user_id).where(TeamMember.team_id == team.id)
+        stmt = (
+            select(Demo)
+            .where(
+                Demo.deleted_at.is_(None),
+                or_(Demo.team_id == team.id, and_(Demo.team_id.is_(None), Demo.owner_id.in_(members))),
+            )
+            .order_by(Demo.uploaded_at)
+        )
+        return list(session.scalars(stmt))
+
+    def _demo_record(self, session: Session, demo: Demo) -> Dict[str, Any]:
+        record = DemoDetail.from_orm(demo).model_dump(mode="json")
+        record["stats"] = []
+        if demo.match is not None:
+            stats = session.scalars(select(PlayerMatchStat).where(PlayerMatchStat.match_id == demo.match.id))
+            record["stats"] = [PlayerMatchStatSummary.from_orm(stat).model_dump(mode="json") for stat in stats]
+        return record
+
+    def _transfer(self, path: Path, bucket: str, prefix: str) -> str:
+        target = S3Storage(
+            bucket=bucket,
+            cache_dir=self.settings.cache_data_path / "tenant-exports",
+            prefix=prefix,
+            endpoint_url=self.settings.s3_endpoint_url,
+            region=self.settings.s3_region,
+            access_key_id=self.settings.s3_access_key_id,
+            secret_access_key=self.settings.s3_secret_access_key,
+        )
+        target.put(path, path.name)
+        return f"s3://{bucket}/{target.object_key(path.name)}"
+
+
+def _team_record(team: Team) -> Dict[str, Any]:
+    return {
+        "id": team.id,
+        "name": team.name,
+        "tag": team.tag,
+        "map_pool": team.map_pool or [],
+        "created_at": team.created_at.isoformat() if team.created_at else None,
+    }
+
+
+def _add_json(archive: tarfile.TarFile, name: str, payload: Any) -> Dict[str, Any]:
+    data = json.dumps(payload, indent=2, default=str).encode()
+    info = tarfile.TarInfo(name)
+    info.size = len(data)
+    info.mtime = int(datetime.utcnow().timestamp())
+    archive.addfile(info, io.BytesIO(data))
+    return {"name": name, "size_bytes": len(data)}
+
+
+def _add_path(archive: tarfile.TarFile, name: str, path: Path) -> Dict[str, Any]:
+    archive.add(path, arcname=name)
+    return {"name": name, "size_bytes": path.stat().st_size}
@@ -0,0 +1,104 @@
+from __future__ import annotations
+
+import math
+import re
+from datetime import datetime, timedelta
+from typing import Any, Dict, List, Optional, Sequence
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ..demos.models import ParseJob
+from .schemas import ThroughputBucket, ThroughputReport
+
+WINDOW_PATTERN = re.compile(r"^(\d+)([mhdw])$")
+WINDOW_UNITS = {"m": "minutes", "h": "hours", "d": "days", "w": "weeks"}
+MAX_WINDOW = timedelta(days=366)
+# Statuses that count against the failure rate; "skipped" (no parser installed) does not.
+FAILED_STATUSES = frozenset({"failed", "error", "dead_letter"})
+
+
+def parse_window(value: str) -> timedelta:
+    """Turn ``30m``, ``24h``, ``7d`` or ``4w`` into a timedelta."""
+
+    found = WINDOW_PATTERN.match(value.strip().lower())
+    if not found or int(found.group(1)) == 0:
+        raise ValueError("Window must look like 30m, 24h, 7d or 4w")
+    window = timedelta(**{WINDOW_UNITS[found.group(2)]: int(found.group(1))})
+    if window > MAX_WINDOW:
+        raise ValueError("Window must not exceed 366 days")
+    return window
+
+
+class ThroughputService:
+    """Summarise the parse job history for capacity and reliability reviews.
+
+    Every processing run leaves a :class:`ParseJob` row, so the report covers
+    reprocessed and since-deleted demos too. Windows up to two days are broken
+    down by hour, longer ones by day.
+    """
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+
+    def report(self, session: Session, window: timedelta, now: Optional[datetime] = None) -> ThroughputReport:
+        until = now or datetime.utcnow()
+        since = until - window
+        jobs = list(
+            session.scalars(
+                select(ParseJob)
+                .where(ParseJob.started_at >= since, ParseJob.started_at <= until)
+                .order_by(ParseJob.started_at)
+            )
+        )
+        bucket_size = timedelta(hours=1) if window <= timedelta(days=2) else timedelta(days=1)
+        start = _floor(since, bucket_size)
+        buckets: List[ThroughputBucket] = []
+        while start <= until:
+            end = start + bucket_size
+            in_bucket = [job for job in jobs if start <= job.started_at < end]
+            buckets.append(ThroughputBucket(start=start, **_totals(in_bucket)))
+            start = end
+        return ThroughputReport(
+            window=_format_window(window),
+            since=since,
+            until=until,
+            bucket="hour" if bucket_size == timedelta(hours=1) else "day",
+            buckets=buckets,
+            **_totals(jobs),
+        )
+
+
+def _totals(jobs: Sequence[ParseJob]) -> Dict[str, Any]:
+    durations = sorted(job.duration_seconds for job in jobs)
+    failed = sum(job.status in FAILED_STATUSES for job in jobs)
+    return {
+        "jobs": len(jobs),
+        "failed": failed,
+        "failure_rate": round(failed / len(jobs), 4) if jobs else None,
+        "p50_parse_seconds": _percentile(durations, 0.5),
+        "p95_parse_seconds": _percentile(durations, 0.95),
+        "bytes_processed": sum(job.size_bytes for job in jobs),
+    }
+
+
+def _percentile(values: Sequence[float], fraction: float) -> Optional[float]:
+    """Nearest-rank percentile of already sorted values."""
+
+    if not values:
+        return None
+    return round(values[max(math.ceil(fraction * len(values)) - 1, 0)], 3)
+
+
+def _floor(moment: datetime, size: timedelta) -> datetime:
+    moment = moment.replace(minute=0, second=0, microsecond=0)
+    return moment.replace(hour=0) if size >= timedelta(days=1) else moment
+
+
+def _format_window(window: timedelta) -> str:
+    seconds = int(window.total_seconds())
+    for suffix, unit in (("w", 604800), ("d", 86400), ("h", 3600), ("m", 60)):
+        if seconds % unit == 0:
+            return f"{seconds // unit}{suffix}"
+    return f"{seconds}s"
@@ -0,0 +1,70 @@
+from __future__ import annotations
+
+from typing import Any, Dict, List, Optional
+
+import pandas as pd
+
+
+def summarize_duels(duels: pd.DataFrame, steamid: Optional[str] = None) -> Dict[str, List[Dict[str, Any]]]:
+    """Fold ``duels`` dataset rows from one or more demos into a who-killed-whom matrix.
+
+    Returns ``players`` (kills and deaths in the matrix) and ``duels`` (one
+    entry per attacker and victim with weapon and situation breakdowns). With
+    ``steamid`` only that player's duels are kept.
+    """
+
+    if duels.empty:
+        return {"players": [], "duels": []}
+    rows = duels.copy()
+    rows["weapon"] = rows["weapon"].fillna("unknown")
+    if steamid:
+        rows = rows[(rows["attacker_steamid"] == steamid) | (rows["victim_steamid"] == steamid)]
+        if rows.empty:
+            return {"players": [], "duels": []}
+
+    names = _names(rows)
+    pairs = []
+    for (attacker, victim), group in rows.groupby(["attacker_steamid", "victim_steamid"], sort=True):
+        pairs.append(
+            {
+                "attacker_steamid": attacker,
+                "attacker_name": names.get(attacker),
+                "victim_steamid": victim,
+                "victim_name": names.get(victim),
+                "kills": int(group["kills"].sum()),
+                "headshots": int(group["headshots"].sum()),
+                "weapons": _breakdown(group, "weapon"),
+                "situations": _breakdown(group, "situation"),
+            }
+        )
+    pairs.sort(key=lambda pair: (-pair["kills"], pair["attacker_steamid"], pair["victim_steamid"]))
+
+    kills = rows.groupby("attacker_steamid")["kills"].sum()
+    deaths = rows.groupby("victim_steamid")["kills"].sum()
+    players = [
+        {
+            "steamid": player,
+            "name": names.get(player),
+            "kills": int(kills.get(player, 0)),
+            "deaths": int(deaths.get(player, 0)),
+        }
+        for player in sorted(set(kills.index) | set(deaths.index))
+    ]
+    players.sort(key=lambda player: (-player["kills"], player["deaths"], player["steamid"]))
+    return {"players": players, "duels": pairs}
+
+
+def _names(rows: pd.DataFrame) -> Dict[str, str]:
+    """Latest known name per SteamID, from either side of a duel."""
+
+    names: Dict[str, str] = {}
+    for role in ("attacker", "victim"):
+        for steamid, name in zip(rows[f"{role}_steamid"], rows[f"{role}_name"]):
+            if isinstance(name, str) and name:
+                names[steamid] = name
+    return names
+
+
+def _breakdown(group: pd.DataFrame, column: str) -> Dict[str, int]:
+    counts = group.groupby(column)["kills"].sum().sort_values(ascending=False)
+    return {str(key): int(value) for key, value in counts.items()}
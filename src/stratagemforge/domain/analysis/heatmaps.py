@@ -0,0 +1,102 @@
+from __future__ import annotations
+
+import struct
+import zlib
+from typing import Any, Dict, List, Optional
+
+import pandas as pd
+
+from ..demos.radar import RADAR_SIZE, radar_for
+
+HEATMAP_BINS = 64
+PNG_SIGNATURE = b"\x89PNG\r\n\x1a\n"
+
+
+def build_heatmap(
+    ticks: pd.DataFrame,
+    map_name: Optional[str],
+    bins: int = HEATMAP_BINS,
+    steamid: Optional[str] = None,
+    side: Optional[str] = None,
+    round_number: Optional[int] = None,
+    phase: Optional[str] = None,
+) -> Dict[str, Any]:
+    """Bin ``player_ticks`` positions into a ``bins`` x ``bins`` grid of sample counts.
+
+    On maps with a known radar the grid covers the radar image, so cell
+    (row, column) lies under the same share of the overview; rows run from the
+    top of the radar down. Other maps get a grid over the extent of the
+    positions themselves. ``extent`` is the covered world area either way.
+    """
+
+    rows = ticks
+    if steamid:
+        rows = rows[rows["steamid"].astype(str) == steamid]
+    if side:
+        rows = rows[rows["side"] == side]
+    if round_number is not None:
+        rows = rows[rows["round"] == round_number]
+    if phase:
+        rows = rows[rows["phase"] == phase]
+    x = pd.to_numeric(rows["x"], errors="coerce") if not rows.empty else pd.Series(dtype="float64")
+    y = pd.to_numeric(rows["y"], errors="coerce") if not rows.empty else pd.Series(dtype="float64")
+    known = x.notna() & y.notna()
+    x, y = x[known], y[known]
+
+    radar = radar_for(map_name)
+    if radar is not None:
+        extent = {
+            "min_x": float(radar.pos_x),
+            "max_x": float(radar.pos_x + RADAR_SIZE * radar.scale),
+            "min_y": float(radar.pos_y - RADAR_SIZE * radar.scale),
+            "max_y": float(radar.pos_y),
+        }
+    elif not x.empty:
+        extent = {"min_x": float(x.min()), "max_x": float(x.max()), "min_y": float(y.min()), "max_y": float(y.max())}
+    else:
+        extent = None
+
+    grid = [[0] * bins for _ in range(bins)]
+    if extent is not None and not x.empty:
+        width = max(extent["max_x"] - extent["min_x"], 1.0)
+        height = max(extent["max_y"] - extent["min_y"], 1.0)
+        columns = ((x - extent["min_x"]) / width * bins).astype(int).clip(upper=bins - 1)
+        lines = ((extent["max_y"] - y) / height * bins).astype(int).clip(upper=bins - 1)
+        inside = (x >= extent["min_x"]) & (x <= extent["max_x"]) & (y >= extent["min_y"]) & (y <= extent["max_y"])
+        cells = pd.DataFrame({"line": lines[inside], "column": columns[inside]}).value_counts()
+        for (line, column), count in cells.items():
+            grid[int(line)][int(column)] = int(count)
+    return {
+        "bins": bins,
+        "samples": sum(map(sum, grid)),
+        "max_count": max(map(max, grid)) if grid else 0,
+        "extent": extent,
+        "radar": radar.to_dict() if radar is not None else None,
+        "grid": grid,
+    }
+
+
+def heatmap_png(grid: List[List[int]]) -> bytes:
+    """Render a heatmap grid as an RGBA PNG, one pixel per cell, to stretch over the radar.
+
+    Empty cells are transparent; busier cells go from translucent yellow to opaque red.
+    """
+
+    peak = max((max(row) for row in grid), default=0) or 1
+    raw = bytearray()
+    for row in grid:
+        raw.append(0)  # no filter
+        for count in row:
+            if not count:
+                raw.extend((0, 0, 0, 0))
+                continue
+            share = count / peak
+            raw.extend((255, int(255 * (1 - share)), 0, int(64 + 191 * share)))
+    height, width = len(grid), len(grid[0]) if grid else 0
+    header = struct.pack(">IIBBBBB", width, height, 8, 6, 0, 0, 0)  # 8-bit RGBA
+    chunks = (_chunk(b"IHDR", header), _chunk(b"IDAT", zlib.compress(bytes(raw))), _chunk(b"IEND", b""))
+    return PNG_SIGNATURE + b"".join(chunks)
+
+
+def _chunk(kind: bytes, data: bytes) -> bytes:
+    return struct.pack(">I", len(data)) + kind + data + struct.pack(">I", zlib.crc32(kind + data) & 0xFFFFFFFF)
@@ -0,0 +1,46 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+
+from sqlalchemy import JSON, DateTime, Float, Integer, String, UniqueConstraint
+from sqlalchemy.orm import Mapped, mapped_column
+
+from ...core.database import Base
+from ...core.ids import new_uuid
+
+
+class PlayerRole(Base):
+    """Inferred playing role for a player on a map within a team lineup."""
+
+    __tablename__ = "player_roles"
+    __table_args__ = (UniqueConstraint("steamid", "map_name", "team_name", name="uq_player_role_scope"),)
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    steamid: Mapped[str] = mapped_column(String(32), nullable=False, index=True)
+    player_name: Mapped[Optional[str]] = mapped_column(String(255))
+    team_name: Mapped[str] = mapped_column(String(255), nullable=False, default="")
+    map_name: Mapped[str] = mapped_column(String(64), nullable=False, index=True)
+    role: Mapped[str] = mapped_column(String(32), nullable=False)
+    matches: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    rounds: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    awp_kill_share: Mapped[float] = mapped_column(Float, default=0.0, nullable=False)
+    opening_duel_rate: Mapped[float] = mapped_column(Float, default=0.0, nullable=False)
+    utility_per_round: Mapped[float] = mapped_column(Float, default=0.0, nullable=False)
+    avg_death_order: Mapped[float] = mapped_column(Float, default=0.0, nullable=False)
+    updated_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+
+class OpponentSiteModel(Base):
+    """Where a team's T side hits on a map, counted by game state (see ``opponents.fit_site_models``)."""
+
+    __tablename__ = "opponent_site_models"
+    __table_args__ = (UniqueConstraint("team_name", "map_name", name="uq_opponent_site_model_scope"),)
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    team_name: Mapped[str] = mapped_column(String(255), nullable=False, index=True)
+    map_name: Mapped[str] = mapped_column(String(64), nullable=False)
+    rounds: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    # One entry per economy, score_state, time_bucket and hit with its round count.
+    counts: Mapped[List[Dict[str, Any]]] = mapped_column(JSON, default=list)
+    updated_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
@@ -0,0 +1,101 @@
+"""Per-opponent site-hit model for mid-game calls.
+
+The model is deliberately simple: per team and map it keeps how often the
+team's T side hit A, B or split through mid, counted by economy, score
+situation and round-time bucket. Predictions combine the counts naive-Bayes
+style with Laplace smoothing, so sparse combinations fall back towards the
+team's overall tendencies instead of jumping to 0 or 1.
+"""
+
+from __future__ import annotations
+
+from typing import Any, Dict, List, Optional
+
+import pandas as pd
+
+HITS = ("A", "B", "mid")
+SCORE_STATES = ("behind", "level", "ahead")
+# (bucket, first second) of round time after freeze time, latest first.
+TIME_BUCKETS = (("late", 60.0), ("default", 25.0), ("early", 0.0))
+SMOOTHING = 1.0
+FEATURES = ("economy", "score_state", "time_bucket")
+
+
+def score_state(score_for: int, score_against: int) -> str:
+    if score_for < score_against:
+        return "behind"
+    return "ahead" if score_for > score_against else "level"
+
+
+def time_bucket(seconds: float) -> str:
+    return next(name for name, start in TIME_BUCKETS if seconds >= start)
+
+
+def fit_site_models(site_hits: pd.DataFrame) -> List[Dict[str, Any]]:
+    """Count ``site_hits`` dataset rows into one model per team and map.
+
+    Each model has ``team_name``, ``map_name``, ``rounds`` and ``counts``: one
+    entry per economy, score state, time bucket and hit with its round count.
+    Rounds without a hit are left out.
+    """
+
+    if site_hits.empty:
+        return []
+    rows = site_hits[site_hits["hit"].isin(HITS) & site_hits["team_name"].notna()].copy()
+    if rows.empty:
+        return []
+    rows["map_name"] = rows["map_name"].fillna("unknown")
+    rows["economy"] = rows["economy"].fillna("unknown")
+    rows["score_state"] = [score_state(a, b) for a, b in zip(rows["score_for"], rows["score_against"])]
+    rows["time_bucket"] = rows["hit_seconds"].fillna(0).map(time_bucket)
+
+    models = []
+    for (team, map_name), group in rows.groupby(["team_name", "map_name"], sort=True):
+        counts = group.groupby(list(FEATURES) + ["hit"]).size().reset_index(name="rounds")
+        models.append(
+            {
+                "team_name": team,
+                "map_name": map_name,
+                "rounds": int(len(group)),
+                "counts": counts.to_dict(orient="records"),
+            }
+        )
+    return models
+
+
+def predict_site(
+    counts: List[Dict[str, Any]],
+    economy: Optional[str] = None,
+    state: Optional[str] = None,
+    elapsed_seconds: Optional[float] = None,
+) -> Dict[str, Any]:
+    """Probability of each hit given what is known about the current round.
+
+    With ``elapsed_seconds`` only hits that came at that point of the round or
+    later count, since earlier ones would already have happened. Returns
+    ``probabilities`` per hit, the ``rounds`` the prediction rests on and the
+    ``matching_rounds`` played in exactly the given economy and score state.
+    """
+
+    table = pd.DataFrame(counts, columns=list(FEATURES) + ["hit", "rounds"])
+    if elapsed_seconds is not None:
+        current = dict(TIME_BUCKETS)[time_bucket(elapsed_seconds)]
+        table = table[table["time_bucket"].isin([name for name, start in TIME_BUCKETS if start >= current])]
+
+    totals = table.groupby("hit")["rounds"].sum().reindex(HITS, fill_value=0)
+    scores = (totals + SMOOTHING) / (totals.sum() + SMOOTHING * len(HITS))
+    matching = table
+    for column, value in (("economy", economy), ("score_state", state)):
+        if value is None:
+            continue
+        levels = max(table[column].nunique(), 1) + (0 if value in set(table[column]) else 1)
+        given = table[table[column] == value].groupby("hit")["rounds"].sum().reindex(HITS, fill_value=0)
+        scores *= (given + SMOOTHING) / (totals + SMOOTHING * levels)
+        matching = matching[matching[column] == value]
+
+    probabilities = scores / scores.sum()
+    return {
+        "probabilities": {hit: round(float(probabilities[hit]), 3) for hit in HITS},
+        "rounds": int(totals.sum()),
+        "matching_rounds": int(matching["rounds"].sum()),
+    }
@@ -0,0 +1,43 @@
+from __future__ import annotations
+
+from typing import Any, Dict, List
+
+import pandas as pd
+
+
+def summarize_pistol_rounds(pistols: pd.DataFrame, team: str) -> List[Dict[str, Any]]:
+    """Group a team's pistol rounds per map and side with a breakdown per buy type."""
+
+    if pistols.empty:
+        return []
+
+    rows = pistols[pistols["team_name"].fillna("").astype(str).str.casefold() == team.casefold()].copy()
+    if rows.empty:
+        return []
+
+    rows["map_name"] = rows["map_name"].fillna("unknown")
+    summaries = []
+    for (map_name, side), group in rows.groupby(["map_name", "side"], sort=True):
+        buys = group.groupby("buy")["won"].agg(rounds="size", wins="sum").reset_index()
+        summaries.append(
+            {
+                "map_name": map_name,
+                "side": side,
+                "rounds": int(len(group)),
+                "wins": int(group["won"].sum()),
+                "win_rate": round(float(group["won"].mean()), 3),
+                "opening_kill_rate": round(float(group["opening_kill"].mean()), 3),
+                "plant_rate": round(float(group["bomb_planted"].mean()), 3) if side == "T" else None,
+                "most_common_buy": group["buy"].mode().iloc[0],
+                "buys": [
+                    {
+                        "buy": buy.buy,
+                        "rounds": int(buy.rounds),
+                        "wins": int(buy.wins),
+                        "win_rate": round(int(buy.wins) / int(buy.rounds), 3),
+                    }
+                    for buy in buys.itertuples(index=False)
+                ],
+            }
+        )
+    return summaries
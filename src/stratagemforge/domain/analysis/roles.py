@@ -0,0 +1,79 @@
+from __future__ import annotations
+
+import pandas as pd
+
+ROLES = ("awper", "entry", "lurker", "support", "igl_proxy")
+AWP_SHARE_THRESHOLD = 0.3
+
+_COUNTERS = [
+    "rounds",
+    "kills",
+    "awp_kills",
+    "opening_kills",
+    "opening_deaths",
+    "t_opening_duels",
+    "utility_thrown",
+    "flashes_thrown",
+    "death_order_total",
+]
+
+
+def role_metrics(features: pd.DataFrame) -> pd.DataFrame:
+    """Aggregate ``role_features`` rows across demos per map, team and player."""
+
+    features = features.copy()
+    features["team_name"] = features["team_name"].fillna("")
+    features["map_name"] = features["map_name"].fillna("unknown")
+    grouped = features.groupby(["map_name", "team_name", "steamid"])
+    metrics = grouped[_COUNTERS].sum()
+    metrics["name"] = grouped["name"].last()
+    metrics["matches"] = grouped["demo_id"].nunique()
+
+    rounds = metrics["rounds"].where(metrics["rounds"] > 0)
+    kills = metrics["kills"].where(metrics["kills"] > 0)
+    metrics["awp_kill_share"] = (metrics["awp_kills"] / kills).fillna(0.0)
+    metrics["opening_duel_rate"] = ((metrics["opening_kills"] + metrics["opening_deaths"]) / rounds).fillna(0.0)
+    metrics["entry_rate"] = (metrics["t_opening_duels"] / rounds).fillna(0.0)
+    metrics["utility_per_round"] = (metrics["utility_thrown"] / rounds).fillna(0.0)
+    metrics["flashes_per_round"] = (metrics["flashes_thrown"] / rounds).fillna(0.0)
+    metrics["kills_per_round"] = (metrics["kills"] / rounds).fillna(0.0)
+    metrics["avg_death_order"] = (metrics["death_order_total"] / rounds).fillna(0.0)
+    return metrics.reset_index()
+
+
+def infer_roles(features: pd.DataFrame) -> pd.DataFrame:
+    """Assign one role per player, map and team using greedy heuristics.
+
+    Within each team/map lineup the AWPer is the player with the highest AWP
+    kill share (if above ``AWP_SHARE_THRESHOLD``), the entry has the most T-side
+    opening duels, the lurker dies latest on average and the support throws the
+    most flashes. The lowest-fragging remaining player is the IGL proxy; any
+    further players default to support.
+    """
+
+    if features.empty:
+        return pd.DataFrame(columns=["map_name", "team_name", "steamid", "name", "role"])
+
+    metrics = role_metrics(features)
+    metrics["role"] = None
+    for _, lineup in metrics.groupby(["map_name", "team_name"]):
+        remaining = lineup.copy()
+
+        awper = remaining["awp_kill_share"].idxmax()
+        if remaining.loc[awper, "awp_kill_share"] >= AWP_SHARE_THRESHOLD:
+            metrics.loc[awper, "role"] = "awper"
+            remaining = remaining.drop(awper)
+
+        for role, column in (("entry", "entry_rate"), ("lurker", "avg_death_order"), ("support", "flashes_per_round")):
+            if remaining.empty:
+                break
+            chosen = remaining[column].idxmax()
+            metrics.loc[chosen, "role"] = role
+            remaining = remaining.drop(chosen)
+
+        if not remaining.empty:
+            igl = remaining["kills_per_round"].idxmin()
+            metrics.loc[igl, "role"] = "igl_proxy"
+            metrics.loc[remaining.index.drop(igl), "role"] = "support"
+
+    return metrics
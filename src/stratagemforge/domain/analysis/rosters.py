@@ -0,0 +1,56 @@
+from __future__ import annotations
+
+from typing import Any, Dict, List
+
+import pandas as pd
+
+
+def detect_roster_eras(appearances: pd.DataFrame, team: str, min_changes: int = 1) -> List[Dict[str, Any]]:
+    """Split a team's matches into eras with a stable lineup.
+
+    ``appearances`` holds one row per player and demo (``demo_id``,
+    ``demo_date``, ``steamid``, ``name``, ``team_name``). Matches are walked in
+    chronological order and a new era starts whenever at least ``min_changes``
+    players differ from the lineup that opened the current era.
+    """
+
+    if appearances.empty:
+        return []
+
+    rows = appearances[appearances["team_name"].fillna("").astype(str).str.casefold() == team.casefold()]
+    if rows.empty:
+        return []
+
+    names = rows.dropna(subset=["name"]).groupby("steamid")["name"].last().to_dict()
+    matches = (
+        rows.groupby("demo_id")
+        .agg(demo_date=("demo_date", "min"), players=("steamid", lambda values: frozenset(map(str, values))))
+        .sort_values("demo_date")
+    )
+
+    eras: List[Dict[str, Any]] = []
+    for demo_id, match in matches.iterrows():
+        current = eras[-1] if eras else None
+        if current is None or len(match["players"] - current["lineup"]) >= min_changes:
+            current = {
+                "era": len(eras) + 1,
+                "lineup": match["players"],
+                "started_at": match["demo_date"],
+                "demo_ids": [],
+            }
+            eras.append(current)
+        current["demo_ids"].append(demo_id)
+        current["ended_at"] = match["demo_date"]
+
+    return [
+        {
+            "era": era["era"],
+            "players": sorted(era["lineup"]),
+            "player_names": [names.get(steamid, steamid) for steamid in sorted(era["lineup"])],
+            "started_at": era["started_at"],
+            "ended_at": era["ended_at"],
+            "matches": len(era["demo_ids"]),
+            "demo_ids": era["demo_ids"],
+        }
+        for era in eras
+    ]
@@ -0,0 +1,45 @@
+from __future__ import annotations
+
+from typing import Any, Dict, List
+
+import pandas as pd
+
+
+def summarize_save_decisions(saves: pd.DataFrame, team: str) -> List[Dict[str, Any]]:
+    """Group a team's post-loss save decisions per map.
+
+    ``save_discipline`` is the share of saves among the save and force
+    decisions, i.e. how often a player who could not afford a full buy kept
+    their money; ``None`` when the team never had to choose. A split round is
+    one where some players saved and others forced.
+    """
+
+    if saves.empty:
+        return []
+
+    rows = saves[saves["team_name"].fillna("").astype(str).str.casefold() == team.casefold()].copy()
+    if rows.empty:
+        return []
+
+    rows["map_name"] = rows["map_name"].fillna("unknown")
+    summaries = []
+    for map_name, group in rows.groupby("map_name", sort=True):
+        lost_rounds = group.groupby(["demo_id", "round"])
+        decisions = group["decision"].value_counts()
+        saved, forced = int(decisions.get("save", 0)), int(decisions.get("force", 0))
+        split = lost_rounds["decision"].agg(lambda values: {"save", "force"} <= set(values))
+        summaries.append(
+            {
+                "map_name": map_name,
+                "lost_rounds": int(lost_rounds.ngroups),
+                "survivors": int(group["survived"].sum()),
+                "equipment_saved": int(group["saved_value"].sum()),
+                "equipment_saved_per_round": round(float(group["saved_value"].sum()) / lost_rounds.ngroups, 1),
+                "buys": int(decisions.get("buy", 0)),
+                "saves": saved,
+                "forces": forced,
+                "split_rounds": int(split.sum()),
+                "save_discipline": round(saved / (saved + forced), 3) if saved + forced else None,
+            }
+        )
+    return summaries
@@ -0,0 +1,47 @@
+from __future__ import annotations
+
+import pandas as pd
+
+SCENARIO_COLUMNS = ["perspective", "site", "scenario", "attempts", "wins", "win_rate"]
+
+
+def summarize_post_plant(scenarios: pd.DataFrame, team: str) -> pd.DataFrame:
+    """Aggregate post-plant (team on T) and retake (team on CT) success rates.
+
+    ``scenarios`` is the concatenation of ``post_plant`` datasets across matches.
+    Scenarios are labelled from the team's point of view, e.g. ``2v3`` means the
+    team had two players alive against three at the time of the plant.
+    """
+
+    if scenarios.empty:
+        return pd.DataFrame(columns=SCENARIO_COLUMNS)
+
+    team_key = team.casefold()
+    frames = []
+    for perspective, own_side, own_team, enemy_side in (
+        ("post_plant", "T", "t_team", "CT"),
+        ("retake", "CT", "ct_team", "T"),
+    ):
+        rows = scenarios[scenarios[own_team].fillna("").astype(str).str.casefold() == team_key]
+        if rows.empty:
+            continue
+        frames.append(
+            pd.DataFrame(
+                {
+                    "perspective": perspective,
+                    "site": rows["site"],
+                    "scenario": rows[f"{own_side.lower()}_alive"].astype(str) + "v" + rows[f"{enemy_side.lower()}_alive"].astype(str),
+                    "won": rows["winner"] == own_side,
+                }
+            )
+        )
+
+    if not frames:
+        return pd.DataFrame(columns=SCENARIO_COLUMNS)
+
+    combined = pd.concat(frames, ignore_index=True)
+    combined["site"] = combined["site"].fillna("unknown")
+    grouped = combined.groupby(["perspective", "site", "scenario"])["won"].agg(attempts="size", wins="sum").reset_index()
+    grouped["wins"] = grouped["wins"].astype("int64")
+    grouped["win_rate"] = (grouped["wins"] / grouped["attempts"]).round(3)
+    return grouped.sort_values(["perspective", "site", "scenario"]).reset_index(drop=True)[SCENARIO_COLUMNS]
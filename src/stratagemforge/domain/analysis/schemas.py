@@ -1,10 +1,11 @@
 from __future__ import annotations
 
-from datetime import datetime
-from typing import Any, Dict, Optional
+from typing import Any, Dict, List, Optional
 
 from pydantic import BaseModel, Field
 
+from ...core.timeutil import UtcDateTime
+
 
 class AnalysisRequest(BaseModel):
     demo_id: str
@@ -17,7 +18,7 @@ class AnalysisResult(BaseModel):
     status: str
     results: Dict[str, Any]
     message: str
-    generated_at: datetime
+    generated_at: UtcDateTime
 
 
 class AnalysisConfig(BaseModel):
@@ -25,3 +26,178 @@ class AnalysisConfig(BaseModel):
     database_url: str
     data_path: str
     message: str
+
+
+class ScenarioStats(BaseModel):
+    perspective: str
+    site: str
+    scenario: str
+    attempts: int
+    wins: int
+    win_rate: float
+
+
+class PostPlantReport(BaseModel):
+    team: str
+    map_name: Optional[str] = None
+    demos_analyzed: int
+    scenarios: List[ScenarioStats]
+    generated_at: UtcDateTime
+
+
+class PistolBuyStats(BaseModel):
+    buy: str
+    rounds: int
+    wins: int
+    win_rate: float
+
+
+class PistolSideStats(BaseModel):
+    map_name: str
+    side: str
+    rounds: int
+    wins: int
+    win_rate: float
+    opening_kill_rate: float
+    plant_rate: Optional[float] = None
+    most_common_buy: str
+    buys: List[PistolBuyStats]
+
+
+class PistolReport(BaseModel):
+    team: str
+    map_name: Optional[str] = None
+    demos_analyzed: int
+    sides: List[PistolSideStats]
+    generated_at: UtcDateTime
+
+
+class SaveMapStats(BaseModel):
+    map_name: str
+    lost_rounds: int
+    survivors: int = Field(description="Players who survived a lost round")
+    equipment_saved: int = Field(description="Buy menu value of the weapons and grenades survivors kept")
+    equipment_saved_per_round: float
+    buys: int = Field(description="Post-loss decisions where the player could afford a full buy")
+    saves: int
+    forces: int
+    split_rounds: int = Field(description="Lost rounds after which some players saved and others forced")
+    save_discipline: Optional[float] = Field(default=None, description="saves / (saves + forces)")
+
+
+class SaveReport(BaseModel):
+    team: str
+    map_name: Optional[str] = None
+    demos_analyzed: int
+    maps: List[SaveMapStats]
+    generated_at: UtcDateTime
+
+
+class DuelPlayer(BaseModel):
+    steamid: str
+    name: Optional[str] = None
+    kills: int
+    deaths: int
+
+
+class Duel(BaseModel):
+    attacker_steamid: str
+    attacker_name: Optional[str] = None
+    victim_steamid: str
+    victim_name: Optional[str] = None
+    kills: int
+    headshots: int
+    weapons: Dict[str, int] = Field(default_factory=dict)
+    situations: Dict[str, int] = Field(default_factory=dict, description="opening, trade or other")
+
+
+class DuelMatrix(BaseModel):
+    demo_id: Optional[str] = None
+    map_name: Optional[str] = None
+    steamid: Optional[str] = None
+    demos_analyzed: int
+    players: List[DuelPlayer]
+    duels: List[Duel]
+    generated_at: UtcDateTime
+
+
+class HeatmapRadar(BaseModel):
+    pos_x: float
+    pos_y: float
+    scale: float = Field(description="World units per radar pixel")
+    size: int = Field(description="Width and height of the radar image in pixels")
+    lower_level_below: Optional[float] = Field(
+        default=None, description="World z below which positions belong on the lower overview (Nuke, Vertigo)"
+    )
+
+
+class Heatmap(BaseModel):
+    match_id: str
+    map_name: Optional[str] = None
+    steamid: Optional[str] = None
+    side: Optional[str] = None
+    round: Optional[int] = None
+    phase: Optional[str] = None
+    bins: int
+    samples: int
+    max_count: int
+    extent: Optional[Dict[str, float]] = Field(default=None, description="World area the grid covers")
+    radar: Optional[HeatmapRadar] = Field(default=None, description="Overview calibration on standard maps")
+    grid: List[List[int]] = Field(description="Position samples per cell, rows from the top of the map down")
+
+
+class PlayerRoleSummary(BaseModel):
+    steamid: str
+    player_name: Optional[str] = None
+    team_name: str
+    map_name: str
+    role: str
+    matches: int
+    rounds: int
+    awp_kill_share: float
+    opening_duel_rate: float
+    utility_per_round: float
+    avg_death_order: float
+    updated_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class OpponentModelSummary(BaseModel):
+    team_name: str
+    map_name: str
+    rounds: int = Field(description="T rounds with a known hit the model was fitted on")
+    updated_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class SitePrediction(BaseModel):
+    team: str
+    map_name: str
+    economy: Optional[str] = None
+    score_state: Optional[str] = Field(default=None, description="behind, level or ahead from the T side's view")
+    elapsed_seconds: Optional[float] = None
+    probabilities: Dict[str, float] = Field(description="Probability of an A hit, a B hit and a mid split")
+    most_likely: str
+    rounds: int = Field(description="Rounds the prediction rests on")
+    matching_rounds: int = Field(description="Of those, rounds played in exactly this economy and score state")
+    updated_at: UtcDateTime
+
+
+class RosterEra(BaseModel):
+    era: int
+    players: List[str]
+    player_names: List[str]
+    started_at: UtcDateTime
+    ended_at: UtcDateTime
+    matches: int
+    demo_ids: List[str]
+
+
+class RosterReport(BaseModel):
+    team: str
+    current_era: Optional[int] = None
+    eras: List[RosterEra]
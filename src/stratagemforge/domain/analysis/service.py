@@ -1,23 +1,73 @@
 from __future__ import annotations
 
+import threading
+import time
 from datetime import datetime
-from pathlib import Path
-from typing import Dict
+from typing import Any, Callable, Dict, Optional, Tuple
 
 import pandas as pd
+from sqlalchemy import delete, select
 from sqlalchemy.orm import Session
 
 from ...core.config import Settings
+from ...core.storage import ArtifactStorage, build_storage
 from ..demos.models import Demo
 from ..demos.repository import DemoRepository
-from .schemas import AnalysisRequest, AnalysisResult
+from .duels import summarize_duels
+from .heatmaps import HEATMAP_BINS, build_heatmap
+from .models import OpponentSiteModel, PlayerRole
+from .opponents import HITS, fit_site_models, predict_site, score_state
+from .pistol import summarize_pistol_rounds
+from .roles import infer_roles
+from .rosters import detect_roster_eras
+from .saves import summarize_save_decisions
+from .scenarios import summarize_post_plant
+from .schemas import (
+    AnalysisRequest,
+    AnalysisResult,
+    Duel,
+    DuelMatrix,
+    DuelPlayer,
+    Heatmap,
+    PistolReport,
+    PistolSideStats,
+    PostPlantReport,
+    SitePrediction,
+    RosterEra,
+    RosterReport,
+    SaveMapStats,
+    SaveReport,
+    ScenarioStats,
+)
+from .windows import AnalysisWindow
 
 
 class AnalysisService:
-    """Perform lightweight analytics on processed demo files."""
+    """Perform lightweight analytics on processed demo files.
 
-    def __init__(self, settings: Settings) -> None:
+    Cross-demo reports take an :class:`AnalysisWindow` (dates, last N matches,
+    competition) and are cached per team, map, era and window for
+    ``analysis_cache_seconds``; the cache is dropped whenever a demo finishes
+    processing or is deleted.
+    """
+
+    def __init__(
+        self, settings: Settings, storage: ArtifactStorage | None = None, clock: Callable[[], float] = time.monotonic
+    ) -> None:
         self.settings = settings
+        self.storage = storage or build_storage(settings)
+        self._clock = clock
+        self._cache: Dict[Tuple[Any, ...], Tuple[float, Any]] = {}
+        self._lock = threading.Lock()
+
+    def invalidate(self) -> None:
+        with self._lock:
+            self._cache.clear()
+
+    def invalidate_after_change(self, session: Session, demo: Demo) -> None:
+        """Post-processing and delete hook: the demo may fall into any cached window."""
+
+        self.invalidate()
 
     def list_available_demos(self, session: Session) -> list[Demo]:
         return DemoRepository(session).list()
@@ -30,11 +80,11 @@ class AnalysisService:
         if not demo.processed_path:
             raise ValueError("Demo has not been processed yet")
 
-        parquet_path = Path(demo.processed_path)
-        if not parquet_path.exists():
-            raise FileNotFoundError(f"Processed parquet file missing at {parquet_path}")
+        summary_key = (demo.extra_metadata or {}).get("summary_key") or demo.processed_path
+        if not self.storage.exists(summary_key):
+            raise FileNotFoundError(f"Processed parquet file missing at {summary_key}")
 
-        df = pd.read_parquet(parquet_path)
+        df = pd.read_parquet(self.storage.get(summary_key))
 
         results: Dict[str, object] = {
             "row_count": int(len(df)),
@@ -62,3 +112,328 @@ class AnalysisService:
             message=f"Analysis completed for demo {demo.id}",
             generated_at=datetime.utcnow(),
         )
+
+    def post_plant_report(
+        self,
+        session: Session,
+        team: str,
+        map_name: Optional[str] = None,
+        era: Optional[str] = None,
+        window: Optional[AnalysisWindow] = None,
+    ) -> PostPlantReport:
+        """Summarise a team's post-plant and retake success across processed demos."""
+
+        def build() -> PostPlantReport:
+            demo_ids = self._era_demo_ids(session, team, era)
+            scenarios, demo_count = self._collect_dataset(
+                session, "post_plant", map_name=map_name, demo_ids=demo_ids, window=window, team=team
+            )
+            summary = summarize_post_plant(scenarios, team)
+            return PostPlantReport(
+                team=team,
+                map_name=map_name,
+                demos_analyzed=demo_count,
+                scenarios=[ScenarioStats(**row) for row in summary.to_dict(orient="records")],
+                generated_at=datetime.utcnow(),
+            )
+
+        return self._cached(("post_plant", team.casefold(), map_name, era, window), build)
+
+    def pistol_report(
+        self,
+        session: Session,
+        team: str,
+        map_name: Optional[str] = None,
+        era: Optional[str] = None,
+        window: Optional[AnalysisWindow] = None,
+    ) -> PistolReport:
+        """Summarise a team's pistol-round buys and results per map and side."""
+
+        def build() -> PistolReport:
+            demo_ids = self._era_demo_ids(session, team, era)
+            pistols, demo_count = self._collect_dataset(
+                session, "pistol_rounds", map_name=map_name, demo_ids=demo_ids, window=window, team=team
+            )
+            return PistolReport(
+                team=team,
+                map_name=map_name,
+                demos_analyzed=demo_count,
+                sides=[PistolSideStats(**row) for row in summarize_pistol_rounds(pistols, team)],
+                generated_at=datetime.utcnow(),
+            )
+
+        return self._cached(("pistol_rounds", team.casefold(), map_name, era, window), build)
+
+    def save_report(
+        self,
+        session: Session,
+        team: str,
+        map_name: Optional[str] = None,
+        era: Optional[str] = None,
+        window: Optional[AnalysisWindow] = None,
+    ) -> SaveReport:
+        """Summarise what a team kept and how it bought after the rounds it lost."""
+
+        def build() -> SaveReport:
+            demo_ids = self._era_demo_ids(session, team, era)
+            saves, demo_count = self._collect_dataset(
+                session, "save_decisions", map_name=map_name, demo_ids=demo_ids, window=window, team=team
+            )
+            return SaveReport(
+                team=team,
+                map_name=map_name,
+                demos_analyzed=demo_count,
+                maps=[SaveMapStats(**row) for row in summarize_save_decisions(saves, team)],
+                generated_at=datetime.utcnow(),
+            )
+
+        return self._cached(("save_decisions", team.casefold(), map_name, era, window), build)
+
+    def duel_matrix(
+        self,
+        session: Session,
+        demo_id: Optional[str] = None,
+        steamid: Optional[str] = None,
+        map_name: Optional[str] = None,
+        window: Optional[AnalysisWindow] = None,
+    ) -> DuelMatrix:
+        """Who killed whom in one demo, or summed across the processed demos in ``window``."""
+
+        if demo_id and not DemoRepository(session).get(demo_id):
+            raise ValueError(f"Demo {demo_id} not found")
+
+        def build() -> DuelMatrix:
+            duels, demo_count = self._collect_dataset(
+                session,
+                "duels",
+                map_name=map_name,
+                demo_ids=[demo_id] if demo_id else None,
+                window=None if demo_id else window,
+            )
+            matrix = summarize_duels(duels, steamid)
+            return DuelMatrix(
+                demo_id=demo_id,
+                map_name=map_name,
+                steamid=steamid,
+                demos_analyzed=demo_count,
+                players=[DuelPlayer(**player) for player in matrix["players"]],
+                duels=[Duel(**duel) for duel in matrix["duels"]],
+                generated_at=datetime.utcnow(),
+            )
+
+        return self._cached(("duels", demo_id, steamid, map_name, None if demo_id else window), build)
+
+    def match_heatmap(
+        self,
+        session: Session,
+        match_id: str,
+        steamid: Optional[str] = None,
+        side: Optional[str] = None,
+        round_number: Optional[int] = None,
+        phase: Optional[str] = None,
+        bins: int = HEATMAP_BINS,
+    ) -> Heatmap:
+        """Where players stood in one match, from its ``player_ticks`` dataset."""
+
+        match = DemoRepository(session).get_match(match_id)
+        if match is None:
+            raise LookupError("Match not found")
+        ticks, found = self._collect_dataset(session, "player_ticks", demo_ids=[match.demo_id])
+        if not found:
+            raise LookupError("This match has no player positions; reprocess the demo to add them")
+        heatmap = build_heatmap(ticks, match.map_name, bins, steamid, side, round_number, phase)
+        return Heatmap(
+            match_id=match.id,
+            map_name=match.map_name,
+            steamid=steamid,
+            side=side,
+            round=round_number,
+            phase=phase,
+            **heatmap,
+        )
+
+    def refresh_player_roles(self, session: Session) -> list[PlayerRole]:
+        """Recompute inferred roles from every processed demo and replace the stored set."""
+
+        features, _ = self._collect_dataset(session, "role_features")
+        inferred = infer_roles(features)
+        updated_at = datetime.utcnow()
+
+        session.execute(delete(PlayerRole))
+        roles = [
+            PlayerRole(
+                steamid=str(row.steamid),
+                player_name=row.name if isinstance(row.name, str) else None,
+                team_name=row.team_name,
+                map_name=row.map_name,
+                role=row.role,
+                matches=int(row.matches),
+                rounds=int(row.rounds),
+                awp_kill_share=round(float(row.awp_kill_share), 3),
+                opening_duel_rate=round(float(row.opening_duel_rate), 3),
+                utility_per_round=round(float(row.utility_per_round), 3),
+                avg_death_order=round(float(row.avg_death_order), 3),
+                updated_at=updated_at,
+            )
+            for row in inferred.itertuples(index=False)
+        ]
+        session.add_all(roles)
+        session.commit()
+        return roles
+
+    def list_player_roles(
+        self,
+        session: Session,
+        map_name: Optional[str] = None,
+        team: Optional[str] = None,
+        steamid: Optional[str] = None,
+    ) -> list[PlayerRole]:
+        stmt = select(PlayerRole).order_by(PlayerRole.map_name, PlayerRole.team_name, PlayerRole.role)
+        if map_name:
+            stmt = stmt.where(PlayerRole.map_name == map_name)
+        if team:
+            stmt = stmt.where(PlayerRole.team_name == team)
+        if steamid:
+            stmt = stmt.where(PlayerRole.steamid == steamid)
+        return list(session.scalars(stmt).all())
+
+    def refresh_opponent_models(self, session: Session) -> list[OpponentSiteModel]:
+        """Refit every team's site-hit model from the processed demos and replace the stored set."""
+
+        site_hits, _ = self._collect_dataset(session, "site_hits")
+        updated_at = datetime.utcnow()
+
+        session.execute(delete(OpponentSiteModel))
+        models = [OpponentSiteModel(**fitted, updated_at=updated_at) for fitted in fit_site_models(site_hits)]
+        session.add_all(models)
+        session.commit()
+        return models
+
+    def list_opponent_models(self, session: Session, team: Optional[str] = None) -> list[OpponentSiteModel]:
+        stmt = select(OpponentSiteModel).order_by(OpponentSiteModel.team_name, OpponentSiteModel.map_name)
+        if team:
+            stmt = stmt.where(OpponentSiteModel.team_name == team)
+        return list(session.scalars(stmt).all())
+
+    def predict_site_hit(
+        self,
+        session: Session,
+        team: str,
+        map_name: str,
+        economy: Optional[str] = None,
+        score_for: Optional[int] = None,
+        score_against: Optional[int] = None,
+        elapsed_seconds: Optional[float] = None,
+    ) -> SitePrediction:
+        """Where ``team`` is likely to hit this T round, given what is known about it."""
+
+        model = session.scalars(
+            select(OpponentSiteModel).where(
+                OpponentSiteModel.team_name == team, OpponentSiteModel.map_name == map_name
+            )
+        ).first()
+        if model is None:
+            raise ValueError(f"No site model for team {team} on {map_name}")
+        state = score_state(score_for, score_against) if score_for is not None and score_against is not None else None
+        prediction = predict_site(model.counts or [], economy=economy, state=state, elapsed_seconds=elapsed_seconds)
+        probabilities = prediction["probabilities"]
+        return SitePrediction(
+            team=team,
+            map_name=map_name,
+            economy=economy,
+            score_state=state,
+            elapsed_seconds=elapsed_seconds,
+            probabilities=probabilities,
+            most_likely=max(HITS, key=lambda hit: probabilities[hit]),
+            rounds=prediction["rounds"],
+            matching_rounds=prediction["matching_rounds"],
+            updated_at=model.updated_at,
+        )
+
+    def roster_report(self, session: Session, team: str, min_changes: int = 1) -> RosterReport:
+        """Detect lineup changes across a team's matches."""
+
+        eras = self._roster_eras(session, team, min_changes)
+        return RosterReport(
+            team=team,
+            current_era=eras[-1]["era"] if eras else None,
+            eras=[RosterEra(**era) for era in eras],
+        )
+
+    def _roster_eras(self, session: Session, team: str, min_changes: int = 1) -> list[dict]:
+        appearances, _ = self._collect_dataset(session, "role_features")
+        return detect_roster_eras(appearances, team, min_changes=min_changes)
+
+    def _era_demo_ids(self, session: Session, team: str, era: Optional[str]) -> Optional[list[str]]:
+        """Resolve an era selector (``current`` or an era number) to its demo ids."""
+
+        if era is None:
+            return None
+        eras = self._roster_eras(session, team)
+        if not eras:
+            raise ValueError(f"No roster history found for team {team}")
+        if era == "current":
+            return eras[-1]["demo_ids"]
+        for candidate in eras:
+            if str(candidate["era"]) == era:
+                return candidate["demo_ids"]
+        raise ValueError(f"Roster era {era} not found for team {team}")
+
+    def _collect_dataset(
+        self,
+        session: Session,
+        name: str,
+        map_name: Optional[str] = None,
+        demo_ids: Optional[list[str]] = None,
+        window: Optional[AnalysisWindow] = None,
+        team: Optional[str] = None,
+    ) -> tuple[pd.DataFrame, int]:
+        """Concatenate a derived dataset across all processed demos.
+
+        Rows are tagged with ``demo_id``, ``demo_date`` and ``map_name``. Demos
+        without the dataset (unparsed uploads, missing files) are skipped, and so
+        are alternate sources of a game unless their demo is asked for by id.
+        ``window`` is applied last, so ``last_n_matches`` counts demos that have
+        the dataset (played by ``team``, when given).
+        """
+
+        window = window or AnalysisWindow()
+        keys: Dict[str, str] = {}
+        candidates = []
+        for demo in DemoRepository(session).list(competition_id=window.competition_id):
+            if demo_ids is not None and demo.id not in demo_ids:
+                continue
+            if demo_ids is None and demo.match is not None and not demo.match.is_primary:
+                continue
+            metadata = demo.extra_metadata or {}
+            if map_name and metadata.get("map_name") != map_name:
+                continue
+            key = (metadata.get("datasets") or {}).get(name)
+            if not key or not self.storage.exists(key):
+                continue
+            keys[demo.id] = key
+            candidates.append(demo)
+
+        frames = []
+        for demo in window.select(candidates, team):
+            metadata = demo.extra_metadata or {}
+            frame = pd.read_parquet(self.storage.get(keys[demo.id]))
+            frame["demo_id"] = demo.id
+            frame["demo_date"] = demo.uploaded_at
+            frame["map_name"] = metadata.get("map_name")
+            frames.append(frame)
+
+        if not frames:
+            return pd.DataFrame(), 0
+        return pd.concat(frames, ignore_index=True), len(frames)
+
+    def _cached(self, key: Tuple[Any, ...], build: Callable[[], Any]) -> Any:
+        now = self._clock()
+        with self._lock:
+            cached = self._cache.get(key)
+            if cached and now - cached[0] < self.settings.analysis_cache_seconds:
+                return cached[1]
+        value = build()
+        with self._lock:
+            self._cache[key] = (now, value)
+        return value
@@ -0,0 +1,59 @@
+from __future__ import annotations
+
+from dataclasses import dataclass
+from datetime import datetime
+from typing import Iterable, List, Optional
+
+from ...core.timeutil import to_utc_naive
+from ..demos.models import Demo
+
+
+@dataclass(frozen=True)
+class AnalysisWindow:
+    """The matches an aggregate covers.
+
+    Dates apply to when the match was played, falling back to the upload time
+    for demos without a match record. ``last_n_matches`` keeps the most
+    recent matches left after the other filters; with a team, only matches
+    the team played count, so a report covers the team's last N games.
+    """
+
+    played_from: Optional[datetime] = None
+    played_to: Optional[datetime] = None
+    last_n_matches: Optional[int] = None
+    competition_id: Optional[str] = None
+
+    def __post_init__(self) -> None:
+        if self.played_from:
+            object.__setattr__(self, "played_from", to_utc_naive(self.played_from))
+        if self.played_to:
+            object.__setattr__(self, "played_to", to_utc_naive(self.played_to))
+        if self.played_from and self.played_to and self.played_to < self.played_from:
+            raise ValueError("'to' must not be before 'from'")
+        if self.last_n_matches is not None and self.last_n_matches < 1:
+            raise ValueError("last_n_matches must be at least 1")
+
+    def select(self, demos: Iterable[Demo], team: Optional[str] = None) -> List[Demo]:
+        """Apply the dates and ``last_n_matches`` to ``demos``, newest first."""
+
+        selected = [
+            demo
+            for demo in sorted(demos, key=played_at, reverse=True)
+            if (not self.played_from or played_at(demo) >= self.played_from)
+            and (not self.played_to or played_at(demo) <= self.played_to)
+        ]
+        if self.last_n_matches is None:
+            return selected
+        if team:
+            selected = [demo for demo in selected if _played_by(demo, team)]
+        return selected[: self.last_n_matches]
+
+
+def played_at(demo: Demo) -> datetime:
+    return demo.match.played_at if demo.match is not None else demo.uploaded_at
+
+
+def _played_by(demo: Demo, team: str) -> bool:
+    if demo.match is None:
+        return False
+    return team.casefold() in {demo.match.team_a.casefold(), demo.match.team_b.casefold()}
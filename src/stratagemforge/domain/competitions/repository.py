@@ -0,0 +1,32 @@
+from __future__ import annotations
+
+from typing import List, Optional
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from .models import Competition
+
+
+class CompetitionRepository:
+    """Data access layer for competitions."""
+
+    def __init__(self, session: Session):
+        self.session = session
+
+    def list(self) -> List[Competition]:
+        stmt = select(Competition).order_by(Competition.start_date.desc(), Competition.name)
+        return list(self.session.scalars(stmt).all())
+
+    def get(self, competition_id: str) -> Optional[Competition]:
+        return self.session.get(Competition, competition_id)
+
+    def get_by_name(self, name: str) -> Optional[Competition]:
+        stmt = select(Competition).where(Competition.name == name)
+        return self.session.scalars(stmt).first()
+
+    def save(self, competition: Competition) -> Competition:
+        self.session.add(competition)
+        self.session.commit()
+        self.session.refresh(competition)
+        return competition
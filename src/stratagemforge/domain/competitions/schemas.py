@@ -0,0 +1,47 @@
+from __future__ import annotations
+
+from datetime import date
+from typing import List, Optional
+
+from pydantic import BaseModel, Field, model_validator
+
+from ...core.timeutil import UtcDateTime
+from ..demos.schemas import DemoSummary
+
+
+class CompetitionBase(BaseModel):
+    name: str = Field(min_length=1, max_length=255)
+    format: Optional[str] = Field(default=None, description="e.g. bo1, bo3, league, scrims")
+    start_date: Optional[date] = None
+    end_date: Optional[date] = None
+    description: Optional[str] = None
+
+    @model_validator(mode="after")
+    def _check_dates(self):
+        if self.start_date and self.end_date and self.end_date < self.start_date:
+            raise ValueError("end_date must not be before start_date")
+        return self
+
+
+class CompetitionCreate(CompetitionBase):
+    pass
+
+
+class CompetitionUpdate(BaseModel):
+    name: Optional[str] = Field(default=None, min_length=1, max_length=255)
+    format: Optional[str] = None
+    start_date: Optional[date] = None
+    end_date: Optional[date] = None
+    description: Optional[str] = None
+
+
+class CompetitionSummary(CompetitionBase):
+    id: str
+    created_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class CompetitionDetail(CompetitionSummary):
+    demos: List[DemoSummary] = Field(default_factory=list)
@@ -0,0 +1,48 @@
+from __future__ import annotations
+
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ..demos.models import Demo
+from ..demos.repository import DemoRepository
+from .models import Competition
+from .repository import CompetitionRepository
+from .schemas import CompetitionCreate, CompetitionUpdate
+
+
+class CompetitionService:
+    """Manage competitions and the demos attached to them."""
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+
+    def list_competitions(self, session: Session) -> list[Competition]:
+        return CompetitionRepository(session).list()
+
+    def get_competition(self, session: Session, competition_id: str) -> Competition | None:
+        return CompetitionRepository(session).get(competition_id)
+
+    def create_competition(self, session: Session, payload: CompetitionCreate) -> Competition:
+        repo = CompetitionRepository(session)
+        if repo.get_by_name(payload.name):
+            raise ValueError(f"Competition {payload.name} already exists")
+        return repo.save(Competition(**payload.model_dump()))
+
+    def update_competition(self, session: Session, competition_id: str, payload: CompetitionUpdate) -> Competition:
+        repo = CompetitionRepository(session)
+        competition = repo.get(competition_id)
+        if not competition:
+            raise LookupError("Competition not found")
+
+        changes = payload.model_dump(exclude_unset=True)
+        if "name" in changes and changes["name"] != competition.name and repo.get_by_name(changes["name"]):
+            raise ValueError(f"Competition {changes['name']} already exists")
+        for field, value in changes.items():
+            setattr(competition, field, value)
+        start, end = competition.start_date, competition.end_date
+        if start and end and end < start:
+            raise ValueError("end_date must not be before start_date")
+        return repo.save(competition)
+
+    def list_competition_demos(self, session: Session, competition_id: str) -> list[Demo]:
+        return DemoRepository(session).list(competition_id=competition_id)
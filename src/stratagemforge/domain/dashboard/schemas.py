@@ -0,0 +1,44 @@
+from __future__ import annotations
+
+from typing import List, Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+from ..demos.schemas import MatchOverview
+
+
+class ProcessingJob(BaseModel):
+    demo_id: str
+    original_filename: str
+    status: str
+    uploaded_at: UtcDateTime
+
+
+class WinrateTrendPoint(BaseModel):
+    week: str = Field(description="ISO week, e.g. 2024-W07")
+    matches: int
+    wins: int
+    win_rate: float
+
+
+class TopPerformer(BaseModel):
+    steamid: Optional[str] = None
+    name: Optional[str] = None
+    matches: int
+    kills: int
+    deaths: int
+    kd_ratio: Optional[float] = None
+    adr: Optional[float] = None
+    rating: Optional[float] = None
+
+
+class Dashboard(BaseModel):
+    recent_matches: List[MatchOverview]
+    processing: List[ProcessingJob]
+    processing_count: int
+    team: Optional[str] = Field(default=None, description="Team the win-rate trend is computed for")
+    winrate_trend: List[WinrateTrendPoint]
+    top_performers: List[TopPerformer]
+    timezone: str = Field(default="UTC", description="Timezone the weekly buckets were computed in")
+    generated_at: UtcDateTime
@@ -0,0 +1,171 @@
+from __future__ import annotations
+
+import threading
+import time
+from collections import Counter, defaultdict
+from datetime import datetime, timedelta
+from typing import Any, Dict, List, Optional, Tuple
+from zoneinfo import ZoneInfo
+
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.timeutil import resolve_timezone, to_local
+from ..demos.models import Demo, Match
+from ..demos.repository import DemoRepository
+from ..demos.schemas import MatchOverview
+from ..stats.repository import StatsRepository
+from ..users.models import User
+from .schemas import Dashboard, ProcessingJob, TopPerformer, WinrateTrendPoint
+
+RECENT_MATCHES = 5
+TOP_PERFORMERS = 5
+PERFORMER_WINDOW = timedelta(days=7)
+
+
+class DashboardService:
+    """Assemble the home-page numbers in one call.
+
+    The aggregates (recent matches, win-rate trend, top performers) are cached
+    for ``dashboard_cache_seconds`` and dropped whenever a demo finishes
+    processing. Demos still waiting to be processed are always read live.
+    Weekly buckets follow the requested timezone (or the user's preference),
+    so a Sunday-evening scrim in Europe lands in the week the team played it.
+    """
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+        self._cache: Dict[Tuple[Optional[str], int, str], Tuple[float, Dict[str, Any]]] = {}
+        self._lock = threading.Lock()
+
+    def dashboard(
+        self,
+        session: Session,
+        team: Optional[str] = None,
+        weeks: int = 8,
+        timezone: Optional[str] = None,
+        user_id: Optional[str] = None,
+    ) -> Dashboard:
+        if timezone is None and user_id:
+            user = session.get(User, user_id)
+            if not user:
+                raise LookupError("User not found")
+            timezone = user.timezone
+        tz = resolve_timezone(timezone)
+        aggregates = self._cached_aggregates(session, team, weeks, tz)
+        pending = DemoRepository(session).in_progress()
+        return Dashboard(
+            processing=[
+                ProcessingJob(
+                    demo_id=demo.id,
+                    original_filename=demo.original_filename,
+                    status=demo.status,
+                    uploaded_at=demo.uploaded_at,
+                )
+                for demo in pending
+            ],
+            processing_count=len(pending),
+            timezone=tz.key,
+            **aggregates,
+        )
+
+    def invalidate(self) -> None:
+        with self._lock:
+            self._cache.clear()
+
+    def invalidate_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: a new match changes every aggregate."""
+
+        self.invalidate()
+
+    def _cached_aggregates(self, session: Session, team: Optional[str], weeks: int, tz: ZoneInfo) -> Dict[str, Any]:
+        key = (team.casefold() if team else None, weeks, tz.key)
+        now = time.monotonic()
+        with self._lock:
+            cached = self._cache.get(key)
+            if cached and now - cached[0] < self.settings.dashboard_cache_seconds:
+                return cached[1]
+
+        aggregates = self._aggregates(session, team, weeks, tz)
+        with self._lock:
+            self._cache[key] = (now, aggregates)
+        return aggregates
+
+    def _aggregates(self, session: Session, team: Optional[str], weeks: int, tz: ZoneInfo) -> Dict[str, Any]:
+        now = datetime.utcnow()
+        repo = DemoRepository(session)
+        recent = repo.matches(limit=RECENT_MATCHES)
+        trend_matches = repo.matches(played_from=now - timedelta(weeks=weeks))
+        team = team or _most_played_team(trend_matches)
+        return {
+            "recent_matches": [MatchOverview.from_orm(match) for match in recent],
+            "team": team,
+            "winrate_trend": _winrate_trend(trend_matches, team, tz) if team else [],
+            "top_performers": self._top_performers(session, now - PERFORMER_WINDOW),
+            "generated_at": now,
+        }
+
+    def _top_performers(self, session: Session, since: datetime) -> List[TopPerformer]:
+        # Demo-derived rows come first so an imported row for the same match and player is skipped.
+        stats = sorted(StatsRepository(session).list(played_from=since, primary_only=True), key=lambda stat: stat.source != "demo")
+        seen = set()
+        players: Dict[str, Dict[str, Any]] = defaultdict(
+            lambda: {"steamid": None, "name": None, "matches": 0, "kills": 0, "deaths": 0, "adr": [], "rating": []}
+        )
+        for stat in stats:
+            if stat.match_id:
+                key = (stat.match_id, stat.steamid or stat.player_key)
+                if key in seen:
+                    continue
+                seen.add(key)
+            bucket = players[stat.steamid or stat.player_key]
+            bucket["steamid"] = bucket["steamid"] or stat.steamid
+            bucket["name"] = bucket["name"] or stat.player_name
+            bucket["matches"] += 1
+            bucket["kills"] += stat.kills or 0
+            bucket["deaths"] += stat.deaths or 0
+            if stat.adr is not None:
+                bucket["adr"].append(stat.adr)
+            if stat.rating is not None:
+                bucket["rating"].append(stat.rating)
+
+        performers = [
+            TopPerformer(
+                steamid=bucket["steamid"],
+                name=bucket["name"],
+                matches=bucket["matches"],
+                kills=bucket["kills"],
+                deaths=bucket["deaths"],
+                kd_ratio=round(bucket["kills"] / bucket["deaths"], 2) if bucket["deaths"] else None,
+                adr=_mean(bucket["adr"]),
+                rating=_mean(bucket["rating"]),
+            )
+            for bucket in players.values()
+        ]
+        performers.sort(key=lambda p: (p.rating or 0.0, p.adr or 0.0, p.kills), reverse=True)
+        return performers[:TOP_PERFORMERS]
+
+
+def _most_played_team(matches: List[Match]) -> Optional[str]:
+    counts = Counter(name for match in matches for name in (match.team_a, match.team_b) if name)
+    return counts.most_common(1)[0][0] if counts else None
+
+
+def _winrate_trend(matches: List[Match], team: str, tz: ZoneInfo) -> List[WinrateTrendPoint]:
+    wanted = team.casefold()
+    weeks: Dict[str, List[int]] = defaultdict(lambda: [0, 0])
+    for match in matches:
+        if wanted not in {(match.team_a or "").casefold(), (match.team_b or "").casefold()}:
+            continue
+        year, week, _ = to_local(match.played_at, tz).isocalendar()
+        bucket = weeks[f"{year}-W{week:02d}"]
+        bucket[0] += 1
+        bucket[1] += int((match.winner or "").casefold() == wanted)
+    return [
+        WinrateTrendPoint(week=week, matches=played, wins=wins, win_rate=round(wins / played, 3))
+        for week, (played, wins) in sorted(weeks.items())
+    ]
+
+
+def _mean(values: List[float]) -> Optional[float]:
+    return round(sum(values) / len(values), 2) if values else None
@@ -0,0 +1,160 @@
+from __future__ import annotations
+
+import bz2
+import gzip
+import hashlib
+import zipfile
+import zlib
+from pathlib import Path
+from typing import IO, List, Optional, Tuple
+from uuid import uuid4
+
+from .parser import CS2_MAGIC, CSGO_MAGIC
+
+DEMO_SUFFIXES = (".dem", ".dem.gz", ".dem.bz2")
+SUPPORTED_SUFFIXES = DEMO_SUFFIXES + (".zip",)
+
+_MAGIC_BYTES = {
+    b"\x1f\x8b": "gzip",
+    b"BZh": "bz2",
+    b"PK\x03\x04": "zip",
+}
+DEMO_MAGIC = (CS2_MAGIC, CSGO_MAGIC)
+
+
+class InvalidDemoError(ValueError):
+    """Raised when a file does not start with a CS2 or CS:GO demo header."""
+
+
+class UploadTooLargeError(ValueError):
+    """Raised when an upload, or what it decompresses to, exceeds ``max_upload_size``."""
+
+
+def is_supported_filename(filename: str) -> bool:
+    return filename.lower().endswith(SUPPORTED_SUFFIXES)
+
+
+def detect_compression(path: Path) -> Optional[str]:
+    """Return ``gzip``, ``bz2`` or ``zip`` based on the file's magic bytes."""
+
+    with path.open("rb") as handle:
+        head = handle.read(4)
+    for magic, kind in _MAGIC_BYTES.items():
+        if head.startswith(magic):
+            return kind
+    return None
+
+
+def is_demo_header(head: bytes) -> bool:
+    return head.startswith(DEMO_MAGIC)
+
+
+def check_upload_header(head: bytes) -> None:
+    """Reject the first bytes of an upload that is neither a demo nor a supported archive."""
+
+    if not is_demo_header(head) and not any(head.startswith(magic) for magic in _MAGIC_BYTES):
+        raise InvalidDemoError("File is not a CS2 or CS:GO demo")
+
+
+def check_demo_file(path: Path) -> None:
+    """Reject a decompressed file that does not carry a demo header."""
+
+    with path.open("rb") as handle:
+        head = handle.read(max(len(magic) for magic in DEMO_MAGIC))
+    if not is_demo_header(head):
+        raise InvalidDemoError("File is not a CS2 or CS:GO demo")
+
+
+def extract_demo(source: Path, destination: Path, max_size: int, chunk_size: int = 4 * 1024 * 1024) -> Tuple[str, int]:
+    """Decompress ``source`` into ``destination`` and return the demo's checksum and size.
+
+    Zip archives must contain exactly one ``.dem`` entry. The decompressed size is
+    capped at ``max_size`` so small archives cannot expand without bound.
+    """
+
+    kind = detect_compression(source)
+    if kind == "gzip":
+        with gzip.open(source, "rb") as stream:
+            return _copy_limited(stream, destination, max_size, chunk_size)
+    if kind == "bz2":
+        with bz2.open(source, "rb") as stream:
+            return _copy_limited(stream, destination, max_size, chunk_size)
+    if kind == "zip":
+        try:
+            archive = zipfile.ZipFile(source)
+        except zipfile.BadZipFile as exc:
+            raise ValueError(f"Could not read zip archive: {exc}") from exc
+        with archive:
+            entry = _single_demo_entry(archive)
+            with archive.open(entry) as stream:
+                return _copy_limited(stream, destination, max_size, chunk_size)
+    raise ValueError("Unsupported or unrecognised compression format")
+
+
+def extract_demo_entries(
+    source: Path, directory: Path, max_size: int, max_entries: int, chunk_size: int = 4 * 1024 * 1024
+) -> List[Tuple[str, Path, int]]:
+    """Copy every demo out of the zip archive ``source`` into ``directory``.
+
+    Entries may be ``.dem`` files or compressed demos (``.dem.gz``, ``.dem.bz2``),
+    which the upload pipeline decompresses later; other files are ignored. Returns
+    each demo's file name, where it was written and its size, in archive order.
+    """
+
+    try:
+        archive = zipfile.ZipFile(source)
+    except zipfile.BadZipFile as exc:
+        raise ValueError(f"Could not read zip archive: {exc}") from exc
+    written: List[Tuple[str, Path, int]] = []
+    with archive:
+        entries = [
+            info for info in archive.infolist() if not info.is_dir() and info.filename.lower().endswith(DEMO_SUFFIXES)
+        ]
+        if not entries:
+            raise ValueError("Zip archive contains no .dem files")
+        if len(entries) > max_entries:
+            raise ValueError(f"Zip archive contains {len(entries)} demos; at most {max_entries} are allowed")
+        try:
+            for entry in entries:
+                destination = directory / f"{uuid4().hex}.tmp"
+                with archive.open(entry) as stream:
+                    _, size = _copy_limited(stream, destination, max_size, chunk_size)
+                written.append((Path(entry.filename).name, destination, size))
+        except ValueError:
+            for _, path, _ in written:
+                path.unlink(missing_ok=True)
+            raise
+    return written
+
+
+def _single_demo_entry(archive: zipfile.ZipFile) -> zipfile.ZipInfo:
+    entries = [info for info in archive.infolist() if not info.is_dir()]
+    if len(entries) != 1:
+        raise ValueError("Zip archives must contain exactly one .dem file")
+    entry = entries[0]
+    if not entry.filename.lower().endswith(".dem"):
+        raise ValueError("Zip archive entry is not a .dem file")
+    return entry
+
+
+def _copy_limited(stream: IO[bytes], destination: Path, max_size: int, chunk_size: int) -> Tuple[str, int]:
+    checksum = hashlib.sha256()
+    total_size = 0
+    try:
+        with destination.open("wb") as buffer:
+            while True:
+                chunk = stream.read(chunk_size)
+                if not chunk:
+                    break
+                total_size += len(chunk)
+                if total_size > max_size:
+                    raise UploadTooLargeError("Decompressed demo exceeds maximum allowed size")
+                checksum.update(chunk)
+                buffer.write(chunk)
+    except (OSError, EOFError, zlib.error, zipfile.BadZipFile) as exc:
+        destination.unlink(missing_ok=True)
+        raise ValueError(f"Could not decompress demo: {exc}") from exc
+    except ValueError:
+        destination.unlink(missing_ok=True)
+        raise
+    return checksum.hexdigest(), total_size
@@ -0,0 +1,87 @@
+"""Sanity checks of parsed positions against the playable area of each map.
+
+Entity glitches occasionally report absurd coordinates (a smoke "landing"
+30,000 units outside the map). Such positions are cleared before datasets
+are built, so they never reach heatmaps or landing spots; the event itself
+is kept and the number of cleared positions goes into the demo's data
+quality report.
+
+Bounds come from the radar overviews (see :mod:`.radar`) plus a margin.
+Maps without an entry are only checked against the limits of the engine's
+world.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+from typing import Dict, Optional
+
+import pandas as pd
+
+from .parser import ParsedDemo
+from .radar import RADAR_SIZE, RADARS, map_key
+# Slack around the radar area; players can stand on roofs and skyboxes the radar clips.
+MARGIN = 256.0
+# Source 2 keeps every entity within +-16384 units on each axis.
+WORLD_LIMIT = 16384.0
+# ParsedDemo tables with x/y/z position columns.
+POSITION_TABLES = ("grenade_events", "round_start_state", "player_ticks")
+
+
+@dataclass(frozen=True)
+class MapBounds:
+    min_x: float
+    max_x: float
+    min_y: float
+    max_y: float
+
+    @classmethod
+    def from_radar(cls, pos_x: float, pos_y: float, scale: float) -> "MapBounds":
+        """Area covered by a radar overview whose top-left corner is (``pos_x``, ``pos_y``)."""
+
+        size = RADAR_SIZE * scale
+        return cls(pos_x - MARGIN, pos_x + size + MARGIN, pos_y - size - MARGIN, pos_y + MARGIN)
+
+
+MAP_BOUNDS: Dict[str, MapBounds] = {
+    name: MapBounds.from_radar(radar.pos_x, radar.pos_y, radar.scale) for name, radar in RADARS.items()
+}
+
+
+def map_bounds(map_name: Optional[str]) -> Optional[MapBounds]:
+    key = map_key(map_name)
+    return MAP_BOUNDS.get(key) if key else None
+
+
+def position_outliers(frame: pd.DataFrame, bounds: Optional[MapBounds]) -> pd.Series:
+    """Rows whose position lies outside ``bounds`` or the world; rows without a position pass."""
+
+    if frame.empty or not {"x", "y"} <= set(frame.columns):
+        return pd.Series(False, index=frame.index)
+    coords = {axis: pd.to_numeric(frame[axis], errors="coerce") for axis in ("x", "y", "z") if axis in frame}
+    outside = pd.Series(False, index=frame.index)
+    for values in coords.values():
+        outside |= values.abs() > WORLD_LIMIT
+    if bounds is not None:
+        outside |= (coords["x"] < bounds.min_x) | (coords["x"] > bounds.max_x)
+        outside |= (coords["y"] < bounds.min_y) | (coords["y"] > bounds.max_y)
+    return outside
+
+
+def clear_position_outliers(parsed: ParsedDemo) -> Dict[str, int]:
+    """Blank out implausible positions in place; returns how many rows of each table were affected."""
+
+    bounds = map_bounds(parsed.map_name)
+    cleared: Dict[str, int] = {}
+    for table in POSITION_TABLES:
+        frame = getattr(parsed, table)
+        outside = position_outliers(frame, bounds)
+        if not outside.any():
+            continue
+        frame = frame.copy()
+        axes = [axis for axis in ("x", "y", "z") if axis in frame.columns]
+        frame[axes] = frame[axes].apply(pd.to_numeric, errors="coerce")
+        frame.loc[outside, axes] = float("nan")
+        setattr(parsed, table, frame)
+        cleared[table] = int(outside.sum())
+    return cleared
@@ -0,0 +1,299 @@
+"""Record CS2 GOTV+ broadcasts while the match is still being played.
+
+A broadcast (the game server's ``tv_broadcast_url`` or a relay in front of it)
+answers ``/sync`` with its newest fragment and serves every fragment as
+``/{n}/start`` (signon data), ``/{n}/full`` (a complete snapshot) and
+``/{n}/delta`` (changes since fragment ``n - 1``). The recorder writes the
+start fragment, one full snapshot and then every delta behind a CS2 demo
+header, so the parsers read the recording like a demo that stopped early.
+
+Every ``broadcast_segment_fragments`` fragments the recording is parsed
+again and what happened since the previous segment is written to
+``processed/live/<id>/segment-<n>/``: rows of tick-stamped datasets (the
+kill feed) newer than the segment before, and rows of per-round datasets for
+rounds finished since. Per-player datasets are rewritten in
+``processed/live/<id>/`` as running totals, and the live round and score
+are updated. Once the broadcast stops sending fragments the recording goes
+through the normal upload pipeline and becomes a regular demo.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import struct
+from datetime import datetime, timedelta
+from pathlib import Path
+from http.client import IncompleteRead
+from typing import Any, Dict, List, Optional
+from urllib.error import HTTPError, URLError
+from urllib.parse import urlparse
+from urllib.request import Request
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from ...core import metrics
+from ...core.config import Settings
+from ...core.netguard import allowed_hosts, public_opener
+from ...core.parquet import ParquetOptions, write_parquet
+from .bounds import clear_position_outliers
+from .extractors import DATASET_BUILDERS, DATASET_SCHEMA_VERSIONS
+from .extractors.match import build_match_info
+from .models import LiveBroadcast
+from .processor import DemoProcessor
+from .retry import DemoProcessingError
+
+logger = logging.getLogger(__name__)
+
+# CS2 demo magic followed by the file-info and spawn-group offsets, unknown while recording.
+RECORDING_HEADER = b"PBDEMS2\x00" + struct.pack("<ii", 0, 0)
+ACTIVE_STATUSES = ("connecting", "live")
+# Deltas fetched per poll at most, so one broadcast that fell behind cannot starve the others.
+MAX_FRAGMENTS_PER_POLL = 32
+
+
+class BroadcastClient:
+    """Minimal client for the CS2 HTTP broadcast protocol; like demo downloads it only reaches public addresses."""
+
+    def __init__(self, timeout: float = 10.0, allowed_hosts: frozenset[str] = frozenset()) -> None:
+        self.timeout = timeout
+        self._opener = public_opener(allowed_hosts)
+
+    def sync(self, url: str) -> Optional[Dict[str, Any]]:
+        """The broadcast's sync document, or ``None`` when the broadcast is not (or no longer) there."""
+
+        body = self._get(f"{url.rstrip('/')}/sync")
+        return json.loads(body) if body is not None else None
+
+    def fragment(self, url: str, number: int, kind: str) -> Optional[bytes]:
+        """One ``start``, ``full`` or ``delta`` fragment, or ``None`` when it has not been produced yet."""
+
+        return self._get(f"{url.rstrip('/')}/{number}/{kind}")
+
+    def _get(self, url: str) -> Optional[bytes]:
+        request = Request(url, headers={"User-Agent": "StratagemForge/1.0"})
+        try:
+            with self._opener.open(request, timeout=self.timeout) as response:
+                return response.read()
+        except HTTPError as exc:
+            if exc.code == 404:
+                return None
+            raise ValueError(f"Broadcast request failed with HTTP {exc.code}") from exc
+        except (URLError, TimeoutError, ConnectionError, IncompleteRead) as exc:
+            raise ValueError(f"Broadcast request failed: {exc}") from exc
+
+
+class BroadcastService:
+    """Record live broadcasts and keep their partial datasets and score up to date."""
+
+    def __init__(
+        self, settings: Settings, processor: DemoProcessor | None = None, client: BroadcastClient | None = None
+    ) -> None:
+        self.settings = settings
+        self.processor = processor or DemoProcessor(
+            settings.processed_data_path,
+            radar_columns=settings.dataset_radar_columns,
+            parquet_options=ParquetOptions.from_settings(settings),
+        )
+        self.client = client or BroadcastClient(
+            timeout=settings.download_timeout_seconds, allowed_hosts=allowed_hosts(settings.outbound_allowed_hosts)
+        )
+        self.live_dir = settings.processed_data_path / "live"
+
+    def start(
+        self, session: Session, url: str, owner_id: str | None = None, team_id: str | None = None
+    ) -> LiveBroadcast:
+        if urlparse(url).scheme not in {"http", "https"}:
+            raise ValueError("Only http and https broadcast URLs are supported")
+        broadcast = LiveBroadcast(url=url, owner_id=owner_id, team_id=team_id, recording_path="")
+        session.add(broadcast)
+        session.flush()
+        broadcast.recording_path = str(self.settings.raw_data_path / f"live-{broadcast.id}.dem")
+        session.commit()
+        session.refresh(broadcast)
+        return broadcast
+
+    def list_broadcasts(self, session: Session, active_only: bool = False) -> List[LiveBroadcast]:
+        stmt = select(LiveBroadcast).order_by(LiveBroadcast.started_at.desc())
+        if active_only:
+            stmt = stmt.where(LiveBroadcast.status.in_(ACTIVE_STATUSES))
+        return list(session.scalars(stmt))
+
+    def get(self, session: Session, broadcast_id: str) -> LiveBroadcast:
+        broadcast = session.get(LiveBroadcast, broadcast_id)
+        if not broadcast:
+            raise LookupError("Broadcast not found")
+        return broadcast
+
+    def stop(self, session: Session, broadcast_id: str) -> LiveBroadcast:
+        """Stop recording; what was recorded so far is ingested like a finished broadcast."""
+
+        broadcast = self.get(session, broadcast_id)
+        if broadcast.status not in ACTIVE_STATUSES:
+            raise ValueError("Broadcast is not being recorded")
+        self._end(broadcast)
+        session.commit()
+        return broadcast
+
+    def segment_path(self, broadcast: LiveBroadcast, number: int, dataset: str) -> Path:
+        return self.live_dir / broadcast.id / f"segment-{number:04d}" / f"{dataset}.parquet"
+
+    def poll_all(self, session: Session) -> None:
+        for broadcast in self.list_broadcasts(session, active_only=True):
+            try:
+                self.poll(session, broadcast)
+            except ValueError as exc:
+                logger.warning("Polling broadcast %s failed: %s", broadcast.id, exc)
+                broadcast.error = str(exc)
+            session.commit()
+
+    def poll(self, session: Session, broadcast: LiveBroadcast) -> int:
+        """Fetch the fragments published since the last poll; returns how many were recorded."""
+
+        now = datetime.utcnow()
+        sync = self.client.sync(broadcast.url)
+        if sync is None:
+            self._expire(broadcast, now, "Broadcast not found")
+            return 0
+        broadcast.map_name = sync.get("map") or broadcast.map_name
+        recording = Path(broadcast.recording_path)
+        recorded = 0
+        if broadcast.fragment is None:
+            start = self.client.fragment(broadcast.url, int(sync["signup_fragment"]), "start")
+            full = self.client.fragment(broadcast.url, int(sync["fragment"]), "full")
+            if start is None or full is None:
+                self._expire(broadcast, now, "Broadcast has no start fragment yet")
+                return 0
+            recording.write_bytes(RECORDING_HEADER + start + full)
+            broadcast.fragment = broadcast.segment_fragment = int(sync["fragment"])
+            broadcast.status = "live"
+            recorded = 1
+
+        with recording.open("ab") as stream:
+            while recorded < MAX_FRAGMENTS_PER_POLL:
+                delta = self.client.fragment(broadcast.url, broadcast.fragment + 1, "delta")
+                if delta is None:
+                    break
+                stream.write(delta)
+                broadcast.fragment += 1
+                recorded += 1
+        if sync.get("tick") is not None:
+            broadcast.tick = int(sync["tick"])
+
+        if recorded:
+            broadcast.updated_at = now
+            broadcast.error = None
+            metrics.BROADCAST_FRAGMENTS.inc(recorded)
+        if recording.stat().st_size > self.settings.max_upload_size:
+            self._end(broadcast)
+        elif not recorded:
+            self._expire(broadcast, now, None)
+        if broadcast.fragment - (broadcast.segment_fragment or 0) >= self.settings.broadcast_segment_fragments:
+            self.write_segment(broadcast)
+        return recorded
+
+    def write_segment(self, broadcast: LiveBroadcast) -> Optional[Dict[str, Any]]:
+        """Parse the recording so far and write what happened since the previous segment."""
+
+        broadcast.segment_fragment = broadcast.fragment
+        if self.processor.parser is None:
+            return None
+        try:
+            parsed = self.processor.parser.parse(Path(broadcast.recording_path))
+        except Exception as exc:  # noqa: BLE001 - a recording cut mid-packet may not parse; the next one will
+            logger.info("Broadcast %s recording did not parse yet: %s", broadcast.id, exc)
+            broadcast.error = str(exc)
+            return None
+
+        if not parsed.map_name and broadcast.map_name:
+            parsed.header["map_name"] = broadcast.map_name  # relay recordings start without the file header
+        clear_position_outliers(parsed)
+        directory = self.live_dir / broadcast.id
+        segments = list(broadcast.segments or [])
+        number = len(segments) + 1
+        previous = segments[-1] if segments else {"up_to_tick": -1, "up_to_round": 0}
+        finished = parsed.rounds[parsed.rounds["end_tick"].notna()]
+        up_to_round = int(finished["round"].max()) if not finished.empty else previous["up_to_round"]
+        up_to_tick = previous["up_to_tick"]
+        written = []
+        for name, builder in DATASET_BUILDERS.items():
+            frame = builder(parsed)
+            if "tick" in frame.columns:
+                rows = frame[frame["tick"] > previous["up_to_tick"]]
+                if not rows.empty:
+                    up_to_tick = max(up_to_tick, int(rows["tick"].max()))
+            elif "round" in frame.columns:
+                rows = frame[(frame["round"] > previous["up_to_round"]) & (frame["round"] <= up_to_round)]
+            else:
+                directory.mkdir(parents=True, exist_ok=True)
+                write_parquet(
+                    frame,
+                    directory / f"{name}.parquet",
+                    name,
+                    DATASET_SCHEMA_VERSIONS[name],
+                    options=self.processor.parquet_options,
+                )
+                continue
+            if rows.empty:
+                continue
+            path = self.segment_path(broadcast, number, name)
+            path.parent.mkdir(parents=True, exist_ok=True)
+            write_parquet(rows, path, name, DATASET_SCHEMA_VERSIONS[name], options=self.processor.parquet_options)
+            written.append(name)
+
+        match = build_match_info(parsed)
+        broadcast.map_name = parsed.map_name or broadcast.map_name
+        broadcast.rounds_played = int(len(parsed.rounds))
+        broadcast.team_a, broadcast.team_b = match["team_a"], match["team_b"]
+        broadcast.score_a, broadcast.score_b = match["score_a"], match["score_b"]
+        if not written:
+            return None
+        segment = {"number": number, "up_to_tick": up_to_tick, "up_to_round": up_to_round, "datasets": written}
+        broadcast.segments = segments + [segment]
+        return segment
+
+    def ended(self, session: Session) -> List[LiveBroadcast]:
+        return list(session.scalars(select(LiveBroadcast).where(LiveBroadcast.status == "ending")))
+
+    async def finish(self, session: Session, broadcast: LiveBroadcast, demos) -> LiveBroadcast:
+        """Hand the complete recording to the upload pipeline (a ``DemoService``)."""
+
+        try:
+            if broadcast.fragment is None:
+                raise ValueError("Nothing was recorded")
+            self.write_segment(broadcast)
+            demo, _ = await demos.ingest_recording(
+                session,
+                Path(broadcast.recording_path),
+                filename=f"broadcast-{broadcast.id}.dem",
+                owner_id=broadcast.owner_id,
+                team_id=broadcast.team_id,
+                metadata={"source_url": broadcast.url, "broadcast_id": broadcast.id},
+            )
+        except (ValueError, OSError, DemoProcessingError) as exc:
+            broadcast.status, broadcast.error = "failed", str(exc)
+        else:
+            broadcast.status, broadcast.demo_id, broadcast.error = "finished", demo.id, None
+        broadcast.finished_at = datetime.utcnow()
+        session.commit()
+        return broadcast
+
+    def _expire(self, broadcast: LiveBroadcast, now: datetime, error: Optional[str]) -> None:
+        """End (or, before anything was recorded, fail) a broadcast that has been quiet for too long."""
+
+        if now - broadcast.updated_at < timedelta(seconds=self.settings.broadcast_idle_seconds):
+            broadcast.error = error
+            return
+        if broadcast.fragment is None:
+            broadcast.status, broadcast.error = "failed", error or "Broadcast never started"
+            broadcast.finished_at = now
+        else:
+            self._end(broadcast)
+
+    @staticmethod
+    def _end(broadcast: LiveBroadcast) -> None:
+        broadcast.status = "ending" if broadcast.fragment is not None else "failed"
+        if broadcast.status == "failed":
+            broadcast.error = "Broadcast never started"
+            broadcast.finished_at = datetime.utcnow()
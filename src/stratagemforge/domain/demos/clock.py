@@ -0,0 +1,69 @@
+"""Map demo ticks onto demo-relative seconds and estimated wall-clock time.
+
+Review tools use this to line VODs or recordings of the same game up with
+parsed events. Tick 0 is the start of the recording; its wall-clock time is
+the match's ``started_at`` (recovered from the demo file name), so the
+estimate is only as good as that stamp and the server's tick rate.
+"""
+
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+from typing import List, Optional
+
+from .models import Match
+from .parser import DEMO_TICK_RATE
+from .schemas import MatchClock, RoundClock
+
+
+class TickClock:
+    def __init__(self, tick_rate: Optional[float] = None, started_at: Optional[datetime] = None) -> None:
+        self.tick_rate = tick_rate or DEMO_TICK_RATE
+        self.started_at = started_at
+
+    def seconds(self, tick: Optional[int]) -> Optional[float]:
+        """Seconds since the start of the recording."""
+
+        return None if tick is None else round(tick / self.tick_rate, 3)
+
+    def wall_clock(self, tick: Optional[int]) -> Optional[datetime]:
+        """Estimated real-world time of ``tick`` (naive UTC), when the recording start is known."""
+
+        if tick is None or self.started_at is None:
+            return None
+        return self.started_at + timedelta(seconds=tick / self.tick_rate)
+
+    def tick_at(self, moment: datetime) -> Optional[int]:
+        """The tick closest to a wall-clock time (naive UTC), e.g. a timestamp in a recording."""
+
+        if self.started_at is None:
+            return None
+        return round((moment - self.started_at).total_seconds() * self.tick_rate)
+
+
+def match_clock(match: Match) -> MatchClock:
+    clock = TickClock(match.tick_rate, match.started_at)
+    rounds: List[RoundClock] = []
+    for entry in match.round_ticks or []:
+        start, freeze_end, end = entry.get("start_tick"), entry.get("freeze_end_tick"), entry.get("end_tick")
+        rounds.append(
+            RoundClock(
+                round=entry["round"],
+                start_tick=start,
+                freeze_end_tick=freeze_end,
+                end_tick=end,
+                start_seconds=clock.seconds(start),
+                freeze_end_seconds=clock.seconds(freeze_end),
+                end_seconds=clock.seconds(end),
+                start_at=clock.wall_clock(start),
+                freeze_end_at=clock.wall_clock(freeze_end),
+                end_at=clock.wall_clock(end),
+            )
+        )
+    return MatchClock(
+        match_id=match.id,
+        tick_rate=clock.tick_rate,
+        started_at=match.started_at,
+        started_at_source=match.started_at_source,
+        rounds=rounds,
+    )
@@ -0,0 +1,72 @@
+from __future__ import annotations
+
+import asyncio
+from contextlib import asynccontextmanager
+from typing import AsyncIterator, Dict
+
+
+class ParseQueueFullError(RuntimeError):
+    """Raised when too many demos are already waiting for a parse slot."""
+
+
+class ParseLimiter:
+    """Bound how many demos are parsed at once and how much memory they may claim.
+
+    ``max_concurrent`` parses run in parallel; further requests wait in a queue
+    of at most ``max_queued`` entries (0 means unbounded) and are rejected with
+    :class:`ParseQueueFullError` beyond that. When ``memory_budget_bytes`` is
+    set, a parse only starts once its estimated footprint fits next to the
+    parses already running; a single demo larger than the whole budget still
+    runs, but on its own.
+    """
+
+    def __init__(self, max_concurrent: int, max_queued: int = 0, memory_budget_bytes: int = 0) -> None:
+        if max_concurrent < 1:
+            raise ValueError("max_concurrent must be at least 1")
+        self.max_concurrent = max_concurrent
+        self.max_queued = max_queued
+        self.memory_budget_bytes = memory_budget_bytes
+        self.running = 0
+        self.waiting = 0
+        self.reserved_bytes = 0
+        self._condition = asyncio.Condition()
+
+    def check_capacity(self) -> None:
+        """Fail fast before accepting more work than the queue can hold."""
+
+        if self.max_queued and self.waiting >= self.max_queued:
+            raise ParseQueueFullError("Too many demos are waiting to be parsed; retry shortly")
+
+    @asynccontextmanager
+    async def slot(self, estimated_bytes: int = 0) -> AsyncIterator[None]:
+        self.check_capacity()
+        self.waiting += 1
+        try:
+            async with self._condition:
+                await self._condition.wait_for(lambda: self._can_start(estimated_bytes))
+                self.running += 1
+                self.reserved_bytes += estimated_bytes
+        finally:
+            self.waiting -= 1
+        try:
+            yield
+        finally:
+            async with self._condition:
+                self.running -= 1
+                self.reserved_bytes -= estimated_bytes
+                self._condition.notify_all()
+
+    def snapshot(self) -> Dict[str, int]:
+        return {
+            "running": self.running,
+            "waiting": self.waiting,
+            "reserved_bytes": self.reserved_bytes,
+            "max_concurrent": self.max_concurrent,
+        }
+
+    def _can_start(self, estimated_bytes: int) -> bool:
+        if self.running >= self.max_concurrent:
+            return False
+        if not self.memory_budget_bytes or self.running == 0:
+            return True
+        return self.reserved_bytes + estimated_bytes <= self.memory_budget_bytes
@@ -0,0 +1,209 @@
+from __future__ import annotations
+
+import logging
+from datetime import timedelta
+from typing import List, Optional, Tuple
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from .models import Demo, Match
+
+logger = logging.getLogger(__name__)
+
+
+class MatchCorrelator:
+    """Link uploads of the same game that came from different sources.
+
+    A GOTV recording, a FACEIT download and a player's POV demo of one game
+    each get their own match record. Two matches are treated as the same game
+    when they share the map and final score, were played within
+    ``correlation_window_minutes`` of each other and their player lists
+    overlap by at least ``correlation_min_player_overlap`` (Jaccard). Linked
+    matches share a ``game_id``; the richest one is flagged ``is_primary`` and
+    is the only one aggregates count.
+
+    A demo of a game still in progress (the first half, or a GOTV recording
+    cut early) joins the group of the full game once that is uploaded, in
+    either order. It is marked ``superseded_by`` the full match, which takes
+    over its external id and is primary however rich the partial demo is.
+    """
+
+    # Rounds a team needs to win a regulation (MR12) game.
+    ROUNDS_TO_WIN = 13
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+
+    def correlate(self, session: Session, match: Match) -> List[Match]:
+        """Link ``match`` to the matches of the same game and return the whole group."""
+
+        same_game = [candidate for candidate in self._candidates(session, match) if self._same_game(match, candidate)]
+        if not same_game:
+            match.game_id = None
+            match.is_primary = True
+            return [match]
+
+        game_id = same_game[0].game_id or same_game[0].id
+        group = {match.id: match}
+        for candidate in same_game:
+            for member in self.group(session, candidate):
+                group[member.id] = member
+        members = list(group.values())
+        for member in members:
+            member.game_id = game_id
+        self._supersede(session, members)
+        self._elect_primary(members)
+        logger.info("Linked match %s to game %s (%d sources)", match.id, game_id, len(members))
+        return members
+
+    def group(self, session: Session, match: Match) -> List[Match]:
+        """Every live match recorded for the same game as ``match``, itself included."""
+
+        if not match.game_id:
+            return [match]
+        stmt = (
+            select(Match)
+            .join(Demo, Demo.id == Match.demo_id)
+            .where(Match.game_id == match.game_id, Demo.deleted_at.is_(None))
+            .order_by(Match.created_at)
+        )
+        return list(session.scalars(stmt).all())
+
+    def correlate_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: link the freshly recorded match to other sources of its game."""
+
+        if demo.match is None:
+            return
+        try:
+            self.correlate(session, demo.match)
+            session.commit()
+        except Exception:  # noqa: BLE001 - the demo is stored either way
+            session.rollback()
+            logger.exception("Could not correlate the match of demo %s", demo.id)
+
+    def release_before_delete(self, session: Session, demo: Demo) -> None:
+        """Delete hook: hand the primary flag to the next richest source of the game."""
+
+        match = demo.match
+        if match is None or not match.game_id:
+            return
+        remaining = [member for member in self.group(session, match) if member.id != match.id]
+        for member in remaining:
+            if member.superseded_by == match.id:
+                member.superseded_by = None
+        if len(remaining) == 1:
+            remaining[0].game_id = None
+            remaining[0].is_primary = True
+        elif remaining:
+            self._elect_primary(remaining)
+        session.flush()
+
+    def _candidates(self, session: Session, match: Match) -> List[Match]:
+        window = timedelta(minutes=self.settings.correlation_window_minutes)
+        stmt = (
+            select(Match)
+            .join(Demo, Demo.id == Match.demo_id)
+            .where(
+                Match.id != match.id,
+                Match.map_name == match.map_name,
+                Match.played_at >= match.played_at - window,
+                Match.played_at <= match.played_at + window,
+                Demo.deleted_at.is_(None),
+            )
+            .order_by(Match.created_at)
+        )
+        return list(session.scalars(stmt).all())
+
+    def _same_game(self, match: Match, other: Match) -> bool:
+        overlap = player_overlap(match, other)
+        if overlap is None or overlap < self.settings.correlation_min_player_overlap:
+            return False
+        if sorted((match.score_a, match.score_b)) == sorted((other.score_a, other.score_b)):
+            return True
+        return self.is_partial_of(match, other) or self.is_partial_of(other, match)
+
+    def is_partial_of(self, partial: Match, full: Match) -> bool:
+        """Whether ``partial`` is an earlier cut of the game ``full`` recorded to the end.
+
+        It must have fewer rounds with neither team ahead of its final tally,
+        and either its rounds start on the same ticks as the first rounds of
+        ``full`` (the same server recording, cut early) or no team had won yet.
+        """
+
+        if partial.rounds >= full.rounds or not _scores_fit(partial, full):
+            return False
+        if max(partial.score_a, partial.score_b) < self.ROUNDS_TO_WIN:
+            return True
+        return _same_round_starts(partial, full)
+
+    def _supersede(self, session: Session, members: List[Match]) -> None:
+        complete = max(members, key=lambda member: member.rounds)
+        for member in members:
+            if member is complete or not self.is_partial_of(member, complete):
+                member.superseded_by = None
+                continue
+            member.superseded_by = complete.id
+            if member.external_id and not complete.external_id:
+                # The full game is now what the external id (e.g. a FACEIT match id) refers to.
+                _move_external_id(session, member, complete)
+            logger.info("Match %s supersedes partial match %s", complete.id, member.id)
+
+    @staticmethod
+    def _elect_primary(members: List[Match]) -> None:
+        primary = max([member for member in members if not member.superseded_by] or members, key=richness)
+        for member in members:
+            member.is_primary = member is primary
+
+
+def player_overlap(match: Match, other: Match) -> Optional[float]:
+    """Jaccard overlap of the two matches' SteamIDs, or ``None`` if either has no players."""
+
+    ours = {player.steamid for player in match.players}
+    theirs = {player.steamid for player in other.players}
+    if not ours or not theirs:
+        return None
+    return len(ours & theirs) / len(ours | theirs)
+
+
+def _scores_fit(partial: Match, full: Match) -> bool:
+    """Neither team of ``partial`` has won more rounds than it has in ``full``."""
+
+    ours = (partial.score_a, partial.score_b)
+    if {partial.team_a, partial.team_b} == {full.team_a, full.team_b} and partial.team_a != partial.team_b:
+        theirs = (full.score_a, full.score_b) if partial.team_a == full.team_a else (full.score_b, full.score_a)
+        return ours[0] <= theirs[0] and ours[1] <= theirs[1]
+    # Team names differ between sources; either orientation will do.
+    return any(ours[0] <= a and ours[1] <= b for a, b in ((full.score_a, full.score_b), (full.score_b, full.score_a)))
+
+
+def _same_round_starts(partial: Match, full: Match) -> bool:
+    ours = {entry["round"]: entry.get("start_tick") for entry in partial.round_ticks or []}
+    theirs = {entry["round"]: entry.get("start_tick") for entry in full.round_ticks or []}
+    if not ours:
+        return False
+    return all(tick is not None and theirs.get(number) == tick for number, tick in ours.items())
+
+
+def _move_external_id(session: Session, source: Match, target: Match) -> None:
+    """Hand ``source``'s external id to ``target``, on the match records and in their demos' metadata."""
+
+    external_id, external_source = source.external_id, source.external_source
+    source.external_id = source.external_source = None
+    session.flush()
+    target.external_id, target.external_source = external_id, external_source
+    keys = ("external_match_id", "external_source")
+    if source.demo is not None:
+        metadata = dict(source.demo.extra_metadata or {})
+        moved = {key: metadata.pop(key) for key in keys if key in metadata}
+        source.demo.extra_metadata = metadata
+        if target.demo is not None:
+            target.demo.extra_metadata = {**(target.demo.extra_metadata or {}), **moved}
+
+
+def richness(match: Match) -> Tuple[int, int, int, int]:
+    """Rank sources of one game: more players, rounds and datasets, then the larger demo, win."""
+
+    size = match.demo.size_bytes if match.demo is not None else 0
+    return (match.player_count, match.rounds, len(match.artifacts or {}), size)
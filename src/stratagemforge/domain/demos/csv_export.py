@@ -0,0 +1,96 @@
+"""Gzip-compressed CSV copies of the parquet datasets, for analysts working in Excel or Sheets.
+
+With ``DATASET_CSV_EXPORT`` the processor writes ``<dataset>.csv.gz`` next to
+every parquet file; otherwise the CSVs are made from the parquet files when a
+match export asks for them. Either way they are converted one record batch at
+a time, so large datasets such as ``player_ticks`` are never held in memory
+as text. Exports of several files are streamed as a zip archive.
+"""
+
+from __future__ import annotations
+
+import gzip
+import zipfile
+import zlib
+from pathlib import Path
+from typing import Iterable, Iterator, List, Tuple
+
+import pyarrow.parquet as pq
+
+CSV_SUFFIX = ".csv.gz"
+ZIP_MEDIA_TYPE = "application/zip"
+CSV_BATCH_ROWS = 64 * 1024
+FILE_CHUNK_BYTES = 1024 * 1024
+
+
+def write_csv_gz(parquet_path: Path, target: Path) -> Path:
+    """Write the parquet file at ``parquet_path`` as a gzip CSV at ``target``."""
+
+    with gzip.GzipFile(target, "wb", mtime=0) as handle:
+        for chunk in csv_chunks(parquet_path):
+            handle.write(chunk)
+    return target
+
+
+def csv_chunks(parquet_path: Path, batch_rows: int = CSV_BATCH_ROWS) -> Iterator[bytes]:
+    """UTF-8 CSV text of a parquet file, one record batch per chunk; the header comes first even when it is empty."""
+
+    parquet = pq.ParquetFile(parquet_path)
+    header = True
+    for batch in parquet.iter_batches(batch_size=batch_rows):
+        yield batch.to_pandas().to_csv(index=False, header=header).encode("utf-8")
+        header = False
+    if header:
+        yield (",".join(parquet.schema_arrow.names) + "\n").encode("utf-8")
+
+
+def gzip_chunks(chunks: Iterable[bytes]) -> Iterator[bytes]:
+    compressor = zlib.compressobj(wbits=zlib.MAX_WBITS | 16)  # gzip container
+    for chunk in chunks:
+        compressed = compressor.compress(chunk)
+        if compressed:
+            yield compressed
+    yield compressor.flush()
+
+
+def file_chunks(path: Path, chunk_size: int = FILE_CHUNK_BYTES) -> Iterator[bytes]:
+    with path.open("rb") as handle:
+        while chunk := handle.read(chunk_size):
+            yield chunk
+
+
+def zip_stream(members: Iterable[Tuple[str, Iterator[bytes]]]) -> Iterator[bytes]:
+    """A zip archive of ``(name, chunks)`` members, produced as it is written.
+
+    Members are stored rather than deflated because they are compressed
+    already; sizes go into data descriptors, so nothing needs to seek back.
+    """
+
+    sink = _Sink()
+    with zipfile.ZipFile(sink, "w", compression=zipfile.ZIP_STORED) as archive:
+        for name, chunks in members:
+            with archive.open(name, "w", force_zip64=True) as member:
+                for chunk in chunks:
+                    member.write(chunk)
+                    yield sink.drain()
+            yield sink.drain()
+    yield sink.drain()
+
+
+class _Sink:
+    """A write-only file: ``zipfile`` falls back to streaming mode because it cannot ``seek`` or ``tell``."""
+
+    def __init__(self) -> None:
+        self._chunks: List[bytes] = []
+
+    def write(self, data: bytes) -> int:
+        self._chunks.append(bytes(data))
+        return len(data)
+
+    def flush(self) -> None:
+        pass
+
+    def drain(self) -> bytes:
+        data = b"".join(self._chunks)
+        self._chunks.clear()
+        return data
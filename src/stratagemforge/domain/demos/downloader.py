@@ -0,0 +1,116 @@
+from __future__ import annotations
+
+import hashlib
+import logging
+import time
+from dataclasses import dataclass
+from http.client import IncompleteRead
+from pathlib import Path
+from typing import Optional
+from urllib.error import HTTPError, URLError
+from urllib.parse import unquote, urlparse
+from urllib.request import Request
+
+from ...core.netguard import check_public_url, public_opener
+
+logger = logging.getLogger(__name__)
+
+
+@dataclass
+class DownloadedDemo:
+    path: Path
+    filename: str
+    content_type: Optional[str]
+    checksum: str
+    size_bytes: int
+
+
+class DemoDownloader:
+    """Fetch remote demo files with retries and a hard size limit.
+
+    Only public addresses are reached, on every redirect hop, unless the host
+    is one of ``allowed_hosts``.
+    """
+
+    def __init__(
+        self,
+        max_size: int,
+        timeout: float = 60.0,
+        max_retries: int = 3,
+        backoff_seconds: float = 1.0,
+        chunk_size: int = 4 * 1024 * 1024,
+        allowed_hosts: frozenset[str] = frozenset(),
+    ) -> None:
+        self.max_size = max_size
+        self.timeout = timeout
+        self.max_retries = max_retries
+        self.backoff_seconds = backoff_seconds
+        self.chunk_size = chunk_size
+        self.allowed_hosts = allowed_hosts
+        self._opener = public_opener(allowed_hosts)
+
+    def download(self, url: str, destination: Path) -> DownloadedDemo:
+        """Download ``url`` into ``destination``, retrying transient failures."""
+
+        parsed = urlparse(url)
+        if parsed.scheme not in {"http", "https"}:
+            raise ValueError("Only http and https demo URLs are supported")
+        check_public_url(url, self.allowed_hosts)
+
+        attempt = 0
+        while True:
+            attempt += 1
+            try:
+                return self._fetch(url, destination)
+            except HTTPError as exc:
+                destination.unlink(missing_ok=True)
+                if exc.code < 500 or attempt > self.max_retries:
+                    raise ValueError(f"Demo download failed with HTTP {exc.code}") from exc
+            except (URLError, TimeoutError, ConnectionError, IncompleteRead) as exc:
+                destination.unlink(missing_ok=True)
+                if attempt > self.max_retries:
+                    raise ValueError(f"Demo download failed: {exc}") from exc
+
+            delay = self.backoff_seconds * 2 ** (attempt - 1)
+            logger.warning("Retrying demo download from %s in %.1fs (attempt %d)", url, delay, attempt)
+            time.sleep(delay)
+
+    def _fetch(self, url: str, destination: Path) -> DownloadedDemo:
+        request = Request(url, headers={"User-Agent": "StratagemForge/1.0"})
+        with self._opener.open(request, timeout=self.timeout) as response:
+            declared = response.headers.get("Content-Length")
+            if declared and declared.isdigit() and int(declared) > self.max_size:
+                raise ValueError("Remote demo exceeds maximum allowed size")
+
+            checksum = hashlib.sha256()
+            total_size = 0
+            with destination.open("wb") as buffer:
+                while True:
+                    chunk = response.read(self.chunk_size)
+                    if not chunk:
+                        break
+                    total_size += len(chunk)
+                    if total_size > self.max_size:
+                        buffer.close()
+                        destination.unlink(missing_ok=True)
+                        raise ValueError("Remote demo exceeds maximum allowed size")
+                    checksum.update(chunk)
+                    buffer.write(chunk)
+            if declared and declared.isdigit() and total_size < int(declared):
+                # Sized reads return short at EOF instead of raising, so a cut-off body is caught here.
+                raise IncompleteRead(b"", int(declared) - total_size)
+
+            return DownloadedDemo(
+                path=destination,
+                filename=_filename_from(response.headers.get_filename(), url),
+                content_type=response.headers.get_content_type(),
+                checksum=checksum.hexdigest(),
+                size_bytes=total_size,
+            )
+
+
+def _filename_from(header_filename: Optional[str], url: str) -> str:
+    if header_filename:
+        return Path(header_filename).name
+    name = Path(unquote(urlparse(url).path)).name
+    return name or "download.dem"
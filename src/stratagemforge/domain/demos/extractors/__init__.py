@@ -0,0 +1,55 @@
+"""Derived datasets computed from a :class:`~stratagemforge.domain.demos.parser.ParsedDemo`."""
+
+from __future__ import annotations
+
+from typing import Callable, Dict
+
+import pandas as pd
+
+from ..parser import ParsedDemo
+from .clutches import build_clutches
+from .duels import build_duels
+from .kills import build_kill_feed
+from .pistol import build_pistol_rounds
+from .player_ticks import build_player_ticks
+from .players import build_role_features
+from .post_plant import build_post_plant_scenarios
+from .rounds import build_round_summary
+from .saves import build_save_decisions
+from .scoreboard import build_player_stats
+from .site_hits import build_site_hits
+from .utility import build_player_utility
+
+DatasetBuilder = Callable[[ParsedDemo], pd.DataFrame]
+
+# Each entry is written to ``<processed>/<demo_id>/<name>.parquet`` after parsing.
+DATASET_BUILDERS: Dict[str, DatasetBuilder] = {
+    "round_summary": build_round_summary,
+    "post_plant": build_post_plant_scenarios,
+    "pistol_rounds": build_pistol_rounds,
+    "role_features": build_role_features,
+    "stats": build_player_stats,
+    "player_utility": build_player_utility,
+    "clutches": build_clutches,
+    "kills": build_kill_feed,
+    "duels": build_duels,
+    "site_hits": build_site_hits,
+    "player_ticks": build_player_ticks,
+    "save_decisions": build_save_decisions,
+}
+
+# Layout version of each dataset, stored in its parquet metadata. Bump it
+# whenever the builder's columns change.
+DATASET_SCHEMA_VERSIONS: Dict[str, int] = {name: 1 for name in DATASET_BUILDERS}
+DATASET_SCHEMA_VERSIONS.update(kills=3, stats=3)  # assists, utility suffered, kill positions and smokes
+
+# Datasets written with one row group per value of a column, whose ranges go into the
+# artefact manifest so single rounds can be read without the rest of the file.
+DATASET_ROW_GROUP_KEYS: Dict[str, str] = {"player_ticks": "round"}
+
+# Datasets rolled out behind a feature flag (dataset name -> flag key). They are
+# built unless the flag exists and is off for the uploading team.
+EXPERIMENTAL_DATASETS: Dict[str, str] = {
+    "player_utility": "datasets.player_utility",
+    "clutches": "datasets.clutches",
+}
@@ -0,0 +1,76 @@
+from __future__ import annotations
+
+from typing import Dict, List, Set
+
+import pandas as pd
+
+from ..parser import KILL_COLUMNS, ParsedDemo
+from .players import player_rounds
+
+CLUTCH_COLUMNS = [
+    "round",
+    "steamid",
+    "name",
+    "team_name",
+    "side",
+    "opponents",
+    "scenario",
+    "start_tick",
+    "kills",
+    "survived",
+    "won",
+]
+
+
+def build_clutches(parsed: ParsedDemo) -> pd.DataFrame:
+    """One row per clutch: the first moment in a round a player is the last alive on their side.
+
+    Alive counts start from the round's roster and drop with every death,
+    team kills included. ``opponents`` is the number of enemies alive at that
+    moment, ``kills`` the enemies the player killed afterwards, and ``won``
+    whether the player's side took the round.
+    """
+
+    players = player_rounds(parsed)
+    if players.empty or parsed.rounds.empty:
+        return pd.DataFrame(columns=CLUTCH_COLUMNS)
+
+    winners = dict(zip(parsed.rounds["round"], parsed.rounds["winner"]))
+    kills = parsed.kills.reindex(columns=KILL_COLUMNS).sort_values("tick")
+    found: List[dict] = []
+    for round_number, roster in players.groupby("round"):
+        alive: Dict[str, Set[str]] = {
+            side: set(group["steamid"]) for side, group in roster.dropna(subset=["side"]).groupby("side")
+        }
+        if len(alive) != 2:
+            continue
+        round_kills = kills[kills["round"] == round_number]
+        for death in round_kills.itertuples(index=False):
+            for members in alive.values():
+                members.discard(death.victim_steamid)
+            lone = [side for side, members in alive.items() if len(members) == 1]
+            if not lone:
+                continue
+            side = lone[0]
+            opponents = next(len(members) for other, members in alive.items() if other != side)
+            if opponents:
+                steamid = next(iter(alive[side]))
+                player = roster[roster["steamid"] == steamid].iloc[0]
+                after = round_kills[round_kills["tick"] > death.tick]
+                found.append(
+                    {
+                        "round": round_number,
+                        "steamid": steamid,
+                        "name": player["name"],
+                        "team_name": player["team_name"],
+                        "side": side,
+                        "opponents": opponents,
+                        "scenario": f"1v{opponents}",
+                        "start_tick": int(death.tick),
+                        "kills": int(((after["attacker_steamid"] == steamid) & (after["victim_side"] != side)).sum()),
+                        "survived": steamid not in set(after["victim_steamid"]),
+                        "won": winners.get(round_number) == side,
+                    }
+                )
+            break
+    return pd.DataFrame(found, columns=CLUTCH_COLUMNS)
@@ -0,0 +1,79 @@
+from __future__ import annotations
+
+import pandas as pd
+
+from ..parser import ParsedDemo
+from .scoreboard import TRADE_WINDOW_SECONDS
+
+DUEL_SITUATIONS = ("opening", "trade", "other")
+DUEL_COLUMNS = [
+    "attacker_steamid",
+    "attacker_name",
+    "victim_steamid",
+    "victim_name",
+    "weapon",
+    "situation",
+    "kills",
+    "headshots",
+]
+
+
+def build_duels(parsed: ParsedDemo) -> pd.DataFrame:
+    """Who killed whom: one row per attacker, victim, weapon and situation with the kill count.
+
+    ``situation`` is ``opening`` for the first kill of a round, ``trade`` for a
+    kill on an enemy who killed one of the attacker's teammates within
+    ``TRADE_WINDOW_SECONDS``, and ``other`` for everything else. Team kills
+    and suicides are left out.
+    """
+
+    kills = parsed.kills
+    if kills.empty:
+        return pd.DataFrame(columns=DUEL_COLUMNS)
+    kills = kills[
+        kills["attacker_steamid"].notna()
+        & (kills["attacker_side"] != kills["victim_side"])
+        & (kills["attacker_steamid"] != kills["victim_steamid"])
+    ].sort_values("tick")
+    if kills.empty:
+        return pd.DataFrame(columns=DUEL_COLUMNS)
+
+    kills = kills.assign(
+        attacker_steamid=kills["attacker_steamid"].astype(str),
+        victim_steamid=kills["victim_steamid"].astype(str),
+        attacker_name=kills.get("attacker_name"),
+        victim_name=kills.get("victim_name"),
+        headshot=kills.get("headshot", pd.Series(False, index=kills.index)).fillna(False).astype(bool),
+    )
+    situation = pd.Series("other", index=kills.index)
+    situation[_trades(kills, parsed.tick_rate)] = "trade"
+    situation[kills.drop_duplicates("round").index] = "opening"
+    kills["situation"] = situation
+
+    duels = (
+        kills.groupby(["attacker_steamid", "victim_steamid", "weapon", "situation"], dropna=False)
+        .agg(
+            attacker_name=("attacker_name", "last"),
+            victim_name=("victim_name", "last"),
+            kills=("tick", "size"),
+            headshots=("headshot", "sum"),
+        )
+        .reset_index()
+    )
+    duels["headshots"] = duels["headshots"].astype("int64")
+    return duels[DUEL_COLUMNS]
+
+
+def _trades(kills: pd.DataFrame, tick_rate: float) -> pd.Index:
+    window = TRADE_WINDOW_SECONDS * tick_rate
+    traded = []
+    for kill in kills.itertuples():
+        earlier = kills[
+            (kills["round"] == kill.round)
+            & (kills["attacker_steamid"] == kill.victim_steamid)
+            & (kills["tick"] < kill.tick)
+            & (kills["tick"] >= kill.tick - window)
+        ]
+        if not earlier.empty:
+            traded.append(kill.Index)
+    return pd.Index(traded)
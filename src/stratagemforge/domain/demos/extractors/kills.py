@@ -0,0 +1,30 @@
+from __future__ import annotations
+
+import pandas as pd
+
+from ..parser import KILL_COLUMNS, PHASE_COLUMNS, ParsedDemo, assign_phases
+from ..smokes import lines_through_smoke, smoke_intervals
+
+KILL_FEED_COLUMNS = list(KILL_COLUMNS) + list(PHASE_COLUMNS) + ["through_smoke"]
+
+
+def build_kill_feed(parsed: ParsedDemo) -> pd.DataFrame:
+    """One row per kill, tagged with the round phase it happened in.
+
+    ``phase`` is a :class:`~..parser.RoundPhase` value and ``phase_code`` its
+    stable integer, so queries can filter on ``is_live`` instead of matching
+    labels. ``through_smoke`` marks kills whose line of fire crossed a smoke
+    that was active at the time (see :mod:`~..smokes`).
+    """
+
+    if parsed.kills.empty:
+        return pd.DataFrame(columns=KILL_FEED_COLUMNS)
+
+    kills = parsed.kills.reindex(columns=KILL_COLUMNS).sort_values("tick").reset_index(drop=True)
+    kills = assign_phases(kills, parsed.rounds)
+    smokes = smoke_intervals(parsed.grenade_events, parsed.rounds, parsed.tick_rate)
+    lines = kills[["tick", "attacker_x", "attacker_y", "victim_x", "victim_y"]].set_axis(
+        ["tick", "x1", "y1", "x2", "y2"], axis=1
+    )
+    kills["through_smoke"] = lines_through_smoke(lines, smokes)
+    return kills[KILL_FEED_COLUMNS]
@@ -0,0 +1,141 @@
+from __future__ import annotations
+
+import re
+from datetime import datetime, tzinfo
+from typing import Any, Dict, List, Optional
+
+import pandas as pd
+
+from ....core.timeutil import to_utc_naive
+
+from ..parser import ParsedDemo
+from .pistol import HALF_LENGTH
+from .players import player_rounds
+
+OVERTIME_HALF_LENGTH = 3
+
+# Recording stamps in demo file names, e.g. CS2's "auto0-20240701-203512-..." or "2024-07-01_20-35-12".
+_FILENAME_STAMP = re.compile(r"(?<!\d)(20\d{2})-?(\d{2})-?(\d{2})[-_ T](\d{2})[-:]?(\d{2})[-:]?(\d{2})(?!\d)")
+# Map names in demo file names, e.g. "auto0-20240701-203512-1234-de_mirage-server.dem".
+_FILENAME_MAP = re.compile(r"(?<![a-z0-9])((?:de|cs|ar)_[a-z0-9]+(?:_[a-z0-9]+)*)")
+
+
+def starting_ct_team_is_ct(round_number: int) -> bool:
+    """Whether the team that started on CT is on CT in ``round_number``.
+
+    Overtime keeps the second-half sides for its first half and swaps every
+    ``OVERTIME_HALF_LENGTH`` rounds after that.
+    """
+
+    if round_number <= HALF_LENGTH:
+        return True
+    if round_number <= HALF_LENGTH * 2:
+        return False
+    overtime_half = (round_number - HALF_LENGTH * 2 - 1) // OVERTIME_HALF_LENGTH
+    return overtime_half % 2 == 1
+
+
+def build_match_info(parsed: ParsedDemo) -> Dict[str, Any]:
+    """Summarise teams, final score and participants for the matches table.
+
+    ``team_a`` is the team that started on CT. When the demo carries no clan
+    names the teams are labelled by starting side and sides are inferred from
+    the half/overtime structure.
+    """
+
+    rounds = parsed.rounds.sort_values("round") if not parsed.rounds.empty else parsed.rounds
+    first = rounds.iloc[0] if not rounds.empty else None
+    team_a = _clean(first.get("ct_team")) if first is not None else None
+    team_b = _clean(first.get("t_team")) if first is not None else None
+
+    score_a = score_b = 0
+    for round_info in rounds.itertuples(index=False):
+        a_is_ct = _team_a_is_ct(round_info, team_a)
+        if round_info.winner == "CT":
+            score_a, score_b = (score_a + 1, score_b) if a_is_ct else (score_a, score_b + 1)
+        elif round_info.winner == "T":
+            score_a, score_b = (score_a, score_b + 1) if a_is_ct else (score_a + 1, score_b)
+
+    team_a = team_a or "Team A"
+    team_b = team_b or "Team B"
+    winner: Optional[str] = None
+    if score_a != score_b:
+        winner = team_a if score_a > score_b else team_b
+
+    return {
+        "map_name": parsed.map_name,
+        "team_a": team_a,
+        "team_b": team_b,
+        "score_a": score_a,
+        "score_b": score_b,
+        "winner": winner,
+        "rounds": int(len(rounds)),
+        "players": _players(parsed),
+        "tick_rate": parsed.tick_rate,
+        "round_ticks": _round_ticks(rounds),
+    }
+
+
+def _round_ticks(rounds: pd.DataFrame) -> List[Dict[str, Optional[int]]]:
+    """Start, freeze-time end and end tick of every round, for mapping rounds onto wall-clock time."""
+
+    return [
+        {
+            "round": int(row["round"]),
+            **{column: _tick(row.get(column)) for column in ("start_tick", "freeze_end_tick", "end_tick")},
+        }
+        for _, row in rounds.iterrows()
+    ]
+
+
+def _tick(value: Any) -> Optional[int]:
+    return None if value is None or pd.isna(value) else int(value)
+
+
+def _team_a_is_ct(round_info: Any, team_a: Optional[str]) -> bool:
+    ct_team = _clean(getattr(round_info, "ct_team", None))
+    if team_a and ct_team:
+        return ct_team == team_a
+    return starting_ct_team_is_ct(int(round_info.round))
+
+
+def _players(parsed: ParsedDemo) -> List[Dict[str, Any]]:
+    players = player_rounds(parsed)
+    if players.empty:
+        return []
+    latest = players.sort_values("round").groupby("steamid").last().reset_index()
+    return [
+        {"steamid": str(row.steamid), "name": _clean(row.name), "team_name": _clean(row.team_name)}
+        for row in latest.itertuples(index=False)
+    ]
+
+
+def _clean(value: Any) -> Optional[str]:
+    if value is None or (isinstance(value, float) and pd.isna(value)):
+        return None
+    text = str(value).strip()
+    return text or None
+
+
+def map_from_filename(filename: str) -> Optional[str]:
+    """The map a demo file name mentions, e.g. ``de_mirage``; ``None`` if it names none."""
+
+    found = _FILENAME_MAP.search(filename.lower())
+    return found.group(1) if found else None
+
+
+def start_time_from_filename(filename: str, tz: tzinfo) -> Optional[datetime]:
+    """Recover the recording start from a demo file name, as naive UTC.
+
+    Servers stamp auto-recorded demos with their local time, so ``tz`` is the
+    timezone the stamp is read in.
+    """
+
+    found = _FILENAME_STAMP.search(filename)
+    if not found:
+        return None
+    try:
+        local = datetime(*(int(part) for part in found.groups()), tzinfo=tz)
+    except ValueError:  # e.g. month 13
+        return None
+    return to_utc_naive(local)
@@ -0,0 +1,131 @@
+from __future__ import annotations
+
+from typing import Dict, Iterable, List
+
+import pandas as pd
+
+from ..parser import ParsedDemo, grenade_kind
+
+HALF_LENGTH = 12
+
+DEFAULT_PISTOLS = {"glock-18", "usp-s", "p2000"}
+PISTOLS = DEFAULT_PISTOLS | {
+    "p250",
+    "five-seven",
+    "tec-9",
+    "cz75-auto",
+    "dual berettas",
+    "desert eagle",
+    "r8 revolver",
+}
+
+PISTOL_COLUMNS = [
+    "round",
+    "side",
+    "team_name",
+    "won",
+    "buy",
+    "players",
+    "armor_count",
+    "helmet_count",
+    "defuser_count",
+    "pistol_upgrades",
+    "utility_bought",
+    "first_contact_tick",
+    "first_contact_place",
+    "opening_kill",
+    "bomb_planted",
+    "plant_site",
+]
+
+
+def pistol_round_numbers(rounds: Iterable[int]) -> List[int]:
+    """Return the first round of each regulation half."""
+
+    return [int(number) for number in rounds if number in (1, HALF_LENGTH + 1)]
+
+
+def equipment_counts(players: pd.DataFrame) -> Dict[str, int]:
+    """Count armor, kits, pistol upgrades and grenades in a freeze-end snapshot."""
+
+    if players.empty:
+        return {key: 0 for key in ("armor_count", "helmet_count", "defuser_count", "pistol_upgrades", "utility_bought")}
+    return {
+        "armor_count": int((players["armor"].fillna(0) > 0).sum()),
+        "helmet_count": int(players["has_helmet"].fillna(False).astype(bool).sum()),
+        "defuser_count": int(players["has_defuser"].fillna(False).astype(bool).sum()),
+        "pistol_upgrades": int(players["inventory"].map(_pistol_upgrades).sum()),
+        "utility_bought": int(players["inventory"].map(_utility_count).sum()),
+    }
+
+
+def classify_buy(players: pd.DataFrame) -> str:
+    """Label a side's pistol-round buy from the freeze-end equipment snapshot."""
+
+    count = len(players)
+    if count == 0:
+        return "unknown"
+
+    equipment = equipment_counts(players)
+    if equipment["armor_count"] * 2 >= count:
+        return "armor"
+    if equipment["pistol_upgrades"] * 2 >= count:
+        return "pistol_upgrades"
+    if equipment["utility_bought"] >= count:
+        return "utility"
+    if not any(equipment.values()):
+        return "default"
+    return "mixed"
+
+
+def build_pistol_rounds(parsed: ParsedDemo) -> pd.DataFrame:
+    """Describe each side's buy, first contact and outcome for the pistol rounds."""
+
+    numbers = pistol_round_numbers(parsed.rounds["round"]) if not parsed.rounds.empty else []
+    if not numbers:
+        return pd.DataFrame(columns=PISTOL_COLUMNS)
+
+    rounds = parsed.rounds.set_index("round")
+    state = parsed.round_start_state
+    rows = []
+    for number in numbers:
+        round_info = rounds.loc[number]
+        kills = parsed.kills[parsed.kills["round"] == number].sort_values("tick")
+        first_kill = kills.iloc[0] if not kills.empty else None
+        plants = parsed.bomb_events[
+            (parsed.bomb_events["round"] == number) & (parsed.bomb_events["event"] == "planted")
+        ]
+
+        for side, team_column in (("CT", "ct_team"), ("T", "t_team")):
+            players = state[(state["round"] == number) & (state["side"] == side)] if not state.empty else state
+            rows.append(
+                {
+                    "round": number,
+                    "side": side,
+                    "team_name": round_info.get(team_column),
+                    "won": round_info["winner"] == side,
+                    "buy": classify_buy(players),
+                    "players": int(len(players)),
+                    **equipment_counts(players),
+                    "first_contact_tick": int(first_kill["tick"]) if first_kill is not None else None,
+                    "first_contact_place": first_kill.get("victim_place") if first_kill is not None else None,
+                    "opening_kill": bool(first_kill is not None and first_kill["attacker_side"] == side),
+                    "bomb_planted": not plants.empty,
+                    "plant_site": plants.iloc[0]["site"] if not plants.empty else None,
+                }
+            )
+    return pd.DataFrame(rows, columns=PISTOL_COLUMNS)
+
+
+def _items(inventory) -> list:
+    if inventory is None or isinstance(inventory, float):
+        return []
+    return list(inventory)
+
+
+def _pistol_upgrades(inventory) -> int:
+    return sum(1 for item in _items(inventory) if str(item).lower() in PISTOLS - DEFAULT_PISTOLS)
+
+
+def _utility_count(inventory) -> int:
+    return sum(1 for item in _items(inventory) if grenade_kind(item) is not None)
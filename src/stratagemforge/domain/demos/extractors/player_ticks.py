@@ -0,0 +1,21 @@
+from __future__ import annotations
+
+import pandas as pd
+
+from ..parser import PHASE_COLUMNS, PLAYER_TICK_COLUMNS, ParsedDemo, assign_phases
+
+PLAYER_TICK_FEED_COLUMNS = list(PLAYER_TICK_COLUMNS) + list(PHASE_COLUMNS)
+
+
+def build_player_ticks(parsed: ParsedDemo) -> pd.DataFrame:
+    """Positions of living players, sampled every ``POSITION_SAMPLE_TICKS`` ticks and tagged with the round phase.
+
+    Samples whose position was cleared as implausible are left out.
+    """
+
+    ticks = parsed.player_ticks.reindex(columns=PLAYER_TICK_COLUMNS)
+    ticks = ticks[ticks["is_alive"].fillna(True).astype(bool)].dropna(subset=["x", "y"])
+    if ticks.empty:
+        return pd.DataFrame(columns=PLAYER_TICK_FEED_COLUMNS)
+    ticks = ticks.sort_values(["tick", "steamid"]).reset_index(drop=True)
+    return assign_phases(ticks, parsed.rounds)[PLAYER_TICK_FEED_COLUMNS]
@@ -0,0 +1,95 @@
+from __future__ import annotations
+
+import pandas as pd
+
+from ..parser import ParsedDemo
+from .post_plant import DEFAULT_TEAM_SIZE
+
+ROLE_FEATURE_COLUMNS = [
+    "steamid",
+    "name",
+    "team_name",
+    "rounds",
+    "kills",
+    "awp_kills",
+    "opening_kills",
+    "opening_deaths",
+    "t_opening_duels",
+    "utility_thrown",
+    "flashes_thrown",
+    "death_order_total",
+]
+
+
+def player_rounds(parsed: ParsedDemo) -> pd.DataFrame:
+    """Return one row per player and round they took part in, with side and team."""
+
+    columns = ["round", "steamid", "name", "side", "team_name"]
+    for frame in (parsed.round_end_inventory, parsed.round_start_state):
+        if not frame.empty:
+            frame = frame.reindex(columns=columns)
+            return frame.dropna(subset=["steamid"]).drop_duplicates(["round", "steamid"])
+    return pd.DataFrame(columns=columns)
+
+
+def build_role_features(parsed: ParsedDemo) -> pd.DataFrame:
+    """Per-player counters used to infer playing roles across matches.
+
+    ``death_order_total`` sums the order in which the player died within their
+    team each round (1 = first to die); surviving a round counts as
+    ``DEFAULT_TEAM_SIZE + 1`` so lurkers and late players score high.
+    """
+
+    players = player_rounds(parsed)
+    if players.empty:
+        return pd.DataFrame(columns=ROLE_FEATURE_COLUMNS)
+
+    features = players.groupby("steamid").agg(
+        name=("name", "last"),
+        team_name=("team_name", _mode),
+        rounds=("round", "nunique"),
+    )
+
+    kills = parsed.kills
+    features["kills"] = kills.groupby("attacker_steamid").size()
+    awp = kills[kills["weapon"].fillna("").astype(str).str.lower().str.contains("awp")]
+    features["awp_kills"] = awp.groupby("attacker_steamid").size()
+
+    openings = kills.sort_values("tick").drop_duplicates("round")
+    features["opening_kills"] = openings.groupby("attacker_steamid").size()
+    features["opening_deaths"] = openings.groupby("victim_steamid").size()
+    t_openings = pd.concat(
+        [
+            openings[openings["attacker_side"] == "T"]["attacker_steamid"],
+            openings[openings["victim_side"] == "T"]["victim_steamid"],
+        ]
+    )
+    features["t_opening_duels"] = t_openings.value_counts()
+
+    throws = parsed.grenade_throws
+    features["utility_thrown"] = throws.groupby("steamid").size()
+    features["flashes_thrown"] = throws[throws["grenade"] == "flash"].groupby("steamid").size()
+
+    features["death_order_total"] = _death_order_totals(players, kills)
+
+    counters = [column for column in ROLE_FEATURE_COLUMNS if column not in ("steamid", "name", "team_name")]
+    features[counters] = features[counters].fillna(0).astype("int64")
+    return features.reset_index()[ROLE_FEATURE_COLUMNS]
+
+
+def _death_order_totals(players: pd.DataFrame, kills: pd.DataFrame) -> pd.Series:
+    deaths = kills.sort_values("tick").drop_duplicates(["round", "victim_steamid"])
+    deaths = deaths.assign(order=deaths.groupby(["round", "victim_side"]).cumcount() + 1)
+    orders = players[["round", "steamid"]].merge(
+        deaths[["round", "victim_steamid", "order"]],
+        left_on=["round", "steamid"],
+        right_on=["round", "victim_steamid"],
+        how="left",
+    )
+    orders["order"] = orders["order"].fillna(DEFAULT_TEAM_SIZE + 1)
+    return orders.groupby("steamid")["order"].sum()
+
+
+def _mode(values: pd.Series):
+    values = values.dropna()
+    return values.mode().iloc[0] if not values.empty else None
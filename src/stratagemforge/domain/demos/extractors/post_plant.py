@@ -0,0 +1,80 @@
+from __future__ import annotations
+
+import pandas as pd
+
+from ..parser import ParsedDemo
+
+ROUND_TIME_SECONDS = 115
+DEFAULT_TEAM_SIZE = 5
+
+POST_PLANT_COLUMNS = [
+    "round",
+    "site",
+    "plant_tick",
+    "seconds_into_round",
+    "round_time_left",
+    "t_alive",
+    "ct_alive",
+    "t_team",
+    "ct_team",
+    "winner",
+    "defused",
+    "exploded",
+]
+
+
+def build_post_plant_scenarios(parsed: ParsedDemo) -> pd.DataFrame:
+    """Describe the game state at every bomb plant and how the round ended.
+
+    Alive counts are taken at the plant tick. A ``T`` winner means the post-plant
+    was held, a ``CT`` winner means the retake succeeded.
+    """
+
+    bombs = parsed.bomb_events
+    if bombs.empty or parsed.rounds.empty:
+        return pd.DataFrame(columns=POST_PLANT_COLUMNS)
+
+    plants = bombs[bombs["event"] == "planted"].sort_values("tick").drop_duplicates("round")
+    rounds = parsed.rounds.set_index("round")
+    roster = _roster_sizes(parsed)
+    tick_rate = parsed.tick_rate
+
+    rows = []
+    for plant in plants.itertuples(index=False):
+        if plant.round not in rounds.index:
+            continue
+        round_info = rounds.loc[plant.round]
+        deaths = parsed.kills[(parsed.kills["round"] == plant.round) & (parsed.kills["tick"] <= plant.tick)]
+        round_bombs = bombs[bombs["round"] == plant.round]
+        elapsed = max(int(plant.tick) - int(round_info["freeze_end_tick"]), 0) / tick_rate
+
+        rows.append(
+            {
+                "round": int(plant.round),
+                "site": plant.site,
+                "plant_tick": int(plant.tick),
+                "seconds_into_round": round(elapsed, 2),
+                "round_time_left": round(max(ROUND_TIME_SECONDS - elapsed, 0.0), 2),
+                "t_alive": _alive(roster, deaths, plant.round, "T"),
+                "ct_alive": _alive(roster, deaths, plant.round, "CT"),
+                "t_team": round_info.get("t_team"),
+                "ct_team": round_info.get("ct_team"),
+                "winner": round_info["winner"],
+                "defused": bool((round_bombs["event"] == "defused").any()),
+                "exploded": bool((round_bombs["event"] == "exploded").any()),
+            }
+        )
+    return pd.DataFrame(rows, columns=POST_PLANT_COLUMNS)
+
+
+def _roster_sizes(parsed: ParsedDemo) -> pd.Series:
+    inventory = parsed.round_end_inventory
+    if inventory.empty:
+        return pd.Series(dtype="int64")
+    return inventory.dropna(subset=["side"]).groupby(["round", "side"])["steamid"].nunique()
+
+
+def _alive(roster: pd.Series, deaths: pd.DataFrame, round_number: int, side: str) -> int:
+    size = int(roster.get((round_number, side), DEFAULT_TEAM_SIZE))
+    dead = deaths[deaths["victim_side"] == side]["victim_steamid"].nunique()
+    return max(size - dead, 0)
@@ -0,0 +1,21 @@
+from __future__ import annotations
+
+import pandas as pd
+
+from ..parser import ParsedDemo
+from .utility import summarize_team_utility
+
+
+def build_round_summary(parsed: ParsedDemo) -> pd.DataFrame:
+    """One row per round and side with the outcome and the side's utility usage."""
+
+    summary = summarize_team_utility(parsed)
+    if summary.empty:
+        return summary
+
+    rounds = parsed.rounds[["round", "start_tick", "freeze_end_tick", "end_tick", "winner", "reason"]]
+    summary = summary.merge(rounds, on="round", how="left")
+    summary["won"] = summary["side"] == summary["winner"]
+
+    leading = ["round", "side", "won", "winner", "reason", "start_tick", "freeze_end_tick", "end_tick"]
+    return summary[leading + [column for column in summary.columns if column not in leading]]
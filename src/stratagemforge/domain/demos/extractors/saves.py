@@ -0,0 +1,174 @@
+from __future__ import annotations
+
+from typing import Optional
+
+import pandas as pd
+
+from ..parser import ParsedDemo
+from .match import OVERTIME_HALF_LENGTH
+from .pistol import DEFAULT_PISTOLS, HALF_LENGTH
+
+SAVE_COLUMNS = [
+    "round",
+    "steamid",
+    "name",
+    "side",
+    "team_name",
+    "survived",
+    "saved_value",
+    "next_value",
+    "next_spend",
+    "next_balance",
+    "decision",
+]
+# Buy menu prices by inventory item name (lower case). Default pistols and knives are free.
+ITEM_PRICES = {
+    "p250": 300,
+    "dual berettas": 300,
+    "five-seven": 500,
+    "tec-9": 500,
+    "cz75-auto": 500,
+    "r8 revolver": 600,
+    "desert eagle": 700,
+    "mac-10": 1050,
+    "nova": 1050,
+    "sawed-off": 1100,
+    "ump-45": 1200,
+    "mp9": 1250,
+    "mag-7": 1300,
+    "pp-bizon": 1400,
+    "mp7": 1500,
+    "mp5-sd": 1500,
+    "ssg 08": 1700,
+    "negev": 1700,
+    "galil ar": 1800,
+    "xm1014": 2000,
+    "famas": 2050,
+    "p90": 2350,
+    "ak-47": 2700,
+    "m4a1-s": 2900,
+    "sg 553": 3000,
+    "m4a4": 3100,
+    "aug": 3300,
+    "awp": 4750,
+    "g3sg1": 5000,
+    "scar-20": 5000,
+    "m249": 5200,
+    "zeus x27": 200,
+    "decoy grenade": 50,
+    "flashbang": 200,
+    "smoke grenade": 300,
+    "high explosive grenade": 300,
+    "molotov": 400,
+    "incendiary grenade": 500,
+}
+KEVLAR_PRICE = 650
+HELMET_PRICE = 350
+DEFUSER_PRICE = 400
+# Equipment a player needs for a full buy: a rifle, armor and some utility.
+FULL_BUY_VALUE = 4000
+# Spending less than this in the next round counts as saving money.
+SAVE_SPEND = 1000
+
+
+def equipment_value(inventory) -> int:
+    """Buy menu value of the weapons and grenades in an inventory snapshot."""
+
+    if inventory is None or isinstance(inventory, float):
+        return 0
+    names = (str(item).lower() for item in inventory)
+    return sum(ITEM_PRICES.get(name, 0) for name in names if name not in DEFAULT_PISTOLS)
+
+
+def carries_money(round_number: int) -> bool:
+    """Whether money carries over from ``round_number`` into the next round (no half or overtime switch)."""
+
+    if round_number <= HALF_LENGTH * 2:
+        return round_number % HALF_LENGTH != 0
+    return (round_number - HALF_LENGTH * 2) % OVERTIME_HALF_LENGTH != 0
+
+
+def classify_save(saved_value: int, next_value: Optional[int], next_balance: Optional[float]) -> str:
+    """Label what a player did after a lost round: ``buy``, ``save``, ``force`` or ``unknown``.
+
+    A player who could afford a full buy (kept equipment plus the money they
+    had in the next buy time) bought; otherwise spending under
+    ``SAVE_SPEND`` is a save and anything more a force buy.
+    """
+
+    if next_value is None or next_balance is None or pd.isna(next_balance):
+        return "unknown"
+    spend = max(next_value - saved_value, 0)
+    if saved_value + spend + float(next_balance) >= FULL_BUY_VALUE:
+        return "buy"
+    return "save" if spend < SAVE_SPEND else "force"
+
+
+def build_save_decisions(parsed: ParsedDemo) -> pd.DataFrame:
+    """Equipment each player of the losing side kept into the next round, and how they bought in it.
+
+    One row per player on the losing side of every round whose money carries
+    into the next one (the last round of a half or overtime half is left
+    out). ``saved_value`` is the buy menu value of the weapons and grenades
+    a survivor still held at the round end (zero for the dead; armor is not
+    in the snapshot). ``next_value`` is the player's equipment value at the
+    next freeze-time end, including armor and kit, ``next_spend`` what they
+    bought on top of the kept equipment and ``next_balance`` the money left
+    after buying; see :func:`classify_save` for ``decision``. Picked-up drops
+    count as spending.
+    """
+
+    rounds = parsed.rounds
+    inventory = parsed.round_end_inventory
+    if rounds.empty or inventory.empty:
+        return pd.DataFrame(columns=SAVE_COLUMNS)
+
+    state = parsed.round_start_state
+    numbers = set(rounds["round"])
+    rows = []
+    for round_info in rounds.sort_values("round").itertuples(index=False):
+        number = int(round_info.round)
+        if round_info.winner not in ("CT", "T") or not carries_money(number) or number + 1 not in numbers:
+            continue
+        loser = "T" if round_info.winner == "CT" else "CT"
+        losers = inventory[(inventory["round"] == number) & (inventory["side"] == loser)]
+        next_state = state[state["round"] == number + 1] if not state.empty else state
+        next_by_player = {str(row.steamid): row for row in next_state.itertuples(index=False)}
+        for player in losers.itertuples(index=False):
+            survived = _flag(getattr(player, "is_alive", None))
+            saved_value = equipment_value(getattr(player, "inventory", None)) if survived else 0
+            following = next_by_player.get(str(player.steamid))
+            next_value = _loadout_value(following) if following is not None else None
+            next_balance = getattr(following, "balance", None) if following is not None else None
+            rows.append(
+                {
+                    "round": number,
+                    "steamid": str(player.steamid),
+                    "name": getattr(player, "name", None),
+                    "side": loser,
+                    "team_name": round_info.ct_team if loser == "CT" else round_info.t_team,
+                    "survived": survived,
+                    "saved_value": saved_value,
+                    "next_value": next_value,
+                    "next_spend": max(next_value - saved_value, 0) if next_value is not None else None,
+                    "next_balance": next_balance,
+                    "decision": classify_save(saved_value, next_value, next_balance),
+                }
+            )
+    return pd.DataFrame(rows, columns=SAVE_COLUMNS)
+
+
+def _loadout_value(state) -> int:
+    value = equipment_value(getattr(state, "inventory", None))
+    armor = getattr(state, "armor", None)
+    if armor is not None and not pd.isna(armor) and armor > 0:
+        value += KEVLAR_PRICE
+        if _flag(getattr(state, "has_helmet", None)):
+            value += HELMET_PRICE
+    if _flag(getattr(state, "has_defuser", None)):
+        value += DEFUSER_PRICE
+    return value
+
+
+def _flag(value) -> bool:
+    return value is not None and not pd.isna(value) and bool(value)
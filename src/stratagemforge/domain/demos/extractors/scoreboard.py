@@ -0,0 +1,133 @@
+from __future__ import annotations
+
+from typing import Dict, Set
+
+import pandas as pd
+
+from ..parser import BLIND_COLUMNS, DAMAGE_COLUMNS, KILL_COLUMNS, ParsedDemo, grenade_kind
+from .clutches import build_clutches
+from .players import player_rounds
+
+TRADE_WINDOW_SECONDS = 5
+
+PLAYER_STAT_COLUMNS = [
+    "steamid",
+    "name",
+    "team_name",
+    "rounds",
+    "kills",
+    "deaths",
+    "assists",
+    "flash_assists",
+    "headshot_kills",
+    "headshot_pct",
+    "damage",
+    "adr",
+    "kast",
+    "opening_kills",
+    "opening_deaths",
+    "clutches_played",
+    "clutches_won",
+    "kills_2k",
+    "kills_3k",
+    "kills_4k",
+    "kills_5k",
+    "blind_seconds",
+    "flashed_by_enemies",
+    "flashed_by_teammates",
+    "fire_damage_taken",
+]
+
+
+def build_player_stats(parsed: ParsedDemo) -> pd.DataFrame:
+    """Scoreboard line per player: K/D/A, ADR, HS%, KAST, opening duels, clutches and multi-kills.
+
+    Team kills do not count as kills. ``assists`` counts damage and flash
+    assists like the in-game scoreboard; ``flash_assists`` is the flash share. KAST is the percentage of rounds with a
+    kill, assist, survival or a death traded within ``TRADE_WINDOW_SECONDS``.
+    Clutches come from :func:`~.clutches.build_clutches`. The utility a
+    player suffered is the total time they spent blinded, how often enemies and
+    teammates flashed them (self-flashes only add blind time) and the molotov
+    and incendiary damage they took from anyone.
+    """
+
+    players = player_rounds(parsed)
+    if players.empty:
+        return pd.DataFrame(columns=PLAYER_STAT_COLUMNS)
+
+    stats = players.groupby("steamid").agg(
+        name=("name", "last"),
+        team_name=("team_name", "last"),
+        rounds=("round", "nunique"),
+    )
+
+    kills = parsed.kills.reindex(columns=KILL_COLUMNS).sort_values("tick")
+    enemy_kills = kills[kills["attacker_side"] != kills["victim_side"]]
+    stats["kills"] = enemy_kills.groupby("attacker_steamid").size()
+    stats["deaths"] = kills.groupby("victim_steamid").size()
+    stats["assists"] = enemy_kills.dropna(subset=["assister_steamid"]).groupby("assister_steamid").size()
+    flash_assists = enemy_kills[enemy_kills["assist_type"] == "flash"]
+    stats["flash_assists"] = flash_assists.groupby("flash_assister_steamid").size()
+    headshots = enemy_kills[enemy_kills["headshot"].fillna(False).astype(bool)]
+    stats["headshot_kills"] = headshots.groupby("attacker_steamid").size()
+
+    damages = parsed.damages.reindex(columns=DAMAGE_COLUMNS)
+    enemy_damage = damages[damages["attacker_side"] != damages["victim_side"]]
+    stats["damage"] = pd.to_numeric(enemy_damage["damage"]).groupby(enemy_damage["attacker_steamid"]).sum()
+    fire = damages[damages["weapon"].map(grenade_kind) == "molotov"]
+    stats["fire_damage_taken"] = pd.to_numeric(fire["damage"]).groupby(fire["victim_steamid"]).sum()
+
+    blinds = parsed.blinds.reindex(columns=BLIND_COLUMNS)
+    stats["blind_seconds"] = pd.to_numeric(blinds["duration"]).fillna(0.0).groupby(blinds["victim_steamid"]).sum()
+    flashed = blinds.dropna(subset=["attacker_steamid"])
+    flashed = flashed[flashed["attacker_steamid"] != flashed["victim_steamid"]]
+    by_enemy = flashed["attacker_side"] != flashed["victim_side"]
+    stats["flashed_by_enemies"] = flashed[by_enemy].groupby("victim_steamid").size()
+    stats["flashed_by_teammates"] = flashed[~by_enemy].groupby("victim_steamid").size()
+
+    openings = kills.drop_duplicates("round")
+    stats["opening_kills"] = openings.groupby("attacker_steamid").size()
+    stats["opening_deaths"] = openings.groupby("victim_steamid").size()
+
+    per_round = enemy_kills.groupby(["round", "attacker_steamid"]).size()
+    for count in (2, 3, 4):
+        stats[f"kills_{count}k"] = per_round[per_round == count].groupby(level="attacker_steamid").size()
+    stats["kills_5k"] = per_round[per_round >= 5].groupby(level="attacker_steamid").size()
+
+    stats["kast_rounds"] = _kast_rounds(players, kills, parsed.tick_rate)
+    clutches = build_clutches(parsed)
+    stats["clutches_played"] = clutches.groupby("steamid").size() if not clutches.empty else 0
+    stats["clutches_won"] = clutches[clutches["won"]].groupby("steamid").size() if not clutches.empty else 0
+
+    counters = [
+        column
+        for column in PLAYER_STAT_COLUMNS + ["kast_rounds"]
+        if column not in ("steamid", "name", "team_name", "headshot_pct", "adr", "kast", "blind_seconds")
+    ]
+    stats[counters] = stats[counters].fillna(0).astype("int64")
+    stats["blind_seconds"] = stats["blind_seconds"].fillna(0.0).astype(float).round(2)
+    stats["headshot_pct"] = (100 * stats["headshot_kills"] / stats["kills"].where(stats["kills"] > 0)).round(1)
+    stats["adr"] = (stats["damage"] / stats["rounds"].where(stats["rounds"] > 0)).round(1)
+    stats["kast"] = (100 * stats["kast_rounds"] / stats["rounds"].where(stats["rounds"] > 0)).round(1)
+    return stats.reset_index()[PLAYER_STAT_COLUMNS]
+
+
+def _kast_rounds(players: pd.DataFrame, kills: pd.DataFrame, tick_rate: float) -> pd.Series:
+    window = TRADE_WINDOW_SECONDS * tick_rate
+    counted: Dict[str, int] = {}
+    for round_number, roster in players.groupby("round"):
+        round_kills = kills[kills["round"] == round_number]
+        enemy = round_kills[round_kills["attacker_side"] != round_kills["victim_side"]]
+        contributed: Set[str] = set(enemy["attacker_steamid"]) | set(enemy["assister_steamid"].dropna())
+        contributed |= set(roster["steamid"]) - set(round_kills["victim_steamid"])
+        for death in round_kills.itertuples(index=False):
+            revenge = round_kills[
+                (round_kills["victim_steamid"] == death.attacker_steamid)
+                & (round_kills["tick"] > death.tick)
+                & (round_kills["tick"] <= death.tick + window)
+            ]
+            if not revenge.empty:
+                contributed.add(death.victim_steamid)
+        for steamid in contributed & set(roster["steamid"]):
+            counted[steamid] = counted.get(steamid, 0) + 1
+    return pd.Series(counted, dtype="int64")
@@ -0,0 +1,118 @@
+from __future__ import annotations
+
+from typing import Optional
+
+import pandas as pd
+
+from ..parser import ParsedDemo, grenade_kind
+from .pistol import PISTOLS, pistol_round_numbers
+
+SITE_HIT_COLUMNS = [
+    "round",
+    "team_name",
+    "opponent",
+    "economy",
+    "score_for",
+    "score_against",
+    "hit",
+    "hit_seconds",
+    "plant_site",
+    "first_contact_place",
+]
+# Inventory items that are neither pistols, grenades nor primary weapons.
+GEAR = {"c4", "c4 explosive", "zeus x27", "kevlar vest", "kevlar + helmet", "defuse kit"}
+
+
+def build_site_hits(parsed: ParsedDemo) -> pd.DataFrame:
+    """Where the T side went each round, with the game state it went there in.
+
+    ``hit`` is ``mid`` when the round's first contact happened in a mid area
+    (a mid split), otherwise the site the bomb was planted on (``A`` or ``B``);
+    rounds with neither are kept with an empty ``hit``. ``hit_seconds`` counts
+    from the end of freeze time to the first contact or plant that decided it.
+    ``economy`` is the T side's buy (``pistol``, ``eco``, ``force`` or
+    ``full``; ``unknown`` without an equipment snapshot) and the score is the
+    T team's before the round.
+    """
+
+    if parsed.rounds.empty:
+        return pd.DataFrame(columns=SITE_HIT_COLUMNS)
+
+    tick_rate = parsed.tick_rate
+    pistols = set(pistol_round_numbers(parsed.rounds["round"]))
+    state = parsed.round_start_state
+    wins: dict = {}
+    rows = []
+    for round_info in parsed.rounds.sort_values("round").itertuples(index=False):
+        team, opponent = round_info.t_team, round_info.ct_team
+        kills = parsed.kills[parsed.kills["round"] == round_info.round].sort_values("tick")
+        plants = parsed.bomb_events[
+            (parsed.bomb_events["round"] == round_info.round) & (parsed.bomb_events["event"] == "planted")
+        ]
+        first_kill = kills.iloc[0] if not kills.empty else None
+        place = _contact_place(first_kill)
+        plant = plants.iloc[0] if not plants.empty else None
+
+        if place and "mid" in place.casefold():
+            hit, hit_tick = "mid", first_kill["tick"]
+        elif plant is not None:
+            hit, hit_tick = str(plant["site"]).upper(), plant["tick"]
+        else:
+            hit, hit_tick = None, None
+        start = round_info.freeze_end_tick if pd.notna(round_info.freeze_end_tick) else round_info.start_tick
+
+        players = state[(state["round"] == round_info.round) & (state["side"] == "T")] if not state.empty else state
+        rows.append(
+            {
+                "round": int(round_info.round),
+                "team_name": team,
+                "opponent": opponent,
+                "economy": "pistol" if round_info.round in pistols else classify_economy(players),
+                "score_for": wins.get(team, 0),
+                "score_against": wins.get(opponent, 0),
+                "hit": hit,
+                "hit_seconds": round(float(hit_tick - start) / tick_rate, 1) if hit_tick is not None else None,
+                "plant_site": plant["site"] if plant is not None else None,
+                "first_contact_place": place,
+            }
+        )
+        winner = team if round_info.winner == "T" else opponent if round_info.winner == "CT" else None
+        if winner is not None:
+            wins[winner] = wins.get(winner, 0) + 1
+    return pd.DataFrame(rows, columns=SITE_HIT_COLUMNS)
+
+
+def classify_economy(players: pd.DataFrame) -> str:
+    """``full`` when four or more players hold a primary weapon, ``force`` for a partial buy, else ``eco``."""
+
+    if players.empty:
+        return "unknown"
+    primaries = int(players["inventory"].map(_has_primary).sum())
+    armored = int((players["armor"].fillna(0) > 0).sum())
+    if primaries >= 4:
+        return "full"
+    if primaries >= 2 or armored * 2 >= len(players):
+        return "force"
+    return "eco"
+
+
+def _contact_place(kill) -> Optional[str]:
+    if kill is None:
+        return None
+    for column in ("victim_place", "attacker_place"):
+        place = kill.get(column)
+        if isinstance(place, str) and place:
+            return place
+    return None
+
+
+def _has_primary(inventory) -> bool:
+    if inventory is None or isinstance(inventory, float):
+        return False
+    for item in inventory:
+        name = str(item).lower()
+        if "knife" in name or "bayonet" in name:
+            continue
+        if name not in PISTOLS and name not in GEAR and grenade_kind(item) is None:
+            return True
+    return False
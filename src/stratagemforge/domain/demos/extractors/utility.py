@@ -0,0 +1,165 @@
+from __future__ import annotations
+
+from typing import List
+
+import pandas as pd
+
+from ..parser import BLIND_COLUMNS, DAMAGE_COLUMNS, GRENADE_KINDS, ParsedDemo, grenade_kind
+from ..smokes import smoke_intervals
+
+UTILITY_KINDS = ("smoke", "flash", "molotov", "he")
+_PLURALS = {"smoke": "smokes", "flash": "flashes", "molotov": "molotovs", "he": "he_grenades"}
+
+PLAYER_UTILITY_COLUMNS = [
+    "round",
+    "steamid",
+    "side",
+    "flashes_thrown",
+    "enemies_flashed",
+    "teammates_flashed",
+    "enemy_blind_seconds",
+    "team_blind_seconds",
+    "he_damage",
+    "fire_damage",
+    "smokes_thrown",
+    "smoke_seconds",
+]
+
+
+def first_contact_ticks(parsed: ParsedDemo) -> pd.Series:
+    """Return the first tick per round at which any player took damage or died."""
+
+    frames = [frame[["round", "tick"]] for frame in (parsed.damages, parsed.kills) if not frame.empty]
+    if not frames:
+        return pd.Series(dtype="float64", name="first_contact_tick")
+    contacts = pd.concat(frames, ignore_index=True)
+    return contacts.groupby("round")["tick"].min().rename("first_contact_tick")
+
+
+def summarize_team_utility(parsed: ParsedDemo) -> pd.DataFrame:
+    """Summarise grenade usage per round and side.
+
+    For each round/side the frame reports how many of each grenade kind were
+    thrown, how many of those throws happened before and after the round's first
+    contact, and how many grenades the side's surviving players still carried
+    when the round ended.
+    """
+
+    columns = ["round", "side"]
+    columns += [f"{_PLURALS[kind]}_thrown" for kind in UTILITY_KINDS]
+    columns += ["utility_thrown", "utility_before_contact", "utility_after_contact", "utility_unused"]
+    columns += [f"{_PLURALS[kind]}_unused" for kind in UTILITY_KINDS]
+
+    if parsed.rounds.empty:
+        return pd.DataFrame(columns=columns)
+
+    index = pd.MultiIndex.from_product([parsed.rounds["round"].tolist(), ["CT", "T"]], names=["round", "side"])
+    summary = pd.DataFrame(0, index=index, columns=columns[2:])
+
+    throws = parsed.grenade_throws
+    if not throws.empty:
+        throws = throws[throws["grenade"].isin(UTILITY_KINDS)]
+    if not throws.empty:
+        counts = throws.groupby(["round", "side", "grenade"]).size().unstack("grenade")
+        for kind in UTILITY_KINDS:
+            if kind in counts:
+                summary[f"{_PLURALS[kind]}_thrown"] = counts[kind]
+
+        contact = throws.join(first_contact_ticks(parsed), on="round")
+        before = contact["first_contact_tick"].isna() | (contact["tick"] < contact["first_contact_tick"])
+        summary["utility_before_contact"] = contact[before].groupby(["round", "side"]).size()
+        summary["utility_after_contact"] = contact[~before].groupby(["round", "side"]).size()
+
+    unused = _unused_utility(parsed)
+    for kind in UTILITY_KINDS:
+        if kind in unused:
+            summary[f"{_PLURALS[kind]}_unused"] = unused[kind]
+
+    summary = summary.fillna(0).astype("int64")
+    summary["utility_thrown"] = summary[[f"{_PLURALS[kind]}_thrown" for kind in UTILITY_KINDS]].sum(axis=1)
+    summary["utility_unused"] = summary[[f"{_PLURALS[kind]}_unused" for kind in UTILITY_KINDS]].sum(axis=1)
+    return summary.reset_index()[columns]
+
+
+def _unused_utility(parsed: ParsedDemo) -> pd.DataFrame:
+    inventory = parsed.round_end_inventory
+    if inventory.empty:
+        return pd.DataFrame()
+
+    alive = inventory[inventory["is_alive"].fillna(False).astype(bool)]
+    items = alive[["round", "side", "inventory"]].explode("inventory")
+    items["grenade"] = items["inventory"].map(grenade_kind)
+    items = items[items["grenade"].isin(GRENADE_KINDS)]
+    if items.empty:
+        return pd.DataFrame()
+    return items.groupby(["round", "side", "grenade"]).size().unstack("grenade")
+
+
+def build_player_utility(parsed: ParsedDemo) -> pd.DataFrame:
+    """Per-round utility effectiveness for every player who threw a grenade.
+
+    Flashes are attributed through ``player_blind`` events: enemies and
+    teammates blinded (self-flashes excluded) and the total blind time dealt
+    to each. HE and fire damage only counts damage to enemies. Smoke coverage
+    is the time between detonation and expiry, capped at the end of the round.
+    """
+
+    if parsed.rounds.empty:
+        return pd.DataFrame(columns=PLAYER_UTILITY_COLUMNS)
+
+    keys = ["round", "steamid"]
+    parts: List[pd.Series] = []
+    sides: List[pd.DataFrame] = []
+
+    throws = parsed.grenade_throws
+    if not throws.empty:
+        throws = throws.dropna(subset=["steamid"])
+        sides.append(throws[keys + ["side"]])
+        parts.append(throws[throws["grenade"] == "flash"].groupby(keys).size().rename("flashes_thrown"))
+        parts.append(throws[throws["grenade"] == "smoke"].groupby(keys).size().rename("smokes_thrown"))
+
+    blinds = parsed.blinds.reindex(columns=BLIND_COLUMNS).dropna(subset=["attacker_steamid"])
+    blinds = blinds[blinds["attacker_steamid"] != blinds["victim_steamid"]]
+    if not blinds.empty:
+        blinds = blinds.rename(columns={"attacker_steamid": "steamid", "attacker_side": "side"})
+        blinds["duration"] = pd.to_numeric(blinds["duration"]).fillna(0.0)
+        sides.append(blinds[keys + ["side"]])
+        enemy = blinds["side"] != blinds["victim_side"]
+        for rows, flashed, seconds in ((enemy, "enemies", "enemy"), (~enemy, "teammates", "team")):
+            grouped = blinds[rows].groupby(keys)
+            parts.append(grouped.size().rename(f"{flashed}_flashed"))
+            parts.append(grouped["duration"].sum().rename(f"{seconds}_blind_seconds"))
+
+    damages = parsed.damages.reindex(columns=DAMAGE_COLUMNS).dropna(subset=["attacker_steamid"])
+    damages = damages[damages["attacker_side"] != damages["victim_side"]]
+    if not damages.empty:
+        damages = damages.rename(columns={"attacker_steamid": "steamid", "attacker_side": "side"})
+        damages["grenade"] = damages["weapon"].map(grenade_kind)
+        damages["damage"] = pd.to_numeric(damages["damage"])
+        for kind, column in (("he", "he_damage"), ("molotov", "fire_damage")):
+            rows = damages[damages["grenade"] == kind]
+            sides.append(rows[keys + ["side"]])
+            parts.append(rows.groupby(keys)["damage"].sum().rename(column))
+
+    smoke_seconds = _smoke_seconds(parsed)
+    if not smoke_seconds.empty:
+        parts.append(smoke_seconds)
+
+    if not sides:
+        return pd.DataFrame(columns=PLAYER_UTILITY_COLUMNS)
+    players = pd.concat(sides, ignore_index=True).drop_duplicates(keys, keep="last").set_index(keys)
+    stats = players.join(pd.concat(parts, axis=1)) if parts else players
+    stats = stats.reindex(columns=PLAYER_UTILITY_COLUMNS[2:])
+    counters = ["flashes_thrown", "enemies_flashed", "teammates_flashed", "he_damage", "fire_damage", "smokes_thrown"]
+    stats[counters] = stats[counters].fillna(0).astype("int64")
+    for column in ("enemy_blind_seconds", "team_blind_seconds", "smoke_seconds"):
+        stats[column] = stats[column].fillna(0.0).astype(float).round(2)
+    return stats.reset_index().sort_values(keys).reset_index(drop=True)[PLAYER_UTILITY_COLUMNS]
+
+
+def _smoke_seconds(parsed: ParsedDemo) -> pd.Series:
+    smokes = smoke_intervals(parsed.grenade_events, parsed.rounds, parsed.tick_rate).dropna(subset=["steamid"])
+    if smokes.empty:
+        return pd.Series(dtype="float64", name="smoke_seconds")
+    seconds = (smokes["end_tick"] - smokes["start_tick"]) / parsed.tick_rate
+    return seconds.groupby([smokes["round"], smokes["steamid"]]).sum().rename("smoke_seconds")
@@ -0,0 +1,221 @@
+from __future__ import annotations
+
+import hashlib
+import logging
+import time
+from dataclasses import dataclass, field
+from datetime import datetime, timezone
+from html.parser import HTMLParser
+from pathlib import Path
+from typing import Dict, List, Optional
+from urllib.error import HTTPError, URLError
+from urllib.parse import urlparse
+from urllib.request import Request, urlopen
+
+logger = logging.getLogger(__name__)
+
+_VOID_TAGS = {"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr"}
+
+
+class HltvError(RuntimeError):
+    """Raised when HLTV cannot be reached or returns an unusable page."""
+
+
+@dataclass
+class HltvMapResult:
+    map_name: str
+    score_1: int
+    score_2: int
+
+
+@dataclass
+class HltvMatch:
+    url: str
+    team_1: str
+    team_2: str
+    event: Optional[str] = None
+    played_at: Optional[datetime] = None
+    series_score_1: Optional[int] = None
+    series_score_2: Optional[int] = None
+    maps: List[HltvMapResult] = field(default_factory=list)
+
+    def map_result(self, map_name: Optional[str]) -> Optional[HltvMapResult]:
+        if map_name is None:
+            return self.maps[0] if len(self.maps) == 1 else None
+        for result in self.maps:
+            if result.map_name == map_name:
+                return result
+        return None
+
+    def to_metadata(self) -> Dict[str, object]:
+        return {
+            "url": self.url,
+            "team_1": self.team_1,
+            "team_2": self.team_2,
+            "event": self.event,
+            "played_at": self.played_at.isoformat() if self.played_at else None,
+            "series_score": [self.series_score_1, self.series_score_2],
+            "maps": [{"map_name": m.map_name, "score_1": m.score_1, "score_2": m.score_2} for m in self.maps],
+        }
+
+
+def validate_match_url(url: str) -> str:
+    parsed = urlparse(url)
+    host = (parsed.hostname or "").lower()
+    if parsed.scheme not in {"http", "https"} or not (host == "hltv.org" or host.endswith(".hltv.org")):
+        raise ValueError("Only hltv.org match URLs are supported")
+    if not parsed.path.startswith("/matches/"):
+        raise ValueError("HLTV URL must point at a match page (/matches/<id>/...)")
+    return url
+
+
+def hltv_map_name(label: str) -> str:
+    """Translate HLTV's display name (``Dust2``, ``Mirage``) to the in-game map name."""
+
+    return "de_" + "".join(label.lower().split())
+
+
+class HltvClient:
+    """Fetch HLTV match pages politely.
+
+    Requests are spaced at least ``min_interval`` seconds apart, ``429``
+    responses are retried after the advertised ``Retry-After`` delay, and pages
+    are cached on disk for ``cache_ttl`` seconds so re-imports do not hit HLTV.
+    """
+
+    def __init__(
+        self,
+        cache_dir: Path,
+        min_interval: float = 2.0,
+        cache_ttl: int = 86_400,
+        timeout: float = 30.0,
+        max_retries: int = 2,
+    ) -> None:
+        self.cache_dir = cache_dir
+        self.min_interval = min_interval
+        self.cache_ttl = cache_ttl
+        self.timeout = timeout
+        self.max_retries = max_retries
+        self._last_request = 0.0
+
+    def fetch_match(self, url: str) -> HltvMatch:
+        html = self._page(validate_match_url(url))
+        return parse_match_page(html, url)
+
+    def _page(self, url: str) -> str:
+        cached = self._cache_path(url)
+        if cached.exists() and time.time() - cached.stat().st_mtime < self.cache_ttl:
+            return cached.read_text(encoding="utf-8")
+
+        html = self._download(url)
+        cached.parent.mkdir(parents=True, exist_ok=True)
+        cached.write_text(html, encoding="utf-8")
+        return html
+
+    def _cache_path(self, url: str) -> Path:
+        return self.cache_dir / f"{hashlib.sha256(url.encode('utf-8')).hexdigest()}.html"
+
+    def _download(self, url: str) -> str:
+        attempt = 0
+        while True:
+            attempt += 1
+            self._throttle()
+            request = Request(url, headers={"User-Agent": "StratagemForge/1.0", "Accept": "text/html"})
+            try:
+                with urlopen(request, timeout=self.timeout) as response:
+                    charset = response.headers.get_content_charset() or "utf-8"
+                    return response.read().decode(charset, errors="replace")
+            except HTTPError as exc:
+                if exc.code != 429 or attempt > self.max_retries:
+                    raise HltvError(f"HLTV request failed with HTTP {exc.code}") from exc
+                retry_after = exc.headers.get("Retry-After", "")
+                delay = float(retry_after) if retry_after.isdigit() else self.min_interval * 2**attempt
+                logger.warning("HLTV rate limited %s; retrying in %.1fs", url, delay)
+                time.sleep(delay)
+            except (URLError, TimeoutError, ConnectionError) as exc:
+                raise HltvError(f"HLTV request failed: {exc}") from exc
+
+    def _throttle(self) -> None:
+        wait = self._last_request + self.min_interval - time.monotonic()
+        if wait > 0:
+            time.sleep(wait)
+        self._last_request = time.monotonic()
+
+
+def parse_match_page(html: str, url: str = "") -> HltvMatch:
+    """Extract teams, event, date and per-map scores from an HLTV match page."""
+
+    collector = _MatchPageCollector()
+    collector.feed(html)
+    collector.close()
+
+    if len(collector.teams) < 2:
+        raise HltvError("Could not find both team names on the HLTV page")
+
+    series = collector.series_scores
+    return HltvMatch(
+        url=url,
+        team_1=collector.teams[0],
+        team_2=collector.teams[1],
+        event=collector.event,
+        played_at=collector.played_at,
+        series_score_1=series[0] if len(series) >= 2 else None,
+        series_score_2=series[1] if len(series) >= 2 else None,
+        maps=[
+            HltvMapResult(map_name=hltv_map_name(label), score_1=scores[0], score_2=scores[1])
+            for label, scores in collector.maps
+            if label and len(scores) >= 2
+        ],
+    )
+
+
+class _MatchPageCollector(HTMLParser):
+    def __init__(self) -> None:
+        super().__init__(convert_charrefs=True)
+        self._stack: List[tuple[str, set[str]]] = []
+        self.teams: List[str] = []
+        self.series_scores: List[int] = []
+        self.event: Optional[str] = None
+        self.played_at: Optional[datetime] = None
+        self.maps: List[tuple[Optional[str], List[int]]] = []
+
+    def handle_starttag(self, tag: str, attrs: list[tuple[str, Optional[str]]]) -> None:
+        attributes = dict(attrs)
+        classes = set((attributes.get("class") or "").split())
+        if "mapholder" in classes:
+            self.maps.append((None, []))
+        if "date" in classes and self.played_at is None and (attributes.get("data-unix") or "").isdigit():
+            self.played_at = datetime.fromtimestamp(int(attributes["data-unix"]) / 1000, tz=timezone.utc).replace(
+                tzinfo=None
+            )
+        if tag == "a" and self._inside("event") and self.event is None and attributes.get("title"):
+            self.event = attributes["title"]
+        if tag not in _VOID_TAGS:
+            self._stack.append((tag, classes))
+
+    def handle_endtag(self, tag: str) -> None:
+        for index in range(len(self._stack) - 1, -1, -1):
+            if self._stack[index][0] == tag:
+                del self._stack[index:]
+                return
+
+    def handle_data(self, data: str) -> None:
+        text = data.strip()
+        if not text or not self._stack:
+            return
+        classes = self._stack[-1][1]
+        in_team = self._inside("team1-gradient") or self._inside("team2-gradient")
+        if "teamName" in classes and in_team and len(self.teams) < 2:
+            self.teams.append(text)
+        elif in_team and classes & {"won", "lost", "tie"} and text.isdigit() and len(self.series_scores) < 2:
+            self.series_scores.append(int(text))
+        elif self._inside("event") and self._stack[-1][0] == "a" and self.event is None:
+            self.event = text
+        elif self.maps and "mapname" in classes:
+            label, scores = self.maps[-1]
+            self.maps[-1] = (label or text, scores)
+        elif self.maps and "results-team-score" in classes and text.isdigit():
+            self.maps[-1][1].append(int(text))
+
+    def _inside(self, css_class: str) -> bool:
+        return any(css_class in classes for _, classes in self._stack)
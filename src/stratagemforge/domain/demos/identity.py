@@ -0,0 +1,24 @@
+from __future__ import annotations
+
+import hashlib
+import re
+from uuid import UUID
+
+_SHA256_HEX = re.compile(r"^[0-9a-f]{64}$")
+
+
+def content_hash(checksum: str) -> str:
+    """Full SHA-256 (hex) identifying a demo's content, as stored on its match."""
+
+    checksum = checksum.lower()
+    return checksum if _SHA256_HEX.match(checksum) else hashlib.sha256(checksum.encode()).hexdigest()
+
+
+def match_id_for(checksum: str) -> str:
+    """Deterministic match id: the first 128 bits of the content hash as a UUID.
+
+    The id is only a truncation of :func:`content_hash`, so the full hash is
+    stored next to it and compared before a match is inserted.
+    """
+
+    return str(UUID(bytes=bytes.fromhex(content_hash(checksum))[:16]))
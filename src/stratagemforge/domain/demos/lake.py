@@ -0,0 +1,118 @@
+from __future__ import annotations
+
+import logging
+import tempfile
+from pathlib import Path
+from typing import Iterator, List, Optional, Tuple
+
+import pyarrow as pa
+import pyarrow.compute as pc
+import pyarrow.parquet as pq
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.parquet import ParquetOptions
+from ...core.storage import ArtifactStorage, build_storage
+from .extractors import DATASET_ROW_GROUP_KEYS
+from .models import Demo
+
+logger = logging.getLogger(__name__)
+
+HIVE_NULL_PARTITION = "__HIVE_DEFAULT_PARTITION__"
+
+
+class LakeWriter:
+    """Mirror processed datasets into a Hive-partitioned layout for Spark, DuckDB or Trino.
+
+    Runs as a post-processing hook when ``LAKE_PARTITIONED`` is set. Every
+    dataset of a match is copied to ``<LAKE_PREFIX>/<dataset>/match_id=<id>/``
+    and the per-round datasets (``player_ticks``) are split further into
+    ``round=<n>/`` directories, so engines reading the whole prefix prune
+    matches and rounds from the paths alone. Partition columns are left out of
+    the files, as Hive expects, and rows are written in row groups of
+    ``LAKE_ROW_GROUP_ROWS`` with the ``PARQUET_COMPRESSION`` codec. The written
+    keys are recorded as ``lake_keys``; like replication, failures are logged
+    and never fail an upload, and demos stored in a residency region stay out
+    of the lake.
+    """
+
+    def __init__(self, settings: Settings, storage: ArtifactStorage | None = None) -> None:
+        self.settings = settings
+        self.storage = storage or build_storage(settings)
+
+    @property
+    def enabled(self) -> bool:
+        return self.settings.lake_partitioned
+
+    def write(self, session: Session, demo: Demo) -> List[str]:
+        """Write the partitions of ``demo``'s datasets, replacing those of an earlier run, and return their keys."""
+
+        if demo.match is None:
+            return []
+        prefix = self.settings.lake_prefix.strip("/")
+        options = ParquetOptions.from_settings(self.settings).writer_arguments()
+        keys: List[str] = []
+        with tempfile.TemporaryDirectory() as workdir:
+            for name, key in demo.artifact_keys().items():
+                if name == "summary" or not self.storage.exists(key):
+                    continue
+                table = pq.read_table(self.storage.get(key))
+                for partitions, part in hive_partitions(table, DATASET_ROW_GROUP_KEYS.get(name)):
+                    relative = "/".join([prefix, name, f"match_id={demo.match.id}", *partitions, "part-0.parquet"])
+                    path = Path(workdir) / relative
+                    path.parent.mkdir(parents=True, exist_ok=True)
+                    pq.write_table(part, path, row_group_size=self.settings.lake_row_group_rows, **options)
+                    keys.append(self.storage.put(path, relative))
+
+        metadata = dict(demo.extra_metadata or {})
+        for stale in set(metadata.get("lake_keys") or []) - set(keys):
+            self.storage.delete(stale)
+        metadata.pop("lake_error", None)
+        metadata["lake_keys"] = keys
+        demo.extra_metadata = metadata
+        session.commit()
+        return keys
+
+    def write_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: write the lake partitions, never failing the upload."""
+
+        if not self.enabled or (demo.extra_metadata or {}).get("storage_region"):
+            return
+        try:
+            self.write(session, demo)
+        except Exception as exc:  # noqa: BLE001 - the datasets are stored either way
+            logger.exception("Writing lake partitions of demo %s failed", demo.id)
+            session.rollback()
+            demo.extra_metadata = {**(demo.extra_metadata or {}), "lake_error": str(exc)}
+            session.commit()
+
+    def delete_partitions(self, session: Session, demo: Demo) -> None:
+        """Delete hook: remove the demo's lake partitions when it is permanently deleted."""
+
+        for key in (demo.extra_metadata or {}).get("lake_keys", []):
+            try:
+                self.storage.delete(key)
+            except Exception:  # noqa: BLE001 - the demo is deleted either way
+                logger.exception("Deleting lake partition %s of demo %s failed", key, demo.id)
+
+
+def hive_partitions(table: pa.Table, column: Optional[str]) -> Iterator[Tuple[List[str], pa.Table]]:
+    """``(["column=value"], rows)`` for every value of ``column``, without that column; the whole table if unset."""
+
+    # The pandas metadata would still list dropped partition columns.
+    metadata = {key: value for key, value in (table.schema.metadata or {}).items() if key != b"pandas"}
+    table = table.replace_schema_metadata(metadata)
+    if column is None or column not in table.column_names:
+        yield [], table
+        return
+    values = table[column]
+    for value in sorted(pc.unique(values).to_pylist(), key=lambda item: (item is None, item)):
+        mask = pc.is_null(values) if value is None else pc.equal(values, pa.scalar(value, values.type))
+        label = HIVE_NULL_PARTITION if value is None else _partition_value(value)
+        yield [f"{column}={label}"], table.filter(mask).drop_columns([column])
+
+
+def _partition_value(value: object) -> str:
+    if isinstance(value, float) and value.is_integer():
+        return str(int(value))  # rounds read back as floats when the column had gaps
+    return str(value)
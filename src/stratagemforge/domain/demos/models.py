@@ -1,13 +1,14 @@
 from __future__ import annotations
 
 from datetime import datetime
-from typing import Any, Dict, Optional
-from uuid import uuid4
+from typing import Any, Dict, List, Optional
 
-from sqlalchemy import BigInteger, DateTime, JSON, String
-from sqlalchemy.orm import Mapped, mapped_column
+from sqlalchemy import BigInteger, Boolean, DateTime, Float, ForeignKey, Integer, JSON, String, Text
+from sqlalchemy.orm import Mapped, mapped_column, relationship
 
 from ...core.database import Base
+from ...core.ids import new_ulid, new_uuid
+from ..competitions.models import Competition  # noqa: F401 - registers the competitions FK target
 
 
 class Demo(Base):
@@ -15,20 +16,224 @@ class Demo(Base):
 
     __tablename__ = "demos"
 
-    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=lambda: str(uuid4()))
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
     original_filename: Mapped[str] = mapped_column(String(255), nullable=False)
     stored_path: Mapped[str] = mapped_column(String(1024), nullable=False)
     processed_path: Mapped[Optional[str]] = mapped_column(String(1024))
     checksum: Mapped[str] = mapped_column(String(128), nullable=False, unique=True, index=True)
     size_bytes: Mapped[int] = mapped_column(BigInteger, nullable=False)
     content_type: Mapped[Optional[str]] = mapped_column(String(128))
+    # uploaded, processed, failed (transient errors outlasted the retries) or dead_letter (corrupt demo).
     status: Mapped[str] = mapped_column(String(32), default="uploaded", nullable=False)
     uploaded_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
     processed_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
     extra_metadata: Mapped[Optional[Dict[str, Any]]] = mapped_column(JSON, default=dict)
+    competition_id: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("competitions.id"), index=True)
+    series: Mapped[Optional[str]] = mapped_column(String(255))
+    # Id of the user who uploaded the demo, as given by their API key or user-service token.
+    owner_id: Mapped[Optional[str]] = mapped_column(String(64), index=True)
+    # Team the demo was uploaded for; with ``team`` visibility only its members (and the owner) see it.
+    team_id: Mapped[Optional[str]] = mapped_column(String(36), index=True)
+    # Who may see the demo: "private" (the owner), "team" (the owner and team members) or "public".
+    # Older rows without a value count as "team" when they have a team and "public" otherwise.
+    visibility: Mapped[Optional[str]] = mapped_column(String(16))
+    deleted_at: Mapped[Optional[datetime]] = mapped_column(DateTime, index=True)
+
+    match: Mapped[Optional["Match"]] = relationship(
+        back_populates="demo", uselist=False, cascade="all, delete-orphan"
+    )
 
     def mark_processed(self, processed_path: str, processed_at: datetime, metadata: Dict[str, Any]) -> None:
         self.status = "processed"
         self.processed_path = processed_path
         self.processed_at = processed_at
         self.extra_metadata = metadata
+
+    def artifact_keys(self) -> Dict[str, str]:
+        """Map artefact names (``summary`` plus dataset names) to storage keys."""
+
+        metadata = self.extra_metadata or {}
+        keys: Dict[str, str] = {}
+        summary_key = metadata.get("summary_key") or self.processed_path
+        if summary_key:
+            keys["summary"] = summary_key
+        keys.update(metadata.get("datasets") or {})
+        return keys
+
+    def csv_keys(self) -> Dict[str, str]:
+        """Map dataset names to the storage keys of their gzip CSV copies, when those were written."""
+
+        return dict((self.extra_metadata or {}).get("csv_datasets") or {})
+
+
+class Match(Base):
+    """Match-level metadata recorded when a demo has been parsed."""
+
+    __tablename__ = "matches"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    demo_id: Mapped[str] = mapped_column(String(36), ForeignKey("demos.id"), nullable=False, unique=True)
+    # Full SHA-256 of the demo; ``id`` is derived from its first 128 bits.
+    content_hash: Mapped[Optional[str]] = mapped_column(String(64), unique=True, index=True)
+    # Optional id the match is known by elsewhere (e.g. a FACEIT match id), usable as an alias.
+    external_id: Mapped[Optional[str]] = mapped_column(String(128), unique=True, index=True)
+    external_source: Mapped[Optional[str]] = mapped_column(String(32))
+    # Matches recorded from other sources of the same game (GOTV, FACEIT, POV) share a game id;
+    # only the richest of them is primary and counted in aggregates.
+    game_id: Mapped[Optional[str]] = mapped_column(String(36), index=True)
+    is_primary: Mapped[bool] = mapped_column(Boolean, default=True, nullable=False)
+    # Set on a demo of an unfinished game (e.g. the first half) once the full game is uploaded.
+    superseded_by: Mapped[Optional[str]] = mapped_column(String(36), index=True)
+    map_name: Mapped[Optional[str]] = mapped_column(String(64), index=True)
+    played_at: Mapped[datetime] = mapped_column(DateTime, nullable=False, index=True)
+    # Wall-clock start recovered from the demo itself (UTC) and where it came from, e.g. "filename".
+    started_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    started_at_source: Mapped[Optional[str]] = mapped_column(String(32))
+    team_a: Mapped[str] = mapped_column(String(255), nullable=False)
+    team_b: Mapped[str] = mapped_column(String(255), nullable=False)
+    score_a: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    score_b: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    winner: Mapped[Optional[str]] = mapped_column(String(255))
+    rounds: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    # Ticks per second and the start/freeze-end/end tick of each round; see :mod:`.clock`.
+    tick_rate: Mapped[Optional[float]] = mapped_column(Float)
+    round_ticks: Mapped[List[Dict[str, Any]]] = mapped_column(JSON, default=list)
+    player_count: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    source: Mapped[str] = mapped_column(String(32), default="upload", nullable=False)
+    event_name: Mapped[Optional[str]] = mapped_column(String(255))
+    hltv_url: Mapped[Optional[str]] = mapped_column(String(1024))
+    artifacts: Mapped[Dict[str, str]] = mapped_column(JSON, default=dict)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+    demo: Mapped[Demo] = relationship(back_populates="match")
+    players: Mapped[List["MatchPlayer"]] = relationship(
+        back_populates="match", cascade="all, delete-orphan", order_by="MatchPlayer.team_name"
+    )
+
+
+class MatchPlayer(Base):
+    """A player who appeared in a match, used for player-based lookups."""
+
+    __tablename__ = "match_players"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    match_id: Mapped[str] = mapped_column(String(36), ForeignKey("matches.id"), nullable=False, index=True)
+    steamid: Mapped[str] = mapped_column(String(32), nullable=False, index=True)
+    name: Mapped[Optional[str]] = mapped_column(String(255))
+    team_name: Mapped[Optional[str]] = mapped_column(String(255))
+
+    match: Mapped[Match] = relationship(back_populates="players")
+
+
+class LiveBroadcast(Base):
+    """A GOTV broadcast being recorded while the match is played."""
+
+    __tablename__ = "live_broadcasts"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    url: Mapped[str] = mapped_column(String(1024), nullable=False)
+    # connecting, live, ending (waiting to be ingested), finished or failed.
+    status: Mapped[str] = mapped_column(String(16), default="connecting", nullable=False, index=True)
+    owner_id: Mapped[Optional[str]] = mapped_column(String(64), index=True)
+    team_id: Mapped[Optional[str]] = mapped_column(String(36), index=True)
+    recording_path: Mapped[str] = mapped_column(String(1024), nullable=False)
+    map_name: Mapped[Optional[str]] = mapped_column(String(64))
+    fragment: Mapped[Optional[int]] = mapped_column(Integer)  # last fragment written to the recording
+    segment_fragment: Mapped[Optional[int]] = mapped_column(Integer)  # fragment the last segment was cut at
+    tick: Mapped[Optional[int]] = mapped_column(Integer)
+    rounds_played: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    team_a: Mapped[Optional[str]] = mapped_column(String(255))
+    team_b: Mapped[Optional[str]] = mapped_column(String(255))
+    score_a: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    score_b: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    # One entry per partial parquet segment: number, up_to_tick and the datasets written.
+    segments: Mapped[List[Dict[str, Any]]] = mapped_column(JSON, default=list)
+    error: Mapped[Optional[str]] = mapped_column(Text)
+    demo_id: Mapped[Optional[str]] = mapped_column(String(36))  # the finished recording once ingested
+    started_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    # When the last fragment arrived; the broadcast has ended after ``broadcast_idle_seconds`` without one.
+    updated_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    finished_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+
+class ParseJob(Base):
+    """One processing run of a demo, kept after the demo is gone for throughput reporting."""
+
+    __tablename__ = "parse_jobs"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_ulid)
+    demo_id: Mapped[str] = mapped_column(String(36), nullable=False, index=True)
+    # parsed, failed or skipped as reported by the processor; error when processing raised a
+    # transient error and dead_letter when it raised anything else.
+    status: Mapped[str] = mapped_column(String(32), nullable=False)
+    attempt: Mapped[int] = mapped_column(Integer, default=1, nullable=False)
+    # The job this run manually retried, if any.
+    retry_of: Mapped[Optional[str]] = mapped_column(String(36))
+    started_at: Mapped[datetime] = mapped_column(DateTime, nullable=False, index=True)
+    finished_at: Mapped[datetime] = mapped_column(DateTime, nullable=False)
+    duration_seconds: Mapped[float] = mapped_column(Float, nullable=False)
+    size_bytes: Mapped[int] = mapped_column(BigInteger, nullable=False)
+    error: Mapped[Optional[str]] = mapped_column(Text)
+
+
+class UploadBatch(Base):
+    """Demos uploaded together; each one is processed as its own job in the background."""
+
+    __tablename__ = "upload_batches"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_ulid)
+    owner_id: Mapped[Optional[str]] = mapped_column(String(64), index=True)
+    team_id: Mapped[Optional[str]] = mapped_column(String(36), index=True)
+    competition_id: Mapped[Optional[str]] = mapped_column(String(36))
+    visibility: Mapped[Optional[str]] = mapped_column(String(16))
+    force: Mapped[bool] = mapped_column(Boolean, default=False, nullable=False)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False, index=True)
+    finished_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+    items: Mapped[List["UploadBatchItem"]] = relationship(
+        back_populates="batch", cascade="all, delete-orphan", order_by="UploadBatchItem.position"
+    )
+
+    @property
+    def counts(self) -> Dict[str, int]:
+        counts = dict.fromkeys(BATCH_ITEM_STATUSES, 0)
+        for item in self.items:
+            counts[item.status] += 1
+        return counts
+
+    @property
+    def status(self) -> str:
+        """queued, processing, completed, completed_with_errors or failed, from the items' statuses."""
+
+        counts = self.counts
+        if counts["queued"] == len(self.items):
+            return "queued"
+        if counts["queued"] or counts["processing"]:
+            return "processing"
+        if counts["failed"] == len(self.items):
+            return "failed"
+        return "completed_with_errors" if counts["failed"] else "completed"
+
+
+# An item is a duplicate when its demo had already been processed (and the batch was not forced).
+BATCH_ITEM_STATUSES = ("queued", "processing", "processed", "duplicate", "failed")
+
+
+class UploadBatchItem(Base):
+    """One demo of an upload batch, staged on disk until its turn comes."""
+
+    __tablename__ = "upload_batch_items"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_ulid)
+    batch_id: Mapped[str] = mapped_column(String(36), ForeignKey("upload_batches.id"), nullable=False, index=True)
+    position: Mapped[int] = mapped_column(Integer, nullable=False)
+    filename: Mapped[str] = mapped_column(String(255), nullable=False)
+    status: Mapped[str] = mapped_column(String(16), default="queued", nullable=False, index=True)
+    staged_path: Mapped[Optional[str]] = mapped_column(String(1024))  # cleared once the item is done
+    size_bytes: Mapped[int] = mapped_column(BigInteger, default=0, nullable=False)
+    demo_id: Mapped[Optional[str]] = mapped_column(String(36))
+    error: Mapped[Optional[str]] = mapped_column(Text)
+    started_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    finished_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+    batch: Mapped[UploadBatch] = relationship(back_populates="items")
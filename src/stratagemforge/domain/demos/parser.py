@@ -0,0 +1,1110 @@
+from __future__ import annotations
+
+import re
+from collections import Counter
+from dataclasses import dataclass, field
+from enum import Enum
+from pathlib import Path
+from typing import Any, Dict, Iterable, Optional, Protocol, Tuple
+
+import pandas as pd
+
+from .smokes import lines_through_smoke, shot_lines, smoke_intervals
+
+GRENADE_KINDS = ("smoke", "flash", "molotov", "he", "decoy")
+
+_GRENADE_ALIASES = {
+    "smokegrenade": "smoke",
+    "smoke grenade": "smoke",
+    "flashbang": "flash",
+    "molotov": "molotov",
+    "incgrenade": "molotov",
+    "incendiary grenade": "molotov",
+    "inferno": "molotov",
+    "hegrenade": "he",
+    "high explosive grenade": "he",
+    "decoy": "decoy",
+    "decoy grenade": "decoy",
+}
+
+
+def grenade_kind(weapon: Any) -> Optional[str]:
+    """Normalise a weapon or inventory item name to a grenade kind, if it is one."""
+
+    if not isinstance(weapon, str):
+        return None
+    name = weapon.lower().strip()
+    if name.startswith("weapon_"):
+        name = name[len("weapon_"):]
+    return _GRENADE_ALIASES.get(name)
+
+
+# Items left out of inventory codes; the bomb and the defuse kit have columns of their own.
+_UNCODED_ITEMS = {"c4", "c4 explosive", "defuse kit", "kevlar vest", "kevlar + helmet"}
+_BOMB_ITEMS = {"c4", "c4 explosive", "weapon_c4"}
+
+
+def inventory_code(items: Any) -> Optional[str]:
+    """Encode an inventory list compactly: weapons, then grenades with their counts.
+
+    ``["AK-47", "Glock-18", "Knife", "Flashbang", "Flashbang", "Smoke Grenade"]``
+    becomes ``"ak47,glock18|flash2,smoke"``; knives and gear are left out.
+    """
+
+    if items is None or isinstance(items, float):
+        return None
+    weapons, grenades = [], Counter()
+    for item in items:
+        kind = grenade_kind(item)
+        if kind:
+            grenades[kind] += 1
+            continue
+        name = str(item).lower().strip()
+        name = name[len("weapon_"):] if name.startswith("weapon_") else name
+        if name in _UNCODED_ITEMS or "knife" in name or "bayonet" in name:
+            continue
+        weapons.append(re.sub(r"[^a-z0-9]", "", name))
+    counts = [f"{kind}{grenades[kind] if grenades[kind] > 1 else ''}" for kind in GRENADE_KINDS if grenades[kind]]
+    return ",".join(weapons) + "|" + ",".join(counts)
+
+
+def carries_bomb(items: Any) -> Optional[bool]:
+    if items is None or isinstance(items, float):
+        return None
+    return any(str(item).lower().strip() in _BOMB_ITEMS for item in items)
+
+
+def normalise_side(value: Any) -> Optional[str]:
+    """Map team numbers and team names to the canonical ``CT``/``T`` labels."""
+
+    if value is None or (isinstance(value, float) and pd.isna(value)):
+        return None
+    label = str(value).strip().upper()
+    if label in {"3", "CT", "COUNTERTERRORIST", "COUNTER-TERRORIST"}:
+        return "CT"
+    if label in {"2", "T", "TERRORIST", "TERRORISTS"}:
+        return "T"
+    return None
+
+
+def bombsite_label(place: Any) -> Optional[str]:
+    """Reduce a map place name such as ``BombsiteA`` to the site letter."""
+
+    if not isinstance(place, str) or not place:
+        return None
+    lowered = place.lower()
+    if lowered.startswith("bombsite"):
+        return place[len("bombsite"):].strip().upper() or None
+    return place
+
+
+def _empty(columns: Iterable[str]) -> pd.DataFrame:
+    return pd.DataFrame(columns=list(columns))
+
+
+ROUND_COLUMNS = ("round", "start_tick", "freeze_end_tick", "end_tick", "winner", "reason", "ct_team", "t_team")
+KILL_COLUMNS = (
+    "tick",
+    "round",
+    "attacker_steamid",
+    "attacker_name",
+    "attacker_side",
+    "victim_steamid",
+    "victim_name",
+    "victim_side",
+    "weapon",
+    "headshot",
+    "assister_steamid",
+    "flash_assister_steamid",
+    "assist_type",
+    "attacker_place",
+    "victim_place",
+    "attacker_x",
+    "attacker_y",
+    "attacker_z",
+    "victim_x",
+    "victim_y",
+    "victim_z",
+)
+DAMAGE_COLUMNS = ("tick", "round", "attacker_steamid", "attacker_side", "victim_steamid", "victim_side", "weapon", "damage")
+GRENADE_THROW_COLUMNS = ("tick", "round", "steamid", "name", "side", "grenade")
+INVENTORY_COLUMNS = ("round", "steamid", "name", "side", "team_name", "is_alive", "inventory")
+BOMB_COLUMNS = ("tick", "round", "event", "steamid", "site")
+BLIND_COLUMNS = ("tick", "round", "attacker_steamid", "attacker_side", "victim_steamid", "victim_side", "duration")
+GRENADE_EVENT_COLUMNS = ("tick", "round", "steamid", "side", "grenade", "event", "entity_id", "x", "y", "z")
+AIM_SAMPLE_COLUMNS = (
+    "tick",
+    "pitch",
+    "yaw",
+    "x",
+    "y",
+    "z",
+    "weapon",
+    "is_alive",
+    "vel_x",
+    "vel_y",
+    "vel_z",
+    "speed",
+    "is_ducking",
+    "is_walking",
+    "is_airborne",
+    "is_blinded",
+    "ammo_clip",
+    "ammo_reserve",
+    "has_bomb",
+    "has_defuser",
+    "inventory",
+    "round_time_remaining",
+    "is_freeze_time",
+    "is_warmup",
+)
+# Per-tick movement props read along with the view angles, when the backend has them.
+MOVEMENT_FIELDS = ["velocity_X", "velocity_Y", "velocity_Z", "ducking", "is_walking", "is_airborne"]
+# Per-tick equipment props read the same way.
+EQUIPMENT_FIELDS = ["active_weapon_ammo", "total_ammo_left", "has_defuser", "inventory"]
+# Ticks per second of CS2 and competitive CS:GO demos, when the demo does not say otherwise.
+DEMO_TICK_RATE = 64
+# Seconds on the round clock once freeze time ends (competitive ``mp_roundtime_defuse`` of 1:55).
+ROUND_TIME_SECONDS = 115.0
+# Vertical speed (units/s) above which a player without an ``is_airborne`` prop counts as in the air.
+AIRBORNE_SPEED = 10.0
+AIM_SHOT_COLUMNS = ("tick", "weapon", "through_smoke")
+# (samples, shots) as returned by ``parse_aim``.
+AimFrames = Tuple[pd.DataFrame, pd.DataFrame]
+# Every player's position every ``POSITION_SAMPLE_TICKS`` ticks of each round, for heatmaps.
+PLAYER_TICK_COLUMNS = ("tick", "round", "steamid", "name", "side", "is_alive", "x", "y", "z")
+POSITION_SAMPLE_TICKS = 16
+# Frames of a round replay: every player's state at each sampled tick of the window.
+REPLAY_POSITION_COLUMNS = (
+    "tick",
+    "steamid",
+    "name",
+    "side",
+    "is_alive",
+    "health",
+    "x",
+    "y",
+    "z",
+    "yaw",
+    "pitch",
+    "weapon",
+)
+REPLAY_SHOT_COLUMNS = ("tick", "steamid", "weapon")
+REPLAY_KILL_COLUMNS = ("tick", "attacker_steamid", "victim_steamid", "assister_steamid", "weapon", "headshot")
+REPLAY_GRENADE_COLUMNS = ("tick", "steamid", "grenade", "event", "entity_id", "x", "y", "z")
+ROUND_START_COLUMNS = (
+    "round",
+    "steamid",
+    "name",
+    "side",
+    "team_name",
+    "inventory",
+    "armor",
+    "has_helmet",
+    "has_defuser",
+    "balance",
+    "x",
+    "y",
+    "z",
+)
+# Net messages a debugging dump can hold -> the native parser call that decodes them. The
+# parsers only expose messages through these calls, so a dump is their decoded form rather
+# than the raw protobuf bytes.
+NETMSG_READERS = {
+    "ServerInfo": "parse_header",
+    "GameEventList": "list_game_events",
+    "SetConVar": "parse_convars",
+    "PlayerInfo": "parse_player_info",
+    "SayText2": "parse_chat_messages",
+}
+
+
+@dataclass
+class ParsedDemo:
+    """Normalised event tables extracted from a demo file.
+
+    Every frame carries a ``round`` column (1-based) so extractors can group by
+    round without knowing which parser produced the data. Sides are always
+    ``CT`` or ``T``.
+    """
+
+    header: Dict[str, Any] = field(default_factory=dict)
+    rounds: pd.DataFrame = field(default_factory=lambda: _empty(ROUND_COLUMNS))
+    kills: pd.DataFrame = field(default_factory=lambda: _empty(KILL_COLUMNS))
+    damages: pd.DataFrame = field(default_factory=lambda: _empty(DAMAGE_COLUMNS))
+    grenade_throws: pd.DataFrame = field(default_factory=lambda: _empty(GRENADE_THROW_COLUMNS))
+    round_end_inventory: pd.DataFrame = field(default_factory=lambda: _empty(INVENTORY_COLUMNS))
+    bomb_events: pd.DataFrame = field(default_factory=lambda: _empty(BOMB_COLUMNS))
+    round_start_state: pd.DataFrame = field(default_factory=lambda: _empty(ROUND_START_COLUMNS))
+    blinds: pd.DataFrame = field(default_factory=lambda: _empty(BLIND_COLUMNS))
+    grenade_events: pd.DataFrame = field(default_factory=lambda: _empty(GRENADE_EVENT_COLUMNS))
+    player_ticks: pd.DataFrame = field(default_factory=lambda: _empty(PLAYER_TICK_COLUMNS))
+
+    @property
+    def map_name(self) -> Optional[str]:
+        return self.header.get("map_name")
+
+    @property
+    def tick_rate(self) -> float:
+        """Ticks per second from the header, :data:`DEMO_TICK_RATE` when the header has none."""
+
+        return float(self.header.get("tick_rate") or DEMO_TICK_RATE)
+
+
+@dataclass
+class ReplayFrames:
+    """Player states at sampled ticks plus every shot, kill and grenade of one tick window.
+
+    Grenade rows are throws (``event="thrown"``, at the thrower's position)
+    and the detonations and expiries of :data:`GRENADE_EVENT_COLUMNS`.
+    """
+
+    positions: pd.DataFrame = field(default_factory=lambda: _empty(REPLAY_POSITION_COLUMNS))
+    shots: pd.DataFrame = field(default_factory=lambda: _empty(REPLAY_SHOT_COLUMNS))
+    kills: pd.DataFrame = field(default_factory=lambda: _empty(REPLAY_KILL_COLUMNS))
+    grenades: pd.DataFrame = field(default_factory=lambda: _empty(REPLAY_GRENADE_COLUMNS))
+
+
+class DemoParser(Protocol):
+    """Anything able to turn a raw demo file into a :class:`ParsedDemo`."""
+
+    name: str
+
+    def parse(self, path: Path) -> ParsedDemo:
+        ...
+
+
+class AimParser(Protocol):
+    """A parser that can also read one player's view angles at every tick of a window."""
+
+    def parse_aim(self, path: Path, steamid: str, start_tick: int, end_tick: int) -> AimFrames:
+        ...
+
+
+class ReplayParser(Protocol):
+    """A parser that can read every player's state at sampled ticks of a window, for 2D replays."""
+
+    def parse_replay(self, path: Path, start_tick: int, end_tick: int, every: int) -> ReplayFrames:
+        ...
+
+
+class NetMessageParser(Protocol):
+    """A parser that can dump selected net messages of a demo, for debugging extraction after game updates."""
+
+    def dump_netmsgs(self, path: Path, types: Iterable[str]) -> Dict[str, Any]:
+        ...
+
+
+def netmsg_types(spec: str) -> list[str]:
+    """Message types from ``"types=ServerInfo,GameEventList"`` (the ``types=`` is optional).
+
+    Raises ``ValueError`` for names missing from :data:`NETMSG_READERS`.
+    """
+
+    names = [name.strip() for name in spec.removeprefix("types=").split(",") if name.strip()]
+    unknown = [name for name in names if name not in NETMSG_READERS]
+    if unknown or not names:
+        given = ", ".join(unknown) or "none given"
+        raise ValueError(f"Unknown net message types ({given}); choose from {', '.join(NETMSG_READERS)}")
+    return names
+
+
+def replay_ticks(start_tick: int, end_tick: int, every: int) -> list[int]:
+    """Every ``every``-th tick from ``start_tick``, always ending on ``end_tick`` so the last state is shown."""
+
+    ticks = list(range(start_tick, end_tick + 1, max(1, every)))
+    if ticks and ticks[-1] != end_tick:
+        ticks.append(end_tick)
+    return ticks
+
+
+def assign_rounds(frame: pd.DataFrame, rounds: pd.DataFrame) -> pd.DataFrame:
+    """Attach a 1-based ``round`` column to ``frame`` using the round end ticks.
+
+    Rows before the first round's start (warmup) or after the last round end are dropped.
+    """
+
+    frame = frame.copy()
+    if frame.empty or rounds.empty:
+        frame["round"] = pd.Series(dtype="int64")
+        return frame
+
+    ordered = rounds.sort_values("round")
+    end_ticks = ordered["end_tick"].to_numpy()
+    positions = end_ticks.searchsorted(frame["tick"].to_numpy(), side="left")
+    frame["round"] = positions + 1
+    keep = frame["round"] <= len(end_ticks)
+    if "start_tick" in ordered and pd.notna(ordered["start_tick"].iloc[0]):
+        keep &= frame["tick"] >= ordered["start_tick"].iloc[0]
+    return frame[keep].reset_index(drop=True)
+
+
+def game_clock(
+    frame: pd.DataFrame,
+    rounds: pd.DataFrame,
+    tick_rate: float = DEMO_TICK_RATE,
+    warmup_end_tick: Optional[int] = None,
+    round_time: float = ROUND_TIME_SECONDS,
+) -> pd.DataFrame:
+    """Attach ``round_time_remaining``, ``is_freeze_time`` and ``is_warmup`` to a frame with ``tick``.
+
+    Ticks before ``warmup_end_tick`` are warmup and have no round clock.
+    Freeze time is the same span as :attr:`RoundPhase.FREEZETIME`; the clock
+    shows the full ``round_time`` then and counts down from the freeze end,
+    stopping at zero. The bomb timer that replaces it after a plant is not
+    modelled.
+    """
+
+    frame = frame.copy()
+    ticks = pd.to_numeric(frame["tick"])
+    warmup = ticks < warmup_end_tick if warmup_end_tick is not None else pd.Series(False, index=frame.index)
+    if rounds.empty:
+        freeze_ends = pd.Series(float("nan"), index=frame.index)
+    else:
+        ordered = rounds.sort_values("round")
+        positions = ordered["end_tick"].to_numpy().searchsorted(ticks.to_numpy(), side="left")
+        in_round = positions < len(ordered)
+        freeze_end_ticks = ordered["freeze_end_tick"].to_numpy()[positions.clip(max=len(ordered) - 1)]
+        freeze_ends = pd.Series(freeze_end_ticks, index=frame.index, dtype="float64").where(in_round)
+    elapsed = ((ticks - freeze_ends) / tick_rate).clip(lower=0)
+    frame["round_time_remaining"] = (round_time - elapsed).clip(lower=0).round(2).where(~warmup)
+    frame["is_freeze_time"] = ((ticks <= freeze_ends) & ~warmup).astype(bool)
+    frame["is_warmup"] = warmup.astype(bool)
+    return frame
+
+
+def attribute_assists(kills: pd.DataFrame) -> pd.DataFrame:
+    """Split ``player_death`` assists into damage and flash assists.
+
+    CS2 credits at most one assister per kill; the event's ``assistedflash``
+    flag means the assister blinded the victim instead of damaging them.
+    ``assist_type`` is ``damage``, ``flash`` or missing for unassisted kills,
+    and ``flash_assister_steamid`` repeats the assister of flash assists.
+    """
+
+    kills = kills.copy()
+    flashed = kills.pop("assistedflash") if "assistedflash" in kills.columns else pd.Series(False, index=kills.index)
+    flashed = flashed.fillna(False).astype(bool)
+    assisters = kills.get("assister_steamid", pd.Series(None, index=kills.index, dtype=object))
+    assisted = assisters.notna() & (assisters.astype(str) != "")
+    kills["assist_type"] = pd.Series(None, index=kills.index, dtype=object)
+    kills.loc[assisted, "assist_type"] = "damage"
+    kills.loc[assisted & flashed, "assist_type"] = "flash"
+    kills["flash_assister_steamid"] = assisters.where(kills["assist_type"] == "flash")
+    return kills
+
+
+def movement_state(samples: pd.DataFrame, blinds: pd.DataFrame, tick_rate: float = DEMO_TICK_RATE) -> pd.DataFrame:
+    """Complete the movement columns of one player's aim samples.
+
+    Velocity the demo did not provide is derived from position deltas over
+    the elapsed ticks, ``speed`` is the horizontal speed the game shows and
+    ``is_airborne`` falls back to vertical movement. ``is_blinded`` holds for
+    the ``duration`` seconds after each of the player's ``blinds`` (``tick``
+    and ``duration`` columns).
+    """
+
+    samples = samples.copy()
+    if samples.empty:
+        return samples
+    seconds = samples["tick"].diff() / tick_rate
+    for axis in ("x", "y", "z"):
+        column = f"vel_{axis}"
+        velocity = pd.to_numeric(samples[column], errors="coerce")
+        if velocity.isna().all():
+            velocity = pd.to_numeric(samples[axis], errors="coerce").diff() / seconds
+        samples[column] = velocity.round(2)
+    samples["speed"] = (samples["vel_x"] ** 2 + samples["vel_y"] ** 2).pow(0.5).round(2)
+    if samples["is_airborne"].isna().all():
+        samples["is_airborne"] = samples["vel_z"].abs().gt(AIRBORNE_SPEED).where(samples["vel_z"].notna())
+
+    blinded = pd.Series(False, index=samples.index)
+    for blind in blinds.itertuples(index=False):
+        until = blind.tick + float(blind.duration or 0) * tick_rate
+        blinded |= (samples["tick"] >= blind.tick) & (samples["tick"] < until)
+    samples["is_blinded"] = blinded
+    return samples
+
+
+class RoundPhase(str, Enum):
+    """Where in a round a tick falls.
+
+    The string values and :data:`PHASE_CODES` are part of the dataset contract:
+    ``freezetime`` covers everything from the previous round end up to the
+    freeze-time end (round-end delay included), ``live`` the rest of the round.
+    """
+
+    FREEZETIME = "freezetime"
+    LIVE = "live"
+
+
+PHASE_CODES = {RoundPhase.FREEZETIME: 0, RoundPhase.LIVE: 1}
+PHASE_COLUMNS = ("phase", "phase_code", "is_freezetime", "is_live")
+
+
+def assign_phases(frame: pd.DataFrame, rounds: pd.DataFrame) -> pd.DataFrame:
+    """Attach ``phase``, ``phase_code``, ``is_freezetime`` and ``is_live`` to a frame with ``tick`` and ``round``."""
+
+    frame = frame.copy()
+    if frame.empty or rounds.empty:
+        for column, dtype in zip(PHASE_COLUMNS, ("object", "int64", "bool", "bool")):
+            frame[column] = pd.Series(dtype=dtype)
+        return frame
+
+    freeze_ends = frame["round"].map(dict(zip(rounds["round"], rounds["freeze_end_tick"])))
+    frozen = (frame["tick"] <= freeze_ends).fillna(False).astype(bool)
+    frame["phase"] = frozen.map({True: RoundPhase.FREEZETIME.value, False: RoundPhase.LIVE.value})
+    frame["phase_code"] = frozen.map({True: PHASE_CODES[RoundPhase.FREEZETIME], False: PHASE_CODES[RoundPhase.LIVE]})
+    frame["is_freezetime"] = frozen
+    frame["is_live"] = ~frozen
+    return frame
+
+
+class Demoparser2Parser:
+    """Adapter around the optional ``demoparser2`` package."""
+
+    name = "demoparser2"
+
+    def __init__(self, include_warmup: bool = False) -> None:
+        # Warmup never reaches the round tables; this only keeps it in per-tick samples.
+        self.include_warmup = include_warmup
+
+    def parse(self, path: Path) -> ParsedDemo:
+        return self._parse_native(self._open(path))
+
+    def parse_aim(self, path: Path, steamid: str, start_tick: int, end_tick: int) -> AimFrames:
+        """Full-rate view angles and the shots fired by one player between two ticks (inclusive).
+
+        Only the requested ticks are decoded, so a single round of a stored
+        demo is cheap enough to read on demand.
+        """
+
+        return self._parse_aim_native(self._open(path), steamid, start_tick, end_tick)
+
+    def parse_replay(self, path: Path, start_tick: int, end_tick: int, every: int) -> ReplayFrames:
+        """Every player's state every ``every`` ticks between two ticks (inclusive), with the events in between."""
+
+        return self._parse_replay_native(self._open(path), start_tick, end_tick, every)
+
+    def dump_netmsgs(self, path: Path, types: Iterable[str]) -> Dict[str, Any]:
+        """The decoded messages of each type as JSON-ready values; ``{"error": ...}`` for types the backend lacks."""
+
+        native = self._open(path)
+        dump: Dict[str, Any] = {}
+        for name in types:
+            reader = getattr(native, NETMSG_READERS[name], None)
+            if reader is None:
+                dump[name] = {"error": f"{self.name} does not expose {name}"}
+                continue
+            try:
+                dump[name] = _jsonable(reader())
+            except Exception as exc:  # noqa: BLE001 - one broken message type should not hide the others
+                dump[name] = {"error": f"{type(exc).__name__}: {exc}"}
+        return dump
+
+    @staticmethod
+    def _open(path: Path) -> Any:
+        from demoparser2 import DemoParser as NativeParser
+
+        return NativeParser(str(path))
+
+    def _parse_aim_native(self, native: Any, steamid: str, start_tick: int, end_tick: int) -> AimFrames:
+        fields = ["pitch", "yaw", "X", "Y", "Z", "active_weapon_name", "is_alive"]
+        warmup_end = self._warmup_end_tick(native)
+        rounds = self._rounds(native, warmup_end)
+        tick_rate = self._tick_rate(native, dict(native.parse_header()), rounds)
+        if warmup_end is not None and not self.include_warmup:
+            start_tick = max(start_tick, warmup_end)
+        if start_tick > end_tick:
+            return _empty(AIM_SAMPLE_COLUMNS), _empty(AIM_SHOT_COLUMNS)
+        window = list(range(start_tick, end_tick + 1))
+        try:
+            ticks = pd.DataFrame(native.parse_ticks(fields + MOVEMENT_FIELDS + EQUIPMENT_FIELDS, ticks=window))
+        except Exception:  # noqa: BLE001 - backends reject props they do not know; equipment stays empty then
+            ticks = pd.DataFrame(native.parse_ticks(fields, ticks=window))
+        if ticks.empty:
+            samples = _empty(AIM_SAMPLE_COLUMNS)
+        else:
+            ticks = ticks[ticks["steamid"].astype(str) == steamid].sort_values("tick")
+            inventory = ticks.get("inventory", pd.Series(None, index=ticks.index, dtype=object))
+            samples = pd.DataFrame(
+                {
+                    "tick": ticks["tick"],
+                    "pitch": ticks.get("pitch"),
+                    "yaw": ticks.get("yaw"),
+                    "x": ticks.get("X"),
+                    "y": ticks.get("Y"),
+                    "z": ticks.get("Z"),
+                    "weapon": ticks.get("active_weapon_name"),
+                    "is_alive": ticks.get("is_alive"),
+                    "vel_x": ticks.get("velocity_X"),
+                    "vel_y": ticks.get("velocity_Y"),
+                    "vel_z": ticks.get("velocity_Z"),
+                    "is_ducking": ticks.get("ducking"),
+                    "is_walking": ticks.get("is_walking"),
+                    "is_airborne": ticks.get("is_airborne"),
+                    "ammo_clip": ticks.get("active_weapon_ammo"),
+                    "ammo_reserve": ticks.get("total_ammo_left"),
+                    "has_bomb": inventory.map(carries_bomb),
+                    "has_defuser": ticks.get("has_defuser"),
+                    "inventory": inventory.map(inventory_code),
+                },
+                columns=list(AIM_SAMPLE_COLUMNS),
+            ).reset_index(drop=True)
+            blinds = self._event(native, "player_blind", [])
+            if "user_steamid" in blinds:
+                blinds = blinds[blinds["user_steamid"].astype(str) == steamid]
+                blinds = pd.DataFrame({"tick": blinds["tick"], "duration": blinds.get("blind_duration")})
+            else:
+                blinds = pd.DataFrame(columns=["tick", "duration"])
+            samples = movement_state(samples, blinds, tick_rate)
+            samples = game_clock(samples, rounds, tick_rate, warmup_end)[list(AIM_SAMPLE_COLUMNS)]
+
+        fires = self._event(native, "weapon_fire", [])
+        if fires.empty:
+            return samples, _empty(AIM_SHOT_COLUMNS)
+        in_window = fires["tick"].between(start_tick, end_tick) & (fires.get("user_steamid").astype(str) == steamid)
+        fires = fires[in_window].sort_values("tick")
+        shots = pd.DataFrame({"tick": fires["tick"], "weapon": fires.get("weapon")}).reset_index(drop=True)
+        aimed = shots.merge(samples[["tick", "x", "y", "yaw"]], on="tick", how="left")
+        smokes = smoke_intervals(assign_rounds(self._grenade_events(native), rounds), rounds, tick_rate)
+        shots["through_smoke"] = lines_through_smoke(shot_lines(aimed), smokes)
+        return samples, shots[list(AIM_SHOT_COLUMNS)]
+
+    def _parse_replay_native(self, native: Any, start_tick: int, end_tick: int, every: int) -> ReplayFrames:
+        if start_tick > end_tick:
+            return ReplayFrames()
+        fields = ["X", "Y", "Z", "yaw", "pitch", "team_num", "is_alive", "health", "active_weapon_name"]
+        ticks = pd.DataFrame(native.parse_ticks(fields, ticks=replay_ticks(start_tick, end_tick, every)))
+        if ticks.empty:
+            positions = _empty(REPLAY_POSITION_COLUMNS)
+        else:
+            positions = pd.DataFrame(
+                {
+                    "tick": ticks["tick"],
+                    "steamid": ticks.get("steamid"),
+                    "name": ticks.get("name"),
+                    "side": ticks.get("team_num", pd.Series(dtype=object)).map(normalise_side),
+                    "is_alive": ticks.get("is_alive"),
+                    "health": ticks.get("health"),
+                    "x": ticks.get("X"),
+                    "y": ticks.get("Y"),
+                    "z": ticks.get("Z"),
+                    "yaw": ticks.get("yaw"),
+                    "pitch": ticks.get("pitch"),
+                    "weapon": ticks.get("active_weapon_name"),
+                },
+                columns=list(REPLAY_POSITION_COLUMNS),
+            )
+            positions = positions.sort_values(["tick", "steamid"]).reset_index(drop=True)
+
+        fires = _between(self._event(native, "weapon_fire", ["X", "Y", "Z"]), start_tick, end_tick)
+        grenade = fires.get("weapon", pd.Series(dtype=object)).map(grenade_kind)
+        shots = pd.DataFrame(
+            {"tick": fires.get("tick"), "steamid": fires.get("user_steamid"), "weapon": fires.get("weapon")},
+            columns=list(REPLAY_SHOT_COLUMNS),
+        )[grenade.isna()]
+        throws = pd.DataFrame(
+            {
+                "tick": fires.get("tick"),
+                "steamid": fires.get("user_steamid"),
+                "grenade": grenade,
+                "event": "thrown",
+                "x": fires.get("user_X"),
+                "y": fires.get("user_Y"),
+                "z": fires.get("user_Z"),
+            },
+            columns=list(REPLAY_GRENADE_COLUMNS),
+        )[grenade.notna()]
+        detonations = _between(self._grenade_events(native), start_tick, end_tick)
+        grenades = pd.concat([throws, detonations[list(REPLAY_GRENADE_COLUMNS)]], ignore_index=True)
+
+        deaths = _between(self._event(native, "player_death", []), start_tick, end_tick)
+        kills = pd.DataFrame(
+            {
+                "tick": deaths.get("tick"),
+                "attacker_steamid": deaths.get("attacker_steamid"),
+                "victim_steamid": deaths.get("user_steamid"),
+                "assister_steamid": deaths.get("assister_steamid"),
+                "weapon": deaths.get("weapon"),
+                "headshot": deaths.get("headshot"),
+            },
+            columns=list(REPLAY_KILL_COLUMNS),
+        )
+        return ReplayFrames(
+            positions=positions,
+            shots=shots.sort_values("tick").reset_index(drop=True),
+            kills=kills.sort_values("tick").reset_index(drop=True),
+            grenades=grenades.sort_values("tick", kind="stable").reset_index(drop=True),
+        )
+
+    def _parse_native(self, native: Any) -> ParsedDemo:
+        header = dict(native.parse_header())
+        warmup_end = self._warmup_end_tick(native)
+        rounds = self._rounds(native, warmup_end)
+        header["tick_rate"] = self._tick_rate(native, header, rounds)
+        header["warmup_end_tick"] = warmup_end
+
+        kills = self._event(native, "player_death", ["team_num", "last_place_name", "X", "Y", "Z"])
+        kills = pd.DataFrame(
+            {
+                "tick": kills.get("tick"),
+                "attacker_steamid": kills.get("attacker_steamid"),
+                "attacker_name": kills.get("attacker_name"),
+                "attacker_side": kills.get("attacker_team_num", pd.Series(dtype=object)).map(normalise_side),
+                "victim_steamid": kills.get("user_steamid"),
+                "victim_name": kills.get("user_name"),
+                "victim_side": kills.get("user_team_num", pd.Series(dtype=object)).map(normalise_side),
+                "weapon": kills.get("weapon"),
+                "headshot": kills.get("headshot"),
+                "assister_steamid": kills.get("assister_steamid"),
+                "assistedflash": kills.get("assistedflash"),
+                "attacker_place": kills.get("attacker_last_place_name"),
+                "victim_place": kills.get("user_last_place_name"),
+                "attacker_x": kills.get("attacker_X"),
+                "attacker_y": kills.get("attacker_Y"),
+                "attacker_z": kills.get("attacker_Z"),
+                "victim_x": kills.get("user_X"),
+                "victim_y": kills.get("user_Y"),
+                "victim_z": kills.get("user_Z"),
+            }
+        )
+        kills = attribute_assists(kills)
+
+        damages = self._event(native, "player_hurt", ["team_num"])
+        damages = pd.DataFrame(
+            {
+                "tick": damages.get("tick"),
+                "attacker_steamid": damages.get("attacker_steamid"),
+                "attacker_side": damages.get("attacker_team_num", pd.Series(dtype=object)).map(normalise_side),
+                "victim_steamid": damages.get("user_steamid"),
+                "victim_side": damages.get("user_team_num", pd.Series(dtype=object)).map(normalise_side),
+                "weapon": damages.get("weapon"),
+                "damage": damages.get("dmg_health"),
+            }
+        )
+
+        fires = self._event(native, "weapon_fire", ["team_num"])
+        throws = pd.DataFrame(
+            {
+                "tick": fires.get("tick"),
+                "steamid": fires.get("user_steamid"),
+                "name": fires.get("user_name"),
+                "side": fires.get("user_team_num", pd.Series(dtype=object)).map(normalise_side),
+                "grenade": fires.get("weapon", pd.Series(dtype=object)).map(grenade_kind),
+            }
+        )
+        throws = throws[throws["grenade"].notna()]
+
+        inventory = self._round_end_inventory(native, rounds)
+        rounds = self._attach_team_names(rounds, inventory)
+        bomb_events = self._bomb_events(native)
+
+        return ParsedDemo(
+            header=header,
+            rounds=rounds,
+            kills=assign_rounds(kills, rounds),
+            damages=assign_rounds(damages, rounds),
+            grenade_throws=assign_rounds(throws, rounds),
+            round_end_inventory=inventory,
+            bomb_events=assign_rounds(bomb_events, rounds),
+            round_start_state=self._round_start_state(native, rounds),
+            blinds=assign_rounds(self._blinds(native), rounds),
+            grenade_events=assign_rounds(self._grenade_events(native), rounds),
+            player_ticks=assign_rounds(self._player_ticks(native, rounds), rounds),
+        )
+
+    @staticmethod
+    def _event(native: Any, name: str, player_fields: list[str]) -> pd.DataFrame:
+        frame = native.parse_event(name, player=player_fields)
+        if frame is None or len(frame) == 0:
+            return pd.DataFrame(columns=["tick"])
+        return pd.DataFrame(frame)
+
+    def _warmup_end_tick(self, native: Any) -> Optional[int]:
+        """Tick the match went live (the last ``round_announce_match_start``); ``None`` without warmup."""
+
+        starts = self._event(native, "round_announce_match_start", [])
+        return None if starts.empty else int(starts["tick"].max())
+
+    @staticmethod
+    def _tick_rate(native: Any, header: Dict[str, Any], rounds: pd.DataFrame) -> float:
+        """Ticks per second from the ServerInfo tick interval, else measured against the game clock."""
+
+        interval = header.get("tick_interval")
+        if interval:
+            return round(1 / float(interval), 2)
+        if len(rounds) < 2:
+            return DEMO_TICK_RATE
+        sample_ticks = [int(rounds["end_tick"].min()), int(rounds["end_tick"].max())]
+        try:
+            clock = pd.DataFrame(native.parse_ticks(["game_time"], ticks=sample_ticks))
+            times = clock.groupby("tick")["game_time"].first()
+        except Exception:  # noqa: BLE001 - backends without a game clock keep the default
+            return DEMO_TICK_RATE
+        if len(times) < 2 or times.iloc[-1] <= times.iloc[0]:
+            return DEMO_TICK_RATE
+        return float(round((times.index[-1] - times.index[0]) / (times.iloc[-1] - times.iloc[0])))
+
+    def _rounds(self, native: Any, warmup_end: Optional[int] = None) -> pd.DataFrame:
+        ends = self._event(native, "round_end", [])
+        if warmup_end is not None:
+            ends = ends[ends["tick"] > warmup_end]  # the warmup "round" ends when the match starts
+        if ends.empty:
+            return _empty(ROUND_COLUMNS)
+        ends = ends.sort_values("tick").reset_index(drop=True)
+        freeze_ends = self._event(native, "round_freeze_end", [])["tick"].sort_values().to_numpy()
+
+        rows = []
+        previous_end = warmup_end or 0
+        for index, end in ends.iterrows():
+            end_tick = int(end["tick"])
+            freeze = [tick for tick in freeze_ends if previous_end < tick <= end_tick]
+            rows.append(
+                {
+                    "round": index + 1,
+                    "start_tick": previous_end,
+                    "freeze_end_tick": int(freeze[0]) if freeze else previous_end,
+                    "end_tick": end_tick,
+                    "winner": normalise_side(end.get("winner")),
+                    "reason": end.get("reason"),
+                    "ct_team": None,
+                    "t_team": None,
+                }
+            )
+            previous_end = end_tick
+        return pd.DataFrame(rows, columns=list(ROUND_COLUMNS))
+
+    @staticmethod
+    def _round_end_inventory(native: Any, rounds: pd.DataFrame) -> pd.DataFrame:
+        if rounds.empty:
+            return _empty(INVENTORY_COLUMNS)
+        # Sample one tick before the round end so dead players are still reported.
+        sample_ticks = [max(int(tick) - 1, 0) for tick in rounds["end_tick"]]
+        ticks = pd.DataFrame(native.parse_ticks(["inventory", "team_num", "team_clan_name", "is_alive"], ticks=sample_ticks))
+        if ticks.empty:
+            return _empty(INVENTORY_COLUMNS)
+        round_by_tick = dict(zip(sample_ticks, rounds["round"]))
+        return pd.DataFrame(
+            {
+                "round": ticks["tick"].map(round_by_tick),
+                "steamid": ticks.get("steamid"),
+                "name": ticks.get("name"),
+                "side": ticks.get("team_num", pd.Series(dtype=object)).map(normalise_side),
+                "team_name": ticks.get("team_clan_name"),
+                "is_alive": ticks.get("is_alive"),
+                "inventory": ticks.get("inventory"),
+            }
+        )
+
+    @staticmethod
+    def _round_start_state(native: Any, rounds: pd.DataFrame) -> pd.DataFrame:
+        if rounds.empty:
+            return _empty(ROUND_START_COLUMNS)
+        sample_ticks = [int(tick) for tick in rounds["freeze_end_tick"]]
+        fields = [
+            "inventory",
+            "team_num",
+            "team_clan_name",
+            "armor_value",
+            "has_helmet",
+            "has_defuser",
+            "balance",
+            "X",
+            "Y",
+            "Z",
+        ]
+        ticks = pd.DataFrame(native.parse_ticks(fields, ticks=sample_ticks))
+        if ticks.empty:
+            return _empty(ROUND_START_COLUMNS)
+        round_by_tick = dict(zip(sample_ticks, rounds["round"]))
+        return pd.DataFrame(
+            {
+                "round": ticks["tick"].map(round_by_tick),
+                "steamid": ticks.get("steamid"),
+                "name": ticks.get("name"),
+                "side": ticks.get("team_num", pd.Series(dtype=object)).map(normalise_side),
+                "team_name": ticks.get("team_clan_name"),
+                "inventory": ticks.get("inventory"),
+                "armor": ticks.get("armor_value"),
+                "has_helmet": ticks.get("has_helmet"),
+                "has_defuser": ticks.get("has_defuser"),
+                "balance": ticks.get("balance"),
+                "x": ticks.get("X"),
+                "y": ticks.get("Y"),
+                "z": ticks.get("Z"),
+            }
+        )
+
+    @staticmethod
+    def _player_ticks(native: Any, rounds: pd.DataFrame) -> pd.DataFrame:
+        if rounds.empty:
+            return _empty(PLAYER_TICK_COLUMNS)
+        sample_ticks = sorted(
+            {
+                tick
+                for start, end in zip(rounds["start_tick"], rounds["end_tick"])
+                for tick in range(int(start), int(end) + 1, POSITION_SAMPLE_TICKS)
+            }
+        )
+        ticks = pd.DataFrame(native.parse_ticks(["X", "Y", "Z", "team_num", "is_alive"], ticks=sample_ticks))
+        if ticks.empty:
+            return _empty(PLAYER_TICK_COLUMNS)
+        return pd.DataFrame(
+            {
+                "tick": ticks["tick"],
+                "steamid": ticks.get("steamid"),
+                "name": ticks.get("name"),
+                "side": ticks.get("team_num", pd.Series(dtype=object)).map(normalise_side),
+                "is_alive": ticks.get("is_alive"),
+                "x": ticks.get("X"),
+                "y": ticks.get("Y"),
+                "z": ticks.get("Z"),
+            }
+        )
+
+    @staticmethod
+    def _attach_team_names(rounds: pd.DataFrame, inventory: pd.DataFrame) -> pd.DataFrame:
+        if rounds.empty or inventory.empty:
+            return rounds
+        named = inventory.dropna(subset=["side", "team_name"])
+        named = named[named["team_name"].astype(str).str.len() > 0]
+        if named.empty:
+            return rounds
+        names = named.groupby(["round", "side"])["team_name"].agg(lambda values: values.mode().iloc[0]).unstack("side")
+        rounds = rounds.copy()
+        for side, column in (("CT", "ct_team"), ("T", "t_team")):
+            if side in names:
+                rounds[column] = rounds["round"].map(names[side])
+        return rounds
+
+    def _blinds(self, native: Any) -> pd.DataFrame:
+        blinds = self._event(native, "player_blind", ["team_num"])
+        if blinds.empty:
+            return _empty(BLIND_COLUMNS)
+        return pd.DataFrame(
+            {
+                "tick": blinds["tick"],
+                "attacker_steamid": blinds.get("attacker_steamid"),
+                "attacker_side": blinds.get("attacker_team_num", pd.Series(dtype=object)).map(normalise_side),
+                "victim_steamid": blinds.get("user_steamid"),
+                "victim_side": blinds.get("user_team_num", pd.Series(dtype=object)).map(normalise_side),
+                "duration": blinds.get("blind_duration"),
+            }
+        )
+
+    def _grenade_events(self, native: Any) -> pd.DataFrame:
+        frames = []
+        for event_name, grenade, label in _GRENADE_EVENTS:
+            events = self._event(native, event_name, ["team_num"])
+            if events.empty:
+                continue
+            frames.append(
+                pd.DataFrame(
+                    {
+                        "tick": events["tick"],
+                        "steamid": events.get("user_steamid"),
+                        "side": events.get("user_team_num", pd.Series(dtype=object)).map(normalise_side),
+                        "grenade": grenade,
+                        "event": label,
+                        "entity_id": events.get("entityid"),
+                        "x": events.get("x"),
+                        "y": events.get("y"),
+                        "z": events.get("z"),
+                    }
+                )
+            )
+        if not frames:
+            return _empty(GRENADE_EVENT_COLUMNS)
+        return pd.concat(frames, ignore_index=True).sort_values("tick").reset_index(drop=True)
+
+    def _bomb_events(self, native: Any) -> pd.DataFrame:
+        frames = []
+        for event_name, label in (("bomb_planted", "planted"), ("bomb_defused", "defused"), ("bomb_exploded", "exploded")):
+            events = self._event(native, event_name, ["last_place_name"])
+            if events.empty:
+                continue
+            frames.append(
+                pd.DataFrame(
+                    {
+                        "tick": events["tick"],
+                        "event": label,
+                        "steamid": events.get("user_steamid"),
+                        "site": events.get("user_last_place_name", pd.Series(dtype=object)).map(bombsite_label),
+                    }
+                )
+            )
+        if not frames:
+            return _empty(BOMB_COLUMNS)
+        return pd.concat(frames, ignore_index=True).sort_values("tick").reset_index(drop=True)
+
+
+def _between(frame: pd.DataFrame, start_tick: int, end_tick: int) -> pd.DataFrame:
+    return frame[frame["tick"].between(start_tick, end_tick)]
+
+
+# (event name, grenade kind, lifecycle label) for projectile events that carry an entity id.
+_GRENADE_EVENTS = (
+    ("smokegrenade_detonate", "smoke", "detonate"),
+    ("smokegrenade_expired", "smoke", "expired"),
+    ("flashbang_detonate", "flash", "detonate"),
+    ("hegrenade_detonate", "he", "detonate"),
+    ("inferno_startburn", "molotov", "detonate"),
+    ("inferno_expire", "molotov", "expired"),
+)
+
+
+CS2_MAGIC = b"PBDEMS2\0"
+CSGO_MAGIC = b"HL2DEMO\0"
+
+
+def _jsonable(value: Any) -> Any:
+    """Frames become lists of records and other containers are converted recursively; the rest is left to ``str``."""
+
+    if isinstance(value, pd.DataFrame):
+        return _jsonable(value.astype(object).where(value.notna(), None).to_dict(orient="records"))
+    if isinstance(value, dict):
+        return {str(key): _jsonable(item) for key, item in value.items()}
+    if isinstance(value, (list, tuple, set)):
+        return [_jsonable(item) for item in value]
+    if hasattr(value, "tolist"):
+        return _jsonable(value.tolist())  # numpy scalars and arrays
+    if value is None or isinstance(value, (str, int, float, bool)):
+        return value
+    return str(value)
+
+
+def detect_demo_format(path: Path) -> Optional[str]:
+    """Return ``"cs2"`` or ``"csgo"`` from the demo's header magic, or ``None`` if unrecognised."""
+
+    with Path(path).open("rb") as handle:
+        magic = handle.read(len(CS2_MAGIC))
+    if magic == CS2_MAGIC:
+        return "cs2"
+    if magic == CSGO_MAGIC:
+        return "csgo"
+    return None
+
+
+class _LegacyNative:
+    """Expose the CS:GO ``demoparser`` package through the demoparser2 calls used above."""
+
+    # demoparser2 player/tick field -> CS:GO network property
+    _PROPS = {
+        "team_num": "m_iTeamNum",
+        "team_clan_name": "m_szClan",
+        "armor_value": "m_ArmorValue",
+        "has_helmet": "m_bHasHelmet",
+        "has_defuser": "m_bHasDefuser",
+        "balance": "m_iAccount",
+        "X": "X",
+        "Y": "Y",
+        "Z": "Z",
+        "pitch": "m_angEyeAngles[0]",
+        "yaw": "m_angEyeAngles[1]",
+        "velocity_X": "m_vecVelocity[0]",
+        "velocity_Y": "m_vecVelocity[1]",
+        "velocity_Z": "m_vecVelocity[2]",
+        "ducking": "m_bDucking",
+        "is_walking": "m_bIsWalking",
+    }
+
+    def __init__(self, path: Path) -> None:
+        from demoparser import DemoParser as LegacyParser
+
+        self._native = LegacyParser(str(path))
+
+    def parse_header(self) -> Dict[str, Any]:
+        header = dict(self._native.parse_header())
+        # CS:GO headers carry the recording length instead of ServerInfo's tick interval.
+        ticks, seconds = header.get("playback_ticks"), header.get("playback_time")
+        if ticks and seconds and "tick_interval" not in header:
+            header["tick_interval"] = float(seconds) / float(ticks)
+        return header
+
+    def parse_event(self, name: str, player: Optional[list[str]] = None) -> pd.DataFrame:
+        props = [self._PROPS.get(field, field) for field in player or []]
+        frame = pd.DataFrame(self._native.parse_events(name, props=props) if props else self._native.parse_events(name))
+        renames = {
+            f"{prefix}{self._PROPS.get(field, field)}": f"{prefix}{field}"
+            for field in player or []
+            for prefix in ("attacker_", "user_")
+        }
+        return frame.rename(columns=renames)
+
+    def parse_ticks(self, fields: list[str], ticks: Optional[list[int]] = None) -> pd.DataFrame:
+        props = [self._PROPS.get(field, field) for field in fields]
+        frame = pd.DataFrame(self._native.parse_ticks(props, ticks=ticks))
+        return frame.rename(columns={self._PROPS.get(field, field): field for field in fields})
+
+
+class LegacyCsgoParser(Demoparser2Parser):
+    """CS:GO (``HL2DEMO``) demos through the ``demoparser`` package, producing the same tables.
+
+    CS:GO lacks a few CS2 events and properties (e.g. inventory lists); those
+    columns come out empty rather than failing the parse.
+    """
+
+    name = "demoparser-csgo"
+
+    @staticmethod
+    def _open(path: Path) -> Any:
+        return _LegacyNative(path)
+
+
+class ProtocolRoutingParser:
+    """Pick a backend per demo from its header, so CS:GO and CS2 share one upload path."""
+
+    name = "auto"
+
+    def __init__(self, backends: Dict[str, DemoParser]) -> None:
+        self.backends = backends
+
+    def parse(self, path: Path) -> ParsedDemo:
+        demo_format = detect_demo_format(path)
+        if demo_format is None:
+            raise ValueError("Unrecognised demo header; expected a CS2 or CS:GO .dem file")
+        backend = self.backends.get(demo_format)
+        if backend is None:
+            raise ValueError(f"No parser installed for {demo_format} demos")
+        parsed = backend.parse(path)
+        parsed.header.setdefault("demo_format", demo_format)
+        parsed.header.setdefault("parser", backend.name)
+        return parsed
+
+    def parse_aim(self, path: Path, steamid: str, start_tick: int, end_tick: int) -> AimFrames:
+        backend = self.backends.get(detect_demo_format(path) or "")
+        if not hasattr(backend, "parse_aim"):
+            raise ValueError("No installed parser can read view angles from this demo")
+        return backend.parse_aim(path, steamid, start_tick, end_tick)
+
+    def parse_replay(self, path: Path, start_tick: int, end_tick: int, every: int) -> ReplayFrames:
+        backend = self.backends.get(detect_demo_format(path) or "")
+        if not hasattr(backend, "parse_replay"):
+            raise ValueError("No installed parser can read replays from this demo")
+        return backend.parse_replay(path, start_tick, end_tick, every)
+
+    def dump_netmsgs(self, path: Path, types: Iterable[str]) -> Dict[str, Any]:
+        backend = self.backends.get(detect_demo_format(path) or "")
+        if not hasattr(backend, "dump_netmsgs"):
+            raise ValueError("No installed parser can dump net messages from this demo")
+        return backend.dump_netmsgs(path, types)
+
+
+def load_default_parser() -> Optional[DemoParser]:
+    """Return a parser for every installed backend, or ``None`` when none is installed."""
+
+    backends: Dict[str, DemoParser] = {}
+    try:
+        import demoparser2  # noqa: F401
+    except ImportError:
+        pass
+    else:
+        backends["cs2"] = Demoparser2Parser()
+    try:
+        import demoparser  # noqa: F401
+    except ImportError:
+        pass
+    else:
+        backends["csgo"] = LegacyCsgoParser()
+    return ProtocolRoutingParser(backends) if backends else None
@@ -0,0 +1,84 @@
+"""The parse path shared by the upload pipeline and ``stratagemforge-admin parse``.
+
+Both build the processor from the same settings (dataset options and the
+parquet codec), unpack gzip, bz2 and zip demos the same way and fill in the
+map and the match start from the file name when the demo header lacks them,
+so a demo parsed locally yields the same datasets as one that is uploaded.
+"""
+
+from __future__ import annotations
+
+from datetime import datetime
+from pathlib import Path
+from typing import FrozenSet, Optional, Tuple
+from uuid import uuid4
+
+from ...core.config import Settings
+from ...core.parquet import ParquetOptions
+from ...core.timeutil import resolve_timezone
+from .archives import extract_demo
+from .extractors.match import map_from_filename, start_time_from_filename
+from .parser import DemoParser
+from .processor import DemoProcessingInput, DemoProcessor
+
+CHUNK_SIZE = 4 * 1024 * 1024
+
+
+def build_processor(
+    settings: Settings, processed_dir: Optional[Path] = None, parser: Optional[DemoParser] = None
+) -> DemoProcessor:
+    """The processor uploads go through, writing to ``processed_dir`` (the processed data path by default)."""
+
+    return DemoProcessor(
+        processed_dir or settings.processed_data_path,
+        parser=parser,
+        radar_columns=settings.dataset_radar_columns,
+        csv_export=settings.dataset_csv_export,
+        parquet_options=ParquetOptions.from_settings(settings),
+    )
+
+
+def match_start_from_filename(settings: Settings, filename: str) -> Optional[datetime]:
+    return start_time_from_filename(filename, resolve_timezone(settings.demo_filename_timezone))
+
+
+def build_processing_input(
+    settings: Settings,
+    *,
+    demo_id: str,
+    original_filename: str,
+    checksum: str,
+    size_bytes: int,
+    uploaded_at: datetime,
+    raw_path: Path,
+    skip_datasets: FrozenSet[str] = frozenset(),
+    map_name: Optional[str] = None,
+) -> DemoProcessingInput:
+    """What the processor needs to know about a demo; the map defaults to the one the file name mentions."""
+
+    return DemoProcessingInput(
+        demo_id=demo_id,
+        original_filename=original_filename,
+        checksum=checksum,
+        size_bytes=size_bytes,
+        uploaded_at=uploaded_at,
+        raw_path=raw_path,
+        skip_datasets=skip_datasets,
+        match_start=match_start_from_filename(settings, original_filename),
+        map_name=map_name or map_from_filename(original_filename),
+    )
+
+
+def unpack_demo(source: Path, directory: Path, max_size: int, chunk_size: int = CHUNK_SIZE) -> Tuple[str, Path, int]:
+    """Decompress the gzip, bz2 or zip demo ``source`` into a new file in ``directory``.
+
+    Returns the demo's checksum, path and size; ``source`` is left alone.
+    """
+
+    demo_path = directory / f"{uuid4().hex}.tmp"
+    try:
+        checksum, total_size = extract_demo(source, demo_path, max_size, chunk_size=chunk_size)
+    except Exception:
+        demo_path.unlink(missing_ok=True)
+        raise
+    return checksum, demo_path, total_size
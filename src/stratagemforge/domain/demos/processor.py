@@ -1,12 +1,43 @@
 from __future__ import annotations
 
-from dataclasses import dataclass
+import logging
+import time
+from dataclasses import dataclass, field
 from datetime import datetime
 from pathlib import Path
-from typing import Dict, Any
+from typing import Dict, Any, FrozenSet, List, Optional
 
 import pandas as pd
 
+from ...core import metrics
+from ...core.parquet import DEFAULT_PARQUET_OPTIONS, ParquetOptions, row_group_ranges, write_parquet
+from ...core.timeutil import as_utc, isoformat_utc
+from ...core.tracing import set_attributes, span
+from .bounds import clear_position_outliers
+from .csv_export import CSV_SUFFIX, write_csv_gz
+from .extractors import DATASET_BUILDERS, DATASET_ROW_GROUP_KEYS, DATASET_SCHEMA_VERSIONS
+from .extractors.match import build_match_info
+from .parser import DemoParser, ParsedDemo, load_default_parser
+from .radar import add_radar_columns
+from .recovery import is_truncation_error, recover_truncated
+
+logger = logging.getLogger(__name__)
+
+# Version of the per-demo summary parquet layout. Version 2 dropped the
+# processing wall-clock columns in favour of demo-derived time.
+SUMMARY_SCHEMA_VERSION = 2
+# Kept in the demo's database metadata but not written to the summary parquet.
+SUMMARY_METADATA_ONLY = (
+    "uploaded_at",
+    "processed_at",
+    "raw_path",
+    "schema_versions",
+    "data_quality",
+    "map_name_source",
+)
+# Position columns (by prefix) of the datasets that get radar coordinates with ``radar_columns``.
+RADAR_DATASETS = {"player_ticks": ("",), "kills": ("attacker_", "victim_")}
+
 
 @dataclass
 class DemoProcessingInput:
@@ -16,6 +47,11 @@ class DemoProcessingInput:
     size_bytes: int
     uploaded_at: datetime
     raw_path: Path
+    skip_datasets: FrozenSet[str] = frozenset()
+    # Wall-clock match start (naive UTC) when the service could recover it.
+    match_start: Optional[datetime] = None
+    # Map named by the uploader or the file name, for demos whose header lacks one.
+    map_name: Optional[str] = None
 
 
 @dataclass
@@ -23,14 +59,41 @@ class DemoProcessingResult:
     parquet_path: Path
     processed_at: datetime
     summary: Dict[str, Any]
+    datasets: Dict[str, Path] = field(default_factory=dict)
+    match: Optional[Dict[str, Any]] = None
+    player_stats: List[Dict[str, Any]] = field(default_factory=list)
+    # Parquet layout version of each written file, ``summary`` included.
+    schema_versions: Dict[str, int] = field(default_factory=dict)
+    # Gzip CSV copies of the datasets, written with ``csv_export``.
+    csv_datasets: Dict[str, Path] = field(default_factory=dict)
+    # Row group ranges of the datasets in ``DATASET_ROW_GROUP_KEYS``, see ``row_group_ranges``.
+    row_groups: Dict[str, List[Dict[str, Any]]] = field(default_factory=dict)
 
 
 class DemoProcessor:
-    """Convert uploaded demo files into parquet summaries for analysis."""
+    """Convert uploaded demo files into parquet summaries for analysis.
+
+    With ``radar_columns`` the position datasets also carry radar coordinates
+    (see :func:`~.radar.add_radar_columns`). The columns are additive, so the
+    datasets keep their schema version. With ``csv_export`` every dataset is
+    also written as ``<name>.csv.gz``. ``parquet_options`` sets the codec and
+    row group and page sizes of every file.
+    """
 
-    def __init__(self, processed_dir: Path) -> None:
+    def __init__(
+        self,
+        processed_dir: Path,
+        parser: Optional[DemoParser] = None,
+        radar_columns: bool = False,
+        csv_export: bool = False,
+        parquet_options: ParquetOptions = DEFAULT_PARQUET_OPTIONS,
+    ) -> None:
         self.processed_dir = processed_dir
         self.processed_dir.mkdir(parents=True, exist_ok=True)
+        self.parser = parser if parser is not None else load_default_parser()
+        self.radar_columns = radar_columns
+        self.csv_export = csv_export
+        self.parquet_options = parquet_options
 
     def process(self, payload: DemoProcessingInput) -> DemoProcessingResult:
         """Produce a minimal parquet dataset describing the uploaded demo."""
@@ -44,12 +107,188 @@ class DemoProcessor:
             "original_filename": payload.original_filename,
             "checksum": payload.checksum,
             "size_bytes": payload.size_bytes,
-            "uploaded_at": payload.uploaded_at.isoformat(),
-            "processed_at": processed_at.isoformat(),
+            "uploaded_at": isoformat_utc(payload.uploaded_at),
+            "processed_at": isoformat_utc(processed_at),
             "raw_path": str(payload.raw_path),
+            "schema_version": SUMMARY_SCHEMA_VERSION,
+            "match_start_epoch": int(as_utc(payload.match_start).timestamp()) if payload.match_start else None,
         }
 
-        df = pd.DataFrame([summary])
-        df.to_parquet(parquet_path, index=False)
+        datasets: Dict[str, Path] = {}
+        csv_datasets: Dict[str, Path] = {}
+        row_groups: Dict[str, List[Dict[str, Any]]] = {}
+        match: Optional[Dict[str, Any]] = None
+        player_stats: List[Dict[str, Any]] = []
+        with span("demo.parse", demo_id=payload.demo_id, parser=getattr(self.parser, "name", None)):
+            parsed = self._parse(payload, summary)
+            set_attributes(parse_status=summary["parse_status"], demo_format=summary.get("demo_format"))
+        if parsed is not None:
+            summary["map_name_source"] = _resolve_map_name(parsed, payload.map_name)
+            summary["map_name"] = parsed.map_name
+            summary["rounds"] = int(len(parsed.rounds))
+            summary["duration_seconds"] = _duration_seconds(parsed)
+            cleared = clear_position_outliers(parsed)
+            summary["data_quality"] = {
+                "position_outliers": sum(cleared.values()),
+                "position_outliers_by_table": cleared,
+            }
+            if cleared:
+                logger.info("Cleared implausible positions of demo %s: %s", payload.demo_id, cleared)
+            with span("demo.extract", demo_id=payload.demo_id):
+                frames = {
+                    name: builder(parsed)
+                    for name, builder in DATASET_BUILDERS.items()
+                    if name not in payload.skip_datasets
+                }
+                if self.radar_columns:
+                    for name, prefixes in RADAR_DATASETS.items():
+                        if name in frames:
+                            frames[name] = add_radar_columns(frames[name], parsed.map_name, prefixes)
+                match = build_match_info(parsed)
+            with span("demo.write_parquet", demo_id=payload.demo_id, datasets=len(frames)):
+                datasets = self._write_datasets(payload.demo_id, frames)
+            row_groups = {
+                name: row_group_ranges(datasets[name], key)
+                for name, key in DATASET_ROW_GROUP_KEYS.items()
+                if name in datasets
+            }
+            if self.csv_export:
+                with span("demo.write_csv", demo_id=payload.demo_id, datasets=len(datasets)):
+                    csv_datasets = {
+                        name: write_csv_gz(path, path.with_name(f"{name}{CSV_SUFFIX}"))
+                        for name, path in datasets.items()
+                    }
+            player_stats = frame_records(frames["stats"])
+
+        schema_versions = {"summary": SUMMARY_SCHEMA_VERSION}
+        schema_versions.update((name, DATASET_SCHEMA_VERSIONS[name]) for name in datasets)
+        summary["schema_versions"] = schema_versions
+        df = pd.DataFrame([{key: value for key, value in summary.items() if key not in SUMMARY_METADATA_ONLY}])
+        write_parquet(df, parquet_path, "summary", SUMMARY_SCHEMA_VERSION, options=self.parquet_options)
+        metrics.PARQUET_BYTES_WRITTEN.inc(parquet_path.stat().st_size)
+        metrics.DEMOS_PROCESSED.labels(status=summary["parse_status"]).inc()
+
+        return DemoProcessingResult(
+            parquet_path=parquet_path,
+            processed_at=processed_at,
+            summary=summary,
+            datasets=datasets,
+            match=match,
+            player_stats=player_stats,
+            schema_versions=schema_versions,
+            csv_datasets=csv_datasets,
+            row_groups=row_groups,
+        )
+
+    def _parse(self, payload: DemoProcessingInput, summary: Dict[str, Any]) -> Optional[ParsedDemo]:
+        if self.parser is None:
+            summary["parse_status"] = "skipped"
+            return None
+
+        started = time.perf_counter()
+        try:
+            parsed = self.parser.parse(payload.raw_path)
+        except Exception as exc:  # parser backends raise a variety of native errors
+            recovered = self._recover(payload, summary, exc) if is_truncation_error(exc) else None
+            if recovered is None:
+                logger.warning("Failed to parse demo %s: %s", payload.demo_id, exc)
+                metrics.PARSE_FAILURES.labels(error_type=type(exc).__name__).inc()
+                summary["parse_status"] = "failed"
+                summary["parse_error"] = str(exc)
+                return None
+            parsed = recovered
+
+        elapsed = time.perf_counter() - started
+        metrics.PARSE_DURATION.observe(elapsed)
+        if not parsed.rounds.empty and elapsed > 0:
+            metrics.PARSE_TICKS_PER_SECOND.observe(float(parsed.rounds["end_tick"].max()) / elapsed)
+        summary["parse_status"] = "parsed"
+        summary["parse_seconds"] = round(elapsed, 3)
+        summary["parser"] = parsed.header.get("parser", self.parser.name)
+        if parsed.header.get("demo_format"):
+            summary["demo_format"] = parsed.header["demo_format"]
+        return parsed
+
+    def _recover(self, payload: DemoProcessingInput, summary: Dict[str, Any], exc: Exception) -> Optional[ParsedDemo]:
+        """Parse what a demo cut off mid-match recorded before it ended.
+
+        Rounds finished by then are kept; the summary is marked ``partial``
+        with the ``last_good_tick`` of the salvaged data.
+        """
+
+        recovered_path = self.processed_dir / f"{payload.demo_id}.recovered.dem"
+        try:
+            recovered = recover_truncated(payload.raw_path, recovered_path)
+            if recovered is None:
+                return None
+            parsed = self.parser.parse(recovered.path)
+        except Exception as retry_exc:  # parser backends raise a variety of native errors
+            logger.warning("Recovering truncated demo %s failed: %s", payload.demo_id, retry_exc)
+            return None
+        finally:
+            recovered_path.unlink(missing_ok=True)
+        logger.info(
+            "Demo %s is truncated; recovered %d frames up to tick %s (%d bytes dropped)",
+            payload.demo_id,
+            recovered.frames,
+            recovered.last_tick,
+            recovered.dropped_bytes,
+        )
+        summary["partial"] = True
+        summary["last_good_tick"] = recovered.last_tick
+        summary["truncation_error"] = str(exc)
+        return parsed
+
+    def _write_datasets(self, demo_id: str, frames: Dict[str, pd.DataFrame]) -> Dict[str, Path]:
+        dataset_dir = self.processed_dir / demo_id
+        dataset_dir.mkdir(parents=True, exist_ok=True)
+
+        written: Dict[str, Path] = {}
+        for name, frame in frames.items():
+            path = dataset_dir / f"{name}.parquet"
+            write_parquet(
+                frame,
+                path,
+                name,
+                DATASET_SCHEMA_VERSIONS[name],
+                DATASET_ROW_GROUP_KEYS.get(name),
+                options=self.parquet_options,
+            )
+            metrics.PARQUET_BYTES_WRITTEN.inc(path.stat().st_size)
+            written[name] = path
+        return written
+
+
+def _duration_seconds(parsed: ParsedDemo) -> Optional[float]:
+    """Demo-relative length in seconds, from the last round end tick."""
+
+    if parsed.rounds.empty:
+        return None
+    return round(float(parsed.rounds["end_tick"].max()) / parsed.tick_rate, 2)
+
+
+def _resolve_map_name(parsed: ParsedDemo, fallback: Optional[str]) -> Optional[str]:
+    """Settle the map before anything reads it; returns where it came from (``header`` or ``upload``).
+
+    Recordings that start mid-stream (e.g. relayed broadcasts) can lack the
+    header's map name, which would leave every dataset without map bounds.
+    """
+
+    if parsed.map_name:
+        return "header"
+    if fallback:
+        parsed.header["map_name"] = fallback
+        return "upload"
+    return None
+
+
+def frame_records(frame: pd.DataFrame) -> List[Dict[str, Any]]:
+    """Convert a frame to plain Python records with ``None`` for missing values."""
+
+    return [{key: _plain(value) for key, value in row.items()} for row in frame.to_dict(orient="records")]
+
 
-        return DemoProcessingResult(parquet_path=parquet_path, processed_at=processed_at, summary=summary)
+def _plain(value: Any) -> Any:
+    if pd.isna(value):
+        return None
+    return value.item() if hasattr(value, "item") else value  # numpy scalars
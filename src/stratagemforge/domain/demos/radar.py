@@ -0,0 +1,104 @@
+"""Radar overview calibration of the standard maps.
+
+Every overview image is ``RADAR_SIZE`` pixels square. Its top-left corner
+sits at world (``pos_x``, ``pos_y``) and one pixel covers ``scale`` world
+units, as in the game's ``resource/overviews/<map>.txt``. Maps with two
+floors (Nuke, Vertigo) have a second ``lower`` overview with the same
+calibration for positions below ``lower_level_below``.
+
+:func:`add_radar_columns` turns world positions into radar coordinates
+normalised to 0-1 from the top-left corner, so frontends can place points on
+any size of overview image without per-map tables.
+"""
+
+from __future__ import annotations
+
+from dataclasses import asdict, dataclass
+from typing import Any, Dict, Iterable, Optional, Tuple
+
+import pandas as pd
+
+RADAR_SIZE = 1024
+RADAR_LEVELS = ("default", "lower")
+
+
+@dataclass(frozen=True)
+class RadarCalibration:
+    pos_x: float
+    pos_y: float
+    scale: float
+    # World z below which the ``lower`` overview applies, on maps with two floors.
+    lower_level_below: Optional[float] = None
+
+    def to_pixels(self, x: Any, y: Any) -> Tuple[Any, Any]:
+        """Radar pixel coordinates (origin top-left, y down) of world ``x``/``y``; works on scalars and Series."""
+
+        return (x - self.pos_x) / self.scale, (self.pos_y - y) / self.scale
+
+    def to_radar(self, x: Any, y: Any) -> Tuple[Any, Any]:
+        """Radar coordinates of world ``x``/``y`` as a share (0-1) of the overview's width and height."""
+
+        column, row = self.to_pixels(x, y)
+        return column / RADAR_SIZE, row / RADAR_SIZE
+
+    def level(self, z: Any) -> str:
+        """The overview a position at height ``z`` is drawn on: ``default`` or ``lower``."""
+
+        below = self.lower_level_below is not None and z is not None and not pd.isna(z) and z < self.lower_level_below
+        return "lower" if below else "default"
+
+    def to_dict(self) -> Dict[str, float]:
+        return {**asdict(self), "size": RADAR_SIZE}
+
+
+RADARS: Dict[str, RadarCalibration] = {
+    "de_ancient": RadarCalibration(-2953, 2164, 5.0),
+    "de_anubis": RadarCalibration(-2796, 3328, 5.22),
+    "de_cache": RadarCalibration(-2000, 3250, 5.5),
+    "de_dust2": RadarCalibration(-2476, 3239, 4.4),
+    "de_inferno": RadarCalibration(-2087, 3870, 4.9),
+    "de_mirage": RadarCalibration(-3230, 1713, 5.0),
+    "de_nuke": RadarCalibration(-3453, 2887, 7.0, lower_level_below=-495),
+    "de_overpass": RadarCalibration(-4831, 1781, 5.2),
+    "de_train": RadarCalibration(-2308, 2078, 4.082),
+    "de_vertigo": RadarCalibration(-3168, 1762, 4.0, lower_level_below=11700),
+}
+
+
+def map_key(map_name: Optional[str]) -> Optional[str]:
+    """``de_mirage`` for ``de_mirage``, ``DE_MIRAGE`` or a workshop path ending in it."""
+
+    if not map_name:
+        return None
+    return map_name.lower().rsplit("/", 1)[-1]
+
+
+def radar_for(map_name: Optional[str]) -> Optional[RadarCalibration]:
+    key = map_key(map_name)
+    return RADARS.get(key) if key else None
+
+
+def add_radar_columns(frame: pd.DataFrame, map_name: Optional[str], prefixes: Iterable[str] = ("",)) -> pd.DataFrame:
+    """Add ``<prefix>radar_x``, ``<prefix>radar_y`` and ``<prefix>radar_level`` for each position in ``frame``.
+
+    A position is the ``<prefix>x``/``<prefix>y`` (and ``<prefix>z``, for the
+    level) columns. The columns are added on every map so the layout stays the
+    same, and left empty where the map has no known radar or the position is
+    missing. Returns a new frame.
+    """
+
+    radar = radar_for(map_name)
+    frame = frame.copy()
+    for prefix in prefixes:
+        x = pd.to_numeric(frame[f"{prefix}x"], errors="coerce") if f"{prefix}x" in frame else None
+        y = pd.to_numeric(frame[f"{prefix}y"], errors="coerce") if f"{prefix}y" in frame else None
+        if radar is None or x is None or y is None:
+            frame[f"{prefix}radar_x"] = float("nan")
+            frame[f"{prefix}radar_y"] = float("nan")
+            frame[f"{prefix}radar_level"] = None
+            continue
+        frame[f"{prefix}radar_x"], frame[f"{prefix}radar_y"] = radar.to_radar(x, y)
+        z = frame[f"{prefix}z"] if f"{prefix}z" in frame else pd.Series(float("nan"), index=frame.index)
+        levels = pd.to_numeric(z, errors="coerce").map(radar.level)
+        frame[f"{prefix}radar_level"] = levels.where(x.notna() & y.notna(), None)
+    return frame
@@ -0,0 +1,120 @@
+"""Salvage demos that were cut off mid-match, e.g. when the game server crashed.
+
+A CS2 demo is the ``PBDEMS2`` header followed by frames of ``command``,
+``tick`` and ``size`` varints and ``size`` bytes of payload. A truncated
+file ends inside a frame, which makes the parsers give up on the whole demo.
+Copying every complete frame behind a header without the (never written)
+file-info offsets and closing the copy with a stop frame gives the parsers
+a demo that simply ends early, like a live broadcast recording.
+
+CS:GO demos cannot be recovered this way and still fail to parse.
+"""
+
+from __future__ import annotations
+
+import re
+import struct
+from dataclasses import dataclass
+from pathlib import Path
+from typing import BinaryIO, Optional, Tuple
+
+from .parser import CS2_MAGIC
+
+# Magic plus the file-info and spawn-group offsets, unknown for a cut-off demo.
+RECOVERED_HEADER = CS2_MAGIC + struct.pack("<ii", 0, 0)
+HEADER_SIZE = len(RECOVERED_HEADER)
+DEM_STOP = 0
+DEM_IS_COMPRESSED = 0x40
+# Signon frames are stamped with tick -1 (as an unsigned 32-bit varint).
+SIGNON_TICK = 0xFFFFFFFF
+# How parser backends report running out of data, in their (mostly native) error messages.
+TRUNCATION_MESSAGE = re.compile(
+    r"unexpected ?(eof|end)|end of (file|demo|stream)|out ?of ?bytes|failed to fill whole buffer|"
+    r"truncat|incomplete|demo ended",
+    re.IGNORECASE,
+)
+
+
+@dataclass(frozen=True)
+class RecoveredDemo:
+    path: Path
+    last_tick: Optional[int]  # last tick of a complete frame; None if only signon data survived
+    frames: int
+    dropped_bytes: int  # the incomplete frame left out at the end
+
+
+def is_truncation_error(exc: BaseException) -> bool:
+    return isinstance(exc, (EOFError, struct.error)) or bool(TRUNCATION_MESSAGE.search(str(exc)))
+
+
+def recover_truncated(source: Path, destination: Path) -> Optional[RecoveredDemo]:
+    """Write the complete frames of ``source`` as a demo that ends cleanly.
+
+    Returns ``None`` when there is nothing to salvage: ``source`` is not a
+    CS2 demo, has no complete frame at all or is not truncated.
+    """
+
+    with Path(source).open("rb") as handle:
+        if handle.read(len(CS2_MAGIC)) != CS2_MAGIC:
+            return None
+        total = Path(source).stat().st_size
+        frames, end, last_tick, stopped = _complete_frames(handle, total)
+        if not frames or stopped:
+            return None
+        handle.seek(HEADER_SIZE)
+        with Path(destination).open("wb") as output:
+            output.write(RECOVERED_HEADER)
+            _copy(handle, output, end - HEADER_SIZE)
+            output.write(_varint(DEM_STOP) + _varint(last_tick or 0) + _varint(0))
+    return RecoveredDemo(path=Path(destination), last_tick=last_tick, frames=frames, dropped_bytes=total - end)
+
+
+def _complete_frames(handle: BinaryIO, total: int) -> Tuple[int, int, Optional[int], bool]:
+    """Walk the frames after the header.
+
+    Returns how many are complete, where the last complete one ends, its game
+    tick and whether the demo ended with a stop frame after all.
+    """
+
+    handle.seek(HEADER_SIZE)
+    frames, end, last_tick = 0, HEADER_SIZE, None
+    while True:
+        command, tick, size = _read_varint(handle), _read_varint(handle), _read_varint(handle)
+        if command is None or tick is None or size is None or handle.tell() + size > total:
+            return frames, end, last_tick, False
+        handle.seek(size, 1)
+        frames, end = frames + 1, handle.tell()
+        if command & ~DEM_IS_COMPRESSED == DEM_STOP:
+            return frames, end, last_tick, True
+        if tick != SIGNON_TICK:
+            last_tick = tick
+
+
+def _read_varint(handle: BinaryIO) -> Optional[int]:
+    value = 0
+    for shift in range(0, 35, 7):
+        byte = handle.read(1)
+        if not byte:
+            return None
+        value |= (byte[0] & 0x7F) << shift
+        if not byte[0] & 0x80:
+            return value
+    return None
+
+
+def _varint(value: int) -> bytes:
+    out = bytearray()
+    while True:
+        byte, value = value & 0x7F, value >> 7
+        out.append(byte | (0x80 if value else 0))
+        if not value:
+            return bytes(out)
+
+
+def _copy(source: BinaryIO, target: BinaryIO, length: int, chunk_size: int = 1024 * 1024) -> None:
+    while length > 0:
+        chunk = source.read(min(chunk_size, length))
+        if not chunk:
+            break
+        target.write(chunk)
+        length -= len(chunk)
@@ -0,0 +1,80 @@
+"""Compact, time-ordered round replays for 2D playback.
+
+A replay is a header (round, tick rate, frame rate, roster and radar
+calibration) plus events sorted by tick. A ``frame`` event holds the state of
+every player at one sampled tick as arrays in :data:`PLAYER_FIELDS` order, so
+the player ids and field names are not repeated in every frame; ``shot``,
+``kill`` and ``grenade`` events sit between the frames at the tick they
+happened. Every event carries ``t``, the seconds since the round started.
+"""
+
+from __future__ import annotations
+
+from typing import Any, Dict, List, Optional
+
+import pandas as pd
+
+from .parser import ReplayFrames
+from .processor import frame_records
+
+# Frames per second when the client does not ask for a rate.
+DEFAULT_REPLAY_RATE = 8.0
+PLAYER_FIELDS = ("steamid", "x", "y", "z", "yaw", "pitch", "health", "is_alive", "weapon")
+# Events at the same tick: the frame first, then what happened on it.
+_EVENT_ORDER = {"frame": 0, "grenade": 1, "shot": 2, "kill": 3}
+
+
+def sample_interval(tick_rate: float, rate: float) -> int:
+    """Ticks between frames for ``rate`` frames per second; never below one tick."""
+
+    return max(1, round(tick_rate / rate))
+
+
+def replay_players(positions: pd.DataFrame) -> List[Dict[str, Any]]:
+    """The roster of a replay: each player's SteamID, latest name and side, ordered by side then name."""
+
+    if positions.empty:
+        return []
+    latest = positions.dropna(subset=["steamid"]).sort_values("tick").groupby("steamid", sort=False).last()
+    players = [
+        {"steamid": str(steamid), "name": record["name"], "side": record["side"]}
+        for steamid, record in zip(latest.index, frame_records(latest[["name", "side"]]))
+    ]
+    return sorted(players, key=lambda player: (player["side"] or "", player["name"] or "", player["steamid"]))
+
+
+def replay_events(frames: ReplayFrames, start_tick: int, tick_rate: float) -> List[Dict[str, Any]]:
+    """Frames, shots, kills and grenades of one round as plain records ordered by tick."""
+
+    def seconds(tick: int) -> float:
+        return round((tick - start_tick) / tick_rate, 3)
+
+    events: List[Dict[str, Any]] = []
+    positions = frames.positions.dropna(subset=["steamid"])
+    for tick, group in positions.groupby("tick", sort=True):
+        players = [[_compact(field, record[field]) for field in PLAYER_FIELDS] for record in frame_records(group)]
+        events.append({"type": "frame", "tick": int(tick), "t": seconds(int(tick)), "players": players})
+    for kind, frame in (("shot", frames.shots), ("kill", frames.kills), ("grenade", frames.grenades)):
+        for record in frame_records(frame):
+            tick = int(record.pop("tick"))
+            details = {key: _compact(key, value) for key, value in record.items() if value is not None}
+            events.append({"type": kind, "tick": tick, "t": seconds(tick), **details})
+    return sorted(events, key=lambda event: (event["tick"], _EVENT_ORDER[event["type"]]))
+
+
+def _compact(field: str, value: Any) -> Optional[Any]:
+    """Whole world units for positions, a tenth of a degree for angles and strings for SteamIDs."""
+
+    if value is None:
+        return None
+    if field.endswith("steamid"):
+        return str(value)
+    if field in ("x", "y", "z"):
+        return round(float(value))
+    if field in ("yaw", "pitch"):
+        return round(float(value), 1)
+    if field in ("health", "entity_id"):
+        return int(value)
+    if field in ("is_alive", "headshot"):
+        return bool(value)
+    return value
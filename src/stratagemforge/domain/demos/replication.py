@@ -0,0 +1,105 @@
+from __future__ import annotations
+
+import logging
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.storage import ArtifactStorage, build_replica_storage, build_storage
+from .models import Demo
+
+logger = logging.getLogger(__name__)
+
+
+class ArtifactReplicator:
+    """Copy processed artefacts to a secondary bucket for durability.
+
+    Runs as a post-processing hook when ``REPLICA_BUCKET`` is set. Each copy
+    is checked against the size recorded at ingest, and the replica location
+    is written to the demo's metadata under ``replicas``. Replication never
+    fails an upload; errors are logged and recorded as ``replication_error``.
+    Demos stored in a residency region are never copied out of it.
+    """
+
+    def __init__(
+        self,
+        settings: Settings,
+        storage: ArtifactStorage | None = None,
+        replica: ArtifactStorage | None = None,
+        location: str | None = None,
+    ) -> None:
+        self.settings = settings
+        self.storage = storage or build_storage(settings)
+        self.replica = replica if replica is not None else build_replica_storage(settings)
+        self.location = location or self._describe_location()
+
+    @property
+    def enabled(self) -> bool:
+        return self.replica is not None
+
+    def replicate(self, session: Session, demo: Demo) -> Dict[str, Any]:
+        """Copy every artefact of ``demo`` to the replica and record where it went."""
+
+        if self.replica is None:
+            raise ValueError("Replication is not configured; set REPLICA_BUCKET")
+        if (demo.extra_metadata or {}).get("storage_region"):
+            raise ValueError("Demos stored in a residency region are not replicated")
+
+        manifest = (demo.extra_metadata or {}).get("artifact_manifest") or {}
+        keys: List[str] = []
+        for key in demo.artifact_keys().values():
+            self.replica.put(self.storage.get(key), key)
+            expected = (manifest.get(key) or {}).get("size_bytes")
+            if expected is not None and self.replica.size(key) != expected:
+                raise RuntimeError(f"Replica of {key} does not match the recorded size")
+            keys.append(key)
+
+        record = {
+            "location": self.location,
+            "region": self.settings.replica_region,
+            "keys": keys,
+            "replicated_at": datetime.utcnow().isoformat(),
+        }
+        metadata = dict(demo.extra_metadata or {})
+        metadata.pop("replication_error", None)
+        metadata["replicas"] = [
+            replica for replica in metadata.get("replicas", []) if replica.get("location") != self.location
+        ] + [record]
+        demo.extra_metadata = metadata
+        session.commit()
+        return record
+
+    def replicate_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: replicate artefacts, never failing the upload."""
+
+        if not self.enabled or (demo.extra_metadata or {}).get("storage_region"):
+            return
+        try:
+            self.replicate(session, demo)
+        except Exception as exc:  # noqa: BLE001 - the primary copy is stored either way
+            logger.exception("Replicating artefacts of demo %s failed", demo.id)
+            session.rollback()
+            demo.extra_metadata = {**(demo.extra_metadata or {}), "replication_error": str(exc)}
+            session.commit()
+
+    def delete_replicas(self, session: Session, demo: Demo) -> None:
+        """Delete hook: remove replicated copies when a demo is permanently deleted."""
+
+        if self.replica is None:
+            return
+        for record in (demo.extra_metadata or {}).get("replicas", []):
+            if record.get("location") != self.location:
+                continue
+            for key in record.get("keys", []):
+                try:
+                    self.replica.delete(key)
+                except Exception:  # noqa: BLE001 - the demo is deleted either way
+                    logger.exception("Deleting replica %s of demo %s failed", key, demo.id)
+
+    def _describe_location(self) -> Optional[str]:
+        if not self.settings.replica_bucket:
+            return None
+        prefix = self.settings.replica_prefix.strip("/")
+        return f"s3://{self.settings.replica_bucket}" + (f"/{prefix}" if prefix else "")
@@ -1,11 +1,47 @@
 from __future__ import annotations
 
-from typing import List, Optional
+from dataclasses import dataclass
+from datetime import datetime
+from typing import List, Optional, Tuple
 
-from sqlalchemy import select
-from sqlalchemy.orm import Session
+from sqlalchemy import and_, func, or_, select
+from sqlalchemy.orm import Session, selectinload
 
-from .models import Demo
+from .models import Demo, Match, MatchPlayer
+
+SORT_COLUMNS = {
+    "uploaded_at": Demo.uploaded_at,
+    "played_at": func.coalesce(Match.played_at, Demo.uploaded_at),
+    "map": Match.map_name,
+    "filename": Demo.original_filename,
+    "size": Demo.size_bytes,
+}
+
+
+VISIBILITY_LEVELS = ("private", "team", "public")
+
+
+def effective_visibility(demo: Demo) -> str:
+    return demo.visibility or ("team" if demo.team_id else "public")
+
+
+@dataclass(frozen=True)
+class DemoVisibility:
+    """Demos a caller may see: public ones, their own uploads and ``team`` demos of their teams."""
+
+    user_id: str
+    team_ids: Tuple[str, ...] = ()
+
+    def clause(self):
+        public = or_(Demo.visibility == "public", and_(Demo.visibility.is_(None), Demo.team_id.is_(None)))
+        team = and_(or_(Demo.visibility.is_(None), Demo.visibility == "team"), Demo.team_id.in_(self.team_ids))
+        return or_(Demo.owner_id == self.user_id, public, team)
+
+    def allows(self, demo: Demo) -> bool:
+        level = effective_visibility(demo)
+        if demo.owner_id == self.user_id or level == "public":
+            return True
+        return level == "team" and demo.team_id in self.team_ids
 
 
 class DemoRepository:
@@ -14,12 +50,122 @@ class DemoRepository:
     def __init__(self, session: Session):
         self.session = session
 
-    def list(self) -> List[Demo]:
-        stmt = select(Demo).order_by(Demo.uploaded_at.desc())
+    def list(self, competition_id: Optional[str] = None) -> List[Demo]:
+        stmt = select(Demo).where(Demo.deleted_at.is_(None)).order_by(Demo.uploaded_at.desc())
+        if competition_id:
+            stmt = stmt.where(Demo.competition_id == competition_id)
         return list(self.session.scalars(stmt).all())
 
-    def get(self, demo_id: str) -> Optional[Demo]:
-        return self.session.get(Demo, demo_id)
+    def search(
+        self,
+        *,
+        competition_id: Optional[str] = None,
+        map_name: Optional[str] = None,
+        player: Optional[str] = None,
+        played_from: Optional[datetime] = None,
+        played_to: Optional[datetime] = None,
+        sort: str = "uploaded_at",
+        descending: bool = True,
+        limit: int = 50,
+        offset: int = 0,
+        visibility: Optional[DemoVisibility] = None,
+        owner_id: Optional[str] = None,
+    ) -> Tuple[List[Demo], int]:
+        """Filter demos by their match metadata and return one page plus the total count.
+
+        ``player`` matches a SteamID exactly or a player name case-insensitively.
+        Dates apply to when the match was played, falling back to the upload time
+        for demos without a match record.
+        """
+
+        played_at = SORT_COLUMNS["played_at"]
+        stmt = select(Demo).outerjoin(Match, Match.demo_id == Demo.id).where(Demo.deleted_at.is_(None))
+        if visibility is not None:
+            stmt = stmt.where(visibility.clause())
+        if owner_id:
+            stmt = stmt.where(Demo.owner_id == owner_id)
+        if competition_id:
+            stmt = stmt.where(Demo.competition_id == competition_id)
+        if map_name:
+            stmt = stmt.where(Match.map_name == map_name)
+        if player:
+            players = select(MatchPlayer.match_id).where(
+                or_(MatchPlayer.steamid == player, func.lower(MatchPlayer.name) == player.lower())
+            )
+            stmt = stmt.where(Match.id.in_(players))
+        if played_from:
+            stmt = stmt.where(played_at >= played_from)
+        if played_to:
+            stmt = stmt.where(played_at <= played_to)
+
+        total = self.session.scalar(select(func.count()).select_from(stmt.subquery())) or 0
+
+        column = SORT_COLUMNS[sort]
+        order = column.desc() if descending else column.asc()
+        page = (
+            stmt.options(selectinload(Demo.match))
+            .order_by(order, Demo.id)
+            .limit(limit)
+            .offset(offset)
+        )
+        return list(self.session.scalars(page).all()), total
+
+    def in_progress(self) -> List[Demo]:
+        """Demos that have been stored but not processed yet, oldest first."""
+
+        stmt = (
+            select(Demo)
+            .where(Demo.deleted_at.is_(None), Demo.status != "processed")
+            .order_by(Demo.uploaded_at)
+        )
+        return list(self.session.scalars(stmt).all())
+
+    def matches(
+        self, played_from: Optional[datetime] = None, limit: Optional[int] = None, primary_only: bool = True
+    ) -> List[Match]:
+        """Matches of demos that have not been deleted, most recently played first.
+
+        Alternate sources of an already recorded game are left out unless ``primary_only`` is off.
+        """
+
+        stmt = (
+            select(Match)
+            .join(Demo, Demo.id == Match.demo_id)
+            .where(Demo.deleted_at.is_(None))
+            .order_by(Match.played_at.desc())
+        )
+        if primary_only:
+            stmt = stmt.where(Match.is_primary.is_(True))
+        if played_from:
+            stmt = stmt.where(Match.played_at >= played_from)
+        if limit:
+            stmt = stmt.limit(limit)
+        return list(self.session.scalars(stmt).all())
+
+    def get(self, demo_id: str, include_deleted: bool = False) -> Optional[Demo]:
+        demo = self.session.get(Demo, demo_id)
+        if demo is not None and demo.deleted_at is not None and not include_deleted:
+            return None
+        return demo
+
+    def get_match(self, key: str) -> Optional[Match]:
+        """Look a match up by its id or by its external id."""
+
+        match = self.session.get(Match, key)
+        if match is None:
+            match = self.session.scalars(select(Match).where(Match.external_id == key)).first()
+        return match
+
+    def game_demos(self, game_id: str) -> List[Demo]:
+        """Live demos whose matches were linked as sources of the same game."""
+
+        stmt = (
+            select(Demo)
+            .join(Match, Match.demo_id == Demo.id)
+            .where(Match.game_id == game_id, Demo.deleted_at.is_(None))
+            .order_by(Match.is_primary.desc(), Demo.uploaded_at)
+        )
+        return list(self.session.scalars(stmt).all())
 
     def get_by_checksum(self, checksum: str) -> Optional[Demo]:
         stmt = select(Demo).where(Demo.checksum == checksum)
@@ -30,3 +176,19 @@ class DemoRepository:
         self.session.commit()
         self.session.refresh(demo)
         return demo
+
+    def delete(self, demo: Demo) -> None:
+        self.session.delete(demo)
+        self.session.commit()
+
+    def replace_match(self, demo: Demo, match: Match) -> Match:
+        """Attach ``match`` to ``demo``, replacing any match recorded by an earlier run."""
+
+        if demo.match is not None:
+            self.session.delete(demo.match)
+            self.session.flush()
+        demo.match = match
+        self.session.add(match)
+        self.session.commit()
+        self.session.refresh(match)
+        return match
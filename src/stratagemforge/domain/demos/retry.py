@@ -0,0 +1,54 @@
+"""Telling transient processing failures from demos that will never process.
+
+A run that fails on I/O or the database is worth repeating a moment later;
+anything else (typically the parser or an extractor tripping over a corrupt
+demo) fails the same way every time, so the demo is dead-lettered instead
+and waits for a manual retry once the cause is fixed.
+"""
+
+from __future__ import annotations
+
+from typing import Tuple, Type
+
+from sqlalchemy.exc import DisconnectionError, OperationalError
+from sqlalchemy.exc import TimeoutError as PoolTimeoutError
+
+# Raised by the file system for a path that is simply wrong; repeating does not help.
+PERMANENT_OS_ERRORS = (FileNotFoundError, IsADirectoryError, NotADirectoryError, PermissionError)
+
+
+def _transient_errors() -> Tuple[Type[BaseException], ...]:
+    errors: Tuple[Type[BaseException], ...] = (OSError, OperationalError, DisconnectionError, PoolTimeoutError)
+    try:
+        from botocore.exceptions import BotoCoreError  # connection and timeout errors talking to S3
+    except ImportError:  # pragma: no cover - only installed for the s3 backend
+        return errors
+    return errors + (BotoCoreError,)
+
+
+TRANSIENT_ERRORS = _transient_errors()
+
+
+class DemoProcessingError(RuntimeError):
+    """Processing a stored demo failed for good; the demo and its raw file are kept.
+
+    ``dead_letter`` is set when the demo itself is at fault and ``job_id`` is
+    the parse job to pass to ``POST /api/jobs/{job_id}/retry``.
+    """
+
+    def __init__(self, message: str, demo_id: str, job_id: str, attempts: int, dead_letter: bool) -> None:
+        super().__init__(message)
+        self.demo_id = demo_id
+        self.job_id = job_id
+        self.attempts = attempts
+        self.dead_letter = dead_letter
+
+
+def is_transient(exc: BaseException) -> bool:
+    return isinstance(exc, TRANSIENT_ERRORS) and not isinstance(exc, PERMANENT_OS_ERRORS)
+
+
+def backoff_delay(attempt: int, base_seconds: float, max_seconds: float = 60.0) -> float:
+    """Seconds to wait before retrying after failed attempt number ``attempt`` (1-based)."""
+
+    return min(max_seconds, base_seconds * 2 ** (attempt - 1))
@@ -1,9 +1,51 @@
 from __future__ import annotations
 
-from datetime import datetime
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, List, Literal, Optional
 
-from pydantic import BaseModel, Field
+from pydantic import AnyHttpUrl, BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+
+class MatchPlayerSummary(BaseModel):
+    steamid: str
+    name: Optional[str] = None
+    team_name: Optional[str] = None
+
+    class Config:
+        orm_mode = True
+
+
+class MatchOverview(BaseModel):
+    id: str
+    map_name: Optional[str] = None
+    played_at: UtcDateTime
+    started_at: Optional[UtcDateTime] = None
+    started_at_source: Optional[str] = None
+    team_a: str
+    team_b: str
+    score_a: int
+    score_b: int
+    winner: Optional[str] = None
+    rounds: int
+    player_count: int
+    source: str
+    event_name: Optional[str] = None
+    hltv_url: Optional[str] = None
+    content_hash: Optional[str] = None
+    external_id: Optional[str] = None
+    external_source: Optional[str] = None
+    game_id: Optional[str] = None
+    is_primary: bool = True
+    superseded_by: Optional[str] = None
+
+    class Config:
+        orm_mode = True
+
+
+class MatchSummary(MatchOverview):
+    artifacts: Dict[str, str] = Field(default_factory=dict)
+    players: List[MatchPlayerSummary] = Field(default_factory=list)
 
 
 class DemoSummary(BaseModel):
@@ -12,32 +54,290 @@ class DemoSummary(BaseModel):
     checksum: str
     size_bytes: int
     status: str
-    uploaded_at: datetime
-    processed_at: Optional[datetime] = None
+    uploaded_at: UtcDateTime
+    processed_at: Optional[UtcDateTime] = None
+    competition_id: Optional[str] = None
+    series: Optional[str] = None
+    owner_id: Optional[str] = None
+    team_id: Optional[str] = None
+    visibility: Optional[str] = None
+    match: Optional[MatchOverview] = None
 
     class Config:
         orm_mode = True
 
 
+class TrashedDemo(DemoSummary):
+    deleted_at: UtcDateTime
+    purge_at: UtcDateTime = Field(description="When the demo and its files are deleted for good")
+
+
 class DemoDetail(DemoSummary):
     processed_path: Optional[str] = None
     content_type: Optional[str] = None
     extra_metadata: Dict[str, Any] = Field(default_factory=dict)
+    match: Optional[MatchSummary] = None
+
+
+class RoundClock(BaseModel):
+    round: int
+    start_tick: Optional[int] = None
+    freeze_end_tick: Optional[int] = None
+    end_tick: Optional[int] = None
+    start_seconds: Optional[float] = Field(default=None, description="Seconds since the recording started")
+    freeze_end_seconds: Optional[float] = None
+    end_seconds: Optional[float] = None
+    start_at: Optional[UtcDateTime] = Field(default=None, description="Estimated wall-clock time of start_tick")
+    freeze_end_at: Optional[UtcDateTime] = None
+    end_at: Optional[UtcDateTime] = None
+
+
+class MatchClock(BaseModel):
+    match_id: str
+    tick_rate: float
+    started_at: Optional[UtcDateTime] = Field(default=None, description="Wall-clock time of tick 0, when known")
+    started_at_source: Optional[str] = None
+    rounds: List[RoundClock] = Field(default_factory=list)
+
+
+class AimSample(BaseModel):
+    tick: int
+    pitch: Optional[float] = None
+    yaw: Optional[float] = None
+    x: Optional[float] = None
+    y: Optional[float] = None
+    z: Optional[float] = None
+    weapon: Optional[str] = None
+    is_alive: Optional[bool] = None
+    vel_x: Optional[float] = None
+    vel_y: Optional[float] = None
+    vel_z: Optional[float] = None
+    speed: Optional[float] = Field(default=None, description="Horizontal speed in units per second")
+    is_ducking: Optional[bool] = None
+    is_walking: Optional[bool] = None
+    is_airborne: Optional[bool] = None
+    is_blinded: Optional[bool] = None
+    ammo_clip: Optional[int] = None
+    ammo_reserve: Optional[int] = None
+    has_bomb: Optional[bool] = None
+    has_defuser: Optional[bool] = None
+    inventory: Optional[str] = Field(
+        default=None, description='Weapons, then grenades with counts, e.g. "ak47,glock18|flash2,smoke"'
+    )
+    round_time_remaining: Optional[float] = Field(default=None, description="Seconds left on the round clock")
+    is_freeze_time: Optional[bool] = None
+    is_warmup: Optional[bool] = None
+
+
+class AimShot(BaseModel):
+    tick: int
+    weapon: Optional[str] = None
+    through_smoke: Optional[bool] = Field(default=None, description="Fired across an active smoke")
+
+
+class AimTrack(BaseModel):
+    match_id: str
+    steamid: str
+    round: int
+    tick_rate: float
+    start_tick: int
+    end_tick: int
+    samples: List[AimSample] = Field(default_factory=list, description="View angles at every tick of the round")
+    shots: List[AimShot] = Field(default_factory=list)
+
+
+class ReplayPlayer(BaseModel):
+    steamid: str
+    name: Optional[str] = None
+    side: Optional[str] = None
+
+
+class RoundReplay(BaseModel):
+    match_id: str
+    round: int
+    map_name: Optional[str] = None
+    tick_rate: float
+    rate: float = Field(description="Frames per second")
+    start_tick: int
+    end_tick: int
+    radar: Optional[Dict[str, float]] = Field(default=None, description="Overview calibration on standard maps")
+    player_fields: List[str] = Field(description="Order of the values of each player in a frame")
+    players: List[ReplayPlayer] = Field(default_factory=list)
+    events: List[Dict[str, Any]] = Field(
+        default_factory=list, description="frame, shot, kill and grenade events ordered by tick"
+    )
+
+
+class RowGroupRange(BaseModel):
+    row_group: int
+    rows: int
+    min: Optional[Any] = None
+    max: Optional[Any] = None
+
+
+class DemoFile(BaseModel):
+    name: str
+    filename: str
+    size_bytes: Optional[int] = None
+    download_url: str
+    schema_version: Optional[int] = Field(
+        default=None, description="Parquet layout version; absent for files written before versioning"
+    )
+    row_groups: Optional[List[RowGroupRange]] = Field(
+        default=None, description="Per-round row groups, for datasets written that way (player_ticks)"
+    )
+
+
+class DemoFileCollection(BaseModel):
+    demo_id: str
+    files: List[DemoFile]
 
 
 class DemoCollection(BaseModel):
     demos: List[DemoSummary]
     count: int
+    total: int
+    page: int = 1
+    page_size: Optional[int] = None
 
 
 class DemoUploadResponse(DemoDetail):
     message: str
 
 
+class ParseJobSummary(BaseModel):
+    id: str
+    demo_id: str
+    status: str = Field(description="parsed, failed, skipped, error (transient) or dead_letter")
+    attempt: int = 1
+    retry_of: Optional[str] = Field(default=None, description="The job this run manually retried")
+    started_at: UtcDateTime
+    finished_at: UtcDateTime
+    duration_seconds: float
+    size_bytes: int
+    error: Optional[str] = None
+
+    class Config:
+        orm_mode = True
+
+
+class UploadBatchItemSummary(BaseModel):
+    id: str
+    position: int
+    filename: str
+    status: str = Field(description="queued, processing, processed, duplicate or failed")
+    size_bytes: int = 0
+    demo_id: Optional[str] = None
+    error: Optional[str] = None
+    started_at: Optional[UtcDateTime] = None
+    finished_at: Optional[UtcDateTime] = None
+
+    class Config:
+        orm_mode = True
+
+
+class UploadBatchSummary(BaseModel):
+    id: str
+    status: str = Field(description="queued, processing, completed, completed_with_errors or failed")
+    counts: Dict[str, int] = Field(description="Items per status")
+    owner_id: Optional[str] = None
+    team_id: Optional[str] = None
+    competition_id: Optional[str] = None
+    visibility: Optional[str] = None
+    force: bool = False
+    created_at: UtcDateTime
+    finished_at: Optional[UtcDateTime] = None
+    items: List[UploadBatchItemSummary]
+
+    class Config:
+        orm_mode = True
+
+
+class GameSources(BaseModel):
+    game_id: Optional[str] = Field(default=None, description="Shared by every source of the game; null if it has one")
+    primary_demo_id: str
+    demos: List[DemoDetail]
+
+
 class DemoProcessingStatus(BaseModel):
     demo_id: str
     status: str
     message: str
-    processed_at: Optional[datetime] = None
+    processed_at: Optional[UtcDateTime] = None
     processed_path: Optional[str] = None
     extra_metadata: Dict[str, Any] = Field(default_factory=dict)
+
+
+class DemoUrlIngestRequest(BaseModel):
+    url: AnyHttpUrl = Field(description="Direct download link to a demo (e.g. FACEIT or Valve replay URL)")
+    competition_id: Optional[str] = None
+    team_id: Optional[str] = None
+    external_match_id: Optional[str] = Field(default=None, max_length=128, description="e.g. the FACEIT match id")
+    external_source: Optional[str] = Field(default=None, max_length=32, description="Where the external id comes from")
+    callback_url: Optional[AnyHttpUrl] = Field(default=None, description="Receives the result as a signed POST")
+    visibility: Optional[Literal["private", "team", "public"]] = Field(
+        default=None, description="Who sees the demo; defaults to team for team uploads"
+    )
+
+
+class DemoSharecodeIngestRequest(BaseModel):
+    sharecode: str = Field(description="Matchmaking sharecode, e.g. CSGO-a2b3c-d4e5f-g6h7i-j8k9L-mnopq")
+    competition_id: Optional[str] = None
+    team_id: Optional[str] = None
+    callback_url: Optional[AnyHttpUrl] = Field(default=None, description="Receives the result as a signed POST")
+    visibility: Optional[Literal["private", "team", "public"]] = Field(
+        default=None, description="Who sees the demo; defaults to team for team uploads"
+    )
+
+
+class DemoVisibilityUpdate(BaseModel):
+    visibility: Literal["private", "team", "public"]
+
+
+class DemoCompetitionAssignment(BaseModel):
+    competition_id: Optional[str] = Field(default=None, description="Competition to attach the demo to; null detaches it")
+    series: Optional[str] = Field(default=None, description="Optional series label within the competition, e.g. 'Grand Final'")
+
+
+class HltvImportRequest(BaseModel):
+    url: AnyHttpUrl = Field(description="HLTV match page, e.g. https://www.hltv.org/matches/2370000/vitality-vs-faze")
+    map_name: Optional[str] = Field(
+        default=None, description="Map to take the score from when the demo's map is unknown, e.g. 'de_mirage'"
+    )
+
+
+class LiveBroadcastStart(BaseModel):
+    url: AnyHttpUrl = Field(description="GOTV+ broadcast root, i.e. the server's tv_broadcast_url plus the match path")
+    team_id: Optional[str] = None
+
+
+class LiveSegment(BaseModel):
+    number: int
+    up_to_tick: int = Field(description="Last tick covered by the segment's tick-stamped rows")
+    up_to_round: int = Field(description="Last finished round covered by the segment's per-round rows")
+    datasets: List[str]
+
+
+class LiveBroadcastSummary(BaseModel):
+    id: str
+    url: str
+    status: str = Field(description="connecting, live, ending, finished or failed")
+    owner_id: Optional[str] = None
+    team_id: Optional[str] = None
+    map_name: Optional[str] = None
+    fragment: Optional[int] = None
+    tick: Optional[int] = None
+    rounds_played: int = 0
+    team_a: Optional[str] = None
+    team_b: Optional[str] = None
+    score_a: int = 0
+    score_b: int = 0
+    segments: List[LiveSegment] = Field(default_factory=list)
+    error: Optional[str] = None
+    demo_id: Optional[str] = Field(default=None, description="The recorded demo once the broadcast has finished")
+    started_at: UtcDateTime
+    updated_at: UtcDateTime
+    finished_at: Optional[UtcDateTime] = None
+
+    class Config:
+        orm_mode = True
@@ -2,85 +2,1389 @@ from __future__ import annotations
 
 import asyncio
 import hashlib
-from datetime import datetime
+import logging
+import shutil
+import time
+from datetime import datetime, timedelta
 from pathlib import Path
-from typing import Tuple
+from typing import Any, Callable, Dict, Iterator, List, Optional, Sequence, Tuple
 from uuid import uuid4
 
+import pandas as pd
 from fastapi import UploadFile
+from sqlalchemy import select
 from sqlalchemy.orm import Session
 
 from ...core.config import Settings
-from .models import Demo
-from .processor import DemoProcessingInput, DemoProcessor
-from .repository import DemoRepository
+from ...core.ids import new_ulid, new_uuid, parse_cursor
+from ...core.netguard import allowed_hosts
+from ...core.parquet import read_row_groups, row_groups_between
+from ...core.tracing import set_attributes, span
+from ...core.storage import ArtifactStorage, RoutedStorage, build_storage
+from ...core.timeutil import to_utc_naive
+from ..competitions.repository import CompetitionRepository
+from ..flags.service import FeatureFlagService
+from ..stats.models import PlayerMatchStat
+from ..stats.repository import StatsRepository
+from ..teams.models import Team
+from .archives import (
+    UploadTooLargeError,
+    check_demo_file,
+    check_upload_header,
+    detect_compression,
+    extract_demo_entries,
+    is_supported_filename,
+)
+from .clock import match_clock
+from .concurrency import ParseLimiter, ParseQueueFullError
+from .csv_export import CSV_SUFFIX, csv_chunks, file_chunks, gzip_chunks
+from .downloader import DemoDownloader
+from .extractors import EXPERIMENTAL_DATASETS
+from .hltv import HltvClient, HltvMatch
+from .identity import content_hash, match_id_for
+from .models import Demo, Match, MatchPlayer, ParseJob, UploadBatch, UploadBatchItem
+from .parser import DEMO_TICK_RATE
+from .pipeline import build_processing_input, build_processor, match_start_from_filename, unpack_demo
+from .processor import DemoProcessingInput, DemoProcessingResult, DemoProcessor, frame_records
+from .radar import radar_for
+from .replay import PLAYER_FIELDS, replay_events, replay_players, sample_interval
+from .repository import SORT_COLUMNS, VISIBILITY_LEVELS, DemoRepository, DemoVisibility
+from .retry import DemoProcessingError, backoff_delay, is_transient
+from .schemas import AimShot, AimSample, AimTrack, MatchClock, ReplayPlayer, RoundReplay
+from .sharecode import SteamReplayClient, decode_sharecode
+from .trajectories import build_trajectories
+from .webhooks import validate_callback_url
+
+logger = logging.getLogger(__name__)
+
+UNSUPPORTED_FILE = "Only .dem files (optionally .gz, .bz2 or zipped) are supported"
+# Scoreboard columns from the ``stats`` dataset copied onto ``PlayerMatchStat`` rows.
+DEMO_STAT_FIELDS = (
+    "rounds",
+    "kills",
+    "deaths",
+    "assists",
+    "flash_assists",
+    "adr",
+    "kast",
+    "headshot_pct",
+    "opening_kills",
+    "opening_deaths",
+    "clutches_played",
+    "clutches_won",
+    "kills_2k",
+    "kills_3k",
+    "kills_4k",
+    "kills_5k",
+    "blind_seconds",
+    "flashed_by_enemies",
+    "flashed_by_teammates",
+    "fire_damage_taken",
+)
 
 
 class DemoService:
     """Application service managing demo uploads and queries."""
 
-    def __init__(self, settings: Settings, processor: DemoProcessor | None = None) -> None:
+    def __init__(
+        self,
+        settings: Settings,
+        processor: DemoProcessor | None = None,
+        downloader: DemoDownloader | None = None,
+        storage: ArtifactStorage | None = None,
+        hltv: HltvClient | None = None,
+        feature_flags: FeatureFlagService | None = None,
+        parse_limiter: ParseLimiter | None = None,
+        replays: SteamReplayClient | None = None,
+    ) -> None:
         self.settings = settings
-        self.processor = processor or DemoProcessor(settings.processed_data_path)
+        self.processor = processor or build_processor(settings)
+        self.storage = storage or build_storage(settings)
         self.chunk_size = 4 * 1024 * 1024  # 4MB streaming chunks
+        self.downloader = downloader or DemoDownloader(
+            max_size=settings.max_upload_size,
+            timeout=settings.download_timeout_seconds,
+            max_retries=settings.download_max_retries,
+            chunk_size=self.chunk_size,
+            allowed_hosts=allowed_hosts(settings.outbound_allowed_hosts),
+        )
+        # Called with (session, demo) after every successful processing run.
+        self.post_process_hooks: List[Callable[[Session, Demo], None]] = []
+        # Called with (session, demo, match) inside the transaction that records a parsed match.
+        self.record_hooks: List[Callable[[Session, Demo, Match], None]] = []
+        # Called with (session, demo) just before a demo is permanently deleted.
+        self.delete_hooks: List[Callable[[Session, Demo], None]] = []
+        self.hltv = hltv or HltvClient(
+            settings.cache_data_path / "hltv",
+            min_interval=settings.hltv_min_interval_seconds,
+            cache_ttl=settings.hltv_cache_ttl_seconds,
+        )
+        self.replays = replays or SteamReplayClient(
+            settings.steam_gc_url, api_key=settings.steam_api_key, timeout=settings.download_timeout_seconds
+        )
+        self.feature_flags = feature_flags
+        self.parse_limiter = parse_limiter or ParseLimiter(
+            settings.max_concurrent_parses,
+            max_queued=settings.max_queued_parses,
+            memory_budget_bytes=settings.parse_memory_budget_mb * 1024 * 1024,
+        )
         self.settings.ensure_directories()
 
-    async def upload_demo(self, upload: UploadFile, session: Session) -> Tuple[Demo, bool]:
-        """Persist an uploaded demo file and generate a parquet summary."""
+    async def upload_demo(
+        self,
+        upload: UploadFile,
+        session: Session,
+        force: bool = False,
+        competition_id: str | None = None,
+        team_id: str | None = None,
+        external_match_id: str | None = None,
+        external_source: str | None = None,
+        callback_url: str | None = None,
+        owner_id: str | None = None,
+        visibility: str | None = None,
+    ) -> Tuple[Demo, bool]:
+        """Persist an uploaded demo file and generate a parquet summary.
+
+        Returns the demo and whether it was processed by this call. Uploads whose
+        checksum matches an already processed demo are skipped unless ``force``.
+        ``external_match_id`` (e.g. a FACEIT match id) becomes an alias of the match,
+        ``callback_url`` receives the processing result as a webhook,
+        ``owner_id`` records the uploading user and ``visibility`` decides who
+        else sees the demo (see :meth:`default_visibility`).
+        """
 
         if not upload.filename:
             raise ValueError("Uploaded file must have a filename")
 
         filename = Path(upload.filename).name
-        if not filename.lower().endswith(".dem"):
-            raise ValueError("Only .dem files are supported")
+        if not is_supported_filename(filename):
+            raise ValueError(UNSUPPORTED_FILE)
+        validate_callback_url(callback_url, allowed_hosts(self.settings.outbound_allowed_hosts))
+        self._require_competition(session, competition_id)
+        self._require_team(session, team_id)
+        self._require_visibility(visibility, team_id)
+        self.parse_limiter.check_capacity()
+
+        with span("demo.receive", filename=filename):
+            checksum, temp_path, total_size = await self._stream_to_disk(upload)
+            set_attributes(size_bytes=total_size, checksum=checksum)
+        return await self._ingest(
+            session,
+            temp_path=temp_path,
+            checksum=checksum,
+            total_size=total_size,
+            filename=filename,
+            content_type=upload.content_type,
+            metadata={
+                **({"team_id": team_id} if team_id else {}),
+                **({"visibility": visibility} if visibility else {}),
+                **({"callback_url": callback_url} if callback_url else {}),
+                **_external_metadata(external_match_id, external_source),
+            },
+            force=force,
+            competition_id=competition_id,
+            owner_id=owner_id,
+        )
+
+    async def ingest_from_url(
+        self,
+        url: str,
+        session: Session,
+        force: bool = False,
+        competition_id: str | None = None,
+        team_id: str | None = None,
+        external_match_id: str | None = None,
+        external_source: str | None = None,
+        callback_url: str | None = None,
+        owner_id: str | None = None,
+        visibility: str | None = None,
+        metadata: Dict[str, Any] | None = None,
+    ) -> Tuple[Demo, bool]:
+        """Download a demo server-side and run it through the upload pipeline.
+
+        ``metadata`` is added to the demo's metadata, e.g. by connectors pulling demos from a platform.
+        """
+
+        validate_callback_url(callback_url, allowed_hosts(self.settings.outbound_allowed_hosts))
+        self._require_competition(session, competition_id)
+        self._require_team(session, team_id)
+        self._require_visibility(visibility, team_id)
+        self.parse_limiter.check_capacity()
+
+        temp_path = self.settings.raw_data_path / f"{uuid4().hex}.tmp"
+        with span("demo.download", url=url):
+            downloaded = await asyncio.to_thread(self.downloader.download, url, temp_path)
+        return await self._ingest(
+            session,
+            temp_path=downloaded.path,
+            checksum=downloaded.checksum,
+            total_size=downloaded.size_bytes,
+            filename=downloaded.filename,
+            content_type=downloaded.content_type,
+            metadata={
+                **(metadata or {}),
+                "source_url": url,
+                **({"team_id": team_id} if team_id else {}),
+                **({"visibility": visibility} if visibility else {}),
+                **({"callback_url": callback_url} if callback_url else {}),
+                **_external_metadata(external_match_id, external_source),
+            },
+            force=force,
+            competition_id=competition_id,
+            owner_id=owner_id,
+        )
+
+    async def ingest_sharecode(
+        self,
+        sharecode: str,
+        session: Session,
+        force: bool = False,
+        competition_id: str | None = None,
+        team_id: str | None = None,
+        callback_url: str | None = None,
+        owner_id: str | None = None,
+        visibility: str | None = None,
+    ) -> Tuple[Demo, bool]:
+        """Resolve a matchmaking sharecode to its Valve replay and ingest that like a URL.
+
+        The decoded match id is recorded as the demo's ``valve`` external id.
+        """
+
+        decoded = decode_sharecode(sharecode)
+        self._require_competition(session, competition_id)
+        self._require_team(session, team_id)
+        self._require_visibility(visibility, team_id)
+        with span("demo.resolve_sharecode", match_id=decoded.match_id):
+            url = await asyncio.to_thread(self.replays.replay_url, decoded)
+        return await self.ingest_from_url(
+            url,
+            session,
+            force=force,
+            competition_id=competition_id,
+            team_id=team_id,
+            external_match_id=str(decoded.match_id),
+            external_source="valve",
+            callback_url=callback_url,
+            owner_id=owner_id,
+            visibility=visibility,
+        )
+
+    async def ingest_recording(
+        self,
+        session: Session,
+        path: Path,
+        filename: str,
+        owner_id: str | None = None,
+        team_id: str | None = None,
+        metadata: Dict[str, Any] | None = None,
+    ) -> Tuple[Demo, bool]:
+        """Run a demo recorded on this server (e.g. a live broadcast) through the upload pipeline.
+
+        The file is moved into demo storage.
+        """
+
+        checksum = await asyncio.to_thread(file_sha256, path, self.chunk_size)
+        return await self._ingest(
+            session,
+            temp_path=path,
+            checksum=checksum,
+            total_size=path.stat().st_size,
+            filename=filename,
+            content_type="application/octet-stream",
+            metadata={**(metadata or {}), **({"team_id": team_id} if team_id else {})},
+            owner_id=owner_id,
+        )
+
+    async def upload_batch(
+        self,
+        uploads: List[UploadFile],
+        session: Session,
+        force: bool = False,
+        competition_id: str | None = None,
+        team_id: str | None = None,
+        owner_id: str | None = None,
+        visibility: str | None = None,
+    ) -> UploadBatch:
+        """Stage several uploaded demos and queue each one for processing as its own job.
+
+        A file that cannot be staged (wrong type, not a demo, too large) becomes a
+        failed item instead of rejecting the whole batch. The queued items are
+        processed by :meth:`process_batches`.
+        """
+
+        if not uploads:
+            raise ValueError("Upload at least one demo")
+        if len(uploads) > self.settings.max_batch_files:
+            raise ValueError(f"A batch may contain at most {self.settings.max_batch_files} demos")
+        self._require_batch_options(session, competition_id, team_id, visibility)
+
+        staged: List[Tuple[str, Path | None, int, str | None]] = []
+        for upload in uploads:
+            filename = Path(upload.filename or "").name
+            if not is_supported_filename(filename):
+                staged.append((filename or "unnamed", None, 0, UNSUPPORTED_FILE))
+                continue
+            try:
+                _, temp_path, total_size = await self._stream_to_disk(upload)
+            except ValueError as exc:
+                staged.append((filename, None, 0, str(exc)))
+            else:
+                staged.append((filename, temp_path, total_size, None))
+        return self._create_batch(session, staged, force, competition_id, team_id, owner_id, visibility)
+
+    async def upload_batch_archive(
+        self,
+        upload: UploadFile,
+        session: Session,
+        force: bool = False,
+        competition_id: str | None = None,
+        team_id: str | None = None,
+        owner_id: str | None = None,
+        visibility: str | None = None,
+    ) -> UploadBatch:
+        """Queue every demo inside an uploaded zip archive as one batch."""
+
+        self._require_batch_options(session, competition_id, team_id, visibility)
+        _, archive_path, _ = await self._stream_to_disk(upload)
+        try:
+            if detect_compression(archive_path) != "zip":
+                raise ValueError("Batch archives must be zip files")
+            entries = await asyncio.to_thread(
+                extract_demo_entries,
+                archive_path,
+                self.settings.raw_data_path,
+                self.settings.max_upload_size,
+                self.settings.max_batch_files,
+                self.chunk_size,
+            )
+        finally:
+            archive_path.unlink(missing_ok=True)
+        staged = [(filename, path, size, None) for filename, path, size in entries]
+        return self._create_batch(session, staged, force, competition_id, team_id, owner_id, visibility)
+
+    def get_batch(self, session: Session, batch_id: str) -> UploadBatch:
+        batch = session.get(UploadBatch, batch_id)
+        if not batch:
+            raise LookupError("Upload batch not found")
+        return batch
+
+    async def process_batches(self, session: Session) -> int:
+        """Process queued batch items, oldest batch first; returns how many items were handled.
+
+        Items left ``processing`` were interrupted by a restart and are picked up
+        again. The pass stops early while the parse queue is full.
+        """
+
+        stmt = (
+            select(UploadBatchItem)
+            .join(UploadBatch)
+            .where(UploadBatchItem.status.in_(("queued", "processing")))
+            .order_by(UploadBatch.created_at, UploadBatchItem.position)
+        )
+        handled = 0
+        for item in list(session.scalars(stmt)):
+            try:
+                self.parse_limiter.check_capacity()
+            except ParseQueueFullError:
+                break
+            await self._process_batch_item(session, item)
+            handled += 1
+        return handled
+
+    async def _process_batch_item(self, session: Session, item: UploadBatchItem) -> None:
+        batch = item.batch
+        item.status, item.started_at = "processing", datetime.utcnow()
+        session.commit()
+        staged_path = Path(item.staged_path) if item.staged_path else None
+        try:
+            if staged_path is None or not staged_path.exists():
+                raise ValueError("The staged demo file is gone; upload it again")
+            checksum = await asyncio.to_thread(file_sha256, staged_path)
+            demo, created = await self._ingest(
+                session,
+                temp_path=staged_path,
+                checksum=checksum,
+                total_size=item.size_bytes,
+                filename=item.filename,
+                content_type="application/octet-stream",
+                metadata={
+                    "batch_id": batch.id,
+                    **({"team_id": batch.team_id} if batch.team_id else {}),
+                    **({"visibility": batch.visibility} if batch.visibility else {}),
+                },
+                force=batch.force,
+                competition_id=batch.competition_id,
+                owner_id=batch.owner_id,
+            )
+        except Exception as exc:  # noqa: BLE001 - one bad demo must not stall the rest of the batch
+            session.rollback()
+            logger.warning("Batch %s item %s failed: %s", batch.id, item.filename, exc)
+            if staged_path is not None:
+                staged_path.unlink(missing_ok=True)
+            item.status, item.error = "failed", str(exc)[:1000]
+        else:
+            item.status, item.demo_id = ("processed" if created else "duplicate"), demo.id
+        item.staged_path, item.finished_at = None, datetime.utcnow()
+        if batch.status not in ("queued", "processing"):
+            batch.finished_at = item.finished_at
+        session.commit()
+
+    def _create_batch(
+        self,
+        session: Session,
+        staged: List[Tuple[str, Path | None, int, str | None]],
+        force: bool,
+        competition_id: str | None,
+        team_id: str | None,
+        owner_id: str | None,
+        visibility: str | None,
+    ) -> UploadBatch:
+        batch = UploadBatch(
+            owner_id=owner_id, team_id=team_id, competition_id=competition_id, visibility=visibility, force=force
+        )
+        now = datetime.utcnow()
+        for position, (filename, path, size, error) in enumerate(staged):
+            batch.items.append(
+                UploadBatchItem(
+                    position=position,
+                    filename=filename[:255],
+                    status="failed" if error else "queued",
+                    staged_path=str(path) if path else None,
+                    size_bytes=size,
+                    error=error,
+                    finished_at=now if error else None,
+                )
+            )
+        if batch.status == "failed":
+            batch.finished_at = now
+        session.add(batch)
+        session.commit()
+        session.refresh(batch)
+        return batch
+
+    def _require_batch_options(
+        self, session: Session, competition_id: str | None, team_id: str | None, visibility: str | None
+    ) -> None:
+        self._require_competition(session, competition_id)
+        self._require_team(session, team_id)
+        self._require_visibility(visibility, team_id)
 
-        checksum, temp_path, total_size = await self._stream_to_disk(upload)
+    async def _ingest(
+        self,
+        session: Session,
+        *,
+        temp_path: Path,
+        checksum: str,
+        total_size: int,
+        filename: str,
+        content_type: str | None,
+        metadata: Dict[str, Any] | None = None,
+        force: bool = False,
+        competition_id: str | None = None,
+        owner_id: str | None = None,
+    ) -> Tuple[Demo, bool]:
+        metadata = dict(metadata or {})
+        compression = detect_compression(temp_path)
+        if compression:
+            with span("demo.decompress", compression=compression):
+                checksum, temp_path, total_size = await asyncio.to_thread(self._decompress, temp_path)
+            metadata["compression"] = compression
+        try:
+            check_demo_file(temp_path)
+        except ValueError:
+            temp_path.unlink(missing_ok=True)
+            raise
 
         repo = DemoRepository(session)
         existing = repo.get_by_checksum(checksum)
-        if existing:
+        try:
+            self._require_free_external_id(session, metadata.get("external_match_id"), existing)
+        except ValueError:
             temp_path.unlink(missing_ok=True)
-            return existing, False
+            raise
+        if existing:
+            if existing.deleted_at is not None:
+                # Uploading a soft-deleted demo again brings it back.
+                existing.deleted_at = None
+            if owner_id and not existing.owner_id:
+                # The first identified uploader owns the demo; re-uploads by others do not take it over.
+                existing.owner_id = owner_id
+            if metadata.get("team_id") and not existing.team_id:
+                existing.team_id = metadata["team_id"]
+            if self.is_processed(existing) and not force:
+                temp_path.unlink(missing_ok=True)
+                return repo.save(existing), False
+            # Stale or forced: keep the original record and reprocess it.
+            if competition_id:
+                existing.competition_id = competition_id
+            stored_path = Path(existing.stored_path)
+            if stored_path.exists():
+                temp_path.unlink(missing_ok=True)
+            else:
+                temp_path.replace(stored_path)
+            return await self._process(repo, existing, {**(existing.extra_metadata or {}), **metadata}), True
 
+        try:
+            region = self.storage_region(session, metadata.get("team_id"))
+        except ValueError:
+            temp_path.unlink(missing_ok=True)
+            raise
+        if region:
+            metadata["storage_region"] = region
         final_path = self.settings.raw_data_path / f"{checksum}.dem"
         temp_path.replace(final_path)
 
         demo = Demo(
-            id=str(uuid4()),
+            id=new_uuid(),
             original_filename=filename,
             stored_path=str(final_path),
             checksum=checksum,
             size_bytes=total_size,
-            content_type=upload.content_type,
+            content_type=content_type,
             status="uploaded",
             uploaded_at=datetime.utcnow(),
+            extra_metadata=metadata,
+            competition_id=competition_id,
+            owner_id=owner_id,
+            team_id=metadata.get("team_id"),
+            visibility=metadata.get("visibility") or self.default_visibility(owner_id, metadata.get("team_id")),
         )
         demo = repo.save(demo)
+        return await self._process(repo, demo, metadata), True
+
+    def is_processed(self, demo: Demo) -> bool:
+        """A demo counts as processed only if its parquet summary is still in storage."""
 
-        processing_input = DemoProcessingInput(
+        if demo.status != "processed" or not demo.processed_path:
+            return False
+        summary_key = (demo.extra_metadata or {}).get("summary_key") or demo.processed_path
+        return self.storage.exists(summary_key)
+
+    async def _process(
+        self, repo: DemoRepository, demo: Demo, metadata: Dict[str, Any], retry_of: str | None = None
+    ) -> Demo:
+        """Process ``demo``, retrying I/O and database failures with exponential backoff.
+
+        Every attempt leaves a parse job. When the last one fails the demo is
+        marked ``failed`` (transient errors) or ``dead_letter`` and
+        :class:`DemoProcessingError` is raised; the raw file is kept so the
+        job can be retried once the cause is fixed.
+        """
+
+        processing_input = build_processing_input(
+            self.settings,
             demo_id=demo.id,
             original_filename=demo.original_filename,
             checksum=demo.checksum,
             size_bytes=demo.size_bytes,
             uploaded_at=demo.uploaded_at,
-            raw_path=final_path,
+            raw_path=Path(demo.stored_path),
+            skip_datasets=self._disabled_datasets(repo.session, metadata.get("team_id")),
+            map_name=metadata.get("map_name"),
         )
 
-        processing_result = await asyncio.to_thread(self.processor.process, processing_input)
-        demo.mark_processed(
-            processed_path=str(processing_result.parquet_path),
-            processed_at=processing_result.processed_at,
-            metadata=processing_result.summary,
+        attempt = 1
+        while True:
+            try:
+                demo = await self._attempt(repo, demo, metadata, processing_input, attempt, retry_of)
+                break
+            except ParseQueueFullError:
+                raise
+            except Exception as exc:  # noqa: BLE001 - the attempt is recorded; classified below
+                transient = is_transient(exc)
+                if transient and attempt <= self.settings.processing_max_retries:
+                    delay = backoff_delay(attempt, self.settings.processing_retry_backoff_seconds)
+                    logger.warning(
+                        "Processing demo %s failed (attempt %d), retrying in %.1fs: %s", demo.id, attempt, delay, exc
+                    )
+                    await asyncio.sleep(delay)
+                    attempt += 1
+                    continue
+                demo.status = "failed" if transient else "dead_letter"
+                demo = repo.save(demo)
+                job = self.latest_job(repo.session, demo.id)
+                assert job is not None
+                logger.error("Processing demo %s failed for good after %d attempt(s): %s", demo.id, attempt, exc)
+                message = (
+                    f"Processing failed after {attempt} attempts; retry job {job.id} later"
+                    if transient
+                    else f"Demo could not be processed and was dead-lettered as job {job.id}"
+                )
+                raise DemoProcessingError(message, demo.id, job.id, attempt, dead_letter=not transient) from exc
+        for hook in self.post_process_hooks:
+            with span("demo.post_process_hook", demo_id=demo.id, hook=getattr(hook, "__qualname__", repr(hook))):
+                await asyncio.to_thread(hook, repo.session, demo)
+        return demo
+
+    async def _attempt(
+        self,
+        repo: DemoRepository,
+        demo: Demo,
+        metadata: Dict[str, Any],
+        processing_input: DemoProcessingInput,
+        attempt: int,
+        retry_of: str | None,
+    ) -> Demo:
+        """One processing run; a failure is recorded as a parse job before it is re-raised."""
+
+        job_id = new_ulid()
+        estimated_bytes = int(demo.size_bytes * self.settings.parse_memory_factor)
+        async with self.parse_limiter.slot(estimated_bytes):
+            started_at, started = datetime.utcnow(), time.perf_counter()
+            logger.info("Processing demo %s as job %s (attempt %d)", demo.id, job_id, attempt)
+            try:
+                with span("demo.process", demo_id=demo.id, job_id=job_id, attempt=attempt):
+                    processing_result = await asyncio.to_thread(self.processor.process, processing_input)
+            except Exception as exc:
+                self._record_failure(repo.session, demo, job_id, started_at, started, exc, attempt, retry_of)
+                raise
+        summary = processing_result.summary
+        self._record_job(
+            repo.session,
+            demo,
+            job_id,
+            started_at,
+            started,
+            summary.get("parse_status", "parsed"),
+            summary.get("parse_error"),
+            attempt,
+            retry_of,
         )
-        demo = repo.save(demo)
-        return demo, True
+        try:
+            region = metadata.get("storage_region")
+            backend = self.storage.backend(region) if region else self.storage
+            with span("demo.store_artifacts", demo_id=demo.id, backend=backend.name):
+                summary_key, dataset_keys, csv_keys, manifest = await asyncio.to_thread(
+                    self._store_artifacts, processing_result, region
+                )
+            demo.mark_processed(
+                processed_path=str(processing_result.parquet_path),
+                processed_at=processing_result.processed_at,
+                metadata={
+                    **metadata,
+                    **processing_result.summary,
+                    "storage_backend": backend.name,
+                    "summary_key": summary_key,
+                    "datasets": dataset_keys,
+                    "csv_datasets": csv_keys,
+                    "artifact_manifest": manifest,
+                },
+            )
+            with span("demo.record", demo_id=demo.id):
+                demo = repo.save(demo)
+                if processing_result.match is not None:
+                    self._record_match(repo, demo, processing_result, summary_key, dataset_keys)
+        except Exception as exc:
+            repo.session.rollback()
+            self._record_failure(repo.session, demo, job_id, started_at, started, exc, attempt, retry_of)
+            raise
+        return demo
+
+    def _record_failure(
+        self,
+        session: Session,
+        demo: Demo,
+        job_id: str,
+        started_at: datetime,
+        started: float,
+        exc: Exception,
+        attempt: int,
+        retry_of: str | None,
+    ) -> None:
+        status = "error" if is_transient(exc) else "dead_letter"
+        error = str(exc) or type(exc).__name__
+        logger.warning("Job %s for demo %s ended as %s: %s", job_id, demo.id, status, error)
+        self._record_job(session, demo, job_id, started_at, started, status, error, attempt, retry_of)
+        session.commit()
+
+    @staticmethod
+    def _record_job(
+        session: Session,
+        demo: Demo,
+        job_id: str,
+        started_at: datetime,
+        started: float,
+        status: str,
+        error: str | None,
+        attempt: int = 1,
+        retry_of: str | None = None,
+    ) -> None:
+        """Add a row to the parse job history; it is committed with the demo."""
+
+        session.add(
+            ParseJob(
+                id=job_id,
+                demo_id=demo.id,
+                status=status,
+                started_at=started_at,
+                finished_at=datetime.utcnow(),
+                duration_seconds=round(time.perf_counter() - started, 3),
+                size_bytes=demo.size_bytes,
+                error=error[:1000] if error else None,
+                attempt=attempt,
+                retry_of=retry_of,
+            )
+        )
+
+    @staticmethod
+    def latest_job(session: Session, demo_id: str) -> ParseJob | None:
+        stmt = select(ParseJob).where(ParseJob.demo_id == demo_id).order_by(ParseJob.started_at.desc()).limit(1)
+        return session.scalars(stmt).first()
+
+    def list_jobs(
+        self,
+        session: Session,
+        status: str | None = None,
+        demo_id: str | None = None,
+        limit: int = 100,
+        cursor: str | None = None,
+        visibility: DemoVisibility | None = None,
+    ) -> List[ParseJob]:
+        """Jobs newest first; ``cursor`` (the last job id of the previous page) continues after it.
+
+        Every page is ordered by id, the ULID the cursor compares against, so
+        jobs whose ``started_at`` is out of step with their id are neither
+        skipped nor repeated. With ``visibility``, only jobs of demos the
+        caller may see are listed (none of permanently deleted demos).
+        """
+
+        stmt = select(ParseJob).order_by(ParseJob.id.desc()).limit(limit)
+        if cursor:
+            stmt = stmt.where(ParseJob.id < parse_cursor(cursor))
+        if visibility is not None:
+            stmt = stmt.join(Demo, Demo.id == ParseJob.demo_id).where(visibility.clause())
+        if status:
+            stmt = stmt.where(ParseJob.status == status)
+        if demo_id:
+            stmt = stmt.where(ParseJob.demo_id == demo_id)
+        return list(session.scalars(stmt))
+
+    def get_job(self, session: Session, job_id: str) -> ParseJob:
+        job = session.get(ParseJob, job_id)
+        if not job:
+            raise LookupError("Job not found")
+        return job
+
+    async def retry_job(self, session: Session, job_id: str) -> ParseJob:
+        """Reprocess the demo of a job, e.g. a dead-lettered one after a parser fix; returns the new job.
+
+        A retry that fails again is recorded like any other run rather than raised.
+        """
+
+        job = self.get_job(session, job_id)
+        demo = self.get_demo(session, job.demo_id)
+        if not demo:
+            raise LookupError("The demo of this job has been deleted")
+        if not Path(demo.stored_path).exists():
+            raise ValueError("The demo's raw file is gone; upload it again")
+        self.parse_limiter.check_capacity()
+        try:
+            await self._process(DemoRepository(session), demo, dict(demo.extra_metadata or {}), retry_of=job.id)
+        except DemoProcessingError:
+            pass
+        latest = self.latest_job(session, demo.id)
+        assert latest is not None
+        return latest
+
+    def _record_match(
+        self,
+        repo: DemoRepository,
+        demo: Demo,
+        result: DemoProcessingResult,
+        summary_key: str,
+        dataset_keys: Dict[str, str],
+    ) -> Match:
+        info = result.match
+        players = info.get("players") or []
+        started_at = self._match_start(demo)
+        metadata = demo.extra_metadata or {}
+        full_hash = content_hash(demo.checksum)
+        match_id = match_id_for(demo.checksum)
+        clash = repo.session.get(Match, match_id)
+        if clash is not None and clash.demo_id != demo.id:
+            # Two demos sharing the first 128 bits of their hash: keep both, but not under the same id.
+            logger.warning(
+                "Match id %s derived from %s collides with demo %s (%s); using a random id",
+                match_id,
+                full_hash,
+                clash.demo_id,
+                clash.content_hash,
+            )
+            match_id = new_uuid()
+        match = Match(
+            id=match_id,
+            content_hash=full_hash,
+            external_id=metadata.get("external_match_id"),
+            external_source=metadata.get("external_source"),
+            map_name=info.get("map_name"),
+            played_at=started_at or demo.uploaded_at,
+            started_at=started_at,
+            started_at_source="filename" if started_at else None,
+            team_a=info["team_a"],
+            team_b=info["team_b"],
+            score_a=info["score_a"],
+            score_b=info["score_b"],
+            winner=info.get("winner"),
+            rounds=info["rounds"],
+            tick_rate=info.get("tick_rate"),
+            round_ticks=info.get("round_ticks") or [],
+            player_count=len(players),
+            source="url" if metadata.get("source_url") else "upload",
+            artifacts={"summary": summary_key, **dataset_keys},
+            players=[MatchPlayer(**player) for player in players],
+        )
+
+        # Stats rows point at the match being replaced; detach them first and
+        # re-point imported history at the new match afterwards.
+        stats_repo = StatsRepository(repo.session)
+        linked = stats_repo.linked_to(demo.match.id) if demo.match else []
+        for stat in linked:
+            stat.match_id = None
+        repo.session.flush()
+
+        match = repo.replace_match(demo, match)
+        for stat in linked:
+            if stat.source != "demo":
+                stat.match_id = match.id
+        stats_repo.replace_demo_stats(
+            demo.id,
+            [
+                PlayerMatchStat(
+                    source="demo",
+                    match_id=match.id,
+                    match_key=demo.id,
+                    player_key=str(row["steamid"]),
+                    played_at=match.played_at,
+                    map_name=match.map_name,
+                    steamid=str(row["steamid"]),
+                    player_name=row.get("name"),
+                    team_name=row.get("team_name"),
+                    **{field: row.get(field) for field in DEMO_STAT_FIELDS},
+                )
+                for row in result.player_stats
+            ],
+        )
+        repo.session.flush()
+        for hook in self.record_hooks:
+            hook(repo.session, demo, match)
+        repo.session.commit()
+        return match
+
+    def _store_artifacts(
+        self, result: DemoProcessingResult, region: str | None = None
+    ) -> Tuple[str, Dict[str, str], Dict[str, str], Dict[str, Dict[str, Any]]]:
+        """Hand processed files to the storage backend and return their keys plus a size/checksum manifest.
+
+        The keys are the summary's, the datasets' and those of their CSV copies.
+        Datasets written with per-round row groups have their ranges recorded
+        in the manifest as ``row_groups``.
+
+        With a residency ``region`` the keys are routed to that region's backend.
+        """
+
+        manifest: Dict[str, Dict[str, Any]] = {}
+
+        def store(path: Path) -> str:
+            key = RoutedStorage.region_key(region, path.relative_to(self.processor.processed_dir).as_posix())
+            key = self.storage.put(path, key)
+            manifest[key] = {"size_bytes": path.stat().st_size, "sha256": file_sha256(path)}
+            return key
+
+        summary_key = store(result.parquet_path)
+        dataset_keys = {name: store(path) for name, path in result.datasets.items()}
+        for name, ranges in result.row_groups.items():
+            manifest[dataset_keys[name]]["row_groups"] = ranges
+        csv_keys = {name: store(path) for name, path in result.csv_datasets.items()}
+        return summary_key, dataset_keys, csv_keys, manifest
+
+    def list_demos(self, session: Session, competition_id: str | None = None) -> list[Demo]:
+        return DemoRepository(session).list(competition_id=competition_id)
+
+    def search_demos(
+        self,
+        session: Session,
+        *,
+        competition_id: str | None = None,
+        map_name: str | None = None,
+        player: str | None = None,
+        played_from: datetime | None = None,
+        played_to: datetime | None = None,
+        sort: str = "-uploaded_at",
+        page: int = 1,
+        page_size: int = 50,
+        visibility: DemoVisibility | None = None,
+        owner_id: str | None = None,
+    ) -> Tuple[list[Demo], int]:
+        """Page through demos; ``sort`` is a field name, prefixed with ``-`` for descending order.
+
+        ``visibility`` limits the results to what one caller may see; ``None`` shows everything.
+        ``owner_id`` keeps only one user's uploads.
+        """
+
+        field = sort.lstrip("-")
+        if field not in SORT_COLUMNS:
+            raise ValueError(f"Unsupported sort field {field!r}; choose one of {', '.join(SORT_COLUMNS)}")
+        played_from = to_utc_naive(played_from) if played_from else None
+        played_to = to_utc_naive(played_to) if played_to else None
+        if played_from and played_to and played_to < played_from:
+            raise ValueError("'to' must not be before 'from'")
+        return DemoRepository(session).search(
+            competition_id=competition_id,
+            map_name=map_name,
+            player=player,
+            played_from=played_from,
+            played_to=played_to,
+            sort=field,
+            descending=sort.startswith("-"),
+            limit=page_size,
+            offset=(page - 1) * page_size,
+            visibility=visibility,
+            owner_id=owner_id,
+        )
+
+    def get_demo(self, session: Session, demo_id: str, include_deleted: bool = False) -> Demo | None:
+        return DemoRepository(session).get(demo_id, include_deleted=include_deleted)
 
-    def list_demos(self, session: Session) -> list[Demo]:
-        return DemoRepository(session).list()
+    def match_clock(self, session: Session, demo_id: str) -> MatchClock:
+        """Per-round ticks with their demo-relative seconds and estimated wall-clock time."""
 
-    def get_demo(self, session: Session, demo_id: str) -> Demo | None:
-        return DemoRepository(session).get(demo_id)
+        demo = DemoRepository(session).get(demo_id)
+        if not demo:
+            raise LookupError("Demo not found")
+        if demo.match is None:
+            raise LookupError("Demo has no parsed match")
+        return match_clock(demo.match)
+
+    def aim_track(self, session: Session, match_id: str, steamid: str, round_number: int) -> AimTrack:
+        """Dense view angles and shots of one player in one round, read from the stored raw demo.
+
+        The round's tick window comes from the match's stored round ticks, so
+        only that slice of the demo is decoded.
+        """
+
+        demo = self.get_demo_by_match(session, match_id)
+        if demo is None or demo.match is None:
+            raise LookupError("Match not found")
+        window = next((entry for entry in demo.match.round_ticks or [] if entry["round"] == round_number), None)
+        if window is None:
+            raise LookupError(f"Round {round_number} not found")
+        raw_path = Path(demo.stored_path)
+        if not raw_path.exists():
+            raise LookupError("The raw demo file is no longer stored")
+        if not hasattr(self.processor.parser, "parse_aim"):
+            raise ValueError("No installed parser can read view angles from this demo")
+
+        start_tick, end_tick = int(window["start_tick"]), int(window["end_tick"])
+        samples, shots = self.processor.parser.parse_aim(raw_path, steamid, start_tick, end_tick)
+        if samples.empty and shots.empty:
+            raise LookupError(f"Player {steamid} has no view angles in round {round_number}")
+        return AimTrack(
+            match_id=demo.match.id,
+            steamid=steamid,
+            round=round_number,
+            tick_rate=demo.match.tick_rate or DEMO_TICK_RATE,
+            start_tick=start_tick,
+            end_tick=end_tick,
+            samples=[AimSample(**row) for row in frame_records(samples)],
+            shots=[AimShot(**row) for row in frame_records(shots)],
+        )
+
+    def round_replay(self, session: Session, match_id: str, round_number: int, rate: float) -> RoundReplay:
+        """Every player's state ``rate`` times a second through one round, with its shots, kills and grenades.
+
+        Read from the stored raw demo like :meth:`aim_track`, decoding only the
+        sampled ticks of the round's window.
+        """
+
+        demo = self.get_demo_by_match(session, match_id)
+        if demo is None or demo.match is None:
+            raise LookupError("Match not found")
+        window = next((entry for entry in demo.match.round_ticks or [] if entry["round"] == round_number), None)
+        if window is None:
+            raise LookupError(f"Round {round_number} not found")
+        raw_path = Path(demo.stored_path)
+        if not raw_path.exists():
+            raise LookupError("The raw demo file is no longer stored")
+        if not hasattr(self.processor.parser, "parse_replay"):
+            raise ValueError("No installed parser can read replays from this demo")
+
+        tick_rate = demo.match.tick_rate or DEMO_TICK_RATE
+        every = sample_interval(tick_rate, rate)
+        start_tick, end_tick = int(window["start_tick"]), int(window["end_tick"])
+        frames = self.processor.parser.parse_replay(raw_path, start_tick, end_tick, every)
+        radar = radar_for(demo.match.map_name)
+        return RoundReplay(
+            match_id=demo.match.id,
+            round=round_number,
+            map_name=demo.match.map_name,
+            tick_rate=tick_rate,
+            rate=round(tick_rate / every, 3),
+            start_tick=start_tick,
+            end_tick=end_tick,
+            radar=radar.to_dict() if radar else None,
+            player_fields=list(PLAYER_FIELDS),
+            players=[ReplayPlayer(**player) for player in replay_players(frames.positions)],
+            events=replay_events(frames, start_tick, tick_rate),
+        )
+
+    def trajectories(
+        self,
+        session: Session,
+        match_id: str,
+        tolerance: float,
+        round_number: Optional[int] = None,
+        steamid: Optional[str] = None,
+    ) -> Dict[str, Any]:
+        """Simplified per-player, per-round paths of a match from its ``player_ticks`` dataset."""
+
+        demo = self.get_demo_by_match(session, match_id)
+        if demo is None or demo.match is None:
+            raise LookupError("Match not found")
+        key = demo.artifact_keys().get("player_ticks")
+        if not key or not self.storage.exists(key):
+            raise LookupError("This match has no player_ticks dataset")
+        path = self.storage.get(key)
+        ranges = self.row_group_ranges(demo).get("player_ticks")
+        if round_number is not None and ranges:
+            player_ticks = read_row_groups(path, row_groups_between(ranges, round_number, round_number))
+        else:
+            filters = [("round", "==", round_number)] if round_number is not None else None
+            player_ticks = pd.read_parquet(path, filters=filters)
+        collection = build_trajectories(player_ticks, tolerance, round_number=round_number, steamid=steamid)
+        collection["properties"].update(match_id=demo.match.id, map_name=demo.match.map_name)
+        return collection
+
+    def game_sources(self, session: Session, demo_id: str) -> List[Demo]:
+        """The demo plus every other source of the same game, primary source first."""
+
+        repo = DemoRepository(session)
+        demo = repo.get(demo_id)
+        if not demo:
+            raise LookupError("Demo not found")
+        if demo.match is None or not demo.match.game_id:
+            return [demo]
+        return repo.game_demos(demo.match.game_id)
+
+    def get_demo_by_match(self, session: Session, match_id: str, include_deleted: bool = False) -> Demo | None:
+        """Resolve a match id or external match id to its demo, skipping trashed ones unless asked."""
+
+        match = DemoRepository(session).get_match(match_id)
+        if match is None or (match.demo.deleted_at is not None and not include_deleted):
+            return None
+        return match.demo
+
+    def delete_demo(self, session: Session, demo_id: str, soft: bool = False) -> None:
+        """Delete a demo with its match record, derived stats and stored artefacts.
+
+        A soft delete only hides the demo from listings and analytics; its files
+        and rows stay in place so it can be brought back.
+        """
+
+        repo = DemoRepository(session)
+        demo = repo.get(demo_id, include_deleted=not soft)
+        if not demo:
+            raise LookupError("Demo not found")
+
+        if soft:
+            demo.deleted_at = datetime.utcnow()
+            repo.save(demo)
+            return
+
+        keys = list(self.artifact_keys(session, demo_id, include_deleted=True).values())
+        keys += demo.csv_keys().values()
+        if demo.match is not None:
+            stats = select(PlayerMatchStat).where(PlayerMatchStat.match_id == demo.match.id)
+            for stat in session.scalars(stats).all():
+                if stat.source == "demo":
+                    session.delete(stat)
+                else:
+                    stat.match_id = None  # imported history outlives the demo
+        local_files = [Path(demo.stored_path)] + ([Path(demo.processed_path)] if demo.processed_path else [])
+        for hook in self.delete_hooks:
+            hook(session, demo)
+        repo.delete(demo)
+
+        for key in keys:
+            self.storage.delete(key)
+        for path in local_files:
+            path.unlink(missing_ok=True)
+        shutil.rmtree(self.processor.processed_dir / demo_id, ignore_errors=True)
+
+    def purge_at(self, demo: Demo) -> datetime | None:
+        """When a trashed demo is deleted for good."""
+
+        if demo.deleted_at is None:
+            return None
+        return demo.deleted_at + timedelta(days=self.settings.trash_retention_days)
+
+    def list_trash(self, session: Session, visibility: DemoVisibility | None = None) -> List[Demo]:
+        """Soft-deleted demos, most recently deleted first."""
+
+        stmt = select(Demo).where(Demo.deleted_at.is_not(None)).order_by(Demo.deleted_at.desc())
+        if visibility is not None:
+            stmt = stmt.where(visibility.clause())
+        return list(session.scalars(stmt).all())
+
+    def restore_demo(self, session: Session, demo_id: str) -> Demo:
+        """Bring a demo back from the trash with its match, stats and files."""
+
+        repo = DemoRepository(session)
+        demo = repo.get(demo_id, include_deleted=True)
+        if not demo or demo.deleted_at is None:
+            raise LookupError("Demo not found in trash")
+        demo.deleted_at = None
+        return repo.save(demo)
+
+    def purge_trash(self, session: Session, now: datetime | None = None) -> int:
+        """Permanently delete demos that have been in the trash longer than ``trash_retention_days``."""
+
+        cutoff = (now or datetime.utcnow()) - timedelta(days=self.settings.trash_retention_days)
+        expired = session.scalars(select(Demo.id).where(Demo.deleted_at.is_not(None), Demo.deleted_at <= cutoff)).all()
+        for demo_id in expired:
+            try:
+                self.delete_demo(session, demo_id)
+            except Exception:  # noqa: BLE001 - one broken demo must not stop the purge
+                session.rollback()
+                logger.exception("Could not purge demo %s from the trash", demo_id)
+        return len(expired)
+
+    def artifact_keys(self, session: Session, demo_id: str, include_deleted: bool = False) -> Dict[str, str]:
+        """Map downloadable artefact names (``summary`` plus dataset names) to storage keys."""
+
+        demo = DemoRepository(session).get(demo_id, include_deleted=include_deleted)
+        if not demo:
+            raise LookupError("Demo not found")
+        return demo.artifact_keys()
+
+    def export_files(
+        self, demo: Demo, export_format: str, datasets: Optional[Sequence[str]] = None
+    ) -> List[Tuple[str, Iterator[bytes]]]:
+        """File names and lazily read contents of a match export, for :func:`~.csv_export.zip_stream`.
+
+        ``csv`` exports use the stored ``.csv.gz`` copies where the demo has
+        them and convert the parquet files on the fly otherwise; ``parquet``
+        exports hold the files as stored. Raises ``ValueError`` for datasets
+        the demo does not have.
+        """
+
+        keys = {name: key for name, key in demo.artifact_keys().items() if self.storage.exists(key)}
+        names = list(dict.fromkeys(datasets)) if datasets else list(keys)
+        unknown = [name for name in names if name not in keys]
+        if unknown:
+            raise ValueError(f"Unknown datasets: {', '.join(unknown)}")
+
+        csv_keys = demo.csv_keys()
+        files: List[Tuple[str, Iterator[bytes]]] = []
+        for name in names:
+            if export_format == "parquet":
+                files.append((f"{name}.parquet", file_chunks(self.storage.get(keys[name]))))
+            elif name in csv_keys and self.storage.exists(csv_keys[name]):
+                files.append((f"{name}{CSV_SUFFIX}", file_chunks(self.storage.get(csv_keys[name]))))
+            else:
+                files.append((f"{name}{CSV_SUFFIX}", gzip_chunks(csv_chunks(self.storage.get(keys[name])))))
+        return files
+
+    @staticmethod
+    def row_group_ranges(demo: Demo) -> Dict[str, List[Dict[str, Any]]]:
+        """Recorded row group ranges of the demo's datasets, for those written with per-round row groups."""
+
+        manifest = (demo.extra_metadata or {}).get("artifact_manifest") or {}
+        return {
+            name: manifest[key]["row_groups"]
+            for name, key in demo.artifact_keys().items()
+            if (manifest.get(key) or {}).get("row_groups")
+        }
+
+    def schema_versions(self, session: Session, demo_id: str) -> Dict[str, int]:
+        """Parquet layout version of each artefact, as recorded when the demo was processed."""
+
+        demo = DemoRepository(session).get(demo_id)
+        if not demo:
+            raise LookupError("Demo not found")
+        return dict((demo.extra_metadata or {}).get("schema_versions") or {})
+
+    def assign_competition(
+        self, session: Session, demo_id: str, competition_id: str | None, series: str | None = None
+    ) -> Demo:
+        """Attach a demo to a competition (and optional series) after upload."""
+
+        repo = DemoRepository(session)
+        demo = repo.get(demo_id)
+        if not demo:
+            raise LookupError("Demo not found")
+        self._require_competition(session, competition_id)
+        demo.competition_id = competition_id
+        demo.series = series if competition_id else None
+        return repo.save(demo)
+
+    def import_hltv_result(
+        self, session: Session, demo_id: str, url: str, map_name: str | None = None
+    ) -> Demo:
+        """Enrich a demo's match record with teams, event and score from an HLTV match page.
+
+        The HLTV map is chosen by the demo's parsed map (or ``map_name``). Team
+        order follows the demo: HLTV teams are aligned by map score first and by
+        name second, and the HLTV names replace the in-game clan names.
+        """
+
+        repo = DemoRepository(session)
+        demo = repo.get(demo_id)
+        if not demo:
+            raise LookupError("Demo not found")
+
+        result = self.hltv.fetch_match(url)
+        match = demo.match
+        target_map = (match.map_name if match else None) or (demo.extra_metadata or {}).get("map_name") or map_name
+        map_result = result.map_result(target_map)
+        if map_result is None:
+            played = ", ".join(m.map_name for m in result.maps) or "none"
+            raise ValueError(f"HLTV match has no result for map {target_map or '(unknown)'}; maps played: {played}")
+
+        if match is None:
+            match = Match(
+                map_name=map_result.map_name,
+                played_at=result.played_at or demo.uploaded_at,
+                team_a=result.team_1,
+                team_b=result.team_2,
+                source="hltv",
+                artifacts={},
+                players=[],
+            )
+            demo.match = match
+
+        swapped = self._hltv_teams_swapped(match, result, map_result.score_1, map_result.score_2)
+        team_a, team_b = (result.team_2, result.team_1) if swapped else (result.team_1, result.team_2)
+        score_a, score_b = (
+            (map_result.score_2, map_result.score_1) if swapped else (map_result.score_1, map_result.score_2)
+        )
+        renames = {match.team_a: team_a, match.team_b: team_b}
+        for player in match.players:
+            player.team_name = renames.get(player.team_name, player.team_name)
+
+        match.map_name = map_result.map_name
+        match.team_a, match.team_b = team_a, team_b
+        match.score_a, match.score_b = score_a, score_b
+        match.winner = team_a if score_a > score_b else team_b if score_b > score_a else None
+        match.rounds = match.rounds or score_a + score_b
+        match.event_name = result.event
+        match.hltv_url = result.url
+        if result.played_at:
+            match.played_at = result.played_at
+        demo.extra_metadata = {**(demo.extra_metadata or {}), "hltv": result.to_metadata()}
+        return repo.save(demo)
+
+    @staticmethod
+    def _hltv_teams_swapped(match: Match, result: HltvMatch, score_1: int, score_2: int) -> bool:
+        # A parsed scoreline pins down the order unless it is a draw; otherwise fall back to names.
+        if match.source != "hltv" and match.score_a != match.score_b:
+            if (match.score_a, match.score_b) == (score_2, score_1):
+                return True
+            if (match.score_a, match.score_b) == (score_1, score_2):
+                return False
+        return _same_team(match.team_a, result.team_2) or _same_team(match.team_b, result.team_1)
+
+    @staticmethod
+    def _require_competition(session: Session, competition_id: str | None) -> None:
+        if competition_id and not CompetitionRepository(session).get(competition_id):
+            raise ValueError(f"Competition {competition_id} does not exist")
+
+    def _match_start(self, demo: Demo) -> datetime | None:
+        return match_start_from_filename(self.settings, demo.original_filename)
+
+    def default_visibility(self, owner_id: str | None, team_id: str | None) -> str:
+        """``team`` for team uploads, ``default_demo_visibility`` for other signed-in uploads, else ``public``."""
+
+        if team_id:
+            return "team"
+        return self.settings.default_demo_visibility if owner_id else "public"
+
+    def set_visibility(self, session: Session, demo_id: str, visibility: str) -> Demo:
+        repo = DemoRepository(session)
+        demo = repo.get(demo_id)
+        if not demo:
+            raise LookupError("Demo not found")
+        self._require_visibility(visibility, demo.team_id)
+        demo.visibility = visibility
+        return repo.save(demo)
+
+    @staticmethod
+    def _require_visibility(visibility: str | None, team_id: str | None) -> None:
+        if visibility is None:
+            return
+        if visibility not in VISIBILITY_LEVELS:
+            raise ValueError(f"Unknown visibility {visibility}; choose one of {', '.join(VISIBILITY_LEVELS)}")
+        if visibility == "team" and not team_id:
+            raise ValueError("Only demos uploaded for a team can be shared with a team")
+
+    def storage_region(self, session: Session, team_id: str | None) -> str | None:
+        """The residency region new uploads of ``team_id`` are stored in, if the team is pinned to one."""
+
+        team = session.get(Team, team_id) if team_id else None
+        region = team.storage_region if team else None
+        if region and region not in self.settings.storage_regions:
+            raise ValueError(f"Storage region {region} is not configured")
+        return region
+
+    def set_storage_region(self, session: Session, team_id: str, region: str | None) -> Team:
+        """Pin a team's future uploads to a region; artefacts stored before stay where they are."""
+
+        team = session.get(Team, team_id)
+        if not team:
+            raise LookupError("Team not found")
+        if region and region not in self.settings.storage_regions:
+            raise ValueError(f"Storage region {region} is not configured")
+        team.storage_region = region
+        session.commit()
+        return team
+
+    def list_storage_regions(self, session: Session) -> List[Dict[str, Any]]:
+        """Configured regions with their backend, location and the teams pinned to them."""
+
+        pinned: Dict[str, List[str]] = {}
+        for team in session.scalars(select(Team).where(Team.storage_region.is_not(None)).order_by(Team.name)):
+            pinned.setdefault(team.storage_region, []).append(team.id)
+        regions = []
+        for name, config in sorted(self.settings.storage_regions.items()):
+            backend = str(config.get("backend", "s3")).lower()
+            if backend == "local":
+                location = config.get("path")
+            else:
+                prefix = str(config.get("prefix", "")).strip("/")
+                location = f"s3://{config.get('bucket')}" + (f"/{prefix}" if prefix else "")
+            regions.append(
+                {
+                    "name": name,
+                    "backend": backend,
+                    "location": location,
+                    "region": config.get("region"),
+                    "team_ids": pinned.get(name, []),
+                }
+            )
+        return regions
+
+    @staticmethod
+    def _require_team(session: Session, team_id: str | None) -> None:
+        if team_id and not session.get(Team, team_id):
+            raise ValueError(f"Team {team_id} does not exist")
+
+    @staticmethod
+    def _require_free_external_id(session: Session, external_match_id: str | None, demo: Demo | None) -> None:
+        if not external_match_id:
+            return
+        match = session.scalars(select(Match).where(Match.external_id == external_match_id)).first()
+        if match is not None and (demo is None or match.demo_id != demo.id):
+            raise ValueError(f"External match ID {external_match_id} is already used by demo {match.demo_id}")
+
+    def _disabled_datasets(self, session: Session, team_id: str | None) -> frozenset[str]:
+        """Experimental datasets whose flag is off for the uploading team."""
+
+        if self.feature_flags is None:
+            return frozenset()
+        return frozenset(
+            name
+            for name, flag in EXPERIMENTAL_DATASETS.items()
+            if not self.feature_flags.is_enabled(session, flag, team_id=team_id, default=True)
+        )
 
     async def _stream_to_disk(self, upload: UploadFile) -> Tuple[str, Path, int]:
         checksum = hashlib.sha256()
@@ -92,14 +1396,51 @@ class DemoService:
                 chunk = await upload.read(self.chunk_size)
                 if not chunk:
                     break
+                if total_size == 0:
+                    # Junk renamed to .dem is turned away before it reaches the disk.
+                    try:
+                        check_upload_header(chunk)
+                    except ValueError:
+                        buffer.close()
+                        temp_path.unlink(missing_ok=True)
+                        raise
                 total_size += len(chunk)
                 if total_size > self.settings.max_upload_size:
                     buffer.close()
                     temp_path.unlink(missing_ok=True)
-                    raise ValueError("Uploaded file exceeds maximum allowed size")
+                    raise UploadTooLargeError("Uploaded file exceeds maximum allowed size")
                 checksum.update(chunk)
                 buffer.write(chunk)
 
         await upload.close()
 
         return checksum.hexdigest(), temp_path, total_size
+
+    def _decompress(self, archive_path: Path) -> Tuple[str, Path, int]:
+        try:
+            return unpack_demo(
+                archive_path, self.settings.raw_data_path, self.settings.max_upload_size, chunk_size=self.chunk_size
+            )
+        finally:
+            archive_path.unlink(missing_ok=True)
+
+
+def _external_metadata(external_match_id: str | None, external_source: str | None) -> Dict[str, str]:
+    if not external_match_id:
+        return {}
+    return {"external_match_id": external_match_id, **({"external_source": external_source} if external_source else {})}
+
+
+def _same_team(in_game: str, listed: str) -> bool:
+    """In-game clan tags are often shortened versions of HLTV names ("Vitality" vs "Team Vitality")."""
+
+    a, b = in_game.casefold(), listed.casefold()
+    return a == b or a in b or b in a
+
+
+def file_sha256(path: Path, chunk_size: int = 1024 * 1024) -> str:
+    digest = hashlib.sha256()
+    with path.open("rb") as handle:
+        while chunk := handle.read(chunk_size):
+            digest.update(chunk)
+    return digest.hexdigest()
@@ -0,0 +1,116 @@
+"""CS match sharecodes and resolving them to Valve replay URLs.
+
+A sharecode (``CSGO-xxxxx-xxxxx-xxxxx-xxxxx-xxxxx``) packs a matchmaking
+game's match id, outcome (reservation) id and TV port into 25 characters of a
+57-letter alphabet. The replay URL itself is only known to the Game
+Coordinator, which answers a full-game-info request with the match's round
+stats; the last round's ``map`` field is the ``.dem.bz2`` link on Valve's
+replay servers. Talking to the GC needs a logged-in Steam client, so the
+lookup goes through a GC bridge (``STEAM_GC_URL``) that forwards the request
+and returns the ``CMsgGCCStrike15_v2_MatchList`` reply as JSON.
+"""
+
+from __future__ import annotations
+
+import json
+import re
+from dataclasses import dataclass
+from typing import Optional
+from urllib.error import HTTPError, URLError
+from urllib.parse import urlencode, urlparse
+from urllib.request import Request, urlopen
+
+DICTIONARY = "ABCDEFGHJKLMNOPQRSTUVWXYZabcdefhijkmnopqrstuvwxyz23456789"
+_SHARECODE = re.compile(rf"^CSGO(-[{DICTIONARY}]{{5}}){{5}}$")
+
+
+@dataclass(frozen=True)
+class Sharecode:
+    match_id: int
+    outcome_id: int
+    token: int  # the TV port
+
+
+def decode_sharecode(code: str) -> Sharecode:
+    """Unpack a sharecode; raises ``ValueError`` if it is not one."""
+
+    code = code.strip()
+    if not _SHARECODE.match(code):
+        raise ValueError("Not a valid match sharecode")
+    value = 0
+    for char in reversed(code[5:].replace("-", "")):
+        value = value * len(DICTIONARY) + DICTIONARY.index(char)
+    if value >= 1 << 144:
+        raise ValueError("Not a valid match sharecode")
+    packed = value.to_bytes(18, "big")
+    return Sharecode(
+        match_id=int.from_bytes(packed[0:8], "little"),
+        outcome_id=int.from_bytes(packed[8:16], "little"),
+        token=int.from_bytes(packed[16:18], "little"),
+    )
+
+
+def encode_sharecode(sharecode: Sharecode) -> str:
+    packed = (
+        sharecode.match_id.to_bytes(8, "little")
+        + sharecode.outcome_id.to_bytes(8, "little")
+        + sharecode.token.to_bytes(2, "little")
+    )
+    value = int.from_bytes(packed, "big")
+    chars = []
+    for _ in range(25):
+        value, index = divmod(value, len(DICTIONARY))
+        chars.append(DICTIONARY[index])
+    code = "".join(chars)
+    return "CSGO-" + "-".join(code[i : i + 5] for i in range(0, 25, 5))
+
+
+class SteamReplayClient:
+    """Ask the Game Coordinator, through a GC bridge, where a match's replay is stored."""
+
+    def __init__(self, gc_url: Optional[str], api_key: Optional[str] = None, timeout: float = 10.0) -> None:
+        self.gc_url = gc_url.rstrip("/") if gc_url else None
+        self.api_key = api_key
+        self.timeout = timeout
+
+    @property
+    def enabled(self) -> bool:
+        return self.gc_url is not None
+
+    def replay_url(self, sharecode: Sharecode) -> str:
+        """The replay URL of a match; ``LookupError`` once Valve no longer keeps it."""
+
+        if self.gc_url is None:
+            raise ValueError("Sharecode ingestion is not configured; set STEAM_GC_URL")
+        params = {"outcomeid": sharecode.outcome_id, "token": sharecode.token}
+        if self.api_key:
+            params["key"] = self.api_key
+        reply = self._get(f"{self.gc_url}/matches/{sharecode.match_id}?{urlencode(params)}")
+        if reply is None:
+            raise LookupError("Match not found on the Game Coordinator")
+        return replay_url_from_match_list(reply)
+
+    def _get(self, url: str) -> Optional[dict]:
+        request = Request(url, headers={"User-Agent": "StratagemForge/1.0", "Accept": "application/json"})
+        try:
+            with urlopen(request, timeout=self.timeout) as response:
+                return json.loads(response.read())
+        except HTTPError as exc:
+            if exc.code == 404:
+                return None
+            raise ValueError(f"Game Coordinator request failed with HTTP {exc.code}") from exc
+        except (URLError, TimeoutError, ConnectionError, json.JSONDecodeError) as exc:
+            raise ValueError(f"Game Coordinator request failed: {exc}") from exc
+
+
+def replay_url_from_match_list(reply: dict) -> str:
+    """The replay link in a GC match list: the ``map`` of the first match's last round."""
+
+    matches = reply.get("matches") or []
+    rounds = (matches[0].get("roundstatsall") or []) if matches else []
+    url = rounds[-1].get("map") if rounds else None
+    if not url:
+        raise LookupError("The Game Coordinator has no replay for this match; replays expire after about a month")
+    if urlparse(url).scheme not in {"http", "https"}:
+        raise ValueError("The Game Coordinator returned an invalid replay URL")
+    return url
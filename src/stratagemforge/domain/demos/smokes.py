@@ -0,0 +1,109 @@
+"""When and where smokes block vision, for tagging kills and shots through them.
+
+A smoke occupies a cylinder of ``SMOKE_RADIUS`` around its detonation point
+from the detonation until its expiry event, or for ``SMOKE_DURATION_SECONDS``
+when the demo misses the expiry, and never past the end of its round. Lines
+of fire are checked on the map plane: a kill or shot counts as through smoke
+when the line from the shooter crosses (or starts or ends in) an active
+smoke. Height is ignored, so a smoke on a lower level can tag a shot above it.
+"""
+
+from __future__ import annotations
+
+import math
+from typing import Dict, List
+
+import pandas as pd
+
+# Used when a smoke's expiry event is missing from the demo (e.g. the round ended first).
+SMOKE_DURATION_SECONDS = 20
+# Radius of a bloomed CS2 smoke on the map plane, in world units.
+SMOKE_RADIUS = 144.0
+# How far a shot is followed along the view direction when checking for smokes.
+SHOT_RANGE = 4096.0
+SMOKE_INTERVAL_COLUMNS = ("round", "steamid", "entity_id", "start_tick", "end_tick", "x", "y")
+
+
+def smoke_intervals(grenade_events: pd.DataFrame, rounds: pd.DataFrame, tick_rate: float) -> pd.DataFrame:
+    """One row per detonated smoke with the ticks it was active and its position."""
+
+    events = grenade_events.reindex(columns=["tick", "round", "steamid", "grenade", "event", "entity_id", "x", "y"])
+    smokes = events[(events["grenade"] == "smoke") & (events["event"] == "detonate")]
+    if smokes.empty:
+        return pd.DataFrame(columns=list(SMOKE_INTERVAL_COLUMNS))
+
+    expired = events[(events["grenade"] == "smoke") & (events["event"] == "expired")]
+    expiries: Dict[object, List[int]] = {}
+    for row in expired.dropna(subset=["entity_id"]).itertuples(index=False):
+        expiries.setdefault(row.entity_id, []).append(int(row.tick))
+    round_ends = dict(zip(rounds["round"], rounds["end_tick"])) if not rounds.empty else {}
+
+    rows = []
+    for smoke in smokes.itertuples(index=False):
+        start = int(smoke.tick)
+        end = next((tick for tick in sorted(expiries.get(smoke.entity_id, [])) if tick >= start), None)
+        if end is None:
+            end = start + int(SMOKE_DURATION_SECONDS * tick_rate)
+        round_end = round_ends.get(smoke.round)
+        if round_end is not None and pd.notna(round_end):
+            end = min(end, int(round_end))
+        rows.append(
+            {
+                "round": smoke.round,
+                "steamid": smoke.steamid,
+                "entity_id": smoke.entity_id,
+                "start_tick": start,
+                "end_tick": max(end, start),
+                "x": smoke.x,
+                "y": smoke.y,
+            }
+        )
+    return pd.DataFrame(rows, columns=list(SMOKE_INTERVAL_COLUMNS))
+
+
+def lines_through_smoke(lines: pd.DataFrame, smokes: pd.DataFrame) -> pd.Series:
+    """Whether each line (``tick``, ``x1``, ``y1``, ``x2``, ``y2``) passes through a smoke active at its tick.
+
+    Lines with a missing end point never count.
+    """
+
+    through = pd.Series(False, index=lines.index)
+    centres = smokes.dropna(subset=["x", "y"])
+    if lines.empty or centres.empty:
+        return through
+    cx, cy = pd.to_numeric(centres["x"]).astype(float), pd.to_numeric(centres["y"]).astype(float)
+    for index, line in lines.iterrows():
+        coords = pd.to_numeric(line[["x1", "y1", "x2", "y2"]], errors="coerce")
+        if coords.isna().any():
+            continue
+        active = (centres["start_tick"] <= line["tick"]) & (line["tick"] <= centres["end_tick"])
+        if not active.any():
+            continue
+        distances = _distance_to_segment(cx[active], cy[active], *coords.astype(float).tolist())
+        through[index] = bool((distances <= SMOKE_RADIUS).any())
+    return through
+
+
+def shot_lines(shots: pd.DataFrame) -> pd.DataFrame:
+    """Lines of fire ``SHOT_RANGE`` long from each shot's ``x``/``y`` along its ``yaw`` (degrees)."""
+
+    yaw = pd.to_numeric(shots["yaw"], errors="coerce").astype(float).map(math.radians)
+    x = pd.to_numeric(shots["x"], errors="coerce").astype(float)
+    y = pd.to_numeric(shots["y"], errors="coerce").astype(float)
+    return pd.DataFrame(
+        {
+            "tick": shots["tick"],
+            "x1": x,
+            "y1": y,
+            "x2": x + SHOT_RANGE * yaw.map(math.cos),
+            "y2": y + SHOT_RANGE * yaw.map(math.sin),
+        },
+        index=shots.index,
+    )
+
+
+def _distance_to_segment(px: pd.Series, py: pd.Series, x1: float, y1: float, x2: float, y2: float) -> pd.Series:
+    dx, dy = x2 - x1, y2 - y1
+    length = dx * dx + dy * dy
+    t = 0.0 if length == 0 else (((px - x1) * dx + (py - y1) * dy) / length).clip(0.0, 1.0)
+    return ((px - (x1 + t * dx)) ** 2 + (py - (y1 + t * dy)) ** 2) ** 0.5
@@ -0,0 +1,114 @@
+"""Simplified player paths for plotting, as GeoJSON-like feature collections.
+
+Each feature is one player's path through one round while alive: a
+``LineString`` of ``[x, y, z]`` world positions reduced with Douglas-Peucker,
+so straight runs collapse to their end points and only the turns remain. The
+``ticks`` property holds the tick of every kept point. Built from the
+``player_ticks`` dataset, so plotting a match no longer means downsampling
+its tick rows client-side.
+"""
+
+from __future__ import annotations
+
+import math
+from typing import Any, Dict, List, Optional, Sequence
+
+import pandas as pd
+
+# Largest distance in world units (about an inch) a dropped point may lie from the simplified path.
+DEFAULT_TOLERANCE = 24.0
+
+
+def simplify(points: Sequence[Sequence[float]], tolerance: float) -> List[int]:
+    """Indexes of the points Douglas-Peucker keeps, measuring distances on the x/y plane.
+
+    The first and last points are always kept. Iterative, so paths of any
+    length are safe.
+    """
+
+    if len(points) <= 2:
+        return list(range(len(points)))
+    keep = {0, len(points) - 1}
+    stack = [(0, len(points) - 1)]
+    while stack:
+        first, last = stack.pop()
+        farthest, distance = None, tolerance
+        for index in range(first + 1, last):
+            offset = _distance_to_segment(points[index], points[first], points[last])
+            if offset > distance:
+                farthest, distance = index, offset
+        if farthest is not None:
+            keep.add(farthest)
+            stack.extend([(first, farthest), (farthest, last)])
+    return sorted(keep)
+
+
+def build_trajectories(
+    player_ticks: pd.DataFrame,
+    tolerance: float = DEFAULT_TOLERANCE,
+    round_number: Optional[int] = None,
+    steamid: Optional[str] = None,
+) -> Dict[str, Any]:
+    """A feature collection with one simplified path per player and round, ordered by round, side and name."""
+
+    frame = player_ticks.dropna(subset=["steamid", "round", "x", "y"])
+    if "is_alive" in frame:
+        frame = frame[frame["is_alive"].fillna(True).astype(bool)]
+    if round_number is not None:
+        frame = frame[frame["round"] == round_number]
+    if steamid is not None:
+        frame = frame[frame["steamid"].astype(str) == str(steamid)]
+
+    features: List[Dict[str, Any]] = []
+    total = kept = 0
+    for (round_value, player), rows in frame.sort_values("tick").groupby(["round", "steamid"], sort=False):
+        points = [
+            [round(float(x), 1), round(float(y), 1), None if pd.isna(z) else round(float(z), 1)]
+            for x, y, z in zip(rows["x"], rows["y"], rows["z"] if "z" in rows else [None] * len(rows))
+        ]
+        indexes = simplify(points, tolerance)
+        if len(indexes) < 2:
+            continue
+        ticks = rows["tick"].tolist()
+        last = rows.iloc[-1]
+        total, kept = total + len(points), kept + len(indexes)
+        features.append(
+            {
+                "type": "Feature",
+                "geometry": {"type": "LineString", "coordinates": [points[index] for index in indexes]},
+                "properties": {
+                    "round": int(round_value),
+                    "steamid": str(player),
+                    "name": _text(last.get("name")),
+                    "side": _text(last.get("side")),
+                    "ticks": [int(ticks[index]) for index in indexes],
+                    "source_points": len(points),
+                },
+            }
+        )
+    features.sort(
+        key=lambda feature: (
+            feature["properties"]["round"],
+            feature["properties"]["side"] or "",
+            feature["properties"]["name"] or "",
+            feature["properties"]["steamid"],
+        )
+    )
+    return {
+        "type": "FeatureCollection",
+        "properties": {"tolerance": tolerance, "source_points": total, "points": kept},
+        "features": features,
+    }
+
+
+def _distance_to_segment(point: Sequence[float], start: Sequence[float], end: Sequence[float]) -> float:
+    dx, dy = end[0] - start[0], end[1] - start[1]
+    length = dx * dx + dy * dy
+    if length == 0:
+        return math.hypot(point[0] - start[0], point[1] - start[1])
+    share = max(0.0, min(1.0, ((point[0] - start[0]) * dx + (point[1] - start[1]) * dy) / length))
+    return math.hypot(point[0] - (start[0] + share * dx), point[1] - (start[1] + share * dy))
+
+
+def _text(value: Any) -> Optional[str]:
+    return None if value is None or pd.isna(value) else str(value)
@@ -0,0 +1,142 @@
+from __future__ import annotations
+
+import hashlib
+import hmac
+import json
+import logging
+import time
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+from urllib.error import HTTPError, URLError
+from urllib.parse import urlparse
+from urllib.request import Request
+
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.netguard import allowed_hosts, check_public_url, public_opener
+from ...core.timeutil import isoformat_utc
+from .models import Demo
+from .schemas import DemoProcessingStatus
+
+logger = logging.getLogger(__name__)
+
+SIGNATURE_HEADER = "X-StratagemForge-Signature"
+TIMESTAMP_HEADER = "X-StratagemForge-Timestamp"
+EVENT_HEADER = "X-StratagemForge-Event"
+
+
+def validate_callback_url(url: Optional[str], allowed: frozenset[str] = frozenset()) -> None:
+    """Refuse callback URLs that are not http(s) or lead to internal addresses, unless their host is ``allowed``."""
+
+    if not url:
+        return
+    if urlparse(url).scheme not in {"http", "https"}:
+        raise ValueError("Only http and https callback URLs are supported")
+    check_public_url(url, allowed)
+
+
+def sign(secret: str, timestamp: str, body: bytes) -> str:
+    """``sha256=<hex>`` HMAC over ``<timestamp>.<body>``, as sent in the signature header."""
+
+    digest = hmac.new(secret.encode(), timestamp.encode() + b"." + body, hashlib.sha256).hexdigest()
+    return f"sha256={digest}"
+
+
+class WebhookNotifier:
+    """POST the processing result to callback URLs once a demo is done.
+
+    Every demo goes to the global ``WEBHOOK_URL`` (if set) and to the
+    ``callback_url`` given with its upload. Bodies are signed with
+    ``WEBHOOK_SECRET`` so receivers can check where they came from. Only
+    public addresses are reached, as for demo downloads. A failed delivery is
+    retried on 5xx and network errors, then recorded on the demo; it never
+    fails the upload. Deliveries run on the processing worker, so each one,
+    retries included, gives up after ``WEBHOOK_RETRY_BUDGET_SECONDS``.
+    """
+
+    def __init__(self, settings: Settings, backoff_seconds: float = 1.0) -> None:
+        self.settings = settings
+        self.backoff_seconds = backoff_seconds
+        self._opener = public_opener(allowed_hosts(settings.outbound_allowed_hosts))
+
+    def urls_for(self, demo: Demo) -> List[str]:
+        urls = [self.settings.webhook_url, (demo.extra_metadata or {}).get("callback_url")]
+        return list(dict.fromkeys(url for url in urls if url))
+
+    def notify_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: deliver the result to every callback URL of the demo."""
+
+        urls = self.urls_for(demo)
+        if not urls:
+            return
+        event = "demo.failed" if (demo.extra_metadata or {}).get("parse_status") == "failed" else "demo.processed"
+        body = json.dumps({"event": event, "result": _result(demo).model_dump(mode="json")}, sort_keys=True).encode()
+        deliveries = [self.deliver(url, event, body) for url in urls]
+        try:
+            demo.extra_metadata = {**(demo.extra_metadata or {}), "webhook_deliveries": deliveries}
+            session.commit()
+        except Exception:  # noqa: BLE001 - the demo is stored either way
+            session.rollback()
+            logger.exception("Could not record webhook deliveries for demo %s", demo.id)
+
+    def deliver(self, url: str, event: str, body: bytes) -> Dict[str, Any]:
+        """POST ``body`` to ``url`` and describe the outcome."""
+
+        deadline = time.monotonic() + self.settings.webhook_retry_budget_seconds
+        attempt = 0
+        while True:
+            attempt += 1
+            outcome: Dict[str, Any] = {"url": url, "event": event, "attempts": attempt}
+            try:
+                outcome["status_code"] = self._post(url, event, body, deadline - time.monotonic())
+                outcome["delivered_at"] = isoformat_utc(datetime.utcnow())
+                return outcome
+            except HTTPError as exc:
+                outcome.update(status_code=exc.code, error=f"HTTP {exc.code}")
+                if exc.code < 500 or attempt > self.settings.webhook_max_retries:
+                    break
+            except ValueError as exc:  # an internal address, here or after a redirect; retrying will not help
+                outcome["error"] = str(exc)
+                break
+            except (URLError, TimeoutError, ConnectionError) as exc:
+                outcome["error"] = str(exc)
+                if attempt > self.settings.webhook_max_retries:
+                    break
+
+            delay = self.backoff_seconds * 2 ** (attempt - 1)
+            if time.monotonic() + delay >= deadline:
+                break
+            logger.warning("Retrying webhook to %s in %.1fs (attempt %d)", url, delay, attempt)
+            time.sleep(delay)
+
+        logger.error("Webhook %s to %s failed: %s", event, url, outcome["error"])
+        return outcome
+
+    def _post(self, url: str, event: str, body: bytes, remaining: float) -> int:
+        timestamp = str(int(time.time()))
+        headers = {
+            "Content-Type": "application/json",
+            "User-Agent": "StratagemForge/1.0",
+            EVENT_HEADER: event,
+            TIMESTAMP_HEADER: timestamp,
+        }
+        if self.settings.webhook_secret:
+            headers[SIGNATURE_HEADER] = sign(self.settings.webhook_secret, timestamp, body)
+        request = Request(url, data=body, headers=headers, method="POST")
+        timeout = max(0.1, min(self.settings.webhook_timeout_seconds, remaining))
+        with self._opener.open(request, timeout=timeout) as response:
+            return response.status
+
+
+def _result(demo: Demo) -> DemoProcessingStatus:
+    metadata = demo.extra_metadata or {}
+    failed = metadata.get("parse_status") == "failed"
+    return DemoProcessingStatus(
+        demo_id=demo.id,
+        status=demo.status,
+        message="Demo could not be parsed" if failed else "Demo is processed",
+        processed_at=demo.processed_at,
+        processed_path=demo.processed_path,
+        extra_metadata={key: value for key, value in metadata.items() if key != "webhook_deliveries"},
+    )
@@ -0,0 +1,25 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Any, Dict, Optional
+
+from sqlalchemy import DateTime, Integer, JSON, String, Text
+from sqlalchemy.orm import Mapped, mapped_column
+
+from ...core.database import Base
+from ...core.ids import new_ulid
+
+
+class OutboxMessage(Base):
+    """An event written in the same transaction as the change it describes, published afterwards."""
+
+    __tablename__ = "outbox_messages"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_ulid)
+    topic: Mapped[str] = mapped_column(String(255), nullable=False, index=True)
+    key: Mapped[Optional[str]] = mapped_column(String(255))
+    payload: Mapped[Dict[str, Any]] = mapped_column(JSON, nullable=False)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False, index=True)
+    published_at: Mapped[Optional[datetime]] = mapped_column(DateTime, index=True)
+    attempts: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    last_error: Mapped[Optional[str]] = mapped_column(Text)
@@ -0,0 +1,86 @@
+from __future__ import annotations
+
+import asyncio
+import json
+from typing import Optional, Protocol, Sequence
+
+from ...core.config import Settings
+from .models import OutboxMessage
+
+
+class EventPublisher(Protocol):
+    """Hands outbox messages to a message broker; raises if any of them could not be delivered."""
+
+    name: str
+
+    def publish(self, messages: Sequence[OutboxMessage]) -> None:
+        ...
+
+
+def encode(message: OutboxMessage) -> bytes:
+    return json.dumps(message.payload, sort_keys=True).encode()
+
+
+class KafkaPublisher:
+    """Publish to Kafka through ``kafka-python``, waiting for every write to be acknowledged."""
+
+    name = "kafka"
+
+    def __init__(self, bootstrap_servers: str, timeout: float = 10.0) -> None:
+        from kafka import KafkaProducer
+
+        self.timeout = timeout
+        self.producer = KafkaProducer(bootstrap_servers=bootstrap_servers.split(","), acks="all")
+
+    def publish(self, messages: Sequence[OutboxMessage]) -> None:
+        futures = [
+            self.producer.send(
+                message.topic,
+                key=message.key.encode() if message.key else None,
+                value=encode(message),
+                headers=[("message_id", message.id.encode())],
+            )
+            for message in messages
+        ]
+        self.producer.flush(timeout=self.timeout)
+        for future in futures:
+            future.get(timeout=self.timeout)
+
+
+class NatsPublisher:
+    """Publish to NATS through ``nats-py``; ``Nats-Msg-Id`` lets JetStream drop redeliveries."""
+
+    name = "nats"
+
+    def __init__(self, servers: str, timeout: float = 10.0) -> None:
+        import nats  # noqa: F401 - fail at startup rather than on the first relay
+
+        self.servers = servers.split(",")
+        self.timeout = timeout
+
+    def publish(self, messages: Sequence[OutboxMessage]) -> None:
+        asyncio.run(self._publish(messages))
+
+    async def _publish(self, messages: Sequence[OutboxMessage]) -> None:
+        import nats
+
+        client = await nats.connect(self.servers, connect_timeout=self.timeout)
+        try:
+            for message in messages:
+                await client.publish(message.topic, encode(message), headers={"Nats-Msg-Id": message.id})
+            await client.flush(timeout=self.timeout)
+        finally:
+            await client.close()
+
+
+def build_publisher(settings: Settings) -> Optional[EventPublisher]:
+    """Return the publisher selected by ``EVENT_BROKER``, or ``None`` when publishing is off."""
+
+    broker = (settings.event_broker or "").lower()
+    if not broker:
+        return None
+    if broker == "kafka":
+        return KafkaPublisher(settings.event_broker_url, timeout=settings.event_publish_timeout_seconds)
+    if broker == "nats":
+        return NatsPublisher(settings.event_broker_url, timeout=settings.event_publish_timeout_seconds)
+    raise ValueError(f"Unsupported event broker {settings.event_broker}")
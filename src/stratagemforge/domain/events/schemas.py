@@ -0,0 +1,20 @@
+from __future__ import annotations
+
+from typing import Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+
+class OutboxSummary(BaseModel):
+    broker: Optional[str] = Field(default=None, description="Configured broker, or null when publishing is off")
+    pending: int
+    published: int
+    oldest_pending_at: Optional[UtcDateTime] = None
+    last_error: Optional[str] = None
+
+
+class OutboxRelayResult(BaseModel):
+    published: int
+    failed: int
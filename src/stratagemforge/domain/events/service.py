@@ -0,0 +1,148 @@
+from __future__ import annotations
+
+import logging
+from datetime import datetime
+from typing import Any, Dict, Optional
+
+from sqlalchemy import func, select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.timeutil import isoformat_utc
+from ..demos.models import Demo, Match
+from ..stats.models import PlayerMatchStat
+from .models import OutboxMessage
+from .publishers import EventPublisher, build_publisher
+from .schemas import OutboxRelayResult, OutboxSummary
+
+logger = logging.getLogger(__name__)
+
+MATCH_PROCESSED = "match.processed"
+# Per-player numbers included in the match.processed stats summary.
+EVENT_STAT_FIELDS = ("kills", "deaths", "assists", "adr", "kast", "rating")
+
+
+class OutboxService:
+    """Publish domain events to a message broker through a transactional outbox.
+
+    Events are written to ``outbox_messages`` in the same transaction as the
+    rows they describe, so a committed match always has its event and a
+    rolled-back one never does. :meth:`relay` then hands pending messages to
+    the broker (Kafka or NATS) and marks them published; a failed publish is
+    retried on the next relay, so consumers see each event at least once and
+    can deduplicate on the message id.
+    """
+
+    def __init__(self, settings: Settings, publisher: EventPublisher | None = None) -> None:
+        self.settings = settings
+        self._publisher = publisher
+
+    @property
+    def enabled(self) -> bool:
+        return self._publisher is not None or bool(self.settings.event_broker)
+
+    @property
+    def publisher(self) -> EventPublisher:
+        if self._publisher is None:
+            self._publisher = build_publisher(self.settings)
+            if self._publisher is None:
+                raise ValueError("No event broker is configured")
+        return self._publisher
+
+    def enqueue(
+        self, session: Session, event: str, payload: Dict[str, Any], key: Optional[str] = None
+    ) -> OutboxMessage:
+        """Add an event to the caller's transaction; it is published once that commits."""
+
+        message = OutboxMessage(topic=f"{self.settings.event_topic_prefix}{event}", key=key, payload=payload)
+        session.add(message)
+        return message
+
+    def record_match_processed(self, session: Session, demo: Demo, match: Match) -> None:
+        """Record hook: queue ``match.processed`` alongside the match and its stats."""
+
+        stats = session.scalars(select(PlayerMatchStat).where(PlayerMatchStat.match_id == match.id)).all()
+        payload = {
+            "event": MATCH_PROCESSED,
+            "occurred_at": isoformat_utc(datetime.utcnow()),
+            "match_id": match.id,
+            "demo_id": demo.id,
+            "external_id": match.external_id,
+            "map_name": match.map_name,
+            "played_at": isoformat_utc(match.played_at),
+            "teams": [
+                {"name": match.team_a, "score": match.score_a},
+                {"name": match.team_b, "score": match.score_b},
+            ],
+            "winner": match.winner,
+            "rounds": match.rounds,
+            "storage_backend": (demo.extra_metadata or {}).get("storage_backend"),
+            "artifacts": dict(match.artifacts or {}),
+            "stats": [
+                {
+                    "steamid": stat.steamid,
+                    "name": stat.player_name,
+                    "team": stat.team_name,
+                    **{field: getattr(stat, field) for field in EVENT_STAT_FIELDS},
+                }
+                for stat in stats
+            ],
+        }
+        self.enqueue(session, MATCH_PROCESSED, payload, key=match.id)
+
+    def relay(self, session: Session, limit: Optional[int] = None) -> OutboxRelayResult:
+        """Publish pending messages, oldest first, and mark the ones the broker accepted."""
+
+        pending = list(
+            session.scalars(
+                select(OutboxMessage)
+                .where(OutboxMessage.published_at.is_(None))
+                .order_by(OutboxMessage.created_at, OutboxMessage.id)
+                .limit(limit or self.settings.event_outbox_batch_size)
+            ).all()
+        )
+        if not pending:
+            return OutboxRelayResult(published=0, failed=0)
+
+        for message in pending:
+            message.attempts += 1
+        try:
+            self.publisher.publish(pending)
+        except Exception as exc:  # noqa: BLE001 - broker clients raise their own error types
+            logger.warning("Publishing %d outbox messages failed: %s", len(pending), exc)
+            for message in pending:
+                message.last_error = str(exc)[:1000]
+            session.commit()
+            return OutboxRelayResult(published=0, failed=len(pending))
+
+        now = datetime.utcnow()
+        for message in pending:
+            message.published_at = now
+            message.last_error = None
+        session.commit()
+        return OutboxRelayResult(published=len(pending), failed=0)
+
+    def relay_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: publish the new event right away instead of waiting for the poller."""
+
+        try:
+            self.relay(session)
+        except Exception:  # noqa: BLE001 - the poller retries
+            session.rollback()
+            logger.exception("Could not relay outbox messages after demo %s", demo.id)
+
+    def summary(self, session: Session) -> OutboxSummary:
+        pending = OutboxMessage.published_at.is_(None)
+        published = OutboxMessage.published_at.is_not(None)
+        last_error = session.scalars(
+            select(OutboxMessage.last_error)
+            .where(pending, OutboxMessage.last_error.is_not(None))
+            .order_by(OutboxMessage.created_at.desc())
+        ).first()
+        return OutboxSummary(
+            broker=self.settings.event_broker,
+            pending=session.scalar(select(func.count(OutboxMessage.id)).where(pending)) or 0,
+            published=session.scalar(select(func.count(OutboxMessage.id)).where(published)) or 0,
+            oldest_pending_at=session.scalar(select(func.min(OutboxMessage.created_at)).where(pending)),
+            last_error=last_error,
+        )
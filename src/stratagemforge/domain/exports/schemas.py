@@ -0,0 +1,13 @@
+from __future__ import annotations
+
+from typing import Dict
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+
+class SheetsExportResult(BaseModel):
+    spreadsheet_id: str
+    tables: Dict[str, int] = Field(description="Rows written per tab, excluding the header")
+    exported_at: UtcDateTime
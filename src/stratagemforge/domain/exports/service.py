@@ -0,0 +1,62 @@
+from __future__ import annotations
+
+import logging
+from datetime import datetime
+from typing import List, Optional
+
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ..demos.models import Demo
+from .schemas import SheetsExportResult
+from .sheets import GoogleSheetsClient
+from .tables import EXPORT_TABLES
+
+logger = logging.getLogger(__name__)
+
+
+class SheetsExportService:
+    """Push aggregate stats tables to a Google Sheet, one tab per table."""
+
+    def __init__(self, settings: Settings, client: Optional[GoogleSheetsClient] = None) -> None:
+        self.settings = settings
+        self._client = client
+
+    @property
+    def enabled(self) -> bool:
+        return bool(self.settings.sheets_spreadsheet_id and (self._client or self.settings.sheets_credentials_file))
+
+    def table_names(self) -> List[str]:
+        names = [name.strip() for name in self.settings.sheets_tables.split(",") if name.strip()]
+        unknown = sorted(set(names) - set(EXPORT_TABLES))
+        if unknown:
+            raise ValueError(f"Unknown export tables: {', '.join(unknown)}; choose from {', '.join(EXPORT_TABLES)}")
+        return names
+
+    def export(self, session: Session) -> SheetsExportResult:
+        if not self.enabled:
+            raise ValueError("Google Sheets export is not configured; set SHEETS_SPREADSHEET_ID and SHEETS_CREDENTIALS_FILE")
+
+        client = self._get_client()
+        spreadsheet_id = self.settings.sheets_spreadsheet_id
+        rows_written = {}
+        for name in self.table_names():
+            rows = EXPORT_TABLES[name](session)
+            client.write_table(spreadsheet_id, name, rows)
+            rows_written[name] = len(rows) - 1  # header row
+        return SheetsExportResult(spreadsheet_id=spreadsheet_id, tables=rows_written, exported_at=datetime.utcnow())
+
+    def export_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: refresh the sheet, never failing the upload."""
+
+        if not (self.enabled and self.settings.sheets_export_on_ingest):
+            return
+        try:
+            self.export(session)
+        except Exception:  # noqa: BLE001 - the demo is stored either way
+            logger.exception("Google Sheets export after processing demo %s failed", demo.id)
+
+    def _get_client(self) -> GoogleSheetsClient:
+        if self._client is None:
+            self._client = GoogleSheetsClient(self.settings.sheets_credentials_file)
+        return self._client
@@ -0,0 +1,53 @@
+from __future__ import annotations
+
+from pathlib import Path
+from typing import Any, List
+from urllib.parse import quote
+
+SHEETS_SCOPE = "https://www.googleapis.com/auth/spreadsheets"
+SHEETS_API = "https://sheets.googleapis.com/v4/spreadsheets"
+
+
+class GoogleSheetsClient:
+    """Minimal Sheets v4 client authenticated with a service account key file.
+
+    Share the target spreadsheet with the service account's e-mail address.
+    Requires the ``sheets`` extra (``google-auth``).
+    """
+
+    def __init__(self, credentials_file: Path, timeout: float = 30.0) -> None:
+        from google.auth.transport.requests import AuthorizedSession
+        from google.oauth2 import service_account
+
+        credentials = service_account.Credentials.from_service_account_file(
+            str(credentials_file), scopes=[SHEETS_SCOPE]
+        )
+        self.session = AuthorizedSession(credentials)
+        self.timeout = timeout
+
+    def write_table(self, spreadsheet_id: str, sheet: str, rows: List[List[Any]]) -> None:
+        """Replace the contents of tab ``sheet`` with ``rows``, creating the tab if needed."""
+
+        base = f"{SHEETS_API}/{spreadsheet_id}"
+        self._ensure_sheet(base, sheet)
+        target = quote(f"'{sheet}'", safe="")
+        self._request("POST", f"{base}/values/{target}:clear", json={})
+        self._request(
+            "PUT",
+            f"{base}/values/{target}!A1",
+            params={"valueInputOption": "RAW"},
+            json={"values": [["" if value is None else value for value in row] for row in rows]},
+        )
+
+    def _ensure_sheet(self, base: str, sheet: str) -> None:
+        metadata = self._request("GET", base, params={"fields": "sheets.properties.title"})
+        titles = {entry["properties"]["title"] for entry in metadata.get("sheets", [])}
+        if sheet not in titles:
+            add_sheet = {"addSheet": {"properties": {"title": sheet}}}
+            self._request("POST", f"{base}:batchUpdate", json={"requests": [add_sheet]})
+
+    def _request(self, method: str, url: str, **kwargs: Any) -> dict:
+        response = self.session.request(method, url, timeout=self.timeout, **kwargs)
+        if response.status_code >= 400:
+            raise RuntimeError(f"Google Sheets API returned HTTP {response.status_code}: {response.text[:200]}")
+        return response.json() if response.content else {}
@@ -0,0 +1,86 @@
+from __future__ import annotations
+
+from collections import defaultdict
+from typing import Any, Callable, Dict, List
+
+from sqlalchemy.orm import Session
+
+from ..stats.repository import StatsRepository
+
+Table = List[List[Any]]
+
+SCOREBOARD_HEADER = [
+    "played_at", "map", "source", "match", "player", "steamid", "team",
+    "kills", "deaths", "assists", "adr", "kast", "hs_pct", "rating",
+]
+WEEKLY_HEADER = ["week", "player", "steamid", "matches", "kills", "deaths", "kd_ratio", "avg_adr", "avg_rating"]
+
+
+def build_scoreboard(session: Session) -> Table:
+    """One row per player per match, newest first."""
+
+    rows: Table = [SCOREBOARD_HEADER]
+    for stat in StatsRepository(session).list(primary_only=True):
+        rows.append(
+            [
+                stat.played_at.isoformat(sep=" ", timespec="minutes") if stat.played_at else "",
+                stat.map_name or "",
+                stat.source,
+                stat.external_match_id or stat.match_id or "",
+                stat.player_name or "",
+                stat.steamid or "",
+                stat.team_name or "",
+                stat.kills, stat.deaths, stat.assists,
+                stat.adr, stat.kast, stat.headshot_pct, stat.rating,
+            ]
+        )
+    return rows
+
+
+def build_weekly_player_stats(session: Session) -> Table:
+    """Totals per player per ISO week; rows without a match date are skipped."""
+
+    weeks: Dict[tuple[str, str], Dict[str, Any]] = defaultdict(
+        lambda: {"name": "", "steamid": "", "matches": 0, "kills": 0, "deaths": 0, "adr": [], "rating": []}
+    )
+    for stat in StatsRepository(session).list(primary_only=True):
+        if stat.played_at is None:
+            continue
+        year, week, _ = stat.played_at.isocalendar()
+        bucket = weeks[(f"{year}-W{week:02d}", stat.player_key)]
+        bucket["name"] = bucket["name"] or stat.player_name or ""
+        bucket["steamid"] = bucket["steamid"] or stat.steamid or ""
+        bucket["matches"] += 1
+        bucket["kills"] += stat.kills or 0
+        bucket["deaths"] += stat.deaths or 0
+        if stat.adr is not None:
+            bucket["adr"].append(stat.adr)
+        if stat.rating is not None:
+            bucket["rating"].append(stat.rating)
+
+    rows: Table = [WEEKLY_HEADER]
+    for (week, _), bucket in sorted(weeks.items(), key=lambda item: (item[0][0], item[1]["name"]), reverse=True):
+        rows.append(
+            [
+                week,
+                bucket["name"],
+                bucket["steamid"],
+                bucket["matches"],
+                bucket["kills"],
+                bucket["deaths"],
+                round(bucket["kills"] / bucket["deaths"], 2) if bucket["deaths"] else None,
+                _mean(bucket["adr"]),
+                _mean(bucket["rating"]),
+            ]
+        )
+    return rows
+
+
+def _mean(values: List[float]) -> float | None:
+    return round(sum(values) / len(values), 2) if values else None
+
+
+EXPORT_TABLES: Dict[str, Callable[[Session], Table]] = {
+    "scoreboard": build_scoreboard,
+    "weekly_players": build_weekly_player_stats,
+}
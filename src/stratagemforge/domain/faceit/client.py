@@ -0,0 +1,90 @@
+"""Minimal client for the FACEIT Data API (v4) and its demo download API."""
+
+from __future__ import annotations
+
+import json
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+from urllib.error import HTTPError, URLError
+from urllib.parse import quote, urlencode
+from urllib.request import Request, urlopen
+
+DATA_API_URL = "https://open.faceit.com/data/v4"
+DOWNLOAD_API_URL = "https://open.faceit.com/download/v2/demos/download-url"
+GAME = "cs2"
+
+
+class FaceitError(ValueError):
+    """FACEIT rejected a request or could not be reached."""
+
+
+class FaceitClient:
+    def __init__(self, base_url: str = DATA_API_URL, timeout: float = 10.0) -> None:
+        self.base_url = base_url.rstrip("/")
+        self.timeout = timeout
+
+    def finished_matches(self, kind: str, faceit_id: str, api_key: str, limit: int = 20) -> List[Dict[str, Any]]:
+        """Recently finished matches of a player or hub, newest first, as ``match_id`` and ``finished_at``."""
+
+        if kind == "player":
+            path = f"/players/{quote(faceit_id)}/history?" + urlencode({"game": GAME, "limit": limit})
+        else:
+            path = f"/hubs/{quote(faceit_id)}/matches?" + urlencode({"type": "past", "limit": limit})
+        items = (self._get(path, api_key) or {}).get("items") or []
+        return [
+            {
+                "match_id": item["match_id"],
+                "finished_at": datetime.utcfromtimestamp(item["finished_at"]) if item.get("finished_at") else None,
+            }
+            for item in items
+            if item.get("match_id") and str(item.get("status", "")).lower() == "finished"
+        ]
+
+    def match(self, match_id: str, api_key: str) -> Dict[str, Any]:
+        details = self._get(f"/matches/{quote(match_id)}", api_key)
+        if details is None:
+            raise LookupError(f"FACEIT match {match_id} not found")
+        return details
+
+    def player_elo(self, player_id: str, api_key: str) -> Optional[int]:
+        """A player's current FACEIT elo in CS2."""
+
+        player = self._get(f"/players/{quote(player_id)}", api_key) or {}
+        elo = ((player.get("games") or {}).get(GAME) or {}).get("faceit_elo")
+        return int(elo) if elo is not None else None
+
+    def download_url(self, resource_url: str, api_key: str) -> str:
+        """Sign a match's demo URL through the download API.
+
+        Keys without download API access get the resource URL back unchanged.
+        """
+
+        body = json.dumps({"resource_url": resource_url}).encode()
+        try:
+            reply = self._request(DOWNLOAD_API_URL, api_key, data=body)
+        except HTTPError as exc:
+            if exc.code in (401, 403, 404):
+                return resource_url
+            raise FaceitError(f"FACEIT download API failed with HTTP {exc.code}") from exc
+        return ((reply or {}).get("payload") or {}).get("download_url") or resource_url
+
+    def _get(self, path: str, api_key: str) -> Optional[Dict[str, Any]]:
+        try:
+            return self._request(f"{self.base_url}{path}", api_key)
+        except HTTPError as exc:
+            if exc.code == 404:
+                return None
+            raise FaceitError(f"FACEIT API request failed with HTTP {exc.code}") from exc
+
+    def _request(self, url: str, api_key: str, data: Optional[bytes] = None) -> Optional[Dict[str, Any]]:
+        headers = {"Authorization": f"Bearer {api_key}", "Accept": "application/json"}
+        if data is not None:
+            headers["Content-Type"] = "application/json"
+        request = Request(url, data=data, headers=headers)
+        try:
+            with urlopen(request, timeout=self.timeout) as response:
+                return json.loads(response.read())
+        except HTTPError:
+            raise
+        except (URLError, TimeoutError, ConnectionError, json.JSONDecodeError) as exc:
+            raise FaceitError(f"FACEIT API request failed: {exc}") from exc
@@ -0,0 +1,53 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import List, Optional
+
+from sqlalchemy import Boolean, DateTime, ForeignKey, Integer, String, Text, UniqueConstraint
+from sqlalchemy.orm import Mapped, mapped_column, relationship
+
+from ...core.database import Base
+from ...core.ids import new_uuid
+
+
+class FaceitConnector(Base):
+    """A FACEIT player or hub whose finished matches are pulled and ingested automatically."""
+
+    __tablename__ = "faceit_connectors"
+    __table_args__ = (UniqueConstraint("kind", "faceit_id"),)
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    kind: Mapped[str] = mapped_column(String(8), nullable=False)  # player or hub
+    faceit_id: Mapped[str] = mapped_column(String(64), nullable=False)
+    api_key: Mapped[Optional[str]] = mapped_column(String(128))  # falls back to FACEIT_API_KEY
+    enabled: Mapped[bool] = mapped_column(Boolean, default=True, nullable=False)
+    team_id: Mapped[Optional[str]] = mapped_column(String(36), index=True)  # team the pulled demos are uploaded for
+    owner_id: Mapped[Optional[str]] = mapped_column(String(64))
+    matches_pulled: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    last_polled_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    last_error: Mapped[Optional[str]] = mapped_column(Text)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+    pulls: Mapped[List["FaceitMatchPull"]] = relationship(
+        back_populates="connector", cascade="all, delete-orphan", order_by="FaceitMatchPull.pulled_at.desc()"
+    )
+
+
+class FaceitMatchPull(Base):
+    """One FACEIT match a connector found, and what became of it."""
+
+    __tablename__ = "faceit_match_pulls"
+    __table_args__ = (UniqueConstraint("connector_id", "faceit_match_id"),)
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    connector_id: Mapped[str] = mapped_column(ForeignKey("faceit_connectors.id", ondelete="CASCADE"), nullable=False)
+    faceit_match_id: Mapped[str] = mapped_column(String(64), nullable=False, index=True)
+    # ingested, skipped (no demo, or already ingested elsewhere) or failed (retried on later polls).
+    status: Mapped[str] = mapped_column(String(16), nullable=False)
+    attempts: Mapped[int] = mapped_column(Integer, default=0, nullable=False)
+    demo_id: Mapped[Optional[str]] = mapped_column(String(36))
+    error: Mapped[Optional[str]] = mapped_column(Text)
+    finished_at: Mapped[Optional[datetime]] = mapped_column(DateTime)  # when the match ended on FACEIT
+    pulled_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+    connector: Mapped[FaceitConnector] = relationship(back_populates="pulls")
@@ -0,0 +1,53 @@
+from __future__ import annotations
+
+from typing import List, Literal, Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+
+class FaceitConnectorCreate(BaseModel):
+    kind: Literal["player", "hub"]
+    faceit_id: str = Field(min_length=1, max_length=64, description="FACEIT player or hub id")
+    api_key: Optional[str] = Field(default=None, max_length=128, description="Defaults to FACEIT_API_KEY")
+    team_id: Optional[str] = Field(default=None, description="Team the pulled demos are uploaded for")
+
+
+class FaceitMatchPullSummary(BaseModel):
+    faceit_match_id: str
+    status: str = Field(description="ingested, skipped or failed (retried on later polls)")
+    attempts: int
+    demo_id: Optional[str] = None
+    error: Optional[str] = None
+    finished_at: Optional[UtcDateTime] = None
+    pulled_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class FaceitConnectorSummary(BaseModel):
+    id: str
+    kind: str
+    faceit_id: str
+    enabled: bool
+    team_id: Optional[str] = None
+    owner_id: Optional[str] = None
+    matches_pulled: int
+    last_polled_at: Optional[UtcDateTime] = None
+    last_error: Optional[str] = None
+    created_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class FaceitConnectorStatus(FaceitConnectorSummary):
+    pulls: List[FaceitMatchPullSummary] = Field(default_factory=list, description="Most recent first")
+
+
+class FaceitPollResult(BaseModel):
+    connector_id: str
+    ingested: int
+    last_error: Optional[str] = None
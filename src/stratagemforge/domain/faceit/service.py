@@ -0,0 +1,189 @@
+from __future__ import annotations
+
+import asyncio
+import logging
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ..demos.models import Demo
+from ..demos.retry import DemoProcessingError
+from ..teams.models import Team
+from .client import FaceitClient, FaceitError
+from .models import FaceitConnector, FaceitMatchPull
+
+logger = logging.getLogger(__name__)
+
+CONNECTOR_KINDS = ("player", "hub")
+# Matches ingested per connector and poll at most, oldest first; the rest follow on later polls.
+MAX_MATCHES_PER_POLL = 5
+# A match that keeps failing is given up on (marked skipped) after this many attempts.
+MAX_ATTEMPTS = 3
+
+
+class FaceitService:
+    """Poll FACEIT players and hubs for finished matches and ingest their demos.
+
+    Each pulled demo is uploaded for the connector's team with the FACEIT
+    match id as its ``faceit`` external id, and its metadata gets a ``faceit``
+    entry with the hub, region and the players' elo at the time of the pull.
+    """
+
+    def __init__(self, settings: Settings, client: FaceitClient | None = None) -> None:
+        self.settings = settings
+        self.client = client or FaceitClient(timeout=settings.download_timeout_seconds)
+
+    def create_connector(
+        self,
+        session: Session,
+        kind: str,
+        faceit_id: str,
+        api_key: str | None = None,
+        team_id: str | None = None,
+        owner_id: str | None = None,
+    ) -> FaceitConnector:
+        if kind not in CONNECTOR_KINDS:
+            raise ValueError(f"Unknown FACEIT connector kind {kind}; choose player or hub")
+        if not (api_key or self.settings.faceit_api_key):
+            raise ValueError("A FACEIT API key is required; pass api_key or set FACEIT_API_KEY")
+        if team_id and not session.get(Team, team_id):
+            raise ValueError(f"Team {team_id} does not exist")
+        existing = session.scalar(
+            select(FaceitConnector).where(FaceitConnector.kind == kind, FaceitConnector.faceit_id == faceit_id)
+        )
+        if existing:
+            raise ValueError(f"FACEIT {kind} {faceit_id} is already connected")
+        connector = FaceitConnector(kind=kind, faceit_id=faceit_id, api_key=api_key, team_id=team_id, owner_id=owner_id)
+        session.add(connector)
+        session.commit()
+        session.refresh(connector)
+        return connector
+
+    def list_connectors(self, session: Session) -> List[FaceitConnector]:
+        return list(session.scalars(select(FaceitConnector).order_by(FaceitConnector.created_at)))
+
+    def get_connector(self, session: Session, connector_id: str) -> FaceitConnector:
+        connector = session.get(FaceitConnector, connector_id)
+        if not connector:
+            raise LookupError("FACEIT connector not found")
+        return connector
+
+    def set_enabled(self, session: Session, connector_id: str, enabled: bool) -> FaceitConnector:
+        connector = self.get_connector(session, connector_id)
+        connector.enabled = enabled
+        session.commit()
+        return connector
+
+    def delete_connector(self, session: Session, connector_id: str) -> None:
+        """Stop pulling; demos already ingested are kept."""
+
+        session.delete(self.get_connector(session, connector_id))
+        session.commit()
+
+    async def poll_all(self, session: Session, demos) -> int:
+        ingested = 0
+        for connector in self.list_connectors(session):
+            if connector.enabled:
+                ingested += await self.poll(session, connector, demos)
+        return ingested
+
+    async def poll(self, session: Session, connector: FaceitConnector, demos) -> int:
+        """Ingest the connector's finished matches that were not pulled yet; returns how many were ingested.
+
+        ``demos`` is the ``DemoService`` running the upload pipeline.
+        """
+
+        api_key = connector.api_key or self.settings.faceit_api_key
+        connector.last_polled_at = datetime.utcnow()
+        try:
+            found = await asyncio.to_thread(self.client.finished_matches, connector.kind, connector.faceit_id, api_key)
+        except FaceitError as exc:
+            connector.last_error = str(exc)
+            session.commit()
+            return 0
+        connector.last_error = None
+        session.commit()
+
+        pulls = {pull.faceit_match_id: pull for pull in connector.pulls}
+        pending = [match for match in reversed(found) if _due(pulls.get(match["match_id"]))]
+        ingested = 0
+        for match in pending[:MAX_MATCHES_PER_POLL]:
+            pull = pulls.get(match["match_id"])
+            if pull is None:
+                pull = FaceitMatchPull(
+                    connector=connector, faceit_match_id=match["match_id"], finished_at=match["finished_at"]
+                )
+                session.add(pull)
+            pull.status, pull.attempts, pull.pulled_at = "failed", pull.attempts + 1, datetime.utcnow()
+            session.commit()
+            try:
+                demo = await self._ingest(session, connector, match["match_id"], demos, api_key)
+            except LookupError as exc:
+                session.rollback()
+                pull.status, pull.error = "skipped", str(exc)
+            except (ValueError, OSError, DemoProcessingError) as exc:
+                session.rollback()
+                logger.warning("Pulling FACEIT match %s failed: %s", match["match_id"], exc)
+                pull.status = "skipped" if pull.attempts >= MAX_ATTEMPTS else "failed"
+                pull.error = str(exc)
+            else:
+                if demo is None:
+                    pull.status, pull.error = "skipped", "The match has no demo"
+                else:
+                    pull.status, pull.demo_id, pull.error = "ingested", demo.id, None
+                    connector.matches_pulled += 1
+                    ingested += 1
+            session.commit()
+        return ingested
+
+    async def _ingest(
+        self, session: Session, connector: FaceitConnector, match_id: str, demos, api_key: str
+    ) -> Optional[Demo]:
+        details = await asyncio.to_thread(self.client.match, match_id, api_key)
+        resource_urls = details.get("demo_url") or []
+        if not resource_urls:
+            return None
+        url = await asyncio.to_thread(self.client.download_url, resource_urls[0], api_key)
+        faceit = await asyncio.to_thread(self._faceit_metadata, connector, details, api_key)
+        demo, _ = await demos.ingest_from_url(
+            url,
+            session,
+            team_id=connector.team_id,
+            owner_id=connector.owner_id,
+            external_match_id=match_id,
+            external_source="faceit",
+            metadata={"faceit": faceit},
+        )
+        return demo
+
+    def _faceit_metadata(self, connector: FaceitConnector, details: Dict[str, Any], api_key: str) -> Dict[str, Any]:
+        elo: Dict[str, int] = {}
+        for faction in (details.get("teams") or {}).values():
+            for player in faction.get("roster") or []:
+                if not player.get("player_id") or not player.get("game_player_id"):
+                    continue
+                try:
+                    rating = self.client.player_elo(player["player_id"], api_key)
+                except FaceitError as exc:
+                    logger.info("FACEIT elo of %s unavailable: %s", player["player_id"], exc)
+                    continue
+                if rating is not None:
+                    elo[str(player["game_player_id"])] = rating
+        hub = details.get("competition_type") == "hub"
+        return {
+            "match_id": details.get("match_id"),
+            "connector_id": connector.id,
+            "hub_id": details.get("competition_id") if hub else None,
+            "hub_name": details.get("competition_name") if hub else None,
+            "competition_type": details.get("competition_type"),
+            "region": details.get("region"),
+            "elo": elo,
+            "average_elo": round(sum(elo.values()) / len(elo)) if elo else None,
+        }
+
+
+def _due(pull: Optional[FaceitMatchPull]) -> bool:
+    return pull is None or pull.status == "failed"
@@ -0,0 +1,42 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import List, Optional
+
+from sqlalchemy import Boolean, DateTime, ForeignKey, String, Text, UniqueConstraint
+from sqlalchemy.orm import Mapped, mapped_column, relationship
+
+from ...core.database import Base
+from ...core.ids import new_uuid
+from ..teams.models import Team  # noqa: F401 - registers the teams FK target
+
+
+class FeatureFlag(Base):
+    """A named switch with a global default; teams can override it."""
+
+    __tablename__ = "feature_flags"
+
+    key: Mapped[str] = mapped_column(String(128), primary_key=True)
+    description: Mapped[Optional[str]] = mapped_column(Text)
+    enabled: Mapped[bool] = mapped_column(Boolean, default=False, nullable=False)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    updated_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+    overrides: Mapped[List["FeatureFlagOverride"]] = relationship(
+        back_populates="flag", cascade="all, delete-orphan", order_by="FeatureFlagOverride.team_id"
+    )
+
+
+class FeatureFlagOverride(Base):
+    """Per-team value for a flag, taking precedence over the global default."""
+
+    __tablename__ = "feature_flag_overrides"
+    __table_args__ = (UniqueConstraint("flag_key", "team_id"),)
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    flag_key: Mapped[str] = mapped_column(String(128), ForeignKey("feature_flags.key"), nullable=False, index=True)
+    team_id: Mapped[str] = mapped_column(String(36), ForeignKey("teams.id"), nullable=False, index=True)
+    enabled: Mapped[bool] = mapped_column(Boolean, nullable=False)
+    updated_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+    flag: Mapped[FeatureFlag] = relationship(back_populates="overrides")
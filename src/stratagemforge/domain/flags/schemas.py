@@ -0,0 +1,42 @@
+from __future__ import annotations
+
+from typing import Dict, List, Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+
+class FeatureFlagOverrideSummary(BaseModel):
+    team_id: str
+    enabled: bool
+    updated_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class FeatureFlagSummary(BaseModel):
+    key: str
+    description: Optional[str] = None
+    enabled: bool
+    created_at: UtcDateTime
+    updated_at: UtcDateTime
+    overrides: List[FeatureFlagOverrideSummary] = Field(default_factory=list)
+
+    class Config:
+        orm_mode = True
+
+
+class FeatureFlagUpdate(BaseModel):
+    enabled: bool = Field(description="Value for teams without an override")
+    description: Optional[str] = None
+
+
+class FeatureFlagOverrideUpdate(BaseModel):
+    enabled: bool
+
+
+class FeatureFlagEvaluation(BaseModel):
+    team_id: Optional[str] = None
+    flags: Dict[str, bool]
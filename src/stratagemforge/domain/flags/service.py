@@ -0,0 +1,121 @@
+from __future__ import annotations
+
+import re
+import threading
+import time
+from datetime import datetime
+from typing import Dict, List, Optional, Tuple
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session, selectinload
+
+from ...core.config import Settings
+from ..teams.models import Team
+from .models import FeatureFlag, FeatureFlagOverride
+
+_KEY = re.compile(r"^[a-z0-9][a-z0-9_.-]{0,127}$")
+
+# (global default, {team_id: override}) per flag key.
+FlagValues = Dict[str, Tuple[bool, Dict[str, bool]]]
+
+
+class FeatureFlagService:
+    """Flags stored in the database with per-team overrides.
+
+    Evaluations read from an in-process snapshot of every flag that is
+    refreshed after ``feature_flag_cache_seconds`` and dropped on every write
+    made through this service.
+    """
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+        self._snapshot: Optional[Tuple[float, FlagValues]] = None
+        self._lock = threading.Lock()
+
+    def is_enabled(self, session: Session, key: str, team_id: Optional[str] = None, default: bool = False) -> bool:
+        """Evaluate ``key`` for ``team_id``; unknown flags fall back to ``default``."""
+
+        values = self._values(session)
+        if key not in values:
+            return default
+        enabled, overrides = values[key]
+        if team_id and team_id in overrides:
+            return overrides[team_id]
+        return enabled
+
+    def evaluate(self, session: Session, team_id: Optional[str] = None) -> Dict[str, bool]:
+        return {key: self.is_enabled(session, key, team_id) for key in sorted(self._values(session))}
+
+    def list_flags(self, session: Session) -> List[FeatureFlag]:
+        stmt = select(FeatureFlag).options(selectinload(FeatureFlag.overrides)).order_by(FeatureFlag.key)
+        return list(session.scalars(stmt).all())
+
+    def get_flag(self, session: Session, key: str) -> Optional[FeatureFlag]:
+        return session.get(FeatureFlag, key)
+
+    def set_flag(self, session: Session, key: str, enabled: bool, description: Optional[str] = None) -> FeatureFlag:
+        if not _KEY.match(key):
+            raise ValueError("Flag keys use lowercase letters, digits, '.', '_' and '-'")
+        flag = session.get(FeatureFlag, key)
+        if flag is None:
+            flag = FeatureFlag(key=key)
+            session.add(flag)
+        flag.enabled = enabled
+        if description is not None:
+            flag.description = description
+        flag.updated_at = datetime.utcnow()
+        return self._commit(session, flag)
+
+    def delete_flag(self, session: Session, key: str) -> None:
+        flag = session.get(FeatureFlag, key)
+        if not flag:
+            raise LookupError("Feature flag not found")
+        session.delete(flag)
+        self._commit(session)
+
+    def set_override(self, session: Session, key: str, team_id: str, enabled: bool) -> FeatureFlag:
+        flag = session.get(FeatureFlag, key)
+        if not flag:
+            raise LookupError("Feature flag not found")
+        if not session.get(Team, team_id):
+            raise LookupError("Team not found")
+        override = next((item for item in flag.overrides if item.team_id == team_id), None)
+        if override is None:
+            override = FeatureFlagOverride(team_id=team_id, enabled=enabled)
+            flag.overrides.append(override)
+        override.enabled = enabled
+        override.updated_at = datetime.utcnow()
+        return self._commit(session, flag)
+
+    def clear_override(self, session: Session, key: str, team_id: str) -> FeatureFlag:
+        flag = session.get(FeatureFlag, key)
+        override = next((item for item in flag.overrides if item.team_id == team_id), None) if flag else None
+        if not override:
+            raise LookupError("No override for this team")
+        flag.overrides.remove(override)
+        return self._commit(session, flag)
+
+    def invalidate(self) -> None:
+        with self._lock:
+            self._snapshot = None
+
+    def _commit(self, session: Session, flag: Optional[FeatureFlag] = None) -> Optional[FeatureFlag]:
+        session.commit()
+        self.invalidate()
+        if flag is not None:
+            session.refresh(flag)
+        return flag
+
+    def _values(self, session: Session) -> FlagValues:
+        now = time.monotonic()
+        with self._lock:
+            if self._snapshot and now - self._snapshot[0] < self.settings.feature_flag_cache_seconds:
+                return self._snapshot[1]
+
+        values: FlagValues = {
+            flag.key: (flag.enabled, {override.team_id: override.enabled for override in flag.overrides})
+            for flag in self.list_flags(session)
+        }
+        with self._lock:
+            self._snapshot = (now, values)
+        return values
@@ -0,0 +1,183 @@
+from __future__ import annotations
+
+import logging
+import shutil
+from datetime import datetime, timedelta
+from typing import Any, Callable, Dict, List, Optional
+
+from sqlalchemy import func, select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ..demos.models import Demo
+from .channels import AlertMessage, ChannelSender
+from .models import AlertRule
+from .schemas import AlertEvaluation, AlertRuleCreate
+from .service import NotificationService
+
+logger = logging.getLogger(__name__)
+
+# Most recent demos looked at when counting consecutive parse failures.
+FAILURE_LOOKBACK = 100
+
+
+class AlertService:
+    """Evaluate admin-defined alert rules and notify their channels.
+
+    A rule fires when its metric rises above the threshold and notifies its
+    channels once; it re-sends every ``repeat_minutes`` if set, escalates to
+    ``escalation_channels`` after ``escalate_after_minutes`` and sends a
+    resolve message once the metric drops back. Rules are evaluated on a
+    schedule (``ALERT_INTERVAL_SECONDS``) and right after each demo is
+    processed, so parse failures alert without delay.
+    """
+
+    def __init__(
+        self,
+        settings: Settings,
+        notifications: NotificationService,
+        sender: Optional[ChannelSender] = None,
+        disk_usage: Callable[[str], Any] = shutil.disk_usage,
+    ) -> None:
+        self.settings = settings
+        self.sender = sender or ChannelSender(settings, notifications)
+        self._disk_usage = disk_usage
+        self._measures: Dict[str, Callable[[Session, datetime], Optional[float]]] = {
+            "consecutive_parse_failures": self._consecutive_parse_failures,
+            "parse_queue_age_minutes": self._parse_queue_age_minutes,
+            "disk_usage_percent": self._disk_usage_percent,
+        }
+
+    def list_rules(self, session: Session) -> List[AlertRule]:
+        return list(session.scalars(select(AlertRule).order_by(AlertRule.created_at, AlertRule.id)).all())
+
+    def create_rule(self, session: Session, request: AlertRuleCreate) -> AlertRule:
+        rule = AlertRule(**request.model_dump())
+        session.add(rule)
+        session.commit()
+        session.refresh(rule)
+        return rule
+
+    def update_rule(self, session: Session, rule_id: str, request: AlertRuleCreate) -> AlertRule:
+        rule = self._get(session, rule_id)
+        for field, value in request.model_dump().items():
+            setattr(rule, field, value)
+        if not rule.enabled:
+            rule.firing_since = rule.escalated_at = None
+        session.commit()
+        return rule
+
+    def delete_rule(self, session: Session, rule_id: str) -> None:
+        session.delete(self._get(session, rule_id))
+        session.commit()
+
+    def measure(self, session: Session, metric: str, now: Optional[datetime] = None) -> Optional[float]:
+        return self._measures[metric](session, now or datetime.utcnow())
+
+    def evaluate(self, session: Session, now: Optional[datetime] = None) -> List[AlertEvaluation]:
+        now = now or datetime.utcnow()
+        rules = session.scalars(select(AlertRule).where(AlertRule.enabled.is_(True)).order_by(AlertRule.created_at))
+        return [self._evaluate_rule(session, rule, now) for rule in list(rules)]
+
+    def evaluate_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: check the rules now rather than at the next scheduled run."""
+
+        try:
+            self.evaluate(session)
+        except Exception:  # noqa: BLE001 - the demo is stored either way
+            session.rollback()
+            logger.exception("Could not evaluate alert rules after demo %s", demo.id)
+
+    def _evaluate_rule(self, session: Session, rule: AlertRule, now: datetime) -> AlertEvaluation:
+        value = self.measure(session, rule.metric, now)
+        rule.last_value, rule.last_evaluated_at = value, now
+        action, channels = None, []
+        if value is not None and value > rule.threshold:
+            if rule.firing_since is None:
+                rule.firing_since = now
+                action, channels = "trigger", rule.channels
+            elif (
+                rule.escalate_after_minutes
+                and rule.escalated_at is None
+                and now - rule.firing_since >= timedelta(minutes=rule.escalate_after_minutes)
+            ):
+                rule.escalated_at = now
+                action, channels = "escalate", rule.escalation_channels or rule.channels
+            elif rule.repeat_minutes and now - (rule.last_notified_at or now) >= timedelta(minutes=rule.repeat_minutes):
+                action, channels = "repeat", rule.channels
+        elif value is not None and rule.firing_since is not None:
+            escalated = rule.escalation_channels if rule.escalated_at else []
+            action, channels = "resolve", _unique(rule.channels + escalated)
+        session.commit()
+
+        deliveries = []
+        if action:
+            message = AlertMessage(
+                rule_id=rule.id,
+                rule_name=rule.name,
+                metric=rule.metric,
+                value=value,
+                threshold=rule.threshold,
+                action=action,
+                firing_since=rule.firing_since,
+            )
+            deliveries = [self.sender.send(session, channel, message) for channel in channels]
+            rule.last_notified_at = now
+            if action == "resolve":
+                rule.firing_since = rule.escalated_at = None
+            session.commit()
+
+        state = "unknown" if value is None else "firing" if rule.firing_since else "ok"
+        return AlertEvaluation(
+            rule_id=rule.id,
+            name=rule.name,
+            metric=rule.metric,
+            value=value,
+            threshold=rule.threshold,
+            state=state,
+            action=action,
+            deliveries=deliveries,
+        )
+
+    def _get(self, session: Session, rule_id: str) -> AlertRule:
+        rule = session.get(AlertRule, rule_id)
+        if not rule:
+            raise LookupError("Alert rule not found")
+        return rule
+
+    def _consecutive_parse_failures(self, session: Session, now: datetime) -> Optional[float]:
+        demos = session.scalars(
+            select(Demo)
+            .where(Demo.status == "processed", Demo.deleted_at.is_(None))
+            .order_by(Demo.processed_at.desc())
+            .limit(FAILURE_LOOKBACK)
+        )
+        failures = 0
+        for demo in demos:
+            if (demo.extra_metadata or {}).get("parse_status") != "failed":
+                break
+            failures += 1
+        return float(failures)
+
+    def _parse_queue_age_minutes(self, session: Session, now: datetime) -> Optional[float]:
+        oldest = session.scalar(
+            select(func.min(Demo.uploaded_at)).where(Demo.status == "uploaded", Demo.deleted_at.is_(None))
+        )
+        return round((now - oldest).total_seconds() / 60, 1) if oldest else 0.0
+
+    def _disk_usage_percent(self, session: Session, now: datetime) -> Optional[float]:
+        try:
+            usage = self._disk_usage(str(self.settings.data_dir))
+        except OSError:
+            return None
+        return round(usage.used / usage.total * 100, 1) if usage.total else None
+
+
+def _unique(channels: List[dict]) -> List[dict]:
+    seen, unique = set(), []
+    for channel in channels:
+        key = (channel.get("type"), channel.get("target"))
+        if key not in seen:
+            seen.add(key)
+            unique.append(channel)
+    return unique
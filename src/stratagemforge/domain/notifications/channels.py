@@ -0,0 +1,128 @@
+"""Deliver alerts to the inbox and to external channels (Discord, email, webhooks, PagerDuty)."""
+
+from __future__ import annotations
+
+import json
+import logging
+import time
+from dataclasses import dataclass
+from datetime import datetime
+from typing import Any, Dict, Optional
+from urllib.request import Request, urlopen
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.mail import send_email
+from ...core.timeutil import isoformat_utc
+from ..demos.webhooks import EVENT_HEADER, SIGNATURE_HEADER, TIMESTAMP_HEADER, sign
+from ..users.models import User
+from .service import NotificationService
+
+logger = logging.getLogger(__name__)
+
+LABELS = {"trigger": "FIRING", "repeat": "FIRING", "escalate": "ESCALATED", "resolve": "RESOLVED"}
+
+
+@dataclass(frozen=True)
+class AlertMessage:
+    rule_id: str
+    rule_name: str
+    metric: str
+    value: Optional[float]
+    threshold: float
+    action: str  # trigger, repeat, escalate or resolve
+    firing_since: Optional[datetime]
+
+    @property
+    def summary(self) -> str:
+        value = "n/a" if self.value is None else f"{self.value:g}"
+        return f"[{LABELS[self.action]}] {self.rule_name}: {self.metric} is {value} (threshold {self.threshold:g})"
+
+    def payload(self) -> Dict[str, Any]:
+        return {
+            "rule_id": self.rule_id,
+            "rule": self.rule_name,
+            "metric": self.metric,
+            "value": self.value,
+            "threshold": self.threshold,
+            "action": self.action,
+            "firing_since": isoformat_utc(self.firing_since) if self.firing_since else None,
+        }
+
+
+class ChannelSender:
+    """Send an :class:`AlertMessage` to one channel, reporting failures instead of raising them."""
+
+    def __init__(self, settings: Settings, notifications: NotificationService) -> None:
+        self.settings = settings
+        self.notifications = notifications
+
+    def send(self, session: Session, channel: Dict[str, Any], message: AlertMessage) -> Dict[str, Any]:
+        kind, target = channel.get("type"), channel.get("target")
+        outcome: Dict[str, Any] = {"type": kind, "target": target}
+        try:
+            handler = getattr(self, f"_send_{kind}")
+            handler(session, target, message)
+            outcome["delivered"] = True
+        except Exception as exc:  # noqa: BLE001 - one broken channel must not stop the others
+            session.rollback()
+            logger.warning("Alert %s to %s channel failed: %s", message.rule_name, kind, exc)
+            outcome.update(delivered=False, error=str(exc)[:500])
+        return outcome
+
+    def _send_inbox(self, session: Session, target: Optional[str], message: AlertMessage) -> None:
+        if target:
+            recipients = [target]
+        else:
+            admins = select(User.id).where(User.role == "admin", User.is_active.is_(True))
+            recipients = list(session.scalars(admins).all())
+        for user_id in recipients:
+            self.notifications.notify(
+                session, "alert", message.summary, user_id=user_id, link="/admin/alerts", data=message.payload()
+            )
+
+    def _send_discord(self, session: Session, target: Optional[str], message: AlertMessage) -> None:
+        self._post_json(target, {"content": message.summary})
+
+    def _send_webhook(self, session: Session, target: Optional[str], message: AlertMessage) -> None:
+        event = f"alert.{message.action}"
+        body = json.dumps({"event": event, "alert": message.payload()}, sort_keys=True).encode()
+        timestamp = str(int(time.time()))
+        headers = {EVENT_HEADER: event, TIMESTAMP_HEADER: timestamp}
+        if self.settings.webhook_secret:
+            headers[SIGNATURE_HEADER] = sign(self.settings.webhook_secret, timestamp, body)
+        self._post(target, body, headers)
+
+    def _send_pagerduty(self, session: Session, target: Optional[str], message: AlertMessage) -> None:
+        self._post_json(
+            self.settings.pagerduty_events_url,
+            {
+                "routing_key": target,
+                "event_action": "resolve" if message.action == "resolve" else "trigger",
+                "dedup_key": f"stratagemforge-alert-{message.rule_id}",
+                "payload": {
+                    "summary": message.summary,
+                    "source": self.settings.app_name,
+                    "severity": "critical" if message.action == "escalate" else "error",
+                    "custom_details": message.payload(),
+                },
+            },
+        )
+
+    def _send_email(self, session: Session, target: Optional[str], message: AlertMessage) -> None:
+        send_email(self.settings, target or "", message.summary, json.dumps(message.payload(), indent=2))
+
+    def _post_json(self, url: Optional[str], payload: Dict[str, Any]) -> None:
+        self._post(url, json.dumps(payload).encode(), {})
+
+    def _post(self, url: Optional[str], body: bytes, headers: Dict[str, str]) -> None:
+        request = Request(
+            url or "",
+            data=body,
+            headers={"Content-Type": "application/json", "User-Agent": "StratagemForge/1.0", **headers},
+            method="POST",
+        )
+        with urlopen(request, timeout=self.settings.webhook_timeout_seconds):
+            pass
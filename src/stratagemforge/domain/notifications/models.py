@@ -0,0 +1,57 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+
+from sqlalchemy import Boolean, DateTime, Float, ForeignKey, Index, Integer, JSON, String, Text
+from sqlalchemy.orm import Mapped, mapped_column
+
+from ...core.database import Base
+from ...core.ids import new_ulid, new_uuid
+from ..users.models import User  # noqa: F401 - registers the users FK target
+
+
+class Notification(Base):
+    """An entry in a user's in-app inbox."""
+
+    __tablename__ = "notifications"
+    __table_args__ = (Index("ix_notifications_user_unread", "user_id", "read_at"),)
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_ulid)
+    user_id: Mapped[str] = mapped_column(String(36), ForeignKey("users.id"), nullable=False, index=True)
+    kind: Mapped[str] = mapped_column(String(32), nullable=False)
+    title: Mapped[str] = mapped_column(String(255), nullable=False)
+    body: Mapped[Optional[str]] = mapped_column(Text)
+    link: Mapped[Optional[str]] = mapped_column(String(1024))
+    data: Mapped[Dict[str, Any]] = mapped_column(JSON, default=dict)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False, index=True)
+    read_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+
+class AlertRule(Base):
+    """Admin-defined condition (``metric`` above ``threshold``) that notifies channels while it holds.
+
+    ``channels`` are ``{"type": ..., "target": ...}`` entries. A rule still
+    firing after ``escalate_after_minutes`` also notifies its
+    ``escalation_channels``; ``repeat_minutes`` re-sends the alert while it
+    lasts. The ``firing_since``/``escalated_at`` state lets every worker
+    evaluate the rules without notifying twice.
+    """
+
+    __tablename__ = "alert_rules"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    name: Mapped[str] = mapped_column(String(255), nullable=False)
+    metric: Mapped[str] = mapped_column(String(64), nullable=False)
+    threshold: Mapped[float] = mapped_column(Float, nullable=False)
+    channels: Mapped[List[Dict[str, Any]]] = mapped_column(JSON, default=list)
+    escalation_channels: Mapped[List[Dict[str, Any]]] = mapped_column(JSON, default=list)
+    escalate_after_minutes: Mapped[Optional[int]] = mapped_column(Integer)
+    repeat_minutes: Mapped[Optional[int]] = mapped_column(Integer)
+    enabled: Mapped[bool] = mapped_column(Boolean, default=True, nullable=False)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    last_value: Mapped[Optional[float]] = mapped_column(Float)
+    last_evaluated_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    firing_since: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    escalated_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    last_notified_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
@@ -0,0 +1,108 @@
+from __future__ import annotations
+
+from typing import Any, Dict, List, Literal, Optional
+
+from pydantic import BaseModel, Field, model_validator
+
+from ...core.timeutil import UtcDateTime
+
+NotificationKind = Literal["processing_done", "mention", "report_ready", "alert"]
+AlertMetric = Literal["consecutive_parse_failures", "parse_queue_age_minutes", "disk_usage_percent"]
+AlertChannelType = Literal["inbox", "discord", "email", "webhook", "pagerduty"]
+
+
+class NotificationSummary(BaseModel):
+    id: str
+    user_id: str
+    kind: str
+    title: str
+    body: Optional[str] = None
+    link: Optional[str] = None
+    data: Dict[str, Any] = Field(default_factory=dict)
+    created_at: UtcDateTime
+    read_at: Optional[UtcDateTime] = None
+
+    class Config:
+        orm_mode = True
+
+
+class NotificationCreate(BaseModel):
+    user_id: Optional[str] = Field(default=None, description="Recipient; omit to notify every active user")
+    kind: NotificationKind
+    title: str = Field(min_length=1, max_length=255)
+    body: Optional[str] = None
+    link: Optional[str] = Field(default=None, description="Frontend path to open, e.g. /demos/<id>")
+    data: Dict[str, Any] = Field(default_factory=dict)
+
+
+class NotificationCollection(BaseModel):
+    notifications: List[NotificationSummary]
+    count: int
+    total: int
+    unread: int
+    page: int = 1
+    page_size: Optional[int] = None
+    next_cursor: Optional[str] = Field(default=None, description="Pass as cursor for the next page")
+
+
+class UnreadCount(BaseModel):
+    user_id: str
+    unread: int
+
+
+class MarkReadResult(BaseModel):
+    updated: int
+    unread: int
+
+
+class AlertChannel(BaseModel):
+    type: AlertChannelType
+    target: Optional[str] = Field(
+        default=None,
+        description="Discord or webhook URL, email address or PagerDuty routing key; "
+        "for inbox an optional user id (default: every admin)",
+    )
+
+    @model_validator(mode="after")
+    def _require_target(self) -> "AlertChannel":
+        if self.type != "inbox" and not self.target:
+            raise ValueError(f"A {self.type} channel needs a target")
+        return self
+
+
+class AlertRuleCreate(BaseModel):
+    name: str = Field(min_length=1, max_length=255)
+    metric: AlertMetric = Field(
+        description="consecutive_parse_failures: latest demos that failed to parse in a row; "
+        "parse_queue_age_minutes: age of the oldest demo still waiting to be parsed; "
+        "disk_usage_percent: used space on the data volume"
+    )
+    threshold: float = Field(description="The rule fires while the metric is above this value")
+    channels: List[AlertChannel] = Field(min_length=1)
+    escalation_channels: List[AlertChannel] = Field(default_factory=list)
+    escalate_after_minutes: Optional[int] = Field(default=None, ge=1)
+    repeat_minutes: Optional[int] = Field(default=None, ge=1, description="Re-send while firing; omit to send once")
+    enabled: bool = True
+
+
+class AlertRuleSummary(AlertRuleCreate):
+    id: str
+    created_at: UtcDateTime
+    last_value: Optional[float] = None
+    last_evaluated_at: Optional[UtcDateTime] = None
+    firing_since: Optional[UtcDateTime] = None
+    escalated_at: Optional[UtcDateTime] = None
+
+    class Config:
+        orm_mode = True
+
+
+class AlertEvaluation(BaseModel):
+    rule_id: str
+    name: str
+    metric: str
+    value: Optional[float] = None
+    threshold: float
+    state: Literal["ok", "firing", "unknown"]
+    action: Optional[Literal["trigger", "repeat", "escalate", "resolve"]] = None
+    deliveries: List[Dict[str, Any]] = Field(default_factory=list)
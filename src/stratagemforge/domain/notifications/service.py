@@ -0,0 +1,126 @@
+from __future__ import annotations
+
+import logging
+from datetime import datetime
+from typing import Any, Dict, List, Optional, Tuple
+
+from sqlalchemy import func, select, update
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.ids import parse_cursor
+from ..demos.models import Demo
+from ..users.models import User
+from .models import Notification
+
+logger = logging.getLogger(__name__)
+
+
+class NotificationService:
+    """Persisted per-user inbox behind the frontend's bell icon."""
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+
+    def notify(
+        self,
+        session: Session,
+        kind: str,
+        title: str,
+        *,
+        user_id: Optional[str] = None,
+        body: Optional[str] = None,
+        link: Optional[str] = None,
+        data: Optional[Dict[str, Any]] = None,
+    ) -> List[Notification]:
+        """Add a notification to one user's inbox, or to every active user's when ``user_id`` is omitted."""
+
+        if user_id:
+            user = session.get(User, user_id)
+            if not user:
+                raise LookupError("User not found")
+            recipients = [user.id]
+        else:
+            recipients = list(session.scalars(select(User.id).where(User.is_active.is_(True))).all())
+
+        notifications = [
+            Notification(user_id=recipient, kind=kind, title=title, body=body, link=link, data=data or {})
+            for recipient in recipients
+        ]
+        session.add_all(notifications)
+        session.commit()
+        return notifications
+
+    def list_notifications(
+        self,
+        session: Session,
+        user_id: str,
+        *,
+        unread_only: bool = False,
+        page: int = 1,
+        page_size: int = 20,
+        cursor: Optional[str] = None,
+    ) -> Tuple[List[Notification], int]:
+        """One page of a user's inbox, newest first.
+
+        A ``cursor`` (the last notification id of the previous page) replaces ``page``.
+        """
+
+        stmt = select(Notification).where(Notification.user_id == user_id)
+        if unread_only:
+            stmt = stmt.where(Notification.read_at.is_(None))
+        total = session.scalar(select(func.count()).select_from(stmt.subquery())) or 0
+        if cursor:
+            page_stmt = stmt.where(Notification.id < parse_cursor(cursor)).order_by(Notification.id.desc())
+        else:
+            newest_first = stmt.order_by(Notification.created_at.desc(), Notification.id.desc())
+            page_stmt = newest_first.offset((page - 1) * page_size)
+        return list(session.scalars(page_stmt.limit(page_size)).all()), total
+
+    def unread_count(self, session: Session, user_id: str) -> int:
+        stmt = select(func.count(Notification.id)).where(
+            Notification.user_id == user_id, Notification.read_at.is_(None)
+        )
+        return session.scalar(stmt) or 0
+
+    def mark_read(self, session: Session, notification_id: str, user_id: Optional[str] = None) -> Notification:
+        notification = session.get(Notification, notification_id)
+        if not notification or (user_id and notification.user_id != user_id):
+            raise LookupError("Notification not found")
+        if notification.read_at is None:
+            notification.read_at = datetime.utcnow()
+            session.commit()
+        return notification
+
+    def mark_all_read(self, session: Session, user_id: str) -> int:
+        result = session.execute(
+            update(Notification)
+            .where(Notification.user_id == user_id, Notification.read_at.is_(None))
+            .values(read_at=datetime.utcnow())
+        )
+        session.commit()
+        return result.rowcount or 0
+
+    def notify_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: tell everyone the demo is ready, never failing the upload."""
+
+        try:
+            self.notify(
+                session,
+                "processing_done",
+                f"{demo.original_filename} is ready",
+                body=_match_line(demo),
+                link=f"/demos/{demo.id}",
+                data={"demo_id": demo.id},
+            )
+        except Exception:  # noqa: BLE001 - the demo is stored either way
+            session.rollback()
+            logger.exception("Could not add inbox notifications for demo %s", demo.id)
+
+
+def _match_line(demo: Demo) -> Optional[str]:
+    match = demo.match
+    if match is None:
+        return None
+    map_name = f" on {match.map_name}" if match.map_name else ""
+    return f"{match.team_a} {match.score_a}-{match.score_b} {match.team_b}{map_name}"
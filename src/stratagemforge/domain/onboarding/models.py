@@ -0,0 +1,27 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import List, Optional
+
+from sqlalchemy import DateTime, ForeignKey, JSON, String
+from sqlalchemy.orm import Mapped, mapped_column
+
+from ...core.database import Base
+from ..demos.models import Demo  # noqa: F401 - registers the demos FK target
+from ..teams.models import Team  # noqa: F401 - registers the teams FK target
+
+
+class OnboardingState(Base):
+    """Where an account is in the first-run setup wizard."""
+
+    __tablename__ = "onboarding_states"
+
+    user_id: Mapped[str] = mapped_column(String(36), ForeignKey("users.id"), primary_key=True)
+    current_step: Mapped[str] = mapped_column(String(32), nullable=False)
+    completed_steps: Mapped[List[str]] = mapped_column(JSON, default=list)
+    skipped_steps: Mapped[List[str]] = mapped_column(JSON, default=list)
+    team_id: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("teams.id"))
+    first_demo_id: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("demos.id"))
+    started_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    updated_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    completed_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
@@ -0,0 +1,68 @@
+from __future__ import annotations
+
+from typing import List, Literal, Optional
+
+from pydantic import BaseModel, EmailStr, Field
+
+from ...core.timeutil import UtcDateTime
+
+OnboardingStep = Literal["create_team", "link_steam", "map_pool", "invite_members", "first_demo", "completed"]
+
+
+class TeamCreate(BaseModel):
+    name: str = Field(min_length=1, max_length=255)
+    tag: Optional[str] = Field(default=None, max_length=16)
+
+
+class SteamLink(BaseModel):
+    steamid: str = Field(description="SteamID64 or a steamcommunity.com/profiles/<id> URL")
+
+
+class MapPoolUpdate(BaseModel):
+    maps: List[str] = Field(min_length=1, description="Map names, e.g. ['de_mirage', 'Inferno']")
+
+
+class MemberInvites(BaseModel):
+    emails: List[EmailStr] = Field(min_length=1)
+
+
+class FirstDemoLink(BaseModel):
+    demo_id: str = Field(description="A demo uploaded through /api/demos/upload")
+
+
+class InviteSummary(BaseModel):
+    id: str
+    email: EmailStr
+    role: str = "player"
+    created_at: UtcDateTime
+    accepted_at: Optional[UtcDateTime] = None
+
+    class Config:
+        orm_mode = True
+
+
+class IssuedInvite(InviteSummary):
+    token: str = Field(description="Shown once; share it with the invitee")
+
+
+class OnboardingStatus(BaseModel):
+    user_id: str
+    current_step: OnboardingStep
+    steps: List[OnboardingStep]
+    completed_steps: List[OnboardingStep]
+    skipped_steps: List[OnboardingStep]
+    skippable_steps: List[OnboardingStep]
+    team_id: Optional[str] = None
+    team_name: Optional[str] = None
+    steamid: Optional[str] = None
+    map_pool: List[str] = Field(default_factory=list)
+    invites: List[InviteSummary] = Field(default_factory=list)
+    first_demo_id: Optional[str] = None
+    started_at: UtcDateTime
+    updated_at: UtcDateTime
+    completed_at: Optional[UtcDateTime] = None
+
+
+class InviteResult(BaseModel):
+    onboarding: OnboardingStatus
+    invites: List[IssuedInvite]
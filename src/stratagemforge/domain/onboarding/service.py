@@ -0,0 +1,192 @@
+from __future__ import annotations
+
+import hashlib
+import re
+import secrets
+from datetime import datetime
+from typing import List, Optional, Tuple
+
+from sqlalchemy import func, select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ..demos.repository import DemoRepository
+from ..stats.importers import normalise_map_name
+from ..teams.models import Team, TeamInvite, TeamMember
+from ..users.models import User
+from .models import OnboardingState
+from .schemas import InviteSummary, IssuedInvite, OnboardingStatus
+
+STEPS = ("create_team", "link_steam", "map_pool", "invite_members", "first_demo")
+SKIPPABLE_STEPS = ("invite_members",)
+COMPLETED = "completed"
+MAX_MAP_POOL = 7
+
+_STEAMID64 = re.compile(r"^7656119\d{10}$")
+_PROFILE_URL = re.compile(r"^https?://steamcommunity\.com/profiles/(\d{17})/?$")
+
+
+class OnboardingStepError(Exception):
+    """Raised when a wizard step is submitted before the steps it depends on."""
+
+
+class OnboardingService:
+    """First-run setup wizard, tracked per account so the frontend can resume it.
+
+    Steps run in the order of ``STEPS``. A finished step can be submitted again
+    to change its answer without moving the wizard backwards.
+    """
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+
+    def status(self, session: Session, user_id: str) -> OnboardingStatus:
+        user, state = self._state(session, user_id)
+        return self._status(session, user, state)
+
+    def create_team(self, session: Session, user_id: str, name: str, tag: Optional[str] = None) -> OnboardingStatus:
+        user, state = self._state(session, user_id)
+        self._require_step(state, "create_team")
+        name = name.strip()
+        existing = session.scalars(select(Team).where(func.lower(Team.name) == name.lower())).first()
+        team = session.get(Team, state.team_id) if state.team_id else None
+        if existing and existing is not team:
+            raise ValueError(f"Team {name} already exists")
+        if team is None:
+            team = Team(name=name, tag=tag, created_by=user.id)
+            session.add(team)
+            session.flush()
+            session.add(TeamMember(team_id=team.id, user_id=user.id, role="coach"))
+        else:
+            team.name, team.tag = name, tag
+        state.team_id = team.id
+        return self._complete(session, user, state, "create_team")
+
+    def link_steam(self, session: Session, user_id: str, steamid: str) -> OnboardingStatus:
+        user, state = self._state(session, user_id)
+        self._require_step(state, "link_steam")
+        steamid = parse_steamid(steamid)
+        owner = session.scalars(select(User).where(User.steamid == steamid)).first()
+        if owner and owner.id != user.id:
+            raise ValueError("That Steam account is already linked to another user")
+        user.steamid = steamid
+        return self._complete(session, user, state, "link_steam")
+
+    def set_map_pool(self, session: Session, user_id: str, maps: List[str]) -> OnboardingStatus:
+        user, state = self._state(session, user_id)
+        self._require_step(state, "map_pool")
+        pool = list(dict.fromkeys(normalise_map_name(name) for name in maps if name.strip()))
+        if not pool:
+            raise ValueError("Pick at least one map")
+        if len(pool) > MAX_MAP_POOL:
+            raise ValueError(f"A map pool has at most {MAX_MAP_POOL} maps")
+        session.get(Team, state.team_id).map_pool = pool
+        return self._complete(session, user, state, "map_pool")
+
+    def invite_members(
+        self, session: Session, user_id: str, emails: List[str]
+    ) -> Tuple[OnboardingStatus, List[IssuedInvite]]:
+        user, state = self._state(session, user_id)
+        self._require_step(state, "invite_members")
+        team = session.get(Team, state.team_id)
+        pending = {invite.email.lower() for invite in team.invites if invite.accepted_at is None}
+
+        issued = []
+        for email in dict.fromkeys(address.lower() for address in emails):
+            if email == (user.email or "").lower() or email in pending:
+                continue
+            token = secrets.token_urlsafe(32)
+            invite = TeamInvite(team_id=team.id, email=email, token_hash=_hash(token), invited_by=user.id)
+            session.add(invite)
+            session.flush()
+            issued.append(IssuedInvite(**InviteSummary.from_orm(invite).model_dump(), token=token))
+        return self._complete(session, user, state, "invite_members"), issued
+
+    def link_first_demo(self, session: Session, user_id: str, demo_id: str) -> OnboardingStatus:
+        user, state = self._state(session, user_id)
+        self._require_step(state, "first_demo")
+        if not DemoRepository(session).get(demo_id):
+            raise LookupError("Demo not found")
+        state.first_demo_id = demo_id
+        return self._complete(session, user, state, "first_demo")
+
+    def skip(self, session: Session, user_id: str) -> OnboardingStatus:
+        user, state = self._state(session, user_id)
+        step = state.current_step
+        if step not in SKIPPABLE_STEPS:
+            raise OnboardingStepError(f"Step {step} cannot be skipped")
+        state.skipped_steps = [*state.skipped_steps, step]
+        state.current_step = _next_step(state)
+        return self._save(session, user, state)
+
+    def _state(self, session: Session, user_id: str) -> Tuple[User, OnboardingState]:
+        user = session.get(User, user_id)
+        if not user:
+            raise LookupError("User not found")
+        state = session.get(OnboardingState, user_id)
+        if state is None:
+            state = OnboardingState(user_id=user_id, current_step=STEPS[0], completed_steps=[], skipped_steps=[])
+            session.add(state)
+            session.commit()
+        return user, state
+
+    def _require_step(self, state: OnboardingState, step: str) -> None:
+        done = set(state.completed_steps) | set(state.skipped_steps)
+        missing = [earlier for earlier in STEPS[: STEPS.index(step)] if earlier not in done]
+        if missing:
+            raise OnboardingStepError(f"Finish {missing[0]} before {step}")
+
+    def _complete(self, session: Session, user: User, state: OnboardingState, step: str) -> OnboardingStatus:
+        if step not in state.completed_steps:
+            state.completed_steps = [*state.completed_steps, step]
+        state.skipped_steps = [skipped for skipped in state.skipped_steps if skipped != step]
+        state.current_step = _next_step(state)
+        return self._save(session, user, state)
+
+    def _save(self, session: Session, user: User, state: OnboardingState) -> OnboardingStatus:
+        now = datetime.utcnow()
+        state.updated_at = now
+        if state.current_step == COMPLETED and state.completed_at is None:
+            state.completed_at = now
+        session.commit()
+        return self._status(session, user, state)
+
+    def _status(self, session: Session, user: User, state: OnboardingState) -> OnboardingStatus:
+        team = session.get(Team, state.team_id) if state.team_id else None
+        return OnboardingStatus(
+            user_id=user.id,
+            current_step=state.current_step,
+            steps=list(STEPS),
+            completed_steps=list(state.completed_steps),
+            skipped_steps=list(state.skipped_steps),
+            skippable_steps=list(SKIPPABLE_STEPS),
+            team_id=team.id if team else None,
+            team_name=team.name if team else None,
+            steamid=user.steamid,
+            map_pool=list(team.map_pool or []) if team else [],
+            invites=[InviteSummary.from_orm(invite) for invite in team.invites] if team else [],
+            first_demo_id=state.first_demo_id,
+            started_at=state.started_at,
+            updated_at=state.updated_at,
+            completed_at=state.completed_at,
+        )
+
+
+def parse_steamid(value: str) -> str:
+    """Accept a SteamID64 or a numeric profile URL and return the SteamID64."""
+
+    value = value.strip()
+    match = _PROFILE_URL.match(value)
+    steamid = match.group(1) if match else value
+    if not _STEAMID64.match(steamid):
+        raise ValueError("Expected a SteamID64 (17 digits starting with 7656119) or a /profiles/ URL")
+    return steamid
+
+
+def _next_step(state: OnboardingState) -> str:
+    done = set(state.completed_steps) | set(state.skipped_steps)
+    return next((step for step in STEPS if step not in done), COMPLETED)
+
+
+def _hash(token: str) -> str:
+    return hashlib.sha256(token.encode()).hexdigest()
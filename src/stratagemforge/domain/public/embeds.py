@@ -0,0 +1,54 @@
+"""Signed embed tokens: ``<payload>.<signature>``, both base64url without padding.
+
+The payload names the card kind, the subject (match id or SteamID) and an
+expiry; the signature is an HMAC-SHA256 of the payload with ``EMBED_SECRET``.
+Tokens are not stored, so a card can be embedded without a database lookup
+and rotating the secret invalidates every token at once.
+"""
+
+from __future__ import annotations
+
+import base64
+import hashlib
+import hmac
+import json
+import time
+from typing import Optional
+
+EMBED_KINDS = ("match", "player")
+
+
+def sign_embed(secret: str, kind: str, subject: str, expires_at: Optional[int] = None) -> str:
+    payload = json.dumps({"k": kind, "s": subject, "e": expires_at}, separators=(",", ":"), sort_keys=True).encode()
+    return f"{_b64(payload)}.{_b64(_mac(secret, payload))}"
+
+
+def verify_embed(secret: str, token: str, kind: str, now: Optional[float] = None) -> str:
+    """Return the subject of a valid ``kind`` token; raises ``PermissionError`` otherwise."""
+
+    try:
+        encoded_payload, encoded_signature = token.split(".")
+        payload = _unb64(encoded_payload)
+        signature = _unb64(encoded_signature)
+        claims = json.loads(payload)
+    except ValueError as exc:
+        raise PermissionError("Invalid embed token") from exc
+    valid = hmac.compare_digest(signature, _mac(secret, payload)) and isinstance(claims, dict)
+    if not valid or claims.get("k") != kind:
+        raise PermissionError("Invalid embed token")
+    expires_at = claims.get("e")
+    if expires_at is not None and expires_at < (now if now is not None else time.time()):
+        raise PermissionError("Embed token has expired")
+    return claims["s"]
+
+
+def _mac(secret: str, payload: bytes) -> bytes:
+    return hmac.new(secret.encode(), payload, hashlib.sha256).digest()
+
+
+def _b64(raw: bytes) -> str:
+    return base64.urlsafe_b64encode(raw).rstrip(b"=").decode()
+
+
+def _unb64(text: str) -> bytes:
+    return base64.urlsafe_b64decode(text + "=" * (-len(text) % 4))
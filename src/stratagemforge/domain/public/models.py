@@ -0,0 +1,29 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Optional
+
+from sqlalchemy import DateTime, ForeignKey, Integer, String
+from sqlalchemy.orm import Mapped, mapped_column
+
+from ...core.database import Base
+from ...core.ids import new_uuid
+from ..teams.models import Team  # noqa: F401 - registers the teams FK target
+
+
+class PublicApiKey(Base):
+    """Key a team embeds in its website to read its own public stats.
+
+    Only the SHA-256 of the key is stored; the plain value is shown once.
+    """
+
+    __tablename__ = "public_api_keys"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    team_id: Mapped[str] = mapped_column(String(36), ForeignKey("teams.id"), nullable=False, index=True)
+    label: Mapped[Optional[str]] = mapped_column(String(255))
+    key_hash: Mapped[str] = mapped_column(String(64), nullable=False, unique=True)
+    rate_limit_per_minute: Mapped[Optional[int]] = mapped_column(Integer)  # falls back to the global limit
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    last_used_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    revoked_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
@@ -0,0 +1,119 @@
+from __future__ import annotations
+
+from typing import List, Literal, Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+
+class PublicApiKeyRequest(BaseModel):
+    team_id: str
+    label: Optional[str] = Field(default=None, max_length=255, description="Where the key is used, e.g. the site URL")
+    rate_limit_per_minute: Optional[int] = Field(default=None, ge=1, description="Overrides the default limit")
+
+
+class PublicApiKeyResponse(BaseModel):
+    id: str
+    team_id: str
+    label: Optional[str] = None
+    key: str = Field(description="Shown once; send it as the X-API-Key header")
+    rate_limit_per_minute: int
+    created_at: UtcDateTime
+
+
+class PublicMapRecord(BaseModel):
+    map_name: str
+    matches: int
+    wins: int
+    win_rate: float
+
+
+class PublicMatchResult(BaseModel):
+    played_at: UtcDateTime
+    map_name: str
+    opponent: Optional[str] = None
+    score: str = Field(description="Team score first, e.g. 13-9")
+    result: str = Field(description="win, loss or draw")
+
+
+class PublicTeamStats(BaseModel):
+    team: str
+    matches: int
+    wins: int
+    losses: int
+    win_rate: Optional[float] = None
+    maps: List[PublicMapRecord]
+    recent_results: List[PublicMatchResult]
+    generated_at: UtcDateTime
+
+
+class PublicPlayerStats(BaseModel):
+    name: Optional[str] = None
+    steamid: Optional[str] = None
+    matches: int
+    kills: int
+    deaths: int
+    kd_ratio: Optional[float] = None
+    adr: Optional[float] = None
+    rating: Optional[float] = None
+
+
+class PublicRoster(BaseModel):
+    team: str
+    players: List[PublicPlayerStats]
+    generated_at: UtcDateTime
+
+
+class EmbedTokenRequest(BaseModel):
+    kind: Literal["match", "player"]
+    subject: str = Field(description="Match id (or external id) for match cards, SteamID64 for player cards")
+    expires_in_days: Optional[int] = Field(default=None, ge=1, description="Omit for a token that does not expire")
+
+
+class EmbedTokenResponse(BaseModel):
+    kind: str
+    subject: str
+    token: str
+    card_url: str
+    expires_at: Optional[UtcDateTime] = None
+
+
+class CardPlayer(BaseModel):
+    name: Optional[str] = None
+    steamid: Optional[str] = None
+    kills: int
+    deaths: int
+    adr: Optional[float] = None
+    rating: Optional[float] = None
+
+
+class CardTeam(BaseModel):
+    name: str
+    score: int
+    won: bool
+
+
+class MatchCard(BaseModel):
+    match_id: str
+    map_name: Optional[str] = None
+    played_at: UtcDateTime
+    event_name: Optional[str] = None
+    rounds: int
+    teams: List[CardTeam]
+    mvp: Optional[CardPlayer] = Field(default=None, description="Best rated player of the match")
+
+
+class PlayerCard(BaseModel):
+    steamid: str
+    name: Optional[str] = None
+    team: Optional[str] = Field(default=None, description="Team of the most recent match")
+    matches: int
+    kills: int
+    deaths: int
+    kd_ratio: Optional[float] = None
+    adr: Optional[float] = None
+    headshot_pct: Optional[float] = None
+    rating: Optional[float] = None
+    favourite_map: Optional[str] = None
+    last_played_at: Optional[UtcDateTime] = None
@@ -0,0 +1,314 @@
+from __future__ import annotations
+
+import hashlib
+import secrets
+import threading
+import time
+from collections import Counter, defaultdict
+from datetime import datetime, timedelta
+from typing import Any, Callable, Dict, List, Optional, Tuple
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.limits import RateLimiter, RateLimitStatus
+from ...core.timeutil import as_utc
+from ..demos.models import Demo, Match
+from ..demos.repository import DemoRepository
+from ..stats.repository import StatsRepository
+from ..teams.models import Team
+from .embeds import sign_embed, verify_embed
+from .models import PublicApiKey
+from .schemas import (
+    CardPlayer,
+    CardTeam,
+    MatchCard,
+    PlayerCard,
+    PublicMapRecord,
+    PublicMatchResult,
+    PublicPlayerStats,
+    PublicRoster,
+    PublicTeamStats,
+)
+
+RECENT_RESULTS = 10
+
+
+class PublicStatsService:
+    """Read-only team aggregates for the public API that teams embed on their own sites.
+
+    Callers authenticate with a team-bound key (``X-API-Key``) and only ever
+    see that team's numbers. Each key gets ``public_api_rate_limit_per_minute``
+    requests per minute unless it has its own limit, and responses are cached
+    for ``public_api_cache_seconds`` (dropped when a demo finishes processing)
+    so embeds on busy pages do not reach the database.
+
+    Match and player cards for community sites need no key: they are read
+    with a signed embed token (see :mod:`.embeds`) naming the one match or
+    player they show.
+    """
+
+    def __init__(self, settings: Settings, clock: Callable[[], float] = time.monotonic) -> None:
+        self.settings = settings
+        self.rate_limiter = RateLimiter(clock)
+        self._clock = clock
+        self._cache: Dict[Tuple[str, str], Tuple[float, Any]] = {}
+        self._lock = threading.Lock()
+
+    def issue_key(
+        self, session: Session, team_id: str, label: Optional[str] = None, rate_limit_per_minute: Optional[int] = None
+    ) -> tuple[PublicApiKey, str]:
+        if not session.get(Team, team_id):
+            raise LookupError("Team not found")
+        key = f"sfp_{secrets.token_urlsafe(32)}"
+        api_key = PublicApiKey(
+            team_id=team_id, label=label, key_hash=_hash(key), rate_limit_per_minute=rate_limit_per_minute
+        )
+        session.add(api_key)
+        session.commit()
+        session.refresh(api_key)
+        return api_key, key
+
+    def revoke_key(self, session: Session, key_id: str) -> None:
+        api_key = session.get(PublicApiKey, key_id)
+        if not api_key or api_key.revoked_at:
+            raise LookupError("Public API key not found")
+        api_key.revoked_at = datetime.utcnow()
+        session.commit()
+
+    def authenticate(self, session: Session, key: Optional[str]) -> PublicApiKey:
+        """Resolve an ``X-API-Key`` value; raises ``PermissionError`` for missing, unknown or revoked keys."""
+
+        api_key = None
+        if key:
+            api_key = session.scalars(select(PublicApiKey).where(PublicApiKey.key_hash == _hash(key))).first()
+        if not api_key or api_key.revoked_at:
+            raise PermissionError("Invalid API key")
+        api_key.last_used_at = datetime.utcnow()
+        session.commit()
+        return api_key
+
+    def limit_for(self, api_key: PublicApiKey) -> int:
+        return api_key.rate_limit_per_minute or self.settings.public_api_rate_limit_per_minute
+
+    def check_rate(self, api_key: PublicApiKey) -> RateLimitStatus:
+        return self.rate_limiter.hit(api_key.id, self.limit_for(api_key))
+
+    def team_stats(self, session: Session, team_id: str) -> PublicTeamStats:
+        return self._cached(team_id, "team", lambda: self._team_stats(session, team_id))
+
+    def roster(self, session: Session, team_id: str) -> PublicRoster:
+        return self._cached(team_id, "roster", lambda: self._roster(session, team_id))
+
+    def issue_embed_token(
+        self, session: Session, kind: str, subject: str, expires_in_days: Optional[int] = None
+    ) -> tuple[str, str, Optional[datetime]]:
+        """Sign a card token for a match or player; returns the token, resolved subject and expiry."""
+
+        secret = self._embed_secret()
+        if kind == "match":
+            match = DemoRepository(session).get_match(subject)
+            if not match:
+                raise LookupError("Match not found")
+            subject = match.id
+        elif not StatsRepository(session).list(steamid=subject):
+            raise LookupError("Player not found")
+        expires_at = datetime.utcnow() + timedelta(days=expires_in_days) if expires_in_days else None
+        expiry = int(as_utc(expires_at).timestamp()) if expires_at else None
+        return sign_embed(secret, kind, subject, expiry), subject, expires_at
+
+    def match_card(self, session: Session, token: str) -> MatchCard:
+        match_id = verify_embed(self._embed_secret(), token, "match")
+        return self._cached(match_id, "match_card", lambda: self._match_card(session, match_id))
+
+    def player_card(self, session: Session, token: str) -> PlayerCard:
+        steamid = verify_embed(self._embed_secret(), token, "player")
+        return self._cached(steamid, "player_card", lambda: self._player_card(session, steamid))
+
+    def invalidate(self) -> None:
+        with self._lock:
+            self._cache.clear()
+
+    def invalidate_after_ingest(self, session: Session, demo: Demo) -> None:
+        """Post-processing hook: a new match changes the team's record."""
+
+        self.invalidate()
+
+    def _embed_secret(self) -> str:
+        if not self.settings.embed_secret:
+            raise ValueError("Embed tokens are not configured")
+        return self.settings.embed_secret
+
+    def _cached(self, subject: str, view: str, build: Callable[[], Any]) -> Any:
+        key = (subject, view)
+        now = self._clock()
+        with self._lock:
+            cached = self._cache.get(key)
+            if cached and now - cached[0] < self.settings.public_api_cache_seconds:
+                return cached[1]
+        value = build()
+        with self._lock:
+            self._cache[key] = (now, value)
+        return value
+
+    def _team_stats(self, session: Session, team_id: str) -> PublicTeamStats:
+        team = _require_team(session, team_id)
+        matches = _team_matches(session, team.name)
+        wanted = team.name.casefold()
+        maps: Dict[str, List[int]] = defaultdict(lambda: [0, 0])
+        results = []
+        for match in matches:
+            ours_first = (match.team_a or "").casefold() == wanted
+            ours, theirs = (match.score_a, match.score_b) if ours_first else (match.score_b, match.score_a)
+            outcome = "win" if ours > theirs else "loss" if ours < theirs else "draw"
+            maps[match.map_name][0] += 1
+            maps[match.map_name][1] += int(outcome == "win")
+            results.append(
+                PublicMatchResult(
+                    played_at=match.played_at,
+                    map_name=match.map_name,
+                    opponent=match.team_b if ours_first else match.team_a,
+                    score=f"{ours}-{theirs}",
+                    result=outcome,
+                )
+            )
+
+        wins = sum(result.result == "win" for result in results)
+        losses = sum(result.result == "loss" for result in results)
+        return PublicTeamStats(
+            team=team.name,
+            matches=len(results),
+            wins=wins,
+            losses=losses,
+            win_rate=round(wins / len(results), 3) if results else None,
+            maps=sorted(
+                (
+                    PublicMapRecord(map_name=name, matches=played, wins=won, win_rate=round(won / played, 3))
+                    for name, (played, won) in maps.items()
+                ),
+                key=lambda record: (-record.matches, record.map_name),
+            ),
+            recent_results=results[:RECENT_RESULTS],
+            generated_at=datetime.utcnow(),
+        )
+
+    def _roster(self, session: Session, team_id: str) -> PublicRoster:
+        team = _require_team(session, team_id)
+        wanted = team.name.casefold()
+        players: Dict[str, Dict[str, Any]] = defaultdict(
+            lambda: {"name": None, "steamid": None, "matches": 0, "kills": 0, "deaths": 0, "adr": [], "rating": []}
+        )
+        for stat in StatsRepository(session).list(source="demo", primary_only=True):
+            if (stat.team_name or "").casefold() != wanted:
+                continue
+            bucket = players[stat.steamid or stat.player_key]
+            bucket["name"] = bucket["name"] or stat.player_name
+            bucket["steamid"] = bucket["steamid"] or stat.steamid
+            bucket["matches"] += 1
+            bucket["kills"] += stat.kills or 0
+            bucket["deaths"] += stat.deaths or 0
+            if stat.adr is not None:
+                bucket["adr"].append(stat.adr)
+            if stat.rating is not None:
+                bucket["rating"].append(stat.rating)
+
+        roster = [
+            PublicPlayerStats(
+                name=bucket["name"],
+                steamid=bucket["steamid"],
+                matches=bucket["matches"],
+                kills=bucket["kills"],
+                deaths=bucket["deaths"],
+                kd_ratio=round(bucket["kills"] / bucket["deaths"], 2) if bucket["deaths"] else None,
+                adr=_mean(bucket["adr"]),
+                rating=_mean(bucket["rating"]),
+            )
+            for bucket in players.values()
+        ]
+        roster.sort(key=lambda player: (player.rating or 0.0, player.kills), reverse=True)
+        return PublicRoster(team=team.name, players=roster, generated_at=datetime.utcnow())
+
+
+    def _match_card(self, session: Session, match_id: str) -> MatchCard:
+        match = DemoRepository(session).get_match(match_id)
+        if not match or match.demo is None or match.demo.deleted_at is not None:
+            raise LookupError("Match not found")
+        stats = [stat for stat in StatsRepository(session).list(match_id=match.id) if stat.source == "demo"]
+        best = max(stats, key=lambda stat: (stat.rating or 0.0, stat.kills or 0), default=None)
+        return MatchCard(
+            match_id=match.id,
+            map_name=match.map_name,
+            played_at=match.played_at,
+            event_name=match.event_name,
+            rounds=match.rounds,
+            teams=[
+                CardTeam(name=match.team_a, score=match.score_a, won=match.score_a > match.score_b),
+                CardTeam(name=match.team_b, score=match.score_b, won=match.score_b > match.score_a),
+            ],
+            mvp=CardPlayer(
+                name=best.player_name,
+                steamid=best.steamid,
+                kills=best.kills or 0,
+                deaths=best.deaths or 0,
+                adr=best.adr,
+                rating=best.rating,
+            )
+            if best
+            else None,
+        )
+
+    def _player_card(self, session: Session, steamid: str) -> PlayerCard:
+        stats = StatsRepository(session).list(steamid=steamid, primary_only=True)
+        if not stats:
+            raise LookupError("Player not found")
+        # Demo-derived rows win over imported rows for the same match.
+        seen = set()
+        rows = []
+        for stat in sorted(stats, key=lambda stat: stat.source != "demo"):
+            key = stat.match_id or (stat.source, stat.match_key)
+            if key not in seen:
+                seen.add(key)
+                rows.append(stat)
+        latest = max(rows, key=lambda stat: stat.played_at or datetime.min)
+        kills = sum(stat.kills or 0 for stat in rows)
+        deaths = sum(stat.deaths or 0 for stat in rows)
+        maps = Counter(stat.map_name for stat in rows if stat.map_name)
+        return PlayerCard(
+            steamid=steamid,
+            name=latest.player_name,
+            team=latest.team_name,
+            matches=len(rows),
+            kills=kills,
+            deaths=deaths,
+            kd_ratio=round(kills / deaths, 2) if deaths else None,
+            adr=_mean([stat.adr for stat in rows if stat.adr is not None]),
+            headshot_pct=_mean([stat.headshot_pct for stat in rows if stat.headshot_pct is not None]),
+            rating=_mean([stat.rating for stat in rows if stat.rating is not None]),
+            favourite_map=maps.most_common(1)[0][0] if maps else None,
+            last_played_at=latest.played_at,
+        )
+
+
+def _require_team(session: Session, team_id: str) -> Team:
+    team = session.get(Team, team_id)
+    if not team:
+        raise LookupError("Team not found")
+    return team
+
+
+def _team_matches(session: Session, team_name: str) -> List[Match]:
+    wanted = team_name.casefold()
+    return [
+        match
+        for match in DemoRepository(session).matches()
+        if wanted in {(match.team_a or "").casefold(), (match.team_b or "").casefold()}
+    ]
+
+
+def _mean(values: List[float]) -> Optional[float]:
+    return round(sum(values) / len(values), 2) if values else None
+
+
+def _hash(key: str) -> str:
+    return hashlib.sha256(key.encode("utf-8")).hexdigest()
@@ -0,0 +1,34 @@
+from __future__ import annotations
+
+from typing import Any, List, Literal
+
+from pydantic import BaseModel, Field
+
+
+class QueryRequest(BaseModel):
+    sql: str = Field(
+        min_length=1,
+        max_length=20_000,
+        description="One SELECT statement over tables named after the matches' datasets, e.g. kills or rounds",
+    )
+    match_ids: List[str] = Field(min_length=1, description="Matches whose parquet files the query may read")
+    format: Literal["json", "arrow"] = Field(default="json", description="arrow answers with an Arrow IPC stream")
+
+
+class QueryColumn(BaseModel):
+    name: str
+    type: str
+
+
+class QueryTable(BaseModel):
+    name: str
+    matches: int = Field(description="Matches that have this dataset")
+
+
+class QueryResult(BaseModel):
+    columns: List[QueryColumn]
+    rows: List[List[Any]]
+    row_count: int
+    truncated: bool = Field(description="More rows matched than QUERY_MAX_ROWS")
+    tables: List[QueryTable] = Field(description="Tables the query could read")
+    elapsed_ms: float
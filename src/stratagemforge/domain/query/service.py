@@ -0,0 +1,147 @@
+"""Read-only SQL over the parquet datasets of chosen matches, answered by an embedded DuckDB.
+
+Every query runs in its own in-memory database. Each dataset of the chosen
+matches (``kills``, ``rounds``, ``player_ticks``, the match ``summary``, ...)
+is loaded into a table of that name with an extra ``match_id`` column, then
+file access is switched off and the configuration locked, so the statement
+can only read what was loaded. Only a single ``SELECT`` is accepted; it is
+interrupted after ``QUERY_TIMEOUT_SECONDS`` and its result cut at
+``QUERY_MAX_ROWS`` rows.
+"""
+
+from __future__ import annotations
+
+import re
+import threading
+import time
+from dataclasses import dataclass
+from typing import Any, Dict, List, Sequence
+
+from ...core.config import Settings
+from ...core.storage import ArtifactStorage, build_storage
+from ..demos.models import Demo
+from .schemas import QueryColumn, QueryResult, QueryTable
+
+_TABLE_NAME = re.compile(r"^[a-z_][a-z0-9_]*$")
+
+
+@dataclass
+class QueryOutput:
+    """A finished query: the Arrow table of its rows and what the JSON answer reports about it."""
+
+    table: Any  # pyarrow.Table
+    truncated: bool
+    tables: List[QueryTable]
+    elapsed_ms: float
+
+    def to_result(self) -> QueryResult:
+        names = self.table.column_names
+        rows = [[record[name] for name in names] for record in self.table.to_pylist()]
+        return QueryResult(
+            columns=[QueryColumn(name=field.name, type=str(field.type)) for field in self.table.schema],
+            rows=rows,
+            row_count=len(rows),
+            truncated=self.truncated,
+            tables=self.tables,
+            elapsed_ms=self.elapsed_ms,
+        )
+
+    def to_arrow_stream(self) -> bytes:
+        import pyarrow as pa
+
+        sink = pa.BufferOutputStream()
+        with pa.ipc.new_stream(sink, self.table.schema) as writer:
+            writer.write_table(self.table)
+        return sink.getvalue().to_pybytes()
+
+
+class QueryService:
+    """Run analyst SQL over the datasets of a handful of matches (see the module docstring)."""
+
+    def __init__(self, settings: Settings, storage: ArtifactStorage | None = None) -> None:
+        self.settings = settings
+        self.storage = storage or build_storage(settings)
+
+    def run(self, demos: Sequence[Demo], sql: str) -> QueryOutput:
+        """Run ``sql`` over the datasets of ``demos``.
+
+        Raises ``ValueError`` for anything but a single valid ``SELECT`` or one
+        that runs too long, and ``RuntimeError`` without the ``query`` extra.
+        """
+
+        try:
+            import duckdb
+        except ImportError as exc:
+            raise RuntimeError("SQL queries need the query extra (duckdb) installed") from exc
+        if len(demos) > self.settings.query_max_matches:
+            raise ValueError(f"Queries may cover at most {self.settings.query_max_matches} matches")
+
+        started = time.perf_counter()
+        connection = duckdb.connect(database=":memory:")
+        try:
+            connection.execute(f"SET memory_limit = '{int(self.settings.query_memory_limit_mb)}MB'")
+            connection.execute(f"SET threads = {max(1, int(self.settings.query_threads))}")
+            tables = self._load_tables(connection, demos)
+            connection.execute("SET enable_external_access = false")
+            connection.execute("SET lock_configuration = true")
+            statement = _single_select(duckdb, connection, sql)
+            limit = self.settings.query_max_rows
+            timer = threading.Timer(self.settings.query_timeout_seconds, connection.interrupt)
+            timer.start()
+            try:
+                wrapped = f"SELECT * FROM ({statement}) AS query LIMIT {limit + 1}"
+                table = connection.execute(wrapped).fetch_arrow_table()
+            except duckdb.InterruptException as exc:
+                raise ValueError(f"Query ran longer than {self.settings.query_timeout_seconds:g} seconds") from exc
+            except duckdb.Error as exc:
+                raise ValueError(str(exc).splitlines()[0]) from exc
+            finally:
+                timer.cancel()
+        finally:
+            connection.close()
+        return QueryOutput(
+            table=table.slice(0, limit),
+            truncated=table.num_rows > limit,
+            tables=tables,
+            elapsed_ms=round((time.perf_counter() - started) * 1000, 1),
+        )
+
+    def _load_tables(self, connection: Any, demos: Sequence[Demo]) -> List[QueryTable]:
+        """Create one table per dataset name, stacking the matches' files by column name."""
+
+        files: Dict[str, List[tuple[str, str]]] = {}
+        for demo in demos:
+            if demo.match is None:
+                continue
+            for name, key in demo.artifact_keys().items():
+                if not _TABLE_NAME.match(name) or not self.storage.exists(key):
+                    continue
+                files.setdefault(name, []).append((demo.match.id, str(self.storage.get(key))))
+
+        tables = []
+        for name, sources in sorted(files.items()):
+            selects = [
+                f"SELECT {_literal(match_id)} AS match_id, * FROM read_parquet({_literal(path)})"
+                for match_id, path in sources
+            ]
+            connection.execute(f'CREATE TABLE "{name}" AS ' + " UNION ALL BY NAME ".join(selects))
+            tables.append(QueryTable(name=name, matches=len(sources)))
+        return tables
+
+
+def _single_select(duckdb: Any, connection: Any, sql: str) -> str:
+    """The statement's text if ``sql`` is exactly one ``SELECT`` (``WITH`` included); ``ValueError`` otherwise."""
+
+    try:
+        statements = connection.extract_statements(sql)
+    except duckdb.Error as exc:
+        raise ValueError(str(exc).splitlines()[0]) from exc
+    if len(statements) != 1:
+        raise ValueError("Send exactly one SQL statement")
+    if statements[0].type != duckdb.StatementType.SELECT:
+        raise ValueError("Only SELECT statements are allowed")
+    return statements[0].query.strip().rstrip(";")
+
+
+def _literal(value: str) -> str:
+    return "'" + value.replace("'", "''") + "'"
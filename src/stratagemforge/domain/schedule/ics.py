@@ -0,0 +1,77 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Iterable, List
+
+from .models import ScheduledSession
+
+PRODID = "-//StratagemForge//Schedule//EN"
+
+
+def render_calendar(sessions: Iterable[ScheduledSession], calendar_name: str, generated_at: datetime) -> str:
+    """Render sessions as an RFC 5545 VCALENDAR. Times are stored and emitted as UTC."""
+
+    lines = [
+        "BEGIN:VCALENDAR",
+        "VERSION:2.0",
+        f"PRODID:{PRODID}",
+        "CALSCALE:GREGORIAN",
+        "METHOD:PUBLISH",
+        f"X-WR-CALNAME:{_escape(calendar_name)}",
+    ]
+    for session in sessions:
+        lines.extend(_event(session, generated_at))
+    lines.append("END:VCALENDAR")
+    return "".join(f"{_fold(line)}\r\n" for line in lines)
+
+
+def _event(session: ScheduledSession, generated_at: datetime) -> List[str]:
+    description = [f"Opponent: {session.opponent}"] if session.opponent else []
+    if session.notes:
+        description.append(session.notes)
+
+    lines = [
+        "BEGIN:VEVENT",
+        f"UID:{session.id}@stratagemforge",
+        f"DTSTAMP:{_timestamp(generated_at)}",
+        f"LAST-MODIFIED:{_timestamp(session.updated_at)}",
+        f"DTSTART:{_timestamp(session.starts_at)}",
+        f"DTEND:{_timestamp(session.ends_at)}",
+        f"SUMMARY:{_escape(session.title)}",
+        f"CATEGORIES:{session.kind.upper()}",
+        f"STATUS:{'CANCELLED' if session.cancelled else 'CONFIRMED'}",
+    ]
+    if description:
+        lines.append(f"DESCRIPTION:{_escape(chr(10).join(description))}")
+    if session.location:
+        lines.append(f"LOCATION:{_escape(session.location)}")
+    lines.append("END:VEVENT")
+    return lines
+
+
+def _timestamp(value: datetime) -> str:
+    return value.strftime("%Y%m%dT%H%M%SZ")
+
+
+def _escape(text: str) -> str:
+    for raw, escaped in (("\\", "\\\\"), (";", "\\;"), (",", "\\,"), ("\r\n", "\\n"), ("\n", "\\n")):
+        text = text.replace(raw, escaped)
+    return text
+
+
+def _fold(line: str, limit: int = 75) -> str:
+    """Fold content lines longer than 75 octets, as required by RFC 5545."""
+
+    encoded = line.encode("utf-8")
+    if len(encoded) <= limit:
+        return line
+    parts = []
+    current = b""
+    for char in line:
+        size = len(char.encode("utf-8"))
+        if len(current) + size > (limit if not parts else limit - 1):
+            parts.append(current.decode("utf-8"))
+            current = b""
+        current += char.encode("utf-8")
+    parts.append(current.decode("utf-8"))
+    return "\r\n ".join(parts)
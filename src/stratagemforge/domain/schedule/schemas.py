@@ -0,0 +1,63 @@
+from __future__ import annotations
+
+from typing import Literal, Optional
+
+from pydantic import BaseModel, Field, model_validator
+
+from ...core.timeutil import UtcDateTime
+
+SessionKind = Literal["scrim", "review"]
+
+
+class ScheduledSessionBase(BaseModel):
+    kind: SessionKind
+    title: str = Field(min_length=1, max_length=255)
+    starts_at: UtcDateTime = Field(description="Start time; naive values are treated as UTC")
+    ends_at: UtcDateTime
+    opponent: Optional[str] = None
+    location: Optional[str] = Field(default=None, description="Server address, voice channel or venue")
+    notes: Optional[str] = None
+    demo_id: Optional[str] = Field(default=None, description="Demo being reviewed, for review sessions")
+
+    @model_validator(mode="after")
+    def _check_times(self):
+        if self.ends_at <= self.starts_at:
+            raise ValueError("ends_at must be after starts_at")
+        return self
+
+
+class ScheduledSessionCreate(ScheduledSessionBase):
+    pass
+
+
+class ScheduledSessionUpdate(BaseModel):
+    kind: Optional[SessionKind] = None
+    title: Optional[str] = Field(default=None, min_length=1, max_length=255)
+    starts_at: Optional[UtcDateTime] = None
+    ends_at: Optional[UtcDateTime] = None
+    opponent: Optional[str] = None
+    location: Optional[str] = None
+    notes: Optional[str] = None
+    demo_id: Optional[str] = None
+    cancelled: Optional[bool] = None
+
+
+class ScheduledSessionSummary(ScheduledSessionBase):
+    id: str
+    cancelled: bool
+    created_at: UtcDateTime
+    updated_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class FeedTokenRequest(BaseModel):
+    user_id: str
+
+
+class FeedTokenResponse(BaseModel):
+    id: str
+    token: str = Field(description="Shown once; store it in the calendar subscription URL")
+    feed_url: str
+    created_at: UtcDateTime
@@ -0,0 +1,118 @@
+from __future__ import annotations
+
+import hashlib
+import secrets
+from datetime import datetime
+from typing import Optional
+
+from sqlalchemy import select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.timeutil import to_utc_naive
+from ..demos.repository import DemoRepository
+from ..users.models import User
+from .ics import render_calendar
+from .models import CalendarFeedToken, ScheduledSession
+from .schemas import ScheduledSessionCreate, ScheduledSessionUpdate
+
+
+class ScheduleService:
+    """Manage scrims and review sessions and publish them as an ICS feed."""
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+
+    def list_sessions(
+        self,
+        session: Session,
+        kind: Optional[str] = None,
+        starts_after: Optional[datetime] = None,
+        starts_before: Optional[datetime] = None,
+    ) -> list[ScheduledSession]:
+        stmt = select(ScheduledSession).order_by(ScheduledSession.starts_at)
+        if kind:
+            stmt = stmt.where(ScheduledSession.kind == kind)
+        if starts_after:
+            stmt = stmt.where(ScheduledSession.starts_at >= to_utc_naive(starts_after))
+        if starts_before:
+            stmt = stmt.where(ScheduledSession.starts_at <= to_utc_naive(starts_before))
+        return list(session.scalars(stmt).all())
+
+    def get_session(self, session: Session, session_id: str) -> ScheduledSession | None:
+        return session.get(ScheduledSession, session_id)
+
+    def create_session(self, session: Session, payload: ScheduledSessionCreate) -> ScheduledSession:
+        self._require_demo(session, payload.demo_id)
+        values = payload.model_dump()
+        values["starts_at"], values["ends_at"] = to_utc_naive(payload.starts_at), to_utc_naive(payload.ends_at)
+        scheduled = ScheduledSession(**values)
+        session.add(scheduled)
+        session.commit()
+        session.refresh(scheduled)
+        return scheduled
+
+    def update_session(self, session: Session, session_id: str, payload: ScheduledSessionUpdate) -> ScheduledSession:
+        scheduled = self.get_session(session, session_id)
+        if not scheduled:
+            raise LookupError("Scheduled session not found")
+
+        changes = payload.model_dump(exclude_unset=True)
+        if "demo_id" in changes:
+            self._require_demo(session, changes["demo_id"])
+        for field, value in changes.items():
+            setattr(scheduled, field, to_utc_naive(value) if isinstance(value, datetime) else value)
+        if scheduled.ends_at <= scheduled.starts_at:
+            raise ValueError("ends_at must be after starts_at")
+        scheduled.updated_at = datetime.utcnow()
+        session.commit()
+        session.refresh(scheduled)
+        return scheduled
+
+    def delete_session(self, session: Session, session_id: str) -> None:
+        scheduled = self.get_session(session, session_id)
+        if not scheduled:
+            raise LookupError("Scheduled session not found")
+        session.delete(scheduled)
+        session.commit()
+
+    def issue_feed_token(self, session: Session, user_id: str) -> tuple[CalendarFeedToken, str]:
+        if not session.get(User, user_id):
+            raise LookupError("User not found")
+        token = secrets.token_urlsafe(32)
+        feed_token = CalendarFeedToken(user_id=user_id, token_hash=_hash(token))
+        session.add(feed_token)
+        session.commit()
+        session.refresh(feed_token)
+        return feed_token, token
+
+    def revoke_feed_token(self, session: Session, token_id: str) -> None:
+        feed_token = session.get(CalendarFeedToken, token_id)
+        if not feed_token or feed_token.revoked_at:
+            raise LookupError("Feed token not found")
+        feed_token.revoked_at = datetime.utcnow()
+        session.commit()
+
+    def render_feed(self, session: Session, token: str) -> str:
+        """Render the calendar for a feed token; raises ``PermissionError`` for unknown or revoked tokens."""
+
+        stmt = select(CalendarFeedToken).where(CalendarFeedToken.token_hash == _hash(token))
+        feed_token = session.scalars(stmt).first()
+        user = session.get(User, feed_token.user_id) if feed_token else None
+        if not feed_token or feed_token.revoked_at or not user or not user.is_active:
+            raise PermissionError("Invalid calendar feed token")
+
+        feed_token.last_used_at = datetime.utcnow()
+        session.commit()
+        return render_calendar(
+            self.list_sessions(session), f"{self.settings.app_name} schedule", generated_at=datetime.utcnow()
+        )
+
+    @staticmethod
+    def _require_demo(session: Session, demo_id: Optional[str]) -> None:
+        if demo_id and not DemoRepository(session).get(demo_id):
+            raise ValueError(f"Demo {demo_id} does not exist")
+
+
+def _hash(token: str) -> str:
+    return hashlib.sha256(token.encode("utf-8")).hexdigest()
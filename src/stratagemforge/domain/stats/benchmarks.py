@@ -0,0 +1,125 @@
+"""Percentile benchmarks of player metrics against every player in the dataset.
+
+Each player's rows are summed into career numbers first: scoreboard ratios
+(ADR, KAST, headshot %, rating) are averaged over their matches and counts
+are divided by the rounds of the matches that report rounds. A player's
+percentile for a metric is the share of players they do better than, ties
+counting half, so the median player sits at 50 whether the metric is one
+where higher or lower is better.
+"""
+
+from __future__ import annotations
+
+from collections import defaultdict
+from typing import Any, Dict, Iterable, List, Optional
+
+from .models import PlayerMatchStat
+
+# (metric, higher is better)
+BENCHMARK_METRICS = (
+    ("rating", True),
+    ("adr", True),
+    ("kast", True),
+    ("headshot_pct", True),
+    ("kills_per_round", True),
+    ("deaths_per_round", False),
+    ("assists_per_round", True),
+    ("flash_assists_per_round", True),
+    ("opening_kills_per_round", True),
+    ("opening_deaths_per_round", False),
+    ("clutch_win_rate", True),
+    ("blind_seconds_per_round", False),
+)
+HIGHER_IS_BETTER = dict(BENCHMARK_METRICS)
+BENCHMARK_QUANTILES = (10, 25, 50, 75, 90)
+_AVERAGED = ("rating", "adr", "kast", "headshot_pct")
+_PER_ROUND = ("kills", "deaths", "assists", "flash_assists", "opening_kills", "opening_deaths", "blind_seconds")
+
+
+def player_metrics(stats: Iterable[PlayerMatchStat], min_rounds: int = 0) -> Dict[str, Dict[str, Any]]:
+    """Career metrics per player (SteamID, or the name key of imported rows without one).
+
+    When a demo and an imported row describe the same match and player, the
+    demo row is used. Players with fewer than ``min_rounds`` rounds are left out.
+    """
+
+    seen = set()
+    players: Dict[str, Dict[str, Any]] = defaultdict(_new_bucket)
+    for stat in sorted(stats, key=lambda stat: stat.source != "demo"):
+        if stat.match_id:
+            key = (stat.match_id, stat.steamid or stat.player_key)
+            if key in seen:
+                continue
+            seen.add(key)
+        bucket = players[stat.steamid or stat.player_key]
+        bucket["steamid"] = bucket["steamid"] or stat.steamid
+        bucket["name"] = bucket["name"] or stat.player_name
+        bucket["matches"] += 1
+        for field in _AVERAGED:
+            if getattr(stat, field) is not None:
+                bucket["averaged"][field].append(getattr(stat, field))
+        if stat.rounds:
+            bucket["rounds"] += stat.rounds
+            for field in _PER_ROUND:
+                bucket["totals"][field] += getattr(stat, field) or 0
+        bucket["clutches_played"] += stat.clutches_played or 0
+        bucket["clutches_won"] += stat.clutches_won or 0
+
+    metrics = {}
+    for key, bucket in players.items():
+        if bucket["rounds"] < min_rounds:
+            continue
+        values: Dict[str, Optional[float]] = {
+            field: sum(values) / len(values) for field, values in bucket["averaged"].items()
+        }
+        for field in _PER_ROUND:
+            values[f"{field}_per_round"] = bucket["totals"][field] / bucket["rounds"] if bucket["rounds"] else None
+        played = bucket["clutches_played"]
+        values["clutch_win_rate"] = bucket["clutches_won"] / played if played else None
+        metrics[key] = {
+            "steamid": bucket["steamid"],
+            "name": bucket["name"],
+            "matches": bucket["matches"],
+            "rounds": bucket["rounds"],
+            "values": values,
+        }
+    return metrics
+
+
+def percentile_rank(value: float, population: List[float], higher_is_better: bool = True) -> Optional[float]:
+    """Share (0-100) of ``population`` that ``value`` does better than, ties counting half."""
+
+    if not population:
+        return None
+    worse = sum(1 for other in population if (other < value if higher_is_better else other > value))
+    ties = sum(1 for other in population if other == value)
+    return round(100 * (worse + ties / 2) / len(population), 1)
+
+
+def quantile(values: List[float], percent: float) -> Optional[float]:
+    """Linear-interpolated ``percent`` quantile of ``values``."""
+
+    if not values:
+        return None
+    ordered = sorted(values)
+    position = (len(ordered) - 1) * percent / 100
+    lower = int(position)
+    upper = min(lower + 1, len(ordered) - 1)
+    return ordered[lower] + (ordered[upper] - ordered[lower]) * (position - lower)
+
+
+def metric_population(metrics: Dict[str, Dict[str, Any]], metric: str) -> List[float]:
+    return [player["values"][metric] for player in metrics.values() if player["values"].get(metric) is not None]
+
+
+def _new_bucket() -> Dict[str, Any]:
+    return {
+        "steamid": None,
+        "name": None,
+        "matches": 0,
+        "rounds": 0,
+        "totals": defaultdict(float),
+        "averaged": defaultdict(list),
+        "clutches_played": 0,
+        "clutches_won": 0,
+    }
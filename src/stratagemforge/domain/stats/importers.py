@@ -0,0 +1,162 @@
+from __future__ import annotations
+
+import csv
+import io
+from datetime import datetime, timezone
+from typing import Any, Dict, Iterable, List, Optional, Tuple
+
+IMPORT_SOURCES = ("leetify", "scopegg")
+
+# Normalised header -> field. Exports differ between tools and over time, so
+# every field accepts a handful of spellings.
+COLUMN_ALIASES: Dict[str, Tuple[str, ...]] = {
+    "steamid": ("steam id", "steamid", "steam64", "steam64 id", "steam id64", "player steam id"),
+    "player_name": ("name", "player", "player name", "nickname"),
+    "team_name": ("team", "team name"),
+    "external_match_id": ("match id", "game id", "match"),
+    "map_name": ("map", "map name"),
+    "played_at": ("date", "match date", "played at", "finished at", "game finished at"),
+    "rounds": ("rounds", "rounds played"),
+    "kills": ("kills", "k"),
+    "deaths": ("deaths", "d"),
+    "assists": ("assists", "a"),
+    "adr": ("adr", "average damage per round"),
+    "kast": ("kast", "kast %", "kast%"),
+    "headshot_pct": ("hs %", "hs%", "hs", "headshot %", "headshot percentage"),
+    "rating": ("rating",),
+}
+
+SOURCE_ALIASES: Dict[str, Dict[str, Tuple[str, ...]]] = {
+    "leetify": {
+        "rating": ("leetify rating",),
+        "external_match_id": ("leetify match id", "game id"),
+        "played_at": ("game finished at", "finished at"),
+    },
+    "scopegg": {
+        "rating": ("rating 2.0", "scope rating", "hltv rating"),
+        "external_match_id": ("scope match id", "match id"),
+    },
+}
+
+INTEGER_FIELDS = {"rounds", "kills", "deaths", "assists"}
+FLOAT_FIELDS = {"adr", "kast", "headshot_pct", "rating"}
+_DATE_FORMATS = ("%d/%m/%Y %H:%M", "%d/%m/%Y", "%d.%m.%Y %H:%M", "%d.%m.%Y")
+
+
+def parse_stats_csv(content: str, source: str) -> Tuple[List[Dict[str, Any]], List[str]]:
+    """Map an exported stats CSV onto ``PlayerMatchStat`` fields.
+
+    Returns the parsed rows and per-row error messages. Columns that do not map
+    to a known field are kept under ``extra`` so nothing from the export is lost.
+    """
+
+    if source not in IMPORT_SOURCES:
+        raise ValueError(f"Unsupported import source {source!r}; choose one of {', '.join(IMPORT_SOURCES)}")
+
+    reader = csv.DictReader(io.StringIO(content.lstrip("\ufeff")), dialect=_sniff(content))
+    if not reader.fieldnames:
+        raise ValueError("CSV file has no header row")
+    columns = _map_columns(reader.fieldnames, source)
+    if "steamid" not in columns.values() and "player_name" not in columns.values():
+        raise ValueError("CSV must include a Steam ID or player name column")
+
+    rows: List[Dict[str, Any]] = []
+    errors: List[str] = []
+    for line, record in enumerate(reader, start=2):
+        try:
+            row = _convert(record, columns)
+        except ValueError as exc:
+            errors.append(f"line {line}: {exc}")
+            continue
+        if not row.get("steamid") and not row.get("player_name"):
+            errors.append(f"line {line}: missing Steam ID and player name")
+            continue
+        rows.append(row)
+    return rows, errors
+
+
+def normalise_map_name(value: str) -> str:
+    name = "".join(value.strip().lower().split())
+    return name if "_" in name and name.split("_", 1)[0] in {"de", "cs", "ar", "dz"} else f"de_{name}"
+
+
+def _sniff(content: str) -> type[csv.Dialect]:
+    try:
+        return csv.Sniffer().sniff(content[:4096], delimiters=",;\t")
+    except csv.Error:
+        return csv.excel
+
+
+def _normalise_header(header: str) -> str:
+    return " ".join(header.replace("_", " ").strip().lower().split())
+
+
+def _map_columns(headers: Iterable[str], source: str) -> Dict[str, str]:
+    lookup: Dict[str, str] = {}
+    for aliases in (SOURCE_ALIASES[source], COLUMN_ALIASES):
+        for field, names in aliases.items():
+            for name in names:
+                lookup.setdefault(name, field)
+
+    columns: Dict[str, str] = {}
+    taken = set()
+    for header in headers:
+        field = lookup.get(_normalise_header(header))
+        if field and field not in taken:
+            columns[header] = field
+            taken.add(field)
+    return columns
+
+
+def _convert(record: Dict[str, Optional[str]], columns: Dict[str, str]) -> Dict[str, Any]:
+    row: Dict[str, Any] = {"extra": {}}
+    for header, raw in record.items():
+        if header is None:
+            continue
+        value = (raw or "").strip()
+        field = columns.get(header)
+        if field is None:
+            if value:
+                row["extra"][header] = value
+            continue
+        if not value:
+            row[field] = None
+        elif field in INTEGER_FIELDS:
+            row[field] = int(float(_number(value, header)))
+        elif field in FLOAT_FIELDS:
+            row[field] = float(_number(value, header))
+        elif field == "played_at":
+            row[field] = _parse_date(value)
+        elif field == "map_name":
+            row[field] = normalise_map_name(value)
+        else:
+            row[field] = value
+    return row
+
+
+def _number(value: str, header: str) -> str:
+    cleaned = value.rstrip("%").strip()
+    if cleaned.count(",") == 1 and "." not in cleaned:
+        cleaned = cleaned.replace(",", ".")  # decimal comma from European locales
+    try:
+        float(cleaned)
+    except ValueError:
+        raise ValueError(f"{header} is not a number: {value!r}") from None
+    return cleaned
+
+
+def _parse_date(value: str) -> datetime:
+    try:
+        parsed = datetime.fromisoformat(value.replace("Z", "+00:00"))
+    except ValueError:
+        for fmt in _DATE_FORMATS:
+            try:
+                parsed = datetime.strptime(value, fmt)
+                break
+            except ValueError:
+                continue
+        else:
+            raise ValueError(f"unrecognised date {value!r}") from None
+    if parsed.tzinfo is not None:
+        parsed = parsed.astimezone(timezone.utc).replace(tzinfo=None)
+    return parsed
@@ -0,0 +1,53 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Any, Dict, Optional
+
+from sqlalchemy import JSON, DateTime, Float, ForeignKey, Integer, String, UniqueConstraint
+from sqlalchemy.orm import Mapped, mapped_column
+
+from ...core.database import Base
+from ...core.ids import new_uuid
+from ..demos.models import Match  # noqa: F401 - registers the matches FK target
+
+
+class PlayerMatchStat(Base):
+    """One player's scoreboard line for one match, from a demo or an external tool."""
+
+    __tablename__ = "player_match_stats"
+    __table_args__ = (UniqueConstraint("source", "match_key", "player_key", name="uq_player_match_stat"),)
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    source: Mapped[str] = mapped_column(String(32), nullable=False, index=True)
+    match_id: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("matches.id"), index=True)
+    match_key: Mapped[str] = mapped_column(String(255), nullable=False)
+    player_key: Mapped[str] = mapped_column(String(255), nullable=False)
+    external_match_id: Mapped[Optional[str]] = mapped_column(String(128))
+    played_at: Mapped[Optional[datetime]] = mapped_column(DateTime, index=True)
+    map_name: Mapped[Optional[str]] = mapped_column(String(64), index=True)
+    steamid: Mapped[Optional[str]] = mapped_column(String(32), index=True)
+    player_name: Mapped[Optional[str]] = mapped_column(String(255))
+    team_name: Mapped[Optional[str]] = mapped_column(String(255))
+    rounds: Mapped[Optional[int]] = mapped_column(Integer)
+    kills: Mapped[Optional[int]] = mapped_column(Integer)
+    deaths: Mapped[Optional[int]] = mapped_column(Integer)
+    assists: Mapped[Optional[int]] = mapped_column(Integer)
+    flash_assists: Mapped[Optional[int]] = mapped_column(Integer)
+    adr: Mapped[Optional[float]] = mapped_column(Float)
+    kast: Mapped[Optional[float]] = mapped_column(Float)
+    headshot_pct: Mapped[Optional[float]] = mapped_column(Float)
+    rating: Mapped[Optional[float]] = mapped_column(Float)
+    opening_kills: Mapped[Optional[int]] = mapped_column(Integer)
+    opening_deaths: Mapped[Optional[int]] = mapped_column(Integer)
+    clutches_played: Mapped[Optional[int]] = mapped_column(Integer)
+    clutches_won: Mapped[Optional[int]] = mapped_column(Integer)
+    kills_2k: Mapped[Optional[int]] = mapped_column(Integer)
+    kills_3k: Mapped[Optional[int]] = mapped_column(Integer)
+    kills_4k: Mapped[Optional[int]] = mapped_column(Integer)
+    kills_5k: Mapped[Optional[int]] = mapped_column(Integer)
+    blind_seconds: Mapped[Optional[float]] = mapped_column(Float)
+    flashed_by_enemies: Mapped[Optional[int]] = mapped_column(Integer)
+    flashed_by_teammates: Mapped[Optional[int]] = mapped_column(Integer)
+    fire_damage_taken: Mapped[Optional[int]] = mapped_column(Integer)
+    extra: Mapped[Dict[str, Any]] = mapped_column(JSON, default=dict)
+    imported_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
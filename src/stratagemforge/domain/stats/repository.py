@@ -0,0 +1,96 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+from typing import List, Optional
+
+from sqlalchemy import delete, func, or_, select
+from sqlalchemy.orm import Session
+
+from ..competitions.models import Competition
+from ..demos.models import Demo, Match, MatchPlayer
+from .models import PlayerMatchStat
+
+
+class StatsRepository:
+    """Data access layer for per-player match statistics."""
+
+    def __init__(self, session: Session):
+        self.session = session
+
+    def list(
+        self,
+        steamid: Optional[str] = None,
+        source: Optional[str] = None,
+        map_name: Optional[str] = None,
+        match_id: Optional[str] = None,
+        played_from: Optional[datetime] = None,
+        primary_only: bool = False,
+        competition_format: Optional[str] = None,
+    ) -> List[PlayerMatchStat]:
+        """Stat rows, newest first; ``primary_only`` drops rows of alternate sources of a game.
+
+        ``competition_format`` keeps rows linked to a match whose demo belongs
+        to a competition of that format (e.g. ``bo3``), ignoring case.
+        """
+
+        stmt = select(PlayerMatchStat).order_by(PlayerMatchStat.played_at.desc(), PlayerMatchStat.player_name)
+        if primary_only:
+            stmt = stmt.outerjoin(Match, Match.id == PlayerMatchStat.match_id).where(
+                or_(Match.id.is_(None), Match.is_primary.is_(True))
+            )
+        if played_from:
+            stmt = stmt.where(PlayerMatchStat.played_at >= played_from)
+        if steamid:
+            stmt = stmt.where(PlayerMatchStat.steamid == steamid)
+        if source:
+            stmt = stmt.where(PlayerMatchStat.source == source)
+        if map_name:
+            stmt = stmt.where(PlayerMatchStat.map_name == map_name)
+        if match_id:
+            stmt = stmt.where(PlayerMatchStat.match_id == match_id)
+        if competition_format:
+            in_format = (
+                select(Match.id)
+                .join(Demo, Demo.id == Match.demo_id)
+                .join(Competition, Competition.id == Demo.competition_id)
+                .where(func.lower(Competition.format) == competition_format.lower())
+            )
+            stmt = stmt.where(PlayerMatchStat.match_id.in_(in_format))
+        return list(self.session.scalars(stmt).all())
+
+    def get_by_key(self, source: str, match_key: str, player_key: str) -> Optional[PlayerMatchStat]:
+        stmt = select(PlayerMatchStat).where(
+            PlayerMatchStat.source == source,
+            PlayerMatchStat.match_key == match_key,
+            PlayerMatchStat.player_key == player_key,
+        )
+        return self.session.scalars(stmt).first()
+
+    def replace_demo_stats(self, demo_id: str, stats: List[PlayerMatchStat]) -> None:
+        """Swap the demo-derived stats for ``demo_id``; callers commit."""
+
+        self.session.execute(
+            delete(PlayerMatchStat).where(PlayerMatchStat.source == "demo", PlayerMatchStat.match_key == demo_id)
+        )
+        self.session.add_all(stats)
+
+    def linked_to(self, match_id: str) -> List[PlayerMatchStat]:
+        stmt = select(PlayerMatchStat).where(PlayerMatchStat.match_id == match_id)
+        return list(self.session.scalars(stmt).all())
+
+    def find_match(self, steamid: str, map_name: str, played_at: datetime, window: timedelta) -> Optional[Match]:
+        """Find a demo-backed match the player appeared in on the same map around ``played_at``."""
+
+        stmt = (
+            select(Match)
+            .join(MatchPlayer, MatchPlayer.match_id == Match.id)
+            .join(Demo, Demo.id == Match.demo_id)
+            .where(
+                Demo.deleted_at.is_(None),
+                MatchPlayer.steamid == steamid,
+                Match.map_name == map_name,
+                Match.played_at.between(played_at - window, played_at + window),
+            )
+            .order_by(Match.played_at)
+        )
+        return self.session.scalars(stmt).first()
@@ -0,0 +1,89 @@
+from __future__ import annotations
+
+from typing import Any, Dict, List, Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+
+class PlayerMatchStatSummary(BaseModel):
+    id: str
+    source: str
+    match_id: Optional[str] = None
+    external_match_id: Optional[str] = None
+    played_at: Optional[UtcDateTime] = None
+    map_name: Optional[str] = None
+    steamid: Optional[str] = None
+    player_name: Optional[str] = None
+    team_name: Optional[str] = None
+    rounds: Optional[int] = None
+    kills: Optional[int] = None
+    deaths: Optional[int] = None
+    assists: Optional[int] = None
+    flash_assists: Optional[int] = None
+    adr: Optional[float] = None
+    kast: Optional[float] = None
+    headshot_pct: Optional[float] = None
+    rating: Optional[float] = None
+    opening_kills: Optional[int] = None
+    opening_deaths: Optional[int] = None
+    clutches_played: Optional[int] = None
+    clutches_won: Optional[int] = None
+    kills_2k: Optional[int] = None
+    kills_3k: Optional[int] = None
+    kills_4k: Optional[int] = None
+    kills_5k: Optional[int] = None
+    blind_seconds: Optional[float] = Field(default=None, description="Total time spent blinded by flashes")
+    flashed_by_enemies: Optional[int] = None
+    flashed_by_teammates: Optional[int] = None
+    fire_damage_taken: Optional[int] = Field(default=None, description="Molotov and incendiary damage taken")
+    extra: Dict[str, Any] = Field(default_factory=dict)
+
+    class Config:
+        orm_mode = True
+
+
+class MetricPercentile(BaseModel):
+    metric: str
+    value: Optional[float] = None
+    percentile: Optional[float] = Field(default=None, description="Share (0-100) of players this value beats")
+    higher_is_better: bool
+    players: int = Field(description="Players in the dataset with this metric")
+    median: Optional[float] = None
+
+
+class PlayerPercentiles(BaseModel):
+    steamid: str
+    player_name: Optional[str] = None
+    matches: int
+    rounds: int
+    format: Optional[str] = None
+    map_name: Optional[str] = None
+    metrics: List[MetricPercentile]
+    generated_at: UtcDateTime
+
+
+class MetricBenchmark(BaseModel):
+    metric: str
+    higher_is_better: bool
+    players: int
+    quantiles: Dict[str, Optional[float]] = Field(description="p10, p25, p50, p75 and p90 across players")
+    value: Optional[float] = Field(default=None, description="The value asked about, if any")
+    percentile: Optional[float] = Field(default=None, description="Share (0-100) of players the value beats")
+
+
+class Benchmarks(BaseModel):
+    format: Optional[str] = None
+    map_name: Optional[str] = None
+    players: int
+    metrics: List[MetricBenchmark]
+    generated_at: UtcDateTime
+
+
+class StatsImportResult(BaseModel):
+    source: str
+    imported: int
+    updated: int
+    linked: int = Field(description="Rows matched to a demo-backed match by player, map and date")
+    errors: List[str] = Field(default_factory=list)
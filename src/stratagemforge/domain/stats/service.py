@@ -0,0 +1,180 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+from typing import Any, Dict, Optional
+
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ..demos.models import Match
+from .benchmarks import (
+    BENCHMARK_METRICS,
+    BENCHMARK_QUANTILES,
+    HIGHER_IS_BETTER,
+    metric_population,
+    percentile_rank,
+    player_metrics,
+    quantile,
+)
+from .importers import parse_stats_csv
+from .models import PlayerMatchStat
+from .repository import StatsRepository
+from .schemas import Benchmarks, MetricBenchmark, MetricPercentile, PlayerPercentiles, StatsImportResult
+
+# Third-party exports record when the match finished, not when the demo was uploaded.
+MATCH_LINK_WINDOW = timedelta(hours=12)
+
+
+class StatsService:
+    """Store per-player match statistics and import history from other tools."""
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+
+    def import_csv(self, session: Session, content: bytes, source: str) -> StatsImportResult:
+        """Import an exported stats CSV (Leetify, Scope.gg).
+
+        Rows are keyed by source, match and player, so importing the same export
+        twice updates the existing rows instead of duplicating them.
+        """
+
+        try:
+            text = content.decode("utf-8-sig")
+        except UnicodeDecodeError:
+            text = content.decode("latin-1")
+        rows, errors = parse_stats_csv(text, source)
+
+        repo = StatsRepository(session)
+        imported = updated = linked = 0
+        for row in rows:
+            match_key = _match_key(row)
+            player_key = row.get("steamid") or row["player_name"].casefold()
+            stat = repo.get_by_key(source, match_key, player_key)
+            if stat is None:
+                stat = PlayerMatchStat(source=source, match_key=match_key, player_key=player_key)
+                session.add(stat)
+                session.flush()  # later rows in the same file may share this key
+                imported += 1
+            else:
+                updated += 1
+            for field, value in row.items():
+                setattr(stat, field, value)
+            match = self._link_match(repo, row)
+            stat.match_id = match.id if match else None
+            linked += match is not None
+
+        session.commit()
+        return StatsImportResult(source=source, imported=imported, updated=updated, linked=linked, errors=errors)
+
+    def list_player_stats(
+        self,
+        session: Session,
+        steamid: Optional[str] = None,
+        source: Optional[str] = None,
+        map_name: Optional[str] = None,
+        match_id: Optional[str] = None,
+    ) -> list[PlayerMatchStat]:
+        return StatsRepository(session).list(steamid=steamid, source=source, map_name=map_name, match_id=match_id)
+
+    def percentiles(
+        self,
+        session: Session,
+        steamid: str,
+        competition_format: Optional[str] = None,
+        map_name: Optional[str] = None,
+        min_rounds: int = 0,
+    ) -> PlayerPercentiles:
+        """Rank one player's career metrics against every player in the dataset (see :mod:`.benchmarks`).
+
+        The player is ranked even with fewer than ``min_rounds`` rounds; the
+        threshold only keeps small samples out of the population.
+        """
+
+        repo = StatsRepository(session)
+        stats = repo.list(map_name=map_name, primary_only=True, competition_format=competition_format)
+        everyone = player_metrics(stats)
+        player = everyone.get(steamid)
+        if player is None:
+            raise LookupError("No stats for this player")
+        population = {key: value for key, value in everyone.items() if value["rounds"] >= min_rounds}
+        metrics = []
+        for metric, higher_is_better in BENCHMARK_METRICS:
+            values = metric_population(population, metric)
+            value = player["values"].get(metric)
+            metrics.append(
+                MetricPercentile(
+                    metric=metric,
+                    value=_rounded(value),
+                    percentile=percentile_rank(value, values, higher_is_better) if value is not None else None,
+                    higher_is_better=higher_is_better,
+                    players=len(values),
+                    median=_rounded(quantile(values, 50)),
+                )
+            )
+        return PlayerPercentiles(
+            steamid=steamid,
+            player_name=player["name"],
+            matches=player["matches"],
+            rounds=player["rounds"],
+            format=competition_format,
+            map_name=map_name,
+            metrics=metrics,
+            generated_at=datetime.utcnow(),
+        )
+
+    def benchmarks(
+        self,
+        session: Session,
+        competition_format: Optional[str] = None,
+        map_name: Optional[str] = None,
+        min_rounds: int = 0,
+        metric: Optional[str] = None,
+        value: Optional[float] = None,
+    ) -> Benchmarks:
+        """Distribution of each metric across players; with ``metric`` and ``value``, where that value ranks."""
+
+        if metric is not None and metric not in HIGHER_IS_BETTER:
+            raise ValueError(f"Unknown metric {metric!r}; choose one of {', '.join(HIGHER_IS_BETTER)}")
+        repo = StatsRepository(session)
+        stats = repo.list(map_name=map_name, primary_only=True, competition_format=competition_format)
+        population = player_metrics(stats, min_rounds=min_rounds)
+        benchmarks = []
+        for name, higher_is_better in BENCHMARK_METRICS:
+            if metric is not None and name != metric:
+                continue
+            values = metric_population(population, name)
+            asked = metric is not None and value is not None
+            benchmarks.append(
+                MetricBenchmark(
+                    metric=name,
+                    higher_is_better=higher_is_better,
+                    players=len(values),
+                    quantiles={f"p{percent}": _rounded(quantile(values, percent)) for percent in BENCHMARK_QUANTILES},
+                    value=value if asked else None,
+                    percentile=percentile_rank(value, values, higher_is_better) if asked else None,
+                )
+            )
+        return Benchmarks(
+            format=competition_format,
+            map_name=map_name,
+            players=len(population),
+            metrics=benchmarks,
+            generated_at=datetime.utcnow(),
+        )
+
+    @staticmethod
+    def _link_match(repo: StatsRepository, row: Dict[str, Any]) -> Optional[Match]:
+        if not (row.get("steamid") and row.get("map_name") and row.get("played_at")):
+            return None
+        return repo.find_match(row["steamid"], row["map_name"], row["played_at"], MATCH_LINK_WINDOW)
+
+
+def _rounded(value: Optional[float]) -> Optional[float]:
+    return round(value, 3) if value is not None else None
+
+
+def _match_key(row: Dict[str, Any]) -> str:
+    if row.get("external_match_id"):
+        return str(row["external_match_id"])
+    played_at = row.get("played_at")
+    return f"{row.get('map_name') or ''}@{played_at.isoformat() if played_at else ''}"
@@ -0,0 +1,68 @@
+from __future__ import annotations
+
+from datetime import datetime
+from typing import Dict, List, Optional
+
+from sqlalchemy import Boolean, DateTime, ForeignKey, JSON, String, UniqueConstraint
+from sqlalchemy.orm import Mapped, mapped_column, relationship
+
+from ...core.database import Base
+from ...core.ids import new_uuid
+from ..users.models import User  # noqa: F401 - registers the users FK target
+
+
+class Team(Base):
+    """A roster that shares demos, a map pool and a schedule."""
+
+    __tablename__ = "teams"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    name: Mapped[str] = mapped_column(String(255), nullable=False, unique=True)
+    tag: Mapped[Optional[str]] = mapped_column(String(16))
+    map_pool: Mapped[List[str]] = mapped_column(JSON, default=list)
+    # Overrides of the default permissions (action -> lowest team role), see ``policy.TeamPolicy``.
+    permissions: Mapped[Dict[str, str]] = mapped_column(JSON, default=dict)
+    usage_analytics_opt_out: Mapped[bool] = mapped_column(Boolean, default=False, nullable=False)
+    # STORAGE_REGIONS entry new uploads of the team are stored in; None uses the default backend.
+    storage_region: Mapped[Optional[str]] = mapped_column(String(64))
+    created_by: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("users.id"))
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+    invites: Mapped[List["TeamInvite"]] = relationship(
+        back_populates="team", cascade="all, delete-orphan", order_by="TeamInvite.created_at"
+    )
+    members: Mapped[List["TeamMember"]] = relationship(
+        back_populates="team", cascade="all, delete-orphan", order_by="TeamMember.joined_at"
+    )
+
+
+class TeamMember(Base):
+    """A user on a team's roster; coaches manage the roster and its roles."""
+
+    __tablename__ = "team_members"
+    __table_args__ = (UniqueConstraint("team_id", "user_id"),)
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    team_id: Mapped[str] = mapped_column(String(36), ForeignKey("teams.id"), nullable=False, index=True)
+    user_id: Mapped[str] = mapped_column(String(36), ForeignKey("users.id"), nullable=False, index=True)
+    role: Mapped[str] = mapped_column(String(32), default="player", nullable=False)  # player, analyst or coach
+    joined_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+    team: Mapped[Team] = relationship(back_populates="members")
+
+
+class TeamInvite(Base):
+    """An emailed invitation to join a team; only a hash of the invite token is stored."""
+
+    __tablename__ = "team_invites"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    team_id: Mapped[str] = mapped_column(String(36), ForeignKey("teams.id"), nullable=False, index=True)
+    email: Mapped[str] = mapped_column(String(255), nullable=False)
+    role: Mapped[str] = mapped_column(String(32), default="player", nullable=False)  # team role on acceptance
+    token_hash: Mapped[str] = mapped_column(String(64), nullable=False, unique=True)
+    invited_by: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("users.id"))
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    accepted_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+    team: Mapped[Team] = relationship(back_populates="invites")
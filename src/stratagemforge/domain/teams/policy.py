@@ -0,0 +1,59 @@
+"""Per-team permission defaults, evaluated the same way by every service.
+
+A team's policy names, for each action on the team's resources, the lowest
+team role that may take it (``player`` means every member). Teams override
+the defaults on ``Team.permissions``. Platform admins, and for a demo its
+uploader, are not subject to the policy; callers check those first.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass, field
+from typing import Dict, Mapping, Optional
+
+from ..users.roles import TEAM_ROLES, role_at_least
+
+# Action -> what it covers.
+TEAM_ACTIONS: Dict[str, str] = {
+    "view": "see the team's demos and matches",
+    "upload": "upload demos for the team",
+    "share": "change who can see the team's demos",
+    "delete": "trash and restore the team's demos and stop its live recordings",
+    "manage_members": "invite members, change their roles and remove them",
+}
+DEFAULT_PERMISSIONS: Dict[str, str] = {
+    "view": "player",
+    "upload": "player",
+    "share": "coach",
+    "delete": "coach",
+    "manage_members": "coach",
+}
+
+
+@dataclass(frozen=True)
+class TeamPolicy:
+    """The lowest team role allowed to take each action."""
+
+    rules: Mapping[str, str] = field(default_factory=lambda: dict(DEFAULT_PERMISSIONS))
+
+    @classmethod
+    def from_overrides(cls, overrides: Optional[Mapping[str, str]]) -> "TeamPolicy":
+        return cls({**DEFAULT_PERMISSIONS, **(overrides or {})})
+
+    def allows(self, role: Optional[str], action: str) -> bool:
+        """Whether a member with team ``role`` may take ``action``; non-members (``None``) never may."""
+
+        if action not in TEAM_ACTIONS:
+            raise ValueError(f"Unknown team action {action}")
+        return role is not None and role_at_least(role, self.rules[action])
+
+
+def validate_permissions(changes: Mapping[str, str]) -> Dict[str, str]:
+    """Check a partial policy update: known actions mapped to team roles."""
+
+    for action, role in changes.items():
+        if action not in TEAM_ACTIONS:
+            raise ValueError(f"Unknown team action {action}; choose one of {', '.join(TEAM_ACTIONS)}")
+        if role not in TEAM_ROLES:
+            raise ValueError(f"Unknown team role {role}; choose one of {', '.join(TEAM_ROLES)}")
+    return dict(changes)
@@ -0,0 +1,64 @@
+from __future__ import annotations
+
+from typing import Dict, List, Literal, Optional
+
+from pydantic import BaseModel, EmailStr, Field
+
+from ...core.timeutil import UtcDateTime
+from ..onboarding.schemas import InviteSummary
+
+TeamRole = Literal["player", "analyst", "coach"]
+
+
+class TeamCreate(BaseModel):
+    name: str = Field(min_length=1, max_length=255)
+    tag: Optional[str] = Field(default=None, max_length=16)
+
+
+class TeamMemberSummary(BaseModel):
+    user_id: str
+    role: str
+    joined_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class TeamSummary(BaseModel):
+    id: str
+    name: str
+    tag: Optional[str] = None
+    map_pool: List[str] = Field(default_factory=list)
+    storage_region: Optional[str] = None
+    created_by: Optional[str] = None
+    created_at: UtcDateTime
+    members: List[TeamMemberSummary] = Field(default_factory=list)
+    invites: List[InviteSummary] = Field(default_factory=list)
+
+    class Config:
+        orm_mode = True
+
+
+class TeamInviteCreate(BaseModel):
+    email: EmailStr
+    role: TeamRole = "player"
+
+
+class InviteAcceptance(BaseModel):
+    token: str
+
+
+class MemberRoleUpdate(BaseModel):
+    role: TeamRole
+
+
+class TeamPermissions(BaseModel):
+    """The lowest team role allowed to take each action; ``overrides`` are the team's changes to the defaults."""
+
+    permissions: Dict[str, TeamRole]
+    overrides: Dict[str, TeamRole] = Field(default_factory=dict)
+    actions: Dict[str, str] = Field(default_factory=dict, description="What each action covers")
+
+
+class TeamPermissionsUpdate(BaseModel):
+    permissions: Dict[str, TeamRole] = Field(description="Actions to change, e.g. {\"upload\": \"analyst\"}")
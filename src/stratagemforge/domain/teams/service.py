@@ -0,0 +1,174 @@
+from __future__ import annotations
+
+import hashlib
+import secrets
+from datetime import datetime
+from typing import List, Optional
+
+from sqlalchemy import func, select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ..onboarding.schemas import InviteSummary, IssuedInvite
+from ..users.models import User
+from ..users.roles import TEAM_ROLES, role_at_least
+from .models import Team, TeamInvite, TeamMember
+from .policy import TeamPolicy, validate_permissions
+
+
+class TeamService:
+    """Teams, their rosters and who may manage them.
+
+    Any member sees the team; members the team's policy allows (coaches by
+    default) and platform admins, passed in as ``admin=True``, invite people,
+    change roles and remove members. Members may always leave. A team keeps
+    at least one coach, and only coaches change the policy itself.
+    """
+
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+
+    def create_team(self, session: Session, user_id: str, name: str, tag: Optional[str] = None) -> Team:
+        name = name.strip()
+        if session.scalars(select(Team.id).where(func.lower(Team.name) == name.lower())).first():
+            raise ValueError(f"Team {name} already exists")
+        team = Team(name=name, tag=tag, created_by=user_id)
+        session.add(team)
+        session.flush()
+        session.add(TeamMember(team_id=team.id, user_id=user_id, role="coach"))
+        session.commit()
+        session.refresh(team)
+        return team
+
+    def list_teams(self, session: Session, user_id: str, admin: bool = False) -> List[Team]:
+        stmt = select(Team).order_by(Team.name)
+        if not admin:
+            stmt = stmt.where(Team.id.in_(select(TeamMember.team_id).where(TeamMember.user_id == user_id)))
+        return list(session.scalars(stmt).all())
+
+    def get_team(self, session: Session, team_id: str, user_id: str, admin: bool = False) -> Team:
+        team = session.get(Team, team_id)
+        # Teams the caller is not on look the same as missing ones.
+        if not team or not (admin or self.membership(session, team_id, user_id)):
+            raise LookupError("Team not found")
+        return team
+
+    def membership(self, session: Session, team_id: str, user_id: str) -> Optional[TeamMember]:
+        return session.scalars(
+            select(TeamMember).where(TeamMember.team_id == team_id, TeamMember.user_id == user_id)
+        ).first()
+
+    def team_ids(self, session: Session, user_id: str, action: Optional[str] = None) -> List[str]:
+        """Teams the user is on; with ``action``, only those whose policy lets the user's role take it."""
+
+        if action is None:
+            return list(session.scalars(select(TeamMember.team_id).where(TeamMember.user_id == user_id)).all())
+        rows = session.execute(
+            select(TeamMember.team_id, TeamMember.role, Team.permissions)
+            .join(Team, Team.id == TeamMember.team_id)
+            .where(TeamMember.user_id == user_id)
+        )
+        policies = ((team_id, role, TeamPolicy.from_overrides(overrides)) for team_id, role, overrides in rows)
+        return [team_id for team_id, role, policy in policies if policy.allows(role, action)]
+
+    def policy(self, session: Session, team_id: str) -> TeamPolicy:
+        team = session.get(Team, team_id)
+        return TeamPolicy.from_overrides(team.permissions if team else None)
+
+    def can(self, session: Session, team_id: str, user_id: str, action: str) -> bool:
+        """Whether the team's policy lets ``user_id`` take ``action`` on the team's resources."""
+
+        member = self.membership(session, team_id, user_id)
+        return member is not None and self.policy(session, team_id).allows(member.role, action)
+
+    def set_permissions(
+        self, session: Session, team_id: str, actor_id: str, changes: Dict[str, str], admin: bool = False
+    ) -> Team:
+        """Override some of the team's default permissions; only coaches and admins may."""
+
+        team = self.get_team(session, team_id, actor_id, admin)
+        if not admin:
+            member = self.membership(session, team_id, actor_id)
+            if not member or not role_at_least(member.role, "coach"):
+                raise PermissionError("Only the team's coaches can change its permissions")
+        team.permissions = {**(team.permissions or {}), **validate_permissions(changes)}
+        session.commit()
+        return team
+
+    def invite(
+        self, session: Session, team_id: str, actor_id: str, email: str, role: str = "player", admin: bool = False
+    ) -> IssuedInvite:
+        team = self._manageable(session, team_id, actor_id, admin)
+        _check_role(role)
+        email = email.lower()
+        for pending in team.invites:
+            if pending.email.lower() == email and pending.accepted_at is None:
+                # Re-inviting replaces the old link, e.g. to change the offered role.
+                session.delete(pending)
+        token = secrets.token_urlsafe(32)
+        invite = TeamInvite(team_id=team.id, email=email, role=role, token_hash=_hash(token), invited_by=actor_id)
+        session.add(invite)
+        session.commit()
+        return IssuedInvite(**InviteSummary.from_orm(invite).model_dump(), token=token)
+
+    def accept_invite(self, session: Session, token: str, user_id: str) -> Team:
+        invite = session.scalars(select(TeamInvite).where(TeamInvite.token_hash == _hash(token))).first()
+        if not invite or invite.accepted_at is not None:
+            raise LookupError("Invite not found")
+        user = session.get(User, user_id)
+        if not user or (user.email or "").lower() != invite.email.lower():
+            raise PermissionError("This invite was sent to another email address")
+        member = self.membership(session, invite.team_id, user_id)
+        if member is None:
+            session.add(TeamMember(team_id=invite.team_id, user_id=user_id, role=invite.role))
+        invite.accepted_at = datetime.utcnow()
+        session.commit()
+        return invite.team
+
+    def set_member_role(
+        self, session: Session, team_id: str, actor_id: str, user_id: str, role: str, admin: bool = False
+    ) -> TeamMember:
+        self._manageable(session, team_id, actor_id, admin)
+        _check_role(role)
+        member = self.membership(session, team_id, user_id)
+        if not member:
+            raise LookupError("Team member not found")
+        if member.role == "coach" and role != "coach":
+            self._keep_a_coach(session, team_id)
+        member.role = role
+        session.commit()
+        return member
+
+    def remove_member(self, session: Session, team_id: str, actor_id: str, user_id: str, admin: bool = False) -> None:
+        if actor_id != user_id:
+            self._manageable(session, team_id, actor_id, admin)
+        member = self.membership(session, team_id, user_id)
+        if not member:
+            raise LookupError("Team member not found")
+        if member.role == "coach":
+            self._keep_a_coach(session, team_id)
+        session.delete(member)
+        session.commit()
+
+    def _manageable(self, session: Session, team_id: str, actor_id: str, admin: bool) -> Team:
+        team = self.get_team(session, team_id, actor_id, admin)
+        if not admin and not self.can(session, team_id, actor_id, "manage_members"):
+            raise PermissionError("Your team role cannot manage this team's roster")
+        return team
+
+    @staticmethod
+    def _keep_a_coach(session: Session, team_id: str) -> None:
+        coaches = session.scalar(
+            select(func.count(TeamMember.id)).where(TeamMember.team_id == team_id, TeamMember.role == "coach")
+        )
+        if (coaches or 0) <= 1:
+            raise ValueError("A team needs at least one coach")
+
+
+def _check_role(role: str) -> None:
+    if role not in TEAM_ROLES:
+        raise ValueError(f"Unknown team role {role}; choose one of {', '.join(TEAM_ROLES)}")
+
+
+def _hash(token: str) -> str:
+    return hashlib.sha256(token.encode()).hexdigest()
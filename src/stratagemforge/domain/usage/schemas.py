@@ -0,0 +1,56 @@
+from __future__ import annotations
+
+from datetime import date
+from typing import Any, Dict, List, Literal, Optional
+
+from pydantic import BaseModel, Field
+
+from ...core.timeutil import UtcDateTime
+
+EventSource = Literal["frontend", "service"]
+
+
+class UsageEventIn(BaseModel):
+    name: str = Field(description="Event name, e.g. report.generated or replay.viewed")
+    team_id: Optional[str] = None
+    user_id: Optional[str] = Field(default=None, description="Hashed before storage")
+    properties: Dict[str, Any] = Field(default_factory=dict)
+    occurred_at: Optional[UtcDateTime] = None
+
+
+class UsageEventBatch(BaseModel):
+    source: EventSource = "frontend"
+    events: List[UsageEventIn] = Field(min_length=1, max_length=500)
+
+
+class UsageIngestResult(BaseModel):
+    accepted: int
+    dropped: int = Field(description="Events from opted-out teams or with invalid names")
+
+
+class UsageOptOut(BaseModel):
+    opted_out: bool
+
+
+class UsageOptOutStatus(BaseModel):
+    team_id: str
+    opted_out: bool
+
+
+class UsageEventCount(BaseModel):
+    name: str
+    events: int
+    teams: int
+    users: int
+
+
+class UsageSummary(BaseModel):
+    since: UtcDateTime
+    until: UtcDateTime
+    events: List[UsageEventCount]
+
+
+class UsageExportResult(BaseModel):
+    day: date
+    rows: int
+    key: Optional[str] = None
@@ -0,0 +1,175 @@
+from __future__ import annotations
+
+import hashlib
+import json
+import os
+import re
+import secrets
+import tempfile
+from datetime import date, datetime, time, timedelta
+from pathlib import Path
+from typing import Any, Dict, Iterable, Optional, Tuple
+
+import pandas as pd
+from sqlalchemy import func, select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from ...core.parquet import write_parquet
+from ...core.timeutil import to_utc_naive
+from ...core.storage import ArtifactStorage, build_storage
+from ..teams.models import Team
+from .models import UsageEvent
+from .schemas import UsageEventCount, UsageEventIn, UsageExportResult, UsageSummary
+
+_NAME = re.compile(r"^[a-z][a-z0-9_]*(\.[a-z0-9_]+)*$")
+# Property keys that could identify a person are dropped rather than stored.
+_PERSONAL_KEYS = {"email", "ip", "ip_address", "name", "player_name", "steamid", "token", "user_id", "username"}
+MAX_PROPERTIES = 20
+MAX_VALUE_LENGTH = 200
+EXPORT_COLUMNS = ["id", "name", "source", "team_id", "user_hash", "properties", "occurred_at", "received_at"]
+EXPORT_SCHEMA_VERSION = 1
+
+
+class UsageService:
+    """Record which analytics features get used, without keeping personal data.
+
+    User ids are replaced by a salted SHA-256, property values are limited to
+    short scalars, and teams that opted out are never recorded. The salt is
+    ``USAGE_HASH_SALT``, or a random one generated once under ``data/usage/``
+    so hashes stay stable across restarts.
+    """
+
+    def __init__(self, settings: Settings, storage: ArtifactStorage | None = None) -> None:
+        self.settings = settings
+        self.storage = storage or build_storage(settings)
+        self._salt: Optional[str] = None
+
+    @property
+    def salt_path(self) -> Path:
+        return self.settings.data_dir / "usage" / "hash-salt"
+
+    @property
+    def salt(self) -> str:
+        if self._salt is None:
+            self._salt = self.settings.usage_hash_salt or self._load_or_create_salt()
+        return self._salt
+
+    def record(self, session: Session, events: Iterable[UsageEventIn], source: str = "service") -> Tuple[int, int]:
+        """Store ``events`` and return (accepted, dropped)."""
+
+        events = list(events)
+        team_ids = {event.team_id for event in events if event.team_id}
+        opted_out = set(
+            session.scalars(
+                select(Team.id).where(Team.id.in_(team_ids), Team.usage_analytics_opt_out.is_(True))
+            ).all()
+        ) if team_ids else set()
+
+        accepted = 0
+        now = datetime.utcnow()
+        for event in events:
+            if not self.settings.usage_events_enabled or event.team_id in opted_out or not _valid_name(event.name):
+                continue
+            session.add(
+                UsageEvent(
+                    name=event.name,
+                    source=source,
+                    team_id=event.team_id,
+                    user_hash=self._hash_user(event.user_id),
+                    properties=_scrub(event.properties),
+                    occurred_at=to_utc_naive(event.occurred_at) if event.occurred_at else now,
+                    received_at=now,
+                )
+            )
+            accepted += 1
+        session.commit()
+        return accepted, len(events) - accepted
+
+    def set_opt_out(self, session: Session, team_id: str, opted_out: bool) -> Team:
+        team = session.get(Team, team_id)
+        if not team:
+            raise LookupError("Team not found")
+        team.usage_analytics_opt_out = opted_out
+        if opted_out:
+            # Opting out also forgets what was recorded before.
+            for event in session.scalars(select(UsageEvent).where(UsageEvent.team_id == team_id)):
+                session.delete(event)
+        session.commit()
+        return team
+
+    def summary(self, session: Session, since: datetime, until: datetime) -> UsageSummary:
+        since, until = to_utc_naive(since), to_utc_naive(until)
+        stmt = (
+            select(
+                UsageEvent.name,
+                func.count(UsageEvent.id),
+                func.count(func.distinct(UsageEvent.team_id)),
+                func.count(func.distinct(UsageEvent.user_hash)),
+            )
+            .where(UsageEvent.occurred_at >= since, UsageEvent.occurred_at < until)
+            .group_by(UsageEvent.name)
+            .order_by(func.count(UsageEvent.id).desc(), UsageEvent.name)
+        )
+        return UsageSummary(
+            since=since,
+            until=until,
+            events=[
+                UsageEventCount(name=name, events=events, teams=teams, users=users)
+                for name, events, teams, users in session.execute(stmt).all()
+            ],
+        )
+
+    def export_day(self, session: Session, day: date) -> UsageExportResult:
+        """Write one day of events to ``usage/day=<date>/events.parquet`` in artifact storage."""
+
+        start = datetime.combine(day, time.min)
+        stmt = select(UsageEvent).where(
+            UsageEvent.occurred_at >= start, UsageEvent.occurred_at < start + timedelta(days=1)
+        )
+        rows = [{column: getattr(event, column) for column in EXPORT_COLUMNS} for event in session.scalars(stmt)]
+        if not rows:
+            return UsageExportResult(day=day, rows=0)
+
+        frame = pd.DataFrame(rows, columns=EXPORT_COLUMNS)
+        frame["properties"] = frame["properties"].map(lambda value: json.dumps(value or {}, sort_keys=True))
+        with tempfile.TemporaryDirectory() as workdir:
+            path = Path(workdir) / "events.parquet"
+            write_parquet(frame, path, "usage_events", EXPORT_SCHEMA_VERSION)
+            key = self.storage.put(path, f"usage/day={day.isoformat()}/events.parquet")
+        return UsageExportResult(day=day, rows=len(rows), key=key)
+
+    def _hash_user(self, user_id: Optional[str]) -> Optional[str]:
+        if not user_id:
+            return None
+        return hashlib.sha256(f"{self.salt}:{user_id}".encode()).hexdigest()
+
+    def _load_or_create_salt(self) -> str:
+        path = self.salt_path
+        if path.exists():
+            salt = path.read_text().strip()
+            if not salt:
+                raise ValueError(f"{path} is empty; delete it or set USAGE_HASH_SALT")
+            return salt
+        salt = secrets.token_hex(32)
+        path.parent.mkdir(parents=True, exist_ok=True)
+        fd = os.open(path, os.O_WRONLY | os.O_CREAT | os.O_EXCL, 0o600)
+        with os.fdopen(fd, "w") as handle:
+            handle.write(salt)
+        return salt
+
+
+def _valid_name(name: str) -> bool:
+    return len(name) <= 64 and bool(_NAME.match(name))
+
+
+def _scrub(properties: Dict[str, Any]) -> Dict[str, Any]:
+    scrubbed: Dict[str, Any] = {}
+    for key, value in properties.items():
+        if len(scrubbed) >= MAX_PROPERTIES or key.lower() in _PERSONAL_KEYS:
+            continue
+        if isinstance(value, str):
+            scrubbed[key] = value[:MAX_VALUE_LENGTH]
+        elif value is None or isinstance(value, (bool, int, float)):
+            scrubbed[key] = value
+    return scrubbed
@@ -1,13 +1,13 @@
 from __future__ import annotations
 
 from datetime import datetime
-from typing import Optional
-from uuid import uuid4
+from typing import List, Optional
 
-from sqlalchemy import Boolean, DateTime, String
+from sqlalchemy import Boolean, DateTime, ForeignKey, Integer, JSON, String
 from sqlalchemy.orm import Mapped, mapped_column
 
 from ...core.database import Base
+from ...core.ids import new_uuid
 
 
 class User(Base):
@@ -15,10 +15,152 @@ class User(Base):
 
     __tablename__ = "users"
 
-    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=lambda: str(uuid4()))
-    email: Mapped[str] = mapped_column(String(255), unique=True, nullable=False)
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    # Accounts created by signing in through Steam have no email until the user adds one.
+    email: Mapped[Optional[str]] = mapped_column(String(255), unique=True)
     display_name: Mapped[str] = mapped_column(String(255), nullable=False)
+    steamid: Mapped[Optional[str]] = mapped_column(String(32), unique=True, index=True)
     role: Mapped[str] = mapped_column(String(64), default="analyst", nullable=False)
+    timezone: Mapped[str] = mapped_column(String(64), default="UTC", nullable=False)  # IANA name for reports
+    # Argon2id hash; users without one cannot log in with a password.
+    password_hash: Mapped[Optional[str]] = mapped_column(String(255))
     is_active: Mapped[bool] = mapped_column(Boolean, default=True, nullable=False)
     created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
     last_login_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    # Soft-deleted accounts keep their rows (and email) but can no longer sign in.
+    deleted_at: Mapped[Optional[datetime]] = mapped_column(DateTime, index=True)
+
+
+class ApiKey(Base):
+    """Machine credential for the API, sent as ``X-API-Key``.
+
+    Only the SHA-256 of the key is stored; the plain value is shown once.
+    ``scopes`` limit what the key may do (``read``, ``upload``, ``admin``).
+    """
+
+    __tablename__ = "api_keys"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    user_id: Mapped[str] = mapped_column(String(36), ForeignKey("users.id"), nullable=False, index=True)
+    name: Mapped[str] = mapped_column(String(255), nullable=False)
+    key_hash: Mapped[str] = mapped_column(String(64), nullable=False, unique=True)
+    scopes: Mapped[List[str]] = mapped_column(JSON, default=list)
+    rate_limit_per_minute: Mapped[Optional[int]] = mapped_column(Integer)  # falls back to the global limit
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    last_used_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    rotated_at: Mapped[Optional[datetime]] = mapped_column(DateTime)  # last time the secret was replaced
+    revoked_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+
+class RefreshToken(Base):
+    """Long-lived credential exchanged for new access tokens at ``/api/auth/refresh``.
+
+    Each refresh rotates the token: the used one is revoked and a new one in
+    the same ``family_id`` is issued. Presenting a revoked token again means
+    it leaked, so the whole family (that login session) is revoked.
+    """
+
+    __tablename__ = "refresh_tokens"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    user_id: Mapped[str] = mapped_column(String(36), ForeignKey("users.id"), nullable=False, index=True)
+    family_id: Mapped[str] = mapped_column(String(36), nullable=False, index=True)
+    token_hash: Mapped[str] = mapped_column(String(64), nullable=False, unique=True)
+    # OIDC scope the session was granted; access tokens of scoped sessions carry no platform scopes.
+    scope: Mapped[Optional[str]] = mapped_column(String(255))
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    expires_at: Mapped[datetime] = mapped_column(DateTime, nullable=False)
+    revoked_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+
+class RevokedAccessToken(Base):
+    """Access tokens revoked before they expire (logout); kept until ``expires_at``."""
+
+    __tablename__ = "revoked_access_tokens"
+
+    jti: Mapped[str] = mapped_column(String(64), primary_key=True)
+    user_id: Mapped[str] = mapped_column(String(36), nullable=False)
+    expires_at: Mapped[datetime] = mapped_column(DateTime, nullable=False, index=True)
+    revoked_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+
+
+class PasswordResetToken(Base):
+    """Single-use token mailed to a user who forgot their password; only its hash is stored."""
+
+    __tablename__ = "password_reset_tokens"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    user_id: Mapped[str] = mapped_column(String(36), ForeignKey("users.id"), nullable=False, index=True)
+    token_hash: Mapped[str] = mapped_column(String(64), nullable=False, unique=True)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    expires_at: Mapped[datetime] = mapped_column(DateTime, nullable=False)
+    used_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+
+class RegistrationPolicy(Base):
+    """Who may sign up; a single row (id 1) that admins edit, defaulting to the settings until they do."""
+
+    __tablename__ = "registration_policy"
+
+    id: Mapped[int] = mapped_column(Integer, primary_key=True, default=1)
+    # open, invite (an invite token is required) or domain (open to the allowed email domains).
+    mode: Mapped[str] = mapped_column(String(16), nullable=False)
+    allowed_domains: Mapped[List[str]] = mapped_column(JSON, default=list)
+    updated_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    updated_by: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("users.id"))
+
+
+class RegistrationInvite(Base):
+    """Single-use sign-up invitation with the role the new account gets; only its hash is stored."""
+
+    __tablename__ = "registration_invites"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    token_hash: Mapped[str] = mapped_column(String(64), nullable=False, unique=True)
+    email: Mapped[Optional[str]] = mapped_column(String(255))  # when set, only this address may use it
+    role: Mapped[str] = mapped_column(String(64), default="analyst", nullable=False)
+    created_by: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("users.id"))
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    expires_at: Mapped[datetime] = mapped_column(DateTime, nullable=False)
+    used_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+    used_by: Mapped[Optional[str]] = mapped_column(String(36), ForeignKey("users.id"))
+    revoked_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+
+class OidcClient(Base):
+    """An application that signs users in through the OIDC provider (``/oauth2/*``).
+
+    Confidential clients (servers) authenticate at the token endpoint with a
+    secret, of which only the SHA-256 is stored; public clients (browser apps)
+    have none and must use PKCE instead.
+    """
+
+    __tablename__ = "oidc_clients"
+
+    id: Mapped[str] = mapped_column(String(36), primary_key=True, default=new_uuid)
+    name: Mapped[str] = mapped_column(String(255), nullable=False)
+    secret_hash: Mapped[Optional[str]] = mapped_column(String(64))
+    redirect_uris: Mapped[List[str]] = mapped_column(JSON, default=list)
+    created_at: Mapped[datetime] = mapped_column(DateTime, default=datetime.utcnow, nullable=False)
+    revoked_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
+
+    @property
+    def confidential(self) -> bool:
+        return self.secret_hash is not None
+
+
+class OidcAuthorizationCode(Base):
+    """Single-use code handed out by the authorization endpoint; only its hash is stored."""
+
+    __tablename__ = "oidc_authorization_codes"
+
+    code_hash: Mapped[str] = mapped_column(String(64), primary_key=True)
+    client_id: Mapped[str] = mapped_column(String(36), ForeignKey("oidc_clients.id"), nullable=False, index=True)
+    user_id: Mapped[str] = mapped_column(String(36), ForeignKey("users.id"), nullable=False)
+    redirect_uri: Mapped[str] = mapped_column(String(2048), nullable=False)
+    scope: Mapped[str] = mapped_column(String(255), nullable=False)
+    nonce: Mapped[Optional[str]] = mapped_column(String(255))
+    code_challenge: Mapped[Optional[str]] = mapped_column(String(128))  # S256 PKCE challenge
+    auth_time: Mapped[datetime] = mapped_column(DateTime, nullable=False)
+    expires_at: Mapped[datetime] = mapped_column(DateTime, nullable=False, index=True)
+    used_at: Mapped[Optional[datetime]] = mapped_column(DateTime)
@@ -0,0 +1,262 @@
+"""Minimal OpenID Connect provider on top of the user service.
+
+Registered clients sign users in with the authorization code flow (PKCE is
+required for public clients), exchange refresh tokens and read
+``/oauth2/userinfo``. ID tokens are signed with the key published at
+``/.well-known/jwks.json``. Access tokens are signed like the ones
+``/api/auth/login`` issues but carry only the granted OIDC scope, so they
+reach ``/oauth2/userinfo`` and nothing that needs a platform scope.
+"""
+
+from __future__ import annotations
+
+import base64
+import hashlib
+import hmac
+import secrets
+from datetime import datetime, timedelta
+from typing import Any, Dict, List, Mapping, Optional, Tuple
+from urllib.parse import urlencode, urlparse
+
+from sqlalchemy import delete, select
+from sqlalchemy.orm import Session
+
+from ...core.config import Settings
+from .models import OidcAuthorizationCode, OidcClient, User
+from .service import SessionTokens, UserService
+
+SUPPORTED_SCOPES = ("openid", "profile", "email")
+GRANT_TYPES = ("authorization_code", "refresh_token")
+CLAIMS = ("sub", "name", "email", "steamid", "role", "zoneinfo")
+
+
+class OidcError(ValueError):
+    """An OAuth 2.0 error; ``error`` is the code from RFC 6749 returned to the client."""
+
+    def __init__(self, error: str, description: str) -> None:
+        super().__init__(description)
+        self.error = error
+
+
+class OidcProvider:
+    def __init__(self, settings: Settings, users: UserService) -> None:
+        self.settings = settings
+        self.users = users
+
+    def discovery(self, base_url: str) -> Dict[str, Any]:
+        """The ``/.well-known/openid-configuration`` document; ``base_url`` is where the API is reached."""
+
+        base_url = (self.settings.public_url or base_url).rstrip("/")
+        return {
+            "issuer": self.settings.auth_token_issuer,
+            "authorization_endpoint": f"{base_url}/oauth2/authorize",
+            "token_endpoint": f"{base_url}/oauth2/token",
+            "userinfo_endpoint": f"{base_url}/oauth2/userinfo",
+            "jwks_uri": f"{base_url}/.well-known/jwks.json",
+            "response_types_supported": ["code"],
+            "grant_types_supported": list(GRANT_TYPES),
+            "subject_types_supported": ["public"],
+            "id_token_signing_alg_values_supported": ["RS256"],
+            "scopes_supported": list(SUPPORTED_SCOPES),
+            "claims_supported": list(CLAIMS),
+            "token_endpoint_auth_methods_supported": ["client_secret_basic", "client_secret_post", "none"],
+            "code_challenge_methods_supported": ["S256"],
+        }
+
+    def create_client(
+        self, session: Session, name: str, redirect_uris: List[str], confidential: bool = True
+    ) -> Tuple[OidcClient, Optional[str]]:
+        """Register a client; returns it with its secret, which is shown once (``None`` for public clients)."""
+
+        if not redirect_uris:
+            raise ValueError("Register at least one redirect URI")
+        for uri in redirect_uris:
+            parsed = urlparse(uri)
+            if parsed.scheme not in {"http", "https"} or not parsed.netloc or parsed.fragment:
+                raise ValueError("Redirect URIs must be absolute http or https URLs without a fragment")
+        secret = f"sfc_{secrets.token_urlsafe(32)}" if confidential else None
+        client = OidcClient(name=name, redirect_uris=list(redirect_uris), secret_hash=_hash(secret) if secret else None)
+        session.add(client)
+        session.commit()
+        session.refresh(client)
+        return client, secret
+
+    def list_clients(self, session: Session) -> List[OidcClient]:
+        stmt = select(OidcClient).where(OidcClient.revoked_at.is_(None)).order_by(OidcClient.created_at)
+        return list(session.scalars(stmt))
+
+    def revoke_client(self, session: Session, client_id: str) -> None:
+        client = session.get(OidcClient, client_id)
+        if not client or client.revoked_at:
+            raise LookupError("OIDC client not found")
+        client.revoked_at = datetime.utcnow()
+        session.execute(delete(OidcAuthorizationCode).where(OidcAuthorizationCode.client_id == client_id))
+        session.commit()
+
+    def authorization_client(self, session: Session, client_id: str, redirect_uri: str) -> OidcClient:
+        """The client of an authorization request.
+
+        Errors here must be shown to the user rather than sent to ``redirect_uri``,
+        which cannot be trusted until it is known to be registered.
+        """
+
+        client = session.get(OidcClient, client_id)
+        if not client or client.revoked_at:
+            raise OidcError("invalid_request", "Unknown client_id")
+        if redirect_uri not in client.redirect_uris:
+            raise OidcError("invalid_request", "redirect_uri is not registered for this client")
+        return client
+
+    def authorize(
+        self,
+        session: Session,
+        user: User,
+        client: OidcClient,
+        redirect_uri: str,
+        params: Mapping[str, Optional[str]],
+    ) -> str:
+        """Issue an authorization code for the signed-in ``user``; returns where to send the browser.
+
+        ``params`` are the request's ``response_type``, ``scope``, ``state``,
+        ``nonce``, ``code_challenge`` and ``code_challenge_method``. Invalid
+        requests are answered on ``redirect_uri`` too, as the spec asks.
+        """
+
+        state = params.get("state")
+        try:
+            scope = self._check_request(client, params)
+        except OidcError as exc:
+            return error_redirect(redirect_uri, exc.error, str(exc), state)
+        code = secrets.token_urlsafe(32)
+        now = datetime.utcnow()
+        session.add(
+            OidcAuthorizationCode(
+                code_hash=_hash(code),
+                client_id=client.id,
+                user_id=user.id,
+                redirect_uri=redirect_uri,
+                scope=scope,
+                nonce=params.get("nonce"),
+                code_challenge=params.get("code_challenge"),
+                auth_time=user.last_login_at or now,
+                expires_at=now + timedelta(seconds=self.settings.oidc_code_seconds),
+            )
+        )
+        session.execute(delete(OidcAuthorizationCode).where(OidcAuthorizationCode.expires_at < now))
+        session.commit()
+        return _with_query(redirect_uri, {"code": code, **({"state": state} if state else {})})
+
+    def exchange(
+        self,
+        session: Session,
+        form: Mapping[str, str],
+        basic_credentials: Optional[Tuple[str, str]] = None,
+    ) -> Dict[str, Any]:
+        """The token endpoint: trade an authorization code or refresh token for new tokens."""
+
+        client = self._authenticate_client(session, form, basic_credentials)
+        grant_type = form.get("grant_type")
+        if grant_type == "authorization_code":
+            return self._exchange_code(session, client, form)
+        if grant_type == "refresh_token":
+            try:
+                user, tokens = self.users.refresh_session(session, form.get("refresh_token") or "")
+            except PermissionError as exc:
+                raise OidcError("invalid_grant", str(exc)) from exc
+            auth_time = user.last_login_at or datetime.utcnow()
+            return self._token_response(client, user, tokens, auth_time, scope=tokens.scope or "openid")
+        raise OidcError("unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+
+    def userinfo(self, user: User) -> Dict[str, Any]:
+        claims: Dict[str, Any] = {
+            "sub": user.id,
+            "name": user.display_name,
+            "role": user.role,
+            "zoneinfo": user.timezone,
+        }
+        if user.email:
+            claims["email"] = user.email
+        if user.steamid:
+            claims["steamid"] = user.steamid
+        return claims
+
+    def _check_request(self, client: OidcClient, params: Mapping[str, Optional[str]]) -> str:
+        if params.get("response_type") != "code":
+            raise OidcError("unsupported_response_type", "Only response_type=code is supported")
+        requested = (params.get("scope") or "").split()
+        if "openid" not in requested:
+            raise OidcError("invalid_scope", "The openid scope is required")
+        challenge = params.get("code_challenge")
+        if challenge and (params.get("code_challenge_method") or "plain") != "S256":
+            raise OidcError("invalid_request", "code_challenge_method must be S256")
+        if not challenge and not client.confidential:
+            raise OidcError("invalid_request", "Public clients must use PKCE")
+        return " ".join(scope for scope in requested if scope in SUPPORTED_SCOPES)
+
+    def _authenticate_client(
+        self, session: Session, form: Mapping[str, str], basic_credentials: Optional[Tuple[str, str]]
+    ) -> OidcClient:
+        client_id, secret = basic_credentials or (form.get("client_id") or "", form.get("client_secret"))
+        client = session.get(OidcClient, client_id) if client_id else None
+        if not client or client.revoked_at:
+            raise OidcError("invalid_client", "Unknown client")
+        if client.confidential and not (secret and hmac.compare_digest(client.secret_hash, _hash(secret))):
+            raise OidcError("invalid_client", "Client authentication failed")
+        return client
+
+    def _exchange_code(self, session: Session, client: OidcClient, form: Mapping[str, str]) -> Dict[str, Any]:
+        now = datetime.utcnow()
+        stored = session.get(OidcAuthorizationCode, _hash(form.get("code") or ""))
+        if not stored or stored.client_id != client.id or stored.used_at or stored.expires_at <= now:
+            raise OidcError("invalid_grant", "Invalid or expired authorization code")
+        if form.get("redirect_uri") != stored.redirect_uri:
+            raise OidcError("invalid_grant", "redirect_uri does not match the authorization request")
+        if stored.code_challenge and not _pkce_matches(stored.code_challenge, form.get("code_verifier") or ""):
+            raise OidcError("invalid_grant", "code_verifier does not match the code_challenge")
+        user = session.get(User, stored.user_id)
+        if not user or not user.is_active or user.deleted_at:
+            raise OidcError("invalid_grant", "Invalid or expired authorization code")
+        stored.used_at = now
+        tokens = self.users.start_session(session, user, scope=stored.scope)
+        return self._token_response(client, user, tokens, stored.auth_time, scope=stored.scope, nonce=stored.nonce)
+
+    def _token_response(
+        self,
+        client: OidcClient,
+        user: User,
+        tokens: SessionTokens,
+        auth_time: datetime,
+        scope: str,
+        nonce: Optional[str] = None,
+    ) -> Dict[str, Any]:
+        id_token = self.users.tokens.issue_id_token(
+            user, client.id, auth_time, nonce=nonce, access_token=tokens.access.token, now=tokens.access.issued_at
+        )
+        return {
+            "access_token": tokens.access.token,
+            "token_type": "Bearer",
+            "expires_in": tokens.access.lifetime_seconds,
+            "refresh_token": tokens.refresh_token,
+            "id_token": id_token,
+            "scope": scope,
+        }
+
+
+def error_redirect(redirect_uri: str, error: str, description: str, state: Optional[str] = None) -> str:
+    return _with_query(
+        redirect_uri, {"error": error, "error_description": description, **({"state": state} if state else {})}
+    )
+
+
+def _with_query(uri: str, params: Dict[str, str]) -> str:
+    return f"{uri}{'&' if urlparse(uri).query else '?'}{urlencode(params)}"
+
+
+def _pkce_matches(challenge: str, verifier: str) -> bool:
+    digest = hashlib.sha256(verifier.encode()).digest()
+    expected = base64.urlsafe_b64encode(digest).rstrip(b"=").decode()
+    return bool(verifier) and hmac.compare_digest(challenge, expected)
+
+
+def _hash(value: str) -> str:
+    return hashlib.sha256(value.encode()).hexdigest()
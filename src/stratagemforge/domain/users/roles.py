@@ -0,0 +1,26 @@
+"""Platform and team roles, lowest to highest.
+
+A user's platform role (``User.role``) decides what their bearer tokens may
+do across the deployment; team roles (``TeamMember.role``) decide who may
+manage a team's roster. ``admin`` exists only as a platform role.
+"""
+
+from __future__ import annotations
+
+from typing import Dict, Tuple
+
+ROLES = ("player", "analyst", "coach", "admin")
+TEAM_ROLES = ("player", "analyst", "coach")
+# Scopes of a bearer token without a ``scope`` claim: players only read, admins may do anything.
+ROLE_SCOPES: Dict[str, Tuple[str, ...]] = {
+    "player": ("read",),
+    "analyst": ("read", "upload"),
+    "coach": ("read", "upload"),
+    "admin": ("read", "upload", "admin"),
+}
+
+
+def role_at_least(role: str | None, minimum: str) -> bool:
+    """Whether ``role`` ranks at or above ``minimum``; unknown roles rank below everything."""
+
+    return role in ROLES and ROLES.index(role) >= ROLES.index(minimum)
@@ -1,29 +1,195 @@
 from __future__ import annotations
 
-from datetime import datetime
-from typing import Optional
+from typing import List, Literal, Optional
 
-from pydantic import BaseModel, EmailStr
+from pydantic import BaseModel, EmailStr, Field
+
+from ...core.timeutil import UtcDateTime
+from .passwords import MIN_PASSWORD_LENGTH
+
+ApiKeyScope = Literal["read", "upload", "admin"]
+UserRole = Literal["player", "analyst", "coach", "admin"]
+RegistrationMode = Literal["open", "invite", "domain"]
 
 
 class UserSummary(BaseModel):
     id: str
-    email: EmailStr
+    email: Optional[EmailStr] = Field(default=None, description="Only returned to admins and the user")
     display_name: str
+    steamid: Optional[str] = None
     role: str
+    timezone: str = "UTC"
     is_active: bool
-    created_at: datetime
-    last_login_at: Optional[datetime] = None
+    created_at: UtcDateTime
+    last_login_at: Optional[UtcDateTime] = None
 
     class Config:
         orm_mode = True
 
 
+class UserSearchResult(BaseModel):
+    id: str
+    display_name: str
+    steamid: Optional[str] = None
+    persona: Optional[str] = Field(default=None, description="In-game name that matched instead of the display name")
+    email: Optional[EmailStr] = Field(default=None, description="Only returned to admins")
+    matched: str = Field(description="display_name, persona or email")
+    score: float = Field(description="1 for an exact match, lower for prefix and fuzzy matches")
+
+
+class UserCreate(BaseModel):
+    email: EmailStr
+    display_name: str = Field(min_length=1, max_length=255)
+    password: str = Field(min_length=MIN_PASSWORD_LENGTH, max_length=256)
+    timezone: str = Field(default="UTC", description="IANA timezone used to format reports")
+    invite_token: Optional[str] = Field(default=None, description="Sign-up invite; required in invite mode")
+
+
+class UserUpdate(BaseModel):
+    email: Optional[EmailStr] = None
+    display_name: Optional[str] = Field(default=None, min_length=1, max_length=255)
+    timezone: Optional[str] = None
+
+
+class UserRoleUpdate(BaseModel):
+    role: UserRole
+
+
+class PasswordChange(BaseModel):
+    current_password: str
+    new_password: str = Field(min_length=MIN_PASSWORD_LENGTH, max_length=256)
+
+
+class PasswordResetRequest(BaseModel):
+    email: EmailStr
+
+
+class PasswordResetConfirm(BaseModel):
+    token: str
+    new_password: str = Field(min_length=MIN_PASSWORD_LENGTH, max_length=256)
+
+
+class SteamLinkUrl(BaseModel):
+    url: str = Field(description="Steam login page; the account is linked once the browser comes back")
+
+
 class LoginRequest(BaseModel):
     email: EmailStr
+    password: str = Field(min_length=1)
 
 
 class LoginResponse(BaseModel):
-    token: str
+    access_token: str = Field(description="Send as Authorization: Bearer <token>")
+    refresh_token: str = Field(description="Exchange at /api/auth/refresh; each use returns a new one")
+    token_type: str = "bearer"
+    expires_in: int = Field(description="Seconds until the access token expires")
+    refresh_expires_at: UtcDateTime
     user: UserSummary
     message: str
+
+
+class RefreshRequest(BaseModel):
+    refresh_token: str
+
+
+class LogoutRequest(BaseModel):
+    refresh_token: Optional[str] = Field(default=None, description="Ends this login session on every device")
+
+
+class UserPreferencesUpdate(BaseModel):
+    timezone: str = Field(description="IANA timezone used to format reports, e.g. Europe/Berlin")
+
+
+class ApiKeyCreate(BaseModel):
+    name: str = Field(min_length=1, max_length=255, description="What the key is for, e.g. the uploader host")
+    scopes: List[ApiKeyScope] = Field(
+        default_factory=lambda: ["read"],
+        min_length=1,
+        description="read: GET requests; upload: demo uploads and URL ingests; admin: everything",
+    )
+    rate_limit_per_minute: Optional[int] = Field(default=None, ge=1, description="Overrides the default limit")
+
+
+class ApiKeyUpdate(BaseModel):
+    name: Optional[str] = Field(default=None, min_length=1, max_length=255)
+    scopes: Optional[List[ApiKeyScope]] = Field(default=None, min_length=1)
+
+
+class ApiKeySummary(BaseModel):
+    id: str
+    name: str
+    scopes: List[ApiKeyScope]
+    rate_limit_per_minute: Optional[int] = Field(default=None, description="Unset keys use the default limit")
+    created_at: UtcDateTime
+    last_used_at: Optional[UtcDateTime] = None
+    rotated_at: Optional[UtcDateTime] = None
+
+    class Config:
+        orm_mode = True
+
+
+class ApiKeyCreated(BaseModel):
+    id: str
+    name: str
+    key: str = Field(description="Shown once; send it as the X-API-Key header")
+    scopes: List[ApiKeyScope]
+    rate_limit_per_minute: int
+    created_at: UtcDateTime
+
+
+class OidcClientCreate(BaseModel):
+    name: str = Field(min_length=1, max_length=255, description="The application, e.g. the frontend")
+    redirect_uris: List[str] = Field(min_length=1, description="Exact URLs the authorization code may be sent to")
+    confidential: bool = Field(default=True, description="Servers get a secret; browser apps use PKCE instead")
+
+
+class OidcClientSummary(BaseModel):
+    id: str = Field(description="The client_id")
+    name: str
+    redirect_uris: List[str]
+    confidential: bool
+    created_at: UtcDateTime
+
+    class Config:
+        orm_mode = True
+
+
+class OidcClientCreated(OidcClientSummary):
+    client_secret: Optional[str] = Field(default=None, description="Shown once; null for public clients")
+
+
+class RegistrationPolicyUpdate(BaseModel):
+    mode: RegistrationMode = Field(description="open, invite (invite token required) or domain")
+    allowed_domains: List[str] = Field(default_factory=list, description="Email domains allowed in domain mode")
+
+
+class RegistrationPolicySummary(RegistrationPolicyUpdate):
+    updated_at: Optional[UtcDateTime] = None
+    updated_by: Optional[str] = None
+
+    class Config:
+        orm_mode = True
+
+
+class RegistrationInviteCreate(BaseModel):
+    role: UserRole = Field(default="analyst", description="Platform role of the account created with the invite")
+    email: Optional[EmailStr] = Field(default=None, description="Only this address may use the invite")
+    expires_in_days: Optional[int] = Field(default=None, ge=1, le=365, description="REGISTRATION_INVITE_DAYS if unset")
+
+
+class RegistrationInviteSummary(BaseModel):
+    id: str
+    role: str
+    email: Optional[str] = None
+    created_by: Optional[str] = None
+    created_at: UtcDateTime
+    expires_at: UtcDateTime
+    used_at: Optional[UtcDateTime] = None
+    used_by: Optional[str] = None
+
+    class Config:
+        orm_mode = True
+
+
+class RegistrationInviteCreated(RegistrationInviteSummary):
+    token: str = Field(description="Shown once; passed as invite_token when signing up")
@@ -0,0 +1,48 @@
+"""Fuzzy matching for user search and mention autocomplete.
+
+Names match by prefix first (the whole name, then any word of it) and fall
+back to trigram similarity, computed like PostgreSQL's ``pg_trgm`` so small
+typos still find the user on any database backend.
+"""
+
+from __future__ import annotations
+
+import re
+from typing import Optional, Set
+
+# Below this trigram similarity a name does not match at all.
+SIMILARITY_THRESHOLD = 0.3
+_WORD = re.compile(r"[^\W_]+")
+
+
+def trigrams(text: str) -> Set[str]:
+    """``pg_trgm`` trigrams: each word padded with two spaces in front and one behind."""
+
+    grams: Set[str] = set()
+    for word in _WORD.findall(text.casefold()):
+        padded = f"  {word} "
+        grams.update(padded[index : index + 3] for index in range(len(padded) - 2))
+    return grams
+
+
+def similarity(a: str, b: str) -> float:
+    ours, theirs = trigrams(a), trigrams(b)
+    if not ours or not theirs:
+        return 0.0
+    return len(ours & theirs) / len(ours | theirs)
+
+
+def match_score(query: str, value: Optional[str]) -> float:
+    """How well ``value`` matches what was typed: 1 for an exact match down to 0 for none."""
+
+    if not value:
+        return 0.0
+    query, folded = query.strip().casefold(), value.casefold()
+    if folded == query:
+        return 1.0
+    if folded.startswith(query):
+        return 0.9
+    if any(word.startswith(query) for word in _WORD.findall(folded)):
+        return 0.8
+    score = similarity(query, value)
+    return round(0.7 * score, 3) if score >= SIMILARITY_THRESHOLD else 0.0
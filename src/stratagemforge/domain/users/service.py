@@ -1,20 +1,84 @@
 from __future__ import annotations
 
-import base64
-from datetime import datetime
+import hashlib
+import logging
+import secrets
+from dataclasses import dataclass
+from datetime import datetime, timedelta, timezone
+from typing import Any, Callable, Dict, Iterable, List, Mapping, Optional
+from urllib.parse import urlencode
+from uuid import uuid4
 
-from sqlalchemy import select
+from sqlalchemy import delete, func, select
 from sqlalchemy.orm import Session
 
 from ...core.config import Settings
-from .models import User
+from ...core.mail import send_email
+from ...core.timeutil import resolve_timezone
+from ...core.tokens import JwtValidator
+from ..demos.models import MatchPlayer
+from .models import (
+    ApiKey,
+    PasswordResetToken,
+    RefreshToken,
+    RegistrationInvite,
+    RegistrationPolicy,
+    RevokedAccessToken,
+    User,
+)
+from .passwords import hash_password, needs_rehash, verify_password
+from .roles import ROLES
+from .search import match_score
+from .steam import SteamOpenId
+from .tokens import AccessToken, TokenIssuer
+
+logger = logging.getLogger(__name__)
+
+# Called with the user and the plain reset token; delivers it (email, chat, ...).
+PasswordResetHook = Callable[[Session, User, str], None]
+STEAM_LINK = "steam_link"
+REGISTRATION_MODES = ("open", "invite", "domain")
+
+
+class EmailTakenError(Exception):
+    """Another account already uses the email address."""
+
+
+@dataclass(frozen=True)
+class SessionTokens:
+    access: AccessToken
+    refresh_token: str
+    refresh_expires_at: datetime
+    scope: Optional[str] = None
 
 
 class UserService:
     """Simplified user management for the modular monolith."""
 
-    def __init__(self, settings: Settings) -> None:
+    def __init__(
+        self, settings: Settings, tokens: Optional[TokenIssuer] = None, steam: Optional[SteamOpenId] = None
+    ) -> None:
         self.settings = settings
+        self.tokens = tokens or TokenIssuer(settings)
+        self.steam = steam or SteamOpenId()
+        self._validator: Optional[JwtValidator] = None
+        self.password_reset_hooks: List[PasswordResetHook] = []
+
+    @property
+    def token_validator(self) -> JwtValidator:
+        """Validator for bearer tokens: the user service's JWKS if configured, else our own key."""
+
+        if self._validator is None:
+            if self.settings.jwt_jwks_url:
+                self._validator = JwtValidator(self.settings)
+            else:
+                self._validator = JwtValidator(
+                    self.settings,
+                    key_resolver=self.tokens.verification_key,
+                    issuer=self.settings.auth_token_issuer,
+                    require_token_use=True,
+                )
+        return self._validator
 
     def ensure_seed(self, session: Session) -> None:
         """Seed the database with a demo user if no accounts exist."""
@@ -27,24 +91,605 @@ class UserService:
             email="analyst@example.com",
             display_name="Demo Analyst",
             role="admin",
+            password_hash=hash_password(self.settings.seed_user_password) if self.settings.seed_user_password else None,
         )
         session.add(demo_user)
         session.commit()
 
     def list_users(self, session: Session) -> list[User]:
-        stmt = select(User).order_by(User.created_at)
+        stmt = select(User).where(User.deleted_at.is_(None)).order_by(User.created_at)
         return list(session.scalars(stmt).all())
 
-    def authenticate(self, session: Session, email: str) -> tuple[User, str]:
-        stmt = select(User).where(User.email == email)
-        user = session.scalars(stmt).first()
-        if not user:
-            raise ValueError("User not found")
+    def search_users(
+        self,
+        session: Session,
+        query: str,
+        include_email: bool = False,
+        user_ids: Optional[Iterable[str]] = None,
+        limit: int = 10,
+    ) -> List[Dict[str, Any]]:
+        """Active users whose display name, Steam persona or email matches ``query``, best match first.
 
-        user.last_login_at = datetime.utcnow()
+        A user's personas are the names their linked SteamID played under in
+        uploaded demos. Without ``include_email`` (for callers who are not
+        admins) an email only matches when typed in full and is not returned.
+        ``user_ids`` limits the search, e.g. to one team's roster.
+        """
+
+        query = query.strip()
+        if len(query) < 2:
+            raise ValueError("Search for at least 2 characters")
+        stmt = select(User).where(User.deleted_at.is_(None), User.is_active.is_(True))
+        if user_ids is not None:
+            stmt = stmt.where(User.id.in_(list(user_ids)))
+        users = list(session.scalars(stmt).all())
+        personas: Dict[str, List[str]] = {}
+        steamids = [user.steamid for user in users if user.steamid]
+        if steamids:
+            rows = session.execute(
+                select(MatchPlayer.steamid, MatchPlayer.name)
+                .where(MatchPlayer.steamid.in_(steamids), MatchPlayer.name.is_not(None))
+                .distinct()
+            )
+            for steamid, name in rows:
+                personas.setdefault(steamid, []).append(name)
+
+        results = []
+        for user in users:
+            candidates = [("display_name", user.display_name, match_score(query, user.display_name))]
+            candidates += [("persona", name, match_score(query, name)) for name in personas.get(user.steamid, [])]
+            if user.email:
+                exact = 1.0 if user.email.casefold() == query.casefold() else 0.0
+                candidates.append(("email", user.email, match_score(query, user.email) if include_email else exact))
+            matched, value, score = max(candidates, key=lambda candidate: candidate[2])
+            if score <= 0:
+                continue
+            results.append(
+                {
+                    "id": user.id,
+                    "display_name": user.display_name,
+                    "steamid": user.steamid,
+                    "persona": value if matched == "persona" else None,
+                    "email": user.email if include_email else None,
+                    "matched": matched,
+                    "score": score,
+                }
+            )
+        results.sort(key=lambda result: (-result["score"], result["display_name"].casefold()))
+        return results[:limit]
+
+    def get_user(self, session: Session, user_id: str) -> User:
+        user = session.get(User, user_id)
+        if not user or user.deleted_at:
+            raise LookupError("User not found")
+        return user
+
+    def register(
+        self,
+        session: Session,
+        email: str,
+        display_name: str,
+        password: str,
+        timezone: str = "UTC",
+        invite_token: Optional[str] = None,
+        admin: bool = False,
+    ) -> User:
+        """Create an account that can log in with ``password`` right away.
+
+        Sign-ups must satisfy the :meth:`registration_policy` unless an admin
+        creates the account. A valid ``invite_token`` is accepted in every mode
+        and gives the new user the invite's role instead of analyst.
+        """
+
+        email = email.lower()
+        invite = self._usable_invite(session, invite_token, email) if invite_token else None
+        if invite is None and not admin:
+            self._check_registration_policy(session, email)
+        self._ensure_email_free(session, email)
+        user = User(
+            email=email,
+            display_name=display_name.strip(),
+            timezone=resolve_timezone(timezone).key,
+            password_hash=hash_password(password),
+        )
+        if invite:
+            user.role = invite.role
         session.add(user)
+        session.flush()
+        if invite:
+            invite.used_at, invite.used_by = datetime.utcnow(), user.id
+        session.commit()
+        session.refresh(user)
+        return user
+
+    def registration_policy(self, session: Session) -> RegistrationPolicy:
+        """The sign-up policy set by an admin, or the one from the settings until then."""
+
+        policy = session.get(RegistrationPolicy, 1)
+        if policy is None:
+            mode = self.settings.registration_mode if self.settings.registration_enabled else "invite"
+            domains = _email_domains(self.settings.registration_allowed_domains.split(","))
+            policy = RegistrationPolicy(id=1, mode=mode, allowed_domains=domains)
+        return policy
+
+    def set_registration_policy(
+        self, session: Session, mode: str, allowed_domains: Iterable[str] = (), actor_id: Optional[str] = None
+    ) -> RegistrationPolicy:
+        if mode not in REGISTRATION_MODES:
+            raise ValueError(f"Unknown registration mode {mode}; choose one of {', '.join(REGISTRATION_MODES)}")
+        domains = _email_domains(allowed_domains)
+        if mode == "domain" and not domains:
+            raise ValueError("Domain-restricted registration needs at least one allowed domain")
+        policy = session.get(RegistrationPolicy, 1) or RegistrationPolicy(id=1)
+        policy.mode, policy.allowed_domains = mode, domains
+        policy.updated_at, policy.updated_by = datetime.utcnow(), actor_id
+        session.add(policy)
+        session.commit()
+        return policy
+
+    def create_registration_invite(
+        self,
+        session: Session,
+        role: str = "analyst",
+        email: Optional[str] = None,
+        expires_in_days: Optional[int] = None,
+        actor_id: Optional[str] = None,
+    ) -> tuple[RegistrationInvite, str]:
+        """Issue a single-use sign-up invite; returns it with the plain token, which is shown once."""
+
+        if role not in ROLES:
+            raise ValueError(f"Unknown role {role}; choose one of {', '.join(ROLES)}")
+        days = expires_in_days or self.settings.registration_invite_days
+        token = f"sfi_{secrets.token_urlsafe(32)}"
+        invite = RegistrationInvite(
+            token_hash=_hash(token),
+            email=email.lower() if email else None,
+            role=role,
+            created_by=actor_id,
+            expires_at=datetime.utcnow() + timedelta(days=days),
+        )
+        session.add(invite)
+        session.commit()
+        session.refresh(invite)
+        return invite, token
+
+    def list_registration_invites(self, session: Session, include_used: bool = False) -> List[RegistrationInvite]:
+        """Invites that can still be used, newest first; ``include_used`` adds used and expired ones."""
+
+        stmt = (
+            select(RegistrationInvite)
+            .where(RegistrationInvite.revoked_at.is_(None))
+            .order_by(RegistrationInvite.created_at.desc())
+        )
+        if not include_used:
+            stmt = stmt.where(RegistrationInvite.used_at.is_(None), RegistrationInvite.expires_at > datetime.utcnow())
+        return list(session.scalars(stmt))
+
+    def revoke_registration_invite(self, session: Session, invite_id: str) -> None:
+        invite = session.get(RegistrationInvite, invite_id)
+        if not invite or invite.revoked_at or invite.used_at:
+            raise LookupError("Invite not found")
+        invite.revoked_at = datetime.utcnow()
+        session.commit()
+
+    def _check_registration_policy(self, session: Session, email: str) -> None:
+        policy = self.registration_policy(session)
+        if policy.mode == "open":
+            return
+        if policy.mode == "domain":
+            if _domain_allowed(policy, email):
+                return
+            domains = ", ".join(policy.allowed_domains)
+            raise PermissionError(f"Sign-up is limited to {domains} addresses; ask an admin for an invite")
+        raise PermissionError("Registration is by invitation only")
+
+    @staticmethod
+    def _usable_invite(session: Session, token: str, email: str) -> RegistrationInvite:
+        stmt = select(RegistrationInvite).where(RegistrationInvite.token_hash == _hash(token))
+        invite = session.scalars(stmt).first()
+        if not invite or invite.used_at or invite.revoked_at or invite.expires_at <= datetime.utcnow():
+            raise PermissionError("Invalid or expired invite")
+        if invite.email and invite.email != email:
+            raise PermissionError("This invite was sent to another email address")
+        return invite
+
+    def update_profile(
+        self,
+        session: Session,
+        user_id: str,
+        email: Optional[str] = None,
+        display_name: Optional[str] = None,
+        timezone: Optional[str] = None,
+        admin: bool = False,
+    ) -> User:
+        """Change profile fields that are not ``None``.
+
+        While sign-ups are limited to some domains, a new email must be at one
+        of them too, so an account cannot move to another domain after signing
+        up; admins may set any address.
+        """
+
+        user = self.get_user(session, user_id)
+        if email is not None and email.lower() != (user.email or "").lower():
+            email = email.lower()
+            policy = self.registration_policy(session)
+            if not admin and policy.mode == "domain" and not _domain_allowed(policy, email):
+                raise PermissionError(f"Email addresses are limited to {', '.join(policy.allowed_domains)}")
+            self._ensure_email_free(session, email)
+            user.email = email
+        if display_name is not None:
+            user.display_name = display_name.strip()
+        if timezone is not None:
+            user.timezone = resolve_timezone(timezone).key
+        session.commit()
+        return user
+
+    def set_role(self, session: Session, user_id: str, role: str) -> User:
+        """Change a platform role; it applies to access tokens issued from now on."""
+
+        if role not in ROLES:
+            raise ValueError(f"Unknown role {role}; choose one of {', '.join(ROLES)}")
+        user = self.get_user(session, user_id)
+        user.role = role
+        session.commit()
+        return user
+
+    def change_password(self, session: Session, user_id: str, current_password: str, new_password: str) -> User:
+        """Replace a password after checking the current one; raises ``PermissionError`` if it is wrong."""
+
+        user = self.get_user(session, user_id)
+        if not verify_password(user.password_hash, current_password):
+            raise PermissionError("Current password is incorrect")
+        return self.set_password(session, user_id, new_password)
+
+    def request_password_reset(self, session: Session, email: str) -> None:
+        """Issue a reset token and hand it to :attr:`password_reset_hooks`.
+
+        Unknown or inactive addresses are ignored silently so the endpoint
+        does not reveal which emails have accounts.
+        """
+
+        user = session.scalars(select(User).where(func.lower(User.email) == email.lower())).first()
+        if not user or not user.is_active or user.deleted_at:
+            return
+        now = datetime.utcnow()
+        token = f"sfw_{secrets.token_urlsafe(32)}"
+        session.add(
+            PasswordResetToken(
+                user_id=user.id,
+                token_hash=_hash(token),
+                created_at=now,
+                expires_at=now + timedelta(minutes=self.settings.password_reset_minutes),
+            )
+        )
+        session.commit()
+        for hook in self.password_reset_hooks:
+            try:
+                hook(session, user, token)
+            except Exception:  # noqa: BLE001 - a failing delivery must not leak whether the email exists
+                logger.exception("Password reset hook failed for user %s", user.id)
+
+    def confirm_password_reset(self, session: Session, token: str, new_password: str) -> User:
+        now = datetime.utcnow()
+        stored = session.scalars(
+            select(PasswordResetToken).where(PasswordResetToken.token_hash == _hash(token))
+        ).first()
+        if not stored or stored.used_at or stored.expires_at <= now:
+            raise ValueError("Invalid or expired reset token")
+        user = session.get(User, stored.user_id)
+        if not user or not user.is_active or user.deleted_at:
+            raise ValueError("Invalid or expired reset token")
+        # Any other outstanding reset links die with this one.
+        for pending in session.scalars(
+            select(PasswordResetToken).where(
+                PasswordResetToken.user_id == user.id, PasswordResetToken.used_at.is_(None)
+            )
+        ):
+            pending.used_at = now
+        return self.set_password(session, user.id, new_password)
+
+    def email_reset_link(self, session: Session, user: User, token: str) -> None:
+        """Password reset hook: mail the token, as a link when ``password_reset_url`` is set."""
+
+        link = self.settings.password_reset_url.format(token=token) if self.settings.password_reset_url else token
+        body = (
+            f"Hi {user.display_name},\n\n"
+            f"Use this to choose a new StratagemForge password:\n\n{link}\n\n"
+            f"It expires in {self.settings.password_reset_minutes} minutes. "
+            "If you did not ask for a reset you can ignore this email."
+        )
+        send_email(self.settings, user.email, "Reset your StratagemForge password", body)
+
+    def delete_user(self, session: Session, user_id: str) -> None:
+        """Soft-delete an account: it disappears from listings and loses its sessions and API keys."""
+
+        user = self.get_user(session, user_id)
+        now = datetime.utcnow()
+        user.deleted_at = now
+        user.is_active = False
+        for api_key in session.scalars(select(ApiKey).where(ApiKey.user_id == user_id, ApiKey.revoked_at.is_(None))):
+            api_key.revoked_at = now
+        session.commit()
+        self.revoke_sessions(session, user_id)
+
+    def authenticate(self, session: Session, email: str, password: str) -> User:
+        """Check an email and password; raises ``PermissionError`` without saying which was wrong."""
+
+        user = session.scalars(select(User).where(func.lower(User.email) == email.lower())).first()
+        valid = verify_password(user.password_hash if user else None, password)
+        if not user or not valid or not user.is_active:
+            raise PermissionError("Invalid email or password")
+        if needs_rehash(user.password_hash):
+            user.password_hash = hash_password(password)
+        user.last_login_at = datetime.utcnow()
+        session.commit()
+        return user
+
+    def steam_login_url(
+        self, callback_url: str, link_user_id: Optional[str] = None, link_nonce: Optional[str] = None
+    ) -> str:
+        """Steam login page; with ``link_user_id`` the Steam account is linked to that user on return.
+
+        A link is bound to ``link_nonce``, which the browser that started it
+        must present again at the callback, so nobody can link their Steam
+        account to someone else's user by sending them the URL.
+        """
+
+        if link_user_id:
+            if not link_nonce:
+                raise ValueError("Linking Steam needs a nonce bound to the browser")
+            state = self.tokens.issue_state(link_user_id, STEAM_LINK, nonce=link_nonce)
+            callback_url = f"{callback_url}?{urlencode({'state': state})}"
+        return self.steam.login_url(callback_url)
+
+    def sign_in_with_steam(
+        self, session: Session, params: Mapping[str, str], callback_url: str, link_nonce: Optional[str] = None
+    ) -> User:
+        """Finish a Steam sign-in: link the SteamID64 or find (or create) the user it belongs to."""
+
+        steamid = self.steam.verify(params, callback_url)
+        owner = session.scalars(select(User).where(User.steamid == steamid)).first()
+        if params.get("state"):
+            user = self.get_user(session, self.tokens.read_state(params["state"], STEAM_LINK, nonce=link_nonce))
+            if owner and owner.id != user.id:
+                raise ValueError("That Steam account is already linked to another user")
+            user.steamid = steamid
+        elif owner:
+            user = owner
+        elif self.registration_policy(session).mode == "open":
+            user = User(display_name=steamid, steamid=steamid)
+            session.add(user)
+        else:
+            raise PermissionError("No account is linked to this Steam account")
+        if not user.is_active or user.deleted_at:
+            raise PermissionError("No account is linked to this Steam account")
+        user.last_login_at = datetime.utcnow()
         session.commit()
         session.refresh(user)
+        return user
+
+    def set_password(self, session: Session, user_id: str, password: str) -> User:
+        user = session.get(User, user_id)
+        if not user:
+            raise LookupError("User not found")
+        user.password_hash = hash_password(password)
+        session.commit()
+        # A new password ends every existing login session.
+        self.revoke_sessions(session, user_id)
+        return user
+
+    def start_session(self, session: Session, user: User, scope: Optional[str] = None) -> SessionTokens:
+        """Issue an access token and the first refresh token of a new login session.
+
+        Sessions started for an OIDC client pass the granted ``scope``; their
+        tokens, refreshed ones included, are limited to it.
+        """
+
+        return self._issue(session, user, family_id=str(uuid4()), scope=scope)
+
+    def refresh_session(self, session: Session, refresh_token: str) -> tuple[User, SessionTokens]:
+        """Rotate a refresh token; raises ``PermissionError`` for unknown, expired or reused tokens."""
+
+        now = datetime.utcnow()
+        stored = session.scalars(select(RefreshToken).where(RefreshToken.token_hash == _hash(refresh_token))).first()
+        if not stored:
+            raise PermissionError("Invalid refresh token")
+        if stored.revoked_at:
+            # Reuse of a rotated token: someone else may hold the family, so end it.
+            self._revoke_family(session, stored.family_id, now)
+            session.commit()
+            raise PermissionError("Invalid refresh token")
+        if stored.expires_at <= now:
+            raise PermissionError("Refresh token has expired")
+        user = session.get(User, stored.user_id)
+        if not user or not user.is_active:
+            raise PermissionError("Invalid refresh token")
+        stored.revoked_at = now
+        return user, self._issue(session, user, family_id=stored.family_id, scope=stored.scope)
+
+    def logout(self, session: Session, refresh_token: Optional[str] = None, access_token: Optional[str] = None) -> None:
+        """End the login session of ``refresh_token`` and revoke ``access_token`` before it expires."""
+
+        now = datetime.utcnow()
+        if refresh_token:
+            stored = session.scalars(
+                select(RefreshToken).where(RefreshToken.token_hash == _hash(refresh_token))
+            ).first()
+            if stored:
+                self._revoke_family(session, stored.family_id, now)
+        if access_token:
+            try:
+                claims = self.token_validator.validate(access_token)
+            except PermissionError:
+                claims = None
+            if claims and claims.get("jti") and claims.get("iss") == self.settings.auth_token_issuer:
+                self.revoke_access_token(session, claims, now)
+        session.execute(delete(RevokedAccessToken).where(RevokedAccessToken.expires_at < now))
+        session.commit()
+
+    def revoke_access_token(self, session: Session, claims: Dict[str, Any], now: Optional[datetime] = None) -> None:
+        if session.get(RevokedAccessToken, claims["jti"]):
+            return
+        session.add(
+            RevokedAccessToken(
+                jti=claims["jti"],
+                user_id=str(claims["sub"]),
+                expires_at=datetime.fromtimestamp(claims["exp"], tz=timezone.utc).replace(tzinfo=None),
+                revoked_at=now or datetime.utcnow(),
+            )
+        )
+
+    def is_access_token_revoked(self, session: Session, jti: str) -> bool:
+        return session.get(RevokedAccessToken, jti) is not None
+
+    def revoke_sessions(self, session: Session, user_id: str) -> int:
+        """Revoke every refresh token of a user; their access tokens lapse within ``access_token_minutes``."""
+
+        now = datetime.utcnow()
+        active = session.scalars(
+            select(RefreshToken).where(RefreshToken.user_id == user_id, RefreshToken.revoked_at.is_(None))
+        ).all()
+        for token in active:
+            token.revoked_at = now
+        session.commit()
+        return len(active)
+
+    def _issue(self, session: Session, user: User, family_id: str, scope: Optional[str] = None) -> SessionTokens:
+        now = datetime.utcnow()
+        refresh_token = f"sfr_{secrets.token_urlsafe(32)}"
+        expires_at = now + timedelta(days=self.settings.refresh_token_days)
+        session.add(
+            RefreshToken(
+                user_id=user.id,
+                family_id=family_id,
+                token_hash=_hash(refresh_token),
+                scope=scope,
+                created_at=now,
+                expires_at=expires_at,
+            )
+        )
+        session.commit()
+        return SessionTokens(
+            access=self.tokens.issue(user, now, scope=scope),
+            refresh_token=refresh_token,
+            refresh_expires_at=expires_at,
+            scope=scope,
+        )
+
+    def _ensure_email_free(self, session: Session, email: str) -> None:
+        if session.scalars(select(User.id).where(func.lower(User.email) == email)).first():
+            raise EmailTakenError("A user with this email already exists")
+
+    def _revoke_family(self, session: Session, family_id: str, now: datetime) -> None:
+        for token in session.scalars(
+            select(RefreshToken).where(RefreshToken.family_id == family_id, RefreshToken.revoked_at.is_(None))
+        ):
+            token.revoked_at = now
+
+    def update_preferences(self, session: Session, user_id: str, timezone: str) -> User:
+        user = self.get_user(session, user_id)
+        user.timezone = resolve_timezone(timezone).key
+        session.commit()
+        return user
+
+    def create_api_key(
+        self,
+        session: Session,
+        user_id: str,
+        name: str,
+        scopes: Iterable[str],
+        rate_limit_per_minute: Optional[int] = None,
+    ) -> tuple[ApiKey, str]:
+        user = session.get(User, user_id)
+        if not user or not user.is_active:
+            raise LookupError("User not found")
+        key = f"sfk_{secrets.token_urlsafe(32)}"
+        api_key = ApiKey(
+            user_id=user_id,
+            name=name,
+            key_hash=_hash(key),
+            scopes=sorted(set(scopes)),
+            rate_limit_per_minute=rate_limit_per_minute,
+        )
+        session.add(api_key)
+        session.commit()
+        session.refresh(api_key)
+        return api_key, key
+
+    def list_api_keys(self, session: Session, user_id: str) -> List[ApiKey]:
+        """A user's keys that have not been revoked, newest first."""
+
+        stmt = (
+            select(ApiKey)
+            .where(ApiKey.user_id == user_id, ApiKey.revoked_at.is_(None))
+            .order_by(ApiKey.created_at.desc())
+        )
+        return list(session.scalars(stmt).all())
+
+    def get_api_key(self, session: Session, user_id: str, key_id: str) -> ApiKey:
+        api_key = session.get(ApiKey, key_id)
+        if not api_key or api_key.user_id != user_id or api_key.revoked_at:
+            raise LookupError("API key not found")
+        return api_key
+
+    def update_api_key(
+        self,
+        session: Session,
+        user_id: str,
+        key_id: str,
+        name: Optional[str] = None,
+        scopes: Optional[Iterable[str]] = None,
+    ) -> ApiKey:
+        api_key = self.get_api_key(session, user_id, key_id)
+        if name is not None:
+            api_key.name = name
+        if scopes is not None:
+            api_key.scopes = sorted(set(scopes))
+        session.commit()
+        return api_key
+
+    def rotate_api_key(self, session: Session, user_id: str, key_id: str) -> tuple[ApiKey, str]:
+        """Replace a key's secret, keeping its id, name and scopes; the old value stops working at once."""
+
+        api_key = self.get_api_key(session, user_id, key_id)
+        key = f"sfk_{secrets.token_urlsafe(32)}"
+        api_key.key_hash = _hash(key)
+        api_key.rotated_at = datetime.utcnow()
+        session.commit()
+        return api_key, key
+
+    def revoke_api_key(self, session: Session, user_id: str, key_id: str) -> None:
+        api_key = self.get_api_key(session, user_id, key_id)
+        api_key.revoked_at = datetime.utcnow()
+        session.commit()
+
+    def api_key_limit(self, api_key: ApiKey) -> int:
+        return api_key.rate_limit_per_minute or self.settings.api_key_rate_limit_per_minute
+
+    def authenticate_api_key(self, session: Session, key: Optional[str]) -> ApiKey:
+        """Resolve an ``X-API-Key`` value; raises ``PermissionError`` for missing, unknown or revoked keys."""
+
+        api_key = None
+        if key:
+            api_key = session.scalars(select(ApiKey).where(ApiKey.key_hash == _hash(key))).first()
+        user = session.get(User, api_key.user_id) if api_key else None
+        if not api_key or api_key.revoked_at or not user or not user.is_active:
+            raise PermissionError("Invalid API key")
+        api_key.last_used_at = datetime.utcnow()
+        session.commit()
+        return api_key
+
+
+def _hash(key: str) -> str:
+    return hashlib.sha256(key.encode("utf-8")).hexdigest()
+
+
+def _domain_allowed(policy: RegistrationPolicy, email: str) -> bool:
+    return email.rpartition("@")[2] in policy.allowed_domains
+
+
+def _email_domains(values: Iterable[str]) -> List[str]:
+    """Lower-case domains without a leading ``@``, blanks and duplicates dropped."""
 
-        token = base64.b64encode(f"{user.id}:{user.email}".encode()).decode()
-        return user, token
+    domains = (value.strip().lower().lstrip("@") for value in values)
+    return list(dict.fromkeys(domain for domain in domains if domain))
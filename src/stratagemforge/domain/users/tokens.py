@@ -0,0 +1,184 @@
+"""Sign access tokens for logged-in users and publish the verification key.
+
+Tokens are RS256 JWTs so other services can check them against the JWKS at
+``/.well-known/jwks.json`` without sharing a secret. The private key is read
+from ``AUTH_SIGNING_KEY_FILE`` or, when that is unset, generated once under
+``<data_dir>/auth`` so tokens survive restarts.
+"""
+
+from __future__ import annotations
+
+import base64
+import hashlib
+import hmac
+import os
+import threading
+from dataclasses import dataclass
+from datetime import datetime, timedelta
+from pathlib import Path
+from typing import Any, Dict, Optional
+from uuid import uuid4
+
+import jwt
+from cryptography.hazmat.primitives import serialization
+from cryptography.hazmat.primitives.asymmetric import rsa
+
+from ...core.config import Settings
+from ...core.timeutil import as_utc
+from ...core.tokens import ACCESS_TOKEN_USE
+from .models import User
+
+ALGORITHM = "RS256"
+ID_TOKEN_USE = "id"  # ``token_use`` of ID tokens, which are never accepted as bearer tokens
+
+
+@dataclass(frozen=True)
+class AccessToken:
+    token: str
+    jti: str
+    issued_at: datetime
+    expires_at: datetime
+
+    @property
+    def lifetime_seconds(self) -> int:
+        return int((self.expires_at - self.issued_at).total_seconds())
+
+
+class TokenIssuer:
+    def __init__(self, settings: Settings) -> None:
+        self.settings = settings
+        self._private_key: Optional[rsa.RSAPrivateKey] = None
+        self._lock = threading.Lock()
+
+    @property
+    def key_path(self) -> Path:
+        return self.settings.auth_signing_key_file or self.settings.data_dir / "auth" / "jwt-signing-key.pem"
+
+    @property
+    def key_id(self) -> str:
+        public = self.private_key.public_key().public_bytes(
+            serialization.Encoding.DER, serialization.PublicFormat.SubjectPublicKeyInfo
+        )
+        return hashlib.sha256(public).hexdigest()[:16]
+
+    @property
+    def private_key(self) -> rsa.RSAPrivateKey:
+        with self._lock:
+            if self._private_key is None:
+                self._private_key = self._load_or_create_key()
+            return self._private_key
+
+    def issue(self, user: User, now: Optional[datetime] = None, scope: Optional[str] = None) -> AccessToken:
+        """Access token of ``user``; with ``scope`` it carries only that scope and no role, so no platform access."""
+
+        now = now or datetime.utcnow()
+        expires_at = now + timedelta(minutes=self.settings.access_token_minutes)
+        jti = uuid4().hex
+        claims: Dict[str, Any] = {
+            "iss": self.settings.auth_token_issuer,
+            "sub": user.id,
+            "email": user.email,
+            "iat": int(as_utc(now).timestamp()),
+            "exp": int(as_utc(expires_at).timestamp()),
+            "jti": jti,
+            "token_use": ACCESS_TOKEN_USE,
+        }
+        if scope is None:
+            claims["role"] = user.role
+        else:
+            claims["scope"] = scope
+        if user.steamid:
+            # Lets routes and other services tie the caller to their player stats.
+            claims["steamid"] = user.steamid
+        if self.settings.jwt_audience:
+            claims["aud"] = self.settings.jwt_audience
+        token = jwt.encode(claims, self.private_key, algorithm=ALGORITHM, headers={"kid": self.key_id})
+        return AccessToken(token=token, jti=jti, issued_at=now, expires_at=expires_at)
+
+    def issue_id_token(
+        self,
+        user: User,
+        audience: str,
+        auth_time: datetime,
+        nonce: Optional[str] = None,
+        access_token: Optional[str] = None,
+        now: Optional[datetime] = None,
+    ) -> str:
+        """OpenID Connect ID token of ``user`` for the client ``audience``."""
+
+        now = now or datetime.utcnow()
+        claims: Dict[str, Any] = {
+            "iss": self.settings.auth_token_issuer,
+            "sub": user.id,
+            "aud": audience,
+            "iat": int(as_utc(now).timestamp()),
+            "exp": int(as_utc(now + timedelta(minutes=self.settings.access_token_minutes)).timestamp()),
+            "auth_time": int(as_utc(auth_time).timestamp()),
+            "name": user.display_name,
+            "token_use": ID_TOKEN_USE,
+        }
+        if user.email:
+            claims["email"] = user.email
+        if user.steamid:
+            claims["steamid"] = user.steamid
+        if nonce:
+            claims["nonce"] = nonce
+        if access_token:
+            # Left half of the token's SHA-256, so the client can tell the pair belongs together.
+            digest = hashlib.sha256(access_token.encode()).digest()
+            claims["at_hash"] = base64.urlsafe_b64encode(digest[: len(digest) // 2]).rstrip(b"=").decode()
+        return jwt.encode(claims, self.private_key, algorithm=ALGORITHM, headers={"kid": self.key_id})
+
+    def issue_state(self, subject: str, purpose: str, minutes: int = 10, nonce: Optional[str] = None) -> str:
+        """Short-lived signed value that survives a browser round trip through another site.
+
+        With ``nonce`` (kept by the browser in a cookie) the value is only
+        accepted back together with that nonce; only its hash is embedded.
+        """
+
+        expires_at = datetime.utcnow() + timedelta(minutes=minutes)
+        claims = {"sub": subject, "purpose": purpose, "exp": int(as_utc(expires_at).timestamp())}
+        if nonce:
+            claims["nonce"] = hashlib.sha256(nonce.encode()).hexdigest()
+        return jwt.encode(claims, self.private_key, algorithm=ALGORITHM)
+
+    def read_state(self, token: str, purpose: str, nonce: Optional[str] = None) -> str:
+        """Subject of a value from :meth:`issue_state`; raises ``PermissionError`` unless it is valid for ``nonce``."""
+
+        try:
+            claims = jwt.decode(token, self.private_key.public_key(), algorithms=[ALGORITHM])
+        except jwt.PyJWTError as exc:
+            raise PermissionError("Sign-in request has expired, please try again") from exc
+        if claims.get("purpose") != purpose:
+            raise PermissionError("Sign-in request has expired, please try again")
+        if "nonce" in claims:
+            expected = hashlib.sha256((nonce or "").encode()).hexdigest()
+            if not nonce or not hmac.compare_digest(claims["nonce"], expected):
+                raise PermissionError("Sign-in request was started in another browser")
+        return str(claims["sub"])
+
+    def verification_key(self, token: str) -> rsa.RSAPublicKey:
+        """Key resolver for :class:`~stratagemforge.core.tokens.JwtValidator`."""
+
+        return self.private_key.public_key()
+
+    def jwks(self) -> Dict[str, Any]:
+        jwk = jwt.algorithms.RSAAlgorithm.to_jwk(self.private_key.public_key(), as_dict=True)
+        return {"keys": [{**jwk, "kid": self.key_id, "use": "sig", "alg": ALGORITHM}]}
+
+    def _load_or_create_key(self) -> rsa.RSAPrivateKey:
+        path = self.key_path
+        if path.exists():
+            key = serialization.load_pem_private_key(path.read_bytes(), password=None)
+            if not isinstance(key, rsa.RSAPrivateKey):
+                raise ValueError(f"{path} does not hold an RSA private key")
+            return key
+        key = rsa.generate_private_key(public_exponent=65537, key_size=2048)
+        pem = key.private_bytes(
+            serialization.Encoding.PEM, serialization.PrivateFormat.PKCS8, serialization.NoEncryption()
+        )
+        path.parent.mkdir(parents=True, exist_ok=True)
+        fd = os.open(path, os.O_WRONLY | os.O_CREAT | os.O_EXCL, 0o600)
+        with os.fdopen(fd, "wb") as handle:
+            handle.write(pem)
+        return key
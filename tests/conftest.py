@@ -0,0 +1,103 @@
+from __future__ import annotations
+
+from pathlib import Path
+
+import pandas as pd
+import pytest
+from sqlalchemy import create_engine
+from sqlalchemy.orm import sessionmaker
+
+from stratagemforge.core.database import Base
+from stratagemforge.domain.demos.parser import ParsedDemo
+
+
+@pytest.fixture
+def session(tmp_path):
+    """A session on a fresh SQLite database with the tables of every imported model.
+
+    Test modules that need rows in it override ``session`` with a fixture that
+    takes this one, adds them and returns it.
+    """
+
+    engine = create_engine(f"sqlite:///{tmp_path}/test.db", future=True)
+    Base.metadata.create_all(bind=engine)
+    session = sessionmaker(bind=engine, future=True, expire_on_commit=False)()
+    try:
+        yield session
+    finally:
+        session.close()
+
+
+@pytest.fixture
+def parsed_demo() -> ParsedDemo:
+    rounds = pd.DataFrame(
+        [
+            {"round": 1, "start_tick": 0, "freeze_end_tick": 100, "end_tick": 1000, "winner": "CT", "reason": 8, "ct_team": "Alpha", "t_team": "Bravo"},
+            {"round": 2, "start_tick": 1000, "freeze_end_tick": 1100, "end_tick": 2000, "winner": "T", "reason": 9, "ct_team": "Alpha", "t_team": "Bravo"},
+        ]
+    )
+    kills = pd.DataFrame(
+        [
+            {"tick": 500, "round": 1, "attacker_steamid": "1", "attacker_side": "CT", "victim_steamid": "6", "victim_side": "T", "weapon": "awp"},
+            {"tick": 1500, "round": 2, "attacker_steamid": "7", "attacker_side": "T", "victim_steamid": "2", "victim_side": "CT", "weapon": "ak47"},
+        ]
+    )
+    throws = pd.DataFrame(
+        [
+            {"tick": 200, "round": 1, "steamid": "1", "side": "CT", "grenade": "smoke"},
+            {"tick": 300, "round": 1, "steamid": "6", "side": "T", "grenade": "flash"},
+            {"tick": 600, "round": 1, "steamid": "1", "side": "CT", "grenade": "molotov"},
+            {"tick": 1200, "round": 2, "steamid": "7", "side": "T", "grenade": "he"},
+        ]
+    )
+    inventory = pd.DataFrame(
+        [
+            {"round": 1, "steamid": "1", "side": "CT", "is_alive": True, "inventory": ["AK-47", "Flashbang", "Smoke Grenade"]},
+            {"round": 1, "steamid": "6", "side": "T", "is_alive": False, "inventory": ["Glock-18", "High Explosive Grenade"]},
+            {"round": 2, "steamid": "7", "side": "T", "is_alive": True, "inventory": ["Molotov"]},
+        ]
+    )
+    bomb_events = pd.DataFrame(
+        [
+            {"tick": 400, "round": 1, "event": "planted", "steamid": "6", "site": "B"},
+            {"tick": 900, "round": 1, "event": "defused", "steamid": "1", "site": "B"},
+            {"tick": 1600, "round": 2, "event": "planted", "steamid": "7", "site": "A"},
+        ]
+    )
+    round_start_state = pd.DataFrame(
+        [
+            {"round": 1, "steamid": "1", "side": "CT", "team_name": "Alpha", "inventory": ["USP-S", "Knife"], "armor": 100, "has_helmet": False, "has_defuser": False},
+            {"round": 1, "steamid": "2", "side": "CT", "team_name": "Alpha", "inventory": ["USP-S", "Knife"], "armor": 100, "has_helmet": False, "has_defuser": True},
+            {"round": 1, "steamid": "6", "side": "T", "team_name": "Bravo", "inventory": ["Glock-18", "Tec-9"], "armor": 0, "has_helmet": False, "has_defuser": False},
+            {"round": 1, "steamid": "7", "side": "T", "team_name": "Bravo", "inventory": ["Glock-18", "Flashbang", "Smoke Grenade"], "armor": 0, "has_helmet": False, "has_defuser": False},
+        ]
+    )
+    return ParsedDemo(
+        header={"map_name": "de_mirage"},
+        rounds=rounds,
+        kills=kills,
+        grenade_throws=throws,
+        round_end_inventory=inventory,
+        bomb_events=bomb_events,
+        round_start_state=round_start_state,
+    )
+
+
+class StubParser:
+    name = "stub"
+
+    def __init__(self, parsed=None, error: Exception | None = None) -> None:
+        self.parsed = parsed
+        self.error = error
+
+    def parse(self, path: Path):
+        if self.error:
+            raise self.error
+        return self.parsed
+
+
+@pytest.fixture
+def make_parser():
+    """Factory for parsers that return a fixed :class:`ParsedDemo` or raise."""
+
+    return StubParser
@@ -7,6 +7,10 @@ from fastapi.testclient import TestClient
 from stratagemforge.api import deps
 from stratagemforge.core.app import create_app
 from stratagemforge.core.config import Settings
+from stratagemforge.core.database import session_scope
+
+# Smallest payload that passes the upload header check.
+DEMO_BYTES = b"PBDEMS2\x00demo data"
 
 
 def create_test_client(tmp_path) -> TestClient:
@@ -25,7 +29,7 @@ def test_full_upload_and_analysis_flow(tmp_path):
 
         upload_response = client.post(
             "/api/demos/upload",
-            files={"demo": ("test.dem", io.BytesIO(b"demo data"), "application/octet-stream")},
+            files={"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")},
         )
         assert upload_response.status_code == 201
         payload = upload_response.json()
@@ -36,7 +40,9 @@ def test_full_upload_and_analysis_flow(tmp_path):
         assert list_response.status_code == 200
         listing = list_response.json()
         assert listing["count"] == 1
+        assert listing["total"] == 1
         assert listing["demos"][0]["id"] == demo_id
+        assert client.get("/api/demos", params={"sort": "checksum"}).status_code == 400
 
         analysis_response = client.post("/api/analysis", json={"demo_id": demo_id})
         assert analysis_response.status_code == 200
@@ -47,3 +53,619 @@ def test_full_upload_and_analysis_flow(tmp_path):
         assert users_response.status_code == 200
         users = users_response.json()
         assert len(users) >= 1
+
+
+def test_duplicate_upload_reports_duplicate_status(tmp_path):
+    with create_test_client(tmp_path) as client:
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        first = client.post("/api/demos/upload", files=files)
+        assert first.status_code == 201
+
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        duplicate = client.post("/api/demos/upload", files=files)
+        assert duplicate.json()["status"] == "duplicate"
+        assert duplicate.json()["id"] == first.json()["id"]
+
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        forced = client.post("/api/demos/upload?force=true", files=files)
+        assert forced.json()["status"] == "processed"
+
+
+def test_upload_rejects_junk_and_oversized_files(tmp_path):
+    with create_test_client(tmp_path) as client:
+        junk = client.post(
+            "/api/demos/upload",
+            files={"demo": ("junk.dem", io.BytesIO(b"just some text"), "application/octet-stream")},
+        )
+        assert junk.status_code == 422
+
+    settings = Settings(data_dir=tmp_path / "small", database_url=f"sqlite:///{tmp_path}/small.db", max_upload_size=16)
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        too_large = client.post(
+            "/api/demos/upload",
+            files={"demo": ("big.dem", io.BytesIO(b"PBDEMS2\x00" + b"x" * 128), "application/octet-stream")},
+        )
+        assert too_large.status_code == 413
+
+
+def test_competition_grouping_flow(tmp_path):
+    with create_test_client(tmp_path) as client:
+        created = client.post(
+            "/api/competitions",
+            json={"name": "Spring Cup", "format": "bo3", "start_date": "2024-03-01", "end_date": "2024-03-10"},
+        )
+        assert created.status_code == 201
+        competition_id = created.json()["id"]
+
+        assert client.post("/api/competitions", json={"name": "Spring Cup"}).status_code == 409
+
+        upload = client.post(
+            "/api/demos/upload",
+            files={"demo": ("final.dem", io.BytesIO(b"PBDEMS2\x00final demo"), "application/octet-stream")},
+            data={"competition_id": competition_id},
+        )
+        assert upload.status_code == 201
+        assert upload.json()["competition_id"] == competition_id
+
+        other = client.post(
+            "/api/demos/upload",
+            files={"demo": ("scrim.dem", io.BytesIO(b"PBDEMS2\x00scrim demo"), "application/octet-stream")},
+        )
+        other_id = other.json()["id"]
+
+        filtered = client.get("/api/demos", params={"competition_id": competition_id}).json()
+        assert filtered["count"] == 1
+
+        assigned = client.put(
+            f"/api/demos/{other_id}/competition",
+            json={"competition_id": competition_id, "series": "Grand Final"},
+        )
+        assert assigned.status_code == 200
+        assert assigned.json()["series"] == "Grand Final"
+
+        detail = client.get(f"/api/competitions/{competition_id}").json()
+        assert len(detail["demos"]) == 2
+
+        missing = client.put(f"/api/demos/{other_id}/competition", json={"competition_id": "nope"})
+        assert missing.status_code == 400
+
+
+def test_anonymous_callers_cannot_edit_demos_that_belong_to_someone(tmp_path):
+    with create_test_client(tmp_path) as client:
+        account = {"email": "coach@example.com", "display_name": "Coach", "password": "s3cret-pass"}
+        assert client.post("/api/users", json=account).status_code == 201
+        login = client.post("/api/auth/login", json={"email": account["email"], "password": "s3cret-pass"})
+        bearer = {"Authorization": f"Bearer {login.json()['access_token']}"}
+        competition_id = client.post("/api/competitions", json={"name": "Spring Cup"}).json()["id"]
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        demo_id = client.post("/api/demos/upload", files=files, headers=bearer).json()["id"]
+
+        assigned = client.put(f"/api/demos/{demo_id}/competition", json={"competition_id": competition_id})
+        assert assigned.status_code == 401
+        hltv = client.post(f"/api/demos/{demo_id}/hltv", json={"url": "https://www.hltv.org/matches/1/a-vs-b"})
+        assert hltv.status_code == 401
+        assert client.delete(f"/api/demos/{demo_id}").status_code == 401
+        assert client.get(f"/api/demos/{demo_id}", headers=bearer).json()["competition_id"] is None
+
+
+def test_processed_files_can_be_listed_and_downloaded(tmp_path):
+    with create_test_client(tmp_path) as client:
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        demo_id = client.post("/api/demos/upload", files=files).json()["id"]
+
+        listing = client.get(f"/api/demos/{demo_id}/files").json()
+        assert [item["name"] for item in listing["files"]] == ["summary"]
+        summary = listing["files"][0]
+
+        plain = client.get(summary["download_url"], headers={"Accept-Encoding": "identity"})
+        assert plain.status_code == 200
+        assert plain.headers["content-disposition"] == f'attachment; filename="{demo_id}-summary.parquet"'
+        assert len(plain.content) == summary["size_bytes"]
+
+        compressed = client.get(summary["download_url"], headers={"Accept-Encoding": "gzip"})
+        assert compressed.headers["content-encoding"] == "gzip"
+        assert compressed.content == plain.content
+
+        assert client.get(f"/api/demos/{demo_id}/files/heatmap").status_code == 404
+
+
+def test_stats_csv_import_is_idempotent(tmp_path):
+    export = "Game ID,Map,Name,Steam ID,Kills,Deaths\nabc-1,Mirage,s1mple,765,25,14\nabc-1,Mirage,b1t,766,17,16\n"
+    with create_test_client(tmp_path) as client:
+        first = client.post(
+            "/api/stats/import",
+            files={"file": ("leetify.csv", io.BytesIO(export.encode()), "text/csv")},
+            data={"source": "leetify"},
+        )
+        assert first.status_code == 200
+        assert first.json()["imported"] == 2
+
+        again = client.post(
+            "/api/stats/import",
+            files={"file": ("leetify.csv", io.BytesIO(export.encode()), "text/csv")},
+            data={"source": "leetify"},
+        ).json()
+        assert (again["imported"], again["updated"]) == (0, 2)
+
+        stats = client.get("/api/stats/players", params={"steamid": "765"}).json()
+        assert [(row["map_name"], row["kills"]) for row in stats] == [("de_mirage", 25)]
+
+
+def test_delete_demo_endpoint(tmp_path):
+    with create_test_client(tmp_path) as client:
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        demo_id = client.post("/api/demos/upload", files=files).json()["id"]
+
+        assert client.delete(f"/api/demos/{demo_id}").status_code == 204
+        assert client.get(f"/api/demos/{demo_id}").status_code == 404
+        assert client.delete(f"/api/demos/{demo_id}").status_code == 404
+
+
+def test_schedule_calendar_feed_requires_valid_token(tmp_path):
+    with create_test_client(tmp_path) as client:
+        created = client.post(
+            "/api/schedule/sessions",
+            json={
+                "kind": "review",
+                "title": "Anti-strat review",
+                "starts_at": "2024-07-07T20:00:00+02:00",
+                "ends_at": "2024-07-07T21:00:00+02:00",
+            },
+        )
+        assert created.status_code == 201
+        assert created.json()["starts_at"].startswith("2024-07-07T18:00:00")
+
+        user_id = client.get("/api/users").json()[0]["id"]
+        issued = client.post("/api/schedule/feed-tokens", json={"user_id": user_id}).json()
+
+        feed = client.get(issued["feed_url"])
+        assert feed.status_code == 200
+        assert feed.headers["content-type"].startswith("text/calendar")
+        assert "SUMMARY:Anti-strat review" in feed.text
+
+        assert client.delete(f"/api/schedule/feed-tokens/{issued['id']}").status_code == 204
+        assert client.get(issued["feed_url"]).status_code == 401
+
+
+def test_onboarding_wizard_resumes_in_order(tmp_path):
+    with create_test_client(tmp_path) as client:
+        user_id = client.get("/api/users").json()[0]["id"]
+
+        state = client.get(f"/api/onboarding/{user_id}").json()
+        assert state["current_step"] == "create_team"
+        assert client.post(f"/api/onboarding/{user_id}/steam", json={"steamid": "76561197960287930"}).status_code == 409
+
+        assert client.post(f"/api/onboarding/{user_id}/team", json={"name": "Alpha", "tag": "ALP"}).status_code == 200
+        assert client.post(f"/api/onboarding/{user_id}/steam", json={"steamid": "12345"}).status_code == 400
+        steam = client.post(
+            f"/api/onboarding/{user_id}/steam", json={"steamid": "https://steamcommunity.com/profiles/76561197960287930"}
+        )
+        assert steam.json()["steamid"] == "76561197960287930"
+
+        pool = client.put(f"/api/onboarding/{user_id}/map-pool", json={"maps": ["Mirage", "de_inferno", "mirage"]})
+        assert pool.json()["map_pool"] == ["de_mirage", "de_inferno"]
+
+        invites = client.post(f"/api/onboarding/{user_id}/invites", json={"emails": ["coach@example.com"]}).json()
+        assert invites["invites"][0]["token"]
+        assert invites["onboarding"]["current_step"] == "first_demo"
+
+        upload = client.post(
+            "/api/demos/upload",
+            files={"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")},
+        )
+        done = client.post(f"/api/onboarding/{user_id}/first-demo", json={"demo_id": upload.json()["id"]}).json()
+        assert done["current_step"] == "completed"
+        assert done["completed_at"] is not None
+        assert client.get(f"/api/onboarding/{user_id}").json()["team_name"] == "Alpha"
+
+
+def test_metrics_endpoint_reports_processed_demos(tmp_path):
+    with create_test_client(tmp_path) as client:
+        client.post(
+            "/api/demos/upload",
+            files={"demo": ("metrics.dem", io.BytesIO(b"PBDEMS2\x00metrics demo"), "application/octet-stream")},
+        )
+
+        response = client.get("/metrics")
+
+        assert response.status_code == 200
+        assert response.headers["content-type"].startswith("text/plain")
+        body = response.text
+        assert "stratagemforge_demos_processed_total" in body
+        assert "stratagemforge_parquet_bytes_written_total" in body
+        assert "stratagemforge_parse_queue_depth 0.0" in body
+
+
+def test_error_messages_follow_accept_language(tmp_path):
+    with create_test_client(tmp_path) as client:
+        response = client.get("/api/demos/missing", headers={"Accept-Language": "de-DE,de;q=0.9,en;q=0.8"})
+
+        assert response.status_code == 404
+        assert response.json()["detail"] == "Demo nicht gefunden"
+        assert response.headers["content-language"] == "de"
+
+        fallback = client.get("/api/demos/missing", headers={"Accept-Language": "fr"})
+        assert fallback.json()["detail"] == "Demo not found"
+
+
+def test_public_api_requires_a_key_and_supports_revalidation(tmp_path):
+    with create_test_client(tmp_path) as client:
+        user_id = client.get("/api/users").json()[0]["id"]
+        client.post(f"/api/onboarding/{user_id}/team", json={"name": "Alpha", "tag": "ALP"})
+        team_id = client.get(f"/api/onboarding/{user_id}").json()["team_id"]
+        issued = client.post("/api/admin/public-keys", json={"team_id": team_id, "rate_limit_per_minute": 2}).json()
+
+        assert client.get("/public/v1/team").status_code == 401
+        response = client.get("/public/v1/team", headers={"X-API-Key": issued["key"]})
+        assert response.status_code == 200
+        assert response.json()["team"] == "Alpha"
+        assert response.headers["x-ratelimit-remaining"] == "1"
+        assert response.headers["cache-control"].startswith("public")
+
+        revalidated = client.get(
+            "/public/v1/team", headers={"X-API-Key": issued["key"], "If-None-Match": response.headers["etag"]}
+        )
+        assert revalidated.status_code == 304
+        limited = client.get("/public/v1/team", headers={"X-API-Key": issued["key"]})
+        assert limited.status_code == 429
+        assert "retry-after" in limited.headers
+
+
+def test_api_key_auth_enforces_scopes_when_enabled(tmp_path):
+    settings = Settings(data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db", auth_required=True)
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        assert client.get("/health").status_code == 200
+        assert client.get("/api/demos").status_code == 401
+
+        with session_scope() as session:
+            users = deps.get_user_service()
+            user = users.list_users(session)[0]
+            _, uploader = users.create_api_key(session, user.id, "uploader", ["upload"])
+            _, reader = users.create_api_key(session, user.id, "reader", ["read"], rate_limit_per_minute=5)
+
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        assert client.post("/api/demos/upload", files=files, headers={"X-API-Key": uploader}).status_code == 201
+        assert client.get("/api/demos", headers={"X-API-Key": uploader}).status_code == 403
+
+        listing = client.get("/api/demos", headers={"X-API-Key": reader})
+        assert listing.status_code == 200
+        assert listing.json()["total"] == 1
+        assert listing.headers["x-ratelimit-limit"] == "5"
+        assert listing.json()["demos"][0]["owner_id"] == user.id
+        demo_id = listing.json()["demos"][0]["id"]
+        assert client.delete(f"/api/demos/{demo_id}", headers={"X-API-Key": reader}).status_code == 403
+
+
+def test_password_login_issues_refreshable_revocable_tokens(tmp_path):
+    settings = Settings(
+        data_dir=tmp_path / "data",
+        database_url=f"sqlite:///{tmp_path}/test.db",
+        auth_required=True,
+        seed_user_password="analyst-pass",
+    )
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        credentials = {"email": "analyst@example.com", "password": "wrong-pass"}
+        assert client.post("/api/auth/login", json=credentials).status_code == 401
+
+        login = client.post("/api/auth/login", json={**credentials, "password": "analyst-pass"})
+        assert login.status_code == 200
+        session_tokens = login.json()
+        assert session_tokens["token_type"] == "bearer"
+        bearer = {"Authorization": f"Bearer {session_tokens['access_token']}"}
+
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        uploaded = client.post("/api/demos/upload", files=files, headers=bearer)
+        assert uploaded.status_code == 201
+        assert uploaded.json()["owner_id"] == session_tokens["user"]["id"]
+        assert client.get("/.well-known/jwks.json").json()["keys"][0]["alg"] == "RS256"
+
+        refreshed = client.post("/api/auth/refresh", json={"refresh_token": session_tokens["refresh_token"]})
+        assert refreshed.status_code == 200
+        replayed = client.post("/api/auth/refresh", json={"refresh_token": session_tokens["refresh_token"]})
+        assert replayed.status_code == 401
+
+        logout = client.post("/api/auth/logout", json={}, headers=bearer)
+        assert logout.status_code == 204
+        assert client.get("/api/demos", headers=bearer).status_code == 401
+
+
+def test_oidc_authorization_code_flow(tmp_path):
+    settings = Settings(
+        data_dir=tmp_path / "data",
+        database_url=f"sqlite:///{tmp_path}/test.db",
+        seed_user_password="analyst-pass",
+    )
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        discovery = client.get("/.well-known/openid-configuration").json()
+        assert discovery["token_endpoint"] == "http://testserver/oauth2/token"
+        registered = client.post(
+            "/api/admin/oidc-clients", json={"name": "Ingestion", "redirect_uris": ["https://ingest.example.com/cb"]}
+        )
+        assert registered.status_code == 201
+        oidc_client = registered.json()
+        request = {
+            "client_id": oidc_client["id"],
+            "redirect_uri": "https://ingest.example.com/cb",
+            "response_type": "code",
+            "scope": "openid",
+            "state": "s1",
+        }
+        assert 'name="state" value="s1"' in client.get("/oauth2/authorize", params=request).text
+        unregistered = {**request, "redirect_uri": "https://evil.example.com/cb"}
+        assert client.get("/oauth2/authorize", params=unregistered).status_code == 400
+
+        signed_in = client.post(
+            "/oauth2/authorize",
+            data={**request, "email": "analyst@example.com", "password": "analyst-pass"},
+            follow_redirects=False,
+        )
+        assert signed_in.status_code == 303
+        code = signed_in.headers["location"].split("code=")[1].split("&")[0]
+        exchanged = client.post(
+            "/oauth2/token",
+            data={"grant_type": "authorization_code", "code": code, "redirect_uri": "https://ingest.example.com/cb"},
+            auth=(oidc_client["id"], oidc_client["client_secret"]),
+        )
+        assert exchanged.status_code == 200 and exchanged.headers["cache-control"] == "no-store"
+        bearer = {"Authorization": f"Bearer {exchanged.json()['access_token']}"}
+        assert client.get("/oauth2/userinfo", headers=bearer).json()["email"] == "analyst@example.com"
+        assert client.get("/api/demos", headers=bearer).status_code == 403
+        assert client.post("/oauth2/token", data={"grant_type": "authorization_code", "code": code}).status_code == 401
+
+
+def test_users_register_manage_their_own_account(tmp_path):
+    settings = Settings(
+        data_dir=tmp_path / "data",
+        database_url=f"sqlite:///{tmp_path}/test.db",
+        auth_required=True,
+    )
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        signup = {"email": "coach@example.com", "display_name": "Coach", "password": "s3cret-pass"}
+        created = client.post("/api/users", json=signup)
+        assert created.status_code == 201
+        user_id = created.json()["id"]
+        assert client.post("/api/users", json=signup).status_code == 409
+
+        login = client.post("/api/auth/login", json={"email": signup["email"], "password": "s3cret-pass"})
+        bearer = {"Authorization": f"Bearer {login.json()['access_token']}"}
+        renamed = client.patch(f"/api/users/{user_id}", json={"display_name": "Head Coach"}, headers=bearer)
+        assert renamed.status_code == 200
+        assert renamed.json()["display_name"] == "Head Coach"
+
+        with session_scope() as session:
+            seeded = deps.get_user_service().list_users(session)[0].id
+        assert client.patch(f"/api/users/{seeded}", json={"display_name": "Me"}, headers=bearer).status_code == 403
+
+        change = {"current_password": "s3cret-pass", "new_password": "n3w-secret"}
+        assert client.post(f"/api/users/{user_id}/password", json=change, headers=bearer).status_code == 204
+        assert client.post("/api/auth/password-reset", json={"email": "nobody@example.com"}).status_code == 204
+
+        login = client.post("/api/auth/login", json={"email": signup["email"], "password": "n3w-secret"})
+        bearer = {"Authorization": f"Bearer {login.json()['access_token']}"}
+        assert client.delete(f"/api/users/{user_id}", headers=bearer).status_code == 204
+        assert client.get(f"/api/users/{user_id}", headers=bearer).status_code == 404
+        assert client.post("/api/auth/login", json={**signup, "password": "n3w-secret"}).status_code == 401
+
+
+def test_users_mint_rotate_and_revoke_their_own_api_keys(tmp_path):
+    settings = Settings(
+        data_dir=tmp_path / "data",
+        database_url=f"sqlite:///{tmp_path}/test.db",
+        auth_required=True,
+    )
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        account = {"email": "relay@example.com", "display_name": "Relay", "password": "s3cret-pass"}
+        assert client.post("/api/users", json=account).status_code == 201
+        login = client.post("/api/auth/login", json={"email": account["email"], "password": "s3cret-pass"})
+        bearer = {"Authorization": f"Bearer {login.json()['access_token']}"}
+
+        escalated = client.post("/api/apikeys", json={"name": "root", "scopes": ["admin"]}, headers=bearer)
+        assert escalated.status_code == 403
+        minted = client.post("/api/apikeys", json={"name": "gotv relay", "scopes": ["upload"]}, headers=bearer)
+        assert minted.status_code == 201
+        key_id, key = minted.json()["id"], minted.json()["key"]
+
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        assert client.post("/api/demos/upload", files=files, headers={"X-API-Key": key}).status_code == 201
+        listed = client.get("/api/apikeys", headers=bearer).json()
+        assert [(item["id"], item["last_used_at"] is not None) for item in listed] == [(key_id, True)]
+        assert "key" not in listed[0]
+
+        rotated = client.post(f"/api/apikeys/{key_id}/rotate", headers=bearer).json()
+        assert rotated["id"] == key_id and rotated["key"] != key
+        assert client.get("/api/demos", headers={"X-API-Key": key}).status_code == 401
+        assert client.get("/api/demos", headers={"X-API-Key": rotated["key"]}).status_code == 403
+
+        assert client.delete(f"/api/apikeys/{key_id}", headers=bearer).status_code == 204
+        assert client.get("/api/apikeys", headers=bearer).json() == []
+        assert client.post("/api/demos/upload", files=files, headers={"X-API-Key": rotated["key"]}).status_code == 401
+
+
+def test_read_only_keys_cannot_rotate_or_rescope_stronger_keys(tmp_path):
+    settings = Settings(data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db", auth_required=True)
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        with session_scope() as session:
+            users = deps.get_user_service()
+            user = users.list_users(session)[0]
+            admin_key, _ = users.create_api_key(session, user.id, "root", ["admin"])
+            _, reader = users.create_api_key(session, user.id, "reader", ["read"])
+            admin_key_id = admin_key.id
+
+        headers = {"X-API-Key": reader}
+        rotated = client.post(f"/api/apikeys/{admin_key_id}/rotate", headers=headers)
+        assert rotated.status_code == 403
+        assert "key" not in rotated.json()
+        rescoped = client.patch(f"/api/apikeys/{admin_key_id}", json={"scopes": ["read"]}, headers=headers)
+        assert rescoped.status_code == 403
+        assert client.post("/api/apikeys/missing/rotate", headers=headers).status_code == 404
+
+
+def test_admin_routes_need_the_admin_scope_to_read(tmp_path):
+    settings = Settings(data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db", auth_required=True)
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        with session_scope() as session:
+            users = deps.get_user_service()
+            user = users.list_users(session)[0]
+            _, admin = users.create_api_key(session, user.id, "root", ["admin"])
+            _, reader = users.create_api_key(session, user.id, "reader", ["read"])
+
+        for path in ("/api/admin/alert-rules", "/api/admin/registration/invites", "/api/admin/exports/teams/t1.tar.gz"):
+            assert client.get(path, headers={"X-API-Key": reader}).status_code == 403
+        assert client.get("/api/admin/alert-rules", headers={"X-API-Key": admin}).status_code == 200
+
+
+def test_user_emails_and_api_keys_stay_with_their_owner(tmp_path):
+    settings = Settings(data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db", auth_required=True)
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        bearers, ids = {}, {}
+        for name in ("coach", "rifler"):
+            account = {"email": f"{name}@example.com", "display_name": name, "password": "s3cret-pass"}
+            ids[name] = client.post("/api/users", json=account).json()["id"]
+            login = client.post("/api/auth/login", json={"email": account["email"], "password": "s3cret-pass"})
+            bearers[name] = {"Authorization": f"Bearer {login.json()['access_token']}"}
+
+        listed = {user["id"]: user["email"] for user in client.get("/api/users", headers=bearers["rifler"]).json()}
+        assert listed[ids["rifler"]] == "rifler@example.com"
+        assert listed[ids["coach"]] is None
+        assert client.get(f"/api/users/{ids['coach']}", headers=bearers["rifler"]).json()["email"] is None
+        assert client.get(f"/api/users/{ids['coach']}", headers=bearers["coach"]).json()["email"] == "coach@example.com"
+
+        assert client.get(f"/api/users/{ids['coach']}/api-keys", headers=bearers["rifler"]).status_code == 403
+        assert client.get(f"/api/users/{ids['rifler']}/api-keys", headers=bearers["rifler"]).status_code == 200
+
+
+def test_jobs_follow_the_visibility_of_their_demos(tmp_path):
+    settings = Settings(data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db", auth_required=True)
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        bearers = {}
+        for name in ("coach", "rifler"):
+            account = {"email": f"{name}@example.com", "display_name": name, "password": "s3cret-pass"}
+            assert client.post("/api/users", json=account).status_code == 201
+            login = client.post("/api/auth/login", json={"email": account["email"], "password": "s3cret-pass"})
+            bearers[name] = {"Authorization": f"Bearer {login.json()['access_token']}"}
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        demo_id = client.post("/api/demos/upload", files=files, headers=bearers["coach"]).json()["id"]
+
+        jobs = client.get("/api/jobs", headers=bearers["coach"]).json()
+        assert {job["demo_id"] for job in jobs} == {demo_id}
+        assert client.get("/api/jobs", headers=bearers["rifler"]).json() == []
+        assert client.get(f"/api/jobs/{jobs[0]['id']}", headers=bearers["rifler"]).status_code == 404
+        assert client.get(f"/api/jobs/{jobs[0]['id']}", headers=bearers["coach"]).status_code == 200
+
+    settings.auth_required = False
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        assert client.post(f"/api/jobs/{jobs[0]['id']}/retry").status_code == 401
+
+
+def test_team_demos_are_visible_to_members_only(tmp_path):
+    settings = Settings(
+        data_dir=tmp_path / "data",
+        database_url=f"sqlite:///{tmp_path}/test.db",
+        auth_required=True,
+    )
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        bearers = {}
+        for name in ("coach", "rifler"):
+            account = {"email": f"{name}@example.com", "display_name": name, "password": "s3cret-pass"}
+            assert client.post("/api/users", json=account).status_code == 201
+            login = client.post("/api/auth/login", json={"email": account["email"], "password": "s3cret-pass"})
+            bearers[name] = {"Authorization": f"Bearer {login.json()['access_token']}"}
+
+        team = client.post("/api/teams", json={"name": "Falcons"}, headers=bearers["coach"])
+        assert team.status_code == 201
+        team_id = team.json()["id"]
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        uploaded = client.post("/api/demos/upload", files=files, headers=bearers["coach"])
+        assert uploaded.json()["team_id"] == team_id
+        demo_id = uploaded.json()["id"]
+
+        assert client.get(f"/api/demos/{demo_id}", headers=bearers["rifler"]).status_code == 404
+        assert client.get("/api/demos", headers=bearers["rifler"]).json()["total"] == 0
+        assert client.get(f"/api/teams/{team_id}", headers=bearers["rifler"]).status_code == 404
+
+        invite = client.post(
+            f"/api/teams/{team_id}/invites", json={"email": "rifler@example.com"}, headers=bearers["coach"]
+        ).json()
+        accepted = client.post("/api/teams/invites/accept", json={"token": invite["token"]}, headers=bearers["rifler"])
+        assert accepted.status_code == 200
+        assert client.get(f"/api/demos/{demo_id}", headers=bearers["rifler"]).status_code == 200
+        assert client.post(
+            f"/api/teams/{team_id}/invites", json={"email": "x@example.com"}, headers=bearers["rifler"]
+        ).status_code == 403
+
+        assert client.delete(f"/api/demos/{demo_id}", headers=bearers["rifler"]).status_code == 403
+        assert client.delete(f"/api/demos/{demo_id}", headers=bearers["coach"]).status_code == 204
+        assert client.get(f"/api/demos/{demo_id}", headers=bearers["rifler"]).status_code == 404
+        trash = client.get("/api/demos/trash", headers=bearers["rifler"]).json()
+        assert [(demo["id"], demo["purge_at"] is not None) for demo in trash] == [(demo_id, True)]
+
+        assert client.post(f"/api/demos/{demo_id}/restore", headers=bearers["rifler"]).status_code == 403
+        restored = client.post(f"/api/demos/{demo_id}/restore", headers=bearers["coach"])
+        assert restored.status_code == 200
+        assert client.get(f"/api/demos/{demo_id}", headers=bearers["rifler"]).status_code == 200
+        assert client.post(f"/api/demos/{demo_id}/restore", headers=bearers["coach"]).status_code == 404
+        assert client.delete(
+            f"/api/demos/{demo_id}", params={"permanent": True}, headers=bearers["coach"]
+        ).status_code == 403
+
+
+def test_demo_visibility_levels_and_per_user_listing(tmp_path):
+    settings = Settings(
+        data_dir=tmp_path / "data",
+        database_url=f"sqlite:///{tmp_path}/test.db",
+        auth_required=True,
+    )
+    settings.ensure_directories()
+    deps.configure(settings)
+    with TestClient(create_app(settings)) as client:
+        bearers, user_ids = {}, {}
+        for name in ("entry", "lurker"):
+            account = {"email": f"{name}@example.com", "display_name": name, "password": "s3cret-pass"}
+            registered = client.post("/api/users", json=account)
+            assert registered.status_code == 201
+            user_ids[name] = registered.json()["id"]
+            login = client.post("/api/auth/login", json={"email": account["email"], "password": "s3cret-pass"})
+            bearers[name] = {"Authorization": f"Bearer {login.json()['access_token']}"}
+
+        files = {"demo": ("test.dem", io.BytesIO(DEMO_BYTES), "application/octet-stream")}
+        uploaded = client.post("/api/demos/upload", files=files, headers=bearers["entry"]).json()
+        assert (uploaded["owner_id"], uploaded["visibility"]) == (user_ids["entry"], "private")
+        demo_id = uploaded["id"]
+
+        matches_url = f"/api/users/{user_ids['entry']}/matches"
+        assert client.get(matches_url, headers=bearers["entry"]).json()["total"] == 1
+        assert client.get(matches_url, headers=bearers["lurker"]).json()["total"] == 0
+        assert client.get(f"/api/demos/{demo_id}", headers=bearers["lurker"]).status_code == 404
+
+        shared = {"visibility": "team"}
+        assert client.put(f"/api/demos/{demo_id}/visibility", json=shared, headers=bearers["entry"]).status_code == 400
+        published = {"visibility": "public"}
+        assert client.put(
+            f"/api/demos/{demo_id}/visibility", json=published, headers=bearers["lurker"]
+        ).status_code == 403
+        updated = client.put(f"/api/demos/{demo_id}/visibility", json=published, headers=bearers["entry"])
+        assert updated.json()["visibility"] == "public"
+        assert client.get(f"/api/demos/{demo_id}", headers=bearers["lurker"]).status_code == 200
+        assert [demo["id"] for demo in client.get(matches_url, headers=bearers["lurker"]).json()["demos"]] == [demo_id]
@@ -0,0 +1,113 @@
+from __future__ import annotations
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.activity.service import ActivityService
+from stratagemforge.domain.demos.models import Demo
+from stratagemforge.domain.teams.service import TeamService
+from stratagemforge.domain.users.models import User
+
+
+@pytest.fixture
+def session(session):
+    session.add_all(
+        [
+            User(id="coach", email="coach@example.com", display_name="Coach"),
+            User(id="rifler", email="rifler@example.com", display_name="Rifler"),
+            User(id="outsider", email="outsider@example.com", display_name="Outsider"),
+        ]
+    )
+    session.commit()
+    return session
+
+
+@pytest.fixture
+def teams(tmp_path):
+    return TeamService(Settings(data_dir=tmp_path))
+
+
+@pytest.fixture
+def service(tmp_path, teams):
+    return ActivityService(Settings(data_dir=tmp_path), teams)
+
+
+def make_demo(session, demo_id, team_id=None, visibility=None) -> Demo:
+    demo = Demo(
+        id=demo_id,
+        original_filename=f"{demo_id}.dem",
+        stored_path=f"/tmp/{demo_id}.dem",
+        checksum=demo_id,
+        size_bytes=1,
+        team_id=team_id,
+        owner_id="coach",
+        visibility=visibility,
+    )
+    session.add(demo)
+    session.commit()
+    return demo
+
+
+def verbs(entries):
+    return [entry.verb for entry in entries]
+
+
+def test_feeds_are_scoped_to_the_viewers_teams_and_privacy(session, teams, service):
+    team = teams.create_team(session, "coach", "Falcons")
+    teams.accept_invite(session, teams.invite(session, team.id, "coach", "rifler@example.com").token, "rifler")
+
+    shared = make_demo(session, "shared", team_id=team.id, visibility="team")
+    service.record_upload_after_ingest(session, shared)
+    service.record_upload_after_ingest(session, shared)  # reprocessing adds nothing
+    service.record_upload_after_ingest(session, make_demo(session, "secret", team_id=team.id, visibility="private"))
+    comment = {"demo_id": "shared", "round": 14}
+    service.record_client_activity(session, "rifler", "comment.created", "comment", team_id=team.id, details=comment)
+    service.record_client_activity(session, "rifler", "strategy.created", "strategy", "s1")
+
+    team_feed, total = service.feed(session, "rifler", team_id=team.id)
+    assert (verbs(team_feed), total) == (["comment.created", "demo.uploaded"], 2)
+    assert team_feed[0].details["round"] == 14
+    assert len(service.feed(session, "coach", team_id=team.id)[0]) == 3  # the coach's own private upload
+    assert verbs(service.feed(session, "rifler", actor_id="coach")[0]) == ["demo.uploaded"]
+    assert verbs(service.feed(session, "rifler", verbs=["strategy.created"])[0]) == ["strategy.created"]
+
+    assert service.feed(session, "outsider", actor_id="rifler") == ([], 0)
+    assert service.feed(session, "outsider", team_id=team.id, admin=True)[1] == 3
+    with pytest.raises(LookupError):
+        service.feed(session, "outsider", team_id=team.id)
+    with pytest.raises(PermissionError):
+        service.record_client_activity(session, "outsider", "comment.created", "comment", team_id=team.id)
+
+    teams.set_permissions(session, team.id, "coach", {"view": "coach"})
+    with pytest.raises(PermissionError):
+        service.feed(session, "rifler", team_id=team.id)
+
+
+def test_sharing_a_demo_rescopes_its_entries(session, teams, service):
+    team = teams.create_team(session, "coach", "Falcons")
+    teams.accept_invite(session, teams.invite(session, team.id, "coach", "rifler@example.com").token, "rifler")
+    demo = make_demo(session, "draft", team_id=team.id, visibility="private")
+    service.record_upload_after_ingest(session, demo)
+    assert service.feed(session, "rifler", team_id=team.id)[1] == 0
+
+    demo.visibility = "team"
+    service.record_demo_shared(session, "coach", demo)
+
+    assert verbs(service.feed(session, "rifler", team_id=team.id)[0]) == ["demo.shared", "demo.uploaded"]
+    with pytest.raises(ValueError, match="limited"):
+        service.record(session, "coach", "comment.created", "comment", details={"text": "x" * 3000})
+
+
+def test_cursor_pages_stay_stable_while_new_entries_arrive(session, service):
+    for number in range(5):
+        service.record(session, "coach", "comment.created", "comment", str(number), private=True)
+
+    first, total = service.feed(session, "coach", page_size=2)
+    service.record(session, "coach", "comment.created", "comment", "late", private=True)
+    second, _ = service.feed(session, "coach", page_size=2, cursor=first[-1].id)
+    third, _ = service.feed(session, "coach", page_size=2, cursor=second[-1].id)
+
+    assert total == 5
+    assert [entry.subject_id for entry in first + second + third] == ["4", "3", "2", "1", "0"]
+    with pytest.raises(ValueError):
+        service.feed(session, "coach", cursor="not-a-ulid")
@@ -0,0 +1,144 @@
+from __future__ import annotations
+
+from collections import namedtuple
+from datetime import datetime, timedelta
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.models import Demo
+from stratagemforge.domain.notifications.alerts import AlertService
+from stratagemforge.domain.notifications.channels import ChannelSender
+from stratagemforge.domain.notifications.schemas import AlertRuleCreate
+from stratagemforge.domain.notifications.service import NotificationService
+from stratagemforge.domain.users.models import User
+
+DiskUsage = namedtuple("DiskUsage", "total used free")
+NOW = datetime(2024, 5, 1, 20, 0)
+
+
+class RecordingSender(ChannelSender):
+    def __init__(self, settings, notifications):
+        super().__init__(settings, notifications)
+        self.sent = []
+
+    def _send_discord(self, session, target, message):
+        self.sent.append((target, message.action, message.summary))
+
+
+@pytest.fixture
+def session(session):
+    session.add_all(
+        [
+            User(id="admin", email="admin@example.com", display_name="Admin", role="admin"),
+            User(id="analyst", email="analyst@example.com", display_name="Analyst"),
+        ]
+    )
+    session.commit()
+    return session
+
+
+@pytest.fixture
+def notifications(tmp_path):
+    return NotificationService(Settings(data_dir=tmp_path))
+
+
+def _service(tmp_path, notifications, used=50):
+    settings = Settings(data_dir=tmp_path)
+    sender = RecordingSender(settings, notifications)
+    disk_usage = lambda path: DiskUsage(100, used, 100 - used)  # noqa: E731
+    return AlertService(settings, notifications, sender=sender, disk_usage=disk_usage)
+
+
+def _demo(session, index, parse_status, processed_at):
+    session.add(
+        Demo(
+            id=f"d{index}",
+            original_filename=f"{index}.dem",
+            stored_path="x",
+            checksum=f"c{index}",
+            size_bytes=1,
+            status="processed",
+            processed_at=processed_at,
+            extra_metadata={"parse_status": parse_status},
+        )
+    )
+    session.commit()
+
+
+def test_parse_failures_fire_once_escalate_and_resolve(session, tmp_path, notifications):
+    service = _service(tmp_path, notifications)
+    service.create_rule(
+        session,
+        AlertRuleCreate(
+            name="Parser broken",
+            metric="consecutive_parse_failures",
+            threshold=2,
+            channels=[{"type": "discord", "target": "https://discord.example/hook"}, {"type": "inbox"}],
+            escalation_channels=[{"type": "discord", "target": "https://discord.example/oncall"}],
+            escalate_after_minutes=30,
+        ),
+    )
+    _demo(session, 1, "parsed", NOW - timedelta(hours=2))
+    for index in range(2, 5):
+        _demo(session, index, "failed", NOW - timedelta(minutes=10 - index))
+
+    first = service.evaluate(session, now=NOW)[0]
+    assert (first.state, first.action, first.value) == ("firing", "trigger", 3.0)
+    assert service.sender.sent[0][:2] == ("https://discord.example/hook", "trigger")
+    assert notifications.list_notifications(session, "admin")[1] == 1
+    assert notifications.list_notifications(session, "analyst")[1] == 0
+
+    assert service.evaluate(session, now=NOW + timedelta(minutes=5))[0].action is None
+    escalated = service.evaluate(session, now=NOW + timedelta(minutes=31))[0]
+    assert escalated.action == "escalate"
+    assert service.sender.sent[-1][:2] == ("https://discord.example/oncall", "escalate")
+
+    _demo(session, 5, "parsed", NOW + timedelta(minutes=40))
+    resolved = service.evaluate(session, now=NOW + timedelta(minutes=41))[0]
+    assert (resolved.state, resolved.action) == ("ok", "resolve")
+    assert {target for target, action, _ in service.sender.sent if action == "resolve"} == {
+        "https://discord.example/hook",
+        "https://discord.example/oncall",
+    }
+
+
+def test_queue_age_and_disk_usage_metrics(session, tmp_path, notifications):
+    session.add(
+        Demo(
+            id="waiting",
+            original_filename="w.dem",
+            stored_path="x",
+            checksum="w",
+            size_bytes=1,
+            status="uploaded",
+            uploaded_at=NOW - timedelta(minutes=45),
+        )
+    )
+    session.commit()
+    service = _service(tmp_path, notifications, used=93)
+
+    assert service.measure(session, "parse_queue_age_minutes", NOW) == 45.0
+    assert service.measure(session, "disk_usage_percent", NOW) == 93.0
+
+
+def test_failed_channels_are_reported_not_raised(session, tmp_path, notifications):
+    service = _service(tmp_path, notifications, used=95)
+    service.create_rule(
+        session,
+        AlertRuleCreate(
+            name="Disk", metric="disk_usage_percent", threshold=90, channels=[{"type": "email", "target": "ops@x.io"}]
+        ),
+    )
+
+    evaluation = service.evaluate(session, now=NOW)[0]
+
+    assert evaluation.action == "trigger"
+    assert evaluation.deliveries == [
+        {"type": "email", "target": "ops@x.io", "delivered": False, "error": "SMTP is not configured"}
+    ]
+
+
+def test_channels_other_than_inbox_need_a_target():
+    with pytest.raises(ValueError):
+        AlertRuleCreate(name="x", metric="disk_usage_percent", threshold=90, channels=[{"type": "pagerduty"}])
@@ -0,0 +1,182 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+
+import pandas as pd
+import pytest
+
+from stratagemforge.domain.analysis.heatmaps import PNG_SIGNATURE, build_heatmap, heatmap_png
+from stratagemforge.domain.analysis.opponents import fit_site_models, predict_site
+from stratagemforge.domain.analysis.roles import infer_roles
+from stratagemforge.domain.analysis.rosters import detect_roster_eras
+from stratagemforge.domain.analysis.windows import AnalysisWindow
+from stratagemforge.domain.demos.models import Demo, Match
+from stratagemforge.domain.demos.radar import add_radar_columns, radar_for
+from stratagemforge.domain.demos.extractors.players import build_role_features
+
+
+def role_feature_rows() -> pd.DataFrame:
+    base = {"team_name": "Alpha", "map_name": "de_mirage", "rounds": 20, "kills": 15, "awp_kills": 0, "opening_kills": 2,
+            "opening_deaths": 2, "t_opening_duels": 1, "utility_thrown": 20, "flashes_thrown": 5, "death_order_total": 60}
+    players = [
+        {"steamid": "1", "name": "sniper", "kills": 20, "awp_kills": 14},
+        {"steamid": "2", "name": "opener", "t_opening_duels": 8, "opening_kills": 6},
+        {"steamid": "3", "name": "late", "death_order_total": 110},
+        {"steamid": "4", "name": "flasher", "flashes_thrown": 30},
+        {"steamid": "5", "name": "caller", "kills": 8},
+    ]
+    rows = []
+    for demo_id in ("demo-1", "demo-2"):
+        rows.extend({**base, **player, "demo_id": demo_id} for player in players)
+    return pd.DataFrame(rows)
+
+
+def test_infer_roles_assigns_each_role_once_per_lineup():
+    roles = infer_roles(role_feature_rows()).set_index("name")["role"].to_dict()
+
+    assert roles == {
+        "sniper": "awper",
+        "opener": "entry",
+        "late": "lurker",
+        "flasher": "support",
+        "caller": "igl_proxy",
+    }
+
+
+def test_infer_roles_counts_matches_per_player():
+    roles = infer_roles(role_feature_rows())
+
+    assert set(roles["matches"]) == {2}
+    assert roles.loc[roles["name"] == "sniper", "awp_kill_share"].iloc[0] == 0.7
+
+
+def test_role_features_count_openings_and_awp_kills(parsed_demo):
+    features = build_role_features(parsed_demo).set_index("steamid")
+
+    assert features.loc["1", "awp_kills"] == 1
+    assert features.loc["1", "opening_kills"] == 1
+    assert features.loc["6", "opening_deaths"] == 1
+    assert features.loc["7", "t_opening_duels"] == 1
+
+
+def appearances(lineups: list[list[str]]) -> pd.DataFrame:
+    rows = []
+    for day, lineup in enumerate(lineups, start=1):
+        for steamid in lineup:
+            rows.append(
+                {
+                    "demo_id": f"demo-{day}",
+                    "demo_date": datetime(2024, 1, day),
+                    "steamid": steamid,
+                    "name": f"player-{steamid}",
+                    "team_name": "Alpha",
+                }
+            )
+    return pd.DataFrame(rows)
+
+
+def test_heatmap_bins_positions_over_the_radar():
+    ticks = pd.DataFrame(
+        [
+            {"steamid": "1", "side": "CT", "round": 1, "phase": "live", "x": -3000.0, "y": 1500.0},
+            {"steamid": "1", "side": "CT", "round": 1, "phase": "live", "x": -2990.0, "y": 1490.0},
+            {"steamid": "1", "side": "CT", "round": 2, "phase": "live", "x": 1800.0, "y": -3300.0},
+            {"steamid": "1", "side": "CT", "round": 2, "phase": "live", "x": 5000.0, "y": 0.0},  # off the radar
+            {"steamid": "6", "side": "T", "round": 1, "phase": "live", "x": -3000.0, "y": 1500.0},
+        ]
+    )
+
+    heatmap = build_heatmap(ticks, "de_mirage", bins=4, side="CT")
+
+    assert heatmap["radar"] == {"pos_x": -3230, "pos_y": 1713, "scale": 5.0, "size": 1024}
+    assert heatmap["grid"][0][0] == 2
+    assert heatmap["grid"][3][3] == 1
+    assert (heatmap["samples"], heatmap["max_count"]) == (3, 2)
+    assert build_heatmap(ticks, "de_mirage", bins=4, round_number=2)["samples"] == 1
+
+    unknown_map = build_heatmap(ticks, "de_custom", bins=2, steamid="6")
+    assert unknown_map["radar"] is None
+    assert unknown_map["samples"] == 1
+
+    png = heatmap_png(heatmap["grid"])
+    assert png.startswith(PNG_SIGNATURE)
+    assert png[16:24] == bytes([0, 0, 0, 4, 0, 0, 0, 4])  # IHDR width and height
+
+
+def test_radar_columns_normalise_positions_and_pick_the_floor():
+    kills = pd.DataFrame(
+        [
+            {"attacker_x": -3453.0, "attacker_y": 2887.0, "attacker_z": -600.0, "victim_x": None, "victim_y": None},
+            {"attacker_x": -1661.0, "attacker_y": -697.0, "attacker_z": 0.0, "victim_x": -1661.0, "victim_y": -697.0},
+        ]
+    )
+
+    columns = add_radar_columns(kills, "de_nuke", ("attacker_", "victim_"))
+
+    assert columns["attacker_radar_x"].tolist() == [0.0, 0.25]
+    assert columns["attacker_radar_y"].tolist() == [0.0, 0.5]
+    assert columns["attacker_radar_level"].tolist() == ["lower", "default"]
+    assert columns["victim_radar_level"].tolist() == [None, "default"]
+    assert "attacker_radar_x" not in kills.columns
+    assert add_radar_columns(kills, "de_unknown")["radar_level"].isna().all()
+    assert radar_for("de_vertigo").level(11500) == "lower"
+    assert radar_for("de_mirage").level(-10000) == "default"
+
+
+def test_analysis_window_keeps_the_teams_last_matches_within_the_dates():
+    now = datetime(2024, 7, 1)
+    demos = []
+    for days, team_b in [(1, "Bravo"), (2, "Charlie"), (3, "Bravo"), (40, "Bravo")]:
+        demo = Demo(original_filename=f"{days}.dem", stored_path="x", checksum=str(days), size_bytes=1)
+        demo.match = Match(team_a="Alpha", team_b=team_b, played_at=now - timedelta(days=days))
+        demos.append(demo)
+    unmatched = Demo(original_filename="u.dem", stored_path="x", checksum="u", size_bytes=1, uploaded_at=now)
+
+    window = AnalysisWindow(played_from=now - timedelta(days=30), last_n_matches=2)
+
+    assert [demo.original_filename for demo in window.select(demos + [unmatched])] == ["u.dem", "1.dem"]
+    assert [demo.original_filename for demo in window.select(demos + [unmatched], team="bravo")] == ["1.dem", "3.dem"]
+    assert len(AnalysisWindow(played_to=now - timedelta(days=10)).select(demos)) == 1
+    with pytest.raises(ValueError):
+        AnalysisWindow(played_from=now, played_to=now - timedelta(days=1))
+
+
+def test_roster_eras_split_on_lineup_change():
+    frame = appearances([["1", "2", "3", "4", "5"], ["1", "2", "3", "4", "5"], ["1", "2", "3", "4", "6"]])
+
+    eras = detect_roster_eras(frame, "alpha")
+
+    assert [era["demo_ids"] for era in eras] == [["demo-1", "demo-2"], ["demo-3"]]
+    assert "6" in eras[1]["players"]
+    assert eras[0]["ended_at"] == datetime(2024, 1, 2)
+
+
+def test_roster_eras_respect_min_changes():
+    frame = appearances([["1", "2", "3", "4", "5"], ["1", "2", "3", "4", "6"], ["1", "2", "3", "7", "8"]])
+
+    eras = detect_roster_eras(frame, "Alpha", min_changes=2)
+
+    assert [era["matches"] for era in eras] == [2, 1]
+
+
+def test_site_model_conditions_on_economy_and_round_time():
+    base = {"team_name": "Bravo", "map_name": "de_mirage", "score_for": 3, "score_against": 3}
+    rounds = (
+        [{**base, "economy": "full", "hit": "A", "hit_seconds": 40.0}] * 6
+        + [{**base, "economy": "eco", "hit": "B", "hit_seconds": 15.0}] * 4
+        + [{**base, "economy": "full", "hit": "mid", "hit_seconds": 70.0}] * 2
+        + [{**base, "economy": "full", "hit": None, "hit_seconds": None}]
+    )
+    [model] = fit_site_models(pd.DataFrame(rounds))
+    assert (model["team_name"], model["map_name"], model["rounds"]) == ("Bravo", "de_mirage", 12)
+
+    full_buy = predict_site(model["counts"], economy="full", state="level")
+    assert max(full_buy["probabilities"], key=full_buy["probabilities"].get) == "A"
+    assert full_buy["matching_rounds"] == 8
+    eco = predict_site(model["counts"], economy="eco")
+    assert eco["probabilities"]["B"] > 0.5
+
+    late = predict_site(model["counts"], elapsed_seconds=65)
+    assert late["rounds"] == 2
+    assert max(late["probabilities"], key=late["probabilities"].get) == "mid"
+    assert abs(sum(late["probabilities"].values()) - 1) < 0.01
@@ -0,0 +1,88 @@
+from __future__ import annotations
+
+import pytest
+
+from stratagemforge.api.auth import is_exempt, required_scope
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.users.models import User
+from stratagemforge.domain.users.service import UserService
+
+
+@pytest.fixture
+def session(session):
+    session.add(User(id="u1", email="uploader@example.com", display_name="Uploader"))
+    session.commit()
+    return session
+
+
+def test_api_keys_are_stored_hashed_and_can_be_revoked(session, tmp_path):
+    service = UserService(Settings(data_dir=tmp_path, api_key_rate_limit_per_minute=30))
+    api_key, key = service.create_api_key(session, "u1", "gotv relay", ["upload", "upload"])
+
+    assert key not in api_key.key_hash
+    assert api_key.scopes == ["upload"]
+    assert service.api_key_limit(api_key) == 30
+    authenticated = service.authenticate_api_key(session, key)
+    assert authenticated.id == api_key.id
+    assert authenticated.last_used_at is not None
+
+    with pytest.raises(LookupError):
+        service.revoke_api_key(session, "someone-else", api_key.id)
+    service.revoke_api_key(session, "u1", api_key.id)
+    with pytest.raises(PermissionError):
+        service.authenticate_api_key(session, key)
+
+
+def test_rotating_a_key_replaces_its_secret_but_keeps_its_identity(session, tmp_path):
+    service = UserService(Settings(data_dir=tmp_path))
+    api_key, old_key = service.create_api_key(session, "u1", "uploader", ["upload"])
+    service.update_api_key(session, "u1", api_key.id, name="gotv relay", scopes=["read", "upload"])
+
+    rotated, new_key = service.rotate_api_key(session, "u1", api_key.id)
+
+    assert (rotated.id, rotated.name, rotated.scopes) == (api_key.id, "gotv relay", ["read", "upload"])
+    assert rotated.rotated_at is not None
+    assert service.authenticate_api_key(session, new_key).id == api_key.id
+    with pytest.raises(PermissionError):
+        service.authenticate_api_key(session, old_key)
+    assert [key.id for key in service.list_api_keys(session, "u1")] == [api_key.id]
+    with pytest.raises(LookupError):
+        service.rotate_api_key(session, "someone-else", api_key.id)
+
+    service.revoke_api_key(session, "u1", api_key.id)
+    assert service.list_api_keys(session, "u1") == []
+
+
+def test_keys_of_deactivated_users_are_refused(session, tmp_path):
+    service = UserService(Settings(data_dir=tmp_path))
+    _, key = service.create_api_key(session, "u1", "reader", ["read"])
+    session.get(User, "u1").is_active = False
+    session.commit()
+
+    with pytest.raises(PermissionError):
+        service.authenticate_api_key(session, key)
+    with pytest.raises(LookupError):
+        service.create_api_key(session, "u1", "another", ["read"])
+
+
+def test_requests_map_to_scopes():
+    assert required_scope("GET", "/api/demos") == "read"
+    assert required_scope("POST", "/api/demos/upload") == "upload"
+    assert required_scope("POST", "/api/demos/ingest/url") == "upload"
+    assert required_scope("POST", "/api/demos/ingest/sharecode") == "upload"
+    assert required_scope("DELETE", "/api/demos/d1") == "upload"
+    assert required_scope("POST", "/api/demos/d1/restore") == "upload"
+    assert required_scope("POST", "/api/demos/matches/m1/restore") == "upload"
+    assert required_scope("PUT", "/api/demos/d1/visibility") == "upload"
+    assert required_scope("POST", "/api/demos/live") == "upload"
+    assert required_scope("POST", "/api/demos/live/b1/stop") == "upload"
+    assert required_scope("POST", "/api/demos/d1/hltv") == "admin"
+    assert required_scope("PATCH", "/api/users/u1") == "read"
+    assert required_scope("POST", "/api/users/u1/password") == "read"
+    assert required_scope("POST", "/api/users/u1/api-keys") == "admin"
+    assert required_scope("POST", "/api/apikeys/k1/rotate") == "read"
+    assert required_scope("GET", "/api/admin/alert-rules") == "admin"
+    assert required_scope("GET", "/api/admin/exports/teams/t1.tar.gz") == "admin"
+    assert is_exempt("/health")
+    assert is_exempt("/public/v1/team")
+    assert not is_exempt("/api/demos")
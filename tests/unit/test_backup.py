@@ -0,0 +1,54 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+import pytest
+from sqlalchemy import create_engine
+from sqlalchemy.orm import sessionmaker
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.database import Base
+from stratagemforge.core.storage import LocalStorage
+from stratagemforge.domain.admin.backup import BackupService
+from stratagemforge.domain.demos.models import Demo, Match
+
+
+def make_session(path):
+    engine = create_engine(f"sqlite:///{path}", future=True)
+    Base.metadata.create_all(bind=engine)
+    return sessionmaker(bind=engine, future=True, expire_on_commit=False)()
+
+
+def test_backup_round_trip_and_artifact_check(tmp_path):
+    storage = LocalStorage(tmp_path / "artifacts")
+    (tmp_path / "summary.parquet").write_bytes(b"parquet")
+    storage.put(tmp_path / "summary.parquet", "d1.parquet")
+    storage.put(tmp_path / "summary.parquet", "d1/stats.parquet")
+
+    source = make_session(tmp_path / "source.db")
+    demo = Demo(
+        id="d1", original_filename="a.dem", stored_path="a.dem", checksum="c1", size_bytes=10, status="processed",
+        extra_metadata={"summary_key": "d1.parquet", "datasets": {"stats": "d1/stats.parquet"}},
+    )
+    demo.match = Match(team_a="Alpha", team_b="Bravo", played_at=datetime(2024, 7, 1, 20))
+    source.add(demo)
+    source.commit()
+    source.close()
+
+    service = BackupService(Settings(data_dir=tmp_path), storage=storage)
+    backup = service.backup(make_session(tmp_path / "source.db"), tmp_path / "backup.tar.gz")
+    assert backup.tables["demos"] == 1
+    assert backup.artifacts == 2
+
+    storage.delete("d1/stats.parquet")
+    result = service.restore(make_session(tmp_path / "target.db"), backup.path)
+
+    assert result.tables["matches"] == 1
+    assert result.missing_artifacts == ["d1/stats.parquet"]
+    restored = make_session(tmp_path / "target.db").get(Demo, "d1")
+    assert restored.match.played_at == datetime(2024, 7, 1, 20)
+    assert restored.extra_metadata["datasets"] == {"stats": "d1/stats.parquet"}
+
+    with pytest.raises(ValueError, match="not empty"):
+        service.restore(make_session(tmp_path / "target.db"), backup.path)
+    assert service.restore(make_session(tmp_path / "target.db"), backup.path, force=True).tables["demos"] == 1
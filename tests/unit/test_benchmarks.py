@@ -0,0 +1,73 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.competitions.models import Competition
+from stratagemforge.domain.demos.models import Demo, Match
+from stratagemforge.domain.stats.benchmarks import percentile_rank, quantile
+from stratagemforge.domain.stats.models import PlayerMatchStat
+from stratagemforge.domain.stats.service import StatsService
+
+
+@pytest.fixture
+def session(session):
+    league = Competition(name="League", format="BO3")
+    session.add(league)
+    session.flush()
+    demo = Demo(original_filename="a.dem", stored_path="a.dem", checksum="a", size_bytes=1, competition_id=league.id)
+    demo.match = Match(team_a="Alpha", team_b="Bravo", played_at=datetime(2024, 7, 1))
+    session.add(demo)
+    session.flush()
+    for steamid, adr, deaths, match_id in [("1", 60.0, 20, None), ("2", 78.0, 15, demo.match.id), ("3", 90.0, 10, demo.match.id)]:
+        session.add(
+            PlayerMatchStat(
+                source="demo", match_key=match_id or "scrim", player_key=steamid, steamid=steamid, match_id=match_id,
+                rounds=20, kills=15, deaths=deaths, adr=adr,
+            )
+        )
+    # An imported line for a match the demo already covers is ignored.
+    session.add(
+        PlayerMatchStat(
+            source="leetify", match_key="x", player_key="3", steamid="3", match_id=demo.match.id, rounds=20, adr=10.0
+        )
+    )
+    session.commit()
+    return session
+
+
+def test_percentile_rank_counts_ties_half_and_respects_direction():
+    assert percentile_rank(78.0, [60.0, 78.0, 90.0]) == 50.0
+    assert percentile_rank(95.0, [60.0, 78.0, 90.0]) == 100.0
+    assert percentile_rank(0.5, [0.75, 1.0], higher_is_better=False) == 100.0
+    assert percentile_rank(1.0, []) is None
+    assert quantile([60.0, 78.0, 90.0], 25) == 69.0
+
+
+def test_player_percentiles_rank_against_everyone_or_one_format(session, tmp_path):
+    service = StatsService(Settings(data_dir=tmp_path))
+
+    ranks = {metric.metric: metric for metric in service.percentiles(session, "2").metrics}
+
+    assert (ranks["adr"].value, ranks["adr"].percentile, ranks["adr"].players) == (78.0, 50.0, 3)
+    assert ranks["deaths_per_round"].percentile == 50.0
+    assert ranks["rating"].value is None
+    in_format = {metric.metric: metric for metric in service.percentiles(session, "2", competition_format="bo3").metrics}
+    assert (in_format["adr"].percentile, in_format["adr"].players) == (25.0, 2)
+    with pytest.raises(LookupError):
+        service.percentiles(session, "1", competition_format="bo3")
+
+
+def test_benchmarks_answer_where_a_value_ranks(session, tmp_path):
+    service = StatsService(Settings(data_dir=tmp_path))
+
+    benchmarks = service.benchmarks(session, metric="adr", value=78.0)
+
+    assert benchmarks.players == 3
+    assert [metric.metric for metric in benchmarks.metrics] == ["adr"]
+    assert benchmarks.metrics[0].quantiles["p50"] == 78.0
+    assert benchmarks.metrics[0].percentile == 50.0
+    with pytest.raises(ValueError):
+        service.benchmarks(session, metric="aim")
@@ -0,0 +1,80 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+from pathlib import Path
+
+import pandas as pd
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.broadcast import RECORDING_HEADER, BroadcastService
+from stratagemforge.domain.demos.processor import DemoProcessor
+from stratagemforge.domain.demos.service import DemoService
+
+
+class FakeBroadcast:
+    """Serves a sync document and fragments like a GOTV+ relay."""
+
+    def __init__(self) -> None:
+        self.sync_doc = {"fragment": 5, "signup_fragment": 1, "tick": 3000, "map": "de_mirage"}
+        self.fragments = {(1, "start"): b"start", (5, "full"): b"full", (6, "delta"): b"d6", (7, "delta"): b"d7"}
+
+    def sync(self, url: str):
+        return self.sync_doc
+
+    def fragment(self, url: str, number: int, kind: str):
+        return self.fragments.get((number, kind))
+
+
+@pytest.mark.asyncio
+async def test_live_broadcast_is_recorded_segmented_and_ingested(session, tmp_path, parsed_demo, make_parser):
+    settings = Settings(
+        data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db", broadcast_segment_fragments=2
+    )
+    settings.ensure_directories()
+    processor = DemoProcessor(settings.processed_data_path, parser=make_parser(parsed_demo))
+    broadcasts = BroadcastService(settings, processor=processor, client=FakeBroadcast())
+
+    broadcast = broadcasts.start(session, "http://relay.example.com/match/1", owner_id="coach")
+    assert broadcasts.poll(session, broadcast) == 3
+    assert Path(broadcast.recording_path).read_bytes() == RECORDING_HEADER + b"startfulld6d7"
+    assert (broadcast.status, broadcast.fragment, broadcast.tick) == ("live", 7, 3000)
+    assert (broadcast.rounds_played, broadcast.score_a, broadcast.score_b) == (2, 1, 1)
+
+    [segment] = broadcast.segments
+    assert (segment["number"], segment["up_to_tick"], segment["up_to_round"]) == (1, 1500, 2)
+    assert {"kills", "round_summary"} <= set(segment["datasets"])
+    kills = pd.read_parquet(broadcasts.segment_path(broadcast, 1, "kills"))
+    assert kills["tick"].tolist() == [500, 1500]
+    assert (broadcasts.live_dir / broadcast.id / "stats.parquet").exists()
+
+    assert broadcasts.poll(session, broadcast) == 0
+    assert broadcast.status == "live"
+    broadcast.updated_at = datetime.utcnow() - timedelta(seconds=settings.broadcast_idle_seconds + 1)
+    broadcasts.poll(session, broadcast)
+    assert broadcasts.ended(session) == [broadcast]
+
+    demos = DemoService(settings, processor=processor)
+    await broadcasts.finish(session, broadcast, demos)
+    assert broadcast.status == "finished"
+    demo = demos.get_demo(session, broadcast.demo_id)
+    assert (demo.owner_id, demo.extra_metadata["broadcast_id"]) == ("coach", broadcast.id)
+    assert not Path(broadcast.recording_path).exists()
+
+
+def test_broadcast_that_never_starts_fails_after_the_idle_timeout(session, tmp_path):
+    settings = Settings(data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db")
+    settings.ensure_directories()
+    client = FakeBroadcast()
+    client.fragments.clear()
+    broadcasts = BroadcastService(settings, processor=DemoProcessor(settings.processed_data_path), client=client)
+
+    broadcast = broadcasts.start(session, "http://relay.example.com/match/2")
+    assert broadcasts.poll(session, broadcast) == 0
+    assert (broadcast.status, broadcast.error) == ("connecting", "Broadcast has no start fragment yet")
+
+    broadcast.updated_at = datetime.utcnow() - timedelta(seconds=settings.broadcast_idle_seconds + 1)
+    broadcasts.poll(session, broadcast)
+    assert broadcast.status == "failed"
+    with pytest.raises(ValueError):
+        broadcasts.start(session, "ftp://relay.example.com/match/3")
@@ -0,0 +1,44 @@
+from __future__ import annotations
+
+import gzip
+import hashlib
+
+import pandas as pd
+import pyarrow.parquet as pq
+
+from stratagemforge import cli
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.identity import match_id_for
+
+# Smallest payload that passes the upload header check.
+DEMO_BYTES = b"PBDEMS2\x00demo data"
+
+
+def test_parse_command_goes_through_the_upload_parse_path(tmp_path, monkeypatch, capsys, parsed_demo, make_parser):
+    parsed_demo.header.pop("map_name")
+    settings = Settings(data_dir=tmp_path / "data", parquet_compression="zstd")
+    monkeypatch.setattr(cli, "get_settings", lambda: settings)
+    monkeypatch.setattr(
+        "stratagemforge.domain.demos.processor.load_default_parser", lambda: make_parser(parsed=parsed_demo)
+    )
+    demo = tmp_path / "match_de_inferno.dem.gz"
+    demo.write_bytes(gzip.compress(DEMO_BYTES))
+
+    assert cli.main(["parse", str(demo), "--output", str(tmp_path / "out"), "--csv"]) == 0
+
+    demo_id = match_id_for(hashlib.sha256(DEMO_BYTES).hexdigest())  # the demo's checksum, not the archive's
+    summary = pd.read_parquet(tmp_path / "out" / f"{demo_id}.parquet").iloc[0]
+    assert summary["map_name"] == "de_inferno"  # from the file name, as for uploads
+    assert summary["size_bytes"] == len(DEMO_BYTES)
+    kills = tmp_path / "out" / demo_id / "kills.parquet"
+    assert pq.ParquetFile(kills).metadata.row_group(0).column(0).compression == "ZSTD"
+    assert "kills.csv.gz" in capsys.readouterr().out
+
+
+def test_parse_command_rejects_files_that_are_not_demos(tmp_path, monkeypatch, capsys):
+    monkeypatch.setattr(cli, "get_settings", lambda: Settings(data_dir=tmp_path / "data"))
+    junk = tmp_path / "notes.dem.gz"
+    junk.write_bytes(gzip.compress(b"not a demo"))
+
+    assert cli.main(["parse", str(junk), "--output", str(tmp_path / "out")]) == 2
+    assert "not a CS2 or CS:GO demo" in capsys.readouterr().err
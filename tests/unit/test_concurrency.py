@@ -0,0 +1,57 @@
+from __future__ import annotations
+
+import asyncio
+
+import pytest
+
+from stratagemforge.domain.demos.concurrency import ParseLimiter, ParseQueueFullError
+
+
+async def _hold(limiter: ParseLimiter, release: asyncio.Event, estimated_bytes: int = 0) -> None:
+    async with limiter.slot(estimated_bytes):
+        await release.wait()
+
+
+@pytest.mark.asyncio
+async def test_limiter_bounds_running_parses_and_rejects_when_queue_full():
+    limiter = ParseLimiter(max_concurrent=1, max_queued=1)
+    release = asyncio.Event()
+
+    first = asyncio.create_task(_hold(limiter, release))
+    second = asyncio.create_task(_hold(limiter, release))
+    await asyncio.sleep(0)
+
+    assert limiter.snapshot()["running"] == 1
+    assert limiter.waiting == 1
+    with pytest.raises(ParseQueueFullError):
+        limiter.check_capacity()
+
+    release.set()
+    await asyncio.gather(first, second)
+    assert (limiter.running, limiter.waiting, limiter.reserved_bytes) == (0, 0, 0)
+
+
+@pytest.mark.asyncio
+async def test_limiter_waits_for_memory_budget():
+    limiter = ParseLimiter(max_concurrent=4, memory_budget_bytes=100)
+    release = asyncio.Event()
+
+    large = asyncio.create_task(_hold(limiter, release, 80))
+    await asyncio.sleep(0)
+    blocked = asyncio.create_task(_hold(limiter, release, 40))
+    await asyncio.sleep(0)
+
+    assert limiter.running == 1
+    assert limiter.waiting == 1
+
+    release.set()
+    await asyncio.gather(large, blocked)
+    assert limiter.running == 0
+
+
+@pytest.mark.asyncio
+async def test_oversized_parse_runs_alone():
+    limiter = ParseLimiter(max_concurrent=2, memory_budget_bytes=10)
+
+    async with limiter.slot(50):
+        assert limiter.reserved_bytes == 50
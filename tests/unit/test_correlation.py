@@ -0,0 +1,113 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.correlation import MatchCorrelator
+from stratagemforge.domain.demos.models import Demo, Match, MatchPlayer
+from stratagemforge.domain.demos.repository import DemoRepository
+from stratagemforge.domain.stats.models import PlayerMatchStat
+from stratagemforge.domain.stats.repository import StatsRepository
+
+PLAYED_AT = datetime(2024, 3, 1, 19, 0)
+STEAMIDS = [str(76561197960265728 + index) for index in range(10)]
+
+
+def add_match(session, demo_id, *, score=(16, 12), players=STEAMIDS, rounds=28, size=100, offset_minutes=0):
+    demo = Demo(
+        id=demo_id, original_filename=f"{demo_id}.dem", stored_path=f"{demo_id}.dem", checksum=demo_id,
+        size_bytes=size, status="processed",
+    )
+    match = Match(
+        id=f"m-{demo_id}", demo=demo, map_name="de_inferno", played_at=PLAYED_AT + timedelta(minutes=offset_minutes),
+        team_a="Alpha", team_b="Bravo", score_a=score[0], score_b=score[1], rounds=rounds,
+        player_count=len(players), players=[MatchPlayer(steamid=steamid) for steamid in players],
+    )
+    session.add_all([demo, match])
+    session.commit()
+    return match
+
+
+def test_sources_of_one_game_are_linked_and_richest_is_primary(session, tmp_path):
+    correlator = MatchCorrelator(Settings(data_dir=tmp_path))
+    gotv = add_match(session, "gotv", size=300)
+    # A POV recording of the same game, uploaded with the teams the other way round.
+    pov = add_match(session, "pov", score=(12, 16), size=200, offset_minutes=45)
+    unrelated = add_match(session, "other", score=(16, 14), offset_minutes=30)
+
+    group = correlator.correlate(session, pov)
+    session.commit()
+
+    assert {member.id for member in group} == {gotv.id, pov.id}
+    assert gotv.game_id == pov.game_id == gotv.id
+    assert (gotv.is_primary, pov.is_primary) == (True, False)
+    assert unrelated.game_id is None
+    assert [match.id for match in DemoRepository(session).matches()] == [unrelated.id, gotv.id]
+    assert len(DemoRepository(session).matches(primary_only=False)) == 3
+
+
+def test_stats_of_alternate_sources_are_left_out_of_aggregates(session, tmp_path):
+    correlator = MatchCorrelator(Settings(data_dir=tmp_path))
+    gotv = add_match(session, "gotv", size=300)
+    pov = add_match(session, "pov", size=200)
+    for match in (gotv, pov):
+        session.add(
+            PlayerMatchStat(source="demo", match_key=match.demo_id, player_key=STEAMIDS[0], match_id=match.id, kills=20)
+        )
+    correlator.correlate(session, pov)
+    session.commit()
+
+    assert len(StatsRepository(session).list()) == 2
+    assert [stat.match_id for stat in StatsRepository(session).list(primary_only=True)] == [gotv.id]
+
+
+def test_games_need_matching_players_and_deleting_the_primary_promotes_another(session, tmp_path):
+    correlator = MatchCorrelator(Settings(data_dir=tmp_path))
+    gotv = add_match(session, "gotv", size=300)
+    pov = add_match(session, "pov", size=200)
+    other_lobby = add_match(session, "lobby", players=STEAMIDS[:5] + ["1", "2", "3", "4", "5"])
+
+    assert correlator.correlate(session, other_lobby) == [other_lobby]
+    correlator.correlate(session, pov)
+    session.commit()
+
+    correlator.release_before_delete(session, gotv.demo)
+
+    assert pov.is_primary is True
+    assert pov.game_id is None
+
+
+def test_full_game_supersedes_its_partial_demo_and_takes_its_external_id(session, tmp_path):
+    correlator = MatchCorrelator(Settings(data_dir=tmp_path))
+    # The first half, uploaded while the game was still being played, from a bigger POV demo.
+    half = add_match(session, "half", score=(8, 4), rounds=12, size=500)
+    half.external_id, half.external_source = "faceit-1", "faceit"
+    half.demo.extra_metadata = {"external_match_id": "faceit-1", "external_source": "faceit"}
+    full = add_match(session, "full", score=(13, 9), rounds=22, size=300, offset_minutes=50)
+
+    group = correlator.correlate(session, full)
+    session.commit()
+
+    assert {member.id for member in group} == {half.id, full.id}
+    assert (half.superseded_by, full.superseded_by) == (full.id, None)
+    assert (full.is_primary, half.is_primary) == (True, False)
+    assert (full.external_id, half.external_id) == ("faceit-1", None)
+    assert full.demo.extra_metadata["external_match_id"] == "faceit-1"
+    assert DemoRepository(session).get_match("faceit-1").id == full.id
+
+    correlator.release_before_delete(session, full.demo)
+    assert (half.superseded_by, half.is_primary) == (None, True)
+
+
+def test_decided_partial_needs_the_same_round_starts(session, tmp_path):
+    correlator = MatchCorrelator(Settings(data_dir=tmp_path))
+    ticks = [{"round": number, "start_tick": number * 1000} for number in range(1, 31)]
+    full = add_match(session, "full", score=(16, 14), rounds=30)
+    full.round_ticks = ticks
+    cut = add_match(session, "cut", score=(14, 13), rounds=27)
+    cut.round_ticks = ticks[:27]
+    rematch = add_match(session, "rematch", score=(16, 5), rounds=21, offset_minutes=90)
+
+    correlator.correlate(session, cut)
+    assert cut.superseded_by == full.id
+    assert correlator.correlate(session, rematch) == [rematch]
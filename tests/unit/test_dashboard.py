@@ -0,0 +1,84 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.dashboard.service import DashboardService
+from stratagemforge.domain.demos.models import Demo, Match
+from stratagemforge.domain.stats.models import PlayerMatchStat
+from stratagemforge.domain.users.models import User
+
+
+def _demo(name: str, status: str = "processed", **match) -> Demo:
+    demo = Demo(original_filename=name, stored_path=name, checksum=name, size_bytes=1, status=status)
+    if match:
+        demo.match = Match(team_a="Alpha", team_b="Bravo", **match)
+    return demo
+
+
+@pytest.fixture
+def session(session):
+    now = datetime.utcnow()
+    first = _demo("a.dem", played_at=now - timedelta(days=15), winner="Alpha", map_name="de_mirage")
+    second = _demo("b.dem", played_at=now - timedelta(days=1), winner="Bravo", map_name="de_inferno")
+    deleted = _demo("c.dem", played_at=now, winner="Alpha")
+    deleted.deleted_at = now
+    session.add_all([first, second, deleted, _demo("d.dem", status="uploaded")])
+    session.flush()
+    for source, steamid, kills, adr in [("demo", "1", 25, 95.0), ("leetify", "1", 99, 150.0), ("demo", "2", 10, 60.0)]:
+        session.add(
+            PlayerMatchStat(
+                source=source, match_key=second.id, player_key=steamid, steamid=steamid, match_id=second.match.id,
+                played_at=second.match.played_at, kills=kills, deaths=10, adr=adr,
+            )
+        )
+    session.commit()
+    return session
+
+
+def test_dashboard_summarises_matches_jobs_and_performers(session, tmp_path):
+    service = DashboardService(Settings(data_dir=tmp_path))
+
+    dashboard = service.dashboard(session, team="alpha", weeks=4)
+
+    assert [match.map_name for match in dashboard.recent_matches] == ["de_inferno", "de_mirage"]
+    assert dashboard.processing_count == 1
+    assert dashboard.processing[0].original_filename == "d.dem"
+    assert [(point.matches, point.wins) for point in dashboard.winrate_trend] == [(1, 1), (1, 0)]
+    top = dashboard.top_performers
+    assert [player.steamid for player in top] == ["1", "2"]
+    # The imported row for the same match is ignored in favour of the demo-derived one.
+    assert (top[0].kills, top[0].adr) == (25, 95.0)
+
+
+def test_dashboard_caches_aggregates_until_invalidated(session, tmp_path):
+    service = DashboardService(Settings(data_dir=tmp_path, dashboard_cache_seconds=300))
+    assert service.dashboard(session).team in {"Alpha", "Bravo"}
+
+    session.add(_demo("e.dem", played_at=datetime.utcnow(), winner="Alpha", map_name="de_nuke"))
+    session.commit()
+    assert len(service.dashboard(session).recent_matches) == 2
+
+    service.invalidate()
+    assert service.dashboard(session).recent_matches[0].map_name == "de_nuke"
+
+
+def test_dashboard_buckets_weeks_in_the_users_timezone(session, tmp_path):
+    today = datetime.utcnow().replace(hour=23, minute=30, second=0, microsecond=0)
+    sunday_night = today - timedelta(days=(today.weekday() + 1) % 7 or 7)
+    session.add(_demo("late.dem", played_at=sunday_night, winner="Charlie", map_name="de_anubis"))
+    user = User(email="coach@example.com", display_name="Coach", timezone="Europe/Berlin")
+    session.add(user)
+    session.commit()
+    service = DashboardService(Settings(data_dir=tmp_path))
+
+    utc_week = service.dashboard(session, team="Bravo", weeks=2).winrate_trend
+    local = service.dashboard(session, team="Bravo", weeks=2, user_id=user.id)
+
+    assert local.timezone == "Europe/Berlin"
+    late_utc = sunday_night.isocalendar()
+    late_local = (sunday_night + timedelta(days=1)).isocalendar()
+    assert f"{late_utc[0]}-W{late_utc[1]:02d}" in {point.week for point in utc_week}
+    assert f"{late_local[0]}-W{late_local[1]:02d}" in {point.week for point in local.winrate_trend}
@@ -1,11 +1,42 @@
 from __future__ import annotations
 
+import io
+import zipfile
 from datetime import datetime
 from pathlib import Path
 
 import pandas as pd
+import pyarrow as pa
+import pyarrow.parquet as pq
+import pytest
 
+from stratagemforge.core.config import Settings
+from stratagemforge.core.parquet import (
+    ParquetOptions,
+    arrow_stream,
+    read_row_groups,
+    read_schema_version,
+    row_group_ranges,
+    row_groups_between,
+    write_parquet,
+)
+from stratagemforge.domain.demos.bounds import MAP_BOUNDS, clear_position_outliers
+from stratagemforge.domain.demos.csv_export import csv_chunks, gzip_chunks, zip_stream
+from stratagemforge.domain.demos.extractors import DATASET_BUILDERS, DATASET_SCHEMA_VERSIONS
+from stratagemforge.domain.demos.parser import (
+    CS2_MAGIC,
+    CSGO_MAGIC,
+    MOVEMENT_FIELDS,
+    Demoparser2Parser,
+    ProtocolRoutingParser,
+    assign_rounds,
+    detect_demo_format,
+    game_clock,
+    inventory_code,
+    netmsg_types,
+)
 from stratagemforge.domain.demos.processor import DemoProcessingInput, DemoProcessor
+from stratagemforge.domain.demos.recovery import RECOVERED_HEADER, SIGNON_TICK, recover_truncated
 
 
 def test_processor_creates_parquet(tmp_path):
@@ -30,5 +61,369 @@ def test_processor_creates_parquet(tmp_path):
     df = pd.read_parquet(result.parquet_path)
     assert df.loc[0, "demo_id"] == "demo-1"
     assert df.loc[0, "checksum"] == "abc123"
-    assert df.loc[0, "raw_path"] == str(raw_path)
-    assert "processed_at" in df.columns
+    assert df.loc[0, "schema_version"] == 2
+    # Processing wall-clock and local paths stay in the database, not the artefact.
+    assert not {"processed_at", "uploaded_at", "raw_path"} & set(df.columns)
+    assert result.summary["raw_path"] == str(raw_path)
+
+
+def make_payload(tmp_path) -> DemoProcessingInput:
+    raw_path = tmp_path / "sample.dem"
+    raw_path.write_bytes(b"demo data")
+    return DemoProcessingInput(
+        demo_id="demo-1",
+        original_filename="sample.dem",
+        checksum="abc123",
+        size_bytes=raw_path.stat().st_size,
+        uploaded_at=datetime.utcnow(),
+        raw_path=raw_path,
+    )
+
+
+def test_processor_writes_round_summary_when_parsed(tmp_path, parsed_demo, make_parser):
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(parsed=parsed_demo))
+
+    result = processor.process(make_payload(tmp_path))
+
+    assert result.summary["parse_status"] == "parsed"
+    assert result.summary["map_name"] == "de_mirage"
+    assert result.summary["duration_seconds"] > 0
+    rounds = pd.read_parquet(result.datasets["round_summary"])
+    assert set(rounds["side"]) == {"CT", "T"}
+    assert "utility_unused" in rounds.columns
+
+
+def test_every_parquet_output_records_its_schema_version(tmp_path, parsed_demo, make_parser):
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(parsed=parsed_demo))
+
+    result = processor.process(make_payload(tmp_path))
+
+    assert set(DATASET_SCHEMA_VERSIONS) == set(DATASET_BUILDERS)
+    assert read_schema_version(result.parquet_path) == ("summary", 2)
+    for name, path in result.datasets.items():
+        assert read_schema_version(path) == (name, DATASET_SCHEMA_VERSIONS[name])
+    assert result.schema_versions == {"summary": 2, **{name: DATASET_SCHEMA_VERSIONS[name] for name in result.datasets}}
+    assert result.summary["schema_versions"] == result.schema_versions
+    assert "schema_versions" not in pd.read_parquet(result.parquet_path).columns
+
+
+def test_arrow_stream_reads_back_with_the_selected_columns_and_metadata(tmp_path):
+    path = tmp_path / "kills.parquet"
+    write_parquet(pd.DataFrame({"tick": range(5), "weapon": list("abcde"), "round": 1}), path, "kills", 3)
+
+    stream = b"".join(arrow_stream(path, columns=["weapon", "tick"], batch_rows=2))
+    table = pa.ipc.open_stream(stream).read_all()
+
+    assert table.column_names == ["weapon", "tick"]
+    assert table.column("tick").to_pylist() == [0, 1, 2, 3, 4]
+    assert table.schema.metadata[b"stratagemforge.dataset"] == b"kills"
+    with pytest.raises(ValueError, match="Unknown columns: team"):
+        arrow_stream(path, columns=["team"])
+
+
+def test_row_group_key_gives_each_round_its_own_row_groups(tmp_path):
+    path = tmp_path / "player_ticks.parquet"
+    frame = pd.DataFrame({"tick": [300, 100, 110, 310, 200], "round": [3, 1, 1, 3, 2], "x": [1.0, 2.0, 3.0, 4.0, 5.0]})
+    write_parquet(frame, path, "player_ticks", 1, row_group_key="round")
+
+    ranges = row_group_ranges(path, "round")
+
+    assert [(entry["rows"], entry["min"], entry["max"]) for entry in ranges] == [(2, 1, 1), (1, 2, 2), (2, 3, 3)]
+    assert row_groups_between(ranges, 3, 3) == [2]
+    assert read_row_groups(path, [2])["tick"].tolist() == [300, 310]  # order within a round is kept
+    assert read_schema_version(path) == ("player_ticks", 1)
+
+
+def test_parquet_options_set_codec_and_row_group_size(tmp_path):
+    path = tmp_path / "player_ticks.parquet"
+    options = ParquetOptions.from_settings(
+        Settings(data_dir=tmp_path, parquet_compression="ZSTD", parquet_row_group_rows=2)
+    )
+    frame = pd.DataFrame({"tick": range(5), "round": [1, 1, 1, 2, 2]})
+
+    write_parquet(frame, path, "player_ticks", 1, row_group_key="round", options=options)
+
+    metadata = pq.ParquetFile(path).metadata
+    assert metadata.row_group(0).column(0).compression == "ZSTD"
+    assert [metadata.row_group(index).num_rows for index in range(metadata.num_row_groups)] == [2, 1, 2]
+    with pytest.raises(ValueError):
+        Settings(data_dir=tmp_path, parquet_compression="lzo")
+    assert Settings(data_dir=tmp_path, parquet_compression="uncompressed").parquet_compression == "none"
+
+
+def test_csv_export_matches_the_parquet_datasets(tmp_path, parsed_demo, make_parser):
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(parsed=parsed_demo), csv_export=True)
+
+    result = processor.process(make_payload(tmp_path))
+
+    assert set(result.csv_datasets) == set(result.datasets)
+    for name, path in result.csv_datasets.items():
+        assert path.name == f"{name}.csv.gz"
+        assert len(pd.read_csv(path)) == len(pd.read_parquet(result.datasets[name]))
+
+    kills = result.datasets["kills"]
+    archive = b"".join(zip_stream([("kills.csv.gz", gzip_chunks(csv_chunks(kills, batch_rows=1)))]))
+    with zipfile.ZipFile(io.BytesIO(archive)) as exported:
+        exported_kills = pd.read_csv(io.BytesIO(exported.read("kills.csv.gz")), compression="gzip")
+    assert len(exported_kills) == len(pd.read_parquet(kills))
+
+
+def test_processor_skips_flagged_off_datasets(tmp_path, parsed_demo, make_parser):
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(parsed=parsed_demo))
+    payload = make_payload(tmp_path)
+    payload.skip_datasets = frozenset({"clutches"})
+
+    result = processor.process(payload)
+
+    assert "clutches" not in result.datasets
+    assert "round_summary" in result.datasets
+
+
+def test_processor_adds_radar_columns_to_position_datasets_when_asked(tmp_path, parsed_demo, make_parser):
+    parsed_demo.player_ticks = pd.DataFrame(
+        [{"tick": 160, "round": 1, "steamid": "1", "side": "CT", "is_alive": True, "x": -670.0, "y": -847.0, "z": 0.0}]
+    )
+    plain = DemoProcessor(tmp_path / "plain", parser=make_parser(parsed=parsed_demo)).process(make_payload(tmp_path))
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(parsed=parsed_demo), radar_columns=True)
+
+    result = processor.process(make_payload(tmp_path))
+
+    assert "radar_x" not in pd.read_parquet(plain.datasets["player_ticks"]).columns
+    ticks = pd.read_parquet(result.datasets["player_ticks"])
+    assert ticks.loc[0, ["radar_x", "radar_y", "radar_level"]].tolist() == [0.5, 0.5, "default"]
+    kills = pd.read_parquet(result.datasets["kills"])
+    assert {"attacker_radar_x", "victim_radar_y", "victim_radar_level"} <= set(kills.columns)
+    assert read_schema_version(result.datasets["player_ticks"]) == ("player_ticks", DATASET_SCHEMA_VERSIONS["player_ticks"])
+
+
+def test_processor_tolerates_parser_failures(tmp_path, make_parser):
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(error=RuntimeError("bad header")))
+
+    result = processor.process(make_payload(tmp_path))
+
+    assert result.parquet_path.exists()
+    assert result.summary["parse_status"] == "failed"
+    assert result.datasets == {}
+
+
+def varint(value: int) -> bytes:
+    out = bytearray()
+    while True:
+        byte, value = value & 0x7F, value >> 7
+        out.append(byte | (0x80 if value else 0))
+        if not value:
+            return bytes(out)
+
+
+def frame(command: int, tick: int, payload: bytes) -> bytes:
+    return varint(command) + varint(tick) + varint(len(payload)) + payload
+
+
+class TruncationSensitiveParser:
+    """Fails like a native parser on a cut-off demo and parses any demo that ends with a stop frame."""
+
+    name = "stub"
+
+    def __init__(self, parsed) -> None:
+        self.parsed = parsed
+
+    def parse(self, path: Path):
+        if not path.read_bytes().endswith(frame(0, 128, b"")):
+            raise RuntimeError("Unexpected EOF while reading demo frame")
+        return self.parsed
+
+
+def test_truncated_demo_is_recovered_up_to_the_last_complete_frame(tmp_path, parsed_demo):
+    payload = make_payload(tmp_path)
+    complete = CS2_MAGIC + b"\x10\x00\x00\x00\x20\x00\x00\x00" + frame(1, SIGNON_TICK, b"signon")
+    complete += frame(7, 64, b"packet") + frame(7 | 0x40, 128, b"compressed")
+    payload.raw_path.write_bytes(complete + frame(7, 192, b"cut off mid-frame")[:-5])
+
+    result = DemoProcessor(tmp_path / "processed", parser=TruncationSensitiveParser(parsed_demo)).process(payload)
+
+    assert result.summary["parse_status"] == "parsed"
+    assert (result.summary["partial"], result.summary["last_good_tick"]) == (True, 128)
+    assert "Unexpected EOF" in result.summary["truncation_error"]
+    assert "round_summary" in result.datasets
+    assert not list((tmp_path / "processed").glob("*.recovered.dem"))
+
+    recovered = recover_truncated(payload.raw_path, tmp_path / "copy.dem")
+    assert (recovered.frames, recovered.dropped_bytes) == (3, len(frame(7, 192, b"cut off mid-frame")) - 5)
+    assert (tmp_path / "copy.dem").read_bytes() == RECOVERED_HEADER + complete[16:] + frame(0, 128, b"")
+    assert recover_truncated(tmp_path / "copy.dem", tmp_path / "again.dem") is None  # ends cleanly now
+
+
+def test_positions_outside_the_map_are_cleared_and_counted(tmp_path, parsed_demo, make_parser):
+    parsed_demo.grenade_events = pd.DataFrame(
+        [
+            {"tick": 210, "round": 1, "grenade": "smoke", "event": "detonate", "x": -300.0, "y": -1200.0, "z": -160.0},
+            {"tick": 220, "round": 1, "grenade": "smoke", "event": "detonate", "x": 9000.0, "y": -1200.0, "z": -160.0},
+            {"tick": 230, "round": 1, "grenade": "flash", "event": "detonate", "x": -300.0, "y": 4e9, "z": None},
+            {"tick": 240, "round": 1, "grenade": "flash", "event": "detonate", "x": None, "y": None, "z": None},
+        ]
+    )
+    assert MAP_BOUNDS["de_mirage"].min_x < -300 < MAP_BOUNDS["de_mirage"].max_x
+
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(parsed=parsed_demo))
+    result = processor.process(make_payload(tmp_path))
+
+    assert result.summary["data_quality"]["position_outliers"] == 2
+    assert result.summary["data_quality"]["position_outliers_by_table"] == {"grenade_events": 2}
+    assert "data_quality" not in pd.read_parquet(result.parquet_path).columns
+    events = parsed_demo.grenade_events
+    assert len(events) == 4  # the events stay, only their positions go
+    assert events["x"].notna().tolist() == [True, False, False, False]
+
+    parsed_demo.header["map_name"] = "de_unknown"
+    parsed_demo.grenade_events.loc[3, ["x", "y"]] = [9000.0, 9000.0]
+    assert clear_position_outliers(parsed_demo) == {}  # without known bounds only the world limit applies
+
+
+def test_processor_falls_back_to_the_uploaded_map_name(tmp_path, parsed_demo, make_parser):
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(parsed=parsed_demo))
+    payload = make_payload(tmp_path)
+    payload.map_name = "de_mirage"
+
+    parsed_demo.header.pop("map_name")
+    parsed_demo.grenade_events = pd.DataFrame(
+        [{"tick": 220, "round": 1, "grenade": "smoke", "event": "detonate", "x": 9000.0, "y": -1200.0, "z": -160.0}]
+    )
+    result = processor.process(payload)
+
+    assert result.summary["map_name"] == "de_mirage"
+    assert result.summary["map_name_source"] == "upload"
+    assert result.match["map_name"] == "de_mirage"
+    assert result.summary["data_quality"]["position_outliers"] == 1  # checked against de_mirage's bounds
+    assert "map_name_source" not in pd.read_parquet(result.parquet_path).columns
+
+
+def test_processor_reports_match_info(tmp_path, parsed_demo, make_parser):
+    processor = DemoProcessor(tmp_path / "processed", parser=make_parser(parsed=parsed_demo))
+
+    match = processor.process(make_payload(tmp_path)).match
+
+    assert match["team_a"] == "Alpha"
+    assert (match["score_a"], match["score_b"]) == (1, 1)
+    assert match["winner"] is None
+    assert {player["steamid"] for player in match["players"]} == {"1", "2", "6", "7"}
+
+
+def test_routing_parser_picks_backend_from_demo_header(tmp_path, parsed_demo, make_parser):
+    payload = make_payload(tmp_path)
+    assert detect_demo_format(payload.raw_path) is None
+    payload.raw_path.write_bytes(CSGO_MAGIC + b"legacy demo")
+    assert detect_demo_format(payload.raw_path) == "csgo"
+
+    routing = ProtocolRoutingParser({"cs2": make_parser(error=RuntimeError("wrong backend")), "csgo": make_parser(parsed=parsed_demo)})
+    result = DemoProcessor(tmp_path / "processed", parser=routing).process(payload)
+
+    assert result.summary["parse_status"] == "parsed"
+    assert result.summary["demo_format"] == "csgo"
+    assert result.summary["parser"] == "stub"
+    assert "round_summary" in result.datasets
+
+    failed = DemoProcessor(tmp_path / "processed", parser=ProtocolRoutingParser({})).process(payload)
+    assert failed.summary["parse_status"] == "failed"
+    assert "csgo" in failed.summary["parse_error"]
+
+
+class FakeNative:
+    """Four ticks of a player running, jumping and getting flashed, with or without the optional props."""
+
+    def __init__(self, movement_props: bool, warmup_end: int | None = None) -> None:
+        self.movement_props = movement_props
+        self.warmup_end = warmup_end
+
+    def parse_header(self):
+        return {"map_name": "de_mirage"}
+
+    def parse_ticks(self, fields, ticks=None):
+        if not self.movement_props and set(MOVEMENT_FIELDS) & set(fields):
+            raise KeyError("unknown prop velocity_X")
+        rows = []
+        for tick, (x, z) in zip(ticks, [(0.0, 0.0), (4.0, 0.0), (8.0, 2.0), (12.0, 2.0)]):
+            rows.append({"tick": tick, "steamid": "7", "X": x, "Y": 0.0, "Z": z, "pitch": 0.0, "yaw": 0.0})
+            if self.movement_props:
+                rows[-1].update({"velocity_X": 250.0, "velocity_Y": 0.0, "velocity_Z": 0.0, "ducking": True})
+                rows[-1].update({"active_weapon_ammo": 30 - tick + 100, "total_ammo_left": 90, "has_defuser": False})
+                rows[-1]["inventory"] = ["Knife", "AK-47", "Glock-18", "C4 Explosive", "Flashbang", "Flashbang"]
+        return pd.DataFrame(rows)
+
+    def parse_event(self, name, player=None):
+        if name == "player_blind":
+            return pd.DataFrame([{"tick": 101, "user_steamid": "7", "blind_duration": 1 / 32}])
+        if name == "round_announce_match_start" and self.warmup_end is not None:
+            return pd.DataFrame([{"tick": self.warmup_end}])
+        return pd.DataFrame()
+
+
+def test_aim_samples_carry_velocity_and_movement_state():
+    derived, _ = Demoparser2Parser()._parse_aim_native(FakeNative(movement_props=False), "7", 100, 103)
+
+    assert derived["vel_x"].tolist()[1:] == [256.0, 256.0, 256.0]  # 4 units per tick at 64 ticks/s
+    assert derived["speed"].iloc[1] == 256.0
+    assert derived["is_airborne"].tolist()[1:] == [False, True, False]
+    assert derived["is_blinded"].tolist() == [False, True, True, False]
+
+    native, _ = Demoparser2Parser()._parse_aim_native(FakeNative(movement_props=True), "7", 100, 103)
+    assert native["vel_x"].tolist() == [250.0] * 4
+    assert native["is_ducking"].all()
+    assert native["ammo_clip"].tolist() == [30, 29, 28, 27]
+    assert native[["ammo_reserve", "has_bomb", "has_defuser"]].iloc[0].tolist() == [90, True, False]
+    assert native["inventory"].iloc[0] == "ak47,glock18|flash2"
+    assert derived["inventory"].isna().all()
+
+
+def test_aim_samples_skip_warmup_unless_asked():
+    live, _ = Demoparser2Parser()._parse_aim_native(FakeNative(movement_props=True, warmup_end=102), "7", 100, 103)
+    assert live["tick"].tolist() == [102, 103]
+    assert not live["is_warmup"].any()
+
+    parser = Demoparser2Parser(include_warmup=True)
+    everything, _ = parser._parse_aim_native(FakeNative(movement_props=True, warmup_end=102), "7", 100, 103)
+    assert everything["is_warmup"].tolist() == [True, True, False, False]
+
+
+def test_game_clock_counts_down_from_the_freeze_end(parsed_demo):
+    frame = pd.DataFrame({"tick": [50, 164, 1050, 1164, 2500]})
+
+    clock = game_clock(frame, parsed_demo.rounds, tick_rate=64, warmup_end_tick=60)
+
+    assert clock["is_warmup"].tolist() == [True, False, False, False, False]
+    assert clock["is_freeze_time"].tolist() == [False, False, True, False, False]
+    assert clock["round_time_remaining"].tolist()[1:4] == [114.0, 115.0, 114.0]
+    assert clock["round_time_remaining"].iloc[[0, 4]].isna().all()  # warmup and after the last round
+
+
+def test_rows_before_the_first_round_start_are_warmup_and_dropped(parsed_demo):
+    rounds = parsed_demo.rounds.assign(start_tick=[60, 1000])
+
+    assigned = assign_rounds(pd.DataFrame({"tick": [50, 60, 1500]}), rounds)
+
+    assert assigned[["tick", "round"]].values.tolist() == [[60, 1], [1500, 2]]
+
+
+def test_inventory_code_lists_weapons_then_grenade_counts():
+    items = ["weapon_knife_t", "M4A1-S", "USP-S", "Smoke Grenade", "Molotov", "Molotov", "Defuse Kit"]
+    assert inventory_code(items) == "m4a1s,usps|smoke,molotov2"
+    assert inventory_code([]) == "|"
+    assert inventory_code(None) is None
+
+
+def test_netmsg_dump_decodes_selected_types_and_reports_missing_ones(tmp_path):
+    class DumpingParser(Demoparser2Parser):
+        @staticmethod
+        def _open(path):
+            native = FakeNative(movement_props=False)
+            native.list_game_events = lambda: {"player_death", "round_end"}
+            return native
+
+    assert netmsg_types("types=ServerInfo, GameEventList") == ["ServerInfo", "GameEventList"]
+    with pytest.raises(ValueError, match="ServerInfoo"):
+        netmsg_types("ServerInfoo")
+
+    dump = DumpingParser().dump_netmsgs(tmp_path / "sample.dem", ["ServerInfo", "GameEventList", "SetConVar"])
+
+    assert dump["ServerInfo"] == {"map_name": "de_mirage"}
+    assert sorted(dump["GameEventList"]) == ["player_death", "round_end"]
+    assert dump["SetConVar"] == {"error": "demoparser2 does not expose SetConVar"}
@@ -1,17 +1,36 @@
 from __future__ import annotations
 
+import bz2
+import gzip
+import hashlib
 import io
+import zipfile
+from datetime import datetime, timedelta
 from pathlib import Path
 
+import pandas as pd
 import pytest
-from sqlalchemy import create_engine
+from sqlalchemy import create_engine, update
 from sqlalchemy.orm import sessionmaker
 from starlette.datastructures import UploadFile
 
 from stratagemforge.core.config import Settings
 from stratagemforge.core.database import Base
+from stratagemforge.core.ids import new_ulid, next_cursor
+from stratagemforge.domain.demos.archives import InvalidDemoError, UploadTooLargeError
+from stratagemforge.domain.demos.downloader import DemoDownloader, DownloadedDemo
+from stratagemforge.domain.demos.hltv import HltvMapResult, HltvMatch
+from stratagemforge.domain.demos.identity import match_id_for
+from stratagemforge.domain.demos.models import Match, ParseJob
+from stratagemforge.domain.demos.parser import ReplayFrames
 from stratagemforge.domain.demos.processor import DemoProcessor
+from stratagemforge.domain.demos.retry import DemoProcessingError
 from stratagemforge.domain.demos.service import DemoService
+from stratagemforge.domain.stats.models import PlayerMatchStat
+from stratagemforge.domain.teams.models import Team
+
+# Smallest payload that passes the upload header check.
+DEMO_BYTES = b"PBDEMS2\x00demo data"
 
 
 @pytest.fixture
@@ -35,7 +54,7 @@ def service_with_session(tmp_path):
 @pytest.mark.asyncio
 async def test_upload_creates_demo(service_with_session):
     service, session, settings = service_with_session
-    upload = UploadFile(filename="match.dem", file=io.BytesIO(b"demo data"))
+    upload = UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES))
 
     demo, created = await service.upload_demo(upload, session)
 
@@ -43,18 +62,595 @@ async def test_upload_creates_demo(service_with_session):
     assert demo.processed_path is not None
     assert Path(demo.processed_path).exists()
     assert demo.extra_metadata["checksum"] == demo.checksum
+    assert demo.extra_metadata["storage_backend"] == "local"
+    assert demo.extra_metadata["summary_key"] == f"{demo.id}.parquet"
 
 
 @pytest.mark.asyncio
 async def test_duplicate_upload_returns_existing(service_with_session):
     service, session, settings = service_with_session
 
-    first_upload = UploadFile(filename="match.dem", file=io.BytesIO(b"demo data"))
+    first_upload = UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES))
     first_demo, created_first = await service.upload_demo(first_upload, session)
     assert created_first is True
 
-    duplicate_upload = UploadFile(filename="match.dem", file=io.BytesIO(b"demo data"))
+    duplicate_upload = UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES))
     second_demo, created_second = await service.upload_demo(duplicate_upload, session)
 
     assert created_second is False
     assert second_demo.id == first_demo.id
+
+
+
+@pytest.mark.asyncio
+async def test_force_reprocesses_existing_demo(service_with_session):
+    service, session, settings = service_with_session
+
+    first, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+    first_processed_at = first.processed_at
+
+    again, created = await service.upload_demo(
+        UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session, force=True
+    )
+
+    assert created is True
+    assert again.id == first.id
+    assert again.processed_at >= first_processed_at
+
+
+@pytest.mark.asyncio
+async def test_missing_artifacts_trigger_reprocessing(service_with_session):
+    service, session, settings = service_with_session
+
+    first, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+    Path(first.processed_path).unlink()
+
+    again, created = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    assert created is True
+    assert again.id == first.id
+    assert Path(again.processed_path).exists()
+
+def zip_bytes(entries: dict[str, bytes]) -> bytes:
+    buffer = io.BytesIO()
+    with zipfile.ZipFile(buffer, "w") as archive:
+        for name, content in entries.items():
+            archive.writestr(name, content)
+    return buffer.getvalue()
+
+
+@pytest.mark.asyncio
+@pytest.mark.parametrize(
+    ("filename", "payload", "compression"),
+    [
+        ("match.dem.gz", gzip.compress(DEMO_BYTES), "gzip"),
+        ("match.dem.bz2", bz2.compress(DEMO_BYTES), "bz2"),
+        ("match.zip", zip_bytes({"match.dem": DEMO_BYTES}), "zip"),
+    ],
+)
+async def test_compressed_upload_is_decompressed(service_with_session, filename, payload, compression):
+    service, session, settings = service_with_session
+    upload = UploadFile(filename=filename, file=io.BytesIO(payload))
+
+    demo, created = await service.upload_demo(upload, session)
+
+    assert created is True
+    assert Path(demo.stored_path).read_bytes() == DEMO_BYTES
+    assert demo.size_bytes == len(DEMO_BYTES)
+    assert demo.extra_metadata["compression"] == compression
+
+
+@pytest.mark.asyncio
+async def test_compressed_upload_matches_plain_duplicate(service_with_session):
+    service, session, settings = service_with_session
+
+    plain, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+    compressed = UploadFile(filename="match.dem.gz", file=io.BytesIO(gzip.compress(DEMO_BYTES)))
+    duplicate, created = await service.upload_demo(compressed, session)
+
+    assert created is False
+    assert duplicate.id == plain.id
+
+
+@pytest.mark.asyncio
+@pytest.mark.parametrize(
+    ("filename", "payload"),
+    [
+        ("renamed.dem", b"MZ\x90\x00 not a demo"),
+        ("renamed.dem.gz", gzip.compress(b"not a demo either")),
+    ],
+)
+async def test_upload_rejects_files_without_demo_header(service_with_session, filename, payload):
+    service, session, settings = service_with_session
+
+    with pytest.raises(InvalidDemoError):
+        await service.upload_demo(UploadFile(filename=filename, file=io.BytesIO(payload)), session)
+
+    assert list(settings.raw_data_path.iterdir()) == []
+
+
+@pytest.mark.asyncio
+async def test_upload_over_size_limit_is_rejected(service_with_session):
+    service, session, settings = service_with_session
+    settings.max_upload_size = len(DEMO_BYTES)
+
+    with pytest.raises(UploadTooLargeError):
+        await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES + b"x" * 32)), session)
+
+    assert list(settings.raw_data_path.iterdir()) == []
+
+
+@pytest.mark.asyncio
+@pytest.mark.parametrize(
+    "entries",
+    [
+        {"first.dem": DEMO_BYTES, "second.dem": b"PBDEMS2\x00more demo data"},
+        {"readme.txt": b"not a demo"},
+    ],
+)
+async def test_zip_upload_requires_single_demo_entry(service_with_session, entries):
+    service, session, settings = service_with_session
+    upload = UploadFile(filename="bundle.zip", file=io.BytesIO(zip_bytes(entries)))
+
+    with pytest.raises(ValueError):
+        await service.upload_demo(upload, session)
+
+    assert list(settings.raw_data_path.iterdir()) == []
+
+
+@pytest.mark.asyncio
+async def test_batch_upload_queues_each_demo_as_its_own_job(service_with_session):
+    service, session, settings = service_with_session
+    await service.upload_demo(UploadFile(filename="known.dem", file=io.BytesIO(DEMO_BYTES)), session)
+    uploads = [
+        UploadFile(filename="known.dem", file=io.BytesIO(DEMO_BYTES)),
+        UploadFile(filename="new.dem.gz", file=io.BytesIO(gzip.compress(b"PBDEMS2\x00another demo"))),
+        UploadFile(filename="notes.txt", file=io.BytesIO(b"not a demo")),
+    ]
+
+    batch = await service.upload_batch(uploads, session)
+
+    assert (batch.status, batch.counts["queued"], batch.counts["failed"]) == ("queued", 2, 1)
+    assert await service.process_batches(session) == 2
+    batch = service.get_batch(session, batch.id)
+    assert [item.status for item in batch.items] == ["duplicate", "processed", "failed"]
+    assert batch.status == "completed_with_errors" and batch.finished_at is not None
+    assert service.get_demo(session, batch.items[1].demo_id).extra_metadata["batch_id"] == batch.id
+    assert await service.process_batches(session) == 0
+
+
+@pytest.mark.asyncio
+async def test_zip_archive_becomes_a_batch(service_with_session):
+    service, session, settings = service_with_session
+    entries = {"maps/first.dem": DEMO_BYTES, "second.dem": b"PBDEMS2\x00more demo data", "readme.txt": b"hi"}
+    upload = UploadFile(filename="bundle.zip", file=io.BytesIO(zip_bytes(entries)))
+
+    batch = await service.upload_batch_archive(upload, session)
+
+    assert [item.filename for item in batch.items] == ["first.dem", "second.dem"]
+    await service.process_batches(session)
+    assert service.get_batch(session, batch.id).status == "completed"
+    with pytest.raises(ValueError, match="no .dem files"):
+        await service.upload_batch_archive(
+            UploadFile(filename="empty.zip", file=io.BytesIO(zip_bytes({"readme.txt": b"hi"}))), session
+        )
+
+
+class FlakyProcessor(DemoProcessor):
+    """Raises the queued errors, one per run, before processing normally."""
+
+    def __init__(self, output_dir: Path, errors: list[Exception]) -> None:
+        super().__init__(output_dir)
+        self.errors = errors
+
+    def process(self, processing_input):
+        if self.errors:
+            raise self.errors.pop(0)
+        return super().process(processing_input)
+
+
+@pytest.mark.asyncio
+async def test_transient_failures_are_retried_with_backoff(service_with_session):
+    service, session, settings = service_with_session
+    settings.processing_retry_backoff_seconds = 0
+    service.processor = FlakyProcessor(settings.processed_data_path, [OSError("disk hiccup"), OSError("again")])
+
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    assert demo.status == "processed"
+    jobs = service.list_jobs(session, demo_id=demo.id)
+    assert sorted((job.attempt, job.status) for job in jobs) == [(1, "error"), (2, "error"), (3, "parsed")]
+
+
+def test_job_pages_follow_the_cursor_order(service_with_session):
+    service, session, _ = service_with_session
+    started = datetime(2024, 7, 1)
+    ids = [new_ulid() for _ in range(5)]
+    # Started times out of step with the ids, as for back-dated jobs.
+    for offset, job_id in zip([3, 0, 4, 1, 2], ids):
+        session.add(
+            ParseJob(
+                id=job_id, demo_id="d1", status="parsed", started_at=started + timedelta(minutes=offset),
+                finished_at=started, duration_seconds=1.0, size_bytes=1,
+            )
+        )
+    session.commit()
+
+    walked, cursor = [], None
+    while True:
+        page = service.list_jobs(session, limit=2, cursor=cursor)
+        walked += [job.id for job in page]
+        cursor = next_cursor([job.id for job in page], page_size=2)
+        if cursor is None:
+            break
+    assert walked == ids[::-1]
+
+
+@pytest.mark.asyncio
+async def test_corrupt_demo_is_dead_lettered_and_can_be_retried(service_with_session):
+    service, session, settings = service_with_session
+    service.processor = FlakyProcessor(settings.processed_data_path, [RuntimeError("bad packet entity")])
+
+    with pytest.raises(DemoProcessingError) as failed:
+        await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    assert failed.value.dead_letter and failed.value.attempts == 1
+    demo = service.get_demo(session, failed.value.demo_id)
+    assert demo.status == "dead_letter"
+    assert [job.id for job in service.list_jobs(session, status="dead_letter")] == [failed.value.job_id]
+
+    retried = await service.retry_job(session, failed.value.job_id)
+    assert (retried.status, retried.retry_of) == ("parsed", failed.value.job_id)
+    assert service.get_demo(session, demo.id).status == "processed"
+
+
+class StubDownloader:
+    def __init__(self, payload: bytes) -> None:
+        self.payload = payload
+        self.urls: list[str] = []
+
+    def download(self, url: str, destination: Path) -> DownloadedDemo:
+        self.urls.append(url)
+        destination.write_bytes(self.payload)
+        return DownloadedDemo(
+            path=destination,
+            filename="replay.dem.bz2",
+            content_type="application/octet-stream",
+            checksum=hashlib.sha256(self.payload).hexdigest(),
+            size_bytes=len(self.payload),
+        )
+
+
+@pytest.mark.asyncio
+async def test_ingest_from_url_runs_upload_pipeline(service_with_session):
+    service, session, settings = service_with_session
+    service.downloader = StubDownloader(bz2.compress(DEMO_BYTES))
+
+    demo, created = await service.ingest_from_url("https://replay.example.com/match.dem.bz2", session)
+
+    assert created is True
+    assert demo.original_filename == "replay.dem.bz2"
+    assert demo.extra_metadata["source_url"] == "https://replay.example.com/match.dem.bz2"
+    assert demo.extra_metadata["compression"] == "bz2"
+    assert Path(demo.stored_path).read_bytes() == DEMO_BYTES
+
+
+@pytest.mark.asyncio
+async def test_team_pinned_to_a_region_stores_artifacts_there(tmp_path):
+    settings = Settings(
+        data_dir=tmp_path / "data",
+        database_url=f"sqlite:///{tmp_path}/test.db",
+        storage_regions={"eu": {"backend": "local", "path": str(tmp_path / "eu")}},
+    )
+    engine = create_engine(settings.database_url, future=True)
+    Base.metadata.create_all(bind=engine)
+    session = sessionmaker(bind=engine, future=True, expire_on_commit=False)()
+    session.add_all([Team(id="t1", name="Alpha"), Team(id="t2", name="Bravo")])
+    session.commit()
+    service = DemoService(settings, processor=DemoProcessor(settings.processed_data_path))
+
+    with pytest.raises(ValueError):
+        service.set_storage_region(session, "t1", "us")
+    service.set_storage_region(session, "t1", "eu")
+    upload = UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES))
+    demo, _ = await service.upload_demo(upload, session, team_id="t1")
+
+    assert demo.extra_metadata["storage_region"] == "eu"
+    assert demo.extra_metadata["summary_key"] == f"@eu/{demo.id}.parquet"
+    assert (tmp_path / "eu" / f"{demo.id}.parquet").exists()
+    assert service.is_processed(demo)
+    [region] = service.list_storage_regions(session)
+    assert (region["name"], region["location"], region["team_ids"]) == ("eu", str(tmp_path / "eu"), ["t1"])
+
+    other = UploadFile(filename="other.dem", file=io.BytesIO(DEMO_BYTES + b"other"))
+    unpinned, _ = await service.upload_demo(other, session, team_id="t2")
+    assert "storage_region" not in unpinned.extra_metadata
+    assert not unpinned.extra_metadata["summary_key"].startswith("@")
+
+    service.delete_demo(session, demo.id)
+    assert not (tmp_path / "eu" / f"{demo.id}.parquet").exists()
+    session.close()
+
+
+def test_downloader_rejects_non_http_urls(tmp_path):
+    downloader = DemoDownloader(max_size=1024)
+
+    with pytest.raises(ValueError):
+        downloader.download("file:///etc/passwd", tmp_path / "demo.tmp")
+
+
+@pytest.mark.asyncio
+async def test_parsed_upload_records_match_and_players(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    service.processor = DemoProcessor(settings.processed_data_path, parser=make_parser(parsed=parsed_demo))
+
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+    await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session, force=True)
+    session.refresh(demo)
+
+    assert demo.match.map_name == "de_mirage"
+    assert (demo.match.team_a, demo.match.team_b) == ("Alpha", "Bravo")
+    assert demo.match.rounds == 2
+    assert demo.match.artifacts["summary"] == f"{demo.id}.parquet"
+    assert sorted(player.steamid for player in demo.match.players) == ["1", "2", "6", "7"]
+    assert session.query(Match).count() == 1
+
+
+@pytest.mark.asyncio
+async def test_match_id_is_derived_from_full_hash_with_external_alias(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    service.processor = DemoProcessor(settings.processed_data_path, parser=make_parser(parsed=parsed_demo))
+
+    upload = UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES))
+    demo, _ = await service.upload_demo(upload, session, external_match_id="1-abc", external_source="faceit")
+
+    assert demo.match.id == match_id_for(demo.checksum)
+    assert demo.match.content_hash == hashlib.sha256(DEMO_BYTES).hexdigest()
+    assert (demo.match.external_id, demo.match.external_source) == ("1-abc", "faceit")
+    assert service.get_demo_by_match(session, "1-abc").id == demo.id
+    assert service.get_demo_by_match(session, demo.match.id).id == demo.id
+
+    other = UploadFile(filename="other.dem", file=io.BytesIO(DEMO_BYTES + b"other"))
+    with pytest.raises(ValueError):
+        await service.upload_demo(other, session, external_match_id="1-abc")
+    assert len(list(settings.raw_data_path.glob("*.tmp"))) == 0
+
+
+@pytest.mark.asyncio
+async def test_match_id_collision_falls_back_to_random_id(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    service.processor = DemoProcessor(settings.processed_data_path, parser=make_parser(parsed=parsed_demo))
+    first, _ = await service.upload_demo(UploadFile(filename="first.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    second_bytes = DEMO_BYTES + b"second"
+    # Pretend the first demo's match already owns the id the second demo derives.
+    session.execute(
+        update(Match)
+        .where(Match.id == first.match.id)
+        .values(id=match_id_for(hashlib.sha256(second_bytes).hexdigest()))
+    )
+    session.commit()
+    session.expire_all()
+
+    second, _ = await service.upload_demo(UploadFile(filename="second.dem", file=io.BytesIO(second_bytes)), session)
+
+    assert second.match.id != match_id_for(second.checksum)
+    assert second.match.content_hash == second.checksum
+    assert session.query(Match).count() == 2
+
+
+class StubHltv:
+    def __init__(self, match: HltvMatch) -> None:
+        self.match = match
+
+    def fetch_match(self, url: str) -> HltvMatch:
+        return self.match
+
+
+@pytest.mark.asyncio
+async def test_hltv_import_enriches_match_in_demo_team_order(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    service.processor = DemoProcessor(settings.processed_data_path, parser=make_parser(parsed=parsed_demo))
+    service.hltv = StubHltv(
+        HltvMatch(
+            url="https://www.hltv.org/matches/1/bravo-vs-alpha",
+            team_1="Bravo Esports",
+            team_2="Alpha Gaming",
+            event="Major",
+            maps=[HltvMapResult(map_name="de_mirage", score_1=13, score_2=10)],
+        )
+    )
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    demo = service.import_hltv_result(session, demo.id, "https://www.hltv.org/matches/1/bravo-vs-alpha")
+
+    assert (demo.match.team_a, demo.match.team_b) == ("Alpha Gaming", "Bravo Esports")
+    assert (demo.match.score_a, demo.match.score_b) == (10, 13)
+    assert demo.match.winner == "Bravo Esports"
+    assert demo.match.event_name == "Major"
+    assert {player.team_name for player in demo.match.players} == {"Alpha Gaming", "Bravo Esports"}
+    assert demo.extra_metadata["hltv"]["event"] == "Major"
+
+
+@pytest.mark.asyncio
+async def test_hltv_import_requires_a_known_map(service_with_session):
+    service, session, settings = service_with_session
+    service.hltv = StubHltv(
+        HltvMatch(
+            url="https://www.hltv.org/matches/1/a-vs-b",
+            team_1="A",
+            team_2="B",
+            maps=[HltvMapResult("de_mirage", 13, 5), HltvMapResult("de_nuke", 7, 13)],
+        )
+    )
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    with pytest.raises(ValueError):
+        service.import_hltv_result(session, demo.id, "https://www.hltv.org/matches/1/a-vs-b")
+
+    demo = service.import_hltv_result(session, demo.id, "https://www.hltv.org/matches/1/a-vs-b", map_name="de_nuke")
+    assert demo.match.source == "hltv"
+    assert (demo.match.team_a, demo.match.score_a, demo.match.score_b) == ("A", 7, 13)
+
+
+@pytest.mark.asyncio
+async def test_search_demos_filters_by_match_metadata(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    scrim = UploadFile(filename="scrim.dem", file=io.BytesIO(b"PBDEMS2\x00scrim"))
+    unparsed, _ = await service.upload_demo(scrim, session)
+    service.processor = DemoProcessor(settings.processed_data_path, parser=make_parser(parsed=parsed_demo))
+    parsed, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    by_map, total = service.search_demos(session, map_name="de_mirage")
+    assert [demo.id for demo in by_map] == [parsed.id]
+    assert total == 1
+
+    by_player, _ = service.search_demos(session, player="6")
+    assert [demo.id for demo in by_player] == [parsed.id]
+    assert service.search_demos(session, player="99") == ([], 0)
+
+    first_page, total = service.search_demos(session, sort="filename", page=1, page_size=1)
+    assert [demo.original_filename for demo in first_page] == ["match.dem"]
+    assert total == 2
+
+    with pytest.raises(ValueError):
+        service.search_demos(session, sort="checksum")
+
+
+@pytest.mark.asyncio
+async def test_delete_demo_removes_rows_and_artifacts(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    service.processor = DemoProcessor(settings.processed_data_path, parser=make_parser(parsed=parsed_demo))
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+    stored_path = Path(demo.stored_path)
+    dataset_dir = settings.processed_data_path / demo.id
+    assert dataset_dir.exists()
+
+    service.delete_demo(session, demo.id)
+
+    assert service.get_demo(session, demo.id) is None
+    assert session.query(Match).count() == 0
+    assert not stored_path.exists()
+    assert not dataset_dir.exists()
+    assert not (settings.processed_data_path / f"{demo.id}.parquet").exists()
+
+
+@pytest.mark.asyncio
+async def test_soft_deleted_demo_is_hidden_until_uploaded_again(service_with_session):
+    service, session, settings = service_with_session
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    service.delete_demo(session, demo.id, soft=True)
+
+    assert service.get_demo(session, demo.id) is None
+    assert service.list_demos(session) == []
+    assert Path(demo.processed_path).exists()
+
+    restored, created = await service.upload_demo(
+        UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session
+    )
+    assert (restored.id, created) == (demo.id, False)
+    assert restored.deleted_at is None
+
+
+@pytest.mark.asyncio
+async def test_trashed_demo_can_be_restored_until_it_is_purged(service_with_session):
+    service, session, settings = service_with_session
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+    stored_path = Path(demo.stored_path)
+
+    service.delete_demo(session, demo.id, soft=True)
+    assert [trashed.id for trashed in service.list_trash(session)] == [demo.id]
+    assert service.purge_at(demo) == demo.deleted_at + timedelta(days=settings.trash_retention_days)
+
+    service.restore_demo(session, demo.id)
+    assert service.get_demo(session, demo.id) is not None
+    assert service.list_trash(session) == []
+    with pytest.raises(LookupError):
+        service.restore_demo(session, demo.id)
+
+    service.delete_demo(session, demo.id, soft=True)
+    assert service.purge_trash(session, now=datetime.utcnow() + timedelta(days=1)) == 0
+    assert stored_path.exists()
+
+    assert service.purge_trash(session, now=datetime.utcnow() + timedelta(days=settings.trash_retention_days + 1)) == 1
+    assert service.get_demo(session, demo.id, include_deleted=True) is None
+    assert not stored_path.exists()
+
+
+@pytest.mark.asyncio
+async def test_aim_track_reads_one_round_of_one_player(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    parser = make_parser(parsed=parsed_demo)
+    windows = []
+
+    def parse_aim(path, steamid, start_tick, end_tick):
+        windows.append((steamid, start_tick, end_tick))
+        samples = pd.DataFrame([{"tick": 1200, "pitch": 1.5, "yaw": 90.0, "weapon": "ak47"}])
+        return samples, pd.DataFrame([{"tick": 1201, "weapon": "ak47"}])
+
+    parser.parse_aim = parse_aim
+    service.processor = DemoProcessor(settings.processed_data_path, parser=parser)
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    track = service.aim_track(session, demo.match.id, "7", 2)
+
+    assert windows == [("7", 1000, 2000)]
+    assert (track.tick_rate, track.samples[0].yaw, track.samples[0].x) == (64.0, 90.0, None)
+    assert [shot.tick for shot in track.shots] == [1201]
+    with pytest.raises(LookupError):
+        service.aim_track(session, demo.match.id, "7", 9)
+
+
+@pytest.mark.asyncio
+async def test_round_replay_orders_frames_and_events_by_tick(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    parser = make_parser(parsed=parsed_demo)
+    windows = []
+
+    def parse_replay(path, start_tick, end_tick, every):
+        windows.append((start_tick, end_tick, every))
+        positions = pd.DataFrame(
+            [
+                {"tick": 1000, "steamid": 7, "name": "seven", "side": "T", "is_alive": True, "health": 100,
+                 "x": -1200.4, "y": 310.6, "z": -160.0, "yaw": 91.27, "pitch": 2.0, "weapon": "ak47"},
+                {"tick": 1008, "steamid": 7, "name": "seven", "side": "T", "is_alive": True, "health": 73,
+                 "x": -1190.0, "y": 305.0, "z": -160.0, "yaw": 88.0, "pitch": 1.0, "weapon": "ak47"},
+            ]
+        )
+        shots = pd.DataFrame([{"tick": 1008, "steamid": 7, "weapon": "ak47"}])
+        kills = pd.DataFrame(
+            [{"tick": 1004, "attacker_steamid": 7, "victim_steamid": 1, "weapon": "ak47", "headshot": True}]
+        )
+        return ReplayFrames(positions=positions, shots=shots, kills=kills)
+
+    parser.parse_replay = parse_replay
+    service.processor = DemoProcessor(settings.processed_data_path, parser=parser)
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+
+    replay = service.round_replay(session, demo.match.id, 2, rate=8)
+
+    assert windows == [(1000, 2000, 8)]
+    assert (replay.rate, replay.radar["scale"], [player.steamid for player in replay.players]) == (8.0, 5.0, ["7"])
+    assert [(event["type"], event["tick"]) for event in replay.events] == [
+        ("frame", 1000), ("kill", 1004), ("frame", 1008), ("shot", 1008)
+    ]
+    assert replay.events[0]["players"] == [["7", -1200, 311, -160, 91.3, 2.0, 100, True, "ak47"]]
+    kill = replay.events[1]
+    assert (kill["t"], kill["victim_steamid"], kill["headshot"]) == (0.062, "1", True)
+    with pytest.raises(LookupError):
+        service.round_replay(session, demo.match.id, 9, rate=8)
+
+
+@pytest.mark.asyncio
+async def test_parsed_upload_persists_player_match_stats(service_with_session, parsed_demo, make_parser):
+    service, session, settings = service_with_session
+    service.processor = DemoProcessor(settings.processed_data_path, parser=make_parser(parsed=parsed_demo))
+
+    demo, _ = await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session)
+    await service.upload_demo(UploadFile(filename="match.dem", file=io.BytesIO(DEMO_BYTES)), session, force=True)
+
+    stats = session.query(PlayerMatchStat).filter_by(source="demo").all()
+    assert sorted(stat.steamid for stat in stats) == ["1", "6", "7"]
+    assert {stat.match_id for stat in stats} == {demo.match.id}
+    assert "stats" in demo.extra_metadata["datasets"]
@@ -0,0 +1,84 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.models import Demo, Match
+from stratagemforge.domain.events.models import OutboxMessage
+from stratagemforge.domain.events.service import OutboxService
+from stratagemforge.domain.stats.models import PlayerMatchStat
+
+
+class FakePublisher:
+    name = "fake"
+
+    def __init__(self, error: Exception | None = None) -> None:
+        self.error = error
+        self.published = []
+
+    def publish(self, messages):
+        if self.error is not None:
+            raise self.error
+        self.published.extend(messages)
+
+
+def add_match(session):
+    demo = Demo(
+        id="d1", original_filename="a.dem", stored_path="a.dem", checksum="c1", size_bytes=10, status="processed",
+    )
+    match = Match(
+        id="m1", demo=demo, map_name="de_nuke", played_at=datetime(2024, 3, 1), team_a="Alpha", team_b="Bravo",
+        score_a=13, score_b=9, rounds=22, artifacts={"kills": "m1/kills.parquet"},
+    )
+    stat = PlayerMatchStat(
+        source="demo", match_key="d1", player_key="765", match_id="m1", steamid="765", player_name="ace", kills=21,
+    )
+    session.add_all([demo, match, stat])
+    session.flush()
+    return demo, match
+
+
+def test_match_processed_is_queued_with_the_match_and_relayed(session, tmp_path):
+    publisher = FakePublisher()
+    service = OutboxService(Settings(data_dir=tmp_path, event_topic_prefix="sf."), publisher=publisher)
+    demo, match = add_match(session)
+
+    service.record_match_processed(session, demo, match)
+    session.commit()
+    assert service.summary(session).pending == 1
+
+    result = service.relay(session)
+
+    assert (result.published, result.failed) == (1, 0)
+    message = publisher.published[0]
+    assert (message.topic, message.key) == ("sf.match.processed", "m1")
+    assert message.payload["map_name"] == "de_nuke"
+    assert message.payload["artifacts"] == {"kills": "m1/kills.parquet"}
+    assert message.payload["stats"][0]["kills"] == 21
+    summary = service.summary(session)
+    assert (summary.pending, summary.published) == (0, 1)
+
+
+def test_rolled_back_matches_leave_no_event(session, tmp_path):
+    service = OutboxService(Settings(data_dir=tmp_path), publisher=FakePublisher())
+    demo, match = add_match(session)
+
+    service.record_match_processed(session, demo, match)
+    session.rollback()
+
+    assert session.query(OutboxMessage).count() == 0
+
+
+def test_failed_publish_keeps_messages_pending(session, tmp_path):
+    service = OutboxService(Settings(data_dir=tmp_path), publisher=FakePublisher(ConnectionError("broker down")))
+    service.enqueue(session, "match.processed", {"match_id": "m1"}, key="m1")
+    session.commit()
+
+    result = service.relay(session)
+    service.relay(session)
+
+    assert (result.published, result.failed) == (0, 1)
+    message = session.query(OutboxMessage).one()
+    assert message.published_at is None
+    assert message.attempts == 2
+    assert service.summary(session).last_error == "broker down"
@@ -0,0 +1,62 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.exports.service import SheetsExportService
+from stratagemforge.domain.exports.tables import build_weekly_player_stats
+from stratagemforge.domain.stats.models import PlayerMatchStat
+
+
+class FakeSheets:
+    def __init__(self) -> None:
+        self.writes: dict[str, list] = {}
+
+    def write_table(self, spreadsheet_id: str, sheet: str, rows: list) -> None:
+        self.writes[sheet] = rows
+
+
+@pytest.fixture
+def session(session):
+    for key, played_at, kills, deaths in [
+        ("m1", datetime(2024, 7, 1, 20), 20, 10),
+        ("m2", datetime(2024, 7, 3, 20), 10, 10),
+        ("m3", datetime(2024, 7, 9, 20), 5, 15),
+    ]:
+        session.add(
+            PlayerMatchStat(
+                source="leetify", match_key=key, player_key="765", steamid="765", player_name="s1mple",
+                played_at=played_at, map_name="de_mirage", kills=kills, deaths=deaths, adr=80.0,
+            )
+        )
+    session.commit()
+    return session
+
+
+def test_weekly_player_stats_groups_by_iso_week(session):
+    header, *rows = build_weekly_player_stats(session)
+
+    assert header[0] == "week"
+    assert [(row[0], row[3], row[4], row[6]) for row in rows] == [("2024-W28", 1, 5, 0.33), ("2024-W27", 2, 30, 1.5)]
+
+
+def test_export_writes_configured_tables(session, tmp_path):
+    fake = FakeSheets()
+    settings = Settings(data_dir=tmp_path, sheets_spreadsheet_id="sheet-1", sheets_tables="scoreboard")
+    service = SheetsExportService(settings, client=fake)
+
+    result = service.export(session)
+
+    assert result.tables == {"scoreboard": 3}
+    assert list(fake.writes) == ["scoreboard"]
+    assert fake.writes["scoreboard"][1][4] == "s1mple"
+
+
+def test_export_requires_configuration(session, tmp_path):
+    service = SheetsExportService(Settings(data_dir=tmp_path))
+
+    assert service.enabled is False
+    with pytest.raises(ValueError):
+        service.export(session)
@@ -0,0 +1,437 @@
+from __future__ import annotations
+
+from datetime import datetime
+from zoneinfo import ZoneInfo
+
+import pandas as pd
+
+from stratagemforge.domain.analysis.duels import summarize_duels
+from stratagemforge.domain.analysis.pistol import summarize_pistol_rounds
+from stratagemforge.domain.analysis.saves import summarize_save_decisions
+from stratagemforge.domain.analysis.scenarios import summarize_post_plant
+from stratagemforge.domain.demos.extractors.clutches import build_clutches
+from stratagemforge.domain.demos.extractors.duels import build_duels
+from stratagemforge.domain.demos.extractors.kills import build_kill_feed
+from stratagemforge.domain.demos.clock import TickClock, match_clock
+from stratagemforge.domain.demos.extractors.match import build_match_info, map_from_filename, start_time_from_filename
+from stratagemforge.domain.demos.extractors.pistol import build_pistol_rounds, pistol_round_numbers
+from stratagemforge.domain.demos.extractors.player_ticks import build_player_ticks
+from stratagemforge.domain.demos.extractors.post_plant import build_post_plant_scenarios
+from stratagemforge.domain.demos.extractors.rounds import build_round_summary
+from stratagemforge.domain.demos.extractors.saves import build_save_decisions, carries_money
+from stratagemforge.domain.demos.extractors.scoreboard import build_player_stats
+from stratagemforge.domain.demos.extractors.site_hits import build_site_hits
+from stratagemforge.domain.demos.extractors.utility import build_player_utility, summarize_team_utility
+from stratagemforge.domain.demos.models import Match
+from stratagemforge.domain.demos.parser import ParsedDemo, RoundPhase, attribute_assists
+from stratagemforge.domain.demos.smokes import lines_through_smoke, shot_lines
+
+
+def test_team_utility_counts_throws_around_first_contact(parsed_demo):
+    summary = summarize_team_utility(parsed_demo).set_index(["round", "side"])
+
+    ct_first = summary.loc[(1, "CT")]
+    assert ct_first["smokes_thrown"] == 1
+    assert ct_first["molotovs_thrown"] == 1
+    assert ct_first["utility_before_contact"] == 1
+    assert ct_first["utility_after_contact"] == 1
+    assert ct_first["utility_unused"] == 2
+
+    t_first = summary.loc[(1, "T")]
+    assert t_first["flashes_thrown"] == 1
+    # Dead players' grenades are not counted as unused utility.
+    assert t_first["utility_unused"] == 0
+
+    assert summary.loc[(2, "T"), "he_grenades_thrown"] == 1
+    assert summary.loc[(2, "T"), "molotovs_unused"] == 1
+    assert summary.loc[(2, "CT"), "utility_thrown"] == 0
+
+
+def test_round_summary_marks_round_winner(parsed_demo):
+    summary = build_round_summary(parsed_demo)
+
+    assert len(summary) == 4
+    winners = summary[summary["won"]][["round", "side"]].values.tolist()
+    assert winners == [[1, "CT"], [2, "T"]]
+
+
+def test_round_summary_is_empty_without_rounds():
+    assert build_round_summary(ParsedDemo()).empty
+
+
+def test_post_plant_scenarios_capture_alive_counts(parsed_demo):
+    scenarios = build_post_plant_scenarios(parsed_demo).set_index("round")
+
+    assert scenarios.loc[1, "site"] == "B"
+    assert bool(scenarios.loc[1, "defused"]) is True
+    # Round 2 has one CT death before the plant; CT roster falls back to five players.
+    assert scenarios.loc[2, "ct_alive"] == 4
+    assert scenarios.loc[2, "winner"] == "T"
+
+
+def test_post_plant_timings_use_the_demo_tick_rate(parsed_demo):
+    # The plant comes 300 ticks after freeze time: 4.69s at the default 64 ticks, 2.34s on a 128-tick server.
+    assert build_post_plant_scenarios(parsed_demo).set_index("round").loc[1, "seconds_into_round"] == 4.69
+    parsed_demo.header["tick_rate"] = 128.0
+    assert build_post_plant_scenarios(parsed_demo).set_index("round").loc[1, "seconds_into_round"] == 2.34
+
+
+def test_post_plant_summary_uses_team_perspective(parsed_demo):
+    scenarios = build_post_plant_scenarios(parsed_demo)
+
+    attackers = summarize_post_plant(scenarios, "bravo")
+    assert set(attackers["perspective"]) == {"post_plant"}
+    held = attackers[attackers["site"] == "A"].iloc[0]
+    assert held["scenario"] == "1v4"
+    assert held["win_rate"] == 1.0
+
+    defenders = summarize_post_plant(scenarios, "Alpha")
+    retakes = defenders.set_index("site")
+    assert retakes.loc["B", "wins"] == 1
+    assert retakes.loc["A", "wins"] == 0
+
+
+def test_post_plant_summary_unknown_team_is_empty(parsed_demo):
+    assert summarize_post_plant(build_post_plant_scenarios(parsed_demo), "Charlie").empty
+
+
+def test_pistol_round_numbers_are_first_round_of_each_half():
+    assert pistol_round_numbers(range(1, 25)) == [1, 13]
+
+
+def test_pistol_rounds_classify_buys_and_outcome(parsed_demo):
+    pistols = build_pistol_rounds(parsed_demo).set_index("side")
+
+    assert list(pistols["round"]) == [1, 1]
+    assert pistols.loc["CT", "buy"] == "armor"
+    assert pistols.loc["CT", "defuser_count"] == 1
+    assert bool(pistols.loc["CT", "won"]) is True
+    assert bool(pistols.loc["CT", "opening_kill"]) is True
+    assert pistols.loc["T", "buy"] == "pistol_upgrades"
+    assert pistols.loc["T", "utility_bought"] == 2
+    assert bool(pistols.loc["T", "bomb_planted"]) is True
+
+
+def test_pistol_summary_groups_per_map_and_side(parsed_demo):
+    pistols = build_pistol_rounds(parsed_demo)
+    pistols["map_name"] = "de_mirage"
+
+    summary = summarize_pistol_rounds(pistols, "Bravo")
+
+    assert len(summary) == 1
+    assert summary[0]["side"] == "T"
+    assert summary[0]["win_rate"] == 0.0
+    assert summary[0]["buys"][0]["buy"] == "pistol_upgrades"
+
+
+def test_save_decisions_follow_the_losing_side_into_the_next_round(parsed_demo):
+    parsed_demo.round_end_inventory = pd.DataFrame(
+        [
+            {"round": 1, "steamid": "6", "side": "T", "is_alive": False, "inventory": ["AK-47"]},
+            {"round": 1, "steamid": "7", "side": "T", "is_alive": True, "inventory": ["Glock-18", "Galil AR"]},
+            {"round": 1, "steamid": "8", "side": "T", "is_alive": False, "inventory": ["Glock-18"]},
+            {"round": 1, "steamid": "1", "side": "CT", "is_alive": True, "inventory": ["M4A4"]},
+            {"round": 2, "steamid": "1", "side": "CT", "is_alive": False, "inventory": ["M4A4"]},
+        ]
+    )
+    parsed_demo.round_start_state = pd.DataFrame(
+        [
+            {"round": 2, "steamid": "6", "side": "T", "inventory": ["Glock-18"], "armor": 0, "balance": 2400},
+            {"round": 2, "steamid": "7", "side": "T", "inventory": ["Glock-18", "Galil AR"], "armor": 0, "balance": 3000},
+            {"round": 2, "steamid": "8", "side": "T", "inventory": ["Tec-9"], "armor": 100, "balance": 50},
+        ]
+    )
+
+    saves = build_save_decisions(parsed_demo).set_index("steamid")
+
+    assert list(saves.index) == ["6", "7", "8"]
+    assert set(saves["team_name"]) == {"Bravo"}
+    assert saves.loc["6", ["saved_value", "next_spend", "decision"]].tolist() == [0, 0, "save"]
+    assert saves.loc["7", ["saved_value", "next_spend", "decision"]].tolist() == [1800, 0, "buy"]
+    assert saves.loc["8", ["next_value", "next_spend", "decision"]].tolist() == [1150, 1150, "force"]
+
+    saves = saves.reset_index().assign(demo_id="d1", map_name="de_mirage")
+    summary = summarize_save_decisions(saves, "bravo")
+    assert summary[0]["lost_rounds"] == 1
+    assert summary[0]["equipment_saved"] == 1800
+    assert summary[0]["split_rounds"] == 1
+    assert summary[0]["save_discipline"] == 0.5
+
+
+def test_money_carries_over_within_halves_only():
+    assert [number for number in range(1, 31) if not carries_money(number)] == [12, 24, 27, 30]
+
+
+def test_player_stats_scoreboard(parsed_demo):
+    parsed_demo.kills = parsed_demo.kills.assign(headshot=[True, False], assister_steamid=[None, "6"])
+    parsed_demo.damages = pd.DataFrame(
+        [
+            {"tick": 490, "round": 1, "attacker_steamid": "1", "attacker_side": "CT", "victim_steamid": "6", "victim_side": "T", "weapon": "awp", "damage": 100},
+            {"tick": 1490, "round": 2, "attacker_steamid": "7", "attacker_side": "T", "victim_steamid": "2", "victim_side": "CT", "weapon": "ak47", "damage": 64},
+            {"tick": 1495, "round": 2, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "7", "victim_side": "T", "weapon": "he", "damage": 30},
+        ]
+    )
+
+    stats = build_player_stats(parsed_demo).set_index("steamid")
+
+    assert stats.loc["1", ["kills", "deaths", "headshot_kills", "opening_kills"]].tolist() == [1, 0, 1, 1]
+    assert stats.loc["1", "headshot_pct"] == 100.0
+    assert stats.loc["1", "adr"] == 100.0
+    assert stats.loc["6", ["deaths", "opening_deaths", "assists"]].tolist() == [1, 1, 1]
+    assert stats.loc["6", "damage"] == 0
+    assert stats.loc["6", "kast"] == 0.0
+    assert stats.loc["7", "kast"] == 100.0
+    assert stats[["clutches_played", "kills_2k"]].to_numpy().sum() == 0
+
+
+def test_flash_assists_are_attributed_on_kills_and_scoreboard(parsed_demo):
+    kills = parsed_demo.kills.assign(assister_steamid=["2", "6"], assistedflash=[True, None])
+    parsed_demo.kills = attribute_assists(kills)
+
+    feed = build_kill_feed(parsed_demo)
+    stats = build_player_stats(parsed_demo).set_index("steamid")
+
+    assert "assistedflash" not in parsed_demo.kills.columns
+    assert feed["assist_type"].tolist() == ["flash", "damage"]
+    assert feed.loc[0, "flash_assister_steamid"] == "2"
+    assert pd.isna(feed.loc[1, "flash_assister_steamid"])
+    assert stats.loc["2", ["assists", "flash_assists"]].tolist() == [1, 1]
+    assert stats.loc["6", ["assists", "flash_assists"]].tolist() == [1, 0]
+
+
+def test_player_utility_attributes_flashes_damage_and_smokes(parsed_demo):
+    parsed_demo.blinds = pd.DataFrame(
+        [
+            {"tick": 310, "round": 1, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "1", "victim_side": "CT", "duration": 2.5},
+            {"tick": 310, "round": 1, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "7", "victim_side": "T", "duration": 1.0},
+            {"tick": 310, "round": 1, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "6", "victim_side": "T", "duration": 3.0},
+        ]
+    )
+    parsed_demo.damages = pd.DataFrame(
+        [
+            {"tick": 700, "round": 1, "attacker_steamid": "1", "attacker_side": "CT", "victim_steamid": "7", "victim_side": "T", "weapon": "inferno", "damage": 12},
+            {"tick": 1250, "round": 2, "attacker_steamid": "7", "attacker_side": "T", "victim_steamid": "2", "victim_side": "CT", "weapon": "hegrenade", "damage": 40},
+            {"tick": 1250, "round": 2, "attacker_steamid": "7", "attacker_side": "T", "victim_steamid": "6", "victim_side": "T", "weapon": "hegrenade", "damage": 20},
+        ]
+    )
+    parsed_demo.grenade_events = pd.DataFrame(
+        [
+            {"tick": 232, "round": 1, "steamid": "1", "side": "CT", "grenade": "smoke", "event": "detonate", "entity_id": 42},
+            {"tick": 1400, "round": 2, "steamid": "1", "side": "CT", "grenade": "smoke", "event": "expired", "entity_id": 42},
+        ]
+    )
+
+    utility = build_player_utility(parsed_demo).set_index(["round", "steamid"])
+
+    flasher = utility.loc[(1, "6")]
+    assert flasher[["flashes_thrown", "enemies_flashed", "teammates_flashed"]].tolist() == [1, 1, 1]
+    assert flasher["enemy_blind_seconds"] == 2.5
+    assert flasher["team_blind_seconds"] == 1.0
+    assert utility.loc[(1, "1"), "fire_damage"] == 12
+    # Smoke coverage is capped at the end of round 1 (tick 1000).
+    assert utility.loc[(1, "1"), "smoke_seconds"] == 12.0
+    assert utility.loc[(2, "7"), "he_damage"] == 40
+
+
+def test_scoreboard_counts_utility_suffered(parsed_demo):
+    parsed_demo.blinds = pd.DataFrame(
+        [
+            {"tick": 310, "round": 1, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "1", "victim_side": "CT", "duration": 2.5},
+            {"tick": 310, "round": 1, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "7", "victim_side": "T", "duration": 1.0},
+            {"tick": 310, "round": 1, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "6", "victim_side": "T", "duration": 3.0},
+            {"tick": 1310, "round": 2, "attacker_steamid": "2", "attacker_side": "CT", "victim_steamid": "7", "victim_side": "T", "duration": 0.5},
+        ]
+    )
+    parsed_demo.damages = pd.DataFrame(
+        [
+            {"tick": 700, "round": 1, "attacker_steamid": "1", "attacker_side": "CT", "victim_steamid": "7", "victim_side": "T", "weapon": "inferno", "damage": 12},
+            {"tick": 1250, "round": 2, "attacker_steamid": "7", "attacker_side": "T", "victim_steamid": "7", "victim_side": "T", "weapon": "molotov", "damage": 8},
+        ]
+    )
+
+    stats = build_player_stats(parsed_demo).set_index("steamid")
+
+    columns = ["blind_seconds", "flashed_by_enemies", "flashed_by_teammates", "fire_damage_taken"]
+    assert stats.loc["7", columns].tolist() == [1.5, 1, 1, 20]
+    assert stats.loc["6", columns].tolist() == [3.0, 0, 0, 0]  # self-flashes only add blind time
+    assert stats.loc["1", columns].tolist() == [2.5, 1, 0, 0]
+
+
+def test_player_utility_is_empty_without_rounds():
+    assert build_player_utility(ParsedDemo()).empty
+
+
+def test_clutches_track_last_player_alive(parsed_demo):
+    parsed_demo.round_end_inventory = pd.DataFrame(
+        [
+            {"round": 1, "steamid": steamid, "name": f"p{steamid}", "side": side, "team_name": team}
+            for steamid, side, team in [("1", "CT", "Alpha"), ("2", "CT", "Alpha"), ("3", "CT", "Alpha"), ("6", "T", "Bravo"), ("7", "T", "Bravo")]
+        ]
+    )
+    parsed_demo.kills = pd.DataFrame(
+        [
+            {"tick": 300, "round": 1, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "2", "victim_side": "CT"},
+            {"tick": 320, "round": 1, "attacker_steamid": "7", "attacker_side": "T", "victim_steamid": "3", "victim_side": "CT"},
+            {"tick": 500, "round": 1, "attacker_steamid": "1", "attacker_side": "CT", "victim_steamid": "6", "victim_side": "T"},
+            {"tick": 600, "round": 1, "attacker_steamid": "1", "attacker_side": "CT", "victim_steamid": "7", "victim_side": "T"},
+        ]
+    )
+
+    clutches = build_clutches(parsed_demo)
+
+    assert len(clutches) == 1
+    clutch = clutches.iloc[0]
+    assert (clutch["steamid"], clutch["scenario"], clutch["start_tick"]) == ("1", "1v2", 320)
+    assert clutch["kills"] == 2
+    assert bool(clutch["survived"]) and bool(clutch["won"])
+    stats = build_player_stats(parsed_demo).set_index("steamid")
+    assert stats.loc["1", ["clutches_played", "clutches_won"]].tolist() == [1, 1]
+
+
+def test_start_time_from_filename_reads_recording_stamps():
+    berlin = ZoneInfo("Europe/Berlin")
+
+    assert start_time_from_filename("auto0-20240701-203512-1234567-de_mirage-server.dem", berlin) == datetime(2024, 7, 1, 18, 35, 12)
+    assert start_time_from_filename("scrim_2024-07-01_20-35-12.dem", ZoneInfo("UTC")) == datetime(2024, 7, 1, 20, 35, 12)
+    assert start_time_from_filename("match730_003689.dem", berlin) is None
+    assert start_time_from_filename("auto0-20241399-203512.dem", berlin) is None
+
+
+def test_map_from_filename_finds_the_map_token():
+    assert map_from_filename("auto0-20240701-203512-1234567-de_mirage-server.dem") == "de_mirage"
+    assert map_from_filename("Scrim_DE_DUST2.dem") == "de_dust2"
+    assert map_from_filename("match730_003689.dem") is None
+
+
+def test_kill_feed_tags_round_phase(parsed_demo):
+    parsed_demo.kills = pd.concat(
+        [parsed_demo.kills, pd.DataFrame([{"tick": 1050, "round": 2, "attacker_steamid": "2", "victim_steamid": "7"}])],
+        ignore_index=True,
+    )
+
+    feed = build_kill_feed(parsed_demo).set_index("tick")
+
+    assert feed.loc[500, "phase"] == RoundPhase.LIVE.value
+    assert feed.loc[500, "phase_code"] == 1
+    assert bool(feed.loc[1050, "is_freezetime"]) is True
+    assert bool(feed.loc[1050, "is_live"]) is False
+    assert feed.loc[1050, "phase_code"] == 0
+
+
+def test_kill_feed_marks_kills_through_active_smokes(parsed_demo):
+    parsed_demo.kills = parsed_demo.kills.assign(
+        attacker_x=[0.0, 0.0], attacker_y=[0.0, 0.0], victim_x=[1000.0, 1000.0], victim_y=[0.0, 300.0]
+    )
+    parsed_demo.grenade_events = pd.DataFrame(
+        [
+            {"tick": 232, "round": 1, "steamid": "1", "grenade": "smoke", "event": "detonate", "entity_id": 42, "x": 500.0, "y": 100.0},
+            {"tick": 1400, "round": 2, "steamid": "1", "grenade": "smoke", "event": "expired", "entity_id": 42},
+        ]
+    )
+
+    feed = build_kill_feed(parsed_demo).set_index("tick")
+
+    assert bool(feed.loc[500, "through_smoke"]) is True
+    # The round 1 smoke is gone at the end of round 1.
+    assert bool(feed.loc[1500, "through_smoke"]) is False
+
+
+def test_lines_through_smoke_need_an_active_smoke_near_the_line():
+    smokes = pd.DataFrame([{"start_tick": 100, "end_tick": 200, "x": 0.0, "y": 200.0}])
+    lines = pd.DataFrame(
+        [
+            {"tick": 150, "x1": -500.0, "y1": 0.0, "x2": 500.0, "y2": 0.0},  # passes 200 units from the centre
+            {"tick": 150, "x1": -500.0, "y1": 100.0, "x2": 500.0, "y2": 100.0},
+            {"tick": 250, "x1": -500.0, "y1": 100.0, "x2": 500.0, "y2": 100.0},
+            {"tick": 150, "x1": None, "y1": 100.0, "x2": 500.0, "y2": 100.0},
+        ]
+    )
+
+    assert lines_through_smoke(lines, smokes).tolist() == [False, True, False, False]
+
+    shot = shot_lines(pd.DataFrame([{"tick": 150, "x": 0.0, "y": 0.0, "yaw": 90.0}]))
+    assert lines_through_smoke(shot, smokes).tolist() == [True]
+
+
+def test_player_ticks_keep_living_players_with_a_position(parsed_demo):
+    parsed_demo.player_ticks = pd.DataFrame(
+        [
+            {"tick": 1050, "round": 2, "steamid": "2", "side": "CT", "is_alive": True, "x": 10.0, "y": 20.0, "z": 0.0},
+            {"tick": 1200, "round": 2, "steamid": "2", "side": "CT", "is_alive": True, "x": 30.0, "y": 20.0, "z": 0.0},
+            {"tick": 1200, "round": 2, "steamid": "6", "side": "T", "is_alive": False, "x": 0.0, "y": 0.0, "z": 0.0},
+            {"tick": 1200, "round": 2, "steamid": "7", "side": "T", "is_alive": True, "x": None, "y": None, "z": None},
+        ]
+    )
+
+    ticks = build_player_ticks(parsed_demo)
+
+    assert ticks[["tick", "steamid"]].values.tolist() == [[1050, "2"], [1200, "2"]]
+    assert ticks["phase"].tolist() == ["freezetime", "live"]
+    assert build_player_ticks(ParsedDemo()).empty
+
+
+def test_kill_feed_is_empty_without_kills():
+    assert build_kill_feed(ParsedDemo()).empty
+
+
+def test_duels_tag_openings_and_trades():
+    kill = {"round": 1, "weapon": "ak47", "headshot": False}
+    parsed = ParsedDemo(
+        kills=pd.DataFrame(
+            [
+                {**kill, "tick": 500, "attacker_steamid": "6", "attacker_side": "T", "victim_steamid": "1", "victim_side": "CT"},
+                {**kill, "tick": 600, "attacker_steamid": "2", "attacker_side": "CT", "victim_steamid": "6", "victim_side": "T", "headshot": True},
+                {**kill, "tick": 1000, "attacker_steamid": "7", "attacker_side": "T", "victim_steamid": "2", "victim_side": "CT"},
+                {**kill, "tick": 1100, "attacker_steamid": "7", "attacker_side": "T", "victim_steamid": "8", "victim_side": "T"},
+                {**kill, "round": 2, "tick": 2100, "attacker_steamid": "2", "attacker_side": "CT", "victim_steamid": "6", "victim_side": "T"},
+            ]
+        )
+    )
+
+    duels = build_duels(parsed)
+
+    situations = {(row.attacker_steamid, row.victim_steamid, row.situation): row.kills for row in duels.itertuples()}
+    assert situations == {("6", "1", "opening"): 1, ("2", "6", "trade"): 1, ("7", "2", "other"): 1, ("2", "6", "opening"): 1}
+
+    matrix = summarize_duels(duels)
+    top = matrix["duels"][0]
+    assert (top["attacker_steamid"], top["victim_steamid"], top["kills"], top["headshots"]) == ("2", "6", 2, 1)
+    assert top["situations"] == {"opening": 1, "trade": 1}
+    assert matrix["players"][0] == {"steamid": "2", "name": None, "kills": 2, "deaths": 1}
+    assert [duel["victim_steamid"] for duel in summarize_duels(duels, "7")["duels"]] == ["2"]
+    assert build_duels(ParsedDemo()).empty
+
+
+def test_match_clock_maps_round_ticks_onto_wall_clock_time(parsed_demo):
+    info = build_match_info(parsed_demo)
+    assert info["tick_rate"] == 64.0
+    assert info["round_ticks"][1] == {"round": 2, "start_tick": 1000, "freeze_end_tick": 1100, "end_tick": 2000}
+
+    started_at = datetime(2024, 7, 1, 18, 35, 12)
+    match = Match(
+        id="m1",
+        tick_rate=info["tick_rate"],
+        round_ticks=info["round_ticks"],
+        started_at=started_at,
+        started_at_source="filename",
+    )
+    clock = match_clock(match)
+    second = clock.rounds[1]
+    assert (second.start_seconds, second.end_seconds) == (15.625, 31.25)
+    assert second.freeze_end_at == datetime(2024, 7, 1, 18, 35, 29, 187500)
+    assert TickClock(64, started_at).tick_at(second.freeze_end_at) == 1100
+
+    undated = match_clock(Match(id="m2", tick_rate=128, round_ticks=info["round_ticks"]))
+    assert (undated.rounds[0].end_seconds, undated.rounds[0].end_at) == (7.812, None)
+
+
+def test_site_hits_record_plants_and_mid_splits_with_game_state(parsed_demo):
+    parsed_demo.kills["victim_place"] = [None, "TopofMid"]
+
+    hits = build_site_hits(parsed_demo)
+
+    assert hits["hit"].tolist() == ["B", "mid"]
+    assert hits["economy"].tolist() == ["pistol", "unknown"]
+    assert hits[["team_name", "score_for", "score_against"]].values.tolist() == [["Bravo", 0, 0], ["Bravo", 0, 1]]
+    assert hits["hit_seconds"].iloc[0] == 4.7
+    assert hits["plant_site"].tolist() == ["B", "A"]
@@ -0,0 +1,127 @@
+from __future__ import annotations
+
+import hashlib
+from datetime import datetime
+from pathlib import Path
+
+import pytest
+from sqlalchemy import create_engine
+from sqlalchemy.orm import sessionmaker
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.database import Base
+from stratagemforge.domain.demos.downloader import DownloadedDemo
+from stratagemforge.domain.demos.processor import DemoProcessor
+from stratagemforge.domain.demos.service import DemoService
+from stratagemforge.domain.faceit.service import MAX_ATTEMPTS, FaceitService
+
+
+class FakeFaceit:
+    """Serves a player's history, match details and elo like the FACEIT Data API."""
+
+    def __init__(self) -> None:
+        self.history = [
+            {"match_id": "1-new", "finished_at": datetime(2024, 7, 2)},
+            {"match_id": "1-old", "finished_at": datetime(2024, 7, 1)},
+        ]
+        self.matches = {
+            "1-old": {
+                "match_id": "1-old",
+                "competition_type": "hub",
+                "competition_id": "hub-1",
+                "competition_name": "Academy Hub",
+                "region": "EU",
+                "demo_url": ["https://demos.faceit.com/1-old.dem.gz"],
+                "teams": {
+                    "faction1": {"roster": [{"player_id": "p1", "game_player_id": "76561198000000001"}]},
+                    "faction2": {"roster": [{"player_id": "p2", "game_player_id": "76561198000000002"}]},
+                },
+            },
+            "1-new": {"match_id": "1-new", "competition_type": "matchmaking", "demo_url": []},
+        }
+        self.elo = {"p1": 2100, "p2": 1900}
+
+    def finished_matches(self, kind, faceit_id, api_key, limit=20):
+        return self.history
+
+    def match(self, match_id, api_key):
+        return self.matches[match_id]
+
+    def player_elo(self, player_id, api_key):
+        return self.elo.get(player_id)
+
+    def download_url(self, resource_url, api_key):
+        return resource_url + "?signed"
+
+
+class StubDownloader:
+    def __init__(self) -> None:
+        self.urls: list[str] = []
+        self.fail = False
+
+    def download(self, url: str, destination: Path) -> DownloadedDemo:
+        self.urls.append(url)
+        if self.fail:
+            raise ValueError("Download failed")
+        payload = b"PBDEMS2\x00" + url.encode()
+        destination.write_bytes(payload)
+        return DownloadedDemo(
+            path=destination,
+            filename="match.dem",
+            content_type="application/octet-stream",
+            checksum=hashlib.sha256(payload).hexdigest(),
+            size_bytes=len(payload),
+        )
+
+
+@pytest.fixture
+def setup(tmp_path):
+    settings = Settings(data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db")
+    engine = create_engine(settings.database_url, future=True)
+    Base.metadata.create_all(bind=engine)
+    session = sessionmaker(bind=engine, future=True, expire_on_commit=False)()
+    downloader = StubDownloader()
+    demos = DemoService(settings, processor=DemoProcessor(settings.processed_data_path), downloader=downloader)
+    try:
+        yield FaceitService(settings, client=FakeFaceit()), demos, downloader, session
+    finally:
+        session.close()
+
+
+@pytest.mark.asyncio
+async def test_poll_ingests_finished_matches_once_with_faceit_metadata(setup):
+    faceit, demos, downloader, session = setup
+    connector = faceit.create_connector(session, "player", "p1", api_key="key")
+
+    assert await faceit.poll(session, connector, demos) == 1
+    assert downloader.urls == ["https://demos.faceit.com/1-old.dem.gz?signed"]
+    pulls = {pull.faceit_match_id: pull for pull in connector.pulls}
+    assert (pulls["1-old"].status, pulls["1-new"].status) == ("ingested", "skipped")
+
+    demo = demos.get_demo(session, pulls["1-old"].demo_id)
+    tags = demo.extra_metadata["faceit"]
+    assert (demo.extra_metadata["external_match_id"], demo.extra_metadata["external_source"]) == ("1-old", "faceit")
+    assert (tags["hub_id"], tags["hub_name"], tags["average_elo"]) == ("hub-1", "Academy Hub", 2000)
+    assert tags["elo"]["76561198000000001"] == 2100
+
+    assert await faceit.poll(session, connector, demos) == 0
+    assert connector.matches_pulled == 1
+    with pytest.raises(ValueError):
+        faceit.create_connector(session, "player", "p1", api_key="key")
+
+
+@pytest.mark.asyncio
+async def test_failed_pulls_are_retried_then_skipped(setup):
+    faceit, demos, downloader, session = setup
+    connector = faceit.create_connector(session, "hub", "hub-1", api_key="key")
+    downloader.fail = True
+
+    for _ in range(MAX_ATTEMPTS):
+        await faceit.poll(session, connector, demos)
+    pulls = {pull.faceit_match_id: pull for pull in connector.pulls}
+    assert (pulls["1-old"].status, pulls["1-old"].attempts) == ("skipped", MAX_ATTEMPTS)
+
+    faceit.set_enabled(session, connector.id, False)
+    assert await faceit.poll_all(session, demos) == 0
+    with pytest.raises(ValueError):
+        faceit.create_connector(session, "team", "x", api_key="key")
@@ -0,0 +1,54 @@
+from __future__ import annotations
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.flags.service import FeatureFlagService
+from stratagemforge.domain.teams.models import Team
+
+
+@pytest.fixture
+def session(session):
+    session.add_all([Team(id="t1", name="Alpha"), Team(id="t2", name="Bravo")])
+    session.commit()
+    return session
+
+
+def test_team_override_beats_global_default(session, tmp_path):
+    flags = FeatureFlagService(Settings(data_dir=tmp_path))
+    assert flags.is_enabled(session, "analytics.heatmaps") is False
+    assert flags.is_enabled(session, "analytics.heatmaps", default=True) is True
+
+    flags.set_flag(session, "analytics.heatmaps", enabled=False, description="Heatmap endpoints")
+    flags.set_override(session, "analytics.heatmaps", "t1", enabled=True)
+
+    assert flags.is_enabled(session, "analytics.heatmaps", team_id="t1") is True
+    assert flags.is_enabled(session, "analytics.heatmaps", team_id="t2") is False
+    assert flags.evaluate(session, "t1") == {"analytics.heatmaps": True}
+
+    flags.clear_override(session, "analytics.heatmaps", "t1")
+    assert flags.is_enabled(session, "analytics.heatmaps", team_id="t1") is False
+
+
+def test_flag_values_are_cached_between_writes(session, tmp_path):
+    flags = FeatureFlagService(Settings(data_dir=tmp_path, feature_flag_cache_seconds=300))
+    flags.set_flag(session, "datasets.clutches", enabled=True)
+    assert flags.is_enabled(session, "datasets.clutches")
+
+    flags.get_flag(session, "datasets.clutches").enabled = False
+    session.commit()
+    assert flags.is_enabled(session, "datasets.clutches")
+
+    flags.invalidate()
+    assert not flags.is_enabled(session, "datasets.clutches")
+
+
+def test_flag_validation(session, tmp_path):
+    flags = FeatureFlagService(Settings(data_dir=tmp_path))
+    with pytest.raises(ValueError):
+        flags.set_flag(session, "Not A Key", enabled=True)
+    with pytest.raises(LookupError):
+        flags.set_override(session, "missing", "t1", enabled=True)
+    flags.set_flag(session, "datasets.clutches", enabled=True)
+    with pytest.raises(LookupError):
+        flags.set_override(session, "datasets.clutches", "nope", enabled=True)
@@ -0,0 +1,72 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+import pytest
+
+from stratagemforge.domain.demos.hltv import HltvClient, HltvError, parse_match_page, validate_match_url
+
+MATCH_PAGE = """
+<html><body>
+<div class="teamsBox">
+  <div class="team"><div class="team1-gradient"><a href="/team/9565/vitality"><img alt="Vitality" src="x.png">
+    <div class="teamName">Vitality</div></a><div class="won">2</div></div></div>
+  <div class="timeAndEvent">
+    <div class="date" data-unix="1720360800000">7th of July 2024</div>
+    <div class="event text-ellipsis"><a href="/events/7437/iem-cologne-2024" title="IEM Cologne 2024">IEM Cologne 2024</a></div>
+  </div>
+  <div class="team"><div class="team2-gradient"><a href="/team/6667/faze"><img alt="FaZe" src="y.png">
+    <div class="teamName">FaZe</div></a><div class="lost">1</div></div></div>
+</div>
+<div class="mapholder"><div class="played"><div class="map-name-holder"><img src="m.png"><div class="mapname">Mirage</div></div></div>
+  <div class="results played"><div class="results-left won"><div class="results-team-score">13</div></div>
+  <span class="results-center"></span><div class="results-right lost"><div class="results-team-score">9</div></div></div></div>
+<div class="mapholder"><div class="played"><div class="map-name-holder"><div class="mapname">Dust2</div></div></div>
+  <div class="results played"><div class="results-left lost"><div class="results-team-score">11</div></div>
+  <div class="results-right won"><div class="results-team-score">13</div></div></div></div>
+<div class="mapholder"><div class="optional"><div class="map-name-holder"><div class="mapname">Inferno</div></div></div>
+  <div class="results"><div class="results-left"><div class="results-team-score">-</div></div>
+  <div class="results-right"><div class="results-team-score">-</div></div></div></div>
+</body></html>
+"""
+
+URL = "https://www.hltv.org/matches/2373000/vitality-vs-faze-iem-cologne-2024"
+
+
+def test_parse_match_page_extracts_result_metadata():
+    match = parse_match_page(MATCH_PAGE, URL)
+
+    assert (match.team_1, match.team_2) == ("Vitality", "FaZe")
+    assert match.event == "IEM Cologne 2024"
+    assert match.played_at == datetime(2024, 7, 7, 14, 0)
+    assert (match.series_score_1, match.series_score_2) == (2, 1)
+    assert [(m.map_name, m.score_1, m.score_2) for m in match.maps] == [("de_mirage", 13, 9), ("de_dust2", 11, 13)]
+    assert match.map_result("de_dust2").score_2 == 13
+    assert match.map_result(None) is None
+
+
+def test_parse_match_page_requires_teams():
+    with pytest.raises(HltvError):
+        parse_match_page("<html><body>Just a moment...</body></html>")
+
+
+@pytest.mark.parametrize(
+    "url",
+    ["https://example.com/matches/1/a-vs-b", "https://www.hltv.org/team/9565/vitality", "ftp://www.hltv.org/matches/1/x"],
+)
+def test_validate_match_url_rejects_other_pages(url):
+    with pytest.raises(ValueError):
+        validate_match_url(url)
+
+
+def test_client_serves_fresh_pages_from_cache(tmp_path, monkeypatch):
+    client = HltvClient(tmp_path, min_interval=0)
+    cached = client._cache_path(URL)
+    cached.write_text(MATCH_PAGE, encoding="utf-8")
+
+    def fail(*args, **kwargs):
+        raise AssertionError("cached pages must not be downloaded again")
+
+    monkeypatch.setattr(client, "_download", fail)
+
+    assert client.fetch_match(URL).team_1 == "Vitality"
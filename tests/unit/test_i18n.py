@@ -0,0 +1,38 @@
+from __future__ import annotations
+
+import json
+import re
+
+from stratagemforge.core.i18n import LOCALES_DIR, Translator
+
+
+def test_negotiate_prefers_highest_quality_supported_locale():
+    translator = Translator({"de": {}, "es": {}})
+
+    assert translator.negotiate("fr-CH, es;q=0.8, de;q=0.9") == "de"
+    assert translator.negotiate("es-MX") == "es"
+    assert translator.negotiate("fr, *;q=0.5") == "en"
+    assert translator.negotiate(None) == "en"
+
+
+def test_translate_fills_placeholders_from_rendered_text():
+    translator = Translator(
+        {"de": {"Demo not found": "Demo nicht gefunden", "Team {team_id} does not exist": "Team {team_id} existiert nicht"}}
+    )
+
+    assert translator.translate("Demo not found", "de") == "Demo nicht gefunden"
+    assert translator.translate("Team abc-123 does not exist", "de") == "Team abc-123 existiert nicht"
+    assert translator.translate("Something else", "de") == "Something else"
+    assert translator.translate("Demo not found", "en") == "Demo not found"
+    assert translator.gettext("Team {team_id} does not exist", "de", team_id="x") == "Team x existiert nicht"
+
+
+def test_bundled_catalogs_share_keys_and_placeholders():
+    catalogs = {path.stem: json.loads(path.read_text(encoding="utf-8")) for path in LOCALES_DIR.glob("*.json")}
+
+    assert catalogs
+    keys = {frozenset(catalog) for catalog in catalogs.values()}
+    assert len(keys) == 1
+    for catalog in catalogs.values():
+        for msgid, translation in catalog.items():
+            assert set(re.findall(r"\{(\w+)\}", msgid)) == set(re.findall(r"\{(\w+)\}", translation))
@@ -0,0 +1,25 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+from stratagemforge.core.ids import is_ulid, new_ulid, new_uuid, next_cursor, parse_cursor, ulid_time
+
+
+def test_ulids_sort_in_creation_order_and_carry_their_time():
+    ids = [new_ulid() for _ in range(1000)]
+
+    assert ids == sorted(ids)
+    assert len(set(ids)) == len(ids)
+    assert all(is_ulid(value) for value in ids)
+    assert ulid_time(new_ulid(datetime(2024, 7, 1, 12, 30))) == datetime(2024, 7, 1, 12, 30)
+    assert new_ulid(datetime(2024, 1, 1)) < new_ulid(datetime(2024, 1, 2))
+
+
+def test_cursors_are_ulids_of_full_pages():
+    ids = [new_ulid() for _ in range(3)]
+
+    assert parse_cursor(ids[0].lower()) == ids[0]
+    assert next_cursor(ids, page_size=3) == ids[-1]
+    assert next_cursor(ids, page_size=4) is None
+    assert next_cursor([new_uuid()], page_size=1) is None
+    assert not is_ulid(new_uuid())
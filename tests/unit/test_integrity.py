@@ -0,0 +1,47 @@
+from __future__ import annotations
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.storage import LocalStorage
+from stratagemforge.domain.admin.integrity import IntegrityAuditService
+from stratagemforge.domain.demos.models import Demo, Match
+from stratagemforge.domain.demos.service import file_sha256
+
+
+def test_integrity_audit_flags_drift(session, tmp_path):
+    storage = LocalStorage(tmp_path / "artifacts")
+    source = tmp_path / "summary.parquet"
+    source.write_bytes(b"parquet")
+    for key in ("d1.parquet", "d1/stats.parquet", "d1/rounds.parquet", "d2.parquet"):
+        storage.put(source, key)
+    entry = {"size_bytes": 7, "sha256": file_sha256(source)}
+
+    demo = Demo(
+        id="d1", original_filename="a.dem", stored_path="a.dem", checksum="c1", size_bytes=10, status="processed",
+        extra_metadata={
+            "summary_key": "d1.parquet",
+            "datasets": {"stats": "d1/stats.parquet", "rounds": "d1/rounds.parquet", "kills": "d1/kills.parquet"},
+            "artifact_manifest": {
+                "d1.parquet": entry,
+                "d1/stats.parquet": {"size_bytes": 9, "sha256": entry["sha256"]},
+                "d1/rounds.parquet": {"size_bytes": 7, "sha256": "0" * 64},
+            },
+        },
+    )
+    demo.match = Match(team_a="Alpha", team_b="Bravo", artifacts={"summary": "d1.parquet"})
+    legacy = Demo(
+        id="d2", original_filename="b.dem", stored_path="b.dem", checksum="c2", size_bytes=10, status="processed",
+        extra_metadata={"summary_key": "d2.parquet"},
+    )
+    session.add_all([demo, legacy])
+    session.commit()
+
+    service = IntegrityAuditService(Settings(data_dir=tmp_path), storage=storage)
+    run = service.run(session)
+
+    assert run.demos_checked == 2
+    assert run.artifacts_checked == 5
+    assert run.unverified == 2
+    assert run.issue_counts == {"size_mismatch": 1, "checksum_mismatch": 1, "missing": 1, "manifest_mismatch": 1}
+    kinds = {(issue["key"], issue["kind"]) for issue in run.issues}
+    assert ("d1/kills.parquet", "missing") in kinds
+    assert service.latest(session).id == run.id
@@ -0,0 +1,52 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+import pandas as pd
+import pyarrow.parquet as pq
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.parquet import write_parquet
+from stratagemforge.core.storage import LocalStorage
+from stratagemforge.domain.demos.lake import LakeWriter
+from stratagemforge.domain.demos.models import Demo, Match
+
+
+def make_demo(session, tmp_path, storage):
+    ticks = tmp_path / "player_ticks.parquet"
+    frame = pd.DataFrame({"tick": [100, 110, 200], "round": [1, 1, 2], "steamid": ["7", "7", "7"]})
+    write_parquet(frame, ticks, "player_ticks", 1)
+    kills = tmp_path / "kills.parquet"
+    write_parquet(pd.DataFrame({"tick": [105], "round": [1], "weapon": ["ak47"]}), kills, "kills", 3)
+    storage.put(ticks, "d1/player_ticks.parquet")
+    storage.put(kills, "d1/kills.parquet")
+    demo = Demo(
+        id="d1", original_filename="a.dem", stored_path="a.dem", checksum="c1", size_bytes=10, status="processed",
+        extra_metadata={"datasets": {"player_ticks": "d1/player_ticks.parquet", "kills": "d1/kills.parquet"}},
+    )
+    demo.match = Match(id="m1", team_a="Alpha", team_b="Bravo", played_at=datetime(2024, 7, 1))
+    session.add(demo)
+    session.commit()
+    return demo
+
+
+def test_lake_partitions_by_match_and_round_without_the_partition_columns(session, tmp_path):
+    storage = LocalStorage(tmp_path / "artifacts")
+    demo = make_demo(session, tmp_path, storage)
+    lake = LakeWriter(Settings(data_dir=tmp_path, lake_partitioned=True), storage=storage)
+
+    keys = lake.write(session, demo)
+
+    assert sorted(keys) == [
+        "lake/kills/match_id=m1/part-0.parquet",
+        "lake/player_ticks/match_id=m1/round=1/part-0.parquet",
+        "lake/player_ticks/match_id=m1/round=2/part-0.parquet",
+    ]
+    round_one = pq.read_table(storage.get("lake/player_ticks/match_id=m1/round=1/part-0.parquet"))
+    assert round_one.column_names == ["tick", "steamid"]
+    assert round_one.column("tick").to_pylist() == [100, 110]
+    assert round_one.schema.metadata[b"stratagemforge.dataset"] == b"player_ticks"
+    assert demo.extra_metadata["lake_keys"] == keys
+
+    lake.delete_partitions(session, demo)
+    assert not any(storage.exists(key) for key in keys)
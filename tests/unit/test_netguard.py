@@ -0,0 +1,81 @@
+from __future__ import annotations
+
+import threading
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+import pytest
+
+from stratagemforge.core.netguard import NonPublicAddressError, allowed_hosts, check_public_url
+from stratagemforge.domain.demos.downloader import DemoDownloader
+
+LOCAL = frozenset({"127.0.0.1"})
+
+
+@pytest.fixture
+def server():
+    class Handler(BaseHTTPRequestHandler):
+        def do_GET(self):  # noqa: N802 - http.server naming
+            if self.path == "/redirect":
+                self.send_response(302)
+                self.send_header("Location", "http://169.254.169.254/latest/meta-data/")
+                self.end_headers()
+            elif self.path == "/truncated":
+                self.send_response(200)
+                self.send_header("Content-Length", "100")
+                self.end_headers()
+                self.wfile.write(b"PBDEMS2\x00")
+            else:
+                self.send_response(200)
+                self.send_header("Content-Length", "8")
+                self.end_headers()
+                self.wfile.write(b"PBDEMS2\x00")
+
+        def log_message(self, *args):
+            pass
+
+    httpd = HTTPServer(("127.0.0.1", 0), Handler)
+    thread = threading.Thread(target=httpd.serve_forever, daemon=True)
+    thread.start()
+    try:
+        yield f"http://127.0.0.1:{httpd.server_port}"
+    finally:
+        httpd.shutdown()
+
+
+@pytest.mark.parametrize(
+    "url",
+    [
+        "http://127.0.0.1/demo.dem",
+        "http://localhost/demo.dem",
+        "http://10.0.0.5/demo.dem",
+        "http://169.254.169.254/latest/meta-data/",
+        "http://[::1]/demo.dem",
+        "http://[::ffff:192.168.1.1]/demo.dem",
+    ],
+)
+def test_internal_addresses_are_rejected(url):
+    with pytest.raises(NonPublicAddressError):
+        check_public_url(url)
+
+
+def test_allowed_hosts_are_exempt():
+    assert allowed_hosts(" Mirror.lan, 127.0.0.1 ,") == frozenset({"mirror.lan", "127.0.0.1"})
+    check_public_url("http://127.0.0.1/demo.dem", LOCAL)
+
+
+def test_downloader_refuses_internal_hosts_and_redirects_to_them(tmp_path, server):
+    with pytest.raises(NonPublicAddressError):
+        DemoDownloader(max_size=1024).download(f"{server}/demo.dem", tmp_path / "demo.tmp")
+
+    downloader = DemoDownloader(max_size=1024, allowed_hosts=LOCAL)
+    assert downloader.download(f"{server}/demo.dem", tmp_path / "demo.tmp").size_bytes == 8
+    with pytest.raises(NonPublicAddressError):
+        downloader.download(f"{server}/redirect", tmp_path / "demo.tmp")
+
+
+def test_truncated_downloads_fail_as_download_errors(tmp_path, server):
+    downloader = DemoDownloader(max_size=1024, max_retries=0, allowed_hosts=LOCAL)
+
+    with pytest.raises(ValueError, match="Demo download failed"):
+        downloader.download(f"{server}/truncated", tmp_path / "demo.tmp")
+    assert not (tmp_path / "demo.tmp").exists()
@@ -0,0 +1,56 @@
+from __future__ import annotations
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.models import Demo
+from stratagemforge.domain.notifications.service import NotificationService
+from stratagemforge.domain.users.models import User
+
+
+@pytest.fixture
+def session(session):
+    session.add_all(
+        [
+            User(id="u1", email="a@example.com", display_name="A"),
+            User(id="u2", email="b@example.com", display_name="B"),
+            User(id="u3", email="c@example.com", display_name="C", is_active=False),
+        ]
+    )
+    session.commit()
+    return session
+
+
+def test_ingest_hook_notifies_active_users(session, tmp_path):
+    service = NotificationService(Settings(data_dir=tmp_path))
+    demo = Demo(id="d1", original_filename="final.dem", stored_path="x", checksum="c", size_bytes=1)
+
+    service.notify_after_ingest(session, demo)
+
+    inbox, total = service.list_notifications(session, "u1")
+    assert total == 1
+    assert inbox[0].kind == "processing_done"
+    assert inbox[0].link == "/demos/d1"
+    assert service.unread_count(session, "u3") == 0
+
+
+def test_inbox_pagination_and_mark_read(session, tmp_path):
+    service = NotificationService(Settings(data_dir=tmp_path))
+    for index in range(3):
+        service.notify(session, "mention", f"Mentioned #{index}", user_id="u1")
+    service.notify(session, "report_ready", "Weekly report", user_id="u2")
+
+    page, total = service.list_notifications(session, "u1", page=2, page_size=2)
+    assert (len(page), total) == (1, 3)
+
+    first = service.list_notifications(session, "u1")[0][0]
+    service.mark_read(session, first.id, user_id="u1")
+    assert service.unread_count(session, "u1") == 2
+    with pytest.raises(LookupError):
+        service.mark_read(session, first.id, user_id="u2")
+
+    assert service.mark_all_read(session, "u1") == 2
+    assert service.list_notifications(session, "u1", unread_only=True)[1] == 0
+    assert service.unread_count(session, "u2") == 1
+    with pytest.raises(LookupError):
+        service.notify(session, "mention", "Hi", user_id="missing")
@@ -0,0 +1,99 @@
+from __future__ import annotations
+
+import base64
+import hashlib
+from urllib.parse import parse_qs, urlparse
+
+import jwt
+import pytest
+
+from stratagemforge.api.auth import identity_from_claims
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.users.models import User
+from stratagemforge.domain.users.oidc import OidcError, OidcProvider
+from stratagemforge.domain.users.passwords import hash_password
+from stratagemforge.domain.users.service import UserService
+
+REDIRECT_URI = "https://app.example.com/callback"
+VERIFIER = "a-long-random-code-verifier-that-is-at-least-43-characters-long"
+CHALLENGE = base64.urlsafe_b64encode(hashlib.sha256(VERIFIER.encode()).digest()).rstrip(b"=").decode()
+
+
+@pytest.fixture
+def session(session):
+    session.add(
+        User(id="u1", email="coach@example.com", display_name="Coach", password_hash=hash_password("s3cret-pass"))
+    )
+    session.commit()
+    return session
+
+
+@pytest.fixture
+def provider(tmp_path):
+    settings = Settings(data_dir=tmp_path, auth_token_issuer="https://auth.example.com")
+    return OidcProvider(settings, UserService(settings))
+
+
+def authorize(provider, session, client, **params):
+    user = provider.users.authenticate(session, "coach@example.com", "s3cret-pass")
+    request = {"response_type": "code", "scope": "openid email", "state": "xyz", **params}
+    query = parse_qs(urlparse(provider.authorize(session, user, client, REDIRECT_URI, request)).query)
+    return {key: values[0] for key, values in query.items()}
+
+
+def test_code_flow_with_pkce_issues_verifiable_id_and_access_tokens(session, provider):
+    client, secret = provider.create_client(session, "Frontend", [REDIRECT_URI], confidential=False)
+    assert secret is None
+
+    reply = authorize(provider, session, client, nonce="n-1", code_challenge=CHALLENGE, code_challenge_method="S256")
+    assert reply["state"] == "xyz"
+    form = {
+        "grant_type": "authorization_code",
+        "client_id": client.id,
+        "code": reply["code"],
+        "redirect_uri": REDIRECT_URI,
+        "code_verifier": VERIFIER,
+    }
+    tokens = provider.exchange(session, form)
+
+    key = provider.users.tokens.private_key.public_key()
+    claims = jwt.decode(tokens["id_token"], key, algorithms=["RS256"], audience=client.id)
+    assert (claims["sub"], claims["nonce"], claims["email"]) == ("u1", "n-1", "coach@example.com")
+    assert claims["iss"] == provider.discovery("http://testserver/")["issuer"]
+    access = provider.users.token_validator.validate(tokens["access_token"])
+    assert access["sub"] == "u1"
+    assert tokens["scope"] == "openid email"
+    assert identity_from_claims(access).scopes == ()  # no platform access through OIDC
+
+    with pytest.raises(OidcError, match="Invalid or expired authorization code"):
+        provider.exchange(session, form)
+    refreshed = provider.exchange(
+        session, {"grant_type": "refresh_token", "client_id": client.id, "refresh_token": tokens["refresh_token"]}
+    )
+    assert refreshed["refresh_token"] != tokens["refresh_token"]
+    assert refreshed["scope"] == "openid email"
+    assert identity_from_claims(provider.users.token_validator.validate(refreshed["access_token"])).scopes == ()
+
+
+def test_wrong_verifier_secret_or_redirect_is_rejected(session, provider):
+    public, _ = provider.create_client(session, "Frontend", [REDIRECT_URI], confidential=False)
+    server, secret = provider.create_client(session, "Ingestion", [REDIRECT_URI])
+
+    reply = authorize(provider, session, public, code_challenge=CHALLENGE, code_challenge_method="S256")
+    form = {"grant_type": "authorization_code", "client_id": public.id, "code": reply["code"]}
+    with pytest.raises(OidcError, match="code_verifier"):
+        provider.exchange(session, {**form, "redirect_uri": REDIRECT_URI, "code_verifier": "guess"})
+
+    assert authorize(provider, session, public)["error"] == "invalid_request"  # public clients need PKCE
+    assert authorize(provider, session, server, scope="email")["error"] == "invalid_scope"
+    code = authorize(provider, session, server)["code"]
+    with pytest.raises(OidcError) as rejected:
+        provider.exchange(session, {"grant_type": "authorization_code", "code": code}, (server.id, "wrong"))
+    assert rejected.value.error == "invalid_client"
+    with pytest.raises(OidcError, match="redirect_uri"):
+        provider.exchange(
+            session, {"grant_type": "authorization_code", "code": code, "redirect_uri": "https://evil.example.com"},
+            (server.id, secret),
+        )
+    with pytest.raises(OidcError, match="not registered"):
+        provider.authorization_client(session, server.id, "https://evil.example.com/callback")
@@ -0,0 +1,152 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.limits import RateLimitExceededError
+from stratagemforge.domain.demos.models import Demo, Match
+from stratagemforge.domain.public.embeds import sign_embed, verify_embed
+from stratagemforge.domain.public.service import PublicStatsService
+from stratagemforge.domain.stats.models import PlayerMatchStat
+from stratagemforge.domain.teams.models import Team
+
+PLAYED_AT = datetime(2024, 5, 1, 20, 0)
+
+
+class Clock:
+    def __init__(self) -> None:
+        self.now = 1000.0
+
+    def __call__(self) -> float:
+        return self.now
+
+
+@pytest.fixture
+def session(session):
+    session.add(Team(id="t1", name="Alpha"))
+    results = [
+        ("de_mirage", "Alpha", "Bravo", 13, 9),
+        ("de_mirage", "Charlie", "alpha", 13, 5),
+        ("de_nuke", "Alpha", "Delta", 13, 2),
+    ]
+    for index, (map_name, team_a, team_b, score_a, score_b) in enumerate(results):
+        demo = Demo(original_filename=f"{index}.dem", stored_path=f"{index}.dem", checksum=str(index), size_bytes=1)
+        demo.match = Match(
+            map_name=map_name, team_a=team_a, team_b=team_b, score_a=score_a, score_b=score_b,
+            played_at=PLAYED_AT + timedelta(days=index),
+        )
+        session.add(demo)
+        session.flush()
+        for steamid, team_name in (("1", "Alpha"), ("2", "Bravo")):
+            session.add(
+                PlayerMatchStat(
+                    source="demo", match_key=demo.id, player_key=steamid, steamid=steamid, match_id=demo.match.id,
+                    team_name=team_name, map_name=map_name, played_at=demo.match.played_at, kills=20, deaths=10,
+                )
+            )
+    session.commit()
+    return session
+
+
+def test_team_stats_only_cover_the_keys_team(session, tmp_path):
+    service = PublicStatsService(Settings(data_dir=tmp_path))
+
+    stats = service.team_stats(session, "t1")
+
+    assert (stats.matches, stats.wins, stats.losses, stats.win_rate) == (3, 2, 1, 0.667)
+    assert [(record.map_name, record.matches, record.wins) for record in stats.maps] == [
+        ("de_mirage", 2, 1),
+        ("de_nuke", 1, 1),
+    ]
+    latest = stats.recent_results[0]
+    assert (latest.opponent, latest.score, latest.result) == ("Delta", "13-2", "win")
+    assert stats.recent_results[1].score == "5-13"
+    roster = service.roster(session, "t1")
+    assert [(player.steamid, player.matches, player.kd_ratio) for player in roster.players] == [("1", 3, 2.0)]
+
+
+def test_keys_are_hashed_and_revocable(session, tmp_path):
+    service = PublicStatsService(Settings(data_dir=tmp_path))
+    api_key, key = service.issue_key(session, "t1", label="alpha.gg")
+
+    assert api_key.key_hash != key
+    assert service.authenticate(session, key).team_id == "t1"
+    with pytest.raises(LookupError):
+        service.issue_key(session, "missing")
+
+    service.revoke_key(session, api_key.id)
+    with pytest.raises(PermissionError):
+        service.authenticate(session, key)
+    with pytest.raises(PermissionError):
+        service.authenticate(session, None)
+
+
+def test_rate_limit_resets_every_minute(session, tmp_path):
+    clock = Clock()
+    service = PublicStatsService(Settings(data_dir=tmp_path, public_api_rate_limit_per_minute=2), clock=clock)
+    api_key, _ = service.issue_key(session, "t1")
+
+    assert service.check_rate(api_key).remaining == 1
+    assert service.check_rate(api_key).remaining == 0
+    with pytest.raises(RateLimitExceededError) as exc:
+        service.check_rate(api_key)
+    assert exc.value.quota.reset_seconds == 20
+
+    clock.now += 20
+    assert service.check_rate(api_key).remaining == 1
+
+
+def test_responses_are_cached_until_a_demo_is_processed(session, tmp_path):
+    clock = Clock()
+    service = PublicStatsService(Settings(data_dir=tmp_path), clock=clock)
+    first = service.team_stats(session, "t1")
+    session.get(Team, "t1").name = "Bravo"
+    session.commit()
+
+    assert service.team_stats(session, "t1") is first
+    service.invalidate_after_ingest(session, None)
+    assert service.team_stats(session, "t1").team == "Bravo"
+
+
+def test_embed_tokens_are_bound_to_kind_subject_and_expiry():
+    token = sign_embed("secret", "match", "m1", expires_at=2000)
+
+    assert verify_embed("secret", token, "match", now=1000) == "m1"
+    with pytest.raises(PermissionError):
+        verify_embed("secret", token, "player", now=1000)
+    with pytest.raises(PermissionError):
+        verify_embed("other", token, "match", now=1000)
+    with pytest.raises(PermissionError, match="expired"):
+        verify_embed("secret", token, "match", now=3000)
+    with pytest.raises(PermissionError):
+        verify_embed("secret", "garbage", "match")
+
+
+def test_match_and_player_cards_are_read_with_embed_tokens(session, tmp_path):
+    service = PublicStatsService(Settings(data_dir=tmp_path, embed_secret="s3cret"))
+    match = session.query(Match).filter(Match.map_name == "de_nuke").one()
+    match.external_id = "faceit-1"
+    session.commit()
+
+    match_token, subject, expires_at = service.issue_embed_token(session, "match", "faceit-1", expires_in_days=7)
+    card = service.match_card(session, match_token)
+
+    assert subject == match.id
+    assert expires_at is not None
+    assert [(team.name, team.score, team.won) for team in card.teams] == [("Alpha", 13, True), ("Delta", 2, False)]
+    assert card.mvp.kills == 20
+
+    player_token, _, _ = service.issue_embed_token(session, "player", "1")
+    player = service.player_card(session, player_token)
+    assert (player.matches, player.kd_ratio, player.favourite_map) == (3, 2.0, "de_mirage")
+    with pytest.raises(PermissionError):
+        service.player_card(session, match_token)
+    with pytest.raises(LookupError):
+        service.issue_embed_token(session, "player", "404")
+
+
+def test_embed_tokens_need_a_secret(session, tmp_path):
+    with pytest.raises(ValueError):
+        PublicStatsService(Settings(data_dir=tmp_path)).issue_embed_token(session, "player", "1")
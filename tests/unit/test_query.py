@@ -0,0 +1,67 @@
+from __future__ import annotations
+
+from types import SimpleNamespace
+
+import pandas as pd
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.storage import LocalStorage
+from stratagemforge.domain.query.service import QueryService
+
+duckdb = pytest.importorskip("duckdb")
+
+
+def _demo(storage: LocalStorage, match_id: str, kills: list[dict]) -> SimpleNamespace:
+    key = f"processed/{match_id}/kills.parquet"
+    path = storage.get(key)
+    path.parent.mkdir(parents=True, exist_ok=True)
+    pd.DataFrame(kills).to_parquet(path)
+    return SimpleNamespace(match=SimpleNamespace(id=match_id), artifact_keys=lambda: {"kills": key})
+
+
+@pytest.fixture
+def demos(tmp_path):
+    storage = LocalStorage(tmp_path / "artifacts")
+    return storage, [
+        _demo(storage, "m1", [{"weapon": "ak47", "tick": 10}, {"weapon": "awp", "tick": 20}]),
+        _demo(storage, "m2", [{"weapon": "ak47", "tick": 5}]),
+    ]
+
+
+def test_query_stacks_matches_into_one_table_per_dataset(tmp_path, demos):
+    storage, selected = demos
+    service = QueryService(Settings(data_dir=tmp_path), storage=storage)
+
+    result = service.run(selected, "SELECT match_id, count(*) AS kills FROM kills GROUP BY match_id ORDER BY match_id")
+
+    assert [column.name for column in result.to_result().columns] == ["match_id", "kills"]
+    assert result.to_result().rows == [["m1", 2], ["m2", 1]]
+    assert [(table.name, table.matches) for table in result.tables] == [("kills", 2)]
+
+
+def test_query_truncates_at_max_rows(tmp_path, demos):
+    storage, selected = demos
+    service = QueryService(Settings(data_dir=tmp_path, query_max_rows=2), storage=storage)
+
+    result = service.run(selected, "SELECT * FROM kills").to_result()
+
+    assert result.row_count == 2
+    assert result.truncated is True
+
+
+@pytest.mark.parametrize(
+    "sql",
+    [
+        "DROP TABLE kills",
+        "SELECT 1; SELECT 2",
+        "COPY kills TO 'out.csv'",
+        "SELECT * FROM read_csv('/etc/passwd')",
+    ],
+)
+def test_query_rejects_anything_but_a_read_of_the_loaded_tables(tmp_path, demos, sql):
+    storage, selected = demos
+    service = QueryService(Settings(data_dir=tmp_path), storage=storage)
+
+    with pytest.raises(ValueError):
+        service.run(selected, sql)
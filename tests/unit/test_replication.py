@@ -0,0 +1,66 @@
+from __future__ import annotations
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.storage import LocalStorage
+from stratagemforge.domain.demos.models import Demo
+from stratagemforge.domain.demos.replication import ArtifactReplicator
+
+
+def make_demo(session, tmp_path, storage):
+    source = tmp_path / "summary.parquet"
+    source.write_bytes(b"parquet")
+    storage.put(source, "d1.parquet")
+    storage.put(source, "d1/stats.parquet")
+    demo = Demo(
+        id="d1", original_filename="a.dem", stored_path="a.dem", checksum="c1", size_bytes=10, status="processed",
+        extra_metadata={
+            "summary_key": "d1.parquet",
+            "datasets": {"stats": "d1/stats.parquet"},
+            "artifact_manifest": {"d1.parquet": {"size_bytes": 7}, "d1/stats.parquet": {"size_bytes": 7}},
+        },
+    )
+    session.add(demo)
+    session.commit()
+    return demo
+
+
+def test_replicate_records_location_and_deletes_copies(session, tmp_path):
+    storage = LocalStorage(tmp_path / "primary")
+    replica = LocalStorage(tmp_path / "replica")
+    demo = make_demo(session, tmp_path, storage)
+    replicator = ArtifactReplicator(
+        Settings(data_dir=tmp_path, replica_region="eu-west-1"), storage=storage, replica=replica, location="dr"
+    )
+
+    replicator.replicate_after_ingest(session, demo)
+    replicator.replicate_after_ingest(session, demo)
+
+    records = session.get(Demo, "d1").extra_metadata["replicas"]
+    assert len(records) == 1
+    assert records[0]["location"] == "dr"
+    assert records[0]["region"] == "eu-west-1"
+    assert sorted(records[0]["keys"]) == ["d1.parquet", "d1/stats.parquet"]
+    assert replica.size("d1/stats.parquet") == 7
+
+    replicator.delete_replicas(session, demo)
+    assert not replica.exists("d1.parquet")
+
+
+def test_replication_failure_is_recorded_without_raising(session, tmp_path):
+    storage = LocalStorage(tmp_path / "primary")
+    demo = make_demo(session, tmp_path, storage)
+    storage.delete("d1/stats.parquet")
+    replicator = ArtifactReplicator(
+        Settings(data_dir=tmp_path), storage=storage, replica=LocalStorage(tmp_path / "replica"), location="dr"
+    )
+
+    replicator.replicate_after_ingest(session, demo)
+
+    metadata = session.get(Demo, "d1").extra_metadata
+    assert "replication_error" in metadata
+    assert "replicas" not in metadata
+
+
+def test_replication_disabled_without_bucket(tmp_path):
+    replicator = ArtifactReplicator(Settings(data_dir=tmp_path), storage=LocalStorage(tmp_path / "primary"))
+    assert not replicator.enabled
@@ -0,0 +1,45 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+from stratagemforge.domain.schedule.ics import render_calendar
+from stratagemforge.domain.schedule.models import ScheduledSession
+
+
+def make_session(**overrides) -> ScheduledSession:
+    values = dict(
+        id="s-1",
+        kind="scrim",
+        title="Scrim vs Bravo; Mirage, Nuke",
+        starts_at=datetime(2024, 7, 7, 18, 0),
+        ends_at=datetime(2024, 7, 7, 20, 0),
+        opponent="Bravo",
+        location=None,
+        notes=None,
+        cancelled=False,
+        updated_at=datetime(2024, 7, 1, 9, 30),
+    )
+    values.update(overrides)
+    return ScheduledSession(**values)
+
+
+def test_render_calendar_emits_escaped_utc_events():
+    ics = render_calendar([make_session()], "Team schedule", generated_at=datetime(2024, 7, 2))
+
+    assert ics.startswith("BEGIN:VCALENDAR\r\n")
+    assert ics.endswith("END:VCALENDAR\r\n")
+    assert "UID:s-1@stratagemforge\r\n" in ics
+    assert "DTSTART:20240707T180000Z\r\n" in ics
+    assert "SUMMARY:Scrim vs Bravo\\; Mirage\\, Nuke\r\n" in ics
+    assert "DESCRIPTION:Opponent: Bravo\r\n" in ics
+    assert "STATUS:CONFIRMED" in ics
+
+
+def test_render_calendar_folds_long_lines_and_marks_cancellations():
+    ics = render_calendar(
+        [make_session(cancelled=True, notes="x" * 200)], "Team schedule", generated_at=datetime(2024, 7, 2)
+    )
+
+    assert "STATUS:CANCELLED" in ics
+    assert all(len(line.encode()) <= 75 for line in ics.split("\r\n"))
+    assert "\r\n x" in ics
@@ -0,0 +1,94 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+
+import pytest
+from sqlalchemy import select
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.users.models import RefreshToken, User
+from stratagemforge.domain.users.passwords import hash_password
+from stratagemforge.domain.users.service import UserService
+
+
+@pytest.fixture
+def session(session):
+    session.add(
+        User(id="u1", email="coach@example.com", display_name="Coach", password_hash=hash_password("s3cret-pass"))
+    )
+    session.commit()
+    return session
+
+
+@pytest.fixture
+def service(tmp_path):
+    return UserService(Settings(data_dir=tmp_path, jwt_audience="stratagemforge"))
+
+
+def test_login_checks_the_password(session, service):
+    assert service.authenticate(session, "coach@example.com", "s3cret-pass").id == "u1"
+    for email, password in [("coach@example.com", "wrong-pass"), ("nobody@example.com", "s3cret-pass")]:
+        with pytest.raises(PermissionError, match="Invalid email or password"):
+            service.authenticate(session, email, password)
+    with pytest.raises(ValueError):
+        service.set_password(session, "u1", "short")
+
+
+def test_access_tokens_validate_against_the_published_key(session, service, tmp_path):
+    user = service.authenticate(session, "coach@example.com", "s3cret-pass")
+    tokens = service.start_session(session, user)
+
+    claims = service.token_validator.validate(tokens.access.token)
+    assert claims["sub"] == "u1"
+    assert claims["iss"] == "stratagemforge"
+    assert claims["aud"] == "stratagemforge"
+    assert tokens.access.lifetime_seconds == 15 * 60
+    assert service.tokens.jwks()["keys"][0]["kid"] == service.tokens.key_id
+
+    # The generated key is kept, so a restarted service still accepts the token.
+    restarted = UserService(Settings(data_dir=tmp_path, jwt_audience="stratagemforge"))
+    assert restarted.token_validator.validate(tokens.access.token)["jti"] == tokens.access.jti
+
+
+def test_refresh_rotates_and_reuse_revokes_the_session(session, service):
+    user = session.get(User, "u1")
+    first = service.start_session(session, user)
+
+    _, second = service.refresh_session(session, first.refresh_token)
+    assert second.refresh_token != first.refresh_token
+    _, third = service.refresh_session(session, second.refresh_token)
+
+    with pytest.raises(PermissionError):
+        service.refresh_session(session, first.refresh_token)  # replayed
+    with pytest.raises(PermissionError):
+        service.refresh_session(session, third.refresh_token)  # the whole family is gone
+
+
+def test_expired_refresh_tokens_are_refused(session, service):
+    tokens = service.start_session(session, session.get(User, "u1"))
+    for stored in session.scalars(select(RefreshToken)):
+        stored.expires_at = datetime.utcnow() - timedelta(minutes=1)
+    session.commit()
+
+    with pytest.raises(PermissionError, match="expired"):
+        service.refresh_session(session, tokens.refresh_token)
+
+
+def test_logout_revokes_the_access_token_and_session(session, service):
+    tokens = service.start_session(session, session.get(User, "u1"))
+
+    service.logout(session, refresh_token=tokens.refresh_token, access_token=tokens.access.token)
+
+    assert service.is_access_token_revoked(session, tokens.access.jti)
+    with pytest.raises(PermissionError):
+        service.refresh_session(session, tokens.refresh_token)
+
+
+def test_new_password_signs_out_every_session(session, service):
+    tokens = service.start_session(session, session.get(User, "u1"))
+
+    service.set_password(session, "u1", "another-pass")
+
+    with pytest.raises(PermissionError):
+        service.refresh_session(session, tokens.refresh_token)
+    assert service.authenticate(session, "coach@example.com", "another-pass").id == "u1"
@@ -0,0 +1,94 @@
+from __future__ import annotations
+
+import hashlib
+from pathlib import Path
+
+import pytest
+from sqlalchemy import create_engine
+from sqlalchemy.orm import sessionmaker
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.database import Base
+from stratagemforge.domain.demos.downloader import DownloadedDemo
+from stratagemforge.domain.demos.processor import DemoProcessor
+from stratagemforge.domain.demos.service import DemoService
+from stratagemforge.domain.demos.sharecode import (
+    Sharecode,
+    SteamReplayClient,
+    decode_sharecode,
+    encode_sharecode,
+    replay_url_from_match_list,
+)
+
+REPLAY_URL = "http://replay185.valve.net/730/003230642215713767580_1234567890.dem.bz2"
+
+
+def test_sharecode_decodes_to_match_outcome_and_token():
+    decoded = decode_sharecode("CSGO-GADqf-jjyJ8-cSP2r-smZRo-TO2xK")
+
+    assert decoded == Sharecode(match_id=3230642215713767580, outcome_id=3230647599455273103, token=55788)
+    assert encode_sharecode(decoded) == "CSGO-GADqf-jjyJ8-cSP2r-smZRo-TO2xK"
+
+
+@pytest.mark.parametrize("code", ["CSGO-GADqf-jjyJ8-cSP2r-smZRo", "CSGO-GADqf-jjyJ8-cSP2r-smZRo-TO2x0", "GADqf"])
+def test_malformed_sharecodes_are_rejected(code):
+    with pytest.raises(ValueError):
+        decode_sharecode(code)
+
+
+def test_replay_url_is_the_last_rounds_map():
+    reply = {"matches": [{"roundstatsall": [{"map": None}, {"map": REPLAY_URL}]}]}
+
+    assert replay_url_from_match_list(reply) == REPLAY_URL
+    with pytest.raises(LookupError):
+        replay_url_from_match_list({"matches": []})
+    with pytest.raises(ValueError):
+        SteamReplayClient(None).replay_url(decode_sharecode("CSGO-GADqf-jjyJ8-cSP2r-smZRo-TO2xK"))
+
+
+class StubReplays:
+    enabled = True
+
+    def __init__(self) -> None:
+        self.requested: list[Sharecode] = []
+
+    def replay_url(self, sharecode: Sharecode) -> str:
+        self.requested.append(sharecode)
+        return REPLAY_URL
+
+
+class StubDownloader:
+    def download(self, url: str, destination: Path) -> DownloadedDemo:
+        payload = b"PBDEMS2\x00sharecode demo"
+        destination.write_bytes(payload)
+        return DownloadedDemo(
+            path=destination,
+            filename=url.rsplit("/", 1)[-1],
+            content_type="application/octet-stream",
+            checksum=hashlib.sha256(payload).hexdigest(),
+            size_bytes=len(payload),
+        )
+
+
+@pytest.mark.asyncio
+async def test_sharecode_ingest_downloads_the_resolved_replay(tmp_path):
+    settings = Settings(data_dir=tmp_path / "data", database_url=f"sqlite:///{tmp_path}/test.db")
+    engine = create_engine(settings.database_url, future=True)
+    Base.metadata.create_all(bind=engine)
+    session = sessionmaker(bind=engine, future=True, expire_on_commit=False)()
+    replays = StubReplays()
+    service = DemoService(
+        settings,
+        processor=DemoProcessor(settings.processed_data_path),
+        downloader=StubDownloader(),
+        replays=replays,
+    )
+
+    demo, created = await service.ingest_sharecode("CSGO-GADqf-jjyJ8-cSP2r-smZRo-TO2xK", session)
+
+    assert created is True
+    assert replays.requested[0].token == 55788
+    assert demo.extra_metadata["source_url"] == REPLAY_URL
+    assert demo.extra_metadata["external_match_id"] == "3230642215713767580"
+    assert demo.extra_metadata["external_source"] == "valve"
+    session.close()
@@ -0,0 +1,55 @@
+from __future__ import annotations
+
+from datetime import datetime
+
+import pytest
+
+from stratagemforge.domain.stats.importers import normalise_map_name, parse_stats_csv
+
+LEETIFY_EXPORT = """Game ID,Game Finished At,Map,Name,Steam ID,Kills,Deaths,Assists,ADR,HS %,Leetify Rating,Trade Kills
+abc-1,2024-07-07T14:00:00Z,Mirage,s1mple,76561198034202275,25,14,3,98.4,52%,0.061,4
+abc-1,2024-07-07T14:00:00Z,Mirage,b1t,76561198246607476,17,16,5,71.0,64%,-0.012,
+abc-1,2024-07-07T14:00:00Z,Mirage,,,not-a-number,1,1,1,1,1,1
+"""
+
+SCOPE_EXPORT = """match_id;date;map;player;team;k;d;a;adr;kast;rating 2.0
+991;07.07.2024 16:30;de_inferno;ropz;FaZe;21;12;4;88,5;78,3;1,32
+"""
+
+
+def test_parse_leetify_export_maps_known_columns():
+    rows, errors = parse_stats_csv(LEETIFY_EXPORT, "leetify")
+
+    assert len(rows) == 2
+    first = rows[0]
+    assert first["external_match_id"] == "abc-1"
+    assert first["played_at"] == datetime(2024, 7, 7, 14, 0)
+    assert first["map_name"] == "de_mirage"
+    assert (first["kills"], first["deaths"], first["assists"]) == (25, 14, 3)
+    assert first["headshot_pct"] == 52.0
+    assert first["rating"] == 0.061
+    assert first["extra"] == {"Trade Kills": "4"}
+    assert errors == ["line 4: Kills is not a number: 'not-a-number'"]
+
+
+def test_parse_scope_export_handles_semicolons_and_decimal_commas():
+    rows, errors = parse_stats_csv(SCOPE_EXPORT, "scopegg")
+
+    assert errors == []
+    assert rows[0]["player_name"] == "ropz"
+    assert rows[0]["team_name"] == "FaZe"
+    assert rows[0]["played_at"] == datetime(2024, 7, 7, 16, 30)
+    assert rows[0]["adr"] == 88.5
+    assert rows[0]["rating"] == 1.32
+
+
+def test_parse_rejects_unknown_sources_and_unidentifiable_rows():
+    with pytest.raises(ValueError):
+        parse_stats_csv(LEETIFY_EXPORT, "faceit")
+    with pytest.raises(ValueError):
+        parse_stats_csv("Map,Kills\nMirage,10\n", "leetify")
+
+
+@pytest.mark.parametrize("label, expected", [("Dust 2", "de_dust2"), ("Mirage", "de_mirage"), ("cs_office", "cs_office")])
+def test_normalise_map_name(label, expected):
+    assert normalise_map_name(label) == expected
@@ -0,0 +1,116 @@
+from __future__ import annotations
+
+from urllib.parse import parse_qs, urlsplit
+
+import jwt
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.users.models import User
+from stratagemforge.domain.users.service import UserService
+from stratagemforge.domain.users.steam import STEAM_OPENID_URL, SteamOpenId
+
+CALLBACK = "https://api.example.com/api/auth/steam/callback"
+STEAMID = "76561198000000001"
+
+
+def _service(tmp_path, response: str = "ns:http://specs.openid.net/auth/2.0\nis_valid:true\n", **overrides):
+    checked = []
+
+    def verifier(params):
+        checked.append(params)
+        return response
+
+    service = UserService(Settings(data_dir=tmp_path, **overrides), steam=SteamOpenId(verifier=verifier))
+    return service, checked
+
+
+def _callback(return_to: str = CALLBACK, steamid: str = STEAMID) -> dict:
+    claimed = f"https://steamcommunity.com/openid/id/{steamid}"
+    params = {
+        "openid.ns": "http://specs.openid.net/auth/2.0",
+        "openid.mode": "id_res",
+        "openid.op_endpoint": STEAM_OPENID_URL,
+        "openid.claimed_id": claimed,
+        "openid.identity": claimed,
+        "openid.return_to": return_to,
+        "openid.response_nonce": "2024-07-10T12:00:00Zabc",
+        "openid.assoc_handle": "1234567890",
+        "openid.signed": "signed,op_endpoint,claimed_id,identity,return_to,response_nonce,assoc_handle",
+        "openid.sig": "c2lnbmF0dXJl",
+    }
+    query = parse_qs(urlsplit(return_to).query)
+    params.update({key: values[0] for key, values in query.items()})
+    return params
+
+
+def test_login_url_asks_steam_to_pick_the_identity():
+    query = parse_qs(urlsplit(SteamOpenId().login_url(CALLBACK)).query)
+
+    assert query["openid.mode"] == ["checkid_setup"]
+    assert query["openid.return_to"] == [CALLBACK]
+    assert query["openid.realm"] == ["https://api.example.com"]
+    assert query["openid.claimed_id"] == ["http://specs.openid.net/auth/2.0/identifier_select"]
+
+
+def test_first_steam_sign_in_creates_a_user_with_the_steamid_claim(session, tmp_path):
+    service, checked = _service(tmp_path)
+
+    user = service.sign_in_with_steam(session, _callback(), CALLBACK)
+
+    assert (user.steamid, user.email, user.display_name) == (STEAMID, None, STEAMID)
+    assert checked[0]["openid.mode"] == "check_authentication"
+    assert service.sign_in_with_steam(session, _callback(), CALLBACK).id == user.id
+    token = service.start_session(session, user).access.token
+    assert jwt.decode(token, options={"verify_signature": False})["steamid"] == STEAMID
+
+
+def test_assertions_steam_does_not_confirm_are_rejected(session, tmp_path):
+    service, _ = _service(tmp_path, response="is_valid:false\n")
+    with pytest.raises(PermissionError):
+        service.sign_in_with_steam(session, _callback(), CALLBACK)
+
+    service, checked = _service(tmp_path)
+    for params in (
+        _callback(return_to="https://evil.example.com/api/auth/steam/callback"),
+        {**_callback(), "openid.op_endpoint": "https://evil.example.com/openid/login"},
+        _callback(steamid="12345"),
+    ):
+        with pytest.raises(PermissionError):
+            service.sign_in_with_steam(session, params, CALLBACK)
+    assert checked == []
+
+
+def test_signed_in_users_can_link_their_steam_account(session, tmp_path):
+    service, _ = _service(tmp_path)
+    session.add_all([User(id="u1", email="coach@example.com", display_name="Coach"), User(id="u2", display_name="x")])
+    session.commit()
+
+    return_to = urlsplit(service.steam_login_url(CALLBACK, link_user_id="u1", link_nonce="n1")).query
+    linked_return_to = parse_qs(return_to)["openid.return_to"][0]
+    assert service.sign_in_with_steam(session, _callback(return_to=linked_return_to), CALLBACK, "n1").id == "u1"
+
+    other_url = service.steam_login_url(CALLBACK, link_user_id="u2", link_nonce="n2")
+    other = parse_qs(urlsplit(other_url).query)["openid.return_to"][0]
+    with pytest.raises(ValueError, match="already linked"):
+        service.sign_in_with_steam(session, _callback(return_to=other), CALLBACK, "n2")
+
+
+def test_links_only_finish_in_the_browser_that_started_them(session, tmp_path):
+    service, _ = _service(tmp_path)
+    session.add(User(id="u1", email="coach@example.com", display_name="Coach"))
+    session.commit()
+    url = service.steam_login_url(CALLBACK, link_user_id="u1", link_nonce="starter-cookie")
+    return_to = parse_qs(urlsplit(url).query)["openid.return_to"][0]
+
+    for nonce in (None, "other-browser-cookie"):
+        with pytest.raises(PermissionError, match="another browser"):
+            service.sign_in_with_steam(session, _callback(return_to=return_to), CALLBACK, nonce)
+    assert session.get(User, "u1").steamid is None
+
+
+def test_closed_registration_refuses_unknown_steam_accounts(session, tmp_path):
+    service, _ = _service(tmp_path, registration_enabled=False)
+
+    with pytest.raises(PermissionError, match="No account is linked"):
+        service.sign_in_with_steam(session, _callback(), CALLBACK)
@@ -0,0 +1,59 @@
+from __future__ import annotations
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.storage import LocalStorage, RoutedStorage, build_storage
+
+
+def test_local_storage_copies_files_outside_root(tmp_path):
+    storage = LocalStorage(tmp_path / "processed")
+    source = tmp_path / "elsewhere.parquet"
+    source.write_bytes(b"parquet")
+
+    key = storage.put(source, "demo-1/round_summary.parquet")
+
+    assert key == "demo-1/round_summary.parquet"
+    assert storage.exists(key)
+    assert storage.get(key).read_bytes() == b"parquet"
+    assert storage.url(key) is None
+
+    storage.delete(key)
+    assert not storage.exists(key)
+
+
+def test_local_storage_resolves_legacy_absolute_paths(tmp_path):
+    storage = LocalStorage(tmp_path / "processed")
+    legacy = tmp_path / "old.parquet"
+    legacy.write_bytes(b"parquet")
+
+    assert storage.exists(str(legacy))
+    assert storage.get(str(legacy)) == legacy
+
+
+def test_build_storage_requires_bucket_for_s3(tmp_path):
+    settings = Settings(data_dir=tmp_path, storage_backend="s3")
+
+    with pytest.raises(ValueError):
+        build_storage(settings)
+
+
+def test_region_keys_are_routed_to_the_region_backend(tmp_path):
+    settings = Settings(data_dir=tmp_path, storage_regions={"eu": {"backend": "local", "path": str(tmp_path / "eu")}})
+    storage = build_storage(settings)
+    source = tmp_path / "summary.parquet"
+    source.write_bytes(b"parquet")
+
+    key = storage.put(source, RoutedStorage.region_key("eu", "demo-1.parquet"))
+
+    assert key == "@eu/demo-1.parquet"
+    assert (tmp_path / "eu" / "demo-1.parquet").read_bytes() == b"parquet"
+    assert not (settings.processed_data_path / "demo-1.parquet").exists()
+    assert storage.size(key) == 7
+    storage.delete(key)
+    assert not storage.exists(key)
+
+    storage.put(source, "demo-2.parquet")
+    assert (settings.processed_data_path / "demo-2.parquet").exists()
+    with pytest.raises(ValueError):
+        storage.get("@us/demo-1.parquet")
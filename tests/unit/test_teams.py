@@ -0,0 +1,142 @@
+from __future__ import annotations
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.models import Demo
+from stratagemforge.domain.demos.repository import DemoRepository, DemoVisibility
+from stratagemforge.domain.teams.service import TeamService
+from stratagemforge.domain.users.models import User
+from stratagemforge.domain.users.roles import role_at_least
+
+
+@pytest.fixture
+def session(session):
+    session.add_all(
+        [
+            User(id="coach", email="coach@example.com", display_name="Coach"),
+            User(id="rifler", email="rifler@example.com", display_name="Rifler"),
+            User(id="outsider", email="outsider@example.com", display_name="Outsider"),
+        ]
+    )
+    session.commit()
+    return session
+
+
+@pytest.fixture
+def service(tmp_path):
+    return TeamService(Settings(data_dir=tmp_path))
+
+
+def test_role_ranking():
+    assert role_at_least("admin", "coach")
+    assert role_at_least("coach", "analyst")
+    assert not role_at_least("player", "analyst")
+    assert not role_at_least(None, "player")
+
+
+def test_creator_coaches_and_invitees_join_with_the_offered_role(session, service):
+    team = service.create_team(session, "coach", "Falcons", "FLC")
+    assert [(member.user_id, member.role) for member in team.members] == [("coach", "coach")]
+    with pytest.raises(ValueError, match="already exists"):
+        service.create_team(session, "outsider", "falcons")
+
+    invite = service.invite(session, team.id, "coach", "Rifler@example.com", role="analyst")
+    with pytest.raises(PermissionError, match="another email"):
+        service.accept_invite(session, invite.token, "outsider")
+    service.accept_invite(session, invite.token, "rifler")
+
+    assert service.membership(session, team.id, "rifler").role == "analyst"
+    assert [team.id] == service.team_ids(session, "rifler")
+    with pytest.raises(LookupError):
+        service.accept_invite(session, invite.token, "rifler")
+
+
+def test_only_coaches_manage_the_roster_and_one_coach_remains(session, service):
+    team = service.create_team(session, "coach", "Falcons")
+    invite = service.invite(session, team.id, "coach", "rifler@example.com")
+    service.accept_invite(session, invite.token, "rifler")
+
+    with pytest.raises(PermissionError):
+        service.invite(session, team.id, "rifler", "outsider@example.com")
+    with pytest.raises(LookupError):
+        service.get_team(session, team.id, "outsider")
+    service.invite(session, team.id, "outsider", "outsider@example.com", admin=True)
+
+    with pytest.raises(ValueError, match="at least one coach"):
+        service.set_member_role(session, team.id, "coach", "coach", "player")
+    service.set_member_role(session, team.id, "coach", "rifler", "coach")
+    service.set_member_role(session, team.id, "rifler", "coach", "analyst")
+
+    service.remove_member(session, team.id, "coach", "coach")
+    assert service.membership(session, team.id, "coach") is None
+
+
+def test_demo_visibility_is_scoped_to_the_callers_teams(session):
+    for demo_id, team_id, owner_id in [("open", None, None), ("ours", "t1", None), ("theirs", "t2", None)]:
+        session.add(
+            Demo(
+                id=demo_id,
+                original_filename=f"{demo_id}.dem",
+                stored_path=f"/tmp/{demo_id}.dem",
+                checksum=demo_id,
+                size_bytes=1,
+                team_id=team_id,
+                owner_id=owner_id,
+            )
+        )
+    session.commit()
+
+    visibility = DemoVisibility(user_id="rifler", team_ids=("t1",))
+    demos, total = DemoRepository(session).search(visibility=visibility)
+
+    assert total == 2
+    assert {demo.id for demo in demos} == {"open", "ours"}
+    assert not visibility.allows(session.get(Demo, "theirs"))
+    assert DemoVisibility(user_id="owner").allows(Demo(team_id="t2", owner_id="owner"))
+
+
+def test_explicit_visibility_overrides_the_team_default(session):
+    for demo_id, team_id, level in [("mine", None, "private"), ("shared", "t1", "public"), ("locked", "t1", "private")]:
+        session.add(
+            Demo(
+                id=demo_id,
+                original_filename=f"{demo_id}.dem",
+                stored_path=f"/tmp/{demo_id}.dem",
+                checksum=demo_id,
+                size_bytes=1,
+                team_id=team_id,
+                owner_id="owner",
+                visibility=level,
+            )
+        )
+    session.commit()
+
+    outsider = DemoVisibility(user_id="outsider")
+    member = DemoVisibility(user_id="rifler", team_ids=("t1",))
+
+    assert {demo.id for demo in DemoRepository(session).search(visibility=outsider)[0]} == {"shared"}
+    assert {demo.id for demo in DemoRepository(session).search(visibility=member)[0]} == {"shared"}
+    assert not member.allows(session.get(Demo, "locked"))
+    assert DemoVisibility(user_id="owner").allows(session.get(Demo, "locked"))
+    assert DemoRepository(session).search(owner_id="owner")[1] == 3
+
+
+def test_team_permissions_override_the_defaults_for_every_check(session, service):
+    team = service.create_team(session, "coach", "Falcons")
+    invite = service.invite(session, team.id, "coach", "rifler@example.com", role="analyst")
+    service.accept_invite(session, invite.token, "rifler")
+    assert service.can(session, team.id, "rifler", "upload")
+    assert not service.can(session, team.id, "rifler", "manage_members")
+
+    with pytest.raises(PermissionError, match="coaches"):
+        service.set_permissions(session, team.id, "rifler", {"manage_members": "analyst"})
+    with pytest.raises(ValueError, match="Unknown team action"):
+        service.set_permissions(session, team.id, "coach", {"launch": "player"})
+    service.set_permissions(session, team.id, "coach", {"manage_members": "analyst", "view": "coach"})
+
+    service.invite(session, team.id, "rifler", "outsider@example.com")
+    assert service.team_ids(session, "rifler", "view") == []
+    assert service.team_ids(session, "coach", "view") == [team.id]
+    assert not service.can(session, team.id, "outsider", "view")
+    assert service.policy(session, team.id).rules["share"] == "coach"
@@ -0,0 +1,76 @@
+from __future__ import annotations
+
+import json
+import tarfile
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.storage import LocalStorage
+from stratagemforge.domain.admin.tenant_export import TenantExportService
+from stratagemforge.domain.demos.models import Demo, Match
+from stratagemforge.domain.teams.models import Team, TeamMember
+from stratagemforge.domain.users.models import User
+
+
+def test_export_team_bundles_its_matches(tmp_path, session):
+    storage = LocalStorage(tmp_path / "artifacts")
+    (tmp_path / "summary.parquet").write_bytes(b"parquet")
+    storage.put(tmp_path / "summary.parquet", "d1.parquet")
+    raw = tmp_path / "d1.dem"
+    raw.write_bytes(b"demo")
+
+    team = Team(id="t1", name="Alpha", map_pool=["de_mirage"])
+    team.members.append(TeamMember(user_id="u1", role="coach"))
+    uploaded = Demo(
+        id="d1", original_filename="scrim.dem", stored_path=str(raw), checksum="c1", size_bytes=4, team_id="t1",
+        status="processed", extra_metadata={"team_id": "t1", "summary_key": "d1.parquet"},
+    )
+    by_member = Demo(
+        id="d2", original_filename="b.dem", stored_path="b.dem", checksum="c2", size_bytes=4, owner_id="u1"
+    )
+    by_member.match = Match(team_a="alpha", team_b="Bravo")
+    other = Demo(id="d3", original_filename="c.dem", stored_path="c.dem", checksum="c3", size_bytes=4)
+    session.add_all([User(id="u1", email="coach@example.com", display_name="Coach"), team, uploaded, by_member, other])
+    session.commit()
+
+    service = TenantExportService(Settings(data_dir=tmp_path / "data"), storage=storage)
+    result = service.export_team(session, "t1", include_raw=True)
+
+    assert result.demos == 2
+    assert service.archive_path(result.path.name) == result.path
+    with tarfile.open(result.path, "r:gz") as archive:
+        names = set(archive.getnames())
+        manifest = json.loads(archive.extractfile("manifest.json").read())
+        metadata = json.loads(archive.extractfile("matches/d2/metadata.json").read())
+    assert {"team.json", "matches/d1/summary.parquet", "matches/d1/raw/scrim.dem"} <= names
+    assert not any(name.startswith("matches/d3") for name in names)
+    assert manifest["demos"] == ["d1", "d2"]
+    assert metadata["match"]["team_b"] == "Bravo"
+
+
+def test_export_leaves_out_demos_of_other_tenants_teams_with_the_same_name(tmp_path, session):
+    ours, theirs = Team(id="t1", name="Alpha"), Team(id="t2", name="alpha")
+    theirs.members.append(TeamMember(user_id="u2", role="coach"))
+    private = Demo(
+        id="d1", original_filename="a.dem", stored_path="a.dem", checksum="c1", size_bytes=4, team_id="t2",
+        owner_id="u2", visibility="private",
+    )
+    private.match = Match(team_a="Alpha", team_b="Bravo")
+    mine = Demo(id="d2", original_filename="b.dem", stored_path="b.dem", checksum="c2", size_bytes=4, team_id="t1")
+    session.add_all([User(id="u2", email="rival@example.com", display_name="Rival"), ours, theirs, private, mine])
+    session.commit()
+
+    service = TenantExportService(Settings(data_dir=tmp_path / "data"), storage=LocalStorage(tmp_path / "artifacts"))
+
+    assert [demo.id for demo in service.team_demos(session, ours)] == ["d2"]
+    assert [demo.id for demo in service.team_demos(session, theirs)] == ["d1"]
+
+
+def test_export_unknown_team_or_archive(tmp_path, session):
+    service = TenantExportService(Settings(data_dir=tmp_path), storage=LocalStorage(tmp_path / "artifacts"))
+
+    with pytest.raises(LookupError):
+        service.export_team(session, "missing")
+    with pytest.raises(LookupError):
+        service.archive_path("../test.db")
@@ -0,0 +1,64 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.admin.throughput import ThroughputService, parse_window
+from stratagemforge.domain.demos.models import ParseJob
+
+NOW = datetime(2024, 7, 10, 12, 30)
+
+
+def _job(started_at: datetime, seconds: float, status: str = "parsed", size: int = 100) -> ParseJob:
+    return ParseJob(
+        demo_id="d1",
+        status=status,
+        started_at=started_at,
+        finished_at=started_at + timedelta(seconds=seconds),
+        duration_seconds=seconds,
+        size_bytes=size,
+    )
+
+
+def test_parse_window_accepts_units_and_rejects_the_rest():
+    assert parse_window("7d") == timedelta(days=7)
+    assert parse_window("24H") == timedelta(hours=24)
+    assert parse_window("2w") == timedelta(weeks=2)
+    for value in ("", "0d", "7", "7y", "400d"):
+        with pytest.raises(ValueError):
+            parse_window(value)
+
+
+def test_report_summarises_jobs_in_the_window(session, tmp_path):
+    durations = [float(seconds) for seconds in range(1, 21)]
+    session.add_all(_job(NOW - timedelta(days=1, minutes=index), seconds) for index, seconds in enumerate(durations))
+    session.add(_job(NOW - timedelta(hours=2), 3.0, status="failed", size=50))
+    session.add(_job(NOW - timedelta(hours=1), 1.0, status="skipped"))
+    session.add(_job(NOW - timedelta(days=9), 99.0, status="failed"))
+    session.commit()
+
+    report = ThroughputService(Settings(data_dir=tmp_path)).report(session, timedelta(days=7), now=NOW)
+
+    assert report.window == "1w"
+    assert (report.jobs, report.failed) == (22, 1)
+    assert report.failure_rate == round(1 / 22, 4)
+    assert report.bytes_processed == 20 * 100 + 50 + 100
+    assert report.p50_parse_seconds == 9.0
+    assert report.p95_parse_seconds == 19.0
+    assert report.bucket == "day"
+    assert len(report.buckets) == 8
+    assert sum(bucket.jobs for bucket in report.buckets) == 22
+    assert report.buckets[0].jobs == 0 and report.buckets[0].failure_rate is None
+
+
+def test_short_windows_are_bucketed_by_hour(session, tmp_path):
+    session.add(_job(NOW - timedelta(minutes=10), 2.0))
+    session.commit()
+
+    report = ThroughputService(Settings(data_dir=tmp_path)).report(session, timedelta(hours=6), now=NOW)
+
+    assert report.bucket == "hour"
+    assert [bucket.jobs for bucket in report.buckets][-1] == 1
+    assert report.buckets[0].start == datetime(2024, 7, 10, 6, 0)
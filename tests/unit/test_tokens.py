@@ -0,0 +1,105 @@
+from __future__ import annotations
+
+import time
+from datetime import datetime
+
+import jwt
+import pytest
+
+from stratagemforge.api.auth import identity_from_claims
+from stratagemforge.core.config import Settings
+from stratagemforge.core.tokens import JwtValidator
+from stratagemforge.domain.users.models import User
+from stratagemforge.domain.users.service import UserService
+
+SECRET = "user-service-test-secret"
+
+
+def _validator(tmp_path, **overrides) -> JwtValidator:
+    settings = Settings(
+        data_dir=tmp_path,
+        jwt_algorithms="HS256",
+        jwt_issuer="https://users.example.com",
+        jwt_audience="stratagemforge",
+        jwt_leeway_seconds=0,
+        **overrides,
+    )
+    return JwtValidator(settings, key_resolver=lambda token: SECRET)
+
+
+def _token(secret: str = SECRET, **claims) -> str:
+    payload = {
+        "sub": "user-1",
+        "iss": "https://users.example.com",
+        "aud": "stratagemforge",
+        "exp": int(time.time()) + 300,
+        **claims,
+    }
+    return jwt.encode(payload, secret, algorithm="HS256")
+
+
+def test_valid_token_returns_its_claims(tmp_path):
+    claims = _validator(tmp_path).validate(_token(team_id="t1"))
+
+    assert claims["sub"] == "user-1"
+    assert claims["team_id"] == "t1"
+
+
+@pytest.mark.parametrize(
+    "token, message",
+    [
+        (_token(exp=int(time.time()) - 60), "Bearer token has expired"),
+        (_token(iss="https://evil.example.com"), "Invalid bearer token"),
+        (_token(aud="another-service"), "Invalid bearer token"),
+        (_token(secret="forged-secret"), "Invalid bearer token"),
+        ("not-a-jwt", "Invalid bearer token"),
+    ],
+)
+def test_rejects_expired_foreign_and_forged_tokens(tmp_path, token, message):
+    with pytest.raises(PermissionError, match=message):
+        _validator(tmp_path).validate(token)
+
+
+def test_tokens_for_another_use_are_rejected(tmp_path):
+    with pytest.raises(PermissionError, match="Invalid bearer token"):
+        _validator(tmp_path).validate(_token(token_use="id"))
+    assert _validator(tmp_path).validate(_token(token_use="access"))["sub"] == "user-1"
+
+
+def test_id_tokens_are_not_accepted_as_bearer_tokens(tmp_path):
+    users = UserService(Settings(data_dir=tmp_path, jwt_audience=None))
+    user = User(id="u1", email="coach@example.com", display_name="Coach", role="admin")
+
+    assert users.token_validator.validate(users.tokens.issue(user).token)["sub"] == "u1"
+    id_token = users.tokens.issue_id_token(user, "client-1", datetime.utcnow())
+    with pytest.raises(PermissionError, match="Invalid bearer token"):
+        users.token_validator.validate(id_token)
+
+
+def test_bearer_tokens_are_refused_without_a_jwks_url(tmp_path):
+    validator = JwtValidator(Settings(data_dir=tmp_path))
+
+    assert not validator.enabled
+    with pytest.raises(PermissionError, match="not accepted"):
+        validator.validate(_token())
+
+
+def test_identity_scopes_come_from_scope_and_role_claims():
+    default = identity_from_claims({"sub": "u1", "jti": "abc", "org": "t9"}, team_claim="org")
+    assert default.scopes == ("read", "upload")
+    assert default.team_id == "t9"
+    assert default.credential_id == "u1"
+
+    reader = identity_from_claims({"sub": "u2", "scope": "read profile"})
+    assert reader.scopes == ("read",)
+    assert not reader.allows("upload")
+
+    admin = identity_from_claims({"sub": "u3", "scope": "read", "role": "admin"})
+    assert admin.allows("upload")
+
+
+def test_platform_roles_decide_default_scopes():
+    player = identity_from_claims({"sub": "u4", "role": "player"})
+    assert player.scopes == ("read",) and player.role == "player"
+    assert identity_from_claims({"sub": "u5", "role": "coach"}).scopes == ("read", "upload")
+    assert identity_from_claims({"sub": "u6", "role": "admin"}).allows("admin")
@@ -0,0 +1,13 @@
+from __future__ import annotations
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.tracing import configure_tracing, set_attributes, span
+
+
+def test_tracing_is_off_by_default(tmp_path):
+    assert configure_tracing(Settings(data_dir=tmp_path)) is False
+
+
+def test_spans_are_safe_without_a_configured_provider():
+    with span("demo.parse", demo_id="d1", parser=None):
+        set_attributes(parse_status="parsed", demo_format=None)
@@ -0,0 +1,38 @@
+from __future__ import annotations
+
+import pandas as pd
+
+from stratagemforge.domain.demos.trajectories import build_trajectories, simplify
+
+
+def test_simplify_keeps_only_the_corners_of_a_path():
+    path = [[0, 0], [10, 1], [20, 0], [30, 0], [30, 10], [30, 20], [31, 30]]
+
+    assert simplify(path, tolerance=2) == [0, 3, 6]
+    assert simplify(path, tolerance=0.5) == [0, 1, 3, 5, 6]
+    assert simplify(path[:2], tolerance=2) == [0, 1]
+
+
+def test_trajectories_split_by_round_and_player_and_skip_the_dead():
+    rows = []
+    for tick in range(0, 80, 16):
+        rows.append({"tick": tick, "round": 1, "steamid": "7", "name": "s1mple", "side": "CT",
+                     "is_alive": True, "x": float(tick), "y": 0.0, "z": 10.0})
+        rows.append({"tick": tick, "round": 1, "steamid": "8", "name": "b1t", "side": "T",
+                     "is_alive": tick < 48, "x": 0.0, "y": float(tick), "z": 10.0})
+    rows.append({"tick": 200, "round": 2, "steamid": "7", "name": "s1mple", "side": "CT",
+                 "is_alive": True, "x": 1.0, "y": 1.0, "z": 10.0})
+
+    collection = build_trajectories(pd.DataFrame(rows), tolerance=1.0)
+
+    assert collection["type"] == "FeatureCollection"
+    assert [(f["properties"]["round"], f["properties"]["steamid"]) for f in collection["features"]] == [
+        (1, "7"),
+        (1, "8"),
+    ]  # a single point in round 2 is no path
+    ct = collection["features"][0]
+    assert ct["geometry"] == {"type": "LineString", "coordinates": [[0.0, 0.0, 10.0], [64.0, 0.0, 10.0]]}
+    assert ct["properties"]["ticks"] == [0, 64]
+    assert collection["features"][1]["properties"]["ticks"] == [0, 32]
+    assert collection["properties"]["source_points"] == 8
+    assert build_trajectories(pd.DataFrame(rows), round_number=2)["features"] == []
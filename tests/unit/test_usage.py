@@ -0,0 +1,78 @@
+from __future__ import annotations
+
+import hashlib
+from datetime import date, datetime, timedelta
+
+import pandas as pd
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.core.storage import LocalStorage
+from stratagemforge.domain.teams.models import Team
+from stratagemforge.domain.usage.models import UsageEvent
+from stratagemforge.domain.usage.schemas import UsageEventIn
+from stratagemforge.domain.usage.service import UsageService
+
+
+@pytest.fixture
+def session(session):
+    session.add_all([Team(id="t1", name="Alpha"), Team(id="t2", name="Bravo")])
+    session.commit()
+    return session
+
+
+def test_record_scrubs_personal_data_and_respects_opt_out(session, tmp_path):
+    service = UsageService(Settings(data_dir=tmp_path, usage_hash_salt="pepper"))
+    service.set_opt_out(session, "t2", True)
+
+    accepted, dropped = service.record(
+        session,
+        [
+            UsageEventIn(name="report.generated", team_id="t1", user_id="u1", properties={"report": "pistol", "email": "a@b.c", "nested": {"x": 1}}),
+            UsageEventIn(name="replay.viewed", team_id="t2", user_id="u2"),
+            UsageEventIn(name="Not Valid", team_id="t1"),
+        ],
+        source="frontend",
+    )
+
+    assert (accepted, dropped) == (1, 2)
+    event = session.query(UsageEvent).one()
+    assert event.properties == {"report": "pistol"}
+    assert event.user_hash and event.user_hash != "u1"
+
+
+def test_summary_and_daily_export(session, tmp_path):
+    service = UsageService(Settings(data_dir=tmp_path), storage=LocalStorage(tmp_path / "artifacts"))
+    day = datetime(2024, 7, 1, 12)
+    service.record(
+        session,
+        [
+            UsageEventIn(name="replay.viewed", team_id="t1", user_id="u1", occurred_at=day),
+            UsageEventIn(name="replay.viewed", team_id="t1", user_id="u2", occurred_at=day),
+            UsageEventIn(name="report.generated", team_id="t2", user_id="u1", occurred_at=day + timedelta(days=1)),
+        ],
+    )
+
+    summary = service.summary(session, day - timedelta(hours=1), day + timedelta(days=2))
+    assert [(row.name, row.events, row.users) for row in summary.events] == [("replay.viewed", 2, 2), ("report.generated", 1, 1)]
+
+    result = service.export_day(session, date(2024, 7, 1))
+    assert result.rows == 2
+    frame = pd.read_parquet(tmp_path / "artifacts" / result.key)
+    assert set(frame["name"]) == {"replay.viewed"}
+
+    service.set_opt_out(session, "t1", True)
+    assert service.export_day(session, date(2024, 7, 1)).rows == 0
+
+
+def test_user_hashes_use_a_generated_salt_when_none_is_configured(session, tmp_path):
+    settings = Settings(data_dir=tmp_path)
+    service = UsageService(settings)
+    service.record(session, [UsageEventIn(name="replay.viewed", team_id="t1", user_id="u1")])
+
+    salt = service.salt_path.read_text()
+    assert len(salt) == 64 and service.salt_path.stat().st_mode & 0o777 == 0o600
+    user_hash = session.query(UsageEvent).one().user_hash
+    assert user_hash != hashlib.sha256(b":u1").hexdigest()
+    assert UsageService(settings).salt == salt  # kept across restarts
+    assert UsageService(Settings(data_dir=tmp_path, usage_hash_salt="pepper")).salt == "pepper"
@@ -0,0 +1,155 @@
+from __future__ import annotations
+
+from datetime import datetime, timedelta
+
+import pytest
+from sqlalchemy import select, update
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.models import Demo, Match, MatchPlayer
+from stratagemforge.domain.users.models import PasswordResetToken, RefreshToken, RegistrationInvite
+from stratagemforge.domain.users.service import EmailTakenError, UserService
+
+
+@pytest.fixture
+def service(tmp_path):
+    return UserService(Settings(data_dir=tmp_path))
+
+
+def test_register_normalises_email_and_rejects_duplicates(session, service):
+    user = service.register(session, "Coach@Example.com", " Coach ", "s3cret-pass", "Europe/Berlin")
+
+    assert (user.email, user.display_name, user.timezone, user.role) == (
+        "coach@example.com",
+        "Coach",
+        "Europe/Berlin",
+        "analyst",
+    )
+    assert service.authenticate(session, "COACH@example.com", "s3cret-pass").id == user.id
+    with pytest.raises(EmailTakenError):
+        service.register(session, "coach@example.com", "Other", "another-pass")
+
+
+def test_invite_only_registration_uses_single_use_invites_with_role_presets(session, service):
+    service.set_registration_policy(session, "invite")
+    with pytest.raises(PermissionError, match="invitation only"):
+        service.register(session, "coach@example.com", "Coach", "s3cret-pass")
+
+    invite, token = service.create_registration_invite(session, role="coach", email="Coach@example.com")
+    with pytest.raises(PermissionError, match="another email address"):
+        service.register(session, "someone@example.com", "Someone", "s3cret-pass", invite_token=token)
+    user = service.register(session, "coach@example.com", "Coach", "s3cret-pass", invite_token=token)
+
+    assert user.role == "coach"
+    assert (invite.used_by, service.list_registration_invites(session)) == (user.id, [])
+    with pytest.raises(PermissionError, match="Invalid or expired invite"):
+        service.register(session, "coach2@example.com", "Coach", "s3cret-pass", invite_token=token)
+    assert service.register(session, "admin-made@example.com", "New", "s3cret-pass", admin=True).role == "analyst"
+
+
+def test_domain_restricted_registration(session, service):
+    with pytest.raises(ValueError, match="at least one allowed domain"):
+        service.set_registration_policy(session, "domain")
+    policy = service.set_registration_policy(session, "domain", ["@Example.com", "example.com", " "])
+    assert policy.allowed_domains == ["example.com"]
+
+    assert service.register(session, "coach@example.com", "Coach", "s3cret-pass").email == "coach@example.com"
+    with pytest.raises(PermissionError, match="limited to example.com"):
+        service.register(session, "coach@elsewhere.org", "Coach", "s3cret-pass")
+    _, expired = service.create_registration_invite(session)
+    session.execute(update(RegistrationInvite).values(expires_at=datetime.utcnow() - timedelta(minutes=1)))
+    with pytest.raises(PermissionError, match="Invalid or expired invite"):
+        service.register(session, "coach@elsewhere.org", "Coach", "s3cret-pass", invite_token=expired)
+
+
+def test_profile_email_changes_stay_within_the_allowed_domains(session, service):
+    user = service.register(session, "coach@example.com", "Coach", "s3cret-pass")
+    service.set_registration_policy(session, "domain", ["example.com"])
+
+    with pytest.raises(PermissionError, match="limited to example.com"):
+        service.update_profile(session, user.id, email="coach@elsewhere.org")
+    assert service.update_profile(session, user.id, email="Head.Coach@example.com").email == "head.coach@example.com"
+    user = service.update_profile(session, user.id, email="coach@elsewhere.org", admin=True)
+    assert user.email == "coach@elsewhere.org"
+    assert service.update_profile(session, user.id, display_name="Head coach").email == "coach@elsewhere.org"
+
+
+def test_change_password_checks_the_current_one_and_ends_sessions(session, service):
+    user = service.register(session, "coach@example.com", "Coach", "s3cret-pass")
+    tokens = service.start_session(session, user)
+
+    with pytest.raises(PermissionError, match="Current password is incorrect"):
+        service.change_password(session, user.id, "wrong-pass", "new-s3cret")
+    service.change_password(session, user.id, "s3cret-pass", "new-s3cret")
+
+    assert service.authenticate(session, "coach@example.com", "new-s3cret").id == user.id
+    with pytest.raises(PermissionError):
+        service.refresh_session(session, tokens.refresh_token)
+
+
+def test_password_reset_tokens_are_single_use(session, service):
+    user = service.register(session, "coach@example.com", "Coach", "s3cret-pass")
+    delivered = []
+    service.password_reset_hooks.append(lambda _session, target, token: delivered.append((target.id, token)))
+
+    service.request_password_reset(session, "nobody@example.com")
+    assert delivered == []
+    service.request_password_reset(session, "COACH@example.com")
+    [(user_id, token)] = delivered
+    assert user_id == user.id
+
+    service.confirm_password_reset(session, token, "reset-pass")
+    assert service.authenticate(session, "coach@example.com", "reset-pass").id == user.id
+    with pytest.raises(ValueError, match="Invalid or expired reset token"):
+        service.confirm_password_reset(session, token, "another-pass")
+
+
+def test_expired_reset_token_is_rejected(session, service):
+    service.register(session, "coach@example.com", "Coach", "s3cret-pass")
+    delivered = []
+    service.password_reset_hooks.append(lambda _session, _user, token: delivered.append(token))
+    service.request_password_reset(session, "coach@example.com")
+    stored = session.scalars(select(PasswordResetToken)).one()
+    stored.expires_at = datetime.utcnow() - timedelta(minutes=1)
+    session.commit()
+
+    with pytest.raises(ValueError, match="Invalid or expired reset token"):
+        service.confirm_password_reset(session, delivered[0], "reset-pass")
+
+
+def test_soft_delete_hides_the_user_and_revokes_credentials(session, service):
+    user = service.register(session, "coach@example.com", "Coach", "s3cret-pass")
+    service.start_session(session, user)
+    _, key = service.create_api_key(session, user.id, "ci", ["read"])
+
+    service.delete_user(session, user.id)
+
+    assert user.deleted_at is not None and not user.is_active
+    assert service.list_users(session) == []
+    with pytest.raises(LookupError):
+        service.get_user(session, user.id)
+    with pytest.raises(PermissionError):
+        service.authenticate_api_key(session, key)
+    assert all(token.revoked_at for token in session.scalars(select(RefreshToken)))
+
+
+def test_search_matches_prefixes_personas_and_typos(session, service):
+    coach = service.register(session, "coach@example.com", "Coach Carter", "s3cret-pass")
+    sniper = service.register(session, "awp@example.com", "Marcus", "s3cret-pass")
+    sniper.steamid = "76561198000000001"
+    demo = Demo(original_filename="a.dem", stored_path="a.dem", checksum="a", size_bytes=1, status="processed")
+    match = Match(demo=demo, team_a="Alpha", team_b="Bravo")
+    match.players = [MatchPlayer(steamid=sniper.steamid, name="s1mple_fan")]
+    session.add_all([demo, match])
+    session.commit()
+
+    assert [(r["id"], r["matched"]) for r in service.search_users(session, "carter")] == [(coach.id, "display_name")]
+    persona = service.search_users(session, "s1mp")[0]
+    assert (persona["id"], persona["persona"], persona["email"]) == (sniper.id, "s1mple_fan", None)
+    assert service.search_users(session, "Marcsu")[0]["id"] == sniper.id
+    assert service.search_users(session, "awp@") == []
+    assert service.search_users(session, "awp@example.com")[0]["id"] == sniper.id
+    assert service.search_users(session, "awp@", include_email=True)[0]["email"] == "awp@example.com"
+    assert service.search_users(session, "coach", user_ids=[sniper.id]) == []
+    with pytest.raises(ValueError):
+        service.search_users(session, " c ")
@@ -0,0 +1,131 @@
+from __future__ import annotations
+
+import json
+import threading
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+import pytest
+
+from stratagemforge.core.config import Settings
+from stratagemforge.domain.demos.models import Demo
+from stratagemforge.domain.demos.webhooks import (
+    SIGNATURE_HEADER,
+    TIMESTAMP_HEADER,
+    WebhookNotifier,
+    sign,
+    validate_callback_url,
+)
+
+LOCAL = "127.0.0.1"
+
+
+@pytest.fixture
+def receiver():
+    received = []
+    statuses = []
+
+    class Handler(BaseHTTPRequestHandler):
+        def do_POST(self):  # noqa: N802 - http.server naming
+            body = self.rfile.read(int(self.headers["Content-Length"]))
+            received.append((self.headers, body))
+            self.send_response(statuses.pop(0) if statuses else 204)
+            self.end_headers()
+
+        def log_message(self, *args):
+            pass
+
+    server = HTTPServer(("127.0.0.1", 0), Handler)
+    thread = threading.Thread(target=server.serve_forever, daemon=True)
+    thread.start()
+    try:
+        yield f"http://127.0.0.1:{server.server_port}/hook", received, statuses
+    finally:
+        server.shutdown()
+
+
+def make_demo(session, **metadata):
+    demo = Demo(
+        id="d1", original_filename="a.dem", stored_path="a.dem", checksum="c1", size_bytes=10, status="processed",
+        extra_metadata={"parse_status": "parsed", **metadata},
+    )
+    session.add(demo)
+    session.commit()
+    return demo
+
+
+def test_webhook_posts_signed_result_to_global_and_callback_urls(session, tmp_path, receiver):
+    url, received, _ = receiver
+    settings = Settings(data_dir=tmp_path, webhook_url=url, webhook_secret="s3cret", outbound_allowed_hosts=LOCAL)
+    demo = make_demo(session, callback_url=url + "?upload=1")
+
+    WebhookNotifier(settings).notify_after_ingest(session, demo)
+
+    assert len(received) == 2
+    headers, body = received[0]
+    assert headers[SIGNATURE_HEADER] == sign("s3cret", headers[TIMESTAMP_HEADER], body)
+    payload = json.loads(body)
+    assert payload["event"] == "demo.processed"
+    assert payload["result"]["demo_id"] == "d1"
+    assert [delivery["status_code"] for delivery in demo.extra_metadata["webhook_deliveries"]] == [204, 204]
+
+
+def test_webhook_reports_failed_parse_and_retries_server_errors(session, tmp_path, receiver):
+    url, received, statuses = receiver
+    statuses.extend([503, 500])
+    settings = Settings(data_dir=tmp_path, webhook_max_retries=2, outbound_allowed_hosts=LOCAL)
+    demo = make_demo(session, callback_url=url, parse_status="failed")
+
+    WebhookNotifier(settings, backoff_seconds=0).notify_after_ingest(session, demo)
+
+    assert len(received) == 3
+    assert json.loads(received[-1][1])["event"] == "demo.failed"
+    assert SIGNATURE_HEADER not in received[-1][0]
+    delivery = demo.extra_metadata["webhook_deliveries"][0]
+    assert (delivery["attempts"], delivery["status_code"]) == (3, 204)
+
+
+def test_webhook_gives_up_on_client_errors(tmp_path, receiver):
+    url, received, statuses = receiver
+    statuses.append(410)
+    notifier = WebhookNotifier(Settings(data_dir=tmp_path, outbound_allowed_hosts=LOCAL), backoff_seconds=0)
+
+    outcome = notifier.deliver(url, "demo.processed", b"{}")
+
+    assert len(received) == 1
+    assert outcome["error"] == "HTTP 410"
+
+
+def test_webhooks_never_reach_internal_addresses(tmp_path, receiver):
+    url, received, _ = receiver
+    notifier = WebhookNotifier(Settings(data_dir=tmp_path, webhook_max_retries=5), backoff_seconds=0)
+
+    outcome = notifier.deliver(url, "demo.processed", b"{}")
+
+    assert received == []
+    assert outcome["attempts"] == 1
+    assert "not a public address" in outcome["error"]
+
+
+def test_webhook_retries_stop_at_the_time_budget(tmp_path, receiver):
+    url, received, statuses = receiver
+    statuses.extend([503] * 10)
+    settings = Settings(
+        data_dir=tmp_path, webhook_max_retries=10, webhook_retry_budget_seconds=0.5, outbound_allowed_hosts=LOCAL
+    )
+
+    outcome = WebhookNotifier(settings, backoff_seconds=0.2).deliver(url, "demo.processed", b"{}")
+
+    assert outcome["error"] == "HTTP 503"
+    assert len(received) == outcome["attempts"] < 4
+
+
+def test_callback_urls_must_be_http():
+    validate_callback_url("https://93.184.216.34/hook")
+    with pytest.raises(ValueError):
+        validate_callback_url("file:///etc/passwd")
+
+
+def test_callback_urls_must_be_public():
+    with pytest.raises(ValueError, match="not a public address"):
+        validate_callback_url("http://169.254.169.254/latest/meta-data/")
+    validate_callback_url("http://127.0.0.1:8080/hook", frozenset({LOCAL}))